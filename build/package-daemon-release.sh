@@ -46,6 +46,7 @@ if [ ! -z "$LNX32_DMN" ]; then
     copy_if_exists "${XGO_DMN_OUTPUT_DIR}/${LNX32_OUT}/${BIN_NAME}" "$LNX32_BIN_DIR"
     copy_if_exists "./linux/50-skyhwd.rules" "$LNX32_RULES_DIR"
     copy_if_exists "./linux/skyhwd.service" "$LNX32_SERVICE_DIR"
+    copy_if_exists "./linux/skyhwd.socket" "$LNX32_SERVICE_DIR"
 fi
 
 # Linux amd64
@@ -56,6 +57,7 @@ if [ ! -z "$LNX64_DMN" ]; then
     copy_if_exists "${XGO_DMN_OUTPUT_DIR}/${LNX64_OUT}/${BIN_NAME}" "$LNX64_BIN_DIR"
     copy_if_exists "./linux/50-skyhwd.rules" "$LNX64_RULES_DIR"
     copy_if_exists "./linux/skyhwd.service" "$LNX64_SERVICE_DIR"
+    copy_if_exists "./linux/skyhwd.socket" "$LNX64_SERVICE_DIR"
 fi
 
 # Linux arm
@@ -66,6 +68,7 @@ if [ ! -z "$LNX_ARM_DMN" ]; then
     copy_if_exists "${XGO_DMN_OUTPUT_DIR}/${LNX_ARM_OUT}/${BIN_NAME}" "$LNX_ARM_BIN_DIR"
     copy_if_exists "./linux/50-skyhwd.rules" "$LNX_ARM_RULES_DIR"
     copy_if_exists "./linux/skyhwd.service" "$LNX_ARM_SERVICE_DIR"
+    copy_if_exists "./linux/skyhwd.socket" "$LNX_ARM_SERVICE_DIR"
 fi
 
 # Windows amd64
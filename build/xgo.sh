@@ -34,8 +34,10 @@ if [ -n "$OUTPUT_DIR" ]; then
 fi
 
 COMMIT=`git rev-parse HEAD`
+BUILD_TIME=`date -u +%Y-%m-%dT%H:%M:%SZ`
 
 xgo -targets="$OSARCH" \
+	-ldflags="-X main.Commit=${COMMIT} -X main.BuildTime=${BUILD_TIME}" \
 	-dest="${OUTPUT_DIR}" \
 	-out="$CMD" \
 	"${CMDDIR}/${CMD}"
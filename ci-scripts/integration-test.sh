@@ -59,7 +59,8 @@ COMMIT=$(git rev-parse HEAD)
 BRANCH=$(git rev-parse --abbrev-ref HEAD)
 CMDPKG=$(go list ./cmd/daemon)
 COVERPKG=$(dirname $(dirname ${CMDPKG}))
-GOLDFLAGS="-X ${CMDPKG}.Commit=${COMMIT} -X ${CMDPKG}.Branch=${BRANCH}"
+BUILD_TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+GOLDFLAGS="-X ${CMDPKG}.Commit=${COMMIT} -X ${CMDPKG}.Branch=${BRANCH} -X ${CMDPKG}.BuildTime=${BUILD_TIME}"
 set -euxo pipefail
 
 DATA_DIR=$(mktemp -d -t daemon-data-dir.XXXXXX)
@@ -79,6 +80,7 @@ AUTO_PRESS_BUTTONS=$AUTO_PRESS_BUTTONS ./"$BINARY" -web-interface-port=$PORT \
             -test.run "^TestRunMain$" \
             -test.coverprofile="${COVERAGEFILE}" \
             -daemon-mode $MODE \
+            -disable-rate-limit \
             $ENABLE_CSRF \
             &
 
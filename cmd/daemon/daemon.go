@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"os"
+	"runtime"
 
 	"github.com/skycoin/hardware-wallet-daemon/src/api"
 
@@ -18,6 +19,8 @@ var (
 	Commit = ""
 	// Branch name. Can be set by -ldflags
 	Branch = ""
+	// Build time, in RFC3339. Can be set by -ldflags
+	BuildTime = ""
 
 	logger = logging.MustGetLogger("hw-daemon")
 
@@ -37,12 +40,36 @@ func main() {
 		flag.Parse()
 	}
 
+	if flag.NArg() > 0 {
+		// the relay, daemon and audit commands use the settings of the profile
+		if flag.Arg(0) == "relay" || flag.Arg(0) == "daemon" || flag.Arg(0) == "audit" {
+			if err := appConfig.ApplyProfile(); err != nil {
+				logger.Error(err)
+				os.Exit(1)
+			}
+		}
+
+		if err := daemon.RunCommand(appConfig, flag.Args(), os.Stdout); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// apply the settings of the profile before the config is copied
+	if err := appConfig.ApplyProfile(); err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+
 	d := daemon.NewDaemon(daemon.Config{
 		App: appConfig,
 		Build: api.BuildInfo{
-			Version: Version,
-			Commit:  Commit,
-			Branch:  Branch,
+			Version:   Version,
+			Commit:    Commit,
+			Branch:    Branch,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
 		},
 	}, logger)
 
@@ -52,6 +79,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	if d.IsDataDirCheck() {
+		if err := d.CheckDataDir(); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if d.IsServiceCommand() {
+		if err := d.RunService(os.Args[1:]); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := d.Run(); err != nil {
 		os.Exit(1)
 	}
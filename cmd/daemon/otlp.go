@@ -0,0 +1,6 @@
+package main
+
+import (
+	// registers the OTLP provider used by -tracing-endpoint
+	_ "github.com/skycoin/hardware-wallet-daemon/src/tracing/otlp"
+)
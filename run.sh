@@ -10,7 +10,8 @@ pushd "$DIR" >/dev/null
 
 COMMIT=$(git rev-parse HEAD)
 BRANCH=$(git rev-parse --abbrev-ref HEAD)
-GOLDFLAGS="-X main.Commit=${COMMIT} -X main.Branch=${BRANCH}"
+BUILD_TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+GOLDFLAGS="-X main.Commit=${COMMIT} -X main.Branch=${BRANCH} -X main.BuildTime=${BUILD_TIME}"
 
 GORUNFLAGS=${GORUNFLAGS:-}
 
@@ -0,0 +1,198 @@
+// Package addressbook is the address book of the daemon: labeled addresses and destination contacts kept in the
+// storage, so the hosts send coins to a contact by its name instead of a copied address.
+package addressbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// Bucket is the storage bucket holding the contacts, keyed by their lower case name
+const Bucket = "addressbook"
+
+// MaxLabelLength is the longest label of a contact
+const MaxLabelLength = 256
+
+// ValidationError is returned when a contact is not valid
+type ValidationError string
+
+func (e ValidationError) Error() string {
+	return string(e)
+}
+
+var (
+	// ErrNotFound is returned when a contact does not exist
+	ErrNotFound = errors.New("contact not found")
+	// ErrExists is returned when a contact is created with the name of another one
+	ErrExists = errors.New("contact already exists")
+	// ErrInvalidName is returned when the name of a contact is not valid
+	ErrInvalidName = ValidationError("contact name must be 1 to 64 letters, digits, spaces, dots, dashes or underscores, and not an address")
+
+	nameRegex = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} ._-]{0,63}$`)
+)
+
+// Contact is a labeled address of the address book
+type Contact struct {
+	// Name is the name the transactions are sent to, unique regardless of the case
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// Label describes the address, such as "savings" or "exchange deposit"
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// key returns the storage key of a contact name
+func key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (c *Contact) validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	// a name that is an address would send the coins to the address instead of the contact
+	if _, err := cipher.DecodeBase58Address(c.Name); err == nil || !nameRegex.MatchString(c.Name) {
+		return ErrInvalidName
+	}
+
+	if _, err := cipher.DecodeBase58Address(c.Address); err != nil {
+		return ValidationError(fmt.Sprintf("invalid address: %v", err))
+	}
+
+	if len(c.Label) > MaxLabelLength {
+		return ValidationError(fmt.Sprintf("label is longer than %d bytes", MaxLabelLength))
+	}
+
+	return nil
+}
+
+// Book is the address book, persisted in a store
+type Book struct {
+	store storage.Store
+
+	mu sync.Mutex
+}
+
+// New creates a Book of the contacts in store
+func New(store storage.Store) *Book {
+	return &Book{
+		store: store,
+	}
+}
+
+// List returns the contacts ordered by name
+func (b *Book) List() ([]Contact, error) {
+	contacts := []Contact{}
+	err := b.store.ForEach(Bucket, func(_ string, value []byte) error {
+		var c Contact
+		if err := json.Unmarshal(value, &c); err != nil {
+			return fmt.Errorf("invalid contact: %v", err)
+		}
+		contacts = append(contacts, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(contacts, func(i, j int) bool {
+		return key(contacts[i].Name) < key(contacts[j].Name)
+	})
+	return contacts, nil
+}
+
+// Get returns the contact of name, regardless of its case
+func (b *Book) Get(name string) (Contact, error) {
+	var c Contact
+	data, err := b.store.Get(Bucket, key(name))
+	switch err {
+	case nil:
+	case storage.ErrNotFound:
+		return c, ErrNotFound
+	default:
+		return c, err
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid contact: %v", err)
+	}
+	return c, nil
+}
+
+// Create adds the contact c at time now, ErrExists is returned if its name is taken
+func (b *Book) Create(c Contact, now time.Time) (Contact, error) {
+	if err := c.validate(); err != nil {
+		return c, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch _, err := b.Get(c.Name); err {
+	case nil:
+		return c, ErrExists
+	case ErrNotFound:
+	default:
+		return c, err
+	}
+
+	c.CreatedAt = now.UTC()
+	c.UpdatedAt = c.CreatedAt
+	return c, b.save(c)
+}
+
+// Update replaces the address and label of the contact named c.Name at time now, its name may change case
+func (b *Book) Update(c Contact, now time.Time) (Contact, error) {
+	if err := c.validate(); err != nil {
+		return c, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	old, err := b.Get(c.Name)
+	if err != nil {
+		return c, err
+	}
+
+	c.CreatedAt = old.CreatedAt
+	c.UpdatedAt = now.UTC()
+	return c, b.save(c)
+}
+
+// Delete removes the contact of name
+func (b *Book) Delete(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.Get(name); err != nil {
+		return err
+	}
+	return b.store.Delete(Bucket, key(name))
+}
+
+// Resolve returns the address of the contact of name, ErrNotFound if there is none
+func (b *Book) Resolve(name string) (string, error) {
+	c, err := b.Get(name)
+	if err != nil {
+		return "", err
+	}
+	return c.Address, nil
+}
+
+func (b *Book) save(c Contact) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return b.store.Put(Bucket, key(c.Name), data)
+}
@@ -0,0 +1,108 @@
+package addressbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const (
+	addressA = "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"
+	addressB = "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"
+)
+
+func TestBook(t *testing.T) {
+	store := storage.NewMemoryStore()
+	b := New(store)
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+
+	c, err := b.Create(Contact{Name: " Exchange ", Address: addressA, Label: "deposit"}, now)
+	require.NoError(t, err)
+	require.Equal(t, "Exchange", c.Name)
+	require.Equal(t, now, c.CreatedAt)
+
+	_, err = b.Create(Contact{Name: "exchange", Address: addressB}, now)
+	require.Equal(t, ErrExists, err)
+
+	_, err = b.Create(Contact{Name: "Savings", Address: addressB}, now)
+	require.NoError(t, err)
+
+	// the names are resolved regardless of their case
+	address, err := b.Resolve("EXCHANGE")
+	require.NoError(t, err)
+	require.Equal(t, addressA, address)
+
+	_, err = b.Resolve("unknown")
+	require.Equal(t, ErrNotFound, err)
+
+	c, err = b.Update(Contact{Name: "exchange", Address: addressB}, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, now, c.CreatedAt)
+	require.Equal(t, now.Add(time.Hour), c.UpdatedAt)
+
+	_, err = b.Update(Contact{Name: "unknown", Address: addressB}, now)
+	require.Equal(t, ErrNotFound, err)
+
+	// the contacts survive a restart
+	contacts, err := New(store).List()
+	require.NoError(t, err)
+	require.Len(t, contacts, 2)
+	require.Equal(t, "exchange", contacts[0].Name)
+	require.Equal(t, addressB, contacts[0].Address)
+	require.Empty(t, contacts[0].Label)
+	require.Equal(t, "Savings", contacts[1].Name)
+
+	require.NoError(t, b.Delete("Exchange"))
+	require.Equal(t, ErrNotFound, b.Delete("Exchange"))
+	contacts, err = b.List()
+	require.NoError(t, err)
+	require.Len(t, contacts, 1)
+}
+
+func TestContactValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		contact Contact
+		err     string
+	}{
+		{
+			name:    "apostrophe",
+			contact: Contact{Name: "Bob's", Address: addressA},
+			err:     ErrInvalidName.Error(),
+		},
+		{
+			name:    "valid",
+			contact: Contact{Name: "Zoë_2 cold.wallet-1", Address: addressA},
+		},
+		{
+			name:    "empty name",
+			contact: Contact{Name: " ", Address: addressA},
+			err:     ErrInvalidName.Error(),
+		},
+		{
+			name:    "address name",
+			contact: Contact{Name: addressB, Address: addressA},
+			err:     ErrInvalidName.Error(),
+		},
+		{
+			name:    "invalid address",
+			contact: Contact{Name: "bob", Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvH"},
+			err:     "invalid address",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.contact.validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
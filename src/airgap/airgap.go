@@ -0,0 +1,187 @@
+// Package airgap exchanges the partial transactions with a daemon signing them offline, on a computer without
+// network, as files or as QR codes scanned by the online computer.
+package airgap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Prefix starts the parts of a payload split across QR codes
+	Prefix = "SKYTX:"
+	// DefaultPartSize is the number of characters of the payload in a part, keeping the QR codes easy to scan
+	DefaultPartSize = 800
+	// MaxParts is the most parts a payload is split in
+	MaxParts = 100
+
+	// idSize is the number of hex characters of the ID of a payload, the start of its SHA256 hash
+	idSize = 8
+)
+
+// ErrAirgapped is returned by Transport for every request
+var ErrAirgapped = errors.New("outbound requests are disabled in airgap mode")
+
+// Transport refuses every request, it is the transport of the outbound clients when the daemon runs air-gapped
+type Transport struct{}
+
+// RoundTrip implements http.RoundTripper
+func (Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	return nil, fmt.Errorf("%s %s: %v", r.Method, r.URL.Host, ErrAirgapped)
+}
+
+// Split splits payload in parts of at most size characters of the payload, each one shown in a QR code.
+// A part is "SKYTX:<index>/<total>:<id>:<data>", the id identifying the payload so the parts of several payloads
+// are not mixed.
+func Split(payload string, size int) ([]string, error) {
+	if payload == "" {
+		return nil, errors.New("payload is empty")
+	}
+	if size <= 0 {
+		size = DefaultPartSize
+	}
+
+	total := (len(payload) + size - 1) / size
+	if total > MaxParts {
+		return nil, fmt.Errorf("payload needs %d parts, at most %d", total, MaxParts)
+	}
+
+	id := payloadID(payload)
+	parts := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		parts = append(parts, fmt.Sprintf("%s%d/%d:%s:%s", Prefix, i+1, total, id, payload[i*size:end]))
+	}
+
+	return parts, nil
+}
+
+// Join reassembles the payload of parts returned by Split, in any order, verifying it is complete
+func Join(parts []string) (string, error) {
+	if len(parts) == 0 {
+		return "", errors.New("no parts")
+	}
+
+	type part struct {
+		index int
+		data  string
+	}
+
+	var id string
+	var total int
+	seen := make(map[int]bool, len(parts))
+	ordered := make([]part, 0, len(parts))
+	for _, s := range parts {
+		index, n, partID, data, err := parsePart(s)
+		if err != nil {
+			return "", err
+		}
+
+		if id == "" {
+			id, total = partID, n
+		} else if partID != id || n != total {
+			return "", errors.New("the parts are of different payloads")
+		}
+
+		if seen[index] {
+			return "", fmt.Errorf("part %d is repeated", index)
+		}
+		seen[index] = true
+		ordered = append(ordered, part{index, data})
+	}
+
+	if len(ordered) != total {
+		var missing []string
+		for i := 1; i <= total; i++ {
+			if !seen[i] {
+				missing = append(missing, strconv.Itoa(i))
+			}
+		}
+		return "", fmt.Errorf("%d of %d parts, missing %s", len(ordered), total, strings.Join(missing, ", "))
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].index < ordered[j].index
+	})
+
+	var b strings.Builder
+	for _, p := range ordered {
+		b.WriteString(p.data)
+	}
+
+	payload := b.String()
+	if payloadID(payload) != id {
+		return "", errors.New("the payload of the parts does not match their id")
+	}
+
+	return payload, nil
+}
+
+// Parse returns the payload of an exchanged file, the payload itself or its parts, one per line
+func Parse(data []byte) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	switch {
+	case len(lines) == 0:
+		return "", errors.New("payload is empty")
+	case strings.HasPrefix(lines[0], Prefix):
+		return Join(lines)
+	case len(lines) == 1:
+		return lines[0], nil
+	default:
+		return "", errors.New("a payload is a single line, or parts starting with " + Prefix)
+	}
+}
+
+func parsePart(s string) (index, total int, id, data string, err error) {
+	fields := strings.SplitN(strings.TrimPrefix(s, Prefix), ":", 3)
+	if !strings.HasPrefix(s, Prefix) || len(fields) != 3 {
+		return 0, 0, "", "", fmt.Errorf("invalid part, it must be %s<index>/<total>:<id>:<data>", Prefix)
+	}
+
+	position := strings.SplitN(fields[0], "/", 2)
+	if len(position) != 2 {
+		return 0, 0, "", "", fmt.Errorf("invalid part position %q", fields[0])
+	}
+	if index, err = strconv.Atoi(position[0]); err != nil {
+		return 0, 0, "", "", fmt.Errorf("invalid part position %q", fields[0])
+	}
+	if total, err = strconv.Atoi(position[1]); err != nil {
+		return 0, 0, "", "", fmt.Errorf("invalid part position %q", fields[0])
+	}
+	if total < 1 || total > MaxParts || index < 1 || index > total {
+		return 0, 0, "", "", fmt.Errorf("invalid part position %q", fields[0])
+	}
+
+	if len(fields[1]) != idSize {
+		return 0, 0, "", "", fmt.Errorf("invalid part id %q", fields[1])
+	}
+	if fields[2] == "" {
+		return 0, 0, "", "", fmt.Errorf("part %d is empty", index)
+	}
+
+	return index, total, fields[1], fields[2], nil
+}
+
+// payloadID returns the id of payload, the start of its SHA256 hash
+func payloadID(payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])[:idSize]
+}
@@ -0,0 +1,91 @@
+package airgap
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitJoin(t *testing.T) {
+	payload := strings.Repeat("0123456789abcdef", 10)
+
+	parts, err := Split(payload, 50)
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+	require.True(t, strings.HasPrefix(parts[0], "SKYTX:1/4:"))
+	require.True(t, strings.HasSuffix(parts[3], ":"+payload[150:]))
+
+	joined, err := Join(parts)
+	require.NoError(t, err)
+	require.Equal(t, payload, joined)
+
+	// the parts are scanned in any order
+	joined, err = Join([]string{parts[2], parts[0], parts[3], parts[1]})
+	require.NoError(t, err)
+	require.Equal(t, payload, joined)
+
+	_, err = Join(parts[:3])
+	require.EqualError(t, err, "3 of 4 parts, missing 4")
+
+	_, err = Join([]string{parts[0], parts[0], parts[1], parts[2]})
+	require.EqualError(t, err, "part 1 is repeated")
+
+	other, err := Split(strings.Repeat("f", 160), 50)
+	require.NoError(t, err)
+	_, err = Join([]string{parts[0], parts[1], parts[2], other[3]})
+	require.EqualError(t, err, "the parts are of different payloads")
+
+	// a part altered in transit does not match the id
+	tampered := append([]string(nil), parts...)
+	tampered[1] = tampered[1][:len(tampered[1])-1] + "x"
+	_, err = Join(tampered)
+	require.EqualError(t, err, "the payload of the parts does not match their id")
+
+	_, err = Join([]string{"SKYTX:2/1:00000000:ab"})
+	require.EqualError(t, err, `invalid part position "2/1"`)
+
+	_, err = Join([]string{"0123"})
+	require.Error(t, err)
+
+	parts, err = Split("ab", 0)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+
+	_, err = Split(strings.Repeat("a", MaxParts*10+1), 10)
+	require.Error(t, err)
+
+	_, err = Split("", 10)
+	require.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	payload := strings.Repeat("0123456789abcdef", 10)
+
+	p, err := Parse([]byte(payload + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, payload, p)
+
+	parts, err := Split(payload, 64)
+	require.NoError(t, err)
+	p, err = Parse([]byte(strings.Join(parts, "\r\n") + "\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, payload, p)
+
+	_, err = Parse([]byte("\n \n"))
+	require.EqualError(t, err, "payload is empty")
+
+	_, err = Parse([]byte("ab\ncd"))
+	require.Error(t, err)
+}
+
+func TestTransport(t *testing.T) {
+	client := &http.Client{
+		Transport: Transport{},
+	}
+
+	_, err := client.Get("https://node.skycoin.com/api/v1/health")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrAirgapped.Error())
+}
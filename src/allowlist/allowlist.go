@@ -0,0 +1,163 @@
+// Package allowlist pins the daemon to the devices of -allowed-devices, refusing the operations on the other devices
+// plugged into the host, such as on shared signing machines. The device ID is read from the features, once per
+// connection to the device, before the first message starting an operation.
+package allowlist
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+var (
+	logger = logging.MustGetLogger("allowlist")
+
+	// ErrNotAllowed is returned for the messages sent to a device which is not allowed
+	ErrNotAllowed = errors.New("the device is not in -allowed-devices")
+	// ErrNoFeatures is returned when the device does not answer the features, its device ID is unknown
+	ErrNoFeatures = errors.New("the device did not answer its features, its device ID is unknown")
+)
+
+// passThrough are the messages sent to any device: they read the features or continue the operation started on the
+// allowed device, and cannot start an operation
+var passThrough = map[messages.MessageType]bool{
+	messages.MessageType_MessageType_Initialize:    true,
+	messages.MessageType_MessageType_GetFeatures:   true,
+	messages.MessageType_MessageType_Cancel:        true,
+	messages.MessageType_MessageType_ButtonAck:     true,
+	messages.MessageType_MessageType_PinMatrixAck:  true,
+	messages.MessageType_MessageType_PassphraseAck: true,
+	messages.MessageType_MessageType_WordAck:       true,
+	messages.MessageType_MessageType_EntropyAck:    true,
+}
+
+// Driver is a device driver refusing the operations on the devices which are not allowed. It wraps the driver
+// opening the device, below the handle kept open across the requests, so the device ID is read again once the device
+// is opened again.
+type Driver struct {
+	skyWallet.DeviceDriver
+	allowed map[string]bool
+
+	mu sync.Mutex
+	// checked is true once the device ID of the open device was read, deviceID is empty if it has none
+	checked  bool
+	deviceID string
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps driver, sending the messages starting an operation to the devices of allowed only
+func NewDriver(driver skyWallet.DeviceDriver, allowed []string) *Driver {
+	d := &Driver{
+		DeviceDriver: driver,
+		allowed:      make(map[string]bool, len(allowed)),
+	}
+	for _, id := range allowed {
+		d.allowed[strings.ToUpper(id)] = true
+	}
+	return d
+}
+
+// GetDevice implements skyWallet.DeviceDriver, the device ID of the device opened is read again
+func (d *Driver) GetDevice() (usb.Device, error) {
+	dev, err := d.DeviceDriver.GetDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.checked = false
+	d.deviceID = ""
+	d.mu.Unlock()
+	return dev, nil
+}
+
+// SendToDevice implements skyWallet.DeviceDriver
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	kind := header.Kind(chunks)
+	if !passThrough[kind] {
+		if err := d.check(dev); err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+	if err == nil && (kind == messages.MessageType_MessageType_Initialize || kind == messages.MessageType_MessageType_GetFeatures) {
+		d.record(msg)
+	}
+	return msg, err
+}
+
+// SendToDeviceNoAnswer implements skyWallet.DeviceDriver
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	if !passThrough[header.Kind(chunks)] {
+		if err := d.check(dev); err != nil {
+			return err
+		}
+	}
+	return d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+}
+
+// check returns ErrNotAllowed if the device is not allowed, reading its features unless they were read since it
+// was opened
+func (d *Driver) check(dev usb.Device) error {
+	d.mu.Lock()
+	checked, deviceID := d.checked, d.deviceID
+	d.mu.Unlock()
+
+	if !checked {
+		chunks, err := skyWallet.MessageGetFeatures()
+		if err != nil {
+			return err
+		}
+		msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+		if err != nil {
+			return err
+		}
+		if deviceID, checked = d.record(msg); !checked {
+			return ErrNoFeatures
+		}
+	}
+
+	if !d.allowed[strings.ToUpper(deviceID)] {
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+// record records the device ID of the features, a device without one is not allowed.
+// It returns false if msg is not the features.
+func (d *Driver) record(msg wire.Message) (string, bool) {
+	if msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+		return "", false
+	}
+	var features messages.Features
+	if err := proto.Unmarshal(msg.Data, &features); err != nil {
+		return "", false
+	}
+	deviceID := features.GetDeviceId()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.checked && !d.allowed[strings.ToUpper(deviceID)] {
+		if deviceID == "" {
+			logger.Warning("The device has no device ID, its operations are refused")
+		} else {
+			logger.Warningf("The device %s is not in -allowed-devices, its operations are refused", deviceID)
+		}
+	}
+	d.checked = true
+	d.deviceID = deviceID
+	return deviceID, true
+}
@@ -0,0 +1,132 @@
+package allowlist
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// fakeDriver answers the features with the device ID of the device plugged in, and Success to the other messages
+type fakeDriver struct {
+	t        *testing.T
+	deviceID *string
+	sent     []messages.MessageType
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	kind := messages.MessageType(binary.BigEndian.Uint16(chunks[0][3:5]))
+	d.sent = append(d.sent, kind)
+
+	if kind != messages.MessageType_MessageType_GetFeatures && kind != messages.MessageType_MessageType_Initialize {
+		return wire.Message{Kind: uint16(messages.MessageType_MessageType_Success)}, nil
+	}
+
+	data, err := proto.Marshal(&messages.Features{DeviceId: d.deviceID})
+	require.NoError(d.t, err)
+	return wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Features),
+		Data: data,
+	}, nil
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	d.sent = append(d.sent, messages.MessageType(binary.BigEndian.Uint16(chunks[0][3:5])))
+	return nil
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeUSB
+}
+
+func (d *fakeDriver) Close() {}
+
+func (d *fakeDriver) take() []messages.MessageType {
+	sent := d.sent
+	d.sent = nil
+	return sent
+}
+
+func TestDriver(t *testing.T) {
+	drv := &fakeDriver{
+		t:        t,
+		deviceID: proto.String("5347B2C2A3EF1A06C6A5D2F1"),
+	}
+	d := NewDriver(drv, []string{"5347b2c2a3ef1a06c6a5d2f1"})
+
+	wipe, err := skyWallet.MessageWipe()
+	require.NoError(t, err)
+	buttonAck, err := skyWallet.MessageButtonAck()
+	require.NoError(t, err)
+	getFeatures, err := skyWallet.MessageGetFeatures()
+	require.NoError(t, err)
+
+	// the device ID is read before the first message starting an operation, once
+	dev, err := d.GetDevice()
+	require.NoError(t, err)
+	_, err = d.SendToDevice(dev, wipe)
+	require.NoError(t, err)
+	_, err = d.SendToDevice(dev, wipe)
+	require.NoError(t, err)
+	require.Equal(t, []messages.MessageType{
+		messages.MessageType_MessageType_GetFeatures,
+		messages.MessageType_MessageType_WipeDevice,
+		messages.MessageType_MessageType_WipeDevice,
+	}, drv.take())
+
+	// another device is plugged in
+	drv.deviceID = proto.String("A1B2C3D4E5F6A1B2C3D4E5F6")
+	dev, err = d.GetDevice()
+	require.NoError(t, err)
+	_, err = d.SendToDevice(dev, wipe)
+	require.Equal(t, ErrNotAllowed, err)
+	require.Equal(t, ErrNotAllowed, d.SendToDeviceNoAnswer(dev, wipe))
+	require.Equal(t, []messages.MessageType{
+		messages.MessageType_MessageType_GetFeatures,
+	}, drv.take())
+
+	// its features are read and the operations continued
+	msg, err := d.SendToDevice(dev, getFeatures)
+	require.NoError(t, err)
+	require.Equal(t, uint16(messages.MessageType_MessageType_Features), msg.Kind)
+	_, err = d.SendToDevice(dev, buttonAck)
+	require.NoError(t, err)
+	require.Equal(t, []messages.MessageType{
+		messages.MessageType_MessageType_GetFeatures,
+		messages.MessageType_MessageType_ButtonAck,
+	}, drv.take())
+
+	// the features read by the client are checked without reading them again
+	drv.deviceID = proto.String("5347B2C2A3EF1A06C6A5D2F1")
+	dev, err = d.GetDevice()
+	require.NoError(t, err)
+	_, err = d.SendToDevice(dev, getFeatures)
+	require.NoError(t, err)
+	_, err = d.SendToDevice(dev, wipe)
+	require.NoError(t, err)
+	require.Equal(t, []messages.MessageType{
+		messages.MessageType_MessageType_GetFeatures,
+		messages.MessageType_MessageType_WipeDevice,
+	}, drv.take())
+
+	// a device without device ID is not allowed
+	drv.deviceID = nil
+	dev, err = d.GetDevice()
+	require.NoError(t, err)
+	_, err = d.SendToDevice(dev, wipe)
+	require.Equal(t, ErrNotAllowed, err)
+}
@@ -0,0 +1,73 @@
+// Package amount converts the amounts of the transactions between their decimal strings and uint64 droplets and coin
+// hours. The amounts never pass through a float64: the coins are decimal strings of at most droplet.Exponent
+// decimals, and the scientific notation and the values losing precision are refused.
+package amount
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/util/droplet"
+)
+
+var (
+	// ErrScientificNotation is returned for the coins written in scientific notation
+	ErrScientificNotation = errors.New("coins must be a decimal number, the scientific notation is not allowed")
+	// ErrInvalidCoins is returned for the coins that are not a decimal number
+	ErrInvalidCoins = errors.New("coins must be a decimal number such as 1.5")
+)
+
+// ParseCoins converts decimal coins to droplets, "1.5" is 1500000 droplets. The coins are digits with an optional
+// decimal point followed by at most droplet.Exponent digits.
+func ParseCoins(coins string) (uint64, error) {
+	if strings.HasPrefix(coins, "-") {
+		return 0, droplet.ErrNegativeValue
+	}
+
+	if strings.ContainsAny(coins, "eE") {
+		return 0, ErrScientificNotation
+	}
+
+	integer, decimals := coins, ""
+	if i := strings.IndexByte(coins, '.'); i >= 0 {
+		integer, decimals = coins[:i], coins[i+1:]
+		if decimals == "" {
+			return 0, ErrInvalidCoins
+		}
+	}
+	if integer == "" || !isDigits(integer) || !isDigits(decimals) {
+		return 0, ErrInvalidCoins
+	}
+
+	if len(decimals) > droplet.Exponent {
+		return 0, droplet.ErrTooManyDecimals
+	}
+
+	return droplet.FromString(coins)
+}
+
+// FormatCoins converts droplets to decimal coins with droplet.Exponent decimals, 1500000 droplets are "1.500000"
+func FormatCoins(droplets uint64) (string, error) {
+	return droplet.ToString(droplets)
+}
+
+// ParseHours converts decimal coin hours to an uint64, the signs and the scientific notation are refused
+func ParseHours(hours string) (uint64, error) {
+	return strconv.ParseUint(hours, 10, 64)
+}
+
+// FormatHours converts coin hours to a decimal string
+func FormatHours(hours uint64) string {
+	return strconv.FormatUint(hours, 10)
+}
+
+// isDigits returns true if s only has ASCII digits
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
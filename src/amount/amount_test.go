@@ -0,0 +1,78 @@
+package amount
+
+import (
+	"math"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCoins(t *testing.T) {
+	cases := []struct {
+		coins    string
+		droplets uint64
+		err      error
+	}{
+		{coins: "1", droplets: 1e6},
+		{coins: "1.5", droplets: 1500000},
+		{coins: "0.000001", droplets: 1},
+		{coins: "001.100000", droplets: 1100000},
+		{coins: "9223372036854.775807", droplets: math.MaxInt64},
+		{coins: "9223372036854.775808", err: droplet.ErrTooLarge},
+		{coins: "0.0000001", err: droplet.ErrTooManyDecimals},
+		{coins: "1.0000000", err: droplet.ErrTooManyDecimals},
+		{coins: "-1", err: droplet.ErrNegativeValue},
+		{coins: "1e6", err: ErrScientificNotation},
+		{coins: "1.5E-3", err: ErrScientificNotation},
+		{coins: "", err: ErrInvalidCoins},
+		{coins: ".5", err: ErrInvalidCoins},
+		{coins: "1.", err: ErrInvalidCoins},
+		{coins: "+1", err: ErrInvalidCoins},
+		{coins: " 1", err: ErrInvalidCoins},
+		{coins: "1,5", err: ErrInvalidCoins},
+		{coins: "1.2.3", err: ErrInvalidCoins},
+		{coins: "0x10", err: ErrInvalidCoins},
+		{coins: "NaN", err: ErrInvalidCoins},
+		{coins: "Inf", err: ErrInvalidCoins},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.coins, func(t *testing.T) {
+			droplets, err := ParseCoins(tc.coins)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.droplets, droplets)
+		})
+	}
+}
+
+func TestFormatCoins(t *testing.T) {
+	coins, err := FormatCoins(1500000)
+	require.NoError(t, err)
+	require.Equal(t, "1.500000", coins)
+
+	// the formatted coins are parsed back to the same droplets
+	for _, droplets := range []uint64{0, 1, 999999, 1e6, 123456789012, math.MaxInt64} {
+		coins, err := FormatCoins(droplets)
+		require.NoError(t, err)
+
+		parsed, err := ParseCoins(coins)
+		require.NoError(t, err)
+		require.Equal(t, droplets, parsed)
+	}
+
+	_, err = FormatCoins(math.MaxInt64 + 1)
+	require.Equal(t, droplet.ErrTooLarge, err)
+}
+
+func TestParseHours(t *testing.T) {
+	hours, err := ParseHours("18446744073709551615")
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64), hours)
+	require.Equal(t, "18446744073709551615", FormatHours(hours))
+
+	for _, s := range []string{"", "-1", "+1", "1e3", "0.2", "18446744073709551616"} {
+		_, err := ParseHours(s)
+		require.Error(t, err, s)
+	}
+}
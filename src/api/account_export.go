@@ -0,0 +1,185 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/descriptor"
+)
+
+const (
+	// accountCoin is the coin of the exported accounts
+	accountCoin = "skycoin"
+	// accountDerivation names the derivation of the addresses of the device: the deterministic key chain of the
+	// skycoin wallets, from the seed of the device
+	accountDerivation = "skycoin-deterministic"
+)
+
+// AccountExportResponse is returned by /api/v1/account_export, describing the addresses of the device for
+// watch-only software
+type AccountExportResponse struct {
+	Coin     string `json:"coin"`
+	DeviceID string `json:"device_id"`
+	Label    string `json:"label,omitempty"`
+	// Derivation is how the device derives the addresses. It exports no extended public key, the addresses
+	// are exported instead.
+	Derivation string            `json:"derivation"`
+	Addresses  []ExportedAddress `json:"addresses"`
+}
+
+// ExportedAddress is an address of the device and its output descriptor
+type ExportedAddress struct {
+	// Index is the index of the address in the key chain of the device
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	// Descriptor is the addr() output descriptor of BIP 380, with its checksum
+	Descriptor string `json:"descriptor"`
+}
+
+// accountExport exports the addresses of the device as output descriptors with their derivation metadata,
+// for watch-only software. The addresses are not shown on the device.
+// URI: /api/v1/account_export
+// Method: GET
+// Args:
+//
+//	address_n: number of addresses to export [required]
+//	start_index: index of the first address [optional, defaults to 0]
+func accountExport(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addressN, err := strconv.Atoi(r.URL.Query().Get("address_n"))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid address_n")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		startIndex := 0
+		if s := r.URL.Query().Get("start_index"); s != "" {
+			startIndex, err = strconv.Atoi(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid start_index")
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		if addressN <= 0 {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "address_n must be greater than 0")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if startIndex < 0 {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "start_index cannot be negative")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// for integration tests
+		if autoPressEmulatorButtons {
+			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
+			if err != nil {
+				requestLogger(r).Error("account export failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		var featuresMsg, addressesMsg wire.Message
+		retCH := make(chan int)
+		errCH := make(chan int)
+		ctx := r.Context()
+
+		go func() {
+			featuresMsg, err = gateway.GetFeatures()
+			if err == nil && featuresMsg.Kind == uint16(messages.MessageType_MessageType_Features) {
+				addressesMsg, err = gateway.AddressGen(uint32(addressN), uint32(startIndex), false)
+			}
+			if err != nil {
+				errCH <- 1
+				return
+			}
+			retCH <- 1
+		}()
+
+		select {
+		case <-retCH:
+			if featuresMsg.Kind != uint16(messages.MessageType_MessageType_Features) {
+				HandleFirmwareResponseMessages(w, featuresMsg)
+				return
+			}
+
+			// the device asks for the PIN or refuses, the export is requested again once the PIN is entered
+			if addressesMsg.Kind != uint16(messages.MessageType_MessageType_ResponseSkycoinAddress) {
+				HandleFirmwareResponseMessages(w, addressesMsg)
+				return
+			}
+
+			features := &messages.Features{}
+			if err := proto.Unmarshal(featuresMsg.Data, features); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			addresses, err := skyWallet.DecodeResponseSkycoinAddress(addressesMsg)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			export := AccountExportResponse{
+				Coin:       accountCoin,
+				DeviceID:   features.GetDeviceId(),
+				Label:      features.GetLabel(),
+				Derivation: accountDerivation,
+				Addresses:  make([]ExportedAddress, 0, len(addresses)),
+			}
+
+			for i, address := range addresses {
+				desc, err := descriptor.Address(address)
+				if err != nil {
+					resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+					writeHTTPResponse(w, resp)
+					return
+				}
+
+				export.Addresses = append(export.Addresses, ExportedAddress{
+					Index:      startIndex + i,
+					Address:    address,
+					Descriptor: desc,
+				})
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: export,
+			})
+		case <-errCH:
+			requestLogger(r).Errorf("account export failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+		case <-ctx.Done():
+			disConnErr := gateway.Disconnect()
+			if disConnErr != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+			} else {
+				resp := NewHTTPErrorResponse(499, "Client Closed Request")
+				writeHTTPResponse(w, resp)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountExport(t *testing.T) {
+	featuresMsg := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: newStrPtr("8A7F7D2E9C0D9B14D7C2E3B0"),
+		Label:    newStrPtr("savings"),
+	})
+
+	failureMsg := newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    messages.FailureType_Failure_NotInitialized.Enum(),
+		Message: newStrPtr("failure msg"),
+	})
+
+	addressesMsg := newReply(t, messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+		Addresses: []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw", "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs"},
+	})
+
+	cases := []struct {
+		name                    string
+		method                  string
+		query                   string
+		status                  int
+		addressN                uint32
+		startIndex              uint32
+		gatewayFeaturesResult   wire.Message
+		gatewayAddressGenResult wire.Message
+		httpResponse            HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			query:        "?address_n=2",
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+
+		{
+			name:         "400 - address_n missing",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid address_n"),
+		},
+
+		{
+			name:         "400 - start_index invalid",
+			method:       http.MethodGet,
+			query:        "?address_n=2&start_index=a",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid start_index"),
+		},
+
+		{
+			name:         "422 - address_n 0",
+			method:       http.MethodGet,
+			query:        "?address_n=0",
+			status:       http.StatusUnprocessableEntity,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "address_n must be greater than 0"),
+		},
+
+		{
+			name:         "422 - start_index negative",
+			method:       http.MethodGet,
+			query:        "?address_n=2&start_index=-1",
+			status:       http.StatusUnprocessableEntity,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "start_index cannot be negative"),
+		},
+
+		{
+			name:                  "409 - Failure msg of the features",
+			method:                http.MethodGet,
+			query:                 "?address_n=2",
+			status:                http.StatusConflict,
+			addressN:              2,
+			gatewayFeaturesResult: failureMsg,
+			httpResponse:          newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
+
+		{
+			name:                    "409 - Failure msg of the addresses",
+			method:                  http.MethodGet,
+			query:                   "?address_n=2",
+			status:                  http.StatusConflict,
+			addressN:                2,
+			gatewayFeaturesResult:   featuresMsg,
+			gatewayAddressGenResult: failureMsg,
+			httpResponse:            newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
+
+		{
+			name:                    "200 - OK",
+			method:                  http.MethodGet,
+			query:                   "?address_n=2&start_index=3",
+			status:                  http.StatusOK,
+			addressN:                2,
+			startIndex:              3,
+			gatewayFeaturesResult:   featuresMsg,
+			gatewayAddressGenResult: addressesMsg,
+			httpResponse: HTTPResponse{
+				Data: AccountExportResponse{
+					Coin:       "skycoin",
+					DeviceID:   "8A7F7D2E9C0D9B14D7C2E3B0",
+					Label:      "savings",
+					Derivation: "skycoin-deterministic",
+					Addresses: []ExportedAddress{
+						{
+							Index:      3,
+							Address:    "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw",
+							Descriptor: "addr(2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw)#73pdwfvc",
+						},
+						{
+							Index:      4,
+							Address:    "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs",
+							Descriptor: "addr(zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs)#xczp7hzr",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetFeatures").Return(tc.gatewayFeaturesResult, nil)
+			gateway.On("AddressGen", tc.addressN, tc.startIndex, false).Return(tc.gatewayAddressGenResult, nil)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/account_export"+tc.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+				var resp AccountExportResponse
+				err = json.Unmarshal(rsp.Data, &resp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(AccountExportResponse), resp)
+			}
+
+			if tc.gatewayFeaturesResult.Kind == uint16(messages.MessageType_MessageType_Failure) {
+				gateway.AssertNotCalled(t, "AddressGen", tc.addressN, tc.startIndex, false)
+			}
+		})
+	}
+}
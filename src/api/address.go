@@ -2,11 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
 )
 
 // GenerateAddressesRequest is request data for /api/v1/generate_addresses
@@ -20,7 +24,7 @@ type GenerateAddressesRequest struct {
 // URI: /api/v1/generate_addresses
 // Method: POST
 // Args: JSON Body
-func generateAddresses(gateway Gatewayer) http.HandlerFunc {
+func generateAddresses(gateway Gatewayer, backend coin.Backend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -42,34 +46,21 @@ func generateAddresses(gateway Gatewayer) http.HandlerFunc {
 		}
 		defer r.Body.Close()
 
-		if req.AddressN == 0 {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "address_n cannot be 0")
-			writeHTTPResponse(w, resp)
-			return
-		}
-
-		if req.AddressN < 0 {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "address_n cannot be negative")
-			writeHTTPResponse(w, resp)
-			return
-		}
-
-		if req.StartIndex < 0 {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "start_index cannot be negative")
-			writeHTTPResponse(w, resp)
+		if err := req.validate(); err != nil {
+			writeValidationError(w, err)
 			return
 		}
 
 		// simple warning for logs
 		if req.AddressN+req.StartIndex > 8 {
-			logger.Warnf("wallet generating high index addresses: start_index: %d; address_n: %d", req.StartIndex, req.AddressN)
+			requestLogger(r).Warnf("wallet generating high index addresses: start_index: %d; address_n: %d", req.StartIndex, req.AddressN)
 		}
 
 		// for integration tests
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("generateAddresses failed: %s", err.Error())
+				requestLogger(r).Error("generateAddresses failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -93,9 +84,16 @@ func generateAddresses(gateway Gatewayer) http.HandlerFunc {
 
 		select {
 		case <-retCH:
+			// the addresses confirmed on the device are only returned once they are valid addresses of the coin
+			if err := validateGeneratedAddresses(backend, msg); err != nil {
+				requestLogger(r).WithError(err).Error("generateAddresses failed")
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Error("generateAddresses failed: %s", err.Error())
+			requestLogger(r).Error("generateAddresses failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -110,3 +108,23 @@ func generateAddresses(gateway Gatewayer) http.HandlerFunc {
 		}
 	}
 }
+
+// validateGeneratedAddresses validates the addresses of an address generation response with backend
+func validateGeneratedAddresses(backend coin.Backend, msg wire.Message) error {
+	if msg.Kind != uint16(messages.MessageType_MessageType_ResponseSkycoinAddress) {
+		return nil
+	}
+
+	addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		if err := coin.ValidateAddress(backend, address); err != nil {
+			return fmt.Errorf("the device returned the invalid address %q: %v", address, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/addressbook"
+)
+
+// ContactRequest is request data for POST and PUT /api/v2/address_book
+type ContactRequest struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+// URI: /api/v2/address_book
+// Method: GET, POST, PUT, DELETE
+// Args:
+//
+//	name: name of the contact returned or deleted [optional for GET, required for DELETE]
+//	JSON Body: the contact created or updated [POST, PUT]
+func addressBookHandler(book *addressbook.Book) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				contacts, err := book.List()
+				if err != nil {
+					writeAddressBookError(w, err)
+					return
+				}
+
+				writeHTTPResponse(w, HTTPResponse{
+					Data: contacts,
+				})
+				return
+			}
+
+			c, err := book.Get(name)
+			if err != nil {
+				writeAddressBookError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: c,
+			})
+		case http.MethodPost, http.MethodPut:
+			if r.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var req ContactRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer r.Body.Close()
+
+			contact := addressbook.Contact{
+				Name:    req.Name,
+				Address: req.Address,
+				Label:   req.Label,
+			}
+
+			var c addressbook.Contact
+			var err error
+			if r.Method == http.MethodPost {
+				c, err = book.Create(contact, time.Now())
+			} else {
+				c, err = book.Update(contact, time.Now())
+			}
+			if err != nil {
+				writeAddressBookError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: c,
+			})
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "name is required")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if err := book.Delete(name); err != nil {
+				writeAddressBookError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: []string{"Contact deleted"},
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+func writeAddressBookError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case addressbook.ErrNotFound:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+	case addressbook.ErrExists:
+		resp = NewHTTPErrorResponse(http.StatusConflict, err.Error())
+	default:
+		if _, ok := err.(addressbook.ValidationError); ok {
+			resp = NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+		} else {
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		}
+	}
+	writeHTTPResponse(w, resp)
+}
+
+// resolveContacts replaces the contacts of outputs with their address in book, writing the error response if a
+// contact cannot be resolved
+func resolveContacts(w http.ResponseWriter, r *http.Request, book *addressbook.Book, outputs []TransactionOutput) bool {
+	for i := range outputs {
+		o := &outputs[i]
+		if o.Contact == "" {
+			continue
+		}
+
+		if book == nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "the address book is disabled, contacts cannot be resolved")
+			writeHTTPResponse(w, resp)
+			return false
+		}
+
+		if o.Address != "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address and contact cannot both be set")
+			writeHTTPResponse(w, resp)
+			return false
+		}
+
+		address, err := book.Resolve(o.Contact)
+		switch err {
+		case nil:
+		case addressbook.ErrNotFound:
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, fmt.Sprintf("contact %q not found", o.Contact))
+			writeHTTPResponse(w, resp)
+			return false
+		default:
+			requestLogger(r).Errorf("resolving contact %q failed: %s", o.Contact, err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return false
+		}
+
+		o.Address = address
+		o.Contact = ""
+	}
+
+	return true
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/addressbook"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestAddressBook(t *testing.T) {
+	gateway := &MockGatewayer{}
+	cfg := defaultMuxConfig()
+	cfg.addressBook = addressbook.New(storage.NewMemoryStore())
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		var r *http.Request
+		var err error
+		if body != nil {
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			r, err = http.NewRequest(method, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			r.Header.Set("Content-Type", ContentTypeJSON)
+		} else {
+			r, err = http.NewRequest(method, url, nil)
+			require.NoError(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/api/v2/address_book", ContactRequest{Name: "Exchange", Address: "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", Label: "deposit"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = do(http.MethodPost, "/api/v2/address_book", ContactRequest{Name: "exchange", Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"})
+	require.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = do(http.MethodPost, "/api/v2/address_book", ContactRequest{Name: "bob", Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvH"})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do(http.MethodPut, "/api/v2/address_book", ContactRequest{Name: "bob", Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"})
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = do(http.MethodGet, "/api/v2/address_book", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var rsp struct {
+		Data []addressbook.Contact `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Len(t, rsp.Data, 1)
+	require.Equal(t, "deposit", rsp.Data[0].Label)
+
+	// the outputs sent to a contact are signed with its address
+	signResponse := messages.ResponseTransactionSign{
+		Signatures: []string{"signature"},
+		Padding:    newBoolPtr(false),
+	}
+	signResponseBytes, err := signResponse.Marshal()
+	require.NoError(t, err)
+	gateway.On("TransactionSign", mock.Anything, mock.MatchedBy(func(outputs []*messages.SkycoinTransactionOutput) bool {
+		return len(outputs) == 1 && outputs[0].GetAddress() == "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"
+	})).Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ResponseTransactionSign),
+		Data: signResponseBytes,
+	}, nil)
+
+	sign := func(output TransactionOutput) *httptest.ResponseRecorder {
+		return do(http.MethodPost, "/api/v2/transaction_sign", TransactionSignRequest{
+			TransactionInputs: []TransactionInput{
+				{newUint32Ptr(0), "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+			},
+			TransactionOutputs: []TransactionOutput{output},
+		})
+	}
+
+	rr = sign(TransactionOutput{Contact: "EXCHANGE", Coins: "2", Hours: "2"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = sign(TransactionOutput{Contact: "unknown", Coins: "2", Hours: "2"})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = sign(TransactionOutput{Contact: "Exchange", Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "2", Hours: "2"})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do(http.MethodDelete, "/api/v2/address_book?name=exchange", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = do(http.MethodGet, "/api/v2/address_book?name=exchange", nil)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	gateway.AssertNumberOfCalls(t, "TransactionSign", 1)
+}
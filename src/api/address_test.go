@@ -28,6 +28,13 @@ func TestGenerateAddresses(t *testing.T) {
 	responseMsgBytes, err := responseAddressMsg.Marshal()
 	require.NoError(t, err)
 
+	invalidAddressMsg := messages.ResponseSkycoinAddress{
+		Addresses: []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw", "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPt"},
+	}
+
+	invalidAddressMsgBytes, err := invalidAddressMsg.Marshal()
+	require.NoError(t, err)
+
 	cases := []struct {
 		name                    string
 		method                  string
@@ -113,7 +120,35 @@ func TestGenerateAddresses(t *testing.T) {
 				Kind: uint16(messages.MessageType_MessageType_Failure),
 				Data: failureMsgBytes,
 			},
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
+
+		{
+			name:        "422 - AddressN too large",
+			method:      http.MethodPost,
+			contentType: ContentTypeJSON,
+			status:      http.StatusUnprocessableEntity,
+			httpBody: toJSON(t, &GenerateAddressesRequest{
+				AddressN: 100,
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "address_n cannot be greater than 99"),
+		},
+
+		{
+			name:        "500 - Invalid address returned by the device",
+			method:      http.MethodPost,
+			contentType: ContentTypeJSON,
+			status:      http.StatusInternalServerError,
+			httpBody: toJSON(t, &GenerateAddressesRequest{
+				AddressN:       2,
+				StartIndex:     0,
+				ConfirmAddress: true,
+			}),
+			gatewayAddressGenResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_ResponseSkycoinAddress),
+				Data: invalidAddressMsgBytes,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, `the device returned the invalid address "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPt": Invalid checksum`),
 		},
 
 		{
@@ -146,7 +181,7 @@ func TestGenerateAddresses(t *testing.T) {
 				gateway.On("AddressGen", uint32(body.AddressN), uint32(body.StartIndex), body.ConfirmAddress).Return(tc.gatewayAddressGenResult, nil)
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/loglevel"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+)
+
+// ModeRequest is request data for PUT /api/v1/admin/mode
+type ModeRequest struct {
+	// Mode is USB or EMULATOR
+	Mode string `json:"mode"`
+}
+
+// ModeResponse is returned by /api/v1/admin/mode
+type ModeResponse struct {
+	Mode string `json:"mode"`
+	// Changed is true if the mode was switched by the request
+	Changed bool `json:"changed"`
+}
+
+// LogLevelRequest is request data for PUT /api/v1/admin/loglevel
+type LogLevelRequest struct {
+	// Level is debug, info, warn, error, fatal or panic, defaults to debug with USB
+	Level string `json:"level"`
+	// USB logs the messages exchanged with the device, at the debug level
+	USB bool `json:"usb"`
+	// Duration is how long the level is kept before the default level is restored, e.g. 10m. Empty keeps it until
+	// it is changed again
+	Duration string `json:"duration"`
+}
+
+// usbModeOnly serves the requests to the endpoints of the USB devices only in USB mode, if the mode can be switched
+func usbModeOnly(modeSwitch *modeswitch.Switch, handler http.Handler) http.Handler {
+	if modeSwitch == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if modeSwitch.Mode() != skyWallet.DeviceTypeUSB {
+			resp := NewHTTPErrorResponse(http.StatusNotFound, "endpoint only available in USB mode")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// adminAuth serves the requests carrying the admin token in the Authorization header, as a bearer token
+func adminAuth(token string, handler http.Handler) http.Handler {
+	return bearerAuth("admin", token, handler)
+}
+
+// bearerAuth serves the requests carrying the token of the realm in the Authorization header, as a bearer token
+func bearerAuth(realm, token string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			criticalRequestLogger(r).Errorf("Invalid %s token for %s %s", realm, r.Method, r.URL.Path)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+			resp := NewHTTPErrorResponse(http.StatusUnauthorized, fmt.Sprintf("invalid %s token", realm))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// adminModeHandler returns or switches the daemon mode, between a USB device and the emulator, without restarting.
+// The operations in progress when the mode is switched fail as if the device was unplugged.
+// Switching the mode is recorded in the audit log.
+// URI: /api/v1/admin/mode
+// Method: GET, PUT
+// Args: JSON Body for PUT
+func adminModeHandler(modeSwitch *modeswitch.Switch, gateway Gatewayer, recorder *history.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeHTTPResponse(w, HTTPResponse{
+				Data: ModeResponse{
+					Mode: modeSwitch.Mode().String(),
+				},
+			})
+		case http.MethodPut:
+			start := time.Now()
+
+			if r.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var req ModeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer r.Body.Close()
+
+			mode := skyWallet.DeviceTypeFromString(strings.ToUpper(req.Mode))
+			if mode == skyWallet.DeviceTypeInvalid {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "mode must be USB or EMULATOR")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			changed, err := modeSwitch.Set(mode)
+			if err != nil {
+				requestLogger(r).Errorf("mode switch failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if changed {
+				// drop the connection to the device of the previous mode
+				if err := gateway.Disconnect(); err != nil {
+					requestLogger(r).WithError(err).Error("gateway.Disconnect failed")
+				}
+
+				if recorder != nil {
+					if err := recorder.Record(history.Record{
+						Time:      start.UTC(),
+						Endpoint:  "/admin/mode?mode=" + mode.String(),
+						Method:    r.Method,
+						Status:    http.StatusOK,
+						Duration:  int64(time.Since(start) / time.Millisecond),
+						RequestID: requestIDFromRequest(r),
+						Trace:     recorder.Trace(r.Header),
+					}, true); err != nil {
+						requestLogger(r).WithError(err).Error("failed to record mode switch")
+					}
+				}
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: ModeResponse{
+					Mode:    mode.String(),
+					Changed: changed,
+				},
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+// adminLogLevelHandler returns or changes the log level of the daemon, and the logging of the messages exchanged with
+// the device, without restarting
+// URI: /api/v1/admin/loglevel
+// Method: GET, PUT
+// Args: JSON Body for PUT
+func adminLogLevelHandler(controller *loglevel.Controller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeHTTPResponse(w, HTTPResponse{
+				Data: controller.Status(),
+			})
+		case http.MethodPut:
+			if r.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var req LogLevelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer r.Body.Close()
+
+			if req.Level == "" {
+				if !req.USB {
+					resp := NewHTTPErrorResponse(http.StatusBadRequest, "level is required")
+					writeHTTPResponse(w, resp)
+					return
+				}
+				req.Level = "debug"
+			}
+
+			level, err := logging.LevelFromString(req.Level)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "level must be debug, info, warn, error, fatal or panic")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var duration time.Duration
+			if req.Duration != "" {
+				duration, err = time.ParseDuration(req.Duration)
+				if err != nil {
+					resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid duration %q", req.Duration))
+					writeHTTPResponse(w, resp)
+					return
+				}
+			}
+
+			status, err := controller.Set(level, req.USB, duration)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if duration > 0 {
+				criticalRequestLogger(r).Infof("Log level set to %s, usb %v, for %s", status.Level, status.USB, duration)
+			} else {
+				criticalRequestLogger(r).Infof("Log level set to %s, usb %v", status.Level, status.USB)
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: status,
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/loglevel"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const testAdminToken = "admin-secret"
+
+// fakeModeDriver is the driver of a mode which finds no device
+type fakeModeDriver struct {
+	mode skyWallet.DeviceType
+}
+
+func (d *fakeModeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return wire.Message{}, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeModeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeModeDriver) GetDevice() (usb.Device, error) {
+	return nil, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeModeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeModeDriver) DeviceType() skyWallet.DeviceType {
+	return d.mode
+}
+
+func (d *fakeModeDriver) Close() {}
+
+func newTestModeSwitch() *modeswitch.Switch {
+	return modeswitch.New(&fakeModeDriver{mode: skyWallet.DeviceTypeUSB}, func(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error) {
+		if mode == skyWallet.DeviceTypeEmulator {
+			return &fakeModeDriver{mode: mode}, nil
+		}
+		return nil, errors.New("no usb bus")
+	})
+}
+
+func TestAdminMode(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		token        string
+		contentType  string
+		body         string
+		status       int
+		disconnect   bool
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "401 - no token",
+			method:       http.MethodGet,
+			status:       http.StatusUnauthorized,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnauthorized, "invalid admin token"),
+		},
+		{
+			name:         "401 - invalid token",
+			method:       http.MethodPut,
+			token:        "admin-secre",
+			status:       http.StatusUnauthorized,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnauthorized, "invalid admin token"),
+		},
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			token:        testAdminToken,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "415",
+			method:       http.MethodPut,
+			token:        testAdminToken,
+			status:       http.StatusUnsupportedMediaType,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+		{
+			name:         "400 - invalid mode",
+			method:       http.MethodPut,
+			token:        testAdminToken,
+			contentType:  ContentTypeJSON,
+			body:         `{"mode": "BLUETOOTH"}`,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "mode must be USB or EMULATOR"),
+		},
+		{
+			name:   "200 - GET",
+			method: http.MethodGet,
+			token:  testAdminToken,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: ModeResponse{
+					Mode: "USB",
+				},
+			},
+		},
+		{
+			name:        "200 - unchanged",
+			method:      http.MethodPut,
+			token:       testAdminToken,
+			contentType: ContentTypeJSON,
+			body:        `{"mode": "USB"}`,
+			status:      http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: ModeResponse{
+					Mode: "USB",
+				},
+			},
+		},
+		{
+			name:        "200 - switched",
+			method:      http.MethodPut,
+			token:       testAdminToken,
+			contentType: ContentTypeJSON,
+			body:        `{"mode": "emulator"}`,
+			status:      http.StatusOK,
+			disconnect:  true,
+			httpResponse: HTTPResponse{
+				Data: ModeResponse{
+					Mode:    "EMULATOR",
+					Changed: true,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.disconnect {
+				gateway.On("Disconnect").Return(nil)
+			}
+
+			cfg := defaultMuxConfig()
+			cfg.modeSwitch = newTestModeSwitch()
+			cfg.adminToken = testAdminToken
+
+			req, err := http.NewRequest(tc.method, "/api/v2/admin/mode", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+			if tc.status == http.StatusUnauthorized {
+				require.Equal(t, `Bearer realm="admin"`, rr.Header().Get("WWW-Authenticate"))
+			}
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data != nil {
+				var resp ModeResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &resp))
+				require.Equal(t, tc.httpResponse.Data, resp)
+			}
+
+			gateway.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminModeSwitch(t *testing.T) {
+	recorder := history.NewRecorder(storage.NewMemoryStore(), nil)
+	modeSwitch := newTestModeSwitch()
+
+	gateway := &MockGatewayer{}
+	gateway.On("Disconnect").Return(nil)
+
+	cfg := defaultMuxConfig()
+	cfg.modeSwitch = modeSwitch
+	cfg.adminToken = testAdminToken
+	cfg.history = recorder
+	handler := newServerMux(cfg, gateway)
+
+	setMode := func(mode string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPut, "/api/v2/admin/mode", bytes.NewBufferString(`{"mode": "`+mode+`"}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := setMode("EMULATOR")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, skyWallet.DeviceTypeEmulator, modeSwitch.Mode())
+
+	// the USB endpoints are not served in emulator mode
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/available", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Contains(t, rr.Body.String(), "endpoint only available in USB mode")
+
+	// the diagnostics report the current mode
+	req, err = http.NewRequest(http.MethodGet, "/api/v2/diagnostics", nil)
+	require.NoError(t, err)
+	gateway.On("Ping", mock.Anything).Return(wire.Message{}, skyWallet.ErrNoDeviceConnected)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var rsp struct {
+		Data DiagnosticsResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, "EMULATOR", rsp.Data.Transport.Mode)
+	require.Equal(t, "udp", rsp.Data.Transport.Name)
+
+	// a failed switch keeps the mode
+	rr = setMode("USB")
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Contains(t, rr.Body.String(), "failed to create the USB driver: no usb bus")
+	require.Equal(t, skyWallet.DeviceTypeEmulator, modeSwitch.Mode())
+
+	audit, err := recorder.Records(storage.AuditBucket, history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, audit, 1)
+	require.Equal(t, "/admin/mode?mode=EMULATOR", audit[0].Endpoint)
+	require.Equal(t, http.MethodPut, audit[0].Method)
+}
+
+func TestAdminModeDisabled(t *testing.T) {
+	// the admin endpoints are not served without token
+	cfg := defaultMuxConfig()
+	cfg.modeSwitch = newTestModeSwitch()
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/admin/mode", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	controller := loglevel.New(logrus.InfoLevel)
+	defer controller.Reset()
+
+	cfg := defaultMuxConfig()
+	cfg.adminToken = testAdminToken
+	cfg.logLevel = controller
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	cases := []struct {
+		name   string
+		method string
+		token  string
+		body   string
+		status int
+		err    string
+		level  loglevel.Status
+	}{
+		{
+			name:   "401",
+			method: http.MethodGet,
+			status: http.StatusUnauthorized,
+			err:    "invalid admin token",
+		},
+		{
+			name:   "200 - get",
+			method: http.MethodGet,
+			token:  testAdminToken,
+			status: http.StatusOK,
+			level: loglevel.Status{
+				Level:        "info",
+				DefaultLevel: "info",
+			},
+		},
+		{
+			name:   "400 - no level",
+			method: http.MethodPut,
+			token:  testAdminToken,
+			body:   `{}`,
+			status: http.StatusBadRequest,
+			err:    "level is required",
+		},
+		{
+			name:   "400 - invalid level",
+			method: http.MethodPut,
+			token:  testAdminToken,
+			body:   `{"level": "verbose"}`,
+			status: http.StatusBadRequest,
+			err:    "level must be debug, info, warn, error, fatal or panic",
+		},
+		{
+			name:   "400 - invalid duration",
+			method: http.MethodPut,
+			token:  testAdminToken,
+			body:   `{"level": "debug", "duration": "a while"}`,
+			status: http.StatusBadRequest,
+			err:    `invalid duration "a while"`,
+		},
+		{
+			name:   "422 - usb without debug",
+			method: http.MethodPut,
+			token:  testAdminToken,
+			body:   `{"level": "error", "usb": true}`,
+			status: http.StatusUnprocessableEntity,
+			err:    loglevel.ErrUSBRequiresDebug.Error(),
+		},
+		{
+			name:   "200 - warn",
+			method: http.MethodPut,
+			token:  testAdminToken,
+			body:   `{"level": "warn"}`,
+			status: http.StatusOK,
+			level: loglevel.Status{
+				Level:        "warning",
+				DefaultLevel: "info",
+			},
+		},
+		{
+			name:   "200 - usb",
+			method: http.MethodPut,
+			token:  testAdminToken,
+			body:   `{"usb": true, "duration": "10m"}`,
+			status: http.StatusOK,
+			level: loglevel.Status{
+				Level:        "debug",
+				USB:          true,
+				DefaultLevel: "info",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "/api/v2/admin/loglevel", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			if tc.err != "" {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			var status loglevel.Status
+			require.NoError(t, json.Unmarshal(rsp.Data, &status))
+			if tc.level.USB {
+				require.NotNil(t, status.ExpiresAt)
+				status.ExpiresAt = nil
+			}
+			require.Equal(t, tc.level, status)
+		})
+	}
+
+	require.True(t, controller.USB())
+}
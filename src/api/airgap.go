@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/airgap"
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+	"github.com/skycoin/hardware-wallet-daemon/src/qrcode"
+)
+
+const (
+	// AirgapFormatFile and AirgapFormatQR are the formats of the payloads exported by /api/v2/airgap/export
+	AirgapFormatFile = "file"
+	AirgapFormatQR   = "qr"
+
+	// maxAirgapFileSize is the largest file imported by /api/v2/airgap/import
+	maxAirgapFileSize = 256 * 1024
+)
+
+// AirgapImportRequest is request data for POST /api/v2/airgap/import, unless a file is uploaded
+type AirgapImportRequest struct {
+	// Payload is the content of an exchanged file, or of a single QR code
+	Payload string `json:"payload"`
+	// Parts are the contents of the scanned QR codes, in any order
+	Parts []string `json:"parts"`
+}
+
+// AirgapExportRequest is request data for POST /api/v2/airgap/export
+type AirgapExportRequest struct {
+	PartialTransaction string `json:"partial_transaction"`
+	// Transaction exports the fully signed transaction to inject in the network, instead of the partial transaction
+	Transaction bool `json:"transaction"`
+	// Format is file or qr
+	Format string `json:"format"`
+	// PartSize is the number of characters of the payload in a QR code
+	PartSize int `json:"part_size"`
+}
+
+// AirgapExportResponse is data returned by POST /api/v2/airgap/export in the qr format
+type AirgapExportResponse struct {
+	// Parts are the contents of the QR codes, all of them are scanned to import the payload
+	Parts []string `json:"parts"`
+	// QRCodes are the PNG images of the QR codes of the parts
+	QRCodes [][]byte `json:"qr_codes"`
+}
+
+// URI: /api/v2/airgap/import
+// Method: POST
+// Args: JSON Body, or a multipart form
+//
+//	file: exchanged file
+func airgapImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var payload string
+		var err error
+		switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+		case ContentTypeJSON:
+			var req AirgapImportRequest
+			if !decodeJSONRequest(w, r, &req) {
+				return
+			}
+
+			switch {
+			case req.Payload != "" && len(req.Parts) > 0:
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "payload and parts cannot both be set")
+				writeHTTPResponse(w, resp)
+				return
+			case len(req.Parts) > 0:
+				payload, err = airgap.Join(req.Parts)
+			default:
+				payload, err = airgap.Parse([]byte(req.Payload))
+			}
+		case "multipart/form-data":
+			data, ok := readAirgapFile(w, r)
+			if !ok {
+				return
+			}
+			payload, err = airgap.Parse(data)
+		default:
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txn, err := partialtx.Decode(payload)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writePartialTransaction(w, txn)
+	}
+}
+
+// URI: /api/v2/airgap/export
+// Method: POST
+// Args: JSON Body
+func airgapExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req AirgapExportRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		if req.Format != AirgapFormatFile && req.Format != AirgapFormatQR {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid format %q, choices are: %s or %s", req.Format, AirgapFormatFile, AirgapFormatQR))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.PartSize < 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "part_size must not be negative")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txn, err := partialtx.Decode(req.PartialTransaction)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		payload, name := req.PartialTransaction, "partial-transaction"
+		if req.Transaction {
+			data, err := txn.Serialize()
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			payload, name = hex.EncodeToString(data), "transaction"
+		}
+
+		if req.Format == AirgapFormatFile {
+			filename := fmt.Sprintf("%s-%s.txt", name, txn.InnerHash().Hex()[:8])
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			if _, err := w.Write([]byte(payload + "\n")); err != nil {
+				requestLogger(r).WithError(err).Error("failed to write airgap file")
+			}
+			return
+		}
+
+		parts, err := airgap.Split(payload, req.PartSize)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp := AirgapExportResponse{
+			Parts:   parts,
+			QRCodes: make([][]byte, 0, len(parts)),
+		}
+		for _, part := range parts {
+			code, err := qrcode.Encode([]byte(part))
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			image, err := code.PNG(qrCodeScale)
+			if err != nil {
+				requestLogger(r).WithError(err).Error("qrcode.PNG failed")
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			rsp.QRCodes = append(rsp.QRCodes, image)
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
+
+// readAirgapFile reads the file of the multipart form, writing the error response if it fails
+func readAirgapFile(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAirgapFileSize)
+	if err := r.ParseMultipartForm(maxAirgapFileSize); err != nil {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+
+	return data, true
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+)
+
+func TestAirgap(t *testing.T) {
+	pubKey, secKey := cipher.MustGenerateDeterministicKeyPair([]byte("alice"))
+	alice := cipher.AddressFromPubKey(pubKey)
+
+	txn, err := partialtx.New([]partialtx.Input{
+		{Hash: cipher.MustSHA256FromHex("c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"), Address: alice},
+		{Hash: cipher.MustSHA256FromHex("4f7250b0b1f588c4dd4b0d5f4d8ec6a3f6b7f6e2dc6a2a1bd4e86a7b1ea6e7b6"), Address: alice},
+	}, []partialtx.Output{
+		{Address: cipher.MustDecodeBase58Address("2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"), Coins: 12500000, Hours: 10},
+	})
+	require.NoError(t, err)
+	require.NoError(t, txn.Sign(0, cipher.MustSignHash(txn.SignatureHash(0), secKey)))
+	unsigned := txn.Encode()
+
+	handler := newServerMux(defaultMuxConfig(), &MockGatewayer{})
+
+	do := func(endpoint, contentType string, body []byte) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodPost, "/api/v2"+endpoint, bytes.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", contentType)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	post := func(endpoint string, req interface{}, data interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := do(endpoint, ContentTypeJSON, body)
+		if rr.Code == http.StatusOK && data != nil {
+			var rsp HTTPResponse
+			rsp.Data = data
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		}
+		return rr
+	}
+
+	// the partial transaction is carried to the air-gapped daemon in QR codes
+	var exported AirgapExportResponse
+	rr := post("/airgap/export", AirgapExportRequest{
+		PartialTransaction: unsigned,
+		Format:             AirgapFormatQR,
+		PartSize:           100,
+	}, &exported)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Len(t, exported.Parts, (len(unsigned)+99)/100)
+	require.Len(t, exported.QRCodes, len(exported.Parts))
+	require.True(t, bytes.HasPrefix(exported.QRCodes[0], []byte("\x89PNG")))
+
+	// the codes are scanned in any order
+	parts := append([]string{exported.Parts[len(exported.Parts)-1]}, exported.Parts[:len(exported.Parts)-1]...)
+	var imported PartialTransactionResponse
+	rr = post("/airgap/import", AirgapImportRequest{Parts: parts}, &imported)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, unsigned, imported.PartialTransaction)
+	require.Equal(t, 1, imported.Signed)
+
+	rr = post("/airgap/import", AirgapImportRequest{Parts: exported.Parts[1:]}, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = post("/airgap/import", AirgapImportRequest{Payload: unsigned, Parts: exported.Parts}, nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// the signed partial transaction is carried back to the online daemon in a file
+	require.NoError(t, txn.Sign(1, cipher.MustSignHash(txn.SignatureHash(1), secKey)))
+	signed := txn.Encode()
+
+	rr = post("/airgap/export", AirgapExportRequest{
+		PartialTransaction: signed,
+		Format:             AirgapFormatFile,
+	}, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, `attachment; filename="partial-transaction-`+txn.InnerHash().Hex()[:8]+`.txt"`, rr.Header().Get("Content-Disposition"))
+	require.Equal(t, signed+"\n", rr.Body.String())
+	file := rr.Body.Bytes()
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, err := mw.CreateFormFile("file", "partial-transaction.txt")
+	require.NoError(t, err)
+	_, err = fw.Write(file)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	rr = do("/airgap/import", mw.FormDataContentType(), form.Bytes())
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	imported = PartialTransactionResponse{}
+	rsp := HTTPResponse{
+		Data: &imported,
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.True(t, imported.Complete)
+
+	rr = post("/airgap/import", AirgapImportRequest{Payload: string(file)}, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// the fully signed transaction is exported to be injected in the network
+	rr = post("/airgap/export", AirgapExportRequest{
+		PartialTransaction: signed,
+		Transaction:        true,
+		Format:             AirgapFormatFile,
+	}, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	data, err := txn.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(data)+"\n", rr.Body.String())
+
+	rr = post("/airgap/export", AirgapExportRequest{
+		PartialTransaction: unsigned,
+		Transaction:        true,
+		Format:             AirgapFormatQR,
+	}, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = post("/airgap/export", AirgapExportRequest{
+		PartialTransaction: signed,
+		Format:             "png",
+	}, nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = post("/airgap/import", AirgapImportRequest{Payload: strings.ToUpper(signed)}, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do("/airgap/import", "text/plain", []byte(signed))
+	require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+}
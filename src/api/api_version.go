@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	apiVersion1 = "v1"
+	apiVersion2 = "v2"
+
+	// DeprecationHeaderName is the header of the responses of the deprecated v1 endpoints, the date of the deprecation
+	DeprecationHeaderName = "Deprecation"
+	// SunsetHeaderName is the header of the responses of the deprecated v1 endpoints, the date they stop being served
+	SunsetHeaderName = "Sunset"
+
+	// apiV1Deprecation is when the v1 endpoints were deprecated in favor of the v2 endpoints, 2026-10-14
+	apiV1Deprecation = 1791936000
+)
+
+// ErrAPIV1Disabled is returned for the requests to the v1 endpoints when they are disabled
+var ErrAPIV1Disabled = fmt.Errorf("the %s API is disabled, use /api/%s", apiVersion1, apiVersion2)
+
+// apiV1Deprecated marks the responses of the v1 endpoints as deprecated with the Deprecation header, the Sunset header
+// if sunset is set, and links them to their successor v2 endpoint. The v1 endpoints answering 410 once disabled are
+// marked too, so clients find their successor.
+func apiV1Deprecated(sunset time.Time, endpoint string, handler http.Handler) http.Handler {
+	prefix := "/api/" + apiVersion1 + "/"
+	if !strings.HasPrefix(endpoint, prefix) {
+		return handler
+	}
+
+	deprecation := fmt.Sprintf("@%d", apiV1Deprecation)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DeprecationHeaderName, deprecation)
+		if !sunset.IsZero() {
+			w.Header().Set(SunsetHeaderName, sunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Add("Link", fmt.Sprintf(`</api/%s/%s>; rel="successor-version"`, apiVersion2, strings.TrimPrefix(r.URL.Path, prefix)))
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// apiV1Disabled answers the requests to the v1 endpoints with a 410 once they are disabled
+func apiV1Disabled() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := NewHTTPErrorResponse(http.StatusGone, ErrAPIV1Disabled.Error())
+		writeHTTPResponse(w, resp)
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIVersions(t *testing.T) {
+	featuresMsgBytes, err := (&messages.Features{
+		Vendor: newStrPtr("Skycoin Foundation"),
+	}).Marshal()
+	require.NoError(t, err)
+
+	sunset := time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		disableAPIV1 bool
+		sunset       time.Time
+		endpoint     string
+		status       int
+		deprecation  string
+		sunsetHeader string
+		link         string
+		category     string
+	}{
+		{
+			name:     "v2",
+			endpoint: "/api/v2/features",
+			status:   http.StatusOK,
+		},
+
+		{
+			name:        "v1 deprecated",
+			endpoint:    "/api/v1/features",
+			status:      http.StatusOK,
+			deprecation: "@1791936000",
+			link:        `</api/v2/features>; rel="successor-version"`,
+		},
+
+		{
+			name:         "v1 sunset",
+			sunset:       sunset,
+			endpoint:     "/api/v1/features",
+			status:       http.StatusOK,
+			deprecation:  "@1791936000",
+			sunsetHeader: "Thu, 01 Apr 2027 00:00:00 GMT",
+			link:         `</api/v2/features>; rel="successor-version"`,
+		},
+
+		{
+			name:         "v1 disabled",
+			disableAPIV1: true,
+			endpoint:     "/api/v1/features",
+			status:       http.StatusGone,
+			deprecation:  "@1791936000",
+			link:         `</api/v2/features>; rel="successor-version"`,
+			category:     ErrorCategoryAPIDisabled,
+		},
+
+		{
+			name:         "v2 with v1 disabled",
+			disableAPIV1: true,
+			endpoint:     "/api/v2/features",
+			status:       http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetFeatures").Return(wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Features),
+				Data: featuresMsgBytes,
+			}, nil)
+
+			c := defaultMuxConfig()
+			c.disableAPIV1 = tc.disableAPIV1
+			c.apiV1Sunset = tc.sunset
+
+			req, err := http.NewRequest(http.MethodGet, tc.endpoint, nil)
+			require.NoError(t, err)
+			// the v1 endpoints write the errors without category in the oldest model version
+			req.Header.Set(ModelVersionHeaderName, strconv.Itoa(ModelVersion))
+
+			rr := httptest.NewRecorder()
+			newServerMux(c, gateway).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.deprecation, rr.Header().Get(DeprecationHeaderName))
+			require.Equal(t, tc.sunsetHeader, rr.Header().Get(SunsetHeaderName))
+			require.Equal(t, tc.link, rr.Header().Get("Link"))
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			if tc.category != "" {
+				require.Equal(t, ErrAPIV1Disabled.Error(), rsp.Error.Message)
+				require.Equal(t, tc.category, rsp.Error.Category)
+				gateway.AssertNotCalled(t, "GetFeatures")
+			} else {
+				require.Nil(t, rsp.Error)
+			}
+		})
+	}
+}
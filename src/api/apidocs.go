@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/go-openapi/swag"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+//go:generate go run gen_spec.go
+
+// DefaultAPIDocsAssetsURL is where the Swagger UI page loads the swagger-ui-dist scripts and styles from by default
+const DefaultAPIDocsAssetsURL = "https://unpkg.com/swagger-ui-dist@5"
+
+var (
+	specOnce sync.Once
+	spec     swag.JSONMapSlice
+	specErr  error
+)
+
+// apiSpec returns the embedded swagger.yml converted to JSON, keeping the order of its keys
+func apiSpec() (swag.JSONMapSlice, error) {
+	specOnce.Do(func() {
+		var doc interface{}
+		doc, specErr = swag.BytesToYAMLDoc([]byte(swaggerYAML))
+		if specErr != nil {
+			return
+		}
+
+		var data json.RawMessage
+		data, specErr = swag.YAMLToJSON(doc)
+		if specErr != nil {
+			return
+		}
+
+		specErr = json.Unmarshal(data, &spec)
+	})
+
+	return spec, specErr
+}
+
+// URI: /api/v1/spec
+// Method: GET
+// Response: the swagger spec of the API as JSON, with the host of the request
+func specHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		s, err := apiSpec()
+		if err != nil {
+			requestLogger(r).WithError(err).Error("failed to convert the API spec")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// the spec of the live daemon is tried out against it, not the default address
+		hostSpec := make(swag.JSONMapSlice, len(s))
+		copy(hostSpec, s)
+		for i := range hostSpec {
+			if hostSpec[i].Key == "host" {
+				hostSpec[i].Value = r.Host
+			}
+		}
+
+		data, err := json.MarshalIndent(hostSpec, "", "    ")
+		if err != nil {
+			requestLogger(r).WithError(err).Error("failed to marshal the API spec")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		if _, err := w.Write(data); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write the API spec")
+		}
+	}
+}
+
+// apiDocsPage is the Swagger UI page, the CSRF token is fetched for the requests tried out when CSRF is enabled
+var apiDocsPage = template.Must(template.New("apidocs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Hardware Wallet Daemon API</title>
+<link rel="stylesheet" href="{{.AssetsURL}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.AssetsURL}}/swagger-ui-bundle.js"></script>
+<script nonce="{{.Nonce}}">
+window.ui = SwaggerUIBundle({
+	url: {{.SpecURL}},
+	dom_id: "#swagger-ui",{{if .CSRF}}
+	requestInterceptor: function (req) {
+		if (req.method === "GET") {
+			return req;
+		}
+		return fetch({{.CSRFURL}}).then(function (rsp) {
+			return rsp.json();
+		}).then(function (body) {
+			req.headers[{{.CSRFHeader}}] = body.data;
+			return req;
+		});
+	}{{end}}
+});
+</script>
+</body>
+</html>
+`))
+
+// URI: /apidocs
+// Method: GET
+// Response: the Swagger UI page exploring the API of the daemon
+func apiDocsHandler(assetsURL string, csrf bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// the page runs its own script and the ones of the assets, and calls the API. The nonce is websafe base64,
+		// the template would escape the + of the standard encoding in the attribute
+		nonce := base64.RawURLEncoding.EncodeToString(cipher.RandByte(16))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", apiDocsPolicy(assetsURL, nonce))
+		if err := apiDocsPage.Execute(w, struct {
+			AssetsURL  string
+			Nonce      string
+			SpecURL    string
+			CSRF       bool
+			CSRFURL    string
+			CSRFHeader string
+		}{
+			AssetsURL:  assetsURL,
+			Nonce:      nonce,
+			SpecURL:    "/api/" + apiVersion2 + "/spec",
+			CSRF:       csrf,
+			CSRFURL:    "/api/" + apiVersion2 + "/csrf",
+			CSRFHeader: CSRFHeaderName,
+		}); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write the API docs")
+		}
+	}
+}
+
+// apiDocsPolicy returns the Content-Security-Policy of the Swagger UI page, loading its assets from assetsURL and
+// running the inline script of nonce
+func apiDocsPolicy(assetsURL, nonce string) string {
+	assets := "'self'"
+	if u, err := url.Parse(assetsURL); err == nil && u.Host != "" {
+		assets = u.Scheme + "://" + u.Host
+	}
+
+	return fmt.Sprintf("default-src 'none'; script-src %[1]s 'nonce-%[2]s'; style-src %[1]s 'unsafe-inline'; img-src %[1]s data:; connect-src 'self'; frame-ancestors 'none'", assets, nonce)
+}
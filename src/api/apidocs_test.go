@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedSpec(t *testing.T) {
+	spec, err := ioutil.ReadFile("../../swagger.yml")
+	require.NoError(t, err)
+	require.Equal(t, string(spec), swaggerYAML, "spec_gen.go is stale, run go generate in src/api")
+}
+
+func TestSpec(t *testing.T) {
+	cases := []struct {
+		name           string
+		method         string
+		endpoint       string
+		disableAPIDocs bool
+		status         int
+	}{
+		{
+			name:     "405",
+			method:   http.MethodPost,
+			endpoint: "/api/v2/spec",
+			status:   http.StatusMethodNotAllowed,
+		},
+
+		{
+			name:     "200 v2",
+			method:   http.MethodGet,
+			endpoint: "/api/v2/spec",
+			status:   http.StatusOK,
+		},
+
+		{
+			name:     "200 v1",
+			method:   http.MethodGet,
+			endpoint: "/api/v2/spec",
+			status:   http.StatusOK,
+		},
+
+		{
+			name:           "404 disabled",
+			method:         http.MethodGet,
+			endpoint:       "/api/v2/spec",
+			disableAPIDocs: true,
+			status:         http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultMuxConfig()
+			c.disableAPIDocs = tc.disableAPIDocs
+
+			req, err := http.NewRequest(tc.method, tc.endpoint, nil)
+			require.NoError(t, err)
+			req.Host = "localhost:9520"
+
+			rr := httptest.NewRecorder()
+			newServerMux(c, &MockGatewayer{}).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+			if tc.status != http.StatusOK {
+				return
+			}
+			require.Equal(t, ContentTypeJSON, rr.Header().Get("Content-Type"))
+
+			var spec struct {
+				Swagger  string                     `json:"swagger"`
+				Host     string                     `json:"host"`
+				BasePath string                     `json:"basePath"`
+				Paths    map[string]json.RawMessage `json:"paths"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&spec))
+			require.Equal(t, "2.0", spec.Swagger)
+			require.Equal(t, "localhost:9520", spec.Host)
+			require.Equal(t, "/api/v2", spec.BasePath)
+			require.Contains(t, spec.Paths, "/features")
+		})
+	}
+}
+
+func TestAPIDocs(t *testing.T) {
+	cases := []struct {
+		name           string
+		method         string
+		enableCSRF     bool
+		disableAPIDocs bool
+		status         int
+		contains       []string
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+		},
+
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			contains: []string{
+				`<script src="https://mirror.example.com/swagger-ui/swagger-ui-bundle.js">`,
+				`url: "/api/v2/spec"`,
+			},
+		},
+
+		{
+			name:       "200 CSRF",
+			method:     http.MethodGet,
+			enableCSRF: true,
+			status:     http.StatusOK,
+			contains: []string{
+				`fetch("/api/v2/csrf")`,
+				`req.headers["X-CSRF-Token"] = body.data`,
+			},
+		},
+
+		{
+			name:           "404 disabled",
+			method:         http.MethodGet,
+			disableAPIDocs: true,
+			status:         http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultMuxConfig()
+			c.enableCSRF = tc.enableCSRF
+			c.disableAPIDocs = tc.disableAPIDocs
+			c.apiDocsAssetsURL = "https://mirror.example.com/swagger-ui"
+
+			req, err := http.NewRequest(tc.method, "/apidocs", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			newServerMux(c, &MockGatewayer{}).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+			if tc.status != http.StatusOK {
+				return
+			}
+			require.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html"))
+
+			body := rr.Body.String()
+			for _, s := range tc.contains {
+				require.Contains(t, body, s)
+			}
+			// the CSRF token is only fetched when CSRF is enabled
+			require.Equal(t, tc.enableCSRF, strings.Contains(body, "requestInterceptor"))
+		})
+	}
+}
@@ -14,6 +14,8 @@ type ApplySettingsRequest struct {
 	Label         string `json:"label"`
 	UsePassphrase *bool  `json:"use_passphrase"`
 	Language      string `json:"language"`
+	// Homescreen is a base64 encoded 128x64 PNG, GIF or JPEG image, or an already packed monochrome bitmap
+	Homescreen []byte `json:"homescreen,omitempty"`
 }
 
 // applySettings apply device settings
@@ -42,11 +44,22 @@ func applySettings(gateway Gatewayer) http.HandlerFunc {
 		}
 		defer r.Body.Close()
 
+		var homescreen []byte
+		if req.Homescreen != nil {
+			var err error
+			homescreen, err = convertHomescreen(req.Homescreen)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
 		// for integration tests
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("applySettings failed: %s", err.Error())
+				requestLogger(r).Error("applySettings failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -60,7 +73,11 @@ func applySettings(gateway Gatewayer) http.HandlerFunc {
 		ctx := r.Context()
 
 		go func() {
-			msg, err = gateway.ApplySettings(req.UsePassphrase, req.Label, req.Language)
+			if homescreen != nil {
+				msg, err = gateway.ApplySettingsHomescreen(req.UsePassphrase, req.Label, req.Language, homescreen)
+			} else {
+				msg, err = gateway.ApplySettings(req.UsePassphrase, req.Label, req.Language)
+			}
 			if err != nil {
 				errCH <- 1
 				return
@@ -72,7 +89,7 @@ func applySettings(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Error("applySettings failed: %s", err.Error())
+			requestLogger(r).Error("applySettings failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
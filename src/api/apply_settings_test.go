@@ -1,7 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -28,12 +32,21 @@ func TestApplySettings(t *testing.T) {
 	successMsgBytes, err := successMsg.Marshal()
 	require.NoError(t, err)
 
+	img := image.NewGray(image.Rect(0, 0, HomescreenWidth, HomescreenHeight))
+	img.SetGray(0, 0, color.Gray{Y: 0xff})
+	var pngBuf bytes.Buffer
+	require.NoError(t, png.Encode(&pngBuf, img))
+
+	packedHomescreen := make([]byte, homescreenSize)
+	packedHomescreen[0] = 0x80
+
 	cases := []struct {
 		name                       string
 		method                     string
 		status                     int
 		contentType                string
 		httpBody                   string
+		homescreen                 []byte
 		gatewayApplySettingsResult wire.Message
 		httpResponse               HTTPResponse
 	}{
@@ -73,7 +86,7 @@ func TestApplySettings(t *testing.T) {
 				Kind: uint16(messages.MessageType_MessageType_Failure),
 				Data: failureMsgBytes,
 			},
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
 		},
 
 		{
@@ -92,6 +105,36 @@ func TestApplySettings(t *testing.T) {
 				Data: []string{"success msg"},
 			},
 		},
+
+		{
+			name:        "422 - invalid homescreen",
+			method:      http.MethodPost,
+			contentType: ContentTypeJSON,
+			status:      http.StatusUnprocessableEntity,
+			httpBody: toJSON(t, &ApplySettingsRequest{
+				Label:      "foo",
+				Homescreen: []byte("not an image"),
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "invalid homescreen image: image: unknown format"),
+		},
+
+		{
+			name:   "200 - OK homescreen",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			httpBody: toJSON(t, &ApplySettingsRequest{
+				Label:      "foo",
+				Homescreen: pngBuf.Bytes(),
+			}),
+			homescreen: packedHomescreen,
+			gatewayApplySettingsResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Success),
+				Data: successMsgBytes,
+			},
+			httpResponse: HTTPResponse{
+				Data: []string{"success msg"},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -103,9 +146,10 @@ func TestApplySettings(t *testing.T) {
 			err := json.Unmarshal([]byte(tc.httpBody), &body)
 			if err == nil {
 				gateway.On("ApplySettings", body.UsePassphrase, body.Label, body.Language).Return(tc.gatewayApplySettingsResult, nil)
+				gateway.On("ApplySettingsHomescreen", body.UsePassphrase, body.Label, body.Language, tc.homescreen).Return(tc.gatewayApplySettingsResult, nil)
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
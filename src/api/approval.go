@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+)
+
+// PendingApproval is returned by an operation held for approval, it is sent again with the approval ID once approved
+type PendingApproval struct {
+	ApprovalID string    `json:"approval_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ApprovalDecisionRequest is request data for POST /api/v1/approvals/approve and /api/v1/approvals/reject
+type ApprovalDecisionRequest struct {
+	ID string `json:"id"`
+}
+
+// useApproval holds the operation for approval, or uses the approval of the approval_id arg of the request.
+// It returns true if the operation was approved, otherwise the response is written.
+func useApproval(w http.ResponseWriter, r *http.Request, approvals *approval.Manager, operation string, details interface{}) bool {
+	id := r.URL.Query().Get("approval_id")
+	if id == "" {
+		a, err := approvals.Request(operation, details, requestIDFromRequest(r))
+		switch err {
+		case nil:
+		case approval.ErrTooMany:
+			resp := NewHTTPErrorResponse(http.StatusTooManyRequests, err.Error())
+			writeHTTPResponse(w, resp)
+			return false
+		default:
+			requestLogger(r).Errorf("%s approval request failed: %s", operation, err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return false
+		}
+
+		writeHTTPResponseStatus(w, http.StatusAccepted, HTTPResponse{
+			Data: PendingApproval{
+				ApprovalID: a.ID,
+				ExpiresAt:  a.ExpiresAt,
+			},
+		})
+		return false
+	}
+
+	switch err := approvals.Use(id, operation, details); err {
+	case nil:
+		return true
+	case approval.ErrPending:
+		resp := NewHTTPErrorResponse(http.StatusConflict, err.Error())
+		writeHTTPResponse(w, resp)
+	case approval.ErrNotFound, approval.ErrRejected, approval.ErrMismatch:
+		resp := NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+		writeHTTPResponse(w, resp)
+	default:
+		requestLogger(r).Errorf("%s approval failed: %s", operation, err.Error())
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+	}
+	return false
+}
+
+// approvalsHandler returns the operations held for approval, with their details for the approver to review
+// URI: /api/v1/approvals
+// Method: GET
+func approvalsHandler(approvals *approval.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: approvals.Approvals(),
+		})
+	}
+}
+
+// approvalDecisionHandler approves or rejects an operation held for approval. The decisions are recorded in the audit log.
+// URI: /api/v1/approvals/approve, /api/v1/approvals/reject
+// Method: POST
+// Args: JSON Body
+func approvalDecisionHandler(name string, decide func(id string) (approval.Approval, error), recorder *history.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		start := time.Now()
+
+		var req ApprovalDecisionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		a, err := decide(req.ID)
+		switch err {
+		case nil:
+		case approval.ErrNotFound:
+			resp := NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		case approval.ErrDecided:
+			resp := NewHTTPErrorResponse(http.StatusConflict, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		default:
+			requestLogger(r).Errorf("approval %s failed: %s", name, err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if recorder != nil {
+			if err := recorder.Record(history.Record{
+				Time:      start.UTC(),
+				Endpoint:  "/approvals/" + name + "?operation=" + a.Operation,
+				Method:    r.Method,
+				Status:    http.StatusOK,
+				Duration:  int64(time.Since(start) / time.Millisecond),
+				RequestID: requestIDFromRequest(r),
+				Trace:     recorder.Trace(r.Header),
+			}, true); err != nil {
+				requestLogger(r).WithError(err).Errorf("failed to record approval %s", name)
+			}
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: a,
+		})
+	}
+}
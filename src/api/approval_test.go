@@ -0,0 +1,223 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const testApprovalToken = "approval-secret"
+
+func TestTransactionSignApproval(t *testing.T) {
+	signResponse := messages.ResponseTransactionSign{
+		Signatures: []string{"signature"},
+		Padding:    newBoolPtr(false),
+	}
+	signResponseBytes, err := signResponse.Marshal()
+	require.NoError(t, err)
+
+	// 10 coins are spent, the change output to the device is not spent
+	txn := TransactionSignRequest{
+		TransactionInputs: []TransactionInput{
+			{newUint32Ptr(0), "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+		},
+		TransactionOutputs: []TransactionOutput{
+			{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "10", Hours: "2"},
+			{AddressIndex: newUint32Ptr(0), Address: "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", Coins: "1000", Hours: "3"},
+		},
+	}
+	ins, outs, err := txn.TransactionParams()
+	require.NoError(t, err)
+
+	gateway := &MockGatewayer{}
+	gateway.On("TransactionSign", ins, outs).Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ResponseTransactionSign),
+		Data: signResponseBytes,
+	}, nil)
+
+	recorder := history.NewRecorder(storage.NewMemoryStore(), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.approvals = approval.NewManager(0)
+	cfg.approvalThreshold = 5e6
+	cfg.approvalToken = testApprovalToken
+	cfg.history = recorder
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, endpoint, token string, body interface{}) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		var data []byte
+		if body != nil {
+			var err error
+			data, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+
+		req, err := http.NewRequest(method, endpoint, bytes.NewReader(data))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	sign := func(approvalID string, txn TransactionSignRequest) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		endpoint := "/api/v2/transaction_sign"
+		if approvalID != "" {
+			endpoint += "?approval_id=" + approvalID
+		}
+		return do(http.MethodPost, endpoint, "", txn)
+	}
+
+	// the transaction is held for approval, the device is not prompted
+	rr, rsp := sign("", txn)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	var pending PendingApproval
+	require.NoError(t, json.Unmarshal(rsp.Data, &pending))
+	require.NotEmpty(t, pending.ApprovalID)
+	gateway.AssertNotCalled(t, "TransactionSign", ins, outs)
+
+	rr, rsp = sign(pending.ApprovalID, txn)
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusConflict, approval.ErrPending.Error()).Error, rsp.Error)
+
+	// the approval endpoints require the approval token
+	for _, token := range []string{"", testAdminToken} {
+		rr, rsp = do(http.MethodGet, "/api/v2/approvals", token, nil)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Equal(t, `Bearer realm="approval"`, rr.Header().Get("WWW-Authenticate"))
+		require.Equal(t, NewHTTPErrorResponse(http.StatusUnauthorized, "invalid approval token").Error, rsp.Error)
+	}
+
+	rr, rsp = do(http.MethodGet, "/api/v2/approvals", testApprovalToken, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var approvals []approval.Approval
+	require.NoError(t, json.Unmarshal(rsp.Data, &approvals))
+	require.Len(t, approvals, 1)
+	require.Equal(t, pending.ApprovalID, approvals[0].ID)
+	require.Equal(t, transactionSignAction, approvals[0].Operation)
+	require.Equal(t, approval.StatePending, approvals[0].State)
+	var details TransactionSignRequest
+	require.NoError(t, json.Unmarshal(approvals[0].Details, &details))
+	require.Equal(t, txn, details)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/approvals", testApprovalToken, nil)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/approvals/approve", testApprovalToken, ApprovalDecisionRequest{})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, "id is required").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/approvals/approve", testApprovalToken, ApprovalDecisionRequest{ID: "foo"})
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusNotFound, approval.ErrNotFound.Error()).Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/approvals/approve", testApprovalToken, ApprovalDecisionRequest{ID: pending.ApprovalID})
+	require.Equal(t, http.StatusOK, rr.Code)
+	var approved approval.Approval
+	require.NoError(t, json.Unmarshal(rsp.Data, &approved))
+	require.Equal(t, approval.StateApproved, approved.State)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/approvals/reject", testApprovalToken, ApprovalDecisionRequest{ID: pending.ApprovalID})
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusConflict, approval.ErrDecided.Error()).Error, rsp.Error)
+
+	// the approval is only used for the approved transaction
+	other := txn
+	other.TransactionOutputs = []TransactionOutput{
+		{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "100", Hours: "2"},
+	}
+	rr, rsp = sign(pending.ApprovalID, other)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusForbidden, approval.ErrMismatch.Error()).Error, rsp.Error)
+
+	rr, rsp = sign(pending.ApprovalID, txn)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var signatures []string
+	require.NoError(t, json.Unmarshal(rsp.Data, &signatures))
+	require.Equal(t, []string{"signature"}, signatures)
+	gateway.AssertNumberOfCalls(t, "TransactionSign", 1)
+
+	// the approval is used once
+	rr, rsp = sign(pending.ApprovalID, txn)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusForbidden, approval.ErrNotFound.Error()).Error, rsp.Error)
+
+	// a rejected transaction is never signed
+	_, rsp = sign("", txn)
+	require.NoError(t, json.Unmarshal(rsp.Data, &pending))
+	rr, _ = do(http.MethodPost, "/api/v2/approvals/reject", testApprovalToken, ApprovalDecisionRequest{ID: pending.ApprovalID})
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr, rsp = sign(pending.ApprovalID, txn)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusForbidden, approval.ErrRejected.Error()).Error, rsp.Error)
+	gateway.AssertNumberOfCalls(t, "TransactionSign", 1)
+
+	// the transactions spending up to the threshold are signed without approval
+	small := txn
+	small.TransactionOutputs = []TransactionOutput{
+		{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "5", Hours: "2"},
+	}
+	ins, outs, err = small.TransactionParams()
+	require.NoError(t, err)
+	gateway.On("TransactionSign", ins, outs).Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ResponseTransactionSign),
+		Data: signResponseBytes,
+	}, nil)
+	rr, _ = sign("", small)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	audit, err := recorder.Records(storage.AuditBucket, history.Filter{})
+	require.NoError(t, err)
+	var endpoints []string
+	for _, r := range audit {
+		if strings.HasPrefix(r.Endpoint, "/approvals/") {
+			endpoints = append(endpoints, r.Endpoint)
+		}
+	}
+	require.ElementsMatch(t, []string{"/approvals/approve?operation=transaction_sign", "/approvals/reject?operation=transaction_sign"}, endpoints)
+}
+
+func TestSpentCoins(t *testing.T) {
+	txn := TransactionSignRequest{
+		TransactionOutputs: []TransactionOutput{
+			{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "1.5", Hours: "2"},
+			{Address: "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", Coins: "2", Hours: "3"},
+			{AddressIndex: newUint32Ptr(3), Address: "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", Coins: "100", Hours: "3"},
+		},
+	}
+	_, outs, err := txn.TransactionParams()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3500000), spentCoins(outs))
+	require.Zero(t, spentCoins(nil))
+}
+
+func TestApprovalsDisabled(t *testing.T) {
+	handler := newServerMux(defaultMuxConfig(), &MockGatewayer{})
+
+	for _, endpoint := range []string{"/api/v2/approvals", "/api/v2/approvals/approve", "/api/v2/approvals/reject"} {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// Attestation identifies the build of the daemon answering the requests
+type Attestation struct {
+	Build BuildInfo `json:"build"`
+	// BinarySHA256 is the hex encoded SHA256 hash of the executable of the daemon
+	BinarySHA256 string `json:"binary_sha256"`
+	// Signature is the hex encoded detached signature of the binary hash published with the release, empty if the
+	// binary is not signed
+	Signature string `json:"signature,omitempty"`
+	// Signer is the public key recovered from the signature, to compare with the public key of the releases
+	Signer string `json:"signer,omitempty"`
+}
+
+// NewAttestation hashes the executable at binaryPath and reads the detached signature of its hash from
+// signaturePath, a file holding the hex encoded signature. An empty signaturePath attests an unsigned binary.
+func NewAttestation(build BuildInfo, binaryPath, signaturePath string) (*Attestation, error) {
+	hash, err := hashFile(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the daemon binary: %v", err)
+	}
+
+	a := &Attestation{
+		Build:        build,
+		BinarySHA256: hash.Hex(),
+	}
+
+	if signaturePath == "" {
+		return a, nil
+	}
+
+	data, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the build signature: %v", err)
+	}
+
+	sig, err := cipher.SigFromHex(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid build signature %s: %v", signaturePath, err)
+	}
+
+	// a signature of another hash recovers another signer, which the operators do not trust
+	signer, err := cipher.PubKeyFromSig(sig, hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid build signature %s: %v", signaturePath, err)
+	}
+
+	a.Signature = sig.Hex()
+	a.Signer = signer.Hex()
+	return a, nil
+}
+
+// hashFile returns the SHA256 hash of the file at path
+func hashFile(path string) (cipher.SHA256, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	return cipher.SHA256FromBytes(h.Sum(nil))
+}
+
+// attestHandler returns the build of the daemon and the hash of its binary, with its signature if any
+// URI: /api/v1/attest
+// Method: GET
+func attestHandler(c muxConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if c.attestation == nil {
+			resp := NewHTTPErrorResponse(http.StatusNotFound, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: c.attestation,
+		})
+	}
+}
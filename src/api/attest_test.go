@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAttestation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	binary := []byte("daemon binary")
+	binaryPath := filepath.Join(dir, "daemon")
+	require.NoError(t, ioutil.WriteFile(binaryPath, binary, 0600))
+	hash := cipher.SumSHA256(binary)
+
+	pubKey, secKey := cipher.GenerateKeyPair()
+	sig := cipher.MustSignHash(hash, secKey)
+	sigPath := filepath.Join(dir, "daemon.sig")
+	require.NoError(t, ioutil.WriteFile(sigPath, []byte(sig.Hex()+"\n"), 0600))
+
+	badSigPath := filepath.Join(dir, "bad.sig")
+	require.NoError(t, ioutil.WriteFile(badSigPath, []byte("abcd"), 0600))
+
+	build := BuildInfo{
+		Version:   "0.1.0",
+		Commit:    "0123abc",
+		BuildTime: "2019-03-01T10:00:00Z",
+		GoVersion: "go1.11",
+	}
+
+	a, err := NewAttestation(build, binaryPath, "")
+	require.NoError(t, err)
+	require.Equal(t, &Attestation{
+		Build:        build,
+		BinarySHA256: hash.Hex(),
+	}, a)
+
+	a, err = NewAttestation(build, binaryPath, sigPath)
+	require.NoError(t, err)
+	require.Equal(t, &Attestation{
+		Build:        build,
+		BinarySHA256: hash.Hex(),
+		Signature:    sig.Hex(),
+		Signer:       pubKey.Hex(),
+	}, a)
+
+	// a signature of another binary recovers another signer
+	otherSig := cipher.MustSignHash(cipher.SumSHA256([]byte("other binary")), secKey)
+	require.NoError(t, ioutil.WriteFile(sigPath, []byte(otherSig.Hex()), 0600))
+	a, err = NewAttestation(build, binaryPath, sigPath)
+	require.NoError(t, err)
+	require.NotEqual(t, pubKey.Hex(), a.Signer)
+
+	_, err = NewAttestation(build, binaryPath, badSigPath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid build signature")
+
+	_, err = NewAttestation(build, binaryPath, filepath.Join(dir, "missing.sig"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read the build signature")
+
+	_, err = NewAttestation(build, filepath.Join(dir, "missing"), "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to hash the daemon binary")
+}
+
+func TestAttest(t *testing.T) {
+	attestation := &Attestation{
+		Build: BuildInfo{
+			Version:   "0.1.0",
+			GoVersion: "go1.11",
+		},
+		BinarySHA256: cipher.SumSHA256([]byte("daemon binary")).Hex(),
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		attestation *Attestation
+		status      int
+	}{
+		{
+			name:        "405",
+			method:      http.MethodPost,
+			attestation: attestation,
+			status:      http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "404 no attestation",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+		},
+		{
+			name:        "200",
+			method:      http.MethodGet,
+			attestation: attestation,
+			status:      http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "/api/v2/attest", nil)
+			require.NoError(t, err)
+
+			cfg := defaultMuxConfig()
+			cfg.attestation = tc.attestation
+
+			rr := httptest.NewRecorder()
+			newServerMux(cfg, &MockGatewayer{}).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var rsp struct {
+				Data Attestation `json:"data"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, *tc.attestation, rsp.Data)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/auditlog"
+)
+
+// maxOutcomeResponseSize is the size of the response body kept to read the outcome of an operation
+const maxOutcomeResponseSize = 4096
+
+// auditLogOperations maps the device endpoints written to the audit log file to their operation
+var auditLogOperations = map[string]string{
+	"/wipe":               "wipe",
+	"/recovery":           "recovery",
+	"/configure_pin_code": "pin_change",
+	"/firmware_update":    "firmware_update",
+	"/transaction_sign":   "transaction_sign",
+
+	"/partial_transaction/sign": "partial_transaction_sign",
+}
+
+// operationAudit writes the operations of auditLogOperations to the audit log file, with the source of their request,
+// the ID of the device and their outcome. An operation waiting for the user input, such as the PIN, is held by the
+// log and written once an intermediate request finishes it, or once it is canceled or abandoned.
+func operationAudit(log *auditlog.Log, gateway Gatewayer, endpoint string, handler http.Handler) http.Handler {
+	if log == nil {
+		return handler
+	}
+
+	operation, audited := auditLogOperations[endpoint]
+	intermediate := strings.HasPrefix(endpoint, "/intermediate/")
+	if !audited && !intermediate && endpoint != "/cancel" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entry auditlog.Entry
+
+		switch {
+		case audited:
+			// the first step of a wipe only returns its confirmation token
+			if endpoint == "/wipe" && r.URL.Query().Get("confirmation_token") == "" {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			if err := log.Abandon(); err != nil {
+				requestLogger(r).WithError(err).Error("failed to write the audit log")
+			}
+
+			entry = auditlog.Entry{
+				Time:      time.Now(),
+				Operation: operation,
+				Method:    r.Method,
+				Endpoint:  endpoint,
+				Source: auditlog.Source{
+					IP:        clientIP(r),
+					Origin:    r.Header.Get("Origin"),
+					UserAgent: r.Header.Get("User-Agent"),
+				},
+				DeviceID:  auditDeviceID(gateway),
+				RequestID: requestIDFromRequest(r),
+			}
+
+		case endpoint == "/cancel":
+			held, ok := log.Release()
+			handler.ServeHTTP(w, r)
+			if ok {
+				held.Outcome = auditlog.OutcomeCancelled
+				writeAuditLog(log, r, held)
+			}
+			return
+
+		default:
+			held, ok := log.Release()
+			if !ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			entry = held
+		}
+
+		sw := &sessionWriter{
+			statusWriter: newStatusWriter(w),
+			size:         maxOutcomeResponseSize,
+		}
+		handler.ServeHTTP(sw, r)
+
+		entry.Status = sw.status
+		if sw.awaitsInput() {
+			if err := log.Hold(entry); err != nil {
+				requestLogger(r).WithError(err).Error("failed to write the audit log")
+			}
+			return
+		}
+
+		entry.Outcome, entry.Error = operationOutcome(sw)
+		writeAuditLog(log, r, entry)
+	})
+}
+
+// auditDeviceID returns the ID of the device, empty if it cannot be read
+func auditDeviceID(gateway Gatewayer) string {
+	msg, err := gateway.GetFeatures()
+	if err != nil || msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+		return ""
+	}
+
+	features := &messages.Features{}
+	if err := proto.Unmarshal(msg.Data, features); err != nil {
+		return ""
+	}
+
+	return features.GetDeviceId()
+}
+
+// operationOutcome returns the outcome of the last response of an operation, and its error message
+func operationOutcome(sw *sessionWriter) (string, string) {
+	switch sw.status {
+	case http.StatusOK:
+		return auditlog.OutcomeSuccess, ""
+	case http.StatusAccepted:
+		return auditlog.OutcomePendingApproval, ""
+	}
+
+	var rsp struct {
+		Error *HTTPError `json:"error"`
+	}
+	if err := json.Unmarshal(sw.body, &rsp); err != nil || rsp.Error == nil {
+		return auditlog.OutcomeFailure, http.StatusText(sw.status)
+	}
+
+	if sw.status == 499 || rsp.Error.Category == ErrorCategoryActionCancelled {
+		return auditlog.OutcomeCancelled, rsp.Error.Message
+	}
+
+	return auditlog.OutcomeFailure, rsp.Error.Message
+}
+
+// writeAuditLog writes an operation to the audit log file
+func writeAuditLog(log *auditlog.Log, r *http.Request, entry auditlog.Entry) {
+	if err := log.Write(entry); err != nil {
+		requestLogger(r).WithError(err).Error("failed to write the audit log")
+	}
+}
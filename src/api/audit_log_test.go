@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/auditlog"
+)
+
+func TestOperationAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit_log")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	key := []byte("key")
+	log, err := auditlog.Open(path, key)
+	require.NoError(t, err)
+
+	pinMatrixRequest := newReply(t, messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{})
+	success := newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: newStrPtr("PIN changed"),
+	})
+
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: newStrPtr("687576E45325EDC184C3B968"),
+	}), nil)
+	gateway.On("ChangePin", mock.Anything).Return(pinMatrixRequest, nil)
+	gateway.On("PinMatrixAck", "1234").Return(success, nil)
+	gateway.On("Cancel").Return(newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: newStrPtr("Action cancelled by user"),
+	}), nil)
+	gateway.On("Recovery", mock.Anything, mock.Anything, mock.Anything).Return(newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    messages.FailureType_Failure_NotInitialized.Enum(),
+		Message: newStrPtr("Device not initialized"),
+	}), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.auditLog = log
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, endpoint, body string) int {
+		req, err := http.NewRequest(method, "/api/v2"+endpoint, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		req.Header.Set("Origin", "http://127.0.0.1:9510")
+		req.RemoteAddr = "192.0.2.1:50000"
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// the PIN change is written once the PIN is entered
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/configure_pin_code", `{}`))
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/intermediate/pin_matrix", `{"pin":"1234"}`))
+
+	// the intermediate requests outside of an audited operation are not written
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/intermediate/pin_matrix", `{"pin":"1234"}`))
+
+	// canceled while waiting for the PIN
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/configure_pin_code", `{}`))
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/cancel", ""))
+
+	// abandoned for a recovery, which fails
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/configure_pin_code", `{}`))
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "/recovery", `{"word_count":12}`))
+
+	// the confirmation token of a wipe is not written
+	require.Equal(t, http.StatusAccepted, do(http.MethodDelete, "/wipe", ""))
+
+	// refused before the operation starts
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/configure_pin_code", `{`))
+
+	require.NoError(t, log.Close())
+	gateway.AssertNumberOfCalls(t, "GetFeatures", 5)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	n, err := auditlog.Verify(bytes.NewReader(b), key)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	var entries []auditlog.Entry
+	for _, l := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var line struct {
+			Entry auditlog.Entry `json:"entry"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(l), &line))
+		require.False(t, line.Entry.Time.IsZero())
+		require.Equal(t, auditlog.Source{
+			IP:     "192.0.2.1",
+			Origin: "http://127.0.0.1:9510",
+		}, line.Entry.Source)
+		require.Equal(t, "687576E45325EDC184C3B968", line.Entry.DeviceID)
+
+		line.Entry.Seq = 0
+		line.Entry.Time = time.Time{}
+		line.Entry.Source = auditlog.Source{}
+		line.Entry.DeviceID = ""
+		entries = append(entries, line.Entry)
+	}
+
+	expected := func(operation, endpoint, outcome string, status int, msg string) auditlog.Entry {
+		return auditlog.Entry{
+			Operation: operation,
+			Method:    http.MethodPost,
+			Endpoint:  endpoint,
+			Outcome:   outcome,
+			Status:    status,
+			Error:     msg,
+		}
+	}
+	require.Equal(t, []auditlog.Entry{
+		expected("pin_change", "/configure_pin_code", auditlog.OutcomeSuccess, http.StatusOK, ""),
+		expected("pin_change", "/configure_pin_code", auditlog.OutcomeCancelled, http.StatusOK, ""),
+		expected("pin_change", "/configure_pin_code", auditlog.OutcomeAbandoned, http.StatusOK, ""),
+		expected("recovery", "/recovery", auditlog.OutcomeFailure, http.StatusConflict, "Device not initialized"),
+		expected("pin_change", "/configure_pin_code", auditlog.OutcomeFailure, http.StatusBadRequest, "unexpected EOF"),
+	}, entries)
+}
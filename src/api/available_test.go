@@ -16,6 +16,7 @@ func TestAvailable(t *testing.T) {
 		status                 int
 		gatewayAvailableResult bool
 		httpResponse           HTTPResponse
+		apiVersion             string
 	}{
 		{
 			name:         "405",
@@ -33,6 +34,25 @@ func TestAvailable(t *testing.T) {
 				Data: []bool{true},
 			},
 		},
+
+		{
+			name:         "405 v2",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			apiVersion:   apiVersion2,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusMethodNotAllowed, ErrorCategoryMethodNotAllowed, ""),
+		},
+
+		{
+			name:                   "200 v2",
+			method:                 http.MethodGet,
+			status:                 http.StatusOK,
+			apiVersion:             apiVersion2,
+			gatewayAvailableResult: true,
+			httpResponse: HTTPResponse{
+				Data: []bool{true},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -42,7 +62,7 @@ func TestAvailable(t *testing.T) {
 
 			gateway.On("Available").Return(tc.gatewayAvailableResult, nil)
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, nil)
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), nil)
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
@@ -56,7 +76,7 @@ func TestAvailable(t *testing.T) {
 			err = json.NewDecoder(rr.Body).Decode(&rsp)
 			require.NoError(t, err)
 
-			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 			if rsp.Data == nil {
 				require.Nil(t, tc.httpResponse.Data)
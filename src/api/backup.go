@@ -22,7 +22,7 @@ func backup(gateway Gatewayer) http.HandlerFunc {
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("backup failed: %s", err.Error())
+				requestLogger(r).Error("backup failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -48,7 +48,7 @@ func backup(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("backup failed: %s", err.Error())
+			requestLogger(r).Errorf("backup failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -27,6 +27,7 @@ func TestBackup(t *testing.T) {
 		status              int
 		httpResponse        HTTPResponse
 		gatewayBackupResult wire.Message
+		apiVersion          string
 	}{
 		{
 			name:         "405",
@@ -45,6 +46,26 @@ func TestBackup(t *testing.T) {
 			},
 			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
 		},
+
+		{
+			name:         "405 v2",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			apiVersion:   apiVersion2,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusMethodNotAllowed, ErrorCategoryMethodNotAllowed, ""),
+		},
+
+		{
+			name:       "409 - Failure msg v2",
+			method:     http.MethodPost,
+			status:     http.StatusConflict,
+			apiVersion: apiVersion2,
+			gatewayBackupResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -54,7 +75,7 @@ func TestBackup(t *testing.T) {
 
 			gateway.On("Backup").Return(tc.gatewayBackupResult, nil)
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, nil)
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), nil)
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
@@ -68,7 +89,7 @@ func TestBackup(t *testing.T) {
 			err = json.NewDecoder(rr.Body).Decode(&rsp)
 			require.NoError(t, err)
 
-			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 			if rsp.Data == nil {
 				require.Nil(t, tc.httpResponse.Data)
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+)
+
+// URI: /api/v1/balance
+// Method: GET
+// Args:
+//
+//	addresses: comma separated addresses [required]
+func balanceHandler(client *node.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addrs, err := node.ParseAddresses(r.URL.Query().Get("addresses"))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		balance, err := client.Balance(addrs)
+		if err != nil {
+			writeNodeError(w, r, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: balance,
+		})
+	}
+}
+
+// writeNodeError writes the error of a node query: 429 with a Retry-After header when the rate limit of the node is
+// exceeded, 502 otherwise
+func writeNodeError(w http.ResponseWriter, r *http.Request, err error) {
+	if e, ok := err.(node.RateLimitError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.Wait.Seconds()))))
+		resp := NewHTTPErrorResponse(http.StatusTooManyRequests, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	requestLogger(r).WithError(err).Error("node query failed")
+	resp := NewHTTPErrorResponse(http.StatusBadGateway, err.Error())
+	writeHTTPResponse(w, resp)
+}
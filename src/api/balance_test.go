@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+)
+
+func TestBalance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/balance":
+			w.Write([]byte(`{"confirmed":{"coins":2000000,"hours":10},"predicted":{"coins":2000000,"hours":10},"addresses":{}}`))
+		case "/api/v1/outputs":
+			w.Write([]byte(`{"incoming_outputs":[],"outgoing_outputs":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := node.New(node.Config{
+		URL:   server.URL,
+		Rate:  0.001,
+		Burst: 1,
+	})
+	require.NoError(t, err)
+
+	cfg := defaultMuxConfig()
+	cfg.node = client
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(url string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := do("/api/v2/balance?addresses=2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var rsp struct {
+		Data node.AddressesBalance `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, uint64(2000000), rsp.Data.Confirmed.Coins)
+
+	// the cached answer does not count against the rate limit
+	rr = do("/api/v2/balance?addresses=2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = do("/api/v2/balance?addresses=2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	rr = do("/api/v2/balance?addresses=2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvH")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do("/api/v2/balance")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuth are the credentials of the HTTP basic authentication of the web interface
+type BasicAuth struct {
+	user         string
+	passwordHash []byte
+
+	// verified is the SHA-256 hash of the password last matched, so the requests of the web interface do not each
+	// compute the bcrypt hash
+	mu       sync.Mutex
+	verified []byte
+}
+
+// NewBasicAuth returns the credentials of user, whose password has the bcrypt hash passwordHash
+func NewBasicAuth(user, passwordHash string) (*BasicAuth, error) {
+	if user == "" || strings.Contains(user, ":") {
+		return nil, errors.New("the basic auth user must not be empty nor contain a colon")
+	}
+
+	if _, err := bcrypt.Cost([]byte(passwordHash)); err != nil {
+		return nil, errors.New("the basic auth password hash must be the bcrypt hash of the password")
+	}
+
+	return &BasicAuth{
+		user:         user,
+		passwordHash: []byte(passwordHash),
+	}, nil
+}
+
+// match returns true if user and password are the credentials
+func (a *BasicAuth) match(user, password string) bool {
+	if subtle.ConstantTimeCompare([]byte(user), []byte(a.user)) != 1 {
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(password))
+
+	a.mu.Lock()
+	verified := a.verified
+	a.mu.Unlock()
+	if verified != nil && subtle.ConstantTimeCompare(hash[:], verified) == 1 {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword(a.passwordHash, []byte(password)) != nil {
+		return false
+	}
+
+	a.mu.Lock()
+	a.verified = hash[:]
+	a.mu.Unlock()
+	return true
+}
+
+// bearerTokenEndpoint returns true for the endpoints checking a bearer token, the admin and approval endpoints
+func bearerTokenEndpoint(path string) bool {
+	for _, version := range []string{apiVersion1, apiVersion2} {
+		path = strings.TrimPrefix(path, "/api/"+version)
+	}
+	return strings.HasPrefix(path, "/admin/") || path == "/approvals" || strings.HasPrefix(path, "/approvals/")
+}
+
+// basicAuth serves the requests carrying the credentials in the Authorization header. The requests to the admin and
+// approval endpoints carrying one of the bearer tokens of the daemon are served too, these endpoints cannot also read
+// the basic credentials in the header. The preflight requests, which browsers send without credentials, and the
+// liveness and readiness probes are not checked.
+func basicAuth(auth *BasicAuth, tokens []string, handler http.Handler) http.Handler {
+	if auth == nil {
+		return handler
+	}
+
+	isToken := func(token string) bool {
+		for _, t := range tokens {
+			if t != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodOptions,
+			r.URL.Path == "/live", r.URL.Path == "/ready":
+		case strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") && bearerTokenEndpoint(r.URL.Path):
+			if !isToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+				refuseBasicAuth(w, r)
+				return
+			}
+		default:
+			user, password, ok := r.BasicAuth()
+			if !ok || !auth.match(user, password) {
+				refuseBasicAuth(w, r)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// refuseBasicAuth refuses a request without the basic auth credentials with a 401
+func refuseBasicAuth(w http.ResponseWriter, r *http.Request) {
+	criticalRequestLogger(r).Errorf("Invalid basic auth credentials for %s %s from %s", r.Method, r.URL.Path, clientIP(r))
+	w.Header().Set("WWW-Authenticate", `Basic realm="hardware-wallet-daemon", charset="UTF-8"`)
+	resp := NewHTTPErrorResponse(http.StatusUnauthorized, "invalid credentials")
+	writeHTTPResponse(w, resp)
+}
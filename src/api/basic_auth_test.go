@@ -0,0 +1,157 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	auth, err := NewBasicAuth("wallet", string(hash))
+	require.NoError(t, err)
+
+	handler := basicAuth(auth, []string{"admin-token", ""}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{
+			name:   "credentials",
+			setup:  func(r *http.Request) { r.SetBasicAuth("wallet", "s3cret") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "wrong password",
+			setup:  func(r *http.Request) { r.SetBasicAuth("wallet", "other") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong user",
+			setup:  func(r *http.Request) { r.SetBasicAuth("other", "s3cret") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "no credentials",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "credentials again",
+			setup:  func(r *http.Request) { r.SetBasicAuth("wallet", "s3cret") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "wrong password after the credentials",
+			setup:  func(r *http.Request) { r.SetBasicAuth("wallet", "other") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "bearer token admin",
+			path:   "/api/v2/admin/mode",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "bearer token approvals",
+			path:   "/api/v1/approvals",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "bearer token approval decision",
+			path:   "/api/v2/approvals/approve",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "bearer token device endpoint",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "bearer token approvals prefix",
+			path:   "/api/v2/approvalsx",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "invalid bearer token",
+			path:   "/api/v2/admin/mode",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "empty bearer token",
+			path:   "/api/v2/admin/mode",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer ") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			status: http.StatusOK,
+		},
+		{
+			name:   "readiness probe",
+			path:   "/ready",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			path := tc.path
+			if path == "" {
+				path = "/api/v2/features"
+			}
+
+			req, err := http.NewRequest(method, path, nil)
+			require.NoError(t, err)
+			if tc.setup != nil {
+				tc.setup(req)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+			if tc.status == http.StatusUnauthorized {
+				require.Equal(t, `Basic realm="hardware-wallet-daemon", charset="UTF-8"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestNewBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	_, err = NewBasicAuth("", string(hash))
+	require.EqualError(t, err, "the basic auth user must not be empty nor contain a colon")
+
+	_, err = NewBasicAuth("wal:let", string(hash))
+	require.EqualError(t, err, "the basic auth user must not be empty nor contain a colon")
+
+	_, err = NewBasicAuth("wallet", "s3cret")
+	require.EqualError(t, err, "the basic auth password hash must be the bcrypt hash of the password")
+
+	// the unsalted SHA-256 hashes are refused
+	sha := sha256.Sum256([]byte("s3cret"))
+	_, err = NewBasicAuth("wallet", hex.EncodeToString(sha[:]))
+	require.EqualError(t, err, "the basic auth password hash must be the bcrypt hash of the password")
+}
@@ -5,11 +5,16 @@ import (
 	messages "github.com/skycoin/hardware-wallet-protob/go"
 
 	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
 )
 
+// cancel sends a Cancel to the device, ending the flow waiting for the user input, and aborts the operation in
+// progress if it does not end within grace
 // URI: /api/v1/cancel
 // Method: PUT
-func cancel(gateway Gatewayer) http.HandlerFunc {
+func cancel(gateway Gatewayer, lock *devicelock.Lock, grace time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -21,7 +26,7 @@ func cancel(gateway Gatewayer) http.HandlerFunc {
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("cancel failed: %s", err.Error())
+				requestLogger(r).Error("cancel failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -29,8 +34,10 @@ func cancel(gateway Gatewayer) http.HandlerFunc {
 		}
 
 		msg, err := gateway.Cancel()
+		// the operation in progress is aborted whether the device answered the Cancel or not
+		abortOperation(r, lock, grace)
 		if err != nil {
-			logger.Errorf("cancel failed: %s", err.Error())
+			requestLogger(r).Errorf("cancel failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 			return
@@ -39,7 +46,7 @@ func cancel(gateway Gatewayer) http.HandlerFunc {
 		if msg.Kind == uint16(messages.MessageType_MessageType_Failure) {
 			failureMsg, err := skyWallet.DecodeFailMsg(msg)
 			if err != nil {
-				logger.Errorf("cancel failed: %s", err.Error())
+				requestLogger(r).Errorf("cancel failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -26,6 +26,7 @@ func TestCancel(t *testing.T) {
 		status              int
 		httpResponse        HTTPResponse
 		gatewayCancelResult wire.Message
+		apiVersion          string
 	}{
 		{
 			name:         "405",
@@ -46,6 +47,28 @@ func TestCancel(t *testing.T) {
 				Data: []string{"Action canceled by User"},
 			},
 		},
+
+		{
+			name:         "405 v2",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			apiVersion:   apiVersion2,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusMethodNotAllowed, ErrorCategoryMethodNotAllowed, ""),
+		},
+
+		{
+			name:       "200 - OK v2",
+			method:     http.MethodPut,
+			status:     http.StatusOK,
+			apiVersion: apiVersion2,
+			gatewayCancelResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: msgBytes,
+			},
+			httpResponse: HTTPResponse{
+				Data: []string{"Action canceled by User"},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -55,7 +78,7 @@ func TestCancel(t *testing.T) {
 
 			gateway.On("Cancel").Return(tc.gatewayCancelResult, nil)
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, nil)
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), nil)
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
@@ -69,7 +92,7 @@ func TestCancel(t *testing.T) {
 			err = json.NewDecoder(rr.Body).Decode(&rsp)
 			require.NoError(t, err)
 
-			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 			if rsp.Data == nil {
 				require.Nil(t, tc.httpResponse.Data)
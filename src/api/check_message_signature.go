@@ -7,7 +7,8 @@ import (
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
-	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
 )
 
 // CheckMessageSignatureRequest is request data for /api/v1/check_message_signature
@@ -21,7 +22,7 @@ type CheckMessageSignatureRequest struct {
 // Method: POST
 // Content-Type: application/json
 // Args: JSON Body
-func checkMessageSignature(gateway Gatewayer) http.HandlerFunc {
+func checkMessageSignature(gateway Gatewayer, backend coin.Backend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -43,28 +44,8 @@ func checkMessageSignature(gateway Gatewayer) http.HandlerFunc {
 		}
 		defer r.Body.Close()
 
-		if req.Address == "" {
-			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
-			writeHTTPResponse(w, resp)
-			return
-		}
-
-		_, err := cipher.DecodeBase58Address(req.Address)
-		if err != nil {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
-			writeHTTPResponse(w, resp)
-			return
-		}
-
-		if req.Signature == "" {
-			resp := NewHTTPErrorResponse(http.StatusBadRequest, "signature is required")
-			writeHTTPResponse(w, resp)
-			return
-		}
-
-		if req.Message == "" {
-			resp := NewHTTPErrorResponse(http.StatusBadRequest, "message is required")
-			writeHTTPResponse(w, resp)
+		if err := req.validate(backend); err != nil {
+			writeValidationError(w, err)
 			return
 		}
 
@@ -72,7 +53,7 @@ func checkMessageSignature(gateway Gatewayer) http.HandlerFunc {
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("checkMessageSignature failed: %s", err.Error())
+				requestLogger(r).Error("checkMessageSignature failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -80,6 +61,7 @@ func checkMessageSignature(gateway Gatewayer) http.HandlerFunc {
 		}
 
 		var msg wire.Message
+		var err error
 		retCH := make(chan int)
 		errCH := make(chan int)
 		ctx := r.Context()
@@ -97,7 +79,7 @@ func checkMessageSignature(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("checkMessageSignature failed: %s", err.Error())
+			requestLogger(r).Errorf("checkMessageSignature failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -29,6 +29,7 @@ func TestCheckMessageSignature(t *testing.T) {
 		httpBody                           string
 		gatewayCheckMessageSignatureResult wire.Message
 		httpResponse                       HTTPResponse
+		apiVersion                         string
 	}{
 		{
 			name:         "405",
@@ -139,6 +140,46 @@ func TestCheckMessageSignature(t *testing.T) {
 			},
 			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
 		},
+
+		{
+			name:         "415 - Unsupported Media Type v2",
+			method:       http.MethodPost,
+			contentType:  ContentTypeForm,
+			status:       http.StatusUnsupportedMediaType,
+			apiVersion:   apiVersion2,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusUnsupportedMediaType, ErrorCategoryUnsupportedMediaType, ""),
+		},
+
+		{
+			name:        "400 - Address missing v2",
+			method:      http.MethodPost,
+			contentType: ContentTypeJSON,
+			status:      http.StatusBadRequest,
+			apiVersion:  apiVersion2,
+			httpBody: toJSON(t, &CheckMessageSignatureRequest{
+				Message:   "foo",
+				Signature: "GvKS4S3CA2YTpEPFA47yFdC5CP3y3qB18jwiX1URXqWQTvMjokd3A4upPz4wyeAyKJEtRdRDGUvUgoGASpsTTUeMn",
+			}),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusBadRequest, ErrorCategoryInvalidRequest, "address is required"),
+		},
+
+		{
+			name:        "409 - Failure msg v2",
+			method:      http.MethodPost,
+			status:      http.StatusConflict,
+			contentType: ContentTypeJSON,
+			apiVersion:  apiVersion2,
+			httpBody: toJSON(t, &CheckMessageSignatureRequest{
+				Address:   "u37EnnuQ4g58sWpd5Ns3FWGPwSgEuQGFBd",
+				Signature: "GvKS4S3CA2YTpEPFA47yFdC5CP3y3qB18jwiX1URXqWQTvMjokd3A4upPz4wyeAyKJEtRdRDGUvUgoGASpsTTUeMn",
+				Message:   "Hello World!",
+			}),
+			gatewayCheckMessageSignatureResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -152,7 +193,7 @@ func TestCheckMessageSignature(t *testing.T) {
 				gateway.On("CheckMessageSignature", body.Message, body.Signature, body.Address).Return(tc.gatewayCheckMessageSignatureResult, nil)
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
@@ -173,7 +214,7 @@ func TestCheckMessageSignature(t *testing.T) {
 			err = json.NewDecoder(rr.Body).Decode(&rsp)
 			require.NoError(t, err)
 
-			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 			if rsp.Data == nil {
 				require.Nil(t, tc.httpResponse.Data)
@@ -43,7 +43,7 @@ func configurePinCode(gateway Gatewayer) http.HandlerFunc {
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("configurePinCode failed: %s", err.Error())
+				requestLogger(r).Error("configurePinCode failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -69,7 +69,7 @@ func configurePinCode(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("configurePinCode failed: %s", err.Error())
+			requestLogger(r).Errorf("configurePinCode failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
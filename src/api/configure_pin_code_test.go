@@ -36,6 +36,7 @@ func TestConfigurePinCode(t *testing.T) {
 		httpBody                      string
 		gatewayConfigurePinCodeResult wire.Message
 		httpResponse                  HTTPResponse
+		apiVersion                    string
 	}{
 		{
 			name:         "405",
@@ -119,6 +120,36 @@ func TestConfigurePinCode(t *testing.T) {
 				Data: []string{"configure pin code success msg"},
 			},
 		},
+
+		{
+			name:       "409 - Failure msg v2",
+			method:     http.MethodPost,
+			status:     http.StatusConflict,
+			apiVersion: apiVersion2,
+			httpBody: toJSON(t, &ConfigurePinCodeRequest{
+				RemovePin: false,
+			}),
+			gatewayConfigurePinCodeResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
+
+		{
+			name:       "409 - Failure msg with remove pin v2",
+			method:     http.MethodPost,
+			status:     http.StatusConflict,
+			apiVersion: apiVersion2,
+			httpBody: toJSON(t, &ConfigurePinCodeRequest{
+				RemovePin: true,
+			}),
+			gatewayConfigurePinCodeResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -132,7 +163,7 @@ func TestConfigurePinCode(t *testing.T) {
 				gateway.On("ChangePin", newBoolPtr(body.RemovePin)).Return(tc.gatewayConfigurePinCodeResult, nil)
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
@@ -153,7 +184,7 @@ func TestConfigurePinCode(t *testing.T) {
 			err = json.NewDecoder(rr.Body).Decode(&rsp)
 			require.NoError(t, err)
 
-			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 			if rsp.Data == nil {
 				require.Nil(t, tc.httpResponse.Data)
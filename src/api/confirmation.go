@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultConfirmationTimeout is how long a confirmation token stays valid by default
+	DefaultConfirmationTimeout = time.Minute
+
+	confirmationTokenSize = 16
+)
+
+type confirmation struct {
+	action  string
+	expires time.Time
+}
+
+// confirmationTokens issues one-time tokens that must be echoed back to perform a destructive action
+type confirmationTokens struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	tokens  map[string]confirmation
+	now     func() time.Time
+}
+
+func newConfirmationTokens(timeout time.Duration) *confirmationTokens {
+	if timeout <= 0 {
+		timeout = DefaultConfirmationTimeout
+	}
+
+	return &confirmationTokens{
+		timeout: timeout,
+		tokens:  make(map[string]confirmation),
+		now:     time.Now,
+	}
+}
+
+// issue creates a token for action, returning it with its expiry time
+func (c *confirmationTokens) issue(action string) (string, time.Time, error) {
+	b := make([]byte, confirmationTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(b)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune()
+
+	expires := c.now().Add(c.timeout)
+	c.tokens[token] = confirmation{
+		action:  action,
+		expires: expires,
+	}
+
+	return token, expires, nil
+}
+
+// redeem consumes the token, returning false if it was not issued for action or has expired.
+// A token can only be redeemed once, whether it is valid or not.
+func (c *confirmationTokens) redeem(action, token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conf, ok := c.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(c.tokens, token)
+
+	return conf.action == action && c.now().Before(conf.expires)
+}
+
+// prune removes expired tokens, must be called with the lock held
+func (c *confirmationTokens) prune() {
+	now := c.now()
+	for token, conf := range c.tokens {
+		if !now.Before(conf.expires) {
+			delete(c.tokens, token)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmationTokens(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newConfirmationTokens(time.Minute)
+	c.now = func() time.Time {
+		return now
+	}
+
+	token, expires, err := c.issue("wipe")
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Minute), expires)
+
+	// tokens are bound to an action and consumed by any redeem attempt
+	require.False(t, c.redeem("other", token))
+	require.False(t, c.redeem("wipe", token))
+
+	token, _, err = c.issue("wipe")
+	require.NoError(t, err)
+	require.False(t, c.redeem("wipe", "unknown"))
+	require.True(t, c.redeem("wipe", token))
+	require.False(t, c.redeem("wipe", token))
+
+	// expired tokens are rejected
+	token, _, err = c.issue("wipe")
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	require.False(t, c.redeem("wipe", token))
+
+	// expired tokens are pruned when issuing new ones
+	_, _, err = c.issue("wipe")
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, _, err = c.issue("wipe")
+	require.NoError(t, err)
+	require.Len(t, c.tokens, 1)
+}
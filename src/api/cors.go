@@ -0,0 +1,204 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/cors"
+)
+
+const (
+	// corsMaxAge is how long browsers may cache a preflight response, in seconds
+	corsMaxAge = 600
+)
+
+// DefaultCORSOrigins are the origins of the Skycoin web wallet, allowed by default
+var DefaultCORSOrigins = []string{
+	"https://wallet.skycoin.net",
+	"https://staging.wallet.skycoin.net",
+}
+
+// CORSConfig configures the cross-origin requests of browser wallets.
+// The localhost origins and the origins of the whitelisted hosts are always allowed.
+type CORSConfig struct {
+	// Origins are the allowed origins, in the scheme://host[:port] form.
+	// A host starting with *. matches its subdomains, e.g. https://*.example.com.
+	Origins []string
+	// AllowCredentials lets the origins listed without wildcard send cookies and HTTP authentication.
+	// Credentials are never allowed to the localhost origins nor the whitelisted hosts.
+	AllowCredentials bool
+}
+
+// Validate checks the origins, credentials cannot be allowed with wildcard origins
+func (c CORSConfig) Validate() error {
+	for _, o := range c.Origins {
+		origin, err := ParseCORSOrigin(o)
+		if err != nil {
+			return err
+		}
+
+		if c.AllowCredentials && isWildcardOrigin(origin) {
+			return fmt.Errorf("credentials cannot be allowed to the wildcard origin %q", o)
+		}
+	}
+
+	return nil
+}
+
+// ParseCORSOrigin checks that origin is an http or https origin and returns it lowercased, without its default port
+func ParseCORSOrigin(origin string) (string, error) {
+	if origin == "*" {
+		return "", fmt.Errorf("invalid CORS origin %q, list the allowed origins instead", origin)
+	}
+
+	u, err := url.Parse(strings.ToLower(strings.TrimSpace(origin)))
+	if err != nil {
+		return "", fmt.Errorf("invalid CORS origin %q: %v", origin, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid CORS origin %q, the scheme must be http or https", origin)
+	}
+
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid CORS origin %q, must be scheme://host[:port]", origin)
+	}
+
+	host := u.Host
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return "", fmt.Errorf("invalid CORS origin %q, only a leading *. wildcard is supported", origin)
+	}
+
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		host = strings.TrimSuffix(host, ":"+u.Port())
+	}
+
+	return u.Scheme + "://" + host, nil
+}
+
+func isWildcardOrigin(origin string) bool {
+	return strings.Contains(origin, "://*.")
+}
+
+// corsOrigins matches origins against an allowlist
+type corsOrigins struct {
+	exact     map[string]struct{}
+	wildcards []corsWildcard
+}
+
+// corsWildcard matches the origins of scheme with a host ending with suffix, e.g. https:// and .example.com
+type corsWildcard struct {
+	scheme string
+	suffix string
+}
+
+// newCORSOrigins creates the matcher of origins, invalid origins are ignored
+func newCORSOrigins(origins []string) *corsOrigins {
+	o := &corsOrigins{
+		exact: make(map[string]struct{}, len(origins)),
+	}
+
+	for _, origin := range origins {
+		origin, err := ParseCORSOrigin(origin)
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+
+		if isWildcardOrigin(origin) {
+			i := strings.Index(origin, "://*.")
+			o.wildcards = append(o.wildcards, corsWildcard{
+				scheme: origin[:i+len("://")],
+				suffix: origin[i+len("://*"):],
+			})
+		} else {
+			o.exact[origin] = struct{}{}
+		}
+	}
+
+	return o
+}
+
+// match returns true if origin is allowed
+func (o *corsOrigins) match(origin string) bool {
+	origin, err := ParseCORSOrigin(origin)
+	if err != nil || isWildcardOrigin(origin) {
+		return false
+	}
+
+	if _, ok := o.exact[origin]; ok {
+		return true
+	}
+
+	for _, w := range o.wildcards {
+		if strings.HasPrefix(origin, w.scheme) && strings.HasSuffix(origin, w.suffix) && len(origin) > len(w.scheme)+len(w.suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchExact returns true if origin is allowed without wildcard
+func (o *corsOrigins) matchExact(origin string) bool {
+	origin, err := ParseCORSOrigin(origin)
+	if err != nil {
+		return false
+	}
+
+	_, ok := o.exact[origin]
+	return ok
+}
+
+// corsPolicy answers the preflight requests and sets the CORS headers of the responses.
+// The origins allowed credentials are served by a separate handler, so the others never get them.
+type corsPolicy struct {
+	cors             *cors.Cors
+	credentials      *cors.Cors
+	allowCredentials func(origin string) bool
+}
+
+func newCORSPolicy(allowOrigin, allowCredentials func(origin string) bool, allowedHeaders, exposedHeaders []string) *corsPolicy {
+	options := cors.Options{
+		AllowOriginFunc:    allowOrigin,
+		Debug:              false,
+		AllowedMethods:     []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPut},
+		AllowedHeaders:     allowedHeaders,
+		ExposedHeaders:     exposedHeaders,
+		MaxAge:             corsMaxAge,
+		AllowCredentials:   false,
+		OptionsPassthrough: false,
+	}
+
+	p := &corsPolicy{
+		cors:             cors.New(options),
+		allowCredentials: allowCredentials,
+	}
+
+	if allowCredentials != nil {
+		options.AllowOriginFunc = allowCredentials
+		options.AllowCredentials = true
+		p.credentials = cors.New(options)
+	}
+
+	return p
+}
+
+// handler wraps handler with the CORS handling of the request origin
+func (p *corsPolicy) handler(handler http.Handler) http.Handler {
+	h := p.cors.Handler(handler)
+	if p.credentials == nil {
+		return h
+	}
+
+	credentialsHandler := p.credentials.Handler(handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.allowCredentials(r.Header.Get("Origin")) {
+			credentialsHandler.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
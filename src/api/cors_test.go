@@ -0,0 +1,185 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCORSOrigin(t *testing.T) {
+	cases := []struct {
+		origin string
+		parsed string
+		err    string
+	}{
+		{
+			origin: "https://wallet.example.com",
+			parsed: "https://wallet.example.com",
+		},
+		{
+			origin: " HTTPS://Wallet.Example.com:443/ ",
+			parsed: "https://wallet.example.com",
+		},
+		{
+			origin: "http://localhost:8080",
+			parsed: "http://localhost:8080",
+		},
+		{
+			origin: "https://*.example.com",
+			parsed: "https://*.example.com",
+		},
+		{
+			origin: "*",
+			err:    `invalid CORS origin "*", list the allowed origins instead`,
+		},
+		{
+			origin: "wallet.example.com",
+			err:    `invalid CORS origin "wallet.example.com", the scheme must be http or https`,
+		},
+		{
+			origin: "https://wallet.example.com/index.html",
+			err:    `invalid CORS origin "https://wallet.example.com/index.html", must be scheme://host[:port]`,
+		},
+		{
+			origin: "https://user@wallet.example.com",
+			err:    `invalid CORS origin "https://user@wallet.example.com", must be scheme://host[:port]`,
+		},
+		{
+			origin: "https://wallet.*.com",
+			err:    `invalid CORS origin "https://wallet.*.com", only a leading *. wildcard is supported`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.origin, func(t *testing.T) {
+			parsed, err := ParseCORSOrigin(tc.origin)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.parsed, parsed)
+		})
+	}
+}
+
+func TestCORSConfigValidate(t *testing.T) {
+	require.NoError(t, CORSConfig{Origins: DefaultCORSOrigins, AllowCredentials: true}.Validate())
+	require.NoError(t, CORSConfig{Origins: []string{"https://*.example.com"}}.Validate())
+	require.EqualError(t, CORSConfig{Origins: []string{"ftp://example.com"}}.Validate(),
+		`invalid CORS origin "ftp://example.com", the scheme must be http or https`)
+	require.EqualError(t, CORSConfig{Origins: []string{"https://*.example.com"}, AllowCredentials: true}.Validate(),
+		`credentials cannot be allowed to the wildcard origin "https://*.example.com"`)
+}
+
+func TestCORSOrigins(t *testing.T) {
+	o := newCORSOrigins([]string{"https://wallet.example.com", "https://*.example.org", "invalid"})
+
+	require.True(t, o.match("https://wallet.example.com"))
+	require.True(t, o.match("https://wallet.example.com:443"))
+	require.False(t, o.match("http://wallet.example.com"))
+	require.False(t, o.match("https://wallet.example.com.evil.com"))
+
+	require.True(t, o.match("https://wallet.example.org"))
+	require.True(t, o.match("https://a.b.example.org"))
+	require.False(t, o.match("https://example.org"))
+	require.False(t, o.match("https://evilexample.org"))
+	require.False(t, o.match("https://*.example.org"))
+	require.False(t, o.match("https://evil.com#.example.org"))
+	require.False(t, o.match("null"))
+
+	require.True(t, o.matchExact("https://wallet.example.com"))
+	require.False(t, o.matchExact("https://wallet.example.org"))
+}
+
+func TestCORSConfiguredOrigins(t *testing.T) {
+	cfg := defaultMuxConfig()
+	cfg.enableCSRF = true
+	cfg.cors = CORSConfig{
+		Origins:          []string{"https://wallet.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+	}
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method, endpoint, origin string) *http.Response {
+		req, err := http.NewRequest(method, endpoint, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", CSRFHeaderName)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Result()
+	}
+
+	cases := []struct {
+		name        string
+		origin      string
+		allowed     bool
+		credentials bool
+	}{
+		{
+			name:        "configured origin",
+			origin:      "https://wallet.example.com",
+			allowed:     true,
+			credentials: true,
+		},
+		{
+			name:    "wildcard origin",
+			origin:  "https://beta.example.org",
+			allowed: true,
+		},
+		{
+			name:    "localhost origin",
+			origin:  "http://localhost:4000",
+			allowed: true,
+		},
+		{
+			name:   "default origin no longer configured",
+			origin: "https://wallet.skycoin.net",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := do(http.MethodOptions, "/api/v2/generate_addresses", tc.origin)
+
+			if !tc.allowed {
+				require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+				require.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+
+				// the requests from the origins which are not allowed are rejected
+				resp = do(http.MethodPost, "/api/v2/generate_addresses", tc.origin)
+				require.Equal(t, http.StatusForbidden, resp.StatusCode)
+				return
+			}
+
+			require.Equal(t, tc.origin, resp.Header.Get("Access-Control-Allow-Origin"))
+			require.Equal(t, http.MethodPost, resp.Header.Get("Access-Control-Allow-Methods"))
+			require.Equal(t, http.CanonicalHeaderKey(CSRFHeaderName), resp.Header.Get("Access-Control-Allow-Headers"))
+			require.Equal(t, strconv.Itoa(corsMaxAge), resp.Header.Get("Access-Control-Max-Age"))
+			if tc.credentials {
+				require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+			} else {
+				require.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+			}
+
+			// the CSRF error of the request carries the CORS headers
+			resp = do(http.MethodPost, "/api/v2/generate_addresses", tc.origin)
+			require.Equal(t, http.StatusForbidden, resp.StatusCode)
+			require.Equal(t, tc.origin, resp.Header.Get("Access-Control-Allow-Origin"))
+			require.Equal(t, "Retry-After, "+ModelVersionHeaderName+", Deprecation, Sunset, Link, Etag", resp.Header.Get("Access-Control-Expose-Headers"))
+
+			// the origin may read the CSRF token
+			resp = do(http.MethodGet, "/api/v2/csrf", tc.origin)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, tc.origin, resp.Header.Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
 	"crypto/hmac"
@@ -22,6 +23,15 @@ const (
 	// CSRFMaxAge is the lifetime of a CSRF token in seconds
 	CSRFMaxAge = time.Second * 30
 
+	// CSRFCookieName is the name of the SameSite cookie carrying the CSRF token in the cookie mode
+	CSRFCookieName = "csrf_token"
+
+	// CSRFModeHeader checks the token of the X-CSRF-Token header
+	CSRFModeHeader = "header"
+	// CSRFModeCookie also accepts the token of the SameSite cookie set with it, which browsers only send from a page of
+	// the same site
+	CSRFModeCookie = "cookie"
+
 	csrfSecretLength = 64
 
 	csrfNonceLength = 64
@@ -34,8 +44,38 @@ var (
 	ErrCSRFInvalidSignature = errors.New("invalid CSRF token signature")
 	// ErrCSRFExpired is returned when the csrf token has expired
 	ErrCSRFExpired = errors.New("csrf token expired")
+	// ErrCSRFSessionMismatch is returned when the csrf token was created for another session
+	ErrCSRFSessionMismatch = errors.New("csrf token of another session")
+	// ErrCSRFUsed is returned when the csrf token was already used by a request, with the rotation of the tokens
+	ErrCSRFUsed = errors.New("csrf token already used")
 )
 
+// CSRFConfig configures the CSRF tokens
+type CSRFConfig struct {
+	// MaxAge is the lifetime of a token, CSRFMaxAge if 0
+	MaxAge time.Duration
+	// BindSession refuses the tokens created for another session than the one of the request
+	BindSession bool
+	// Rotate accepts a token for a single state-changing request, whose response carries the next token in the
+	// X-CSRF-Token header
+	Rotate bool
+	// Mode is CSRFModeHeader or CSRFModeCookie, CSRFModeHeader if empty
+	Mode string
+}
+
+// Validate checks the mode and the lifetime of the tokens
+func (c CSRFConfig) Validate() error {
+	switch c.Mode {
+	case "", CSRFModeHeader, CSRFModeCookie:
+	default:
+		return fmt.Errorf("invalid CSRF mode %q, choices are %s and %s", c.Mode, CSRFModeHeader, CSRFModeCookie)
+	}
+	if c.MaxAge < 0 {
+		return errors.New("CSRF max age must not be negative")
+	}
+	return nil
+}
+
 var csrfSecretKey []byte
 
 func init() {
@@ -46,6 +86,8 @@ func init() {
 type CSRFToken struct {
 	Nonce     []byte
 	ExpiresAt time.Time
+	// Session is the ID of the session the token was created for
+	Session string `json:",omitempty"`
 }
 
 // newCSRFToken generates a new CSRF Token
@@ -54,9 +96,15 @@ func newCSRFToken() (string, error) {
 }
 
 func newCSRFTokenWithTime(expiresAt time.Time) (string, error) {
+	return newSessionCSRFToken(expiresAt, "")
+}
+
+// newSessionCSRFToken generates a new CSRF Token of the session
+func newSessionCSRFToken(expiresAt time.Time, session string) (string, error) {
 	token := &CSRFToken{
 		Nonce:     cipher.RandByte(csrfNonceLength),
 		ExpiresAt: expiresAt,
+		Session:   session,
 	}
 
 	tokenJSON, err := json.Marshal(token)
@@ -79,47 +127,146 @@ func newCSRFTokenWithTime(expiresAt time.Time) (string, error) {
 
 // verifyCSRFToken checks validity of the given token
 func verifyCSRFToken(headerToken string) error {
+	_, err := parseCSRFToken(headerToken)
+	return err
+}
+
+// parseCSRFToken returns the token if it is valid
+func parseCSRFToken(headerToken string) (*CSRFToken, error) {
 	tokenParts := strings.Split(headerToken, ".")
 	if len(tokenParts) != 2 {
-		return ErrCSRFInvalid
+		return nil, ErrCSRFInvalid
 	}
 
 	signingString, err := base64.RawURLEncoding.DecodeString(tokenParts[0])
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	h := hmac.New(sha256.New, csrfSecretKey)
 	_, err = h.Write([]byte(signingString))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sig := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 
 	if sig != tokenParts[1] {
-		return ErrCSRFInvalidSignature
+		return nil, ErrCSRFInvalidSignature
 	}
 
 	var csrfToken CSRFToken
 	err = json.Unmarshal(signingString, &csrfToken)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if time.Now().After(csrfToken.ExpiresAt) {
-		return ErrCSRFExpired
+		return nil, ErrCSRFExpired
+	}
+
+	return &csrfToken, nil
+}
+
+// csrfTokens creates and checks the CSRF tokens with a config, and records the tokens used when they are rotated
+type csrfTokens struct {
+	config CSRFConfig
+
+	mu        sync.Mutex
+	used      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newCSRFTokens(c CSRFConfig) *csrfTokens {
+	if c.MaxAge == 0 {
+		c.MaxAge = CSRFMaxAge
+	}
+	if c.Mode == "" {
+		c.Mode = CSRFModeHeader
+	}
+
+	return &csrfTokens{
+		config: c,
+		used:   make(map[string]time.Time),
+	}
+}
+
+// create returns a new token for the session of the request
+func (t *csrfTokens) create(r *http.Request) (string, error) {
+	var session string
+	if t.config.BindSession {
+		session = r.Header.Get(SessionHeaderName)
+	}
+	return newSessionCSRFToken(time.Now().Add(t.config.MaxAge), session)
+}
+
+// verify checks the token of the request, and records it as used when the tokens are rotated
+func (t *csrfTokens) verify(r *http.Request) error {
+	token := r.Header.Get(CSRFHeaderName)
+	if token == "" && t.config.Mode == CSRFModeCookie {
+		if cookie, err := r.Cookie(CSRFCookieName); err == nil {
+			token = cookie.Value
+		}
+	}
+
+	csrfToken, err := parseCSRFToken(token)
+	if err != nil {
+		return err
 	}
 
+	if t.config.BindSession && csrfToken.Session != r.Header.Get(SessionHeaderName) {
+		return ErrCSRFSessionMismatch
+	}
+
+	if !t.config.Rotate {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// the used tokens are forgotten once they expired
+	now := time.Now()
+	if now.Sub(t.lastPrune) >= t.config.MaxAge {
+		for nonce, expiresAt := range t.used {
+			if now.After(expiresAt) {
+				delete(t.used, nonce)
+			}
+		}
+		t.lastPrune = now
+	}
+
+	nonce := string(csrfToken.Nonce)
+	if _, ok := t.used[nonce]; ok {
+		return ErrCSRFUsed
+	}
+	t.used[nonce] = csrfToken.ExpiresAt
 	return nil
 }
 
+// write sets the token on the response: in the X-CSRF-Token header, and in the cookie in the cookie mode
+func (t *csrfTokens) write(w http.ResponseWriter, r *http.Request, token string) {
+	w.Header().Set(CSRFHeaderName, token)
+
+	if t.config.Mode == CSRFModeCookie {
+		http.SetCookie(w, &http.Cookie{
+			Name:     CSRFCookieName,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int(t.config.MaxAge / time.Second),
+			HttpOnly: true,
+			Secure:   requestScheme(r) == "https",
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
+
 // Creates a new CSRF token. Previous CSRF tokens are invalidated by this call.
 // URI: /api/v1/csrf
 // Method: GET
 // Response:
 //  csrf_token: CSRF token to use in POST requests
-func getCSRFToken(enabled bool) http.HandlerFunc {
+func getCSRFToken(tokens *csrfTokens) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -127,7 +274,7 @@ func getCSRFToken(enabled bool) http.HandlerFunc {
 			return
 		}
 
-		if !enabled {
+		if tokens == nil {
 			logger.Warning("CSRF check disabled")
 			resp := NewHTTPErrorResponse(http.StatusNotFound, "")
 			writeHTTPResponse(w, resp)
@@ -135,7 +282,7 @@ func getCSRFToken(enabled bool) http.HandlerFunc {
 		}
 
 		// generate a new token
-		csrfToken, err := newCSRFToken()
+		csrfToken, err := tokens.create(r)
 		if err != nil {
 			logger.Error(err)
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, fmt.Sprintf("Failed to create a csrf token: %v", err))
@@ -143,6 +290,10 @@ func getCSRFToken(enabled bool) http.HandlerFunc {
 			return
 		}
 
+		if tokens.config.Mode == CSRFModeCookie {
+			tokens.write(w, r, csrfToken)
+		}
+
 		writeHTTPResponse(w, HTTPResponse{
 			Data: csrfToken,
 		})
@@ -151,17 +302,34 @@ func getCSRFToken(enabled bool) http.HandlerFunc {
 
 // CSRFCheck verifies X-CSRF-Token header value
 func CSRFCheck(enabled bool, handler http.Handler) http.Handler {
+	if !enabled {
+		return handler
+	}
+	return csrfCheck(newCSRFTokens(CSRFConfig{}), handler)
+}
+
+// csrfCheck verifies the CSRF token of the state-changing requests, nil tokens disable the check.
+// With the rotation of the tokens, the response carries the next token.
+func csrfCheck(tokens *csrfTokens, handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if enabled {
+		if tokens != nil {
 			switch r.Method {
 			case http.MethodPost, http.MethodPut, http.MethodDelete:
-				token := r.Header.Get(CSRFHeaderName)
-				if err := verifyCSRFToken(token); err != nil {
-					logger.Errorf("CSRF token invalid: %v", err)
+				if err := tokens.verify(r); err != nil {
+					requestLogger(r).Errorf("CSRF token invalid: %v", err)
 					resp := NewHTTPErrorResponse(http.StatusForbidden, err.Error())
 					writeHTTPResponse(w, resp)
 					return
 				}
+
+				if tokens.config.Rotate {
+					next, err := tokens.create(r)
+					if err != nil {
+						requestLogger(r).WithError(err).Error("Failed to create the next csrf token")
+					} else {
+						tokens.write(w, r, next)
+					}
+				}
 			}
 		}
 
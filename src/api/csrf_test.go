@@ -1,9 +1,11 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,6 +45,15 @@ func setCSRFParameters(t *testing.T, tokenType string, req *http.Request) {
 	}
 }
 
+// csrfErrorBody returns the body of the CSRF error response of the endpoint, the /api/v1 responses are written in the
+// oldest model version
+func csrfErrorBody(endpoint string, errMsg error) string {
+	if strings.HasPrefix(endpoint, "/api/"+apiVersion1+"/") {
+		return fmt.Sprintf("{\n    \"error\": {\n        \"message\": \"%s\",\n        \"code\": 403\n    }\n}", errMsg)
+	}
+	return fmt.Sprintf("{\n    \"error\": {\n        \"message\": \"%s\",\n        \"code\": 403,\n        \"category\": \"%s\"\n    },\n    \"model_version\": %d\n}", errMsg, errorCategory(http.StatusForbidden, errMsg.Error()), ModelVersion)
+}
+
 func TestCSRFWrapper(t *testing.T) {
 	methods := []string{http.MethodPost, http.MethodPut, http.MethodDelete}
 	cases := []string{tokenInvalid, tokenExpired, tokenEmpty, tokenInvalidSignature}
@@ -80,7 +91,7 @@ func TestCSRFWrapper(t *testing.T) {
 						errMsg = ErrCSRFExpired
 					}
 
-					require.Equal(t, fmt.Sprintf("{\n    \"error\": {\n        \"message\": \"%s\",\n        \"code\": 403\n    }\n}", errMsg), rr.Body.String())
+					require.Equal(t, csrfErrorBody(endpoint, errMsg), rr.Body.String())
 				})
 			}
 		}
@@ -131,7 +142,7 @@ func TestCSRFWrapperConcurrent(t *testing.T) {
 								errMsg = ErrCSRFExpired
 							}
 
-							require.Equal(t, fmt.Sprintf("{\n    \"error\": {\n        \"message\": \"%s\",\n        \"code\": 403\n    }\n}", errMsg), rr.Body.String())
+							require.Equal(t, csrfErrorBody(endpoint, errMsg), rr.Body.String())
 						})
 					}
 				}
@@ -141,3 +152,133 @@ func TestCSRFWrapperConcurrent(t *testing.T) {
 	wg.Wait()
 
 }
+
+func TestCSRFTokens(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newToken := func(t *testing.T, tokens *csrfTokens, session string) (string, *http.Cookie) {
+		req, err := http.NewRequest(http.MethodGet, "/api/v2/csrf", nil)
+		require.NoError(t, err)
+		if session != "" {
+			req.Header.Set(SessionHeaderName, session)
+		}
+
+		rr := httptest.NewRecorder()
+		getCSRFToken(tokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var rsp struct {
+			Data string `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+
+		var cookie *http.Cookie
+		if cookies := rr.Result().Cookies(); len(cookies) == 1 {
+			cookie = cookies[0]
+		}
+		return rsp.Data, cookie
+	}
+
+	post := func(t *testing.T, tokens *csrfTokens, token, session string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/sign_message", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set(CSRFHeaderName, token)
+		}
+		if session != "" {
+			req.Header.Set(SessionHeaderName, session)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+
+		rr := httptest.NewRecorder()
+		csrfCheck(tokens, ok).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("rotate", func(t *testing.T) {
+		tokens := newCSRFTokens(CSRFConfig{Rotate: true})
+		token, cookie := newToken(t, tokens, "")
+		require.Nil(t, cookie)
+
+		rr := post(t, tokens, token, "", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		next := rr.Header().Get(CSRFHeaderName)
+		require.NotEmpty(t, next)
+		require.NotEqual(t, token, next)
+
+		// the token is used once
+		rr = post(t, tokens, token, "", nil)
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrCSRFUsed.Error())
+		require.Contains(t, rr.Body.String(), ErrorCategoryCSRFInvalid)
+
+		require.Equal(t, http.StatusOK, post(t, tokens, next, "", nil).Code)
+	})
+
+	t.Run("without rotation", func(t *testing.T) {
+		tokens := newCSRFTokens(CSRFConfig{})
+		token, _ := newToken(t, tokens, "")
+
+		rr := post(t, tokens, token, "", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Header().Get(CSRFHeaderName))
+		require.Equal(t, http.StatusOK, post(t, tokens, token, "", nil).Code)
+	})
+
+	t.Run("bind session", func(t *testing.T) {
+		tokens := newCSRFTokens(CSRFConfig{BindSession: true})
+		token, _ := newToken(t, tokens, "session-1")
+
+		require.Equal(t, http.StatusOK, post(t, tokens, token, "session-1", nil).Code)
+
+		rr := post(t, tokens, token, "session-2", nil)
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrCSRFSessionMismatch.Error())
+		require.Equal(t, http.StatusForbidden, post(t, tokens, token, "", nil).Code)
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		tokens := newCSRFTokens(CSRFConfig{MaxAge: time.Nanosecond})
+		token, _ := newToken(t, tokens, "")
+		time.Sleep(time.Millisecond)
+
+		rr := post(t, tokens, token, "", nil)
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Contains(t, rr.Body.String(), ErrCSRFExpired.Error())
+	})
+
+	t.Run("cookie", func(t *testing.T) {
+		tokens := newCSRFTokens(CSRFConfig{Mode: CSRFModeCookie, Rotate: true})
+		token, cookie := newToken(t, tokens, "")
+		require.NotNil(t, cookie)
+		require.Equal(t, CSRFCookieName, cookie.Name)
+		require.Equal(t, token, cookie.Value)
+		require.True(t, cookie.HttpOnly)
+		require.Equal(t, int(CSRFMaxAge/time.Second), cookie.MaxAge)
+
+		// the cookie stands for the header
+		rr := post(t, tokens, "", "", cookie)
+		require.Equal(t, http.StatusOK, rr.Code)
+		cookies := rr.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.NotEqual(t, token, cookies[0].Value)
+
+		require.Equal(t, http.StatusForbidden, post(t, tokens, "", "", cookie).Code)
+		require.Equal(t, http.StatusOK, post(t, tokens, "", "", cookies[0]).Code)
+
+		// the cookie is not accepted in the header mode
+		tokens = newCSRFTokens(CSRFConfig{})
+		require.Equal(t, http.StatusForbidden, post(t, tokens, "", "", cookie).Code)
+	})
+}
+
+func TestCSRFConfigValidate(t *testing.T) {
+	require.NoError(t, CSRFConfig{}.Validate())
+	require.NoError(t, CSRFConfig{Mode: CSRFModeCookie, MaxAge: time.Minute}.Validate())
+	require.EqualError(t, CSRFConfig{Mode: "query"}.Validate(), `invalid CSRF mode "query", choices are header and cookie`)
+	require.EqualError(t, CSRFConfig{MaxAge: -time.Second}.Validate(), "CSRF max age must not be negative")
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+)
+
+// operationDeadline bounds the device messages of the device operations by the context of their request,
+// they are canceled when the client disconnects
+func operationDeadline(driver *deadline.Driver, handler http.Handler) http.Handler {
+	if driver == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		end := driver.Operation(r.Context())
+		defer end()
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+)
+
+const (
+	// AuthenticityGenuine is the status of a device passing all the checks
+	AuthenticityGenuine = "genuine"
+	// AuthenticityNotGenuine is the status of a device failing a check
+	AuthenticityNotGenuine = "not_genuine"
+	// AuthenticityUnverified is the status of a device passing the checks run, when the bootloader was not checked
+	AuthenticityUnverified = "unverified"
+
+	// CheckPassed is the result of a passed authenticity check
+	CheckPassed = "passed"
+	// CheckFailed is the result of a failed authenticity check
+	CheckFailed = "failed"
+	// CheckSkipped is the result of an authenticity check which could not run
+	CheckSkipped = "skipped"
+
+	// SkycoinVendor is the vendor of the genuine devices
+	SkycoinVendor = "Skycoin Foundation"
+)
+
+// DeviceAuthenticityResponse is returned by /api/v1/device_authenticity
+type DeviceAuthenticityResponse struct {
+	// Status is genuine, not_genuine or unverified
+	Status string              `json:"status"`
+	Checks []AuthenticityCheck `json:"checks"`
+}
+
+// AuthenticityCheck is the outcome of an authenticity check
+type AuthenticityCheck struct {
+	// Name is firmware, emulator, vendor, memory_protection, bootloader or certificate
+	Name string `json:"name"`
+	// Result is passed, failed or skipped
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// checkAuthenticity checks the features of the device against the ones of a genuine device running signed firmware.
+// The bootloader of a genuine device only starts the firmware signed by Skycoin without a warning, and reports its
+// hash in the features, compared to the trusted bootloader hashes.
+func checkAuthenticity(f *messages.Features, trustedBootloaders []string) DeviceAuthenticityResponse {
+	var checks []AuthenticityCheck
+	check := func(name string, passed bool, detail string) {
+		result := CheckPassed
+		if !passed {
+			result = CheckFailed
+		}
+		checks = append(checks, AuthenticityCheck{
+			Name:   name,
+			Result: result,
+			Detail: detail,
+		})
+	}
+
+	version, ok := firmwareVersion(f)
+	if ok {
+		check("firmware", true, "the device runs the firmware "+version.String())
+	} else {
+		check("firmware", false, "the device runs no firmware")
+	}
+
+	ff := skyWallet.NewFirmwareFeatures(uint64(f.GetFirmwareFeatures())).(*skyWallet.FirmwareFeatures)
+	ff.Unmarshal() // nolint: errcheck
+	if ff.IsEmulator {
+		check("emulator", false, "the device is an emulator")
+	} else {
+		check("emulator", true, "")
+	}
+
+	if f.GetVendor() == SkycoinVendor {
+		check("vendor", true, "")
+	} else {
+		check("vendor", false, fmt.Sprintf("the vendor of the device is %q", f.GetVendor()))
+	}
+
+	if ff.HasRdpMemProtectEnabled() {
+		check("memory_protection", true, "")
+	} else {
+		check("memory_protection", false, "the flash memory of the device can be read out")
+	}
+
+	bootloader := hex.EncodeToString(f.GetBootloaderHash())
+	switch {
+	case len(trustedBootloaders) == 0:
+		checks = append(checks, AuthenticityCheck{
+			Name:   "bootloader",
+			Result: CheckSkipped,
+			Detail: "no trusted bootloader hash is set",
+		})
+	case bootloader == "":
+		check("bootloader", false, "the device reports no bootloader hash")
+	default:
+		trusted := false
+		for _, h := range trustedBootloaders {
+			if h == bootloader {
+				trusted = true
+				break
+			}
+		}
+		if trusted {
+			check("bootloader", true, "")
+		} else {
+			check("bootloader", false, "the bootloader hash "+bootloader+" is not trusted")
+		}
+	}
+
+	// a device certificate would answer a challenge with a key provisioned at manufacturing
+	checks = append(checks, AuthenticityCheck{
+		Name:   "certificate",
+		Result: CheckSkipped,
+		Detail: "the firmware has no device certificate to answer a challenge",
+	})
+
+	rsp := DeviceAuthenticityResponse{
+		Status: AuthenticityGenuine,
+		Checks: checks,
+	}
+	for _, c := range checks {
+		switch {
+		case c.Result == CheckFailed:
+			rsp.Status = AuthenticityNotGenuine
+			return rsp
+		case c.Result == CheckSkipped && c.Name == "bootloader":
+			rsp.Status = AuthenticityUnverified
+		}
+	}
+	return rsp
+}
+
+// deviceAuthenticity reports whether the device appears genuine and runs signed firmware, from its features.
+// The checks rely on what the firmware reports, the Skywallet firmware has no device certificate to prove it.
+// URI: /api/v1/device_authenticity
+// Method: GET
+func deviceAuthenticity(gateway Gatewayer, trustedBootloaders []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var msg wire.Message
+		var err error
+		retCH := make(chan int)
+		errCH := make(chan int)
+		ctx := r.Context()
+
+		go func() {
+			msg, err = gateway.GetFeatures()
+			if err != nil {
+				errCH <- 1
+				return
+			}
+			retCH <- 1
+		}()
+
+		select {
+		case <-retCH:
+			if msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+				HandleFirmwareResponseMessages(w, msg)
+				return
+			}
+
+			features := &messages.Features{}
+			if err := proto.Unmarshal(msg.Data, features); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: checkAuthenticity(features, trustedBootloaders),
+			})
+		case <-errCH:
+			requestLogger(r).Errorf("device authenticity failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+		case <-ctx.Done():
+			disConnErr := gateway.Disconnect()
+			if disConnErr != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, disConnErr.Error())
+				writeHTTPResponse(w, resp)
+			} else {
+				resp := NewHTTPErrorResponse(499, "Client Closed Request")
+				writeHTTPResponse(w, resp)
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAuthenticity(t *testing.T) {
+	failureMsg := messages.Failure{
+		Code:    messages.FailureType_Failure_NotInitialized.Enum(),
+		Message: newStrPtr("failure msg"),
+	}
+
+	failureMsgBytes, err := failureMsg.Marshal()
+	require.NoError(t, err)
+
+	featuresMsg := func(f *messages.Features) wire.Message {
+		data, err := f.Marshal()
+		require.NoError(t, err)
+		return wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_Features),
+			Data: data,
+		}
+	}
+
+	// the firmware features of a production device have the RDP level 2 memory protection
+	genuine := func() *messages.Features {
+		return &messages.Features{
+			Vendor:           newStrPtr(SkycoinVendor),
+			FwMajor:          newUint32Ptr(1),
+			FwMinor:          newUint32Ptr(8),
+			FwPatch:          newUint32Ptr(0),
+			BootloaderHash:   []byte{0xab, 0xcd},
+			FirmwareFeatures: newUint32Ptr(0x10),
+		}
+	}
+	emulator := genuine()
+	emulator.FirmwareFeatures = newUint32Ptr(0x04)
+	unknownBootloader := genuine()
+	unknownBootloader.BootloaderHash = []byte{0x01}
+	bootloaderMode := genuine()
+	bootloaderMode.BootloaderMode = newBoolPtr(true)
+
+	certificate := AuthenticityCheck{
+		Name:   "certificate",
+		Result: CheckSkipped,
+		Detail: "the firmware has no device certificate to answer a challenge",
+	}
+
+	cases := []struct {
+		name                  string
+		method                string
+		status                int
+		trustedBootloaders    []string
+		gatewayFeaturesResult wire.Message
+		httpResponse          HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+
+		{
+			name:   "409 - Failure msg",
+			method: http.MethodGet,
+			status: http.StatusConflict,
+			gatewayFeaturesResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
+
+		{
+			name:                  "200 - genuine",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			trustedBootloaders:    []string{"0102", "abcd"},
+			gatewayFeaturesResult: featuresMsg(genuine()),
+			httpResponse: HTTPResponse{
+				Data: DeviceAuthenticityResponse{
+					Status: AuthenticityGenuine,
+					Checks: []AuthenticityCheck{
+						{Name: "firmware", Result: CheckPassed, Detail: "the device runs the firmware 1.8.0"},
+						{Name: "emulator", Result: CheckPassed},
+						{Name: "vendor", Result: CheckPassed},
+						{Name: "memory_protection", Result: CheckPassed},
+						{Name: "bootloader", Result: CheckPassed},
+						certificate,
+					},
+				},
+			},
+		},
+
+		{
+			name:                  "200 - unverified without trusted bootloaders",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			gatewayFeaturesResult: featuresMsg(genuine()),
+			httpResponse: HTTPResponse{
+				Data: DeviceAuthenticityResponse{
+					Status: AuthenticityUnverified,
+					Checks: []AuthenticityCheck{
+						{Name: "firmware", Result: CheckPassed, Detail: "the device runs the firmware 1.8.0"},
+						{Name: "emulator", Result: CheckPassed},
+						{Name: "vendor", Result: CheckPassed},
+						{Name: "memory_protection", Result: CheckPassed},
+						{Name: "bootloader", Result: CheckSkipped, Detail: "no trusted bootloader hash is set"},
+						certificate,
+					},
+				},
+			},
+		},
+
+		{
+			name:                  "200 - unknown bootloader",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			trustedBootloaders:    []string{"abcd"},
+			gatewayFeaturesResult: featuresMsg(unknownBootloader),
+			httpResponse: HTTPResponse{
+				Data: DeviceAuthenticityResponse{
+					Status: AuthenticityNotGenuine,
+					Checks: []AuthenticityCheck{
+						{Name: "firmware", Result: CheckPassed, Detail: "the device runs the firmware 1.8.0"},
+						{Name: "emulator", Result: CheckPassed},
+						{Name: "vendor", Result: CheckPassed},
+						{Name: "memory_protection", Result: CheckPassed},
+						{Name: "bootloader", Result: CheckFailed, Detail: "the bootloader hash 01 is not trusted"},
+						certificate,
+					},
+				},
+			},
+		},
+
+		{
+			name:                  "200 - emulator",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			trustedBootloaders:    []string{"abcd"},
+			gatewayFeaturesResult: featuresMsg(emulator),
+			httpResponse: HTTPResponse{
+				Data: DeviceAuthenticityResponse{
+					Status: AuthenticityNotGenuine,
+					Checks: []AuthenticityCheck{
+						{Name: "firmware", Result: CheckPassed, Detail: "the device runs the firmware 1.8.0"},
+						{Name: "emulator", Result: CheckFailed, Detail: "the device is an emulator"},
+						{Name: "vendor", Result: CheckPassed},
+						{Name: "memory_protection", Result: CheckFailed, Detail: "the flash memory of the device can be read out"},
+						{Name: "bootloader", Result: CheckPassed},
+						certificate,
+					},
+				},
+			},
+		},
+
+		{
+			name:                  "200 - bootloader mode",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			trustedBootloaders:    []string{"abcd"},
+			gatewayFeaturesResult: featuresMsg(bootloaderMode),
+			httpResponse: HTTPResponse{
+				Data: DeviceAuthenticityResponse{
+					Status: AuthenticityNotGenuine,
+					Checks: []AuthenticityCheck{
+						{Name: "firmware", Result: CheckFailed, Detail: "the device runs no firmware"},
+						{Name: "emulator", Result: CheckPassed},
+						{Name: "vendor", Result: CheckPassed},
+						{Name: "memory_protection", Result: CheckPassed},
+						{Name: "bootloader", Result: CheckPassed},
+						certificate,
+					},
+				},
+			},
+		},
+
+		{
+			name:                  "200 - other vendor",
+			method:                http.MethodGet,
+			status:                http.StatusOK,
+			trustedBootloaders:    []string{"abcd"},
+			gatewayFeaturesResult: featuresMsg(&messages.Features{}),
+			httpResponse: HTTPResponse{
+				Data: DeviceAuthenticityResponse{
+					Status: AuthenticityNotGenuine,
+					Checks: []AuthenticityCheck{
+						{Name: "firmware", Result: CheckFailed, Detail: "the device runs no firmware"},
+						{Name: "emulator", Result: CheckPassed},
+						{Name: "vendor", Result: CheckFailed, Detail: `the vendor of the device is ""`},
+						{Name: "memory_protection", Result: CheckFailed, Detail: "the flash memory of the device can be read out"},
+						{Name: "bootloader", Result: CheckFailed, Detail: "the device reports no bootloader hash"},
+						certificate,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetFeatures").Return(tc.gatewayFeaturesResult, nil)
+
+			cfg := defaultMuxConfig()
+			cfg.trustedBootloaders = tc.trustedBootloaders
+
+			req, err := http.NewRequest(tc.method, "/api/v2/device_authenticity", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var resp DeviceAuthenticityResponse
+				err = json.Unmarshal(rsp.Data, &resp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data, resp)
+			}
+		})
+	}
+}
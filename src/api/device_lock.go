@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+)
+
+// Kinds of the operations holding the device
+const (
+	OperationKindSigning       = "signing"
+	OperationKindRecovery      = "recovery"
+	OperationKindFirmware      = "firmware"
+	OperationKindSetup         = "setup"
+	OperationKindBackup        = "backup"
+	OperationKindWipe          = "wipe"
+	OperationKindSettings      = "settings"
+	OperationKindAddresses     = "addresses"
+	OperationKindUserInput     = "user_input"
+	OperationKindRead          = "read"
+	OperationKindStartupChecks = "startup_checks"
+	OperationKindSession       = "session"
+)
+
+// operationKinds are the kinds of the operations of the device endpoints, the others read from the device
+var operationKinds = map[string]string{
+	"/sign_message":             OperationKindSigning,
+	"/transaction_sign":         OperationKindSigning,
+	"/partial_transaction/sign": OperationKindSigning,
+	"/recovery":                 OperationKindRecovery,
+	"/firmware_update":          OperationKindFirmware,
+	"/firmware_check":           OperationKindFirmware,
+	"/generate_mnemonic":        OperationKindSetup,
+	"/set_mnemonic":             OperationKindSetup,
+	"/configure_pin_code":       OperationKindSettings,
+	"/apply_settings":           OperationKindSettings,
+	"/backup":                   OperationKindBackup,
+	"/wipe":                     OperationKindWipe,
+	"/generate_addresses":       OperationKindAddresses,
+	"/account_export":           OperationKindAddresses,
+	"/qrcode/address":           OperationKindAddresses,
+}
+
+// operationKind returns the kind of the operation of a device endpoint
+func operationKind(endpoint string) string {
+	if kind, ok := operationKinds[endpoint]; ok {
+		return kind
+	}
+	if strings.HasPrefix(endpoint, "/intermediate/") {
+		return OperationKindUserInput
+	}
+	return OperationKindRead
+}
+
+// cancelGrace is how long the device operation in progress has to end once the device was sent a Cancel,
+// before it is aborted
+const cancelGrace = 2 * time.Second
+
+// operationLock serves the device operations one at a time, in their arrival order, so the messages of concurrent
+// requests are not interleaved. The requests waiting longer than the timeout of the lock are refused with a 423.
+// A cancel is not queued, it interrupts the operation in progress.
+func operationLock(lock *devicelock.Lock, endpoint string, handler http.Handler) http.Handler {
+	if lock == nil || endpoint == "/cancel" {
+		return handler
+	}
+
+	kind := operationKind(endpoint)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a cancel aborts the operation by canceling the context of its request
+		ctx, abort := context.WithCancel(r.Context())
+		defer abort()
+
+		release, err := lock.Acquire(ctx, devicelock.Operation{
+			Kind:      kind,
+			Endpoint:  endpoint,
+			RequestID: requestIDFromRequest(r),
+			Abort:     abort,
+		})
+		switch err {
+		case nil:
+		case devicelock.ErrBusy:
+			resp := newHTTPErrorResponseCategory(http.StatusLocked, ErrorCategoryDeviceBusy, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		default:
+			resp := NewHTTPErrorResponse(499, "Client Closed Request")
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer release()
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// abortOperation aborts the device operation in progress if it did not end within grace after the device was sent
+// a Cancel, the device does not answer its messages anymore
+func abortOperation(r *http.Request, lock *devicelock.Lock, grace time.Duration) {
+	if lock == nil {
+		return
+	}
+
+	if op := lock.Abort(grace); op != nil {
+		requestLogger(r).Warningf("Aborted the %s operation of %s, request %q, it did not end once canceled", op.Kind, op.Endpoint, op.RequestID)
+	}
+}
+
+// withDeviceLock calls f holding the device lock for an operation of the daemon of kind, for the device messages
+// sent outside of the device endpoints
+func withDeviceLock(lock *devicelock.Lock, kind string, f func() error) error {
+	if lock == nil {
+		return f()
+	}
+
+	release, err := lock.Acquire(context.Background(), devicelock.Operation{
+		Kind: kind,
+	})
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return f()
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+)
+
+func TestOperationLock(t *testing.T) {
+	featuresMsg := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor: newStrPtr("Skycoin Foundation"),
+	})
+	cancelMsg := newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    messages.FailureType_Failure_ActionCancelled.Enum(),
+		Message: newStrPtr("Action canceled by User"),
+	})
+
+	// the first features are read until release is closed
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(featuresMsg, nil).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Once()
+	gateway.On("GetFeatures").Return(featuresMsg, nil)
+	// the device answers the message in progress once canceled
+	gateway.On("Cancel").Return(cancelMsg, nil).Run(func(mock.Arguments) {
+		close(release)
+	})
+	gateway.On("Enumerate").Return([]usb.Info{}, nil)
+
+	lock := devicelock.New(20 * time.Millisecond)
+	cfg := defaultMuxConfig()
+	cfg.deviceLock = lock
+	cfg.cancelGrace = time.Minute
+	handler := newServerMux(cfg, gateway)
+
+	do := func(ctx context.Context, method, endpoint string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req.WithContext(ctx))
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rr, _ := do(context.Background(), http.MethodGet, "/api/v2/features")
+		inFlight <- rr
+	}()
+	<-started
+
+	// the device is still busy once the wait timed out
+	rr, rsp := do(context.Background(), http.MethodGet, "/api/v2/features")
+	require.Equal(t, http.StatusLocked, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusLocked, devicelock.ErrBusy.Error()).Error, rsp.Error)
+	require.Equal(t, ErrorCategoryDeviceBusy, rsp.Error.Category)
+
+	// the client disconnected while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr, _ = do(ctx, http.MethodGet, "/api/v2/features")
+	require.Equal(t, 499, rr.Code)
+
+	// a cancel is not queued, the operation in progress ends within the grace
+	rr, _ = do(context.Background(), http.MethodPut, "/api/v2/cancel")
+	require.Equal(t, http.StatusOK, rr.Code)
+	gateway.AssertCalled(t, "Cancel")
+	require.Equal(t, http.StatusOK, (<-inFlight).Code)
+
+	// the device is free again
+	rr, _ = do(context.Background(), http.MethodGet, "/api/v2/features")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Zero(t, lock.Waiting())
+}
+
+func TestCancelAbortsOperation(t *testing.T) {
+	featuresMsg := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor: newStrPtr("Skycoin Foundation"),
+	})
+	cancelMsg := newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    messages.FailureType_Failure_ActionCancelled.Enum(),
+		Message: newStrPtr("Action canceled by User"),
+	})
+
+	// the device does not answer the first features once canceled
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(featuresMsg, nil).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Once()
+	gateway.On("GetFeatures").Return(featuresMsg, nil)
+	gateway.On("Cancel").Return(cancelMsg, nil)
+	gateway.On("Disconnect").Return(nil)
+	gateway.On("Enumerate").Return([]usb.Info{}, nil)
+
+	lock := devicelock.New(0)
+	cfg := defaultMuxConfig()
+	cfg.deviceLock = lock
+	cfg.cancelGrace = 10 * time.Millisecond
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, endpoint string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, endpoint, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() {
+		inFlight <- do(http.MethodGet, "/api/v2/features")
+	}()
+	<-started
+
+	// the operation still in progress after the grace is aborted, the device is free again
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/api/v2/cancel").Code)
+	require.Equal(t, 499, (<-inFlight).Code)
+	gateway.AssertCalled(t, "Disconnect")
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v2/features").Code)
+	require.Nil(t, lock.Status().Operation)
+}
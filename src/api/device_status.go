@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/inventory"
+)
+
+const (
+	// DeviceStatusIdle is the status of the device without operation in progress
+	DeviceStatusIdle = "idle"
+	// DeviceStatusBusy is the status of the device serving an operation
+	DeviceStatusBusy = "busy"
+
+	// CurrentDeviceID is the ID of the status endpoint standing for the device of the daemon, whatever its device ID
+	CurrentDeviceID = "current"
+)
+
+// DeviceStatusResponse is returned by /api/v1/devices/{id}/status
+type DeviceStatusResponse struct {
+	// DeviceID is the device ID of the device last read by the features, empty if none was read yet
+	DeviceID string `json:"device_id,omitempty"`
+	// Status is idle or busy
+	Status string `json:"status"`
+	// Operation is the kind of the operation in progress, such as signing, recovery or firmware
+	Operation string `json:"operation,omitempty"`
+	// Endpoint is the endpoint of the operation in progress, empty for the operations of the daemon
+	Endpoint string `json:"endpoint,omitempty"`
+	// RequestID is the ID of the request of the operation in progress
+	RequestID string `json:"request_id,omitempty"`
+	// StartedAt is when the operation in progress got the device
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// RunningMillis is how long the operation in progress has been running, in milliseconds
+	RunningMillis int64 `json:"running_ms,omitempty"`
+	// Waiting is how many requests wait for the device
+	Waiting int `json:"waiting"`
+}
+
+// deviceIDRecorder records the device ID of the features read by the features endpoint
+type deviceIDRecorder struct {
+	mu sync.Mutex
+	id string
+}
+
+func (d *deviceIDRecorder) get() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.id
+}
+
+func (d *deviceIDRecorder) set(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.id = id
+}
+
+// operationDeviceID records the device ID of the responses of the features endpoint
+func operationDeviceID(ids *deviceIDRecorder, endpoint string, handler http.Handler) http.Handler {
+	if ids == nil || endpoint != "/features" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &sessionWriter{
+			statusWriter: newStatusWriter(w),
+			size:         maxOutcomeResponseSize,
+		}
+		handler.ServeHTTP(sw, r)
+
+		if sw.status != http.StatusOK {
+			return
+		}
+
+		var rsp struct {
+			Data messages.Features `json:"data"`
+		}
+		if err := json.Unmarshal(sw.body, &rsp); err != nil {
+			return
+		}
+		if id := rsp.Data.GetDeviceId(); inventory.ValidateDeviceID(id) == nil {
+			ids.set(id)
+		}
+	})
+}
+
+// deviceSubresourcesHandler serves the metadata and the status of a device
+// URI: /api/v1/devices/{id}/meta, /api/v1/devices/{id}/status
+func deviceSubresourcesHandler(meta, status http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the path is /api/{version}/devices/{id}/{resource}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		var handler http.Handler
+		if len(parts) == 5 {
+			switch parts[4] {
+			case "meta":
+				handler = meta
+			case "status":
+				handler = status
+			}
+		}
+
+		if handler == nil {
+			resp := NewHTTPErrorResponse(http.StatusNotFound, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// deviceStatusHandler returns whether the device is busy, with the operation in progress and since when, so the
+// clients explain why their request waits. The daemon drives a single device, the ID is the one last read by the
+// features or current.
+// URI: /api/v1/devices/{id}/status
+// Method: GET
+func deviceStatusHandler(lock *devicelock.Lock, ids *deviceIDRecorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// the path is /api/{version}/devices/{id}/status
+		id := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")[3]
+		known := ids.get()
+		if id != CurrentDeviceID {
+			if err := inventory.ValidateDeviceID(id); err != nil {
+				writeInventoryError(w, err)
+				return
+			}
+			// until the features are read, the device of the daemon has any ID
+			if known != "" && id != known {
+				writeInventoryError(w, inventory.ErrNotFound)
+				return
+			}
+		}
+
+		status := lock.Status()
+		rsp := DeviceStatusResponse{
+			DeviceID: known,
+			Status:   DeviceStatusIdle,
+			Waiting:  status.Waiting,
+		}
+		if op := status.Operation; op != nil {
+			since := status.Since.UTC()
+			rsp.Status = DeviceStatusBusy
+			rsp.Operation = op.Kind
+			rsp.Endpoint = op.Endpoint
+			rsp.RequestID = op.RequestID
+			rsp.StartedAt = &since
+			rsp.RunningMillis = int64(time.Since(status.Since) / time.Millisecond)
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
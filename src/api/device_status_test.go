@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+)
+
+func TestDeviceStatus(t *testing.T) {
+	const deviceID = "687576E45325EDC184C3B968"
+
+	features := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: newStrPtr(deviceID),
+	})
+	signature := newReply(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+		SignedMessage: newStrPtr("signature"),
+	})
+
+	// the message is signed until release is closed
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(features, nil)
+	gateway.On("SignMessage", 0, "hello").Return(signature, nil).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	})
+	gateway.On("Enumerate").Return([]usb.Info{}, nil)
+
+	cfg := defaultMuxConfig()
+	cfg.deviceLock = devicelock.New(0)
+	cfg.requestID = true
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, url string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	status := func(id string) DeviceStatusResponse {
+		rr := do(http.MethodGet, "/api/v2/devices/"+id+"/status")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var rsp struct {
+			Data DeviceStatusResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rsp.Data
+	}
+
+	// until the features are read the device has any ID
+	require.Equal(t, DeviceStatusResponse{Status: DeviceStatusIdle}, status(deviceID))
+	require.Equal(t, DeviceStatusResponse{Status: DeviceStatusIdle}, status("other"))
+
+	rr := do(http.MethodGet, "/api/v2/features")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, DeviceStatusResponse{DeviceID: deviceID, Status: DeviceStatusIdle}, status(CurrentDeviceID))
+
+	rr = do(http.MethodGet, "/api/v2/devices/other/status")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	rr = do(http.MethodGet, "/api/v2/devices/not-an-id/status")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = do(http.MethodPost, "/api/v2/devices/"+deviceID+"/status")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	// the inventory is disabled
+	rr = do(http.MethodGet, "/api/v2/devices/"+deviceID+"/meta")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	signed := make(chan int)
+	go func() {
+		r, err := http.NewRequest(http.MethodPost, "/api/v2/sign_message", strings.NewReader(`{"address_n":0,"message":"hello"}`))
+		if err != nil {
+			signed <- 0
+			return
+		}
+		r.Header.Set("Content-Type", ContentTypeJSON)
+		r.Header.Set(RequestIDHeaderName, "flow-42")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		signed <- rr.Code
+	}()
+	<-started
+
+	// the status is read while the device is busy
+	s := status(deviceID)
+	require.Equal(t, DeviceStatusBusy, s.Status)
+	require.Equal(t, OperationKindSigning, s.Operation)
+	require.Equal(t, "/sign_message", s.Endpoint)
+	require.Equal(t, "flow-42", s.RequestID)
+	require.NotNil(t, s.StartedAt)
+	require.True(t, s.RunningMillis >= 0)
+	require.Zero(t, s.Waiting)
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-signed)
+	require.Equal(t, DeviceStatusIdle, status(deviceID).Status)
+}
+
+func TestOperationKind(t *testing.T) {
+	require.Equal(t, OperationKindSigning, operationKind("/transaction_sign"))
+	require.Equal(t, OperationKindRecovery, operationKind("/recovery"))
+	require.Equal(t, OperationKindFirmware, operationKind("/firmware_update"))
+	require.Equal(t, OperationKindUserInput, operationKind("/intermediate/pin_matrix"))
+	require.Equal(t, OperationKindRead, operationKind("/features"))
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/inventory"
+)
+
+// maxInventoryRequestSize is the size of the generate addresses request read to record the highest index derived
+const maxInventoryRequestSize = 4096
+
+// DeviceMetaRequest is request data for PUT /api/v1/devices/{id}/meta
+type DeviceMetaRequest struct {
+	Nickname string `json:"nickname"`
+}
+
+// URI: /api/v1/devices
+// Method: GET
+func devicesHandler(inv *inventory.Inventory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		devices, err := inv.List()
+		if err != nil {
+			writeInventoryError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: devices,
+		})
+	}
+}
+
+// URI: /api/v1/devices/{id}/meta
+// Method: GET, PUT, DELETE
+// Args: JSON Body with the nickname of the device [PUT]
+func deviceMetaHandler(inv *inventory.Inventory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the path is /api/{version}/devices/{id}/meta
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if len(parts) != 5 || parts[4] != "meta" {
+			resp := NewHTTPErrorResponse(http.StatusNotFound, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+		id := parts[3]
+
+		var m inventory.Metadata
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			m, err = inv.Get(id)
+		case http.MethodPut:
+			if r.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var req DeviceMetaRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer r.Body.Close()
+
+			m, err = inv.SetNickname(id, strings.TrimSpace(req.Nickname), time.Now())
+		case http.MethodDelete:
+			if err := inv.Delete(id); err != nil {
+				writeInventoryError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: []string{"Device metadata deleted"},
+			})
+			return
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err != nil {
+			writeInventoryError(w, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: m,
+		})
+	}
+}
+
+func writeInventoryError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case inventory.ErrNotFound:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+	case inventory.ErrInvalidDeviceID, inventory.ErrNicknameTooLong:
+		resp = NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+	default:
+		resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	writeHTTPResponse(w, resp)
+}
+
+// inventoryRecorder records the metadata of the devices from the responses of the device endpoints. The addresses
+// generated after the user input, such as the PIN, are held until an intermediate request returns them.
+type inventoryRecorder struct {
+	inv     *inventory.Inventory
+	gateway Gatewayer
+
+	mu sync.Mutex
+	// held is the highest index of the addresses waiting for the user input, nil if none
+	held *uint32
+}
+
+func newInventoryRecorder(inv *inventory.Inventory, gateway Gatewayer) *inventoryRecorder {
+	if inv == nil {
+		return nil
+	}
+	return &inventoryRecorder{
+		inv:     inv,
+		gateway: gateway,
+	}
+}
+
+// swap holds index, nil to stop holding, and returns the index held before
+func (ir *inventoryRecorder) swap(index *uint32) *uint32 {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	held := ir.held
+	ir.held = index
+	return held
+}
+
+// operationInventory records the device seen by a features request, and the highest address index generated
+func operationInventory(ir *inventoryRecorder, endpoint string, handler http.Handler) http.Handler {
+	if ir == nil {
+		return handler
+	}
+
+	intermediate := strings.HasPrefix(endpoint, "/intermediate/")
+	switch {
+	case endpoint == "/features", endpoint == "/generate_addresses", endpoint == "/cancel", intermediate:
+	default:
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var index *uint32
+		switch endpoint {
+		case "/cancel":
+			ir.swap(nil)
+			handler.ServeHTTP(w, r)
+			return
+		case "/generate_addresses":
+			ir.swap(nil)
+			index = generatedIndex(r)
+		case "/features":
+		default:
+			index = ir.swap(nil)
+			if index == nil {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		sw := &sessionWriter{
+			statusWriter: newStatusWriter(w),
+			size:         maxOutcomeResponseSize,
+		}
+		handler.ServeHTTP(sw, r)
+
+		if sw.status != http.StatusOK {
+			return
+		}
+		if sw.awaitsInput() {
+			ir.swap(index)
+			return
+		}
+
+		if endpoint == "/features" {
+			var rsp struct {
+				Data messages.Features `json:"data"`
+			}
+			if err := json.Unmarshal(sw.body, &rsp); err != nil {
+				return
+			}
+			ir.seen(r, &rsp.Data, nil)
+			return
+		}
+
+		features, err := readFeatures(ir.gateway)
+		if err != nil {
+			requestLogger(r).WithError(err).Error("failed to read the device of the inventory")
+			return
+		}
+		ir.seen(r, features, index)
+	})
+}
+
+// seen records the device of features, and the highest address index it generated if index is set
+func (ir *inventoryRecorder) seen(r *http.Request, features *messages.Features, index *uint32) {
+	id := features.GetDeviceId()
+	if inventory.ValidateDeviceID(id) != nil {
+		return
+	}
+
+	var version string
+	if v, ok := firmwareVersion(features); ok {
+		version = v.String()
+	}
+
+	now := time.Now()
+	_, err := ir.inv.Seen(id, version, now)
+	if err == nil && index != nil {
+		_, err = ir.inv.Derived(id, *index, now)
+	}
+	if err != nil {
+		requestLogger(r).WithError(err).Error("failed to record the device in the inventory")
+	}
+}
+
+// generatedIndex returns the highest address index of a generate addresses request, nil if it is invalid.
+// The body of the request is read and restored for the handler.
+func generatedIndex(r *http.Request) *uint32 {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxInventoryRequestSize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || len(body) > maxInventoryRequestSize {
+		return nil
+	}
+
+	var req GenerateAddressesRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.AddressN <= 0 || req.StartIndex < 0 {
+		return nil
+	}
+
+	index := uint64(req.StartIndex) + uint64(req.AddressN) - 1
+	if index > uint64(^uint32(0)) {
+		return nil
+	}
+	i := uint32(index)
+	return &i
+}
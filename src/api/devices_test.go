@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/inventory"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestDeviceMeta(t *testing.T) {
+	const deviceID = "687576E45325EDC184C3B968"
+
+	features := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: newStrPtr(deviceID),
+		FwMajor:  newUint32Ptr(1),
+		FwMinor:  newUint32Ptr(7),
+		FwPatch:  newUint32Ptr(0),
+	})
+	addresses := newReply(t, messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+		Addresses: []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"},
+	})
+
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(features, nil)
+	gateway.On("AddressGen", uint32(5), uint32(0), false).Return(addresses, nil)
+	gateway.On("AddressGen", uint32(2), uint32(10), false).Return(newReply(t, messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{}), nil)
+	gateway.On("PinMatrixAck", "1234").Return(addresses, nil)
+
+	inv := inventory.New(storage.NewMemoryStore())
+	cfg := defaultMuxConfig()
+	cfg.inventory = inv
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		var r *http.Request
+		var err error
+		if body != nil {
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+			r, err = http.NewRequest(method, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			r.Header.Set("Content-Type", ContentTypeJSON)
+		} else {
+			r, err = http.NewRequest(method, url, nil)
+			require.NoError(t, err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	meta := func() inventory.Metadata {
+		rr := do(http.MethodGet, "/api/v2/devices/"+deviceID+"/meta", nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var rsp struct {
+			Data inventory.Metadata `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rsp.Data
+	}
+
+	rr := do(http.MethodGet, "/api/v2/devices/"+deviceID+"/meta", nil)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	// a nickname cannot be given to an unknown device
+	rr = do(http.MethodPut, "/api/v2/devices/"+deviceID+"/meta", DeviceMetaRequest{Nickname: "desk"})
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = do(http.MethodGet, "/api/v2/features", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	m := meta()
+	require.Equal(t, "1.7.0", m.FirmwareVersion)
+	require.Nil(t, m.HighestIndex)
+
+	rr = do(http.MethodPost, "/api/v2/generate_addresses", GenerateAddressesRequest{AddressN: 5})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, uint32(4), *meta().HighestIndex)
+
+	// the addresses generated after the PIN is entered are recorded
+	rr = do(http.MethodPost, "/api/v2/generate_addresses", GenerateAddressesRequest{AddressN: 2, StartIndex: 10})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, uint32(4), *meta().HighestIndex)
+	rr = do(http.MethodPost, "/api/v2/intermediate/pin_matrix", PinMatrixRequest{Pin: "1234"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, uint32(11), *meta().HighestIndex)
+
+	rr = do(http.MethodPut, "/api/v2/devices/"+deviceID+"/meta", DeviceMetaRequest{Nickname: " desk "})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "desk", meta().Nickname)
+
+	rr = do(http.MethodGet, "/api/v2/devices", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var rsp struct {
+		Data []inventory.Metadata `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Len(t, rsp.Data, 1)
+	require.Equal(t, "desk", rsp.Data[0].Nickname)
+
+	rr = do(http.MethodGet, "/api/v2/devices/not-an-id/meta", nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do(http.MethodDelete, "/api/v2/devices/"+deviceID+"/meta", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = do(http.MethodGet, "/api/v2/devices/"+deviceID+"/meta", nil)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+	"github.com/skycoin/hardware-wallet-daemon/src/stats"
+)
+
+const (
+	// diagnosticsTimeout is how long the diagnostics wait for the device before reporting it as not responding
+	diagnosticsTimeout = 5 * time.Second
+	// diagnosticsPings is the number of pings measuring the round trip latency
+	diagnosticsPings = 3
+)
+
+// DiagnosticsResponse is returned by /api/v1/diagnostics
+type DiagnosticsResponse struct {
+	Daemon    BuildInfo            `json:"daemon"`
+	Transport DiagnosticsTransport `json:"transport"`
+	Device    DiagnosticsDevice    `json:"device"`
+	Timeouts  DiagnosticsTimeouts  `json:"timeouts"`
+	// Messages are the statistics of the messages exchanged with the device since the daemon started, per message type
+	Messages []stats.MessageStats `json:"messages,omitempty"`
+}
+
+// DiagnosticsTransport describes the transport between the daemon and the device
+type DiagnosticsTransport struct {
+	// Mode is the daemon mode, USB or EMULATOR
+	Mode string `json:"mode"`
+	// Name is the transport implementation: libusb, hidapi, udp, simulator or replay
+	Name string `json:"name"`
+	// FaultInjection is true if the daemon injects transport faults
+	FaultInjection bool                   `json:"fault_injection"`
+	Devices        []DiagnosticsUsbDevice `json:"devices"`
+	Error          string                 `json:"error,omitempty"`
+}
+
+// DiagnosticsUsbDevice is a device found by the transport
+type DiagnosticsUsbDevice struct {
+	Path      string `json:"path"`
+	VendorID  int    `json:"vendor_id"`
+	ProductID int    `json:"product_id"`
+}
+
+// DiagnosticsDevice is the outcome of the device self-test
+type DiagnosticsDevice struct {
+	// Responding is true if the device answered a ping
+	Responding bool `json:"responding"`
+	// SelfTestPassed is true if the device echoed all the pings
+	SelfTestPassed bool                  `json:"self_test_passed"`
+	RoundTrip      *DiagnosticsRoundTrip `json:"round_trip,omitempty"`
+	// FirmwareVersion is the version of the firmware, which sets the version of the protocol.
+	// It is empty if the device runs no firmware.
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	BootloaderMode  bool   `json:"bootloader_mode"`
+	Model           string `json:"model,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// DiagnosticsRoundTrip is the round trip latency of the pings, in milliseconds
+type DiagnosticsRoundTrip struct {
+	MinMs float64 `json:"min_ms"`
+	AvgMs float64 `json:"avg_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// DiagnosticsTimeouts are the timeouts applied to the device messages, in milliseconds
+type DiagnosticsTimeouts struct {
+	// DiagnosticsMs is how long the diagnostics wait for the device
+	DiagnosticsMs int64 `json:"diagnostics_ms"`
+	// MessageMs is how long the other endpoints wait for a device reply.
+	// 0 waits until the device replies or the client closes the request.
+	MessageMs int64 `json:"message_ms"`
+}
+
+// diagnosticsProbe is the outcome of probing the transport and the device
+type diagnosticsProbe struct {
+	devices        []DiagnosticsUsbDevice
+	transportError string
+	device         DiagnosticsDevice
+}
+
+// Diagnostics reports the transport in use and runs a self-test of the device, pinging it and reading its features.
+// Device errors are part of the report, so it is returned even when the device is not responding.
+// URI: /api/v1/diagnostics
+// Method: GET
+func diagnostics(gateway Gatewayer, c muxConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		mode := c.currentMode()
+		transport := c.transport
+		if c.modeSwitch != nil {
+			transport = modeswitch.TransportName(mode)
+		}
+
+		report := DiagnosticsResponse{
+			Daemon: c.build,
+			Transport: DiagnosticsTransport{
+				Mode:           mode.String(),
+				Name:           transport,
+				FaultInjection: c.faultInjection,
+				Devices:        []DiagnosticsUsbDevice{},
+			},
+			Timeouts: DiagnosticsTimeouts{
+				DiagnosticsMs: int64(diagnosticsTimeout / time.Millisecond),
+			},
+		}
+
+		probeCH := make(chan diagnosticsProbe, 1)
+		ctx := r.Context()
+
+		go func() {
+			probeCH <- probe(gateway, mode == skyWallet.DeviceTypeUSB)
+		}()
+
+		select {
+		case p := <-probeCH:
+			if p.devices != nil {
+				report.Transport.Devices = p.devices
+			}
+			report.Transport.Error = p.transportError
+			report.Device = p.device
+		case <-time.After(diagnosticsTimeout):
+			requestLogger(r).Errorf("diagnostics: device did not respond within %s", diagnosticsTimeout)
+			report.Device.Error = fmt.Sprintf("device did not respond within %s", diagnosticsTimeout)
+			if err := gateway.Disconnect(); err != nil {
+				requestLogger(r).WithError(err).Error("diagnostics: gateway.Disconnect failed")
+			}
+		case <-ctx.Done():
+			disConnErr := gateway.Disconnect()
+			if disConnErr != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, disConnErr.Error())
+				writeHTTPResponse(w, resp)
+			} else {
+				resp := NewHTTPErrorResponse(499, "Client Closed Request")
+				writeHTTPResponse(w, resp)
+			}
+			return
+		}
+
+		// the statistics include the messages of the self-test
+		if c.stats != nil {
+			report.Messages = c.stats.Snapshot()
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: report,
+		})
+	}
+}
+
+// probe lists the USB devices found by the transport if listDevices is set, then pings the device and reads its features
+func probe(gateway Gatewayer, listDevices bool) diagnosticsProbe {
+	var p diagnosticsProbe
+
+	if listDevices {
+		infos, err := gateway.GetUsbInfo()
+		if err != nil {
+			p.transportError = err.Error()
+		}
+		for _, info := range infos {
+			p.devices = append(p.devices, DiagnosticsUsbDevice{
+				Path:      info.Path,
+				VendorID:  info.VendorID,
+				ProductID: info.ProductID,
+			})
+		}
+	}
+
+	p.device = selfTest(gateway)
+	return p
+}
+
+// selfTest pings the device with random messages it must echo, then reads its features
+func selfTest(gateway Gatewayer) DiagnosticsDevice {
+	var d DiagnosticsDevice
+
+	nonce := hex.EncodeToString(cipher.RandByte(8))
+	var roundTrips []time.Duration
+	for i := 0; i < diagnosticsPings; i++ {
+		message := fmt.Sprintf("diagnostics %s %d", nonce, i)
+
+		start := time.Now()
+		msg, err := gateway.Ping(message)
+		if err != nil {
+			d.Error = err.Error()
+			break
+		}
+		roundTrips = append(roundTrips, time.Since(start))
+		d.Responding = true
+
+		echoed, err := successMessage(msg)
+		if err != nil {
+			d.Error = err.Error()
+			break
+		}
+
+		if echoed != message {
+			d.Error = "self-test failed: the device did not echo the ping message"
+			break
+		}
+	}
+
+	d.RoundTrip = newDiagnosticsRoundTrip(roundTrips)
+	if !d.Responding {
+		return d
+	}
+	d.SelfTestPassed = d.Error == ""
+
+	features, err := readFeatures(gateway)
+	if err != nil {
+		if d.Error == "" {
+			d.Error = err.Error()
+		}
+		return d
+	}
+
+	if version, ok := firmwareVersion(features); ok {
+		d.FirmwareVersion = version.String()
+	}
+	d.BootloaderMode = features.GetBootloaderMode()
+	d.Model = features.GetModel()
+
+	return d
+}
+
+// readFeatures reads the features of the device
+func readFeatures(gateway Gatewayer) (*messages.Features, error) {
+	msg, err := gateway.GetFeatures()
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+		if _, err := successMessage(msg); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unexpected response to GetFeatures: %s", messages.MessageType(msg.Kind))
+	}
+
+	features := &messages.Features{}
+	if err := proto.Unmarshal(msg.Data, features); err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+// successMessage returns the message of a Success, or an error describing any other response
+func successMessage(msg wire.Message) (string, error) {
+	switch msg.Kind {
+	case uint16(messages.MessageType_MessageType_Success):
+		return skyWallet.DecodeSuccessMsg(msg)
+	case uint16(messages.MessageType_MessageType_Failure):
+		failMsg, err := skyWallet.DecodeFailMsg(msg)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("device failure: %s", failMsg)
+	default:
+		return "", fmt.Errorf("unexpected response: %s", messages.MessageType(msg.Kind))
+	}
+}
+
+// newDiagnosticsRoundTrip returns the statistics of roundTrips, nil if there is none
+func newDiagnosticsRoundTrip(roundTrips []time.Duration) *DiagnosticsRoundTrip {
+	if len(roundTrips) == 0 {
+		return nil
+	}
+
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	rt := &DiagnosticsRoundTrip{
+		MinMs: ms(roundTrips[0]),
+		MaxMs: ms(roundTrips[0]),
+	}
+
+	var total time.Duration
+	for _, d := range roundTrips {
+		total += d
+		if v := ms(d); v < rt.MinMs {
+			rt.MinMs = v
+		} else if v > rt.MaxMs {
+			rt.MaxMs = v
+		}
+	}
+	rt.AvgMs = ms(total / time.Duration(len(roundTrips)))
+
+	return rt
+}
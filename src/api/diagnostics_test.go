@@ -0,0 +1,285 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	featuresMsgBytes, err := proto.Marshal(&messages.Features{
+		FwMajor: newUint32Ptr(1),
+		FwMinor: newUint32Ptr(7),
+		FwPatch: newUint32Ptr(0),
+		Model:   newStrPtr("1"),
+	})
+	require.NoError(t, err)
+
+	featuresMsg := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Features),
+		Data: featuresMsgBytes,
+	}
+
+	failureMsgBytes, err := proto.Marshal(&messages.Failure{
+		Code:    messages.FailureType_Failure_FirmwareError.Enum(),
+		Message: newStrPtr("firmware error"),
+	})
+	require.NoError(t, err)
+
+	failureMsg := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Failure),
+		Data: failureMsgBytes,
+	}
+
+	echo := func(message string) wire.Message {
+		data, err := proto.Marshal(&messages.Success{
+			Message: newStrPtr(message),
+		})
+		require.NoError(t, err)
+		return wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_Success),
+			Data: data,
+		}
+	}
+
+	infos := []usb.Info{
+		{
+			Path:      "1-1:1.0",
+			VendorID:  0x313A,
+			ProductID: 0x0001,
+		},
+	}
+
+	cases := []struct {
+		name      string
+		mode      skyWallet.DeviceType
+		method    string
+		status    int
+		err       string
+		usbInfos  []usb.Info
+		usbErr    error
+		ping      func(message string) wire.Message
+		pingErr   error
+		features  wire.Message
+		transport DiagnosticsTransport
+		device    DiagnosticsDevice
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+
+		{
+			name:     "200 - healthy device",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			usbInfos: infos,
+			ping:     echo,
+			features: featuresMsg,
+			transport: DiagnosticsTransport{
+				Mode: "USB",
+				Name: "libusb",
+				Devices: []DiagnosticsUsbDevice{
+					{
+						Path:      "1-1:1.0",
+						VendorID:  0x313A,
+						ProductID: 0x0001,
+					},
+				},
+			},
+			device: DiagnosticsDevice{
+				Responding:      true,
+				SelfTestPassed:  true,
+				FirmwareVersion: "1.7.0",
+				Model:           "1",
+			},
+		},
+
+		{
+			name:     "200 - emulator",
+			mode:     skyWallet.DeviceTypeEmulator,
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			ping:     echo,
+			features: featuresMsg,
+			transport: DiagnosticsTransport{
+				Mode:    "EMULATOR",
+				Name:    "libusb",
+				Devices: []DiagnosticsUsbDevice{},
+			},
+			device: DiagnosticsDevice{
+				Responding:      true,
+				SelfTestPassed:  true,
+				FirmwareVersion: "1.7.0",
+				Model:           "1",
+			},
+		},
+
+		{
+			name:    "200 - no device",
+			method:  http.MethodGet,
+			status:  http.StatusOK,
+			usbErr:  errors.New("no device connected"),
+			pingErr: errors.New("no device connected"),
+			transport: DiagnosticsTransport{
+				Mode:    "USB",
+				Name:    "libusb",
+				Devices: []DiagnosticsUsbDevice{},
+				Error:   "no device connected",
+			},
+			device: DiagnosticsDevice{
+				Error: "no device connected",
+			},
+		},
+
+		{
+			name:     "200 - ping failure",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			usbInfos: infos,
+			ping: func(string) wire.Message {
+				return failureMsg
+			},
+			features: featuresMsg,
+			transport: DiagnosticsTransport{
+				Mode: "USB",
+				Name: "libusb",
+				Devices: []DiagnosticsUsbDevice{
+					{
+						Path:      "1-1:1.0",
+						VendorID:  0x313A,
+						ProductID: 0x0001,
+					},
+				},
+			},
+			device: DiagnosticsDevice{
+				Responding:      true,
+				FirmwareVersion: "1.7.0",
+				Model:           "1",
+				Error:           "device failure: firmware error",
+			},
+		},
+
+		{
+			name:     "200 - message not echoed",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			usbInfos: infos,
+			ping: func(string) wire.Message {
+				return echo("garbled")
+			},
+			features: failureMsg,
+			transport: DiagnosticsTransport{
+				Mode: "USB",
+				Name: "libusb",
+				Devices: []DiagnosticsUsbDevice{
+					{
+						Path:      "1-1:1.0",
+						VendorID:  0x313A,
+						ProductID: 0x0001,
+					},
+				},
+			},
+			device: DiagnosticsDevice{
+				Responding: true,
+				Error:      "self-test failed: the device did not echo the ping message",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetUsbInfo").Return(tc.usbInfos, tc.usbErr)
+			if tc.ping != nil {
+				gateway.On("Ping", mock.Anything).Return(tc.ping, nil)
+			} else {
+				gateway.On("Ping", mock.Anything).Return(wire.Message{}, tc.pingErr)
+			}
+			gateway.On("GetFeatures").Return(tc.features, nil)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/diagnostics", nil)
+			require.NoError(t, err)
+
+			cfg := defaultMuxConfig()
+			cfg.transport = "libusb"
+			if tc.mode != 0 {
+				cfg.mode = tc.mode
+			}
+			cfg.build = BuildInfo{
+				Version: "0.1.0",
+			}
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if tc.status != http.StatusOK {
+				var rsp ReceivedHTTPResponse
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			var rsp struct {
+				Data DiagnosticsResponse `json:"data"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+
+			report := rsp.Data
+			require.Equal(t, cfg.build, report.Daemon)
+			require.Equal(t, tc.transport, report.Transport)
+			require.Equal(t, int64(diagnosticsTimeout/time.Millisecond), report.Timeouts.DiagnosticsMs)
+			require.Zero(t, report.Timeouts.MessageMs)
+
+			if tc.device.Responding {
+				require.NotNil(t, report.Device.RoundTrip)
+				require.True(t, report.Device.RoundTrip.MinMs <= report.Device.RoundTrip.AvgMs)
+				require.True(t, report.Device.RoundTrip.AvgMs <= report.Device.RoundTrip.MaxMs)
+			} else {
+				require.Nil(t, report.Device.RoundTrip)
+			}
+			report.Device.RoundTrip = nil
+			require.Equal(t, tc.device, report.Device)
+
+			if tc.mode == skyWallet.DeviceTypeEmulator {
+				gateway.AssertNotCalled(t, "GetUsbInfo")
+			}
+
+			if tc.device.SelfTestPassed {
+				gateway.AssertNumberOfCalls(t, "Ping", diagnosticsPings)
+			}
+		})
+	}
+}
+
+func TestNewDiagnosticsRoundTrip(t *testing.T) {
+	require.Nil(t, newDiagnosticsRoundTrip(nil))
+
+	rt := newDiagnosticsRoundTrip([]time.Duration{
+		2 * time.Millisecond,
+		time.Millisecond,
+		6 * time.Millisecond,
+	})
+	require.Equal(t, &DiagnosticsRoundTrip{
+		MinMs: 1,
+		AvgMs: 3,
+		MaxMs: 6,
+	}, rt)
+}
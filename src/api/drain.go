@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+)
+
+// operationDrain tracks the device operations in flight, so the daemon waits for them when it shuts down.
+// The operations started while it shuts down are refused.
+func operationDrain(tracker *drain.Tracker, handler http.Handler) http.Handler {
+	if tracker == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		end, err := tracker.Begin()
+		if err != nil {
+			w.Header().Set("Connection", "close")
+			resp := newHTTPErrorResponseCategory(http.StatusServiceUnavailable, ErrorCategoryShuttingDown, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer end()
+
+		handler.ServeHTTP(w, r)
+	})
+}
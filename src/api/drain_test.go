@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+)
+
+func TestOperationDrain(t *testing.T) {
+	featuresMsg := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor: newStrPtr("Skycoin Foundation"),
+	})
+
+	// the features are read until release is closed
+	release := make(chan struct{})
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(featuresMsg, nil).Run(func(mock.Arguments) {
+		<-release
+	})
+	gateway.On("Enumerate").Return([]usb.Info{}, nil)
+
+	tracker := drain.NewTracker()
+	cfg := defaultMuxConfig()
+	cfg.drain = tracker
+	handler := newServerMux(cfg, gateway)
+
+	do := func(endpoint string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	rr, _ := do("/ready")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rr, _ := do("/api/v2/features")
+		inFlight <- rr
+	}()
+
+	for tracker.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	drained := make(chan error)
+	go func() {
+		drained <- tracker.Drain(context.Background())
+	}()
+
+	for !tracker.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the new operations are refused, the other endpoints are served
+	rr, rsp := do("/api/v2/features")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusServiceUnavailable, drain.ErrDraining.Error()).Error, rsp.Error)
+	require.Equal(t, "close", rr.Header().Get("Connection"))
+
+	rr, rsp = do("/ready")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusServiceUnavailable, drain.ErrDraining.Error()).Error, rsp.Error)
+
+	rr, _ = do("/api/v2/version")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	select {
+	case <-drained:
+		t.Fatal("drained with an operation in flight")
+	default:
+	}
+
+	// the operation in flight finishes
+	close(release)
+	require.Equal(t, http.StatusOK, (<-inFlight).Code)
+	require.NoError(t, <-drained)
+	gateway.AssertNumberOfCalls(t, "GetFeatures", 1)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/emulator"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+)
+
+// emulatorHandler returns the state of the emulator process
+// URI: /api/v1/emulator
+// Method: GET
+func emulatorHandler(e *emulator.Emulator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: e.Status(),
+		})
+	}
+}
+
+// emulatorOperationHandler starts, stops, resets or wipes the emulator process and returns its state.
+// The connection to the emulator is dropped once it is stopped, the operations in progress fail as if the device
+// was unplugged. The operations are recorded in the audit log.
+// URI: /api/v1/emulator/start, /api/v1/emulator/stop, /api/v1/emulator/reset, /api/v1/emulator/wipe
+// Method: POST
+func emulatorOperationHandler(name string, operation func() (emulator.Status, error), gateway Gatewayer, recorder *history.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		start := time.Now()
+
+		status, err := operation()
+		switch err {
+		case nil:
+		case emulator.ErrRunning, emulator.ErrNotRunning:
+			resp := NewHTTPErrorResponse(http.StatusConflict, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		default:
+			requestLogger(r).Errorf("emulator %s failed: %s", name, err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// drop the connection to the stopped emulator
+		if name != "start" {
+			if err := gateway.Disconnect(); err != nil {
+				requestLogger(r).WithError(err).Error("gateway.Disconnect failed")
+			}
+		}
+
+		if recorder != nil {
+			if err := recorder.Record(history.Record{
+				Time:      start.UTC(),
+				Endpoint:  "/emulator/" + name,
+				Method:    r.Method,
+				Status:    http.StatusOK,
+				Duration:  int64(time.Since(start) / time.Millisecond),
+				RequestID: requestIDFromRequest(r),
+				Trace:     recorder.Trace(r.Header),
+			}, true); err != nil {
+				requestLogger(r).WithError(err).Errorf("failed to record emulator %s", name)
+			}
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: status,
+		})
+	}
+}
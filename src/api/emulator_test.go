@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/emulator"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestEmulator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emulator")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a fake emulator running until it is interrupted
+	binary := filepath.Join(dir, "emulator.sh")
+	require.NoError(t, ioutil.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 60\n"), 0755))
+
+	e := emulator.New(emulator.Config{
+		Binary: binary,
+	})
+	defer e.Close() // nolint: errcheck
+
+	recorder := history.NewRecorder(storage.NewMemoryStore(), nil)
+
+	gateway := &MockGatewayer{}
+	gateway.On("Disconnect").Return(nil)
+
+	cfg := defaultMuxConfig()
+	cfg.emulator = e
+	cfg.history = recorder
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, endpoint string) (*httptest.ResponseRecorder, ReceivedHTTPResponse, emulator.Status) {
+		req, err := http.NewRequest(method, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+
+		var status emulator.Status
+		if rsp.Data != nil {
+			require.NoError(t, json.Unmarshal(rsp.Data, &status))
+		}
+		return rr, rsp, status
+	}
+
+	rr, rsp, _ := do(http.MethodPost, "/api/v2/emulator")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusMethodNotAllowed, "").Error, rsp.Error)
+
+	rr, _, status := do(http.MethodGet, "/api/v2/emulator")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, emulator.Status{
+		State:  emulator.StateStopped,
+		Binary: binary,
+	}, status)
+
+	rr, rsp, _ = do(http.MethodGet, "/api/v2/emulator/start")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusMethodNotAllowed, "").Error, rsp.Error)
+
+	rr, rsp, _ = do(http.MethodPost, "/api/v2/emulator/stop")
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusConflict, emulator.ErrNotRunning.Error()).Error, rsp.Error)
+
+	rr, _, status = do(http.MethodPost, "/api/v2/emulator/start")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, emulator.StateRunning, status.State)
+	require.NotZero(t, status.PID)
+
+	rr, rsp, _ = do(http.MethodPost, "/api/v2/emulator/start")
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusConflict, emulator.ErrRunning.Error()).Error, rsp.Error)
+
+	rr, _, reset := do(http.MethodPost, "/api/v2/emulator/reset")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, emulator.StateRunning, reset.State)
+	require.NotEqual(t, status.PID, reset.PID)
+
+	rr, _, status = do(http.MethodPost, "/api/v2/emulator/wipe")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, emulator.StateRunning, status.State)
+
+	rr, _, status = do(http.MethodPost, "/api/v2/emulator/stop")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, emulator.StateStopped, status.State)
+
+	// the connection is dropped once the emulator is stopped
+	gateway.AssertNumberOfCalls(t, "Disconnect", 3)
+
+	audit, err := recorder.Records(storage.AuditBucket, history.Filter{})
+	require.NoError(t, err)
+	var endpoints []string
+	for _, r := range audit {
+		endpoints = append(endpoints, r.Endpoint)
+	}
+	require.ElementsMatch(t, []string{"/emulator/start", "/emulator/reset", "/emulator/wipe", "/emulator/stop"}, endpoints)
+}
+
+func TestEmulatorDisabled(t *testing.T) {
+	handler := newServerMux(defaultMuxConfig(), &MockGatewayer{})
+
+	for _, endpoint := range []string{"/api/v2/emulator", "/api/v2/emulator/start", "/api/v2/emulator/stop", "/api/v2/emulator/reset", "/api/v2/emulator/wipe"} {
+		req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/entropy"
+)
+
+const (
+	// defaultEntropyCheckBytes is the number of bytes tested when the request does not set it
+	defaultEntropyCheckBytes = 4096
+	// maxEntropyCheckBytes is the maximum number of bytes that can be requested from the device
+	maxEntropyCheckBytes = 65536
+)
+
+// EntropyCheckRequest is request data for /api/v1/entropy_check
+type EntropyCheckRequest struct {
+	// Bytes is the number of bytes read from the device RNG, 4096 if not set
+	Bytes uint32 `json:"bytes"`
+}
+
+// Entropy check reads random bytes from the device RNG and runs statistical health tests on them.
+// URI: /api/v1/entropy_check
+// Method: POST
+// Args: JSON Body
+func entropyCheck(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req EntropyCheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer r.Body.Close()
+
+		if req.Bytes == 0 {
+			req.Bytes = defaultEntropyCheckBytes
+		}
+
+		if req.Bytes < entropy.MinBytes || req.Bytes > maxEntropyCheckBytes {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "bytes must be between 1280 and 65536")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// for integration tests
+		if autoPressEmulatorButtons {
+			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
+			if err != nil {
+				requestLogger(r).Error("entropyCheck failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		var data []byte
+		var err error
+		retCH := make(chan int)
+		errCH := make(chan int)
+		ctx := r.Context()
+
+		go func() {
+			data, err = gateway.GetRawEntropy(req.Bytes)
+			if err != nil {
+				errCH <- 1
+				return
+			}
+			retCH <- 1
+		}()
+
+		select {
+		case <-retCH:
+			writeHTTPResponse(w, HTTPResponse{
+				Data: entropy.Check(data, entropy.DefaultAlpha),
+			})
+		case <-errCH:
+			requestLogger(r).Errorf("entropyCheck failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+		case <-ctx.Done():
+			disConnErr := gateway.Disconnect()
+			if disConnErr != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, disConnErr.Error())
+				writeHTTPResponse(w, resp)
+			} else {
+				resp := NewHTTPErrorResponse(499, "Client Closed Request")
+				writeHTTPResponse(w, resp)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/entropy"
+)
+
+func TestEntropyCheck(t *testing.T) {
+	random := make([]byte, defaultEntropyCheckBytes)
+	_, err := rand.New(rand.NewSource(1)).Read(random)
+	require.NoError(t, err)
+
+	zeros := make([]byte, 2048)
+
+	cases := []struct {
+		name         string
+		method       string
+		status       int
+		contentType  string
+		httpBody     string
+		httpResponse HTTPResponse
+		size         uint32
+		entropy      []byte
+		gatewayErr   error
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+
+		{
+			name:         "415 - Unsupported Media Type",
+			method:       http.MethodPost,
+			contentType:  ContentTypeForm,
+			status:       http.StatusUnsupportedMediaType,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+
+		{
+			name:         "400 - EOF",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "EOF"),
+		},
+
+		{
+			name:   "422 - too few bytes",
+			method: http.MethodPost,
+			status: http.StatusUnprocessableEntity,
+			httpBody: toJSON(t, &EntropyCheckRequest{
+				Bytes: 1000,
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "bytes must be between 1280 and 65536"),
+		},
+
+		{
+			name:   "422 - too many bytes",
+			method: http.MethodPost,
+			status: http.StatusUnprocessableEntity,
+			httpBody: toJSON(t, &EntropyCheckRequest{
+				Bytes: maxEntropyCheckBytes + 1,
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "bytes must be between 1280 and 65536"),
+		},
+
+		{
+			name:         "500 - device error",
+			method:       http.MethodPost,
+			status:       http.StatusInternalServerError,
+			httpBody:     `{}`,
+			size:         defaultEntropyCheckBytes,
+			gatewayErr:   errors.New("Action canceled by User"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "Action canceled by User"),
+		},
+
+		{
+			name:     "200 - default size",
+			method:   http.MethodPost,
+			status:   http.StatusOK,
+			httpBody: `{}`,
+			size:     defaultEntropyCheckBytes,
+			entropy:  random,
+			httpResponse: HTTPResponse{
+				Data: entropy.Check(random, entropy.DefaultAlpha),
+			},
+		},
+
+		{
+			name:   "200 - failed tests",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			httpBody: toJSON(t, &EntropyCheckRequest{
+				Bytes: uint32(len(zeros)),
+			}),
+			size:    uint32(len(zeros)),
+			entropy: zeros,
+			httpResponse: HTTPResponse{
+				Data: entropy.Check(zeros, entropy.DefaultAlpha),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			endpoint := "/entropy_check"
+
+			if tc.size != 0 {
+				gateway.On("GetRawEntropy", tc.size).Return(tc.entropy, tc.gatewayErr)
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, strings.NewReader(tc.httpBody))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+				require.JSONEq(t, toJSON(t, tc.httpResponse.Data), string(rsp.Data))
+			}
+
+			gateway.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/allowlist"
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/chaos"
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+	"github.com/skycoin/hardware-wallet-daemon/src/emulator"
+	"github.com/skycoin/hardware-wallet-daemon/src/session"
+	"github.com/skycoin/hardware-wallet-daemon/src/u2f"
+)
+
+// Error categories of the error responses. Clients decide what to show from the category, the message is for humans
+// and may change.
+const (
+	ErrorCategoryInvalidRequest       = "invalid_request"
+	ErrorCategoryUnauthorized         = "unauthorized"
+	ErrorCategoryForbidden            = "forbidden"
+	ErrorCategoryNotFound             = "not_found"
+	ErrorCategoryMethodNotAllowed     = "method_not_allowed"
+	ErrorCategoryUnsupportedMediaType = "unsupported_media_type"
+	ErrorCategoryRateLimited          = "rate_limited"
+	ErrorCategoryRequestCancelled     = "request_cancelled"
+	ErrorCategoryInternal             = "internal_error"
+	ErrorCategoryUnavailable          = "unavailable"
+	ErrorCategoryAPIDisabled          = "api_disabled"
+
+	ErrorCategoryCSRFInvalid = "csrf_invalid"
+	ErrorCategoryCSRFExpired = "csrf_expired"
+
+	ErrorCategoryDeviceDisconnected = "device_disconnected"
+	ErrorCategoryDeviceTimeout      = "device_timeout"
+	ErrorCategoryDeviceLocked       = "device_locked"
+	ErrorCategoryDeviceBusy         = "device_busy"
+	ErrorCategoryDeviceNotAllowed   = "device_not_allowed"
+	ErrorCategorySessionInvalid     = "session_invalid"
+	ErrorCategorySessionRequired    = "session_required"
+	ErrorCategoryShuttingDown       = "shutting_down"
+	ErrorCategoryReadOnly           = "read_only"
+	ErrorCategoryStartupChecks      = "startup_checks_failed"
+	ErrorCategorySigningWindow      = "signing_window_closed"
+	ErrorCategoryPolicyRejected     = "policy_rejected"
+
+	ErrorCategoryApprovalPending  = "approval_pending"
+	ErrorCategoryApprovalInvalid  = "approval_invalid"
+	ErrorCategoryApprovalRejected = "approval_rejected"
+	ErrorCategoryApprovalDecided  = "approval_decided"
+	ErrorCategoryApprovalMismatch = "approval_mismatch"
+	ErrorCategoryApprovalTooMany  = "approval_too_many"
+
+	ErrorCategoryEmulatorRunning    = "emulator_running"
+	ErrorCategoryEmulatorNotRunning = "emulator_not_running"
+
+	ErrorCategoryIdempotencyMismatch = "idempotency_mismatch"
+	ErrorCategoryTransactionTooLarge = "transaction_too_large"
+
+	// ErrorCategoryDeviceFailure is the category of the failures of the device without a category of their own
+	ErrorCategoryDeviceFailure     = "device_failure"
+	ErrorCategoryUnexpectedMessage = "unexpected_message"
+	ErrorCategoryButtonExpected    = "button_expected"
+	ErrorCategoryDataError         = "data_error"
+	ErrorCategoryActionCancelled   = "action_cancelled"
+	ErrorCategoryPinExpected       = "pin_expected"
+	ErrorCategoryPinCancelled      = "pin_cancelled"
+	ErrorCategoryPinInvalid        = "pin_invalid"
+	ErrorCategoryInvalidSignature  = "invalid_signature"
+	ErrorCategoryProcessError      = "process_error"
+	ErrorCategoryNotEnoughFunds    = "not_enough_funds"
+	ErrorCategoryNotInitialized    = "not_initialized"
+	ErrorCategoryPinMismatch       = "pin_mismatch"
+	ErrorCategoryAddressGeneration = "address_generation"
+	ErrorCategoryFirmwarePanic     = "firmware_panic"
+	ErrorCategoryFirmwareError     = "firmware_error"
+)
+
+// failureCategories are the categories of the failures of the device, by failure type
+var failureCategories = map[messages.FailureType]string{
+	messages.FailureType_Failure_UnexpectedMessage: ErrorCategoryUnexpectedMessage,
+	messages.FailureType_Failure_ButtonExpected:    ErrorCategoryButtonExpected,
+	messages.FailureType_Failure_DataError:         ErrorCategoryDataError,
+	messages.FailureType_Failure_ActionCancelled:   ErrorCategoryActionCancelled,
+	messages.FailureType_Failure_PinExpected:       ErrorCategoryPinExpected,
+	messages.FailureType_Failure_PinCancelled:      ErrorCategoryPinCancelled,
+	messages.FailureType_Failure_PinInvalid:        ErrorCategoryPinInvalid,
+	messages.FailureType_Failure_InvalidSignature:  ErrorCategoryInvalidSignature,
+	messages.FailureType_Failure_ProcessError:      ErrorCategoryProcessError,
+	messages.FailureType_Failure_NotEnoughFunds:    ErrorCategoryNotEnoughFunds,
+	messages.FailureType_Failure_NotInitialized:    ErrorCategoryNotInitialized,
+	messages.FailureType_Failure_PinMismatch:       ErrorCategoryPinMismatch,
+	messages.FailureType_Failure_AddressGeneration: ErrorCategoryAddressGeneration,
+	messages.FailureType_Failure_FirmwarePanic:     ErrorCategoryFirmwarePanic,
+	messages.FailureType_Failure_FirmwareError:     ErrorCategoryFirmwareError,
+}
+
+// errorCategories are the categories of the errors returned by the device stack and the daemon,
+// by message, for the handlers answering with the message of an error
+var errorCategories = func() map[string]string {
+	categories := make(map[string]string)
+	for category, errs := range map[string][]error{
+		ErrorCategoryCSRFInvalid:        {ErrCSRFInvalid, ErrCSRFInvalidSignature, ErrCSRFSessionMismatch, ErrCSRFUsed},
+		ErrorCategoryCSRFExpired:        {ErrCSRFExpired},
+		ErrorCategoryDeviceDisconnected: {skyWallet.ErrNoDeviceConnected, usb.ErrNotFound, usb.ErrDisconnect, usb.ErrClosedDevice, chaos.ErrDisconnected, u2f.ErrNotFound},
+		ErrorCategoryDeviceTimeout:      {deadline.ErrTimeout, u2f.ErrTimeout},
+		ErrorCategoryRequestCancelled:   {deadline.ErrCanceled},
+		ErrorCategoryShuttingDown:       {drain.ErrDraining},
+		ErrorCategoryDeviceLocked:       {session.ErrLocked},
+		ErrorCategoryDeviceBusy:         {devicelock.ErrBusy},
+		ErrorCategoryDeviceNotAllowed:   {allowlist.ErrNotAllowed},
+		ErrorCategorySessionInvalid:     {session.ErrInvalidSession},
+		ErrorCategorySessionRequired:    {session.ErrSessionRequired},
+		ErrorCategoryApprovalPending:    {approval.ErrPending},
+		ErrorCategoryApprovalInvalid:    {approval.ErrNotFound},
+		ErrorCategoryApprovalRejected:   {approval.ErrRejected},
+		ErrorCategoryApprovalDecided:    {approval.ErrDecided},
+		ErrorCategoryApprovalMismatch:   {approval.ErrMismatch},
+		ErrorCategoryApprovalTooMany:    {approval.ErrTooMany},
+		ErrorCategoryEmulatorRunning:    {emulator.ErrRunning},
+		ErrorCategoryEmulatorNotRunning: {emulator.ErrNotRunning},
+		ErrorCategoryForbidden:          {ErrNotTestDevice},
+	} {
+		for _, err := range errs {
+			categories[err.Error()] = category
+		}
+	}
+	return categories
+}()
+
+// statusCategories are the categories of the other errors, by status code
+var statusCategories = map[int]string{
+	http.StatusBadRequest:           ErrorCategoryInvalidRequest,
+	http.StatusUnauthorized:         ErrorCategoryUnauthorized,
+	http.StatusForbidden:            ErrorCategoryForbidden,
+	http.StatusNotFound:             ErrorCategoryNotFound,
+	http.StatusMethodNotAllowed:     ErrorCategoryMethodNotAllowed,
+	http.StatusConflict:             ErrorCategoryDeviceFailure,
+	http.StatusGone:                 ErrorCategoryAPIDisabled,
+	http.StatusLocked:               ErrorCategoryDeviceLocked,
+	http.StatusUnsupportedMediaType: ErrorCategoryUnsupportedMediaType,
+	http.StatusUnprocessableEntity:  ErrorCategoryInvalidRequest,
+	http.StatusTooManyRequests:      ErrorCategoryRateLimited,
+	499:                             ErrorCategoryRequestCancelled,
+	http.StatusServiceUnavailable:   ErrorCategoryUnavailable,
+}
+
+// newHTTPErrorResponseCategory returns an HTTPResponse with the Error field populated, in the category
+func newHTTPErrorResponseCategory(code int, category, msg string) HTTPResponse {
+	resp := NewHTTPErrorResponse(code, msg)
+	resp.Error.Category = category
+	return resp
+}
+
+// newFailureResponse returns the error response of a failure of the device, in the category of its failure type
+func newFailureResponse(failure *messages.Failure) HTTPResponse {
+	category := ErrorCategoryDeviceFailure
+	if failure.Code != nil {
+		if c, ok := failureCategories[*failure.Code]; ok {
+			category = c
+		}
+	}
+	return newHTTPErrorResponseCategory(http.StatusConflict, category, failure.GetMessage())
+}
+
+// errorCategory returns the category of an error response from its message, if it is the message of a known error,
+// or its status code
+func errorCategory(code int, msg string) string {
+	if category, ok := errorCategories[msg]; ok {
+		return category
+	}
+
+	if category, ok := statusCategories[code]; ok {
+		return category
+	}
+
+	if code >= 400 && code < 500 {
+		return ErrorCategoryInvalidRequest
+	}
+	return ErrorCategoryInternal
+}
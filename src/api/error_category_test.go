@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/allowlist"
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+	"github.com/skycoin/hardware-wallet-daemon/src/session"
+)
+
+func TestErrorCategory(t *testing.T) {
+	cases := []struct {
+		code     int
+		msg      string
+		category string
+	}{
+		{http.StatusInternalServerError, skyWallet.ErrNoDeviceConnected.Error(), ErrorCategoryDeviceDisconnected},
+		{http.StatusInternalServerError, deadline.ErrTimeout.Error(), ErrorCategoryDeviceTimeout},
+		{http.StatusInternalServerError, allowlist.ErrNotAllowed.Error(), ErrorCategoryDeviceNotAllowed},
+		{http.StatusLocked, session.ErrLocked.Error(), ErrorCategoryDeviceLocked},
+		{http.StatusForbidden, ErrCSRFExpired.Error(), ErrorCategoryCSRFExpired},
+		{http.StatusBadRequest, "EOF", ErrorCategoryInvalidRequest},
+		{http.StatusTooManyRequests, "", ErrorCategoryRateLimited},
+		{499, "Client Closed Request", ErrorCategoryRequestCancelled},
+		{http.StatusRequestEntityTooLarge, "", ErrorCategoryInvalidRequest},
+		{http.StatusInternalServerError, "failed", ErrorCategoryInternal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.category, func(t *testing.T) {
+			require.Equal(t, tc.category, errorCategory(tc.code, tc.msg))
+			require.Equal(t, tc.category, NewHTTPErrorResponse(tc.code, tc.msg).Error.Category)
+		})
+	}
+}
+
+func TestFailureCategory(t *testing.T) {
+	cases := []struct {
+		name     string
+		code     *messages.FailureType
+		category string
+	}{
+		{
+			name:     "pin invalid",
+			code:     messages.FailureType_Failure_PinInvalid.Enum(),
+			category: ErrorCategoryPinInvalid,
+		},
+		{
+			name:     "action cancelled",
+			code:     messages.FailureType_Failure_ActionCancelled.Enum(),
+			category: ErrorCategoryActionCancelled,
+		},
+		{
+			name:     "no failure type",
+			category: ErrorCategoryDeviceFailure,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			HandleFirmwareResponseMessages(rr, newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+				Code:    tc.code,
+				Message: newStrPtr("failure msg"),
+			}))
+			require.Equal(t, http.StatusConflict, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, &HTTPError{
+				Code:     http.StatusConflict,
+				Message:  "failure msg",
+				Category: tc.category,
+			}, rsp.Error)
+		})
+	}
+}
+
+func TestDeviceDisconnectedCategory(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(wire.Message{}, skyWallet.ErrNoDeviceConnected)
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/features", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newServerMux(defaultMuxConfig(), gateway).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	var rsp ReceivedHTTPResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, ErrorCategoryDeviceDisconnected, rsp.Error.Category)
+	require.Equal(t, skyWallet.ErrNoDeviceConnected.Error(), rsp.Error.Message)
+}
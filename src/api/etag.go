@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// cacheableEndpoints are the GET endpoints answering with an ETag, the clients polling them revalidate their copy
+// with If-None-Match instead of downloading it again
+var cacheableEndpoints = map[string]struct{}{
+	"/features": {},
+	"/devices":  {},
+	"/spec":     {},
+}
+
+// entityTag sets the ETag of the responses of the cacheable endpoints, and answers a 304 without body when the
+// If-None-Match header of the request matches it. The tag is weak, the responses may be gzipped or not.
+func entityTag(endpoint string, handler http.Handler) http.Handler {
+	if _, ok := cacheableEndpoints[endpoint]; !ok {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		bw := &bufferedWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+		handler.ServeHTTP(bw, r)
+
+		if bw.status == http.StatusOK {
+			tag := responseTag(r, bw.body.Bytes())
+			w.Header().Set("ETag", tag)
+			// the response depends on the model version negotiated
+			w.Header().Add("Vary", ModelVersionHeaderName)
+
+			if tagsMatch(r.Header.Get("If-None-Match"), tag) {
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		w.WriteHeader(bw.status)
+		if _, err := w.Write(bw.body.Bytes()); err != nil {
+			requestLogger(r).WithError(err).Error("Failed to write the response")
+		}
+	})
+}
+
+// responseTag returns the weak ETag of a response body, for the model version requested
+func responseTag(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Header.Get(ModelVersionHeaderName) + "\n")) // nolint: errcheck
+	h.Write(body)                                                // nolint: errcheck
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// tagsMatch returns true if the If-None-Match header lists tag, or is *. The tags are compared weakly.
+func tagsMatch(ifNoneMatch, tag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "" {
+		return false
+	}
+	if ifNoneMatch == "*" {
+		return true
+	}
+
+	opaque := strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds the status and the body of a response, its headers are the ones of the response
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Write implements http.ResponseWriter
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
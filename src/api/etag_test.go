@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityTag(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor:           newStrPtr("Skycoin Foundation"),
+		UnfinishedBackup: newBoolPtr(true),
+	}), nil)
+	handler := newServerMux(defaultMuxConfig(), gateway)
+
+	get := func(endpoint, version, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/api/v2"+endpoint, nil)
+		require.NoError(t, err)
+		if version != "" {
+			req.Header.Set(ModelVersionHeaderName, version)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/features", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	tag := rr.Header().Get("ETag")
+	require.Regexp(t, `^W/"[0-9a-f]{32}"$`, tag)
+	require.Contains(t, rr.Header()["Vary"], ModelVersionHeaderName)
+
+	// the unchanged features are revalidated
+	rr = get("/features", "", tag)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Empty(t, rr.Body.String())
+	require.Equal(t, tag, rr.Header().Get("ETag"))
+
+	rr = get("/features", "", `W/"other", `+tag)
+	require.Equal(t, http.StatusNotModified, rr.Code)
+
+	rr = get("/features", "", `W/"other"`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEmpty(t, rr.Body.String())
+
+	// the features of another model version have another tag
+	rr = get("/features", "1", tag)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEqual(t, tag, rr.Header().Get("ETag"))
+
+	rr = get("/spec", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = get("/spec", "", rr.Header().Get("ETag"))
+	require.Equal(t, http.StatusNotModified, rr.Code)
+}
+
+func TestTagsMatch(t *testing.T) {
+	require.True(t, tagsMatch(`W/"a"`, `W/"a"`))
+	require.True(t, tagsMatch(`"a"`, `W/"a"`))
+	require.True(t, tagsMatch(`"b", W/"a"`, `W/"a"`))
+	require.True(t, tagsMatch(`*`, `W/"a"`))
+	require.False(t, tagsMatch(``, `W/"a"`))
+	require.False(t, tagsMatch(`W/"b"`, `W/"a"`))
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+)
+
+const (
+	// eventsHeartbeatInterval is how often a comment line is sent to keep idle event streams open
+	eventsHeartbeatInterval = 15 * time.Second
+
+	// lastEventIDHeader is sent by EventSource clients when they reconnect
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// OperationEventData is the data of an operation_finished event
+type OperationEventData struct {
+	Endpoint string `json:"endpoint"`
+	Method   string `json:"method"`
+	Status   int    `json:"status"`
+	// Duration of the operation in milliseconds
+	Duration int64 `json:"duration_ms"`
+	// RequestID is the ID of the request of the operation
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// eventsCursor returns the sequence number after which events are requested.
+// The since query parameter takes precedence over the Last-Event-ID header.
+func eventsCursor(r *http.Request) (uint64, error) {
+	cursor := r.URL.Query().Get("since")
+	if cursor == "" {
+		cursor = r.Header.Get(lastEventIDHeader)
+	}
+
+	if cursor == "" {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event cursor %q", cursor)
+	}
+
+	return seq, nil
+}
+
+// eventsFilter returns the filter selecting the requested events
+func eventsFilter(r *http.Request) (events.Filter, error) {
+	filter := events.Filter{
+		DeviceID: r.URL.Query().Get("device_id"),
+	}
+
+	if types := r.URL.Query().Get("types"); types != "" {
+		for _, s := range strings.Split(types, ",") {
+			t, err := events.ParseType(strings.TrimSpace(s))
+			if err != nil {
+				return events.Filter{}, err
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	return filter, nil
+}
+
+// eventsHandler streams daemon events using server-sent events.
+// Clients that reconnect receive the events they missed since their cursor before live events.
+// Events are dropped for a client that does not keep up, it then receives an overflow notice
+// and can reconnect from the last event it received.
+// URI: /api/v1/events
+// Method: GET
+// Args:
+//  since: sequence number of the last event received [optional, defaults to the Last-Event-ID header]
+//  stream: set to false to return the events since the cursor as JSON instead of streaming [optional]
+//  device_id: only return the events of this device [optional]
+//  types: comma separated list of the event types to return [optional]
+func eventsHandler(bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		cursor, err := eventsCursor(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		filter, err := eventsFilter(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		stream := true
+		if s := r.URL.Query().Get("stream"); s != "" {
+			stream, err = strconv.ParseBool(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid value for stream")
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		if !stream {
+			evs, err := bus.Since(cursor, 0, filter)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if evs == nil {
+				evs = []events.Event{}
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: evs,
+			})
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, "streaming unsupported")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// subscribe before reading the backlog so no event falls in between
+		sub := bus.Subscribe(filter)
+		defer sub.Unsubscribe()
+
+		backlog, err := bus.Since(cursor, 0, filter)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		last := cursor
+		for _, ev := range backlog {
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+			last = ev.Seq
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(eventsHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		ctx := r.Context()
+		for {
+			select {
+			case ev := <-sub.C:
+				if ev.Seq <= last {
+					continue
+				}
+				if err := writeEvent(w, ev); err != nil {
+					return
+				}
+				last = ev.Seq
+			case <-sub.Overflow:
+				if err := writeEvent(w, overflowEvent(sub.Dropped(), last)); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// overflowEvent creates the notice sent to a client for which events were dropped
+func overflowEvent(dropped, last uint64) events.Event {
+	data, err := json.Marshal(events.OverflowData{
+		Dropped: dropped,
+		LastSeq: last,
+	})
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	return events.Event{
+		Type: events.TypeOverflow,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+}
+
+// writeEvent writes an event in the server-sent events format.
+// Events without sequence number, such as overflow notices, do not move the client cursor.
+func writeEvent(w http.ResponseWriter, ev events.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	if ev.Seq != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", ev.Seq); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+	return err
+}
+
+// operationEvents publishes an operation_finished event after each request to a device endpoint
+func operationEvents(bus *events.Bus, endpoint string, handler http.Handler) http.Handler {
+	if bus == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := newStatusWriter(w)
+
+		handler.ServeHTTP(sw, r)
+
+		if _, err := bus.Publish(events.TypeOperationFinished, "", OperationEventData{
+			Endpoint:  endpoint,
+			Method:    r.Method,
+			Status:    sw.status,
+			Duration:  int64(time.Since(start) / time.Millisecond),
+			RequestID: requestIDFromRequest(r),
+		}); err != nil {
+			requestLogger(r).WithError(err).Error("failed to publish operation event")
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func newTestBus(t *testing.T) *events.Bus {
+	bus, err := events.NewBus(storage.NewMemoryStore(), 0)
+	require.NoError(t, err)
+	return bus
+}
+
+func TestEventsCatchUp(t *testing.T) {
+	bus := newTestBus(t)
+	for i := 0; i < 3; i++ {
+		_, err := bus.Publish(events.TypeDeviceConnected, "", nil)
+		require.NoError(t, err)
+	}
+	_, err := bus.Publish(events.TypeOperationFinished, "dev", nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		method string
+		query  string
+		header string
+		status int
+		seqs   []uint64
+		// httpResponse is the expected error response
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			query:        "?stream=false",
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - invalid cursor",
+			method:       http.MethodGet,
+			query:        "?stream=false&since=abc",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid event cursor "abc"`),
+		},
+		{
+			name:         "400 - invalid stream",
+			method:       http.MethodGet,
+			query:        "?stream=foo",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid value for stream"),
+		},
+		{
+			name:   "200 - all events",
+			method: http.MethodGet,
+			query:  "?stream=false",
+			status: http.StatusOK,
+			seqs:   []uint64{1, 2, 3, 4},
+		},
+		{
+			name:   "200 - since cursor",
+			method: http.MethodGet,
+			query:  "?stream=false&since=1",
+			status: http.StatusOK,
+			seqs:   []uint64{2, 3, 4},
+		},
+		{
+			name:   "200 - Last-Event-ID",
+			method: http.MethodGet,
+			query:  "?stream=false",
+			header: "2",
+			status: http.StatusOK,
+			seqs:   []uint64{3, 4},
+		},
+		{
+			name:   "200 - up to date",
+			method: http.MethodGet,
+			query:  "?stream=false&since=4",
+			status: http.StatusOK,
+			seqs:   []uint64{},
+		},
+		{
+			name:         "400 - invalid type",
+			method:       http.MethodGet,
+			query:        "?stream=false&types=foo",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid event type "foo"`),
+		},
+		{
+			name:   "200 - types",
+			method: http.MethodGet,
+			query:  "?stream=false&since=1&types=device_connected,device_disconnected",
+			status: http.StatusOK,
+			seqs:   []uint64{2, 3},
+		},
+		{
+			name:   "200 - device",
+			method: http.MethodGet,
+			query:  "?stream=false&device_id=dev",
+			status: http.StatusOK,
+			seqs:   []uint64{4},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "/api/v2/events"+tc.query, nil)
+			require.NoError(t, err)
+			if tc.header != "" {
+				req.Header.Set(lastEventIDHeader, tc.header)
+			}
+
+			cfg := defaultMuxConfig()
+			cfg.events = bus
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, &MockGatewayer{})
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			if rsp.Error != nil {
+				return
+			}
+
+			var evs []events.Event
+			err = json.Unmarshal(rsp.Data, &evs)
+			require.NoError(t, err)
+
+			seqs := []uint64{}
+			for _, ev := range evs {
+				seqs = append(seqs, ev.Seq)
+			}
+			require.Equal(t, tc.seqs, seqs)
+		})
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	bus := newTestBus(t)
+	for i := 0; i < 2; i++ {
+		_, err := bus.Publish(events.TypeDeviceConnected, "", nil)
+		require.NoError(t, err)
+	}
+
+	cfg := defaultMuxConfig()
+	cfg.events = bus
+	server := httptest.NewServer(newServerMux(cfg, &MockGatewayer{}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v2/events", nil)
+	require.NoError(t, err)
+	req = req.WithContext(ctx)
+	req.Header.Set(lastEventIDHeader, "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	readEvent := func() events.Event {
+		var ev events.Event
+		var id string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				require.Equal(t, fmt.Sprint(ev.Seq), id)
+				return ev
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev)
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, scanner.Err())
+		t.Fatal("stream closed")
+		return ev
+	}
+
+	// missed event is replayed first
+	ev := readEvent()
+	require.Equal(t, uint64(2), ev.Seq)
+
+	_, err = bus.Publish(events.TypeDeviceDisconnected, "", nil)
+	require.NoError(t, err)
+
+	ev = readEvent()
+	require.Equal(t, uint64(3), ev.Seq)
+	require.Equal(t, events.TypeDeviceDisconnected, ev.Type)
+}
+
+func TestOperationEvents(t *testing.T) {
+	bus := newTestBus(t)
+
+	cfg := defaultMuxConfig()
+	cfg.events = bus
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/features", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler := newServerMux(cfg, &MockGatewayer{})
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	evs, err := bus.Since(0, 0, events.Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 1)
+	require.Equal(t, events.TypeOperationFinished, evs[0].Type)
+
+	var data OperationEventData
+	err = json.Unmarshal(evs[0].Data, &data)
+	require.NoError(t, err)
+	require.Equal(t, "/features", data.Endpoint)
+	require.Equal(t, http.MethodPost, data.Method)
+	require.Equal(t, http.StatusMethodNotAllowed, data.Status)
+}
+
+func TestWriteOverflowEvent(t *testing.T) {
+	rr := httptest.NewRecorder()
+	err := writeEvent(rr, overflowEvent(5, 42))
+	require.NoError(t, err)
+
+	lines := strings.Split(rr.Body.String(), "\n")
+	require.Len(t, lines, 4)
+	// overflow notices do not move the client cursor
+	require.Equal(t, "event: overflow", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "data: "))
+
+	var ev events.Event
+	err = json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &ev)
+	require.NoError(t, err)
+	require.Equal(t, events.TypeOverflow, ev.Type)
+
+	var data events.OverflowData
+	err = json.Unmarshal(ev.Data, &data)
+	require.NoError(t, err)
+	require.Equal(t, events.OverflowData{
+		Dropped: 5,
+		LastSeq: 42,
+	}, data)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+)
+
+// DefaultFeaturesCacheTTL is how long the features of the device are cached by default
+const DefaultFeaturesCacheTTL = 2 * time.Second
+
+// FeaturesCache is a Gatewayer caching the features of the device for a short time, so the clients polling them
+// do not make a USB round trip each time. The cache is cleared by the operations changing the state of the device,
+// the replies to its requests, such as a PIN, included, when the device is plugged in or out and when the mode is
+// switched.
+type FeaturesCache struct {
+	Gatewayer
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	features *wire.Message
+	expires  time.Time
+	// generation is incremented by each invalidation, the features read across one are not cached
+	generation uint64
+}
+
+// NewFeaturesCache wraps gateway so its features are cached for ttl
+func NewFeaturesCache(gateway Gatewayer, ttl time.Duration) *FeaturesCache {
+	return &FeaturesCache{
+		Gatewayer: gateway,
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// Invalidate clears the cached features
+func (c *FeaturesCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.features = nil
+	c.generation++
+}
+
+// Watch clears the cached features when the device is plugged in or out, until quit is closed
+func (c *FeaturesCache) Watch(bus *events.Bus, quit <-chan struct{}) {
+	sub := bus.Subscribe(events.Filter{
+		Types: []events.Type{events.TypeDeviceConnected, events.TypeDeviceDisconnected},
+	})
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-sub.C:
+			c.Invalidate()
+		case <-sub.Overflow:
+			c.Invalidate()
+		}
+	}
+}
+
+// InvalidateOnSwitch clears the cached features when modeSwitch switches the mode, they are the features of the
+// device of the previous mode
+func (c *FeaturesCache) InvalidateOnSwitch(modeSwitch *modeswitch.Switch) {
+	modeSwitch.OnSwitch(func(skyWallet.DeviceType) {
+		c.Invalidate()
+	})
+}
+
+// GetFeatures returns the cached features, or reads them from the device
+func (c *FeaturesCache) GetFeatures() (wire.Message, error) {
+	c.mu.Lock()
+	if c.features != nil && c.now().Before(c.expires) {
+		msg := *c.features
+		c.mu.Unlock()
+		return msg, nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	msg, err := c.Gatewayer.GetFeatures()
+	if err != nil || msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+		return msg, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.features = &msg
+		c.expires = c.now().Add(c.ttl)
+	}
+	return msg, nil
+}
+
+// ApplySettings changes the settings of the device
+func (c *FeaturesCache) ApplySettings(usePassphrase *bool, label, language string) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.ApplySettings(usePassphrase, label, language)
+}
+
+// ApplySettingsHomescreen changes the settings and the homescreen of the device
+func (c *FeaturesCache) ApplySettingsHomescreen(usePassphrase *bool, label, language string, homescreen []byte) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.ApplySettingsHomescreen(usePassphrase, label, language, homescreen)
+}
+
+// Backup backs up the seed of the device
+func (c *FeaturesCache) Backup() (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.Backup()
+}
+
+// Cancel cancels the operation in progress
+func (c *FeaturesCache) Cancel() (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.Cancel()
+}
+
+// ChangePin changes or removes the PIN of the device
+func (c *FeaturesCache) ChangePin(removePin *bool) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.ChangePin(removePin)
+}
+
+// FirmwareUpload updates the firmware of the device
+func (c *FeaturesCache) FirmwareUpload(payload []byte, hash [32]byte) error {
+	defer c.Invalidate()
+	return c.Gatewayer.FirmwareUpload(payload, hash)
+}
+
+// GenerateMnemonic generates the seed of the device
+func (c *FeaturesCache) GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.GenerateMnemonic(wordCount, usePassphrase)
+}
+
+// GenerateMnemonicWithEntropy generates the seed of the device, mixing entropy into the host entropy
+func (c *FeaturesCache) GenerateMnemonicWithEntropy(wordCount uint32, usePassphrase bool, entropy []byte) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.GenerateMnemonicWithEntropy(wordCount, usePassphrase, entropy)
+}
+
+// Recovery recovers the seed of the device
+func (c *FeaturesCache) Recovery(wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.Recovery(wordCount, usePassphrase, dryRun)
+}
+
+// SetMnemonic sets the seed of the device
+func (c *FeaturesCache) SetMnemonic(mnemonic string) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.SetMnemonic(mnemonic)
+}
+
+// Wipe wipes the device
+func (c *FeaturesCache) Wipe() (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.Wipe()
+}
+
+// PinMatrixAck replies to a PIN matrix request, the PIN is then cached by the device
+func (c *FeaturesCache) PinMatrixAck(p string) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.PinMatrixAck(p)
+}
+
+// WordAck replies to a word request
+func (c *FeaturesCache) WordAck(word string) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.WordAck(word)
+}
+
+// PassphraseAck replies to a passphrase request, the passphrase is then cached by the device
+func (c *FeaturesCache) PassphraseAck(passphrase string) (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.PassphraseAck(passphrase)
+}
+
+// ButtonAck replies to a button request
+func (c *FeaturesCache) ButtonAck() (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.ButtonAck()
+}
+
+// Initialize resets the session of the device, clearing its cached PIN and passphrase
+func (c *FeaturesCache) Initialize() (wire.Message, error) {
+	defer c.Invalidate()
+	return c.Gatewayer.Initialize()
+}
+
+// Connect connects to the device
+func (c *FeaturesCache) Connect() error {
+	defer c.Invalidate()
+	return c.Gatewayer.Connect()
+}
+
+// Disconnect disconnects from the device
+func (c *FeaturesCache) Disconnect() error {
+	defer c.Invalidate()
+	return c.Gatewayer.Disconnect()
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestFeaturesCache(t *testing.T) {
+	features := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: proto.String("687576E45325EDC184C3B968"),
+	})
+
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(features, nil)
+	gateway.On("PinMatrixAck", "123").Return(newReply(t, messages.MessageType_MessageType_Success, &messages.Success{}), nil)
+
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	c := NewFeaturesCache(gateway, time.Second)
+	c.now = func() time.Time {
+		return now
+	}
+
+	get := func() {
+		msg, err := c.GetFeatures()
+		require.NoError(t, err)
+		require.Equal(t, features, msg)
+	}
+
+	get()
+	get()
+	gateway.AssertNumberOfCalls(t, "GetFeatures", 1)
+
+	// the cache expires
+	now = now.Add(time.Second)
+	get()
+	gateway.AssertNumberOfCalls(t, "GetFeatures", 2)
+
+	// the PIN cached by the device changes the features
+	_, err := c.PinMatrixAck("123")
+	require.NoError(t, err)
+	get()
+	gateway.AssertNumberOfCalls(t, "GetFeatures", 3)
+	get()
+	gateway.AssertNumberOfCalls(t, "GetFeatures", 3)
+
+	// the device plugged in or out clears the cache
+	bus, err := events.NewBus(storage.NewMemoryStore(), 10)
+	require.NoError(t, err)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.Watch(bus, quit)
+		close(done)
+	}()
+
+	for i := 0; ; i++ {
+		_, err = bus.Publish(events.TypeDeviceDisconnected, "", nil)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		c.mu.Lock()
+		cleared := c.features == nil
+		c.mu.Unlock()
+		if cleared {
+			break
+		}
+		require.True(t, i < 100, "the features were not cleared")
+	}
+	close(quit)
+	<-done
+}
+
+func TestFeaturesCacheModeSwitch(t *testing.T) {
+	usbFeatures := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: proto.String("687576E45325EDC184C3B968"),
+	})
+	emulatorFeatures := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		DeviceId: proto.String("A1B2C3D4E5F6A1B2C3D4E5F6"),
+	})
+
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(usbFeatures, nil).Once()
+	gateway.On("GetFeatures").Return(emulatorFeatures, nil).Once()
+
+	c := NewFeaturesCache(gateway, time.Minute)
+	modeSwitch := newTestModeSwitch()
+	c.InvalidateOnSwitch(modeSwitch)
+
+	msg, err := c.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, usbFeatures, msg)
+
+	// the features of the emulator are read once switched to it
+	changed, err := modeSwitch.Set(skyWallet.DeviceTypeEmulator)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	msg, err = c.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, emulatorFeatures, msg)
+	msg, err = c.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, emulatorFeatures, msg)
+
+	gateway.AssertExpectations(t)
+}
+
+func TestFeaturesCacheErrors(t *testing.T) {
+	gateway := &MockGatewayer{}
+	failure := newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+		Message: proto.String("failure msg"),
+	})
+	gateway.On("GetFeatures").Return(failure, nil).Once()
+	gateway.On("GetFeatures").Return(wire.Message{}, errors.New("no device")).Once()
+
+	c := NewFeaturesCache(gateway, time.Minute)
+
+	// the failures and errors are not cached
+	msg, err := c.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, failure, msg)
+
+	_, err = c.GetFeatures()
+	require.EqualError(t, err, "no device")
+
+	gateway.AssertExpectations(t)
+}
@@ -21,7 +21,9 @@ func TestFeatures(t *testing.T) {
 	require.NoError(t, err)
 
 	var featuresMsg = &messages.Features{
-		Vendor: newStrPtr("Skycoin Foundation"),
+		Vendor:           newStrPtr("Skycoin Foundation"),
+		NeedsBackup:      newBoolPtr(true),
+		UnfinishedBackup: newBoolPtr(false),
 	}
 
 	featuresMsgBytes, err := featuresMsg.Marshal()
@@ -49,7 +51,7 @@ func TestFeatures(t *testing.T) {
 				Kind: uint16(messages.MessageType_MessageType_Failure),
 				Data: failureMsgBytes,
 			},
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
 		},
 
 		{
@@ -73,7 +75,7 @@ func TestFeatures(t *testing.T) {
 
 			gateway.On("GetFeatures").Return(tc.gatewayFeaturesResult, nil)
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, nil)
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, nil)
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+)
+
+// Lists or purges the firmware images cached by the updates to a release version
+// URI: /api/v1/firmware/cache
+// Method: GET, DELETE
+// Args:
+//  version: release version of the image to purge [DELETE, optional], all the images are purged if empty
+func firmwareCacheHandler(cache *firmware.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			entries, err := cache.List()
+			if err != nil {
+				requestLogger(r).Errorf("firmware cache list failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if entries == nil {
+				entries = []firmware.CacheEntry{}
+			}
+			writeHTTPResponse(w, HTTPResponse{
+				Data: entries,
+			})
+		case http.MethodDelete:
+			var version *firmware.Version
+			if s := r.URL.Query().Get("version"); s != "" {
+				v, err := firmware.ParseVersion(s)
+				if err != nil {
+					resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+					writeHTTPResponse(w, resp)
+					return
+				}
+				version = &v
+			}
+
+			purged, err := cache.Purge(version)
+			switch {
+			case err == firmware.ErrNotCached:
+				resp := NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			case err != nil:
+				requestLogger(r).Errorf("firmware cache purge failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if purged == nil {
+				purged = []firmware.CacheEntry{}
+			}
+			writeHTTPResponse(w, HTTPResponse{
+				Data: purged,
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+)
+
+// newTestFirmwareImage writes a firmware image of version to dir and returns the manifest of the channel publishing it
+func newTestFirmwareImage(t *testing.T, dir, version string) ([]byte, string) {
+	image := bytes.Repeat([]byte(version), 0x100)
+	path := filepath.Join(dir, "skyfirmware-"+version+".bin")
+	require.NoError(t, ioutil.WriteFile(path, image, 0600))
+
+	manifest := fmt.Sprintf(`{"channel":"stable","releases":[{"version":%q,"url":%q,"sha256":%q}]}`,
+		version, path, cipher.SumSHA256(image).Hex())
+	return image, manifest
+}
+
+func TestFirmwareUpdateVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firmware_update")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	image, manifest := newTestFirmwareImage(t, dir, "1.8.0")
+	channel := newTestFirmwareChannel(t, dir, manifest, manifest)
+
+	cases := []struct {
+		name       string
+		query      string
+		noCache    bool
+		noChannel  bool
+		status     int
+		err        string
+		updated    bool
+		cachedSize int
+	}{
+		{
+			name:    "422 - cache disabled",
+			query:   "version=1.8.0",
+			noCache: true,
+			status:  http.StatusUnprocessableEntity,
+			err:     "the firmware cache is disabled, upload the firmware file",
+		},
+		{
+			name:   "422 - invalid version",
+			query:  "version=latest",
+			status: http.StatusUnprocessableEntity,
+			err:    `invalid firmware version "latest"`,
+		},
+		{
+			name:      "404 - not cached without channel",
+			query:     "version=1.8.0",
+			noChannel: true,
+			status:    http.StatusNotFound,
+			err:       firmware.ErrNoChannel.Error(),
+		},
+		{
+			name:   "404 - release not found",
+			query:  "version=1.9.0",
+			status: http.StatusNotFound,
+			err:    firmware.ErrReleaseNotFound.Error(),
+		},
+		{
+			name:       "200 - downloaded",
+			query:      "version=1.8.0",
+			status:     http.StatusOK,
+			updated:    true,
+			cachedSize: 1,
+		},
+		{
+			name:       "200 - cached",
+			query:      "version=v1.8.0",
+			noChannel:  true,
+			status:     http.StatusOK,
+			updated:    true,
+			cachedSize: 1,
+		},
+	}
+
+	cache := firmware.NewCache(filepath.Join(dir, "cache"), cipher.PubKey{})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("FirmwareUpload", image, sha256.Sum256(image[firmwareHeaderSize:])).Return(nil)
+
+			config := defaultMuxConfig()
+			if !tc.noChannel {
+				config.firmwareChannel = channel
+			}
+			if !tc.noCache {
+				config.firmwareCache = cache
+			}
+
+			req, err := http.NewRequest(http.MethodPut, "/api/v2/firmware_update?"+tc.query, nil)
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			newServerMux(config, gateway).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code, rr.Body.String())
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			if tc.err != "" {
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+			} else {
+				require.Nil(t, rsp.Error)
+			}
+
+			if tc.updated {
+				gateway.AssertCalled(t, "FirmwareUpload", image, mock.Anything)
+			} else {
+				gateway.AssertNotCalled(t, "FirmwareUpload", mock.Anything, mock.Anything)
+			}
+
+			entries, err := cache.List()
+			require.NoError(t, err)
+			require.Len(t, entries, tc.cachedSize)
+		})
+	}
+}
+
+func TestFirmwareCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firmware_cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	_, manifest := newTestFirmwareImage(t, dir, "1.8.0")
+	channel := newTestFirmwareChannel(t, dir, manifest, manifest)
+	cache := firmware.NewCache(filepath.Join(dir, "cache"), cipher.PubKey{})
+	_, entry, err := firmware.Image(channel, cache, firmware.Version{Major: 1, Minor: 8})
+	require.NoError(t, err)
+
+	config := defaultMuxConfig()
+	config.firmwareCache = cache
+	handler := newServerMux(config, &MockGatewayer{})
+
+	do := func(method, query string) (int, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, "/api/v2/firmware/cache"+query, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr.Code, rsp
+	}
+
+	entries := func(rsp ReceivedHTTPResponse) []firmware.CacheEntry {
+		var entries []firmware.CacheEntry
+		require.NoError(t, json.Unmarshal(rsp.Data, &entries))
+		return entries
+	}
+
+	status, rsp := do(http.MethodPost, "")
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, rsp = do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, status)
+	listed := entries(rsp)
+	require.Len(t, listed, 1)
+	require.Equal(t, entry.Version, listed[0].Version)
+	require.Equal(t, entry.SHA256, listed[0].SHA256)
+	require.Equal(t, entry.ManifestHash, listed[0].ManifestHash)
+
+	status, rsp = do(http.MethodDelete, "?version=1.8")
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	require.Equal(t, `invalid firmware version "1.8"`, rsp.Error.Message)
+
+	status, rsp = do(http.MethodDelete, "?version=1.9.0")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, firmware.ErrNotCached.Error(), rsp.Error.Message)
+
+	status, rsp = do(http.MethodDelete, "?version=1.8.0")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, entries(rsp), 1)
+
+	status, rsp = do(http.MethodDelete, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, entries(rsp))
+	require.Equal(t, "[]", string(rsp.Data))
+
+	status, rsp = do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "[]", string(rsp.Data))
+}
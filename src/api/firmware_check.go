@@ -0,0 +1,147 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+)
+
+// FirmwareCheckResponse is returned by /api/v1/firmware_check
+type FirmwareCheckResponse struct {
+	// CurrentVersion is empty if the device runs no firmware
+	CurrentVersion  string `json:"current_version"`
+	LatestVersion   string `json:"latest_version"`
+	UpdateAvailable bool   `json:"update_available"`
+	// UpdateHeld is true if an update is available but not offered to the device yet by the staged rollout
+	UpdateHeld   bool              `json:"update_held"`
+	Rollout      *firmware.Rollout `json:"rollout,omitempty"`
+	Release      firmware.Release  `json:"release"`
+	Channel      string            `json:"channel"`
+	ManifestHash string            `json:"manifest_hash"`
+}
+
+// firmwareVersion returns the firmware version of the device, false if it runs no firmware
+func firmwareVersion(f *messages.Features) (firmware.Version, bool) {
+	if f.FwMajor == nil || f.FwMinor == nil || f.FwPatch == nil || f.GetBootloaderMode() {
+		return firmware.Version{}, false
+	}
+
+	return firmware.Version{
+		Major: f.GetFwMajor(),
+		Minor: f.GetFwMinor(),
+		Patch: f.GetFwPatch(),
+	}, true
+}
+
+// Compares the firmware of the device with the latest release of the firmware channel.
+// If a rollout policy is set, available updates can be held depending on the rollout group of the device.
+// URI: /api/v1/firmware_check
+// Method: GET
+func firmwareCheck(gateway Gatewayer, channel *firmware.Channel, rollout *firmware.RolloutPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		manifest, hash, err := channel.Manifest()
+		if err != nil {
+			requestLogger(r).Errorf("firmware check failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusBadGateway, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		release, latest, err := manifest.Latest()
+		if err != nil {
+			requestLogger(r).Errorf("firmware check failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusBadGateway, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// for integration tests
+		if autoPressEmulatorButtons {
+			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
+			if err != nil {
+				requestLogger(r).Error("firmware check failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		var msg wire.Message
+		retCH := make(chan int)
+		errCH := make(chan int)
+		ctx := r.Context()
+
+		go func() {
+			msg, err = gateway.GetFeatures()
+			if err != nil {
+				errCH <- 1
+				return
+			}
+			retCH <- 1
+		}()
+
+		select {
+		case <-retCH:
+			if msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+				HandleFirmwareResponseMessages(w, msg)
+				return
+			}
+
+			features := &messages.Features{}
+			if err := proto.Unmarshal(msg.Data, features); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			check := FirmwareCheckResponse{
+				LatestVersion:   latest.String(),
+				UpdateAvailable: true,
+				Release:         release,
+				Channel:         manifest.Channel,
+				ManifestHash:    hash.Hex(),
+			}
+
+			if current, ok := firmwareVersion(features); ok {
+				check.CurrentVersion = current.String()
+				check.UpdateAvailable = current.Compare(latest) < 0
+			}
+
+			if check.UpdateAvailable && rollout != nil {
+				state := rollout.Rollout(features.GetDeviceId(), release, time.Now())
+				check.Rollout = &state
+				check.UpdateHeld = state.Held
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: check,
+			})
+		case <-errCH:
+			requestLogger(r).Errorf("firmware check failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+		case <-ctx.Done():
+			disConnErr := gateway.Disconnect()
+			if disConnErr != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+			} else {
+				resp := NewHTTPErrorResponse(499, "Client Closed Request")
+				writeHTTPResponse(w, resp)
+			}
+		}
+	}
+}
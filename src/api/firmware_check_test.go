@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+)
+
+var testFirmwareManifest = `{"channel":"stable","releases":[` +
+	`{"version":"1.7.0","url":"https://example.com/skyfirmware-1.7.0.bin","sha256":"aa"},` +
+	`{"version":"1.8.0","url":"https://example.com/skyfirmware-1.8.0.bin","sha256":"bb"}]}`
+
+// newTestFirmwareChannel writes the manifest to a file and returns a channel pinned to pinnedManifest
+func newTestFirmwareChannel(t *testing.T, dir, manifest, pinnedManifest string) *firmware.Channel {
+	path := filepath.Join(dir, "manifest.json")
+	err := ioutil.WriteFile(path, []byte(`{"manifest":`+manifest+`}`), 0600)
+	require.NoError(t, err)
+
+	c, err := firmware.NewChannel(firmware.ChannelConfig{
+		Source:     path,
+		PinnedHash: cipher.SumSHA256([]byte(pinnedManifest)).Hex(),
+	})
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestFirmwareCheck(t *testing.T) {
+	failureMsg := messages.Failure{
+		Code:    messages.FailureType_Failure_NotInitialized.Enum(),
+		Message: newStrPtr("failure msg"),
+	}
+
+	failureMsgBytes, err := failureMsg.Marshal()
+	require.NoError(t, err)
+
+	featuresMsg := func(f *messages.Features) wire.Message {
+		data, err := f.Marshal()
+		require.NoError(t, err)
+		return wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_Features),
+			Data: data,
+		}
+	}
+
+	release := firmware.Release{
+		Version: "1.8.0",
+		URL:     "https://example.com/skyfirmware-1.8.0.bin",
+		SHA256:  "bb",
+	}
+	manifestHash := cipher.SumSHA256([]byte(testFirmwareManifest)).Hex()
+
+	// the release is only offered to canary devices
+	noDevices := 0.0
+	frozenRollout, err := firmware.NewRolloutPolicy([]firmware.RolloutGroup{
+		{
+			Name:    "canary",
+			Devices: []string{"canary-dev"},
+		},
+	}, firmware.RolloutGroup{
+		Percentage: &noDevices,
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name                  string
+		method                string
+		status                int
+		pinnedManifest        string
+		rollout               *firmware.RolloutPolicy
+		gatewayFeaturesResult wire.Message
+		httpResponse          HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+
+		{
+			name:           "502 - manifest not pinned",
+			method:         http.MethodGet,
+			status:         http.StatusBadGateway,
+			pinnedManifest: `{"channel":"stable","releases":[]}`,
+			httpResponse:   NewHTTPErrorResponse(http.StatusBadGateway, firmware.ErrManifestNotPinned.Error()),
+		},
+
+		{
+			name:   "409 - Failure msg",
+			method: http.MethodGet,
+			status: http.StatusConflict,
+			gatewayFeaturesResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
+
+		{
+			name:   "200 - update available",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayFeaturesResult: featuresMsg(&messages.Features{
+				FwMajor: newUint32Ptr(1),
+				FwMinor: newUint32Ptr(7),
+				FwPatch: newUint32Ptr(0),
+			}),
+			httpResponse: HTTPResponse{
+				Data: FirmwareCheckResponse{
+					CurrentVersion:  "1.7.0",
+					LatestVersion:   "1.8.0",
+					UpdateAvailable: true,
+					Release:         release,
+					Channel:         "stable",
+					ManifestHash:    manifestHash,
+				},
+			},
+		},
+
+		{
+			name:    "200 - update held",
+			method:  http.MethodGet,
+			status:  http.StatusOK,
+			rollout: frozenRollout,
+			gatewayFeaturesResult: featuresMsg(&messages.Features{
+				DeviceId: newStrPtr("dev"),
+				FwMajor:  newUint32Ptr(1),
+				FwMinor:  newUint32Ptr(7),
+				FwPatch:  newUint32Ptr(0),
+			}),
+			httpResponse: HTTPResponse{
+				Data: FirmwareCheckResponse{
+					CurrentVersion:  "1.7.0",
+					LatestVersion:   "1.8.0",
+					UpdateAvailable: true,
+					UpdateHeld:      true,
+					Rollout: &firmware.Rollout{
+						Group:      "default",
+						Percentage: 0,
+						Held:       true,
+					},
+					Release:      release,
+					Channel:      "stable",
+					ManifestHash: manifestHash,
+				},
+			},
+		},
+
+		{
+			name:    "200 - update offered to canary",
+			method:  http.MethodGet,
+			status:  http.StatusOK,
+			rollout: frozenRollout,
+			gatewayFeaturesResult: featuresMsg(&messages.Features{
+				DeviceId: newStrPtr("canary-dev"),
+				FwMajor:  newUint32Ptr(1),
+				FwMinor:  newUint32Ptr(7),
+				FwPatch:  newUint32Ptr(0),
+			}),
+			httpResponse: HTTPResponse{
+				Data: FirmwareCheckResponse{
+					CurrentVersion:  "1.7.0",
+					LatestVersion:   "1.8.0",
+					UpdateAvailable: true,
+					Rollout: &firmware.Rollout{
+						Group:      "canary",
+						Percentage: 100,
+					},
+					Release:      release,
+					Channel:      "stable",
+					ManifestHash: manifestHash,
+				},
+			},
+		},
+
+		{
+			name:   "200 - up to date",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayFeaturesResult: featuresMsg(&messages.Features{
+				FwMajor: newUint32Ptr(1),
+				FwMinor: newUint32Ptr(8),
+				FwPatch: newUint32Ptr(0),
+			}),
+			httpResponse: HTTPResponse{
+				Data: FirmwareCheckResponse{
+					CurrentVersion: "1.8.0",
+					LatestVersion:  "1.8.0",
+					Release:        release,
+					Channel:        "stable",
+					ManifestHash:   manifestHash,
+				},
+			},
+		},
+
+		{
+			name:   "200 - bootloader mode",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			gatewayFeaturesResult: featuresMsg(&messages.Features{
+				BootloaderMode: newBoolPtr(true),
+			}),
+			httpResponse: HTTPResponse{
+				Data: FirmwareCheckResponse{
+					LatestVersion:   "1.8.0",
+					UpdateAvailable: true,
+					Release:         release,
+					Channel:         "stable",
+					ManifestHash:    manifestHash,
+				},
+			},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "firmware_check")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/firmware_check"
+			gateway := &MockGatewayer{}
+
+			gateway.On("GetFeatures").Return(tc.gatewayFeaturesResult, nil)
+
+			pinnedManifest := tc.pinnedManifest
+			if pinnedManifest == "" {
+				pinnedManifest = testFirmwareManifest
+			}
+
+			cfg := defaultMuxConfig()
+			cfg.firmwareChannel = newTestFirmwareChannel(t, dir, testFirmwareManifest, pinnedManifest)
+			cfg.firmwareRollout = tc.rollout
+
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var resp FirmwareCheckResponse
+				err = json.Unmarshal(rsp.Data, &resp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data, resp)
+			}
+		})
+	}
+}
@@ -2,20 +2,31 @@ package api
 
 import (
 	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
 )
 
 const (
 	// maxUploadSize is max firmware file size
 	maxUploadSize = 1024 * 1024 // 1 MB
+	// firmwareHeaderSize is the size of the header of the firmware, not covered by the hash sent to the device
+	firmwareHeaderSize = 0x100
 )
 
+// errFirmwareCacheDisabled is returned when a firmware update to a release version is requested without firmware cache
+var errFirmwareCacheDisabled = errors.New("the firmware cache is disabled, upload the firmware file")
+
 // URI: /api/v1/firmware_update
 // Method: PUT
 // Args:
 //  file: firmware file
-func firmwareUpdate(gateway Gatewayer) http.HandlerFunc {
+//  version: release version of the firmware channel, instead of the file. The firmware is read from the cache,
+//           or downloaded from the release channel and cached
+func firmwareUpdate(gateway Gatewayer, channel *firmware.Channel, cache *firmware.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -23,34 +34,44 @@ func firmwareUpdate(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
-		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
-			writeHTTPResponse(w, resp)
-			return
-		}
+		var fileBytes []byte
+		if version := r.URL.Query().Get("version"); version != "" {
+			var ok bool
+			fileBytes, ok = firmwareImage(w, r, channel, cache, version)
+			if !ok {
+				return
+			}
+		} else {
+			r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+			if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
 
-		file, _, err := r.FormFile("file")
-		if err != nil {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
-			writeHTTPResponse(w, resp)
-			return
-		}
-		defer file.Close()
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer file.Close()
 
-		fileBytes, err := ioutil.ReadAll(file)
-		if err != nil {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
-			writeHTTPResponse(w, resp)
-			return
+			fileBytes, err = ioutil.ReadAll(file)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
 		}
 
+		var err error
 		retCH := make(chan int)
 		errCH := make(chan int)
 		ctx := r.Context()
 
 		go func() {
-			err = gateway.FirmwareUpload(fileBytes, sha256.Sum256(fileBytes[0x100:]))
+			err = gateway.FirmwareUpload(fileBytes, sha256.Sum256(fileBytes[firmwareHeaderSize:]))
 			if err != nil {
 				errCH <- 1
 				return
@@ -62,7 +83,7 @@ func firmwareUpdate(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			writeHTTPResponse(w, HTTPResponse{})
 		case <-errCH:
-			logger.Errorf("firmwareUpdate failed: %s", err.Error())
+			requestLogger(r).Errorf("firmwareUpdate failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -77,3 +98,46 @@ func firmwareUpdate(gateway Gatewayer) http.HandlerFunc {
 		}
 	}
 }
+
+// firmwareImage returns the firmware image of the release version from the cache, or downloaded from the release
+// channel, writing the error response if it cannot be read
+func firmwareImage(w http.ResponseWriter, r *http.Request, channel *firmware.Channel, cache *firmware.Cache, version string) ([]byte, bool) {
+	if cache == nil {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, errFirmwareCacheDisabled.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+
+	v, err := firmware.ParseVersion(version)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+
+	data, entry, err := firmware.Image(channel, cache, v)
+	if err != nil {
+		requestLogger(r).Errorf("firmwareUpdate failed: %s", err.Error())
+		var resp HTTPResponse
+		switch err {
+		case firmware.ErrNoChannel, firmware.ErrReleaseNotFound:
+			resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+		case firmware.ErrCacheCorrupted:
+			resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusBadGateway, err.Error())
+		}
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+
+	// the device checks the header and the hash of the firmware, an image too short has none
+	if len(data) <= firmwareHeaderSize {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, fmt.Sprintf("firmware image %s is too short", entry.Version))
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+
+	requestLogger(r).Infof("Updating the firmware to %s, sha256 %s", entry.Version, entry.SHA256)
+	return data, true
+}
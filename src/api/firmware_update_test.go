@@ -41,6 +41,7 @@ binary data
 		header       string
 		emulator     bool
 		httpResponse HTTPResponse
+		apiVersion   string
 	}{
 		{
 			name:         "405",
@@ -65,6 +66,24 @@ binary data
 			data:         postData,
 			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "unexpected EOF"),
 		},
+
+		{
+			name:         "405 v2",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			apiVersion:   apiVersion2,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusMethodNotAllowed, ErrorCategoryMethodNotAllowed, ""),
+		},
+
+		{
+			name:         "400 - EOF v2",
+			method:       http.MethodPut,
+			status:       http.StatusBadRequest,
+			header:       `multipart/form-data; boundary=xxx`,
+			data:         postData,
+			apiVersion:   apiVersion2,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusBadRequest, ErrorCategoryInvalidRequest, "unexpected EOF"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -72,7 +91,7 @@ binary data
 			endpoint := "/firmware_update"
 			gateway := &MockGatewayer{}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, nil)
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), nil)
 			require.NoError(t, err)
 
 			if tc.data != "" {
@@ -98,7 +117,7 @@ binary data
 				err = json.NewDecoder(rr.Body).Decode(&rsp)
 				require.NoError(t, err)
 
-				require.Equal(t, tc.httpResponse.Error, rsp.Error)
+				require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 				if rsp.Data == nil {
 					require.Nil(t, tc.httpResponse.Data)
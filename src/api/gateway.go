@@ -1,14 +1,29 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
 )
 
+// entropyAckSize is the number of entropy bytes sent in reply to an EntropyRequest
+const entropyAckSize = 32
+
 //go:generate mockery -name Gatewayer -case underscore -inpkg -testonly
 
 // Gateway is the api gateway
 type Gateway struct {
-	Device *skyWallet.Device
+	*skyWallet.Device
 }
 
 // NewGateway creates a Gateway
@@ -18,7 +33,328 @@ func NewGateway(device *skyWallet.Device) *Gateway {
 	}
 }
 
+// coinGateway sends the address derivation and signing requests of a Gatewayer through a coin backend
+type coinGateway struct {
+	Gatewayer
+	backend coin.Backend
+}
+
+// NewCoinGateway wraps gateway so its address derivation and signing requests are sent by backend
+func NewCoinGateway(gateway Gatewayer, backend coin.Backend) Gatewayer {
+	return &coinGateway{
+		Gatewayer: gateway,
+		backend:   backend,
+	}
+}
+
+// AddressGen derives the addresses with the backend
+func (g *coinGateway) AddressGen(addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return g.backend.AddressGen(g.Gatewayer, addressN, startIndex, confirmAddress)
+}
+
+// SignMessage signs message with the backend
+func (g *coinGateway) SignMessage(addressIndex int, message string) (wire.Message, error) {
+	return g.backend.SignMessage(g.Gatewayer, addressIndex, message)
+}
+
+// CheckMessageSignature checks the signature of message with the backend
+func (g *coinGateway) CheckMessageSignature(message, signature, address string) (wire.Message, error) {
+	return g.backend.CheckMessageSignature(g.Gatewayer, message, signature, address)
+}
+
+// TransactionSign signs the transaction with the backend
+func (g *coinGateway) TransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	return g.backend.TransactionSign(g.Gatewayer, inputs, outputs)
+}
+
+// DeterministicMnemonic and DeterministicMnemonic24 are the seeds of the 12 and 24 word devices of the
+// deterministic mode, the all zero entropy test vectors of BIP 39. They are public, never store coins on them.
+const (
+	DeterministicMnemonic   = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	DeterministicMnemonic24 = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+)
+
+// ErrNotTestDevice is returned by the deterministic mode when the daemon is not in EMULATOR mode, so the public
+// deterministic mnemonics are never written to a hardware wallet
+var ErrNotTestDevice = errors.New("the deterministic mnemonics seed the emulator or the simulated device only, the daemon is not in EMULATOR mode")
+
+// deterministicGateway seeds the devices of a Gatewayer with the deterministic mnemonics instead of generating a seed
+type deterministicGateway struct {
+	Gatewayer
+	mode      func() skyWallet.DeviceType
+	simulated bool
+}
+
+// NewDeterministicGateway wraps gateway so the generated seeds are the deterministic mnemonics, without entropy from
+// the device nor the host, and the addresses and signatures of the tests are the same across the runs. mode returns
+// the current mode of the daemon, the seeds are refused with ErrNotTestDevice unless it is EMULATOR or the device is
+// simulated.
+func NewDeterministicGateway(gateway Gatewayer, mode func() skyWallet.DeviceType, simulated bool) Gatewayer {
+	return &deterministicGateway{
+		Gatewayer: gateway,
+		mode:      mode,
+		simulated: simulated,
+	}
+}
+
+// GenerateMnemonic sets the deterministic mnemonic of wordCount words. The passphrase protection is not set, it is
+// enabled with ApplySettings.
+func (g *deterministicGateway) GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	if !g.simulated && g.mode() != skyWallet.DeviceTypeEmulator {
+		return wire.Message{}, ErrNotTestDevice
+	}
+
+	switch wordCount {
+	case 12:
+		return g.Gatewayer.SetMnemonic(DeterministicMnemonic)
+	case 24:
+		return g.Gatewayer.SetMnemonic(DeterministicMnemonic24)
+	default:
+		return wire.Message{}, skyWallet.ErrInvalidWordCount
+	}
+}
+
+// GenerateMnemonicWithEntropy sets the deterministic mnemonic of wordCount words, entropy is not mixed. It is
+// refused like GenerateMnemonic, the entropy is not sent to the device either.
+func (g *deterministicGateway) GenerateMnemonicWithEntropy(wordCount uint32, usePassphrase bool, entropy []byte) (wire.Message, error) {
+	return g.GenerateMnemonic(wordCount, usePassphrase)
+}
+
 // Gatewayer interface for Gateway methods
 type Gatewayer interface {
 	skyWallet.Devicer
+	ApplySettingsHomescreen(usePassphrase *bool, label, language string, homescreen []byte) (wire.Message, error)
+	Enumerate() ([]usb.Info, error)
+	GenerateMnemonicWithEntropy(wordCount uint32, usePassphrase bool, entropy []byte) (wire.Message, error)
+	GetRawEntropy(size uint32) ([]byte, error)
+	GetUsbInfo() ([]usb.Info, error)
+	Initialize() (wire.Message, error)
+	Ping(message string) (wire.Message, error)
+}
+
+// ApplySettingsHomescreen sends an ApplySettings request that also replaces the device homescreen.
+// homescreen must already be in the 128x64 monochrome format expected by the firmware.
+func (g *Gateway) ApplySettingsHomescreen(usePassphrase *bool, label, language string, homescreen []byte) (wire.Message, error) {
+	settings := &messages.ApplySettings{
+		UsePassphrase: usePassphrase,
+		Homescreen:    homescreen,
+	}
+
+	if label != "" {
+		settings.Label = proto.String(label)
+	}
+
+	if language != "" {
+		settings.Language = proto.String(language)
+	}
+
+	return g.sendMessage(messages.MessageType_MessageType_ApplySettings, settings)
+}
+
+// GenerateMnemonicWithEntropy sends a GenerateMnemonic request and mixes entropy into the
+// host entropy sent when the device asks for it. The entropy is hashed together with random
+// bytes of the host, so weak entropy cannot lower the quality of the generated seed.
+func (g *Gateway) GenerateMnemonicWithEntropy(wordCount uint32, usePassphrase bool, entropy []byte) (wire.Message, error) {
+	if wordCount != 12 && wordCount != 24 {
+		return wire.Message{}, skyWallet.ErrInvalidWordCount
+	}
+
+	chunks, err := skyWallet.MessageGenerateMnemonic(wordCount, usePassphrase)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	dev, err := g.Driver.GetDevice()
+	if err != nil {
+		return wire.Message{}, err
+	}
+	defer dev.Close(false)
+
+	return g.sendWithEntropy(dev, chunks, entropy)
+}
+
+// GetRawEntropy reads size bytes from the device RNG, with no host entropy mixed in.
+// The device may return less bytes than requested, so the request is repeated until size bytes are read.
+func (g *Gateway) GetRawEntropy(size uint32) ([]byte, error) {
+	dev, err := g.Driver.GetDevice()
+	if err != nil {
+		return nil, err
+	}
+	defer dev.Close(false)
+
+	entropy := make([]byte, 0, size)
+	for uint32(len(entropy)) < size {
+		chunks, err := skyWallet.MessageDeviceGetRawEntropy(size - uint32(len(entropy)))
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := g.Driver.SendToDevice(dev, chunks)
+		if err != nil {
+			return nil, err
+		}
+
+		for msg.Kind == uint16(messages.MessageType_MessageType_ButtonRequest) {
+			chunks, err := skyWallet.MessageButtonAck()
+			if err != nil {
+				return nil, err
+			}
+
+			msg, err = g.Driver.SendToDevice(dev, chunks)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch msg.Kind {
+		case uint16(messages.MessageType_MessageType_Entropy):
+			e, err := skyWallet.DecodeResponseEntropyMessage(msg)
+			if err != nil {
+				return nil, err
+			}
+			if len(e.Entropy) == 0 {
+				return nil, errors.New("device returned no entropy")
+			}
+			entropy = append(entropy, e.Entropy...)
+		case uint16(messages.MessageType_MessageType_Failure):
+			failMsg, err := skyWallet.DecodeFailMsg(msg)
+			if err != nil {
+				return nil, err
+			}
+			return nil, errors.New(failMsg)
+		default:
+			return nil, fmt.Errorf("unexpected response to GetRawEntropy: %s", messages.MessageType(msg.Kind))
+		}
+	}
+
+	return entropy[:size], nil
+}
+
+// Enumerate lists the USB devices found by the transport without connecting to them, unlike GetUsbInfo.
+// It fails in emulator mode, the emulator is not enumerated.
+func (g *Gateway) Enumerate() ([]usb.Info, error) {
+	return g.Driver.GetDeviceInfos()
+}
+
+// Initialize sends an Initialize request without session state, resetting the session of the device
+// so it forgets the passphrase it cached. The device answers with its Features.
+func (g *Gateway) Initialize() (wire.Message, error) {
+	return g.sendMessage(messages.MessageType_MessageType_Initialize, &messages.Initialize{})
+}
+
+// Ping sends a Ping request without button protection, the device answers with a Success echoing message
+func (g *Gateway) Ping(message string) (wire.Message, error) {
+	return g.sendMessage(messages.MessageType_MessageType_Ping, &messages.Ping{
+		Message:          proto.String(message),
+		ButtonProtection: proto.Bool(false),
+	})
+}
+
+// mixEntropy returns the entropy sent in an EntropyAck, the SHA256 of entropy and random host bytes
+func mixEntropy(entropy []byte) []byte {
+	h := sha256.New()
+	h.Write(entropy)                         // nolint: errcheck
+	h.Write(cipher.RandByte(entropyAckSize)) // nolint: errcheck
+	return h.Sum(nil)
+}
+
+// sendWithEntropy sends chunks through the driver and answers the EntropyRequests of the device with entropy mixed by
+// mixEntropy. The skywallet driver answers them itself with random host bytes, the EntropyAck it writes to the device
+// handle is replaced with the mixed entropy.
+func (g *Gateway) sendWithEntropy(dev usb.Device, chunks [][64]byte, entropy []byte) (wire.Message, error) {
+	msg, err := g.Driver.SendToDevice(&entropyDevice{
+		Device:  dev,
+		entropy: entropy,
+	}, chunks)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	for msg.Kind == uint16(messages.MessageType_MessageType_EntropyRequest) {
+		data, err := proto.Marshal(&messages.EntropyAck{
+			Entropy: mixEntropy(entropy),
+		})
+		if err != nil {
+			return wire.Message{}, err
+		}
+
+		msg, err = g.Driver.SendToDevice(dev, messageChunks(wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_EntropyAck),
+			Data: data,
+		}))
+		if err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	return msg, nil
+}
+
+// entropyDevice is a device handle replacing the entropy of the EntropyAck messages written to the device with the
+// entropy mixed by mixEntropy
+type entropyDevice struct {
+	usb.Device
+	entropy []byte
+}
+
+func (dev *entropyDevice) Write(p []byte) (int, error) {
+	// the EntropyAck fits in one packet, its header is "?##" and the message type
+	if len(p) < 5 || string(p[:3]) != "?##" || binary.BigEndian.Uint16(p[3:5]) != uint16(messages.MessageType_MessageType_EntropyAck) {
+		return dev.Device.Write(p)
+	}
+
+	data, err := proto.Marshal(&messages.EntropyAck{
+		Entropy: mixEntropy(dev.entropy),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	chunks := messageChunks(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_EntropyAck),
+		Data: data,
+	})
+	if _, err := dev.Device.Write(chunks[0][:]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sendMessage writes a protobuf message to the device and returns its response.
+// It is used for messages that the skywallet library does not expose through the Devicer interface.
+func (g *Gateway) sendMessage(kind messages.MessageType, pb proto.Message) (wire.Message, error) {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	dev, err := g.Driver.GetDevice()
+	if err != nil {
+		return wire.Message{}, err
+	}
+	defer dev.Close(false)
+
+	return g.Driver.SendToDevice(dev, messageChunks(wire.Message{
+		Kind: uint16(kind),
+		Data: data,
+	}))
+}
+
+// chunkWriter collects the 64 byte packets written by wire.Message.WriteTo
+type chunkWriter struct {
+	chunks [][64]byte
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	var chunk [64]byte
+	n := copy(chunk[:], p)
+	cw.chunks = append(cw.chunks, chunk)
+	return n, nil
+}
+
+// messageChunks splits a wire message into the packets sent over the transport
+func messageChunks(msg wire.Message) [][64]byte {
+	var cw chunkWriter
+	msg.WriteTo(&cw) // nolint: errcheck
+	return cw.chunks
 }
@@ -0,0 +1,588 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/bip39"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/allowlist"
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+)
+
+// fakeDevice replies with a queued message each time the previous request has been written
+type fakeDevice struct {
+	mu      sync.Mutex
+	written bytes.Buffer
+	replies bytes.Buffer
+}
+
+func (d *fakeDevice) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.replies.Read(p)
+}
+
+func (d *fakeDevice) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.written.Write(p)
+}
+
+func (d *fakeDevice) Close(disconnected bool) error {
+	return nil
+}
+
+func (d *fakeDevice) reply(t *testing.T, kind messages.MessageType, pb proto.Message) {
+	var data []byte
+	if pb != nil {
+		var err error
+		data, err = proto.Marshal(pb)
+		require.NoError(t, err)
+	}
+
+	for _, chunk := range messageChunks(wire.Message{
+		Kind: uint16(kind),
+		Data: data,
+	}) {
+		d.replies.Write(chunk[:])
+	}
+}
+
+// sentMessages decodes the messages written to the device
+func (d *fakeDevice) sentMessages(t *testing.T) []*wire.Message {
+	var msgs []*wire.Message
+	r := bytes.NewReader(d.written.Bytes())
+	for r.Len() > 0 {
+		msg, err := wire.ReadFrom(r)
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestMixEntropy(t *testing.T) {
+	entropy := []byte("host provided entropy")
+
+	a := mixEntropy(entropy)
+	b := mixEntropy(entropy)
+	require.Len(t, a, entropyAckSize)
+	require.Len(t, b, entropyAckSize)
+	require.NotEqual(t, a, b)
+	require.NotContains(t, string(a), string(entropy))
+
+	require.Len(t, mixEntropy(nil), entropyAckSize)
+}
+
+// libraryDriver sends the messages as the skywallet driver does, answering the EntropyRequests of the device with
+// random bytes
+type libraryDriver struct {
+	fakeDriver
+	// entropy are the random bytes sent by the driver
+	entropy [][]byte
+}
+
+func (d *libraryDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	for _, chunk := range chunks {
+		if _, err := dev.Write(chunk[:]); err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	msg, err := wire.ReadFrom(dev)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	for msg.Kind == uint16(messages.MessageType_MessageType_EntropyRequest) {
+		entropy := cipher.RandByte(entropyAckSize)
+		d.entropy = append(d.entropy, entropy)
+		data, err := proto.Marshal(&messages.EntropyAck{
+			Entropy: entropy,
+		})
+		if err != nil {
+			return wire.Message{}, err
+		}
+		for _, chunk := range messageChunks(wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_EntropyAck),
+			Data: data,
+		}) {
+			if _, err := dev.Write(chunk[:]); err != nil {
+				return wire.Message{}, err
+			}
+		}
+
+		if msg, err = wire.ReadFrom(dev); err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	for msg.Kind == uint16(messages.MessageType_MessageType_Success) {
+		var success messages.Success
+		if err := proto.Unmarshal(msg.Data, &success); err != nil {
+			return wire.Message{}, err
+		}
+		if success.GetMsgType() != messages.MessageType_MessageType_EntropyAck {
+			break
+		}
+		if msg, err = wire.ReadFrom(dev); err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	return *msg, nil
+}
+
+func TestGenerateMnemonicWithEntropy(t *testing.T) {
+	entropy := []byte{0x00, 0x11, 0x22, 0x33}
+
+	requireEntropyAck := func(t *testing.T, msg *wire.Message) []byte {
+		require.Equal(t, uint16(messages.MessageType_MessageType_EntropyAck), msg.Kind)
+		var ack messages.EntropyAck
+		require.NoError(t, proto.Unmarshal(msg.Data, &ack))
+		require.Len(t, ack.Entropy, entropyAckSize)
+		require.NotEqual(t, entropy, ack.Entropy[:len(entropy)])
+		return ack.Entropy
+	}
+
+	t.Run("driver answering the entropy request", func(t *testing.T) {
+		dev := &fakeDevice{}
+		dev.reply(t, messages.MessageType_MessageType_EntropyRequest, &messages.EntropyRequest{})
+		dev.reply(t, messages.MessageType_MessageType_Success, &messages.Success{
+			MsgType: messages.MessageType_MessageType_EntropyAck.Enum(),
+		})
+		dev.reply(t, messages.MessageType_MessageType_Success, &messages.Success{
+			Message: newStrPtr("Mnemonic successfully configured"),
+		})
+		drv := &libraryDriver{
+			fakeDriver: fakeDriver{dev: dev},
+		}
+		g := NewGateway(&skyWallet.Device{
+			Driver: drv,
+		})
+
+		msg, err := g.GenerateMnemonicWithEntropy(24, false, entropy)
+		require.NoError(t, err)
+		successMsg, err := skyWallet.DecodeSuccessMsg(msg)
+		require.NoError(t, err)
+		require.Equal(t, "Mnemonic successfully configured", successMsg)
+
+		sent := dev.sentMessages(t)
+		require.Len(t, sent, 2)
+		require.Equal(t, uint16(messages.MessageType_MessageType_GenerateMnemonic), sent[0].Kind)
+
+		// the random bytes of the driver are replaced with the mixed entropy
+		require.Len(t, drv.entropy, 1)
+		require.NotEqual(t, drv.entropy[0], requireEntropyAck(t, sent[1]))
+	})
+
+	t.Run("driver returning the entropy request", func(t *testing.T) {
+		dev := &fakeDevice{}
+		drv := &fakeDriver{
+			dev: dev,
+			replies: []wire.Message{
+				newReply(t, messages.MessageType_MessageType_EntropyRequest, &messages.EntropyRequest{}),
+				newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+					Message: newStrPtr("Mnemonic successfully configured"),
+				}),
+			},
+			written: true,
+		}
+		g := NewGateway(&skyWallet.Device{
+			Driver: drv,
+		})
+
+		msg, err := g.GenerateMnemonicWithEntropy(12, false, entropy)
+		require.NoError(t, err)
+		require.Equal(t, uint16(messages.MessageType_MessageType_Success), msg.Kind)
+
+		sent := dev.sentMessages(t)
+		require.Len(t, sent, 2)
+		require.Equal(t, uint16(messages.MessageType_MessageType_GenerateMnemonic), sent[0].Kind)
+		requireEntropyAck(t, sent[1])
+	})
+
+	t.Run("invalid word count", func(t *testing.T) {
+		drv := &fakeDriver{
+			dev: &fakeDevice{},
+		}
+		g := NewGateway(&skyWallet.Device{
+			Driver: drv,
+		})
+
+		_, err := g.GenerateMnemonicWithEntropy(18, false, entropy)
+		require.Equal(t, skyWallet.ErrInvalidWordCount, err)
+		require.Zero(t, drv.sent)
+	})
+}
+
+func TestGenerateMnemonicWithEntropyAllowlist(t *testing.T) {
+	features := func(deviceID string) wire.Message {
+		return newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+			DeviceId: proto.String(deviceID),
+		})
+	}
+
+	// the device is not allowed, only its features are read
+	drv := &fakeDriver{
+		dev:     &fakeDevice{},
+		replies: []wire.Message{features("A1B2C3D4E5F6A1B2C3D4E5F6")},
+	}
+	g := NewGateway(&skyWallet.Device{
+		Driver: allowlist.NewDriver(drv, []string{"5347B2C2A3EF1A06C6A5D2F1"}),
+	})
+
+	_, err := g.GenerateMnemonicWithEntropy(12, false, []byte("entropy"))
+	require.Equal(t, allowlist.ErrNotAllowed, err)
+	require.Equal(t, 1, drv.sent)
+
+	// the allowed device is sent the GenerateMnemonic and the EntropyAck
+	drv = &fakeDriver{
+		dev: &fakeDevice{},
+		replies: []wire.Message{
+			features("5347B2C2A3EF1A06C6A5D2F1"),
+			newReply(t, messages.MessageType_MessageType_EntropyRequest, &messages.EntropyRequest{}),
+			newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+				Message: newStrPtr("Mnemonic successfully configured"),
+			}),
+		},
+	}
+	g = NewGateway(&skyWallet.Device{
+		Driver: allowlist.NewDriver(drv, []string{"5347B2C2A3EF1A06C6A5D2F1"}),
+	})
+
+	msg, err := g.GenerateMnemonicWithEntropy(12, false, []byte("entropy"))
+	require.NoError(t, err)
+	require.Equal(t, uint16(messages.MessageType_MessageType_Success), msg.Kind)
+	require.Equal(t, 3, drv.sent)
+}
+
+// stalledDevice does not answer, reading blocks until it is closed
+type stalledDevice struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (d *stalledDevice) Read(p []byte) (int, error) {
+	<-d.closed
+	return 0, errors.New("device closed")
+}
+
+func (d *stalledDevice) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *stalledDevice) Close(disconnected bool) error {
+	d.closeOnce.Do(func() {
+		close(d.closed)
+	})
+	return nil
+}
+
+func TestGenerateMnemonicWithEntropyTimeout(t *testing.T) {
+	dev := &stalledDevice{
+		closed: make(chan struct{}),
+	}
+	g := NewGateway(&skyWallet.Device{
+		Driver: deadline.NewDriver(&libraryDriver{
+			fakeDriver: fakeDriver{dev: dev},
+		}, deadline.Config{
+			DeviceTimeout: 50 * time.Millisecond,
+		}),
+	})
+
+	_, err := g.GenerateMnemonicWithEntropy(12, false, []byte("entropy"))
+	require.Equal(t, deadline.ErrTimeout, err)
+
+	// the device is closed, unblocking the pending read
+	select {
+	case <-dev.closed:
+	default:
+		t.Fatal("the device was not closed")
+	}
+}
+
+// fakeDriver answers each request sent to the device with the next queued reply
+type fakeDriver struct {
+	dev     usb.Device
+	replies []wire.Message
+	sent    int
+	// written writes the requests to the device
+	written bool
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	d.sent++
+	if d.written {
+		for _, chunk := range chunks {
+			if _, err := dev.Write(chunk[:]); err != nil {
+				return wire.Message{}, err
+			}
+		}
+	}
+	if len(d.replies) == 0 {
+		return wire.Message{}, errors.New("no reply")
+	}
+	msg := d.replies[0]
+	d.replies = d.replies[1:]
+	return msg, nil
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return nil
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return d.dev, nil
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeUSB
+}
+
+func (d *fakeDriver) Close() {}
+
+func newReply(t *testing.T, kind messages.MessageType, pb proto.Message) wire.Message {
+	data, err := proto.Marshal(pb)
+	require.NoError(t, err)
+	return wire.Message{
+		Kind: uint16(kind),
+		Data: data,
+	}
+}
+
+func TestGetRawEntropy(t *testing.T) {
+	entropyReply := func(entropy []byte) wire.Message {
+		return newReply(t, messages.MessageType_MessageType_Entropy, &messages.Entropy{
+			Entropy: entropy,
+		})
+	}
+
+	cases := []struct {
+		name    string
+		size    uint32
+		replies []wire.Message
+		entropy []byte
+		sent    int
+		err     string
+	}{
+		{
+			name:    "single reply",
+			size:    4,
+			replies: []wire.Message{entropyReply([]byte{1, 2, 3, 4})},
+			entropy: []byte{1, 2, 3, 4},
+			sent:    1,
+		},
+		{
+			name: "short replies",
+			size: 5,
+			replies: []wire.Message{
+				entropyReply([]byte{1, 2}),
+				entropyReply([]byte{3, 4}),
+				entropyReply([]byte{5, 6}),
+			},
+			entropy: []byte{1, 2, 3, 4, 5},
+			sent:    3,
+		},
+		{
+			name: "button request",
+			size: 2,
+			replies: []wire.Message{
+				newReply(t, messages.MessageType_MessageType_ButtonRequest, &messages.ButtonRequest{}),
+				entropyReply([]byte{1, 2}),
+			},
+			entropy: []byte{1, 2},
+			sent:    2,
+		},
+		{
+			name:    "empty entropy",
+			size:    2,
+			replies: []wire.Message{entropyReply([]byte{})},
+			err:     "device returned no entropy",
+		},
+		{
+			name: "failure",
+			size: 2,
+			replies: []wire.Message{
+				newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+					Message: proto.String("Action canceled by User"),
+				}),
+			},
+			err: "Action canceled by User",
+		},
+		{
+			name: "unexpected response",
+			size: 2,
+			replies: []wire.Message{
+				newReply(t, messages.MessageType_MessageType_Success, &messages.Success{}),
+			},
+			err: "unexpected response to GetRawEntropy: MessageType_Success",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			drv := &fakeDriver{
+				dev:     &fakeDevice{},
+				replies: tc.replies,
+			}
+			g := NewGateway(&skyWallet.Device{
+				Driver: drv,
+			})
+
+			entropy, err := g.GetRawEntropy(tc.size)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.entropy, entropy)
+			require.Equal(t, tc.sent, drv.sent)
+		})
+	}
+}
+
+// fiberBackend derives the addresses of a fiber coin with another account of the device seed
+type fiberBackend struct {
+	coin.Skycoin
+}
+
+func (fiberBackend) Name() string {
+	return "fibercoin"
+}
+
+func (fiberBackend) AddressGen(device skyWallet.Devicer, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return device.AddressGen(addressN, startIndex+1000, confirmAddress)
+}
+
+func TestCoinGateway(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("AddressGen", uint32(1), uint32(1002), false).Return(newReply(t, messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+		Addresses: []string{"2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"},
+	}), nil)
+	gateway.On("SignMessage", 2, "hello").Return(newReply(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+		SignedMessage: proto.String("signature"),
+	}), nil)
+
+	handler := newServerMux(defaultMuxConfig(), NewCoinGateway(gateway, fiberBackend{}))
+
+	post := func(endpoint string, req interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		r, err := http.NewRequest(http.MethodPost, "/api/v2"+endpoint, bytes.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	// the backend derives the addresses
+	rr := post("/generate_addresses", GenerateAddressesRequest{AddressN: 1, StartIndex: 2})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG")
+
+	// the requests the backend does not change are sent as they are
+	rr = post("/sign_message", SignMessageRequest{AddressN: 2, Message: "hello"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), "signature")
+
+	gateway.AssertExpectations(t)
+}
+
+func TestDeterministicGateway(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("SetMnemonic", DeterministicMnemonic).Return(newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: proto.String("Mnemonic successfully configured"),
+	}), nil)
+	gateway.On("SetMnemonic", DeterministicMnemonic24).Return(newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: proto.String("Mnemonic successfully configured"),
+	}), nil)
+
+	g := NewDeterministicGateway(gateway, func() skyWallet.DeviceType {
+		return skyWallet.DeviceTypeEmulator
+	}, false)
+
+	msg, err := g.GenerateMnemonic(12, false)
+	require.NoError(t, err)
+	require.Equal(t, uint16(messages.MessageType_MessageType_Success), msg.Kind)
+
+	// the entropy is not mixed
+	_, err = g.GenerateMnemonicWithEntropy(24, false, []byte("entropy"))
+	require.NoError(t, err)
+
+	_, err = g.GenerateMnemonic(18, false)
+	require.Equal(t, skyWallet.ErrInvalidWordCount, err)
+
+	gateway.AssertExpectations(t)
+	gateway.AssertNotCalled(t, "GenerateMnemonic", mock.Anything, mock.Anything)
+	gateway.AssertNotCalled(t, "GenerateMnemonicWithEntropy", mock.Anything, mock.Anything, mock.Anything)
+
+	// the mnemonics are valid
+	require.NoError(t, bip39.ValidateMnemonic(DeterministicMnemonic))
+	require.NoError(t, bip39.ValidateMnemonic(DeterministicMnemonic24))
+}
+
+func TestDeterministicGatewayModeSwitch(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("SetMnemonic", DeterministicMnemonic).Return(newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: proto.String("Mnemonic successfully configured"),
+	}), nil)
+
+	modeSwitch := modeswitch.New(&fakeModeDriver{mode: skyWallet.DeviceTypeEmulator}, func(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error) {
+		return &fakeModeDriver{mode: mode}, nil
+	})
+	g := NewDeterministicGateway(gateway, modeSwitch.Mode, false)
+
+	_, err := g.GenerateMnemonic(12, false)
+	require.NoError(t, err)
+
+	// the daemon switched to a hardware wallet, the public mnemonic is not written to it
+	switched, err := modeSwitch.Set(skyWallet.DeviceTypeUSB)
+	require.NoError(t, err)
+	require.True(t, switched)
+
+	_, err = g.GenerateMnemonic(12, false)
+	require.Equal(t, ErrNotTestDevice, err)
+	_, err = g.GenerateMnemonicWithEntropy(12, false, []byte("entropy"))
+	require.Equal(t, ErrNotTestDevice, err)
+	gateway.AssertNumberOfCalls(t, "SetMnemonic", 1)
+
+	rr := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/generate_mnemonic", bytes.NewBufferString(`{"word_count":12}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", ContentTypeJSON)
+	newServerMux(defaultMuxConfig(), g).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusInternalServerError, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), ErrorCategoryForbidden)
+	gateway.AssertNumberOfCalls(t, "SetMnemonic", 1)
+
+	// the simulated device is seeded in any mode
+	g = NewDeterministicGateway(gateway, modeSwitch.Mode, true)
+	_, err = g.GenerateMnemonic(12, false)
+	require.NoError(t, err)
+	gateway.AssertNumberOfCalls(t, "SetMnemonic", 2)
+}
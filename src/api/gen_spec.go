@@ -0,0 +1,45 @@
+// +build ignore
+
+// gen_spec embeds swagger.yml in the daemon, as spec_gen.go, so the API spec is served without the repository.
+// Run with go generate in src/api.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+const (
+	specPath   = "../../swagger.yml"
+	outputPath = "spec_gen.go"
+)
+
+func main() {
+	spec, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if bytes.ContainsRune(spec, '`') {
+		log.Fatalf("%s must not contain backquotes, it is embedded as a raw string", specPath)
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by gen_spec.go from swagger.yml; DO NOT EDIT.\n\n")
+	b.WriteString("package api\n\n")
+	b.WriteString("// swaggerYAML is swagger.yml, the API spec\n")
+	fmt.Fprintf(&b, "const swaggerYAML = `%s`\n", spec)
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(outputPath, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
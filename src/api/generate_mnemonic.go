@@ -1,20 +1,46 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 )
 
+// maxHostEntropySize is the maximum number of bytes of additional entropy accepted from the host
+const maxHostEntropySize = 1024
+
 // GenerateMnemonicRequest is request data for /api/v1/generate_mnemonic
 type GenerateMnemonicRequest struct {
 	WordCount     uint32 `json:"word_count"`
 	UsePassphrase bool   `json:"use_passphrase"`
+	// Entropy is hex encoded additional entropy mixed into the host entropy sent to the device
+	Entropy string `json:"entropy,omitempty"`
+}
+
+// GenerateMnemonicResponse is returned when the device has generated its seed
+type GenerateMnemonicResponse struct {
+	Message   string `json:"message"`
+	WordCount uint32 `json:"word_count"`
+	// EntropyBits is the strength of the seed, 128 bits for 12 words and 256 bits for 24 words
+	EntropyBits int `json:"entropy_bits"`
+	// HostEntropy is true if the additional entropy of the request was mixed into the host entropy
+	HostEntropy bool `json:"host_entropy"`
 }
 
+// mnemonicEntropyBits returns the entropy of a mnemonic with wordCount words
+func mnemonicEntropyBits(wordCount uint32) int {
+	if wordCount == 24 {
+		return 256
+	}
+	return 128
+}
+
+// Generate mnemonic can be used to initialize the device with a random seed.
 // URI: /api/v1/generate_mnemonic
 // Method: POST
 // Args: JSON Body
@@ -41,8 +67,23 @@ func generateMnemonic(gateway Gatewayer) http.HandlerFunc {
 		defer r.Body.Close()
 
 		if req.WordCount != 12 && req.WordCount != 24 {
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "word count must be 12 or 24")
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "word count must be 12 or 24")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var entropy []byte
+		if req.Entropy != "" {
+			var err error
+			entropy, err = hex.DecodeString(req.Entropy)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "invalid entropy: "+err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if len(entropy) > maxHostEntropySize {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "entropy is too large")
 				writeHTTPResponse(w, resp)
 				return
 			}
@@ -52,7 +93,7 @@ func generateMnemonic(gateway Gatewayer) http.HandlerFunc {
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("generateMnemonic failed: %s", err.Error())
+				requestLogger(r).Error("generateMnemonic failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -66,7 +107,11 @@ func generateMnemonic(gateway Gatewayer) http.HandlerFunc {
 		ctx := r.Context()
 
 		go func() {
-			msg, err = gateway.GenerateMnemonic(req.WordCount, req.UsePassphrase)
+			if len(entropy) > 0 {
+				msg, err = gateway.GenerateMnemonicWithEntropy(req.WordCount, req.UsePassphrase, entropy)
+			} else {
+				msg, err = gateway.GenerateMnemonic(req.WordCount, req.UsePassphrase)
+			}
 			if err != nil {
 				errCH <- 1
 				return
@@ -76,9 +121,28 @@ func generateMnemonic(gateway Gatewayer) http.HandlerFunc {
 
 		select {
 		case <-retCH:
-			HandleFirmwareResponseMessages(w, msg)
+			if msg.Kind != uint16(messages.MessageType_MessageType_Success) {
+				HandleFirmwareResponseMessages(w, msg)
+				return
+			}
+
+			successMsg, err := skyWallet.DecodeSuccessMsg(msg)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnauthorized, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: GenerateMnemonicResponse{
+					Message:     successMsg,
+					WordCount:   req.WordCount,
+					EntropyBits: mnemonicEntropyBits(req.WordCount),
+					HostEntropy: len(entropy) > 0,
+				},
+			})
 		case <-errCH:
-			logger.Errorf("generateMnemonic failed: %s", err.Error())
+			requestLogger(r).Errorf("generateMnemonic failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
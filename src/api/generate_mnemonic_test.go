@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,7 +76,7 @@ func TestGenerateMnemonic(t *testing.T) {
 			name:         "409 - Failure msg",
 			method:       http.MethodPost,
 			status:       http.StatusConflict,
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
 			httpBody: toJSON(t, &GenerateMnemonicRequest{
 				WordCount: 12,
 			}),
@@ -84,12 +86,53 @@ func TestGenerateMnemonic(t *testing.T) {
 			},
 		},
 
+		{
+			name:   "422 - invalid entropy",
+			method: http.MethodPost,
+			status: http.StatusUnprocessableEntity,
+			httpBody: toJSON(t, &GenerateMnemonicRequest{
+				WordCount: 12,
+				Entropy:   "zz",
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "invalid entropy: encoding/hex: invalid byte: U+007A 'z'"),
+		},
+
+		{
+			name:   "422 - entropy too large",
+			method: http.MethodPost,
+			status: http.StatusUnprocessableEntity,
+			httpBody: toJSON(t, &GenerateMnemonicRequest{
+				WordCount: 12,
+				Entropy:   strings.Repeat("ab", maxHostEntropySize+1),
+			}),
+			httpResponse: NewHTTPErrorResponse(http.StatusUnprocessableEntity, "entropy is too large"),
+		},
+
+		{
+			name:   "200 - ButtonRequest",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: []string{"ButtonRequest"},
+			},
+			httpBody: toJSON(t, &GenerateMnemonicRequest{
+				WordCount: 12,
+			}),
+			gatewayGenerateMnemonicResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_ButtonRequest),
+			},
+		},
+
 		{
 			name:   "200 - OK",
 			method: http.MethodPost,
 			status: http.StatusOK,
 			httpResponse: HTTPResponse{
-				Data: []string{*successMsg.Message},
+				Data: GenerateMnemonicResponse{
+					Message:     *successMsg.Message,
+					WordCount:   12,
+					EntropyBits: 128,
+				},
 			},
 			httpBody: toJSON(t, &GenerateMnemonicRequest{
 				WordCount: 12,
@@ -99,6 +142,28 @@ func TestGenerateMnemonic(t *testing.T) {
 				Data: successMsgBytes,
 			},
 		},
+
+		{
+			name:   "200 - OK with host entropy",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: GenerateMnemonicResponse{
+					Message:     *successMsg.Message,
+					WordCount:   24,
+					EntropyBits: 256,
+					HostEntropy: true,
+				},
+			},
+			httpBody: toJSON(t, &GenerateMnemonicRequest{
+				WordCount: 24,
+				Entropy:   "00112233445566778899aabbccddeeff",
+			}),
+			gatewayGenerateMnemonicResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Success),
+				Data: successMsgBytes,
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -110,9 +175,13 @@ func TestGenerateMnemonic(t *testing.T) {
 			err := json.Unmarshal([]byte(tc.httpBody), &body)
 			if err == nil {
 				gateway.On("GenerateMnemonic", body.WordCount, body.UsePassphrase).Return(tc.gatewayGenerateMnemonicResult, nil)
+
+				if entropy, err := hex.DecodeString(body.Entropy); err == nil && len(entropy) > 0 {
+					gateway.On("GenerateMnemonicWithEntropy", body.WordCount, body.UsePassphrase, entropy).Return(tc.gatewayGenerateMnemonicResult, nil)
+				}
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
@@ -139,12 +208,15 @@ func TestGenerateMnemonic(t *testing.T) {
 				require.Nil(t, tc.httpResponse.Data)
 			} else {
 				require.NotNil(t, tc.httpResponse.Data)
+				require.JSONEq(t, toJSON(t, tc.httpResponse.Data), string(rsp.Data))
+			}
 
-				var resp []string
-				err = json.Unmarshal(rsp.Data, &resp)
-				require.NoError(t, err)
-
-				require.Equal(t, tc.httpResponse.Data, resp)
+			if tc.status == http.StatusOK {
+				if body.Entropy != "" {
+					gateway.AssertNotCalled(t, "GenerateMnemonic", body.WordCount, body.UsePassphrase)
+				} else {
+					gateway.AssertNotCalled(t, "GenerateMnemonicWithEntropy", body.WordCount, body.UsePassphrase, mock.Anything)
+				}
 			}
 		})
 	}
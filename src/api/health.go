@@ -0,0 +1,210 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+	"github.com/skycoin/hardware-wallet-daemon/src/smoketest"
+	"github.com/skycoin/hardware-wallet-daemon/src/updatecheck"
+)
+
+const (
+	// HealthStatusOK is the status of a daemon able to talk to the device
+	HealthStatusOK = "ok"
+	// HealthStatusDegraded is the status of a daemon whose transport fails, or whose startup checks have not passed
+	HealthStatusDegraded = "degraded"
+
+	// DeviceConnected is reported when the transport finds the device
+	DeviceConnected = "connected"
+	// DeviceDisconnected is reported when the transport finds no device
+	DeviceDisconnected = "disconnected"
+	// DeviceUnknown is reported in emulator mode, the emulator is only found by sending it a message
+	DeviceUnknown = "unknown"
+)
+
+// HealthResponse is returned by /api/v1/health
+type HealthResponse struct {
+	// Status is ok or degraded
+	Status    string    `json:"status"`
+	Daemon    BuildInfo `json:"daemon"`
+	StartedAt time.Time `json:"started_at"`
+	// Uptime is the number of seconds since the daemon started
+	Uptime    int64           `json:"uptime"`
+	Mode      string          `json:"mode"`
+	Transport HealthTransport `json:"transport"`
+	// Device is connected, disconnected or unknown
+	Device string `json:"device"`
+	// Update is the outcome of the daemon update check, if enabled
+	Update *updatecheck.Status `json:"update,omitempty"`
+	// StartupChecks is the outcome of the startup checks, if any
+	StartupChecks *smoketest.Status `json:"startup_checks,omitempty"`
+}
+
+// HealthTransport is the status of the transport to the device
+type HealthTransport struct {
+	// Name is the transport implementation: libusb, hidapi, udp, simulator or replay
+	Name string `json:"name"`
+	// Status is ok or error
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ProbeResponse is returned by /live and /ready
+type ProbeResponse struct {
+	Status string `json:"status"`
+}
+
+// transportCheck is the outcome of enumerating the devices with the transport
+type transportCheck struct {
+	err    error
+	device string
+}
+
+// checkTransport enumerates the devices with the transport of the current mode, without connecting to them,
+// so it does not disturb an operation in progress
+func checkTransport(gateway Gatewayer, mode skyWallet.DeviceType) transportCheck {
+	if mode != skyWallet.DeviceTypeUSB {
+		return transportCheck{
+			device: DeviceUnknown,
+		}
+	}
+
+	infos, err := gateway.Enumerate()
+	if err != nil {
+		return transportCheck{
+			err:    err,
+			device: DeviceUnknown,
+		}
+	}
+
+	if len(infos) == 0 {
+		return transportCheck{
+			device: DeviceDisconnected,
+		}
+	}
+
+	return transportCheck{
+		device: DeviceConnected,
+	}
+}
+
+// healthHandler returns the daemon version and uptime, the status of the transport and whether a device is found.
+// It does not send messages to the device, use the diagnostics to test the device.
+// URI: /api/v1/health
+// Method: GET
+func healthHandler(gateway Gatewayer, c muxConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		mode := c.currentMode()
+		transport := c.transport
+		if c.modeSwitch != nil {
+			transport = modeswitch.TransportName(mode)
+		}
+
+		check := checkTransport(gateway, mode)
+
+		health := HealthResponse{
+			Status:    HealthStatusOK,
+			Daemon:    c.build,
+			StartedAt: c.startedAt.UTC(),
+			Uptime:    int64(time.Since(c.startedAt) / time.Second),
+			Mode:      mode.String(),
+			Transport: HealthTransport{
+				Name:   transport,
+				Status: "ok",
+			},
+			Device: check.device,
+		}
+
+		if check.err != nil {
+			health.Status = HealthStatusDegraded
+			health.Transport.Status = "error"
+			health.Transport.Error = check.err.Error()
+		}
+
+		if c.updateCheck != nil {
+			update := c.updateCheck.Status()
+			health.Update = &update
+		}
+
+		if c.startupChecks != nil {
+			status := c.startupChecks.Status()
+			if !status.Passed {
+				health.Status = HealthStatusDegraded
+			}
+			health.StartupChecks = &status
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: health,
+		})
+	}
+}
+
+// liveHandler answers while the daemon serves requests, for liveness probes
+// URI: /live
+// Method: GET
+func liveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: ProbeResponse{
+				Status: "alive",
+			},
+		})
+	}
+}
+
+// readyHandler answers when the transport to the device works, for readiness probes.
+// A daemon without device plugged in is ready, it answers the device requests with a no device error.
+// A daemon refusing the mutating endpoints until the startup checks pass, or shutting down, is not ready.
+// URI: /ready
+// Method: GET
+func readyHandler(gateway Gatewayer, c muxConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if check := checkTransport(gateway, c.currentMode()); check.err != nil {
+			resp := NewHTTPErrorResponse(http.StatusServiceUnavailable, fmt.Sprintf("transport unavailable: %v", check.err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if c.startupChecks != nil && c.startupChecks.Blocking() {
+			resp := newHTTPErrorResponseCategory(http.StatusServiceUnavailable, ErrorCategoryStartupChecks, "the startup checks have not passed")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if c.drain != nil && c.drain.Draining() {
+			resp := NewHTTPErrorResponse(http.StatusServiceUnavailable, drain.ErrDraining.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: ProbeResponse{
+				Status: "ready",
+			},
+		})
+	}
+}
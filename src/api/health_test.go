@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/updatecheck"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+func TestHealth(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		mode         skyWallet.DeviceType
+		usbInfos     []usb.Info
+		usbErr       error
+		status       int
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:     "200 - connected",
+			method:   http.MethodGet,
+			mode:     skyWallet.DeviceTypeUSB,
+			usbInfos: []usb.Info{{Path: "1-1.2:1.0"}},
+			status:   http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: HealthResponse{
+					Status: HealthStatusOK,
+					Mode:   "USB",
+					Transport: HealthTransport{
+						Name:   "libusb",
+						Status: "ok",
+					},
+					Device: DeviceConnected,
+				},
+			},
+		},
+		{
+			name:   "200 - disconnected",
+			method: http.MethodGet,
+			mode:   skyWallet.DeviceTypeUSB,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: HealthResponse{
+					Status: HealthStatusOK,
+					Mode:   "USB",
+					Transport: HealthTransport{
+						Name:   "libusb",
+						Status: "ok",
+					},
+					Device: DeviceDisconnected,
+				},
+			},
+		},
+		{
+			name:   "200 - transport error",
+			method: http.MethodGet,
+			mode:   skyWallet.DeviceTypeUSB,
+			usbErr: errors.New("LIBUSB_ERROR_ACCESS"),
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: HealthResponse{
+					Status: HealthStatusDegraded,
+					Mode:   "USB",
+					Transport: HealthTransport{
+						Name:   "libusb",
+						Status: "error",
+						Error:  "LIBUSB_ERROR_ACCESS",
+					},
+					Device: DeviceUnknown,
+				},
+			},
+		},
+		{
+			name:   "200 - emulator",
+			method: http.MethodGet,
+			mode:   skyWallet.DeviceTypeEmulator,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: HealthResponse{
+					Status: HealthStatusOK,
+					Mode:   "EMULATOR",
+					Transport: HealthTransport{
+						Name:   "libusb",
+						Status: "ok",
+					},
+					Device: DeviceUnknown,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.mode == skyWallet.DeviceTypeUSB {
+				gateway.On("Enumerate").Return(tc.usbInfos, tc.usbErr)
+			}
+
+			cfg := defaultMuxConfig()
+			cfg.mode = tc.mode
+			cfg.transport = "libusb"
+			cfg.build = BuildInfo{Version: "0.1.0"}
+			cfg.startedAt = time.Now().Add(-time.Minute)
+
+			req, err := http.NewRequest(tc.method, "/api/v2/health", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data != nil {
+				var health HealthResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &health))
+
+				require.Equal(t, cfg.build, health.Daemon)
+				require.True(t, cfg.startedAt.UTC().Equal(health.StartedAt))
+				require.Equal(t, int64(60), health.Uptime)
+
+				expected := tc.httpResponse.Data.(HealthResponse)
+				expected.Daemon = health.Daemon
+				expected.StartedAt = health.StartedAt
+				expected.Uptime = health.Uptime
+				require.Equal(t, expected, health)
+			}
+
+			gateway.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateCheck(t *testing.T) {
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "v0.2.0", "html_url": "https://example.com/v0.2.0", "body": "- Fixes"}`)) // nolint: errcheck
+	}))
+	defer feed.Close()
+
+	checker, err := updatecheck.NewChecker(feed.URL, "0.1.0", updatecheck.DefaultInterval, nil)
+	require.NoError(t, err)
+
+	cfg := defaultMuxConfig()
+	cfg.mode = skyWallet.DeviceTypeEmulator
+	cfg.build = BuildInfo{Version: "0.1.0"}
+
+	get := func(endpoint string) *updatecheck.Status {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		newServerMux(cfg, &MockGatewayer{}).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var rsp struct {
+			Data struct {
+				Version string              `json:"version"`
+				Update  *updatecheck.Status `json:"update"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rsp.Data.Update
+	}
+
+	// the update is not reported without update check
+	require.Nil(t, get("/api/v2/version"))
+	require.Nil(t, get("/api/v2/health"))
+
+	cfg.updateCheck = checker
+	update := get("/api/v2/version")
+	require.NotNil(t, update)
+	require.False(t, update.UpdateAvailable)
+	require.Nil(t, update.Latest)
+
+	require.NoError(t, checker.Check())
+	for _, endpoint := range []string{"/api/v2/version", "/api/v2/health"} {
+		update = get(endpoint)
+		require.NotNil(t, update)
+		require.True(t, update.UpdateAvailable)
+		require.Equal(t, "0.1.0", update.CurrentVersion)
+		require.Equal(t, "0.2.0", update.Latest.Version)
+		require.Equal(t, "- Fixes", update.Latest.Notes)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	cases := []struct {
+		name         string
+		endpoint     string
+		method       string
+		usbErr       error
+		status       int
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "live 405",
+			endpoint:     "/live",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:     "live 200",
+			endpoint: "/live",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: ProbeResponse{
+					Status: "alive",
+				},
+			},
+		},
+		{
+			name:         "ready 405",
+			endpoint:     "/ready",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "ready 503",
+			endpoint:     "/ready",
+			method:       http.MethodGet,
+			usbErr:       errors.New("LIBUSB_ERROR_ACCESS"),
+			status:       http.StatusServiceUnavailable,
+			httpResponse: NewHTTPErrorResponse(http.StatusServiceUnavailable, "transport unavailable: LIBUSB_ERROR_ACCESS"),
+		},
+		{
+			name:     "ready 200",
+			endpoint: "/ready",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: ProbeResponse{
+					Status: "ready",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("Enumerate").Return(nil, tc.usbErr)
+
+			req, err := http.NewRequest(tc.method, tc.endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data != nil {
+				var probe ProbeResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &probe))
+				require.Equal(t, tc.httpResponse.Data, probe)
+			}
+		})
+	}
+}
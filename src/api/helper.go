@@ -10,27 +10,41 @@ import (
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 	messages "github.com/skycoin/hardware-wallet-protob/go"
 	wh "github.com/skycoin/skycoin/src/util/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/i18n"
 )
 
 // HTTPResponse represents the http response struct
 type HTTPResponse struct {
 	Error *HTTPError  `json:"error,omitempty"`
 	Data  interface{} `json:"data,omitempty"`
+	// ModelVersion is the model version of the response, negotiated with the X-Model-Version header
+	ModelVersion int `json:"model_version,omitempty"`
+	// Prompt describes the prompt of the device to show to the user, in the language of the Accept-Language header
+	Prompt string `json:"prompt,omitempty"`
 }
 
 // ReceivedHTTPResponse parsed is a Parsed HTTPResponse
 type ReceivedHTTPResponse struct {
-	Error *HTTPError      `json:"error,omitempty"`
-	Data  json.RawMessage `json:"data"`
+	Error        *HTTPError      `json:"error,omitempty"`
+	Data         json.RawMessage `json:"data"`
+	ModelVersion int             `json:"model_version"`
+	Prompt       string          `json:"prompt,omitempty"`
 }
 
 // HTTPError is included in an HTTPResponse
 type HTTPError struct {
 	Message string `json:"message"`
-	Code    int    `json:"code"`
+	// Code is the status code of the response
+	Code int `json:"code"`
+	// Category names the error for the clients, such as device_disconnected or pin_invalid
+	Category string `json:"category,omitempty"`
+	// RequestID is the ID of the failed request, to find it in the daemon logs
+	RequestID string `json:"request_id,omitempty"`
 }
 
-// NewHTTPErrorResponse returns an HTTPResponse with the Error field populated
+// NewHTTPErrorResponse returns an HTTPResponse with the Error field populated, in the category of the error
+// of the message or of the status code
 func NewHTTPErrorResponse(code int, msg string) HTTPResponse {
 	if msg == "" {
 		msg = http.StatusText(code)
@@ -38,13 +52,34 @@ func NewHTTPErrorResponse(code int, msg string) HTTPResponse {
 
 	return HTTPResponse{
 		Error: &HTTPError{
-			Code:    code,
-			Message: msg,
+			Code:     code,
+			Message:  msg,
+			Category: errorCategory(code, msg),
 		},
 	}
 }
 
 func writeHTTPResponse(w http.ResponseWriter, resp HTTPResponse) {
+	writeHTTPResponseStatus(w, http.StatusOK, resp)
+}
+
+// writeHTTPResponseStatus writes resp with the given status code if it is not an error response
+func writeHTTPResponseStatus(w http.ResponseWriter, status int, resp HTTPResponse) {
+	if resp.Error != nil {
+		httpErr := *resp.Error
+		if id := w.Header().Get(RequestIDHeaderName); id != "" {
+			httpErr.RequestID = id
+		}
+		httpErr.Message = i18n.Translate(responseLanguage(w), httpErr.Message)
+		resp.Error = &httpErr
+	}
+
+	if resp.Prompt != "" {
+		resp.Prompt = i18n.Translate(responseLanguage(w), resp.Prompt)
+	}
+
+	resp = convertModelVersion(resp, responseModelVersion(w))
+
 	out, err := json.MarshalIndent(resp, "", "    ")
 	if err != nil {
 		wh.Error500(w, "json.MarshalIndent failed")
@@ -54,7 +89,7 @@ func writeHTTPResponse(w http.ResponseWriter, resp HTTPResponse) {
 	w.Header().Add("Content-Type", ContentTypeJSON)
 
 	if resp.Error == nil {
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(status)
 	} else {
 		if resp.Error.Code < 400 || resp.Error.Code >= 600 {
 			logger.Critical().Errorf("writeHTTPResponse invalid error status code: %d", resp.Error.Code)
@@ -74,29 +109,32 @@ func HandleFirmwareResponseMessages(w http.ResponseWriter, msg wire.Message) {
 	switch msg.Kind {
 	case uint16(messages.MessageType_MessageType_PinMatrixRequest):
 		writeHTTPResponse(w, HTTPResponse{
-			Data: []string{"PinMatrixRequest"},
+			Data:   []string{"PinMatrixRequest"},
+			Prompt: promptPinMatrix,
 		})
 	case uint16(messages.MessageType_MessageType_PassphraseRequest):
 		writeHTTPResponse(w, HTTPResponse{
-			Data: []string{"PassPhraseRequest"},
+			Data:   []string{"PassPhraseRequest"},
+			Prompt: promptPassphrase,
 		})
 	case uint16(messages.MessageType_MessageType_WordRequest):
 		writeHTTPResponse(w, HTTPResponse{
-			Data: []string{"WordRequest"},
+			Data:   []string{"WordRequest"},
+			Prompt: promptWord,
 		})
 	case uint16(messages.MessageType_MessageType_ButtonRequest):
 		writeHTTPResponse(w, HTTPResponse{
-			Data: []string{"ButtonRequest"},
+			Data:   []string{"ButtonRequest"},
+			Prompt: promptButton,
 		})
 	case uint16(messages.MessageType_MessageType_Failure):
-		failureMsg, err := skyWallet.DecodeFailMsg(msg)
-		if err != nil {
+		failure := &messages.Failure{}
+		if err := proto.Unmarshal(msg.Data, failure); err != nil {
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 			return
 		}
-		resp := NewHTTPErrorResponse(http.StatusConflict, failureMsg)
-		writeHTTPResponse(w, resp)
+		writeHTTPResponse(w, newFailureResponse(failure))
 	case uint16(messages.MessageType_MessageType_Success):
 		successMsg, err := skyWallet.DecodeSuccessMsg(msg)
 		if err != nil {
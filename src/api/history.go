@@ -0,0 +1,259 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// auditEndpoints are the device endpoints that change the device or sign with its keys,
+// their requests are recorded in the audit log as well as in the history
+var auditEndpoints = map[string]struct{}{
+	"/apply_settings":     {},
+	"/backup":             {},
+	"/configure_pin_code": {},
+	"/firmware_update":    {},
+	"/generate_mnemonic":  {},
+	"/recovery":           {},
+	"/set_mnemonic":       {},
+	"/sign_message":       {},
+	"/transaction_sign":   {},
+	"/wipe":               {},
+
+	"/partial_transaction/sign": {},
+}
+
+// historyLogs maps the log query parameter of the history endpoint to the storage buckets
+var historyLogs = map[string]string{
+	"history": storage.HistoryBucket,
+	"audit":   storage.AuditBucket,
+}
+
+// historyLog returns the log requested by the log query parameter and its bucket
+func historyLog(r *http.Request) (string, string, error) {
+	logName := r.URL.Query().Get("log")
+	if logName == "" {
+		logName = "history"
+	}
+
+	bucket, ok := historyLogs[logName]
+	if !ok {
+		return "", "", fmt.Errorf("invalid log %q, must be history or audit", logName)
+	}
+
+	return logName, bucket, nil
+}
+
+// historyFilter returns the filter selecting the requested records
+func historyFilter(r *http.Request) (history.Filter, error) {
+	filter := history.Filter{
+		Trace: r.URL.Query().Get("trace"),
+	}
+
+	if deleted := r.URL.Query().Get("deleted"); deleted != "" {
+		d, err := strconv.ParseBool(deleted)
+		if err != nil {
+			return history.Filter{}, fmt.Errorf("invalid value for deleted %q", deleted)
+		}
+		filter.Deleted = d
+	}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return history.Filter{}, fmt.Errorf("invalid value for since %q", since)
+		}
+		filter.Since = seq
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return history.Filter{}, fmt.Errorf("invalid value for limit %q", limit)
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+// HistoryPurgeResponse is returned by DELETE /api/v1/history
+type HistoryPurgeResponse struct {
+	// Purged is the number of records purged
+	Purged int `json:"purged"`
+}
+
+// historyHandler returns or purges the operation history or audit records, the oldest first.
+// Records carry the trace headers sent by the client, so they can be joined with client side logs.
+// Purged records are hidden, and removed by the retention policy once the purge grace period is over.
+// Purging records is recorded in the audit log.
+// URI: /api/v1/history
+// Method: GET, DELETE
+// Args:
+//  log: history or audit [optional, defaults to history]
+//  trace: only return the records with a trace header of this value [optional, GET]
+//  since: only return the records with a sequence number greater than since [optional, GET]
+//  limit: maximum number of records returned [optional, GET]
+//  deleted: return the purged records as well [optional, GET]
+//  through: purge the records with a sequence number up to through [optional, DELETE, defaults to all records]
+func historyHandler(recorder *history.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		_, bucket, err := historyLog(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			purgeHistory(w, r, recorder, bucket)
+			return
+		}
+
+		filter, err := historyFilter(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		records, err := recorder.Records(bucket, filter)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: records,
+		})
+	}
+}
+
+// historyExportHandler exports the operation history or audit records as a tamper evident archive:
+// the records are hash chained and the archive is signed by the daemon export key.
+// The archive is written as is, it must not be reformatted for its signature to verify.
+// URI: /api/v1/history/export
+// Method: GET
+// Args:
+//  log: history or audit [optional, defaults to history]
+//  trace: only export the records with a trace header of this value [optional]
+//  since: only export the records with a sequence number greater than since [optional]
+//  limit: maximum number of records exported [optional]
+//  deleted: export the purged records as well [optional]
+func historyExportHandler(recorder *history.Recorder, key *history.ExportKey) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		logName, bucket, err := historyLog(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		filter, err := historyFilter(r)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		archive, err := recorder.Export(bucket, filter, key)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		filename := fmt.Sprintf("%s-%s.json", logName, time.Now().UTC().Format("20060102T150405Z"))
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if _, err := w.Write(archive); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write history archive")
+		}
+	}
+}
+
+// purgeHistory purges the records of bucket requested by DELETE /api/v1/history and records the purge in the audit log
+func purgeHistory(w http.ResponseWriter, r *http.Request, recorder *history.Recorder, bucket string) {
+	var through uint64
+	if v := r.URL.Query().Get("through"); v != "" {
+		seq, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid value for through %q", v))
+			writeHTTPResponse(w, resp)
+			return
+		}
+		through = seq
+	}
+
+	start := time.Now()
+	n, err := recorder.Purge(bucket, through)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	if err := recorder.Record(history.Record{
+		Time:      start.UTC(),
+		Endpoint:  "/history?log=" + bucket,
+		Method:    r.Method,
+		Status:    http.StatusOK,
+		Duration:  int64(time.Since(start) / time.Millisecond),
+		RequestID: requestIDFromRequest(r),
+		Trace:     recorder.Trace(r.Header),
+	}, true); err != nil {
+		requestLogger(r).WithError(err).Error("failed to record history purge")
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: HistoryPurgeResponse{
+			Purged: n,
+		},
+	})
+}
+
+// operationHistory records each request to a device endpoint in the history, and in the audit log
+// for the endpoints that change the device or sign with its keys, with the trace headers of the request
+func operationHistory(recorder *history.Recorder, endpoint string, handler http.Handler) http.Handler {
+	if recorder == nil {
+		return handler
+	}
+
+	_, audit := auditEndpoints[endpoint]
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := newStatusWriter(w)
+
+		handler.ServeHTTP(sw, r)
+
+		if err := recorder.Record(history.Record{
+			Time:      start.UTC(),
+			Endpoint:  endpoint,
+			Method:    r.Method,
+			Status:    sw.status,
+			Duration:  int64(time.Since(start) / time.Millisecond),
+			RequestID: requestIDFromRequest(r),
+			Trace:     recorder.Trace(r.Header),
+		}, audit); err != nil {
+			requestLogger(r).WithError(err).Error("failed to record operation history")
+		}
+	})
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestHistory(t *testing.T) {
+	recorder := history.NewRecorder(storage.NewMemoryStore(), history.DefaultTraceHeaders)
+	records := []struct {
+		rec   history.Record
+		audit bool
+	}{
+		{
+			rec: history.Record{
+				Endpoint: "/features",
+				Method:   http.MethodGet,
+				Status:   http.StatusOK,
+			},
+		},
+		{
+			rec: history.Record{
+				Endpoint: "/sign_message",
+				Method:   http.MethodPost,
+				Status:   http.StatusOK,
+				Trace:    map[string]string{"x-request-id": "req-1"},
+			},
+			audit: true,
+		},
+		{
+			rec: history.Record{
+				Endpoint: "/intermediate/button",
+				Method:   http.MethodPost,
+				Status:   http.StatusOK,
+				Trace:    map[string]string{"x-request-id": "req-1"},
+			},
+		},
+	}
+	for _, r := range records {
+		require.NoError(t, recorder.Record(r.rec, r.audit))
+	}
+
+	cases := []struct {
+		name         string
+		method       string
+		query        string
+		status       int
+		endpoints    []string
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - invalid log",
+			method:       http.MethodGet,
+			query:        "?log=events",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid log "events", must be history or audit`),
+		},
+		{
+			name:         "400 - invalid since",
+			method:       http.MethodGet,
+			query:        "?since=abc",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid value for since "abc"`),
+		},
+		{
+			name:         "400 - invalid limit",
+			method:       http.MethodGet,
+			query:        "?limit=-1",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid value for limit "-1"`),
+		},
+		{
+			name:         "400 - invalid deleted",
+			method:       http.MethodGet,
+			query:        "?deleted=maybe",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid value for deleted "maybe"`),
+		},
+		{
+			name:      "200 - history",
+			method:    http.MethodGet,
+			status:    http.StatusOK,
+			endpoints: []string{"/features", "/sign_message", "/intermediate/button"},
+		},
+		{
+			name:      "200 - audit",
+			method:    http.MethodGet,
+			query:     "?log=audit",
+			status:    http.StatusOK,
+			endpoints: []string{"/sign_message"},
+		},
+		{
+			name:      "200 - trace",
+			method:    http.MethodGet,
+			query:     "?trace=req-1",
+			status:    http.StatusOK,
+			endpoints: []string{"/sign_message", "/intermediate/button"},
+		},
+		{
+			name:      "200 - since and limit",
+			method:    http.MethodGet,
+			query:     "?since=1&limit=1",
+			status:    http.StatusOK,
+			endpoints: []string{"/sign_message"},
+		},
+		{
+			name:      "200 - no record",
+			method:    http.MethodGet,
+			query:     "?log=audit&trace=unknown",
+			status:    http.StatusOK,
+			endpoints: []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "/api/v2/history"+tc.query, nil)
+			require.NoError(t, err)
+
+			cfg := defaultMuxConfig()
+			cfg.history = recorder
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, &MockGatewayer{})
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rr.Body).Decode(&rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			if rsp.Error != nil {
+				return
+			}
+
+			var recs []history.Record
+			err = json.Unmarshal(rsp.Data, &recs)
+			require.NoError(t, err)
+
+			endpoints := []string{}
+			for _, rec := range recs {
+				endpoints = append(endpoints, rec.Endpoint)
+			}
+			require.Equal(t, tc.endpoints, endpoints)
+		})
+	}
+}
+
+func TestHistoryPurge(t *testing.T) {
+	recorder := history.NewRecorder(storage.NewMemoryStore(), history.DefaultTraceHeaders)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, recorder.Record(history.Record{
+			Endpoint: "/wipe",
+			Method:   http.MethodDelete,
+			Status:   http.StatusOK,
+		}, true))
+	}
+
+	cfg := defaultMuxConfig()
+	cfg.history = recorder
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method, query string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, "/api/v2/history"+query, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Request-Id", "req-1")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	rr, rsp := do(http.MethodDelete, "?log=events")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, `invalid log "events", must be history or audit`).Error, rsp.Error)
+
+	rr, rsp = do(http.MethodDelete, "?through=abc")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, `invalid value for through "abc"`).Error, rsp.Error)
+
+	rr, rsp = do(http.MethodDelete, "?log=audit&through=2")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"purged": 2}`, string(rsp.Data))
+
+	// the purged records are hidden unless requested, and the purge is audited
+	rr, rsp = do(http.MethodGet, "?log=audit")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var recs []history.Record
+	require.NoError(t, json.Unmarshal(rsp.Data, &recs))
+	require.Len(t, recs, 2)
+	require.Equal(t, uint64(3), recs[0].Seq)
+	require.Equal(t, "/history?log=audit", recs[1].Endpoint)
+	require.Equal(t, http.MethodDelete, recs[1].Method)
+	require.Equal(t, map[string]string{"x-request-id": "req-1"}, recs[1].Trace)
+
+	rr, rsp = do(http.MethodGet, "?log=audit&deleted=true")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.Unmarshal(rsp.Data, &recs))
+	require.Len(t, recs, 4)
+	require.NotNil(t, recs[0].DeletedAt)
+	require.Nil(t, recs[2].DeletedAt)
+
+	// the history is purged separately, it holds the audited records and the purge too
+	rr, rsp = do(http.MethodDelete, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"purged": 4}`, string(rsp.Data))
+}
+
+func TestHistoryExport(t *testing.T) {
+	recorder := history.NewRecorder(storage.NewMemoryStore(), history.DefaultTraceHeaders)
+	for _, endpoint := range []string{"/sign_message", "/wipe"} {
+		require.NoError(t, recorder.Record(history.Record{
+			Endpoint: endpoint,
+			Method:   http.MethodPost,
+			Status:   http.StatusOK,
+		}, true))
+	}
+
+	pubKey, secKey := cipher.GenerateKeyPair()
+	cfg := defaultMuxConfig()
+	cfg.history = recorder
+	cfg.historyExportKey = &history.ExportKey{
+		PubKey: pubKey,
+		SecKey: secKey,
+	}
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method, query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v2/history/export"+query, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPost, "")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	rr = do(http.MethodGet, "?log=events")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	var rsp ReceivedHTTPResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, `invalid log "events", must be history or audit`).Error, rsp.Error)
+
+	rr = do(http.MethodGet, "?log=audit&since=1")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, ContentTypeJSON, rr.Header().Get("Content-Type"))
+	require.Contains(t, rr.Header().Get("Content-Disposition"), `attachment; filename="audit-`)
+
+	archive, err := history.VerifyArchive(rr.Body.Bytes(), pubKey)
+	require.NoError(t, err)
+	require.Equal(t, storage.AuditBucket, archive.Bucket)
+	require.Len(t, archive.Records, 1)
+
+	var rec history.Record
+	require.NoError(t, json.Unmarshal(archive.Records[0].Record, &rec))
+	require.Equal(t, "/wipe", rec.Endpoint)
+
+	// the export endpoint is only served with an export key
+	cfg.historyExportKey = nil
+	handler = newServerMux(cfg, &MockGatewayer{})
+	rr = do(http.MethodGet, "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestOperationHistory(t *testing.T) {
+	recorder := history.NewRecorder(storage.NewMemoryStore(), history.DefaultTraceHeaders)
+
+	cfg := defaultMuxConfig()
+	cfg.history = recorder
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	// requests rejected by the handler are recorded with their status
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/sign_message", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	req, err = http.NewRequest(http.MethodPost, "/api/v2/generate_addresses", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Correlation-Id", "corr-1")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+	// reading the history is not recorded
+	req, err = http.NewRequest(http.MethodGet, "/api/v2/history", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	recs, err := recorder.Records(storage.HistoryBucket, history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+
+	require.Equal(t, "/sign_message", recs[0].Endpoint)
+	require.Equal(t, http.MethodGet, recs[0].Method)
+	require.Equal(t, http.StatusMethodNotAllowed, recs[0].Status)
+	require.Equal(t, map[string]string{
+		"x-request-id": "req-1",
+		"traceparent":  "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}, recs[0].Trace)
+
+	require.Equal(t, "/generate_addresses", recs[1].Endpoint)
+	require.Equal(t, http.StatusUnsupportedMediaType, recs[1].Status)
+	require.Equal(t, map[string]string{"x-correlation-id": "corr-1"}, recs[1].Trace)
+
+	audit, err := recorder.Records(storage.AuditBucket, history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, audit, 1)
+	require.Equal(t, "/sign_message", audit[0].Endpoint)
+	require.Equal(t, recs[0].Trace, audit[0].Trace)
+}
+
+func TestHistoryCORS(t *testing.T) {
+	cfg := defaultMuxConfig()
+	cfg.history = history.NewRecorder(storage.NewMemoryStore(), []string{"X-Request-Id"})
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	req, err := http.NewRequest(http.MethodOptions, "/api/v2/features", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://wallet.skycoin.net")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "X-Request-Id")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "X-Request-Id", rr.Header().Get("Access-Control-Allow-Headers"))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	// register the image formats accepted for homescreen uploads
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// HomescreenWidth is the width in pixels of the device homescreen
+	HomescreenWidth = 128
+	// HomescreenHeight is the height in pixels of the device homescreen
+	HomescreenHeight = 64
+
+	// homescreenSize is the size of a packed 1 bit per pixel homescreen
+	homescreenSize = HomescreenWidth * HomescreenHeight / 8
+)
+
+var (
+	// ErrHomescreenEmpty is returned when an empty homescreen is uploaded
+	ErrHomescreenEmpty = errors.New("homescreen cannot be empty")
+)
+
+// convertHomescreen converts an uploaded homescreen into the packed monochrome
+// format the firmware expects: 128x64 pixels, 1 bit per pixel, row major, most significant bit first.
+// The input may either already be in that raw format or be a PNG, GIF or JPEG image of exactly 128x64 pixels.
+func convertHomescreen(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrHomescreenEmpty
+	}
+
+	if len(data) == homescreenSize {
+		if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+			// not a recognized image, assume it is already packed
+			return data, nil
+		}
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid homescreen image: %v", err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() != HomescreenWidth || bounds.Dy() != HomescreenHeight {
+		return nil, fmt.Errorf("homescreen %s image must be %dx%d pixels, got %dx%d",
+			format, HomescreenWidth, HomescreenHeight, bounds.Dx(), bounds.Dy())
+	}
+
+	out := make([]byte, homescreenSize)
+	for y := 0; y < HomescreenHeight; y++ {
+		for x := 0; x < HomescreenWidth; x++ {
+			if !pixelLit(img.At(bounds.Min.X+x, bounds.Min.Y+y)) {
+				continue
+			}
+
+			i := y*HomescreenWidth + x
+			out[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+
+	return out, nil
+}
+
+// pixelLit reports whether a pixel maps to a lit pixel on the monochrome display.
+// Transparent pixels are treated as unlit.
+func pixelLit(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	if a < 0x8000 {
+		return false
+	}
+
+	gray := color.GrayModel.Convert(c).(color.Gray)
+	return gray.Y >= 0x80
+}
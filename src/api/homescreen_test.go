@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestConvertHomescreen(t *testing.T) {
+	lit := image.NewNRGBA(image.Rect(0, 0, HomescreenWidth, HomescreenHeight))
+	lit.Set(0, 0, color.White)
+	lit.Set(9, 0, color.White)
+	lit.Set(HomescreenWidth-1, HomescreenHeight-1, color.White)
+	// transparent white pixel must stay unlit
+	lit.Set(1, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x10})
+	// dark gray pixel must stay unlit
+	lit.Set(2, 0, color.Gray{Y: 0x20})
+
+	litPacked := make([]byte, homescreenSize)
+	litPacked[0] = 0x80
+	litPacked[1] = 0x40
+	litPacked[homescreenSize-1] = 0x01
+
+	raw := make([]byte, homescreenSize)
+	raw[10] = 0xaa
+
+	cases := []struct {
+		name string
+		data []byte
+		out  []byte
+		err  string
+	}{
+		{
+			name: "empty",
+			err:  ErrHomescreenEmpty.Error(),
+		},
+		{
+			name: "raw bitmap",
+			data: raw,
+			out:  raw,
+		},
+		{
+			name: "png",
+			data: encodePNG(t, lit),
+			out:  litPacked,
+		},
+		{
+			name: "wrong size",
+			data: encodePNG(t, image.NewGray(image.Rect(0, 0, 64, 64))),
+			err:  "homescreen png image must be 128x64 pixels, got 64x64",
+		},
+		{
+			name: "garbage",
+			data: []byte("foo"),
+			err:  "invalid homescreen image: image: unknown format",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := convertHomescreen(tc.data)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.out, out)
+		})
+	}
+}
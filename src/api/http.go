@@ -7,12 +7,40 @@ import (
 	"os"
 	"regexp"
 	"runtime"
+	"time"
 
 	"github.com/NYTimes/gziphandler"
-	"github.com/rs/cors"
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
-	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/addressbook"
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/auditlog"
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+	"github.com/skycoin/hardware-wallet-daemon/src/emulator"
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/idempotency"
+	"github.com/skycoin/hardware-wallet-daemon/src/inventory"
+	"github.com/skycoin/hardware-wallet-daemon/src/loglevel"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+	"github.com/skycoin/hardware-wallet-daemon/src/plugin"
+	"github.com/skycoin/hardware-wallet-daemon/src/profiling"
+	"github.com/skycoin/hardware-wallet-daemon/src/relay"
+	"github.com/skycoin/hardware-wallet-daemon/src/session"
+	"github.com/skycoin/hardware-wallet-daemon/src/signwindow"
+	"github.com/skycoin/hardware-wallet-daemon/src/smoketest"
+	"github.com/skycoin/hardware-wallet-daemon/src/stats"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+	"github.com/skycoin/hardware-wallet-daemon/src/tracing"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+	"github.com/skycoin/hardware-wallet-daemon/src/updatecheck"
+	"github.com/skycoin/hardware-wallet-daemon/src/wirecapture"
 )
 
 const (
@@ -20,8 +48,6 @@ const (
 	ContentTypeJSON = "application/json"
 	// ContentTypeForm form data content type header
 	ContentTypeForm = "application/x-www-form-urlencoded"
-
-	apiVersion1 = "v1"
 )
 
 var (
@@ -61,20 +87,203 @@ type Config struct {
 	HostWhitelist      []string
 	Mode               skyWallet.DeviceType
 	Build              BuildInfo
+	// Attestation is the build and binary hash served on the attest endpoint, nil disables the endpoint
+	Attestation *Attestation
+	// TrustedBootloaders are the lowercase hex encoded hashes of the genuine bootloaders, checked by the device
+	// authenticity endpoint. Empty skips the bootloader check, the devices are not verified
+	TrustedBootloaders []string
+	// U2F relays the U2F registrations and authentications to the device, nil disables the u2f endpoints
+	U2F U2FRelay
+	// Coin is the coin backend validating the destination addresses and the generated addresses, nil validates them
+	// as Skycoin addresses
+	Coin coin.Backend
+	// AllowedIPs are the networks the requests are accepted from, besides the loopback addresses. Empty allows every
+	// address. The requests forwarded by the relay or over native messaging are not checked.
+	AllowedIPs []*net.IPNet
+	// BasicAuth are the credentials the requests received on the listener must carry, nil disables the basic auth
+	BasicAuth *BasicAuth
+	// BehindProxy takes the client address and scheme from the X-Forwarded-For and X-Forwarded-Proto headers
+	BehindProxy bool
+	// TrustedProxies are the networks of the reverse proxies whose forwarding headers are taken with BehindProxy,
+	// besides the loopback addresses
+	TrustedProxies []*net.IPNet
+	// CSRF configures the CSRF tokens checked with EnableCSRF
+	CSRF CSRFConfig
+	// SecurityHeaders are set on all the responses
+	SecurityHeaders SecurityHeaders
+	// Store persists daemon state such as history, audit and inventory records
+	Store storage.Store
+	// Events is the event bus served on the events endpoint, nil disables the endpoint
+	Events *events.Bus
+	// History records the device operations with the trace headers of the requests, nil disables the history
+	History *history.Recorder
+	// HistoryExportKey signs the history archives, nil disables the history export
+	HistoryExportKey *history.ExportKey
+	// AuditLog is the audit log file of the security relevant operations, nil disables it
+	AuditLog *auditlog.Log
+	// ConfirmationTimeout is how long the confirmation token of a destructive operation stays valid
+	ConfirmationTimeout time.Duration
+	// FirmwareChannel is the firmware release channel, nil disables the firmware check endpoint
+	FirmwareChannel *firmware.Channel
+	// FirmwareRollout is the staged rollout policy of firmware updates, nil offers updates to all devices
+	FirmwareRollout *firmware.RolloutPolicy
+	// FirmwareCache holds the firmware images downloaded from the release channel, nil disables the updates to a
+	// release version and the firmware cache endpoints
+	FirmwareCache *firmware.Cache
+	// Profiler captures the profiles of the admin profiling endpoints, nil disables them. They also need AdminToken
+	Profiler *profiling.Profiler
+	// LogLevel changes the log level from the admin endpoints, nil disables the admin log level endpoint. It also
+	// needs AdminToken
+	LogLevel *loglevel.Controller
+	// WireCapture captures the messages exchanged with the device from the admin endpoints, nil disables the admin
+	// wire capture endpoints. It also needs AdminToken
+	WireCapture *wirecapture.Capture
+	// UpdateCheck reports the daemon release newer than the build on the health and version endpoints, nil disables it
+	UpdateCheck *updatecheck.Checker
+	// Transport names the transport to the device reported by the diagnostics endpoint: libusb, hidapi, udp, simulator or replay
+	Transport string
+	// FaultInjection is true if the daemon injects transport faults
+	FaultInjection bool
+	// SessionIdleTimeout is how long a session stays open without operations before the device is locked
+	SessionIdleTimeout time.Duration
+	// RequireSession rejects the device requests made outside of a session
+	RequireSession bool
+	// RateLimits are the request budgets of each client IP on the device endpoints, nil disables rate limiting
+	RateLimits *RateLimits
+	// CORS sets the origins of the browser wallets allowed to call the API
+	CORS CORSConfig
+	// DisableRequestID disables the request IDs of the X-Request-Id header
+	DisableRequestID bool
+	// ModeSwitch switches the mode at runtime, nil keeps the mode set by Mode
+	ModeSwitch *modeswitch.Switch
+	// AdminToken authenticates the requests to the admin endpoints, empty disables them
+	AdminToken string
+	// Tracer traces the requests and their device messages, nil disables tracing
+	Tracer *tracing.Tracer
+	// Stats collects the statistics of the device messages, nil disables the metrics endpoint
+	Stats *stats.Collector
+	// Relay serves the clients paired through a relay server, nil disables the relay endpoints
+	Relay *relay.Relay
+	// Emulator runs the emulator process, nil disables the emulator endpoints
+	Emulator *emulator.Emulator
+	// Approvals holds the transactions spending more than ApprovalThreshold for approval, nil disables the approvals
+	Approvals *approval.Manager
+	// ApprovalThreshold is the number of droplets a transaction can spend without approval
+	ApprovalThreshold uint64
+	// ApprovalToken authenticates the requests of the approver to the approval endpoints
+	ApprovalToken string
+	// SigningWindow allows signing transactions and messages within its windows only, nil allows signing at any time
+	SigningWindow *signwindow.Policy
+	// TransactionPolicy evaluates the transactions before they are signed, nil signs them without policy
+	TransactionPolicy *txpolicy.Engine
+	// TransactionLimits bound the transactions signed and the size of their requests, the zero value bounds nothing
+	TransactionLimits TransactionLimits
+	// Plugins are the plugins started by the daemon, their endpoints are served under /plugins/{name}/
+	Plugins []*plugin.Client
+	// AddressBook holds the contacts the transactions are sent to by name, nil disables the address book
+	AddressBook *addressbook.Book
+	// Inventory keeps the metadata of the devices seen by the daemon, nil disables it
+	Inventory *inventory.Inventory
+	// Idempotency records the responses of the requests sent with an idempotency key, nil ignores the keys
+	Idempotency *idempotency.Store
+	// Node is the Skycoin node or explorer the balances and transactions are queried from, nil disables their endpoints
+	Node *node.Client
+	// StartupChecks is reported by the health endpoints and may refuse the mutating endpoints until it passes,
+	// nil disables the startup checks
+	StartupChecks *smoketest.Suite
+	// DeviceDeadline bounds the device messages of the requests by their context, nil leaves them unbounded
+	DeviceDeadline *deadline.Driver
+	// Drain tracks the device operations in flight, the daemon waits for them when it shuts down
+	Drain *drain.Tracker
+	// DeviceLock serves the device operations one at a time, nil lets them run concurrently
+	DeviceLock *devicelock.Lock
+	// ReadOnly refuses the requests changing the device or signing with its keys, only the endpoints deriving the
+	// addresses, reading the features and verifying are served
+	ReadOnly bool
+	// DisableAPIV1 stops serving the deprecated v1 endpoints, they are served under /api/v2 only
+	DisableAPIV1 bool
+	// APIV1Sunset is when the v1 endpoints stop being served, announced in their Sunset header, zero omits the header
+	APIV1Sunset time.Time
+	// DisableAPIDocs disables the spec endpoint and the Swagger UI page exploring the API
+	DisableAPIDocs bool
+	// APIDocsAssetsURL is where the Swagger UI page loads its scripts and styles from, defaults to DefaultAPIDocsAssetsURL
+	APIDocsAssetsURL string
 }
 
 type muxConfig struct {
-	host               string
-	enableCSRF         bool
-	disableHeaderCheck bool
-	hostWhitelist      []string
-	mode               skyWallet.DeviceType
-	build              BuildInfo
+	host                string
+	enableCSRF          bool
+	csrf                CSRFConfig
+	disableHeaderCheck  bool
+	hostWhitelist       []string
+	behindProxy         bool
+	mode                skyWallet.DeviceType
+	build               BuildInfo
+	attestation         *Attestation
+	trustedBootloaders  []string
+	u2f                 U2FRelay
+	coin                coin.Backend
+	store               storage.Store
+	events              *events.Bus
+	history             *history.Recorder
+	historyExportKey    *history.ExportKey
+	auditLog            *auditlog.Log
+	confirmationTimeout time.Duration
+	firmwareChannel     *firmware.Channel
+	firmwareRollout     *firmware.RolloutPolicy
+	firmwareCache       *firmware.Cache
+	updateCheck         *updatecheck.Checker
+	profiler            *profiling.Profiler
+	logLevel            *loglevel.Controller
+	wireCapture         *wirecapture.Capture
+	transport           string
+	faultInjection      bool
+	sessions            *session.Manager
+	rateLimiter         *rateLimiter
+	cors                CORSConfig
+	requestID           bool
+	modeSwitch          *modeswitch.Switch
+	adminToken          string
+	tracer              *tracing.Tracer
+	stats               *stats.Collector
+	relay               *relay.Relay
+	emulator            *emulator.Emulator
+	approvals           *approval.Manager
+	approvalThreshold   uint64
+	approvalToken       string
+	signingWindow       *signwindow.Policy
+	transactionPolicy   *txpolicy.Engine
+	transactionLimits   TransactionLimits
+	plugins             []*plugin.Client
+	addressBook         *addressbook.Book
+	inventory           *inventory.Inventory
+	idempotency         *idempotency.Store
+	node                *node.Client
+	startupChecks       *smoketest.Suite
+	deviceDeadline      *deadline.Driver
+	drain               *drain.Tracker
+	deviceLock          *devicelock.Lock
+	cancelGrace         time.Duration
+	readOnly            bool
+	disableAPIV1        bool
+	apiV1Sunset         time.Time
+	disableAPIDocs      bool
+	apiDocsAssetsURL    string
+	startedAt           time.Time
+}
+
+// currentMode returns the mode of the daemon, which may be switched at runtime
+func (c muxConfig) currentMode() skyWallet.DeviceType {
+	if c.modeSwitch != nil {
+		return c.modeSwitch.Mode()
+	}
+	return c.mode
 }
 
 // Server exposes an HTTP API
 type Server struct {
 	server   *http.Server
+	handler  http.Handler
 	listener net.Listener
 	done     chan struct{}
 }
@@ -91,9 +300,14 @@ func (s *Server) Serve() error {
 	return nil
 }
 
+// Handler returns the handler serving the API, it serves the requests forwarded by the relay server
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
 // Shutdown closes the HTTP service. This can only be called after Serve or ServeHTTPS has been called.
 func (s *Server) Shutdown() {
-	if s == nil {
+	if s == nil || s.listener == nil {
 		return
 	}
 
@@ -105,44 +319,119 @@ func (s *Server) Shutdown() {
 	<-s.done
 }
 
-func create(host string, c Config, gateway *Gateway) *Server {
+func create(host string, c Config, gateway Gatewayer) *Server {
 	mc := muxConfig{
-		host:               host,
-		enableCSRF:         c.EnableCSRF,
-		disableHeaderCheck: c.DisableHeaderCheck,
-		hostWhitelist:      c.HostWhitelist,
-		mode:               c.Mode,
-		build:              c.Build,
+		host:                host,
+		enableCSRF:          c.EnableCSRF,
+		csrf:                c.CSRF,
+		disableHeaderCheck:  c.DisableHeaderCheck,
+		hostWhitelist:       c.HostWhitelist,
+		behindProxy:         c.BehindProxy,
+		mode:                c.Mode,
+		build:               c.Build,
+		attestation:         c.Attestation,
+		trustedBootloaders:  c.TrustedBootloaders,
+		u2f:                 c.U2F,
+		coin:                c.Coin,
+		store:               c.Store,
+		events:              c.Events,
+		history:             c.History,
+		historyExportKey:    c.HistoryExportKey,
+		auditLog:            c.AuditLog,
+		confirmationTimeout: c.ConfirmationTimeout,
+		firmwareChannel:     c.FirmwareChannel,
+		firmwareRollout:     c.FirmwareRollout,
+		firmwareCache:       c.FirmwareCache,
+		updateCheck:         c.UpdateCheck,
+		profiler:            c.Profiler,
+		logLevel:            c.LogLevel,
+		wireCapture:         c.WireCapture,
+		transport:           c.Transport,
+		faultInjection:      c.FaultInjection,
+		cors:                c.CORS,
+		requestID:           !c.DisableRequestID,
+		modeSwitch:          c.ModeSwitch,
+		adminToken:          c.AdminToken,
+		tracer:              c.Tracer,
+		stats:               c.Stats,
+		relay:               c.Relay,
+		emulator:            c.Emulator,
+		approvals:           c.Approvals,
+		approvalThreshold:   c.ApprovalThreshold,
+		approvalToken:       c.ApprovalToken,
+		signingWindow:       c.SigningWindow,
+		transactionPolicy:   c.TransactionPolicy,
+		transactionLimits:   c.TransactionLimits,
+		plugins:             c.Plugins,
+		addressBook:         c.AddressBook,
+		inventory:           c.Inventory,
+		idempotency:         c.Idempotency,
+		node:                c.Node,
+		startupChecks:       c.StartupChecks,
+		deviceDeadline:      c.DeviceDeadline,
+		drain:               c.Drain,
+		deviceLock:          c.DeviceLock,
+		cancelGrace:         cancelGrace,
+		readOnly:            c.ReadOnly,
+		disableAPIV1:        c.DisableAPIV1,
+		apiV1Sunset:         c.APIV1Sunset,
+		disableAPIDocs:      c.DisableAPIDocs,
+		apiDocsAssetsURL:    c.APIDocsAssetsURL,
+		startedAt:           time.Now(),
+		sessions: session.NewManager(session.Config{
+			IdleTimeout: c.SessionIdleTimeout,
+			Required:    c.RequireSession,
+		}, lockDevice(gateway, c.DeviceLock)),
 	}
 
-	srvMux := newServerMux(mc, gateway.Device)
+	if c.RateLimits != nil {
+		mc.rateLimiter = newRateLimiter(*c.RateLimits)
+	}
+
+	srvMux := newServerMux(mc, gateway)
 
+	// the allowlist and the basic auth apply to the requests received on the listener, from the client address
+	// forwarded by the proxy
+	handler := basicAuth(c.BasicAuth, []string{c.AdminToken, c.ApprovalToken}, srvMux)
+	handler = ipAllowlist(c.AllowedIPs, handler)
+	handler = behindProxy(c.BehindProxy, c.TrustedProxies, handler)
 	srv := &http.Server{
-		Handler: srvMux,
+		Handler: securityHeaders(c.SecurityHeaders, handler),
 	}
 
 	return &Server{
-		server: srv,
-		done:   make(chan struct{}),
+		server:  srv,
+		handler: securityHeaders(c.SecurityHeaders, srvMux),
+		done:    make(chan struct{}),
 	}
 }
 
 // Create create a new http server
-func Create(host string, c Config, gateway *Gateway) (*Server, error) {
+func Create(host string, c Config, gateway Gatewayer) (*Server, error) {
 	listener, err := net.Listen("tcp", host)
 	if err != nil {
 		return nil, err
 	}
 
+	return CreateWithListener(listener, c, gateway), nil
+}
+
+// CreateWithoutListener creates a new Server which does not listen, its Handler serves the API with native messaging
+func CreateWithoutListener(host string, c Config, gateway Gatewayer) *Server {
+	return create(host, c, gateway)
+}
+
+// CreateWithListener creates a new Server serving on a listener opened beforehand, such as a socket passed by systemd
+func CreateWithListener(listener net.Listener, c Config, gateway Gatewayer) *Server {
 	// If the host did not specify a port, allowing the kernel to assign one,
 	// we need to get the assigned address to know the full hostname
-	host = listener.Addr().String()
+	host := listener.Addr().String()
 
 	s := create(host, c, gateway)
 
 	s.listener = listener
 
-	return s, nil
+	return s
 }
 
 func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
@@ -150,14 +439,18 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 
 	allowedOrigins := []string{
 		fmt.Sprintf("http://%s", c.host),
-		"https://staging.wallet.skycoin.net",
-		"https://wallet.skycoin.net",
 	}
 
 	for _, s := range c.hostWhitelist {
 		allowedOrigins = append(allowedOrigins, fmt.Sprintf("http://%s", s))
+		if c.behindProxy {
+			// the proxy may serve the hosts over TLS
+			allowedOrigins = append(allowedOrigins, fmt.Sprintf("https://%s", s))
+		}
 	}
 
+	corsOrigins := newCORSOrigins(c.cors.Origins)
+
 	corsValidator := func(origin string) bool {
 		if corsRegex.MatchString(origin) {
 			return true
@@ -169,48 +462,146 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 			}
 		}
 
-		return false
+		return corsOrigins.match(origin)
+	}
+
+	// only the configured origins listed without wildcard may send credentials
+	var credentialsValidator func(origin string) bool
+	if c.cors.AllowCredentials {
+		credentialsValidator = corsOrigins.matchExact
+	}
+
+	allowedHeaders := []string{"Origin", "Accept", "Content-Type", "X-Requested-With", CSRFHeaderName, ModelVersionHeaderName}
+	if c.history != nil {
+		// browser clients must be allowed to send the trace headers recorded in the history
+		allowedHeaders = append(allowedHeaders, c.history.TraceHeaders()...)
+	}
+	if c.sessions != nil {
+		allowedHeaders = append(allowedHeaders, SessionHeaderName)
+	}
+
+	// let browser wallets back off when rate limited, and read the model version of the responses
+	exposedHeaders := []string{"Retry-After", ModelVersionHeaderName}
+	if !c.disableAPIV1 {
+		// and learn the v1 endpoints they call are deprecated
+		exposedHeaders = append(exposedHeaders, DeprecationHeaderName, SunsetHeaderName, "Link")
+	}
+	if c.adminToken != "" {
+		allowedHeaders = append(allowedHeaders, "Authorization")
+	}
+	if c.requestID {
+		allowedHeaders = append(allowedHeaders, RequestIDHeaderName)
+		exposedHeaders = append(exposedHeaders, RequestIDHeaderName)
+	}
+	if c.idempotency != nil {
+		allowedHeaders = append(allowedHeaders, IdempotencyKeyHeaderName)
+		exposedHeaders = append(exposedHeaders, IdempotentReplayedHeaderName)
+	}
+	// browser wallets revalidate the cacheable responses
+	allowedHeaders = append(allowedHeaders, "If-None-Match")
+	exposedHeaders = append(exposedHeaders, "ETag")
+	if c.enableCSRF && c.csrf.Rotate {
+		// and read the next CSRF token
+		exposedHeaders = append(exposedHeaders, CSRFHeaderName)
 	}
 
-	corsHandler := cors.New(cors.Options{
-		AllowOriginFunc:    corsValidator,
-		Debug:              false,
-		AllowedMethods:     []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPut},
-		AllowedHeaders:     []string{"Origin", "Accept", "Content-Type", "X-Requested-With", CSRFHeaderName},
-		AllowCredentials:   false, // credentials are not used, but it would be safe to enable if necessary
-		OptionsPassthrough: false,
-	})
+	corsHandler := newCORSPolicy(corsValidator, credentialsValidator, allowedHeaders, exposedHeaders)
 
 	headerCheck := func(host string, hostWhitelist []string, handler http.Handler) http.Handler {
-		handler = originRefererCheck(host, hostWhitelist, handler)
+		handler = originRefererCheck(host, hostWhitelist, corsOrigins.match, handler)
 		handler = hostCheck(host, hostWhitelist, handler)
 		return handler
 	}
 
-	webHandlerWithOptionals := func(endpoint string, handlerFunc http.Handler, checkCSRF, checkHeaders bool) {
-		handler := wh.ElapsedHandler(logger, handlerFunc)
-
-		handler = corsHandler.Handler(handler)
+	var csrf *csrfTokens
+	if c.enableCSRF {
+		csrf = newCSRFTokens(c.csrf)
+	}
 
+	wrapHandler := func(handler http.Handler, checkCSRF, checkHeaders bool) http.Handler {
 		if checkCSRF {
-			handler = CSRFCheck(c.enableCSRF, handler)
+			handler = csrfCheck(csrf, handler)
 		}
 
+		// the CSRF errors carry the CORS headers, so browser wallets can read them and fetch a new token
+		handler = corsHandler.handler(handler)
+
 		if checkHeaders {
 			handler = headerCheck(c.host, c.hostWhitelist, handler)
 		}
 
+		return handler
+	}
+
+	webHandlerWithOptionals := func(endpoint string, handler http.Handler, checkCSRF, checkHeaders bool) {
+		handler = elapsedHandler(handler)
+		handler = wrapHandler(handler, checkCSRF, checkHeaders)
 		handler = gziphandler.GzipHandler(handler)
+		handler = requestTracing(c.tracer, endpoint, handler)
+		handler = negotiateModelVersion(endpoint, handler)
+		handler = negotiateLanguage(handler)
+		handler = requestID(c.requestID, handler)
 
-		mux.Handle(endpoint, handler)
+		mux.Handle(endpoint, apiV1Deprecated(c.apiV1Sunset, endpoint, handler))
+	}
+
+	// the versioned endpoints are served under /api/v2, and under the deprecated /api/v1 unless it is disabled
+	apiPaths := func(endpoint string) []string {
+		paths := []string{"/api/" + apiVersion2 + endpoint}
+		if !c.disableAPIV1 {
+			paths = append(paths, "/api/"+apiVersion1+endpoint)
+		}
+		return paths
+	}
+
+	// streaming handlers are not gzipped nor wrapped by the elapsed handler, both buffer the response
+	streamHandler := func(endpoint string, handler http.Handler) {
+		handler = wrapHandler(handler, c.enableCSRF, !c.disableHeaderCheck)
+		for _, path := range apiPaths(endpoint) {
+			mux.Handle(path, apiV1Deprecated(c.apiV1Sunset, path, requestID(c.requestID, negotiateLanguage(negotiateModelVersion(path, handler)))))
+		}
 	}
 
 	webHandler := func(endpoint string, handler http.Handler) {
 		webHandlerWithOptionals(endpoint, handler, c.enableCSRF, !c.disableHeaderCheck)
 	}
 
-	webHandlerV1 := func(endpoint string, handler http.Handler) {
-		webHandler("/api/"+apiVersion1+endpoint, handler)
+	// the responses of the cacheable endpoints carry an ETag
+	apiHandler := func(endpoint string, handler http.Handler) {
+		handler = entityTag(endpoint, handler)
+		for _, path := range apiPaths(endpoint) {
+			webHandler(path, handler)
+		}
+	}
+
+	// the signatures and firmware updates waiting for the user input are held across their intermediate requests
+	outcomes := newOutcomeEvents(c.events)
+	// the devices seen and the addresses they generated are recorded in the inventory
+	devices := newInventoryRecorder(c.inventory, gateway)
+	// the device ID of the status endpoint is the one of the features last read
+	deviceIDs := &deviceIDRecorder{}
+
+	// device endpoints are rate limited and only serve the open session, the mutating ones may wait for the startup checks,
+	// the ones which are not read-only are refused in read-only mode,
+	// they are refused while the daemon shuts down, they publish an operation_finished event and are recorded in the history when they complete,
+	// they wait for the device operation in progress, their device messages are traced in the span of the request,
+	// the retries of the state-changing ones sent with an idempotency key get the original response
+	deviceHandler := func(endpoint string, handler http.Handler) {
+		handler = operationTracing(c.tracer, handler)
+		handler = operationOutcomeEvents(outcomes, endpoint, handler)
+		handler = operationInventory(devices, endpoint, handler)
+		handler = operationDeviceID(deviceIDs, endpoint, handler)
+		handler = operationAudit(c.auditLog, gateway, endpoint, handler)
+		handler = operationDeadline(c.deviceDeadline, handler)
+		handler = operationLock(c.deviceLock, endpoint, handler)
+		handler = operationIdempotency(c.idempotency, endpoint, handler)
+		handler = operationSession(c.sessions, handler)
+		handler = rateLimit(c.rateLimiter, endpoint, handler)
+		handler = startupChecksGate(c.startupChecks, endpoint, handler)
+		handler = readOnlyGate(c.readOnly, endpoint, handler)
+		handler = operationDrain(c.drain, handler)
+		handler = operationHistory(c.history, endpoint, handler)
+		apiHandler(endpoint, operationEvents(c.events, endpoint, handler))
 	}
 
 	if autoPressEmulatorButtons && c.mode != skyWallet.DeviceTypeEmulator {
@@ -218,36 +609,203 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	}
 
 	// get the current CSRF token
-	csrfHandlerV1 := func(endpoint string, handler http.Handler) {
-		webHandlerWithOptionals("/api/"+apiVersion1+endpoint, handler, false, !c.disableHeaderCheck)
+	csrfHandler := func(endpoint string, handler http.Handler) {
+		for _, path := range apiPaths(endpoint) {
+			webHandlerWithOptionals(path, handler, false, !c.disableHeaderCheck)
+		}
 	}
-	csrfHandlerV1("/csrf", getCSRFToken(c.enableCSRF)) // csrf is always available, regardless of the API set
+	csrfHandler("/csrf", getCSRFToken(csrf)) // csrf is always available, regardless of the API set
+
+	confirmations := newConfirmationTokens(c.confirmationTimeout)
 
 	// hw daemon endpoints
-	webHandlerV1("/generate_addresses", generateAddresses(gateway))
-	webHandlerV1("/apply_settings", applySettings(gateway))
-	webHandlerV1("/backup", backup(gateway))
-	webHandlerV1("/cancel", cancel(gateway))
-	webHandlerV1("/check_message_signature", checkMessageSignature(gateway))
-	webHandlerV1("/features", features(gateway))
+	deviceHandler("/generate_addresses", generateAddresses(gateway, c.coin))
+	deviceHandler("/account_export", accountExport(gateway))
+	deviceHandler("/apply_settings", applySettings(gateway))
+	deviceHandler("/backup", backup(gateway))
+	deviceHandler("/cancel", cancel(gateway, c.deviceLock, c.cancelGrace))
+	deviceHandler("/check_message_signature", checkMessageSignature(gateway, c.coin))
+	deviceHandler("/device_authenticity", deviceAuthenticity(gateway, c.trustedBootloaders))
+	deviceHandler("/diagnostics", diagnostics(gateway, c))
+	deviceHandler("/entropy_check", entropyCheck(gateway))
+	deviceHandler("/features", features(gateway))
 	// enable firmware update endpoint only for hw wallet
-	if c.mode == skyWallet.DeviceTypeUSB {
-		webHandlerV1("/firmware_update", firmwareUpdate(gateway))
-		webHandlerV1("/available", available(gateway))
-	}
-	webHandlerV1("/generate_mnemonic", generateMnemonic(gateway))
-	webHandlerV1("/recovery", recovery(gateway))
-	webHandlerV1("/set_mnemonic", setMnemonic(gateway))
-	webHandlerV1("/configure_pin_code", configurePinCode(gateway))
-	webHandlerV1("/sign_message", signMessage(gateway))
-	webHandlerV1("/transaction_sign", transactionSign(gateway))
-	webHandlerV1("/wipe", wipe(gateway))
-
-	webHandlerV1("/intermediate/pin_matrix", pinMatrixRequestHandler(gateway))
-	webHandlerV1("/intermediate/passphrase", passphraseRequestHandler(gateway))
-	webHandlerV1("/intermediate/word", wordRequestHandler(gateway))
-	webHandlerV1("/intermediate/button", buttonRequestHandler(gateway))
-
-	webHandlerV1("/version", versionHandler(c))
+	if c.mode == skyWallet.DeviceTypeUSB || c.modeSwitch != nil {
+		deviceHandler("/firmware_update", usbModeOnly(c.modeSwitch, firmwareUpdate(gateway, c.firmwareChannel, c.firmwareCache)))
+		deviceHandler("/available", usbModeOnly(c.modeSwitch, available(gateway)))
+		if c.firmwareChannel != nil {
+			deviceHandler("/firmware_check", usbModeOnly(c.modeSwitch, firmwareCheck(gateway, c.firmwareChannel, c.firmwareRollout)))
+		}
+		// the cached firmware images are listed and purged without the device
+		if c.firmwareCache != nil {
+			apiHandler("/firmware/cache", readOnlyMethods(c.readOnly, firmwareCacheHandler(c.firmwareCache)))
+		}
+	}
+	deviceHandler("/generate_mnemonic", generateMnemonic(gateway))
+	deviceHandler("/recovery", recovery(gateway))
+	deviceHandler("/set_mnemonic", setMnemonic(gateway))
+	deviceHandler("/configure_pin_code", configurePinCode(gateway))
+	deviceHandler("/sign_message", signingWindow(c.signingWindow, signMessage(gateway)))
+	deviceHandler("/transaction_sign", transactionRequestSize(c.transactionLimits, signingWindow(c.signingWindow, transactionSign(gateway, c.approvals, c.approvalThreshold, c.transactionPolicy, c.addressBook, c.transactionLimits, c.coin))))
+	deviceHandler("/partial_transaction/sign", transactionRequestSize(c.transactionLimits, signingWindow(c.signingWindow, partialTransactionSign(gateway, c.approvals, c.approvalThreshold, c.transactionPolicy, c.transactionLimits))))
+	deviceHandler("/wipe", wipe(gateway, confirmations))
+	// the transactions of the device addresses are queried from the node
+	if c.node != nil {
+		deviceHandler("/transactions", transactions(gateway, c.node))
+	}
+
+	deviceHandler("/intermediate/pin_matrix", pinMatrixRequestHandler(gateway))
+	deviceHandler("/intermediate/passphrase", passphraseRequestHandler(gateway))
+	deviceHandler("/intermediate/word", wordRequestHandler(gateway))
+	deviceHandler("/intermediate/button", buttonRequestHandler(gateway))
+
+	// the U2F requests use the U2F interface of the device, not the wallet protocol
+	if c.u2f != nil {
+		apiHandler("/u2f/register", readOnlyMethods(c.readOnly, u2fRegister(c.u2f)))
+		apiHandler("/u2f/authenticate", readOnlyMethods(c.readOnly, u2fAuthenticate(c.u2f)))
+	}
+
+	// the partial transactions are exchanged between the parties without the device, only their signature uses it
+	apiHandler("/partial_transaction/create", partialTransactionCreate())
+	apiHandler("/partial_transaction/import", partialTransactionImport())
+	apiHandler("/partial_transaction/export", partialTransactionExport())
+
+	// the partial transactions are exchanged with an air-gapped daemon as files or QR codes
+	apiHandler("/airgap/import", airgapImport())
+	apiHandler("/airgap/export", airgapExport())
+
+	// the transactions are estimated without the device, before it asks to confirm them
+	apiHandler("/transaction_estimate", transactionEstimate(c.node, c.addressBook))
+	apiHandler("/transaction_decode", transactionDecode())
+
+	// the QR codes of the addresses and transactions shown by the GUIs and kiosks
+	deviceHandler("/qrcode/address", qrCodeAddress(gateway))
+	apiHandler("/qrcode/transaction", qrCodeTransaction())
+
+	if c.addressBook != nil {
+		apiHandler("/address_book", readOnlyMethods(c.readOnly, addressBookHandler(c.addressBook)))
+	}
+
+	// the status of the device is read without waiting for the device
+	var deviceMeta, deviceStatus http.Handler
+	if c.inventory != nil {
+		apiHandler("/devices", devicesHandler(c.inventory))
+		deviceMeta = deviceMetaHandler(c.inventory)
+	}
+	if c.deviceLock != nil {
+		deviceStatus = deviceStatusHandler(c.deviceLock, deviceIDs)
+	}
+	if deviceMeta != nil || deviceStatus != nil {
+		apiHandler("/devices/", deviceSubresourcesHandler(deviceMeta, deviceStatus))
+	}
+
+	// the thin wallets query the balances through the daemon, without talking to the node
+	if c.node != nil {
+		apiHandler("/balance", balanceHandler(c.node))
+	}
+
+	if len(c.plugins) > 0 {
+		apiHandler("/plugins", pluginsHandler(c.plugins))
+		for _, p := range c.plugins {
+			if p.Manifest().Endpoints {
+				// the plugins drive the device through their endpoints, such as signing, they are not served in read-only mode
+				apiHandler("/plugins/"+p.Name()+"/", readOnlyGate(c.readOnly, "/plugins/", pluginHandler(p)))
+			}
+		}
+	}
+
+	apiHandler("/version", versionHandler(c))
+	apiHandler("/attest", attestHandler(c))
+	apiHandler("/health", healthHandler(gateway, c))
+
+	// the probes of container orchestrators and service managers are not versioned, nor CSRF checked
+	probeHandler := func(endpoint string, handler http.Handler) {
+		webHandlerWithOptionals(endpoint, handler, false, !c.disableHeaderCheck)
+	}
+	probeHandler("/live", liveHandler())
+	probeHandler("/ready", readyHandler(gateway, c))
+
+	if !c.disableAPIDocs {
+		apiHandler("/spec", specHandler())
+
+		// the Swagger UI page is not versioned, it explores the latest API version
+		assetsURL := c.apiDocsAssetsURL
+		if assetsURL == "" {
+			assetsURL = DefaultAPIDocsAssetsURL
+		}
+		webHandlerWithOptionals("/apidocs", apiDocsHandler(assetsURL, c.enableCSRF), false, !c.disableHeaderCheck)
+	}
+
+	if c.disableAPIV1 {
+		webHandlerWithOptionals("/api/"+apiVersion1+"/", apiV1Disabled(), false, !c.disableHeaderCheck)
+	}
+
+	if c.events != nil {
+		streamHandler("/events", eventsHandler(c.events))
+	}
+
+	if c.history != nil {
+		apiHandler("/history", readOnlyMethods(c.readOnly, historyHandler(c.history)))
+
+		if c.historyExportKey != nil {
+			apiHandler("/history/export", historyExportHandler(c.history, c.historyExportKey))
+		}
+	}
+
+	if c.sessions != nil {
+		apiHandler("/session", readOnlyMethods(c.readOnly, sessionHandler(c.sessions)))
+	}
+
+	if c.stats != nil {
+		apiHandler("/metrics", metricsHandler(c.stats))
+	}
+
+	if c.relay != nil {
+		apiHandler("/relay", relayHandler(c.relay))
+		apiHandler("/relay/clients", readOnlyMethods(c.readOnly, relayClientsHandler(c.relay)))
+		apiHandler("/relay/pairing_code", readOnlyMethods(c.readOnly, relayPairingCodeHandler(c.relay)))
+	}
+
+	if c.emulator != nil {
+		apiHandler("/emulator", emulatorHandler(c.emulator))
+		apiHandler("/emulator/start", readOnlyMethods(c.readOnly, emulatorOperationHandler("start", c.emulator.Start, gateway, c.history)))
+		apiHandler("/emulator/stop", readOnlyMethods(c.readOnly, emulatorOperationHandler("stop", c.emulator.Stop, gateway, c.history)))
+		apiHandler("/emulator/reset", readOnlyMethods(c.readOnly, emulatorOperationHandler("reset", c.emulator.Reset, gateway, c.history)))
+		apiHandler("/emulator/wipe", readOnlyMethods(c.readOnly, emulatorOperationHandler("wipe", c.emulator.Wipe, gateway, c.history)))
+	}
+
+	if c.approvals != nil {
+		approvalHandler := func(endpoint string, handler http.Handler) {
+			apiHandler(endpoint, bearerAuth("approval", c.approvalToken, handler))
+		}
+		approvalHandler("/approvals", approvalsHandler(c.approvals))
+		approvalHandler("/approvals/approve", readOnlyMethods(c.readOnly, approvalDecisionHandler("approve", c.approvals.Approve, c.history)))
+		approvalHandler("/approvals/reject", readOnlyMethods(c.readOnly, approvalDecisionHandler("reject", c.approvals.Reject, c.history)))
+	}
+
+	if c.adminToken != "" && c.modeSwitch != nil {
+		apiHandler("/admin/mode", adminAuth(c.adminToken, readOnlyMethods(c.readOnly, adminModeHandler(c.modeSwitch, gateway, c.history))))
+	}
+
+	if c.adminToken != "" && c.logLevel != nil {
+		apiHandler("/admin/loglevel", adminAuth(c.adminToken, adminLogLevelHandler(c.logLevel)))
+	}
+
+	if c.adminToken != "" && c.profiler != nil {
+		adminHandler := func(endpoint string, handler http.Handler) {
+			apiHandler(endpoint, adminAuth(c.adminToken, handler))
+		}
+		adminHandler("/admin/profiles", adminProfilesHandler(c.profiler))
+		adminHandler("/admin/profiles/cpu/start", adminCPUProfileHandler("start", c.profiler))
+		adminHandler("/admin/profiles/cpu/stop", adminCPUProfileHandler("stop", c.profiler))
+		adminHandler("/admin/profiles/capture", adminProfileCaptureHandler(c.profiler))
+		adminHandler("/admin/profiles/download", adminProfileDownloadHandler(c.profiler))
+	}
+
+	if c.adminToken != "" && c.wireCapture != nil {
+		apiHandler("/admin/wirecapture", adminAuth(c.adminToken, adminWireCaptureHandler(c.wireCapture)))
+		apiHandler("/admin/wirecapture/download", adminAuth(c.adminToken, adminWireCaptureDownloadHandler(c.wireCapture)))
+	}
+
 	return mux
 }
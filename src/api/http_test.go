@@ -1,7 +1,9 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,7 +19,27 @@ func defaultMuxConfig() muxConfig {
 		host:       configuredHost,
 		enableCSRF: false,
 		mode:       skyWallet.DeviceTypeUSB,
+		cors: CORSConfig{
+			Origins: DefaultCORSOrigins,
+		},
+	}
+}
+
+// apiPath returns the path of the endpoint in the API version, the table tests without a version request /api/v1
+func apiPath(apiVersion, endpoint string) string {
+	if apiVersion == "" {
+		apiVersion = apiVersion1
 	}
+	return "/api/" + apiVersion + endpoint
+}
+
+// expectedError returns the error of the expected response as it is written in the API version, the /api/v1
+// responses are written in the oldest model version
+func expectedError(apiVersion string, resp HTTPResponse) *HTTPError {
+	if apiVersion == "" || apiVersion == apiVersion1 {
+		resp = convertModelVersion(resp, minModelVersion)
+	}
+	return resp.Error
 }
 
 var endpointsMethods = map[string][]string{
@@ -66,6 +88,81 @@ var endpointsMethods = map[string][]string{
 	"/api/v1/version": []string{
 		http.MethodGet,
 	},
+	"/api/v1/account_export": []string{
+		http.MethodGet,
+	},
+	"/api/v1/diagnostics": []string{
+		http.MethodGet,
+	},
+	"/api/v1/entropy_check": []string{
+		http.MethodPost,
+	},
+	"/api/v1/attest": []string{
+		http.MethodGet,
+	},
+	"/api/v1/health": []string{
+		http.MethodGet,
+	},
+	"/api/v2/generate_addresses": []string{
+		http.MethodPost,
+	},
+	"/api/v2/account_export": []string{
+		http.MethodGet,
+	},
+	"/api/v2/apply_settings": []string{
+		http.MethodPost,
+	},
+	"/api/v2/backup": []string{
+		http.MethodPost,
+	},
+	"/api/v2/cancel": []string{
+		http.MethodPut,
+	},
+	"/api/v2/check_message_signature": []string{
+		http.MethodPost,
+	},
+	"/api/v2/diagnostics": []string{
+		http.MethodGet,
+	},
+	"/api/v2/entropy_check": []string{
+		http.MethodPost,
+	},
+	"/api/v2/features": []string{
+		http.MethodGet,
+	},
+	"/api/v2/generate_mnemonic": []string{
+		http.MethodPost,
+	},
+	"/api/v2/recovery": []string{
+		http.MethodPost,
+	},
+	"/api/v2/set_mnemonic": []string{
+		http.MethodPost,
+	},
+	"/api/v2/configure_pin_code": []string{
+		http.MethodPost,
+	},
+	"/api/v2/sign_message": []string{
+		http.MethodPost,
+	},
+	"/api/v2/transaction_sign": []string{
+		http.MethodPost,
+	},
+	"/api/v2/wipe": []string{
+		http.MethodDelete,
+	},
+	"/api/v2/available": []string{
+		http.MethodGet,
+	},
+	"/api/v2/version": []string{
+		http.MethodGet,
+	},
+	"/api/v2/attest": []string{
+		http.MethodGet,
+	},
+	"/api/v2/health": []string{
+		http.MethodGet,
+	},
 }
 
 func allEndpoints() []string {
@@ -192,3 +289,26 @@ func TestCORS(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateWithListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := CreateWithListener(listener, Config{
+		Mode: skyWallet.DeviceTypeUSB,
+	}, &MockGatewayer{})
+
+	go s.Serve() // nolint: errcheck
+
+	// the requests to the listener address pass the host check
+	rsp, err := http.Get(fmt.Sprintf("http://%s/live", listener.Addr()))
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	var probe ReceivedHTTPResponse
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&probe))
+	require.Nil(t, probe.Error)
+
+	s.Shutdown()
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/idempotency"
+)
+
+const (
+	// IdempotencyKeyHeaderName is the header of the key identifying a request retried by the client
+	IdempotencyKeyHeaderName = "Idempotency-Key"
+	// IdempotentReplayedHeaderName is set on the responses recorded for an idempotency key, returned to a retry
+	IdempotentReplayedHeaderName = "Idempotent-Replayed"
+
+	// maxIdempotentRequestSize is the largest body of a request with an idempotency key, a firmware and its form
+	maxIdempotentRequestSize = 2 * maxUploadSize
+)
+
+// idempotentEndpoints are the state-changing endpoints accepting an idempotency key
+var idempotentEndpoints = map[string]struct{}{
+	"/sign_message":             {},
+	"/transaction_sign":         {},
+	"/partial_transaction/sign": {},
+	"/wipe":                     {},
+	"/recovery":                 {},
+	"/firmware_update":          {},
+}
+
+// operationIdempotency returns the response recorded for the idempotency key of a request, so a client retrying it
+// after a network failure gets the original result instead of the device prompting the user again. The operation of
+// a request with a key goes on when its client disconnects, a retry arriving meanwhile waits for its response.
+// The responses of the requests which did not reach the device, such as the ones refused while it is busy, are not
+// recorded.
+func operationIdempotency(keys *idempotency.Store, endpoint string, handler http.Handler) http.Handler {
+	if keys == nil {
+		return handler
+	}
+	if _, ok := idempotentEndpoints[endpoint]; !ok {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeaderName)
+		if key == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if err := idempotency.ValidateKey(key); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestSize))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		recorded, end, err := keys.Begin(r.Context(), endpoint, key, idempotencyRequestHash(r, body))
+		switch err {
+		case nil:
+		case idempotency.ErrMismatch:
+			resp := newHTTPErrorResponseCategory(http.StatusUnprocessableEntity, ErrorCategoryIdempotencyMismatch, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		case context.Canceled, context.DeadlineExceeded:
+			resp := NewHTTPErrorResponse(499, "Client Closed Request")
+			writeHTTPResponse(w, resp)
+			return
+		default:
+			requestLogger(r).WithError(err).Error("Failed to read the idempotency key")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if recorded != nil {
+			writeIdempotentResponse(w, recorded)
+			return
+		}
+
+		iw := &idempotencyWriter{
+			statusWriter: newStatusWriter(w),
+		}
+		handler.ServeHTTP(iw, r.WithContext(detachedContext{r.Context()}))
+
+		// the intermediate requests of the device are not the result of the operation, a retry starts it again
+		var rsp *idempotency.Response
+		if idempotentStatus(iw.status) && !isIntermediateResponse(iw.status, iw.body.Bytes()) {
+			rsp = &idempotency.Response{
+				Status:      iw.status,
+				ContentType: iw.Header().Get("Content-Type"),
+				Body:        iw.body.Bytes(),
+			}
+		}
+		if err := end(rsp); err != nil {
+			requestLogger(r).WithError(err).Error("Failed to record the response of the idempotency key")
+		}
+	})
+}
+
+// idempotentStatus returns true if the response of a status is recorded for the idempotency key. The requests waiting
+// for a confirmation token, refused before the device answered, canceled or failing with an error of the daemon are sent
+// again by a retry.
+func idempotentStatus(status int) bool {
+	switch status {
+	case http.StatusAccepted, http.StatusLocked, http.StatusTooManyRequests, 499:
+		return false
+	default:
+		return status < http.StatusInternalServerError
+	}
+}
+
+// writeIdempotentResponse writes the response recorded for an idempotency key
+func writeIdempotentResponse(w http.ResponseWriter, rsp *idempotency.Response) {
+	w.Header().Set(IdempotentReplayedHeaderName, "true")
+	if rsp.ContentType != "" {
+		w.Header().Set("Content-Type", rsp.ContentType)
+	}
+	w.WriteHeader(rsp.Status)
+	if _, err := w.Write(rsp.Body); err != nil {
+		logger.WithError(err).Error("Failed to write the response of the idempotency key")
+	}
+}
+
+// idempotencyRequestHash returns the hash of a request, the idempotency key of the request is refused with another one.
+// The parts of the multipart forms are hashed, their boundary changes when the client sends them again.
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RawQuery)
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		if sum, err := multipartHash(body, params["boundary"]); err == nil {
+			fmt.Fprintf(h, "%s\n%s", mediaType, sum)
+			return hex.EncodeToString(h.Sum(nil))
+		}
+	}
+
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// multipartHash returns the hash of the names and the contents of the parts of a multipart body
+func multipartHash(body []byte, boundary string) (string, error) {
+	h := sha256.New()
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		ph := sha256.New()
+		if _, err := io.Copy(ph, part); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%q %q %x\n", part.FormName(), part.FileName(), ph.Sum(nil))
+	}
+}
+
+// idempotencyWriter records the response of a request with an idempotency key
+type idempotencyWriter struct {
+	*statusWriter
+	body bytes.Buffer
+}
+
+// Write implements http.ResponseWriter
+func (w *idempotencyWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.statusWriter.Write(p)
+}
+
+// detachedContext keeps the values of the context of a request but not its cancellation, the operation of a request
+// with an idempotency key goes on when its client disconnects
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/idempotency"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestOperationIdempotency(t *testing.T) {
+	signature := newReply(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+		SignedMessage: newStrPtr("signature"),
+	})
+
+	// the first message is signed until release is closed
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gateway := &MockGatewayer{}
+	gateway.On("SignMessage", 0, "hello").Return(signature, nil).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Once()
+	gateway.On("SignMessage", mock.Anything, mock.Anything).Return(signature, nil)
+
+	cfg := defaultMuxConfig()
+	cfg.idempotency = idempotency.New(storage.NewMemoryStore(), time.Hour)
+	handler := newServerMux(cfg, gateway)
+
+	sign := func(ctx context.Context, key, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/sign_message", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeaderName, key)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	// the client disconnects while the message is signed, the operation goes on
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		first <- sign(ctx, "sign-1", `{"address_n":0,"message":"hello"}`)
+	}()
+	<-started
+	cancel()
+
+	// the retry waits for the response of the first request
+	retried := make(chan *httptest.ResponseRecorder)
+	go func() {
+		retried <- sign(context.Background(), "sign-1", `{"address_n":0,"message":"hello"}`)
+	}()
+	close(release)
+
+	rr := <-first
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Empty(t, rr.Header().Get(IdempotentReplayedHeaderName))
+
+	replay := <-retried
+	require.Equal(t, http.StatusOK, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeaderName))
+	require.Equal(t, ContentTypeJSON, replay.Header().Get("Content-Type"))
+	require.Equal(t, rr.Body.String(), replay.Body.String())
+	gateway.AssertNumberOfCalls(t, "SignMessage", 1)
+
+	// the key is refused with another request
+	rr = sign(context.Background(), "sign-1", `{"address_n":0,"message":"other"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var rsp ReceivedHTTPResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, ErrorCategoryIdempotencyMismatch, rsp.Error.Category)
+
+	rr = sign(context.Background(), "", `{"address_n":0,"message":"hello"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	gateway.AssertNumberOfCalls(t, "SignMessage", 2)
+
+	rr = sign(context.Background(), "new\nline", `{"address_n":0,"message":"hello"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	gateway.AssertNumberOfCalls(t, "SignMessage", 2)
+}
+
+func TestOperationIdempotencyNotRecorded(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("SignMessage", 0, "hello").Return(wire.Message{}, errors.New("no device")).Once()
+	gateway.On("SignMessage", 0, "hello").Return(newReply(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+		SignedMessage: newStrPtr("signature"),
+	}), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.idempotency = idempotency.New(storage.NewMemoryStore(), time.Hour)
+	handler := newServerMux(cfg, gateway)
+
+	sign := func() int {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/sign_message", strings.NewReader(`{"address_n":0,"message":"hello"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		req.Header.Set(IdempotencyKeyHeaderName, "sign-1")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// the error of the daemon is not recorded, the retry signs the message
+	require.Equal(t, http.StatusInternalServerError, sign())
+	require.Equal(t, http.StatusOK, sign())
+	require.Equal(t, http.StatusOK, sign())
+	gateway.AssertNumberOfCalls(t, "SignMessage", 2)
+}
+
+func TestOperationIdempotencyIntermediateRequest(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("SignMessage", 0, "hello").Return(newReply(t, messages.MessageType_MessageType_ButtonRequest, &messages.ButtonRequest{}), nil).Once()
+	gateway.On("SignMessage", 0, "hello").Return(newReply(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+		SignedMessage: newStrPtr("signature"),
+	}), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.idempotency = idempotency.New(storage.NewMemoryStore(), time.Hour)
+	handler := newServerMux(cfg, gateway)
+
+	sign := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/sign_message", strings.NewReader(`{"address_n":0,"message":"hello"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		req.Header.Set(IdempotencyKeyHeaderName, "sign-1")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// the ButtonRequest is not recorded, the retry gets the signature
+	rr := sign()
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), `"ButtonRequest"`)
+	require.Empty(t, rr.Header().Get(IdempotentReplayedHeaderName))
+
+	rr = sign()
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), `"signature"`)
+	require.Empty(t, rr.Header().Get(IdempotentReplayedHeaderName))
+
+	// the signature is
+	replay := sign()
+	require.Equal(t, http.StatusOK, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeaderName))
+	require.Equal(t, rr.Body.String(), replay.Body.String())
+	gateway.AssertNumberOfCalls(t, "SignMessage", 2)
+}
+
+func TestIdempotencyRequestHash(t *testing.T) {
+	form := func(firmware string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "firmware.bin")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(firmware))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v2/firmware_update", &body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return req
+	}
+
+	hash := func(r *http.Request) string {
+		var body bytes.Buffer
+		_, err := body.ReadFrom(r.Body)
+		require.NoError(t, err)
+		return idempotencyRequestHash(r, body.Bytes())
+	}
+
+	// the boundaries of the forms differ
+	require.Equal(t, hash(form("firmware")), hash(form("firmware")))
+	require.NotEqual(t, hash(form("firmware")), hash(form("other")))
+
+	wipe := func(query, body string) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/wipe"+query, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		return req
+	}
+	require.Equal(t, hash(wipe("", "{}")), hash(wipe("", "{}")))
+	require.NotEqual(t, hash(wipe("", "{}")), hash(wipe("", "[]")))
+	require.NotEqual(t, hash(wipe("", "{}")), hash(wipe("?confirmation_token=t", "{}")))
+}
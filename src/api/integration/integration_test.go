@@ -232,8 +232,7 @@ func TestGenerateMnemonic(t *testing.T) {
 	}
 
 	// wipe existing data
-	resp, err := daemonClient.Operations.DeleteWipe(nil, addCSRFHeader(t, daemonClient))
-	require.NoError(t, err)
+	resp := requestWipe(t)
 	require.Equal(t, resp.Payload.Data[0], "ButtonRequest")
 
 	buttonResp, err := daemonClient.Operations.PostIntermediateButton(nil, addCSRFHeader(t, daemonClient))
@@ -247,7 +246,30 @@ func TestGenerateMnemonic(t *testing.T) {
 
 	mnemonicResp, err := daemonClient.Operations.PostGenerateMnemonic(mnemonicParams, addCSRFHeader(t, daemonClient))
 	require.NoError(t, err)
-	require.Equal(t, "Mnemonic successfully configured", mnemonicResp.Payload.Data[0])
+	require.Equal(t, "Mnemonic successfully configured", mnemonicResp.Payload.Data.Message)
+	require.Equal(t, int64(12), mnemonicResp.Payload.Data.WordCount)
+	require.Equal(t, int64(128), mnemonicResp.Payload.Data.EntropyBits)
+	require.False(t, mnemonicResp.Payload.Data.HostEntropy)
+
+	// generate a 24 word seed mixing additional host entropy
+	resp = requestWipe(t)
+	require.Equal(t, resp.Payload.Data[0], "ButtonRequest")
+
+	buttonResp, err = daemonClient.Operations.PostIntermediateButton(nil, addCSRFHeader(t, daemonClient))
+	require.NoError(t, err)
+	require.Equal(t, "Device wiped", buttonResp.Payload.Data[0])
+
+	mnemonicParams.GenerateMnemonicRequest = &models.GenerateMnemonicRequest{
+		WordCount: newInt64Ptr(24),
+		Entropy:   "00112233445566778899aabbccddeeff",
+	}
+
+	mnemonicResp, err = daemonClient.Operations.PostGenerateMnemonic(mnemonicParams, addCSRFHeader(t, daemonClient))
+	require.NoError(t, err)
+	require.Equal(t, "Mnemonic successfully configured", mnemonicResp.Payload.Data.Message)
+	require.Equal(t, int64(24), mnemonicResp.Payload.Data.WordCount)
+	require.Equal(t, int64(256), mnemonicResp.Payload.Data.EntropyBits)
+	require.True(t, mnemonicResp.Payload.Data.HostEntropy)
 }
 
 func TestRecovery(t *testing.T) {
@@ -260,8 +282,7 @@ func TestRecovery(t *testing.T) {
 	}
 
 	// wipe existing data
-	resp, err := daemonClient.Operations.DeleteWipe(nil, addCSRFHeader(t, daemonClient))
-	require.NoError(t, err)
+	resp := requestWipe(t)
 	require.Equal(t, resp.Payload.Data[0], "ButtonRequest")
 
 	buttonResp, err := daemonClient.Operations.PostIntermediateButton(nil, addCSRFHeader(t, daemonClient))
@@ -292,6 +313,42 @@ func TestRecovery(t *testing.T) {
 	require.Subset(t, [2]string{"Wrong word retyped", "Word not found in a wordlist"}, [1]string{err.Error()})
 }
 
+func TestRecoveryDryRun(t *testing.T) {
+	if *update {
+		t.SkipNow()
+	}
+
+	if !doEmulatorOrWallet(t) {
+		return
+	}
+
+	// dry run checks the seed of an initialized device without wiping it first
+	bootstrap(t)
+
+	params := operations.NewPostRecoveryParams()
+	params.RecoveryRequest = &models.RecoveryRequest{
+		WordCount: newInt64Ptr(12),
+		DryRun:    true,
+	}
+
+	recoveryResp, err := daemonClient.Operations.PostRecovery(params, addCSRFHeader(t, daemonClient))
+	require.NoError(t, err)
+	require.Equal(t, recoveryResp.Payload.Data[0], "ButtonRequest")
+
+	buttonResp, err := daemonClient.Operations.PostIntermediateButton(nil, addCSRFHeader(t, daemonClient))
+	require.NoError(t, err)
+	require.Equal(t, "WordRequest", buttonResp.Payload.Data[0])
+
+	wordParams := operations.NewPostIntermediateWordParams()
+	wordParams.WordRequest = &models.WordRequest{
+		Word: newStrPtr("foobar"),
+	}
+
+	wordParamsResp, err := daemonClient.Operations.PostIntermediateWord(wordParams, addCSRFHeader(t, daemonClient))
+	require.Nil(t, wordParamsResp)
+	require.Subset(t, [2]string{"Wrong word retyped", "Word not found in a wordlist"}, [1]string{err.Error()})
+}
+
 func TestSetMnemonic(t *testing.T) {
 	if *update {
 		t.SkipNow()
@@ -366,8 +423,7 @@ func TestWipe(t *testing.T) {
 		return
 	}
 
-	resp, err := daemonClient.Operations.DeleteWipe(nil, addCSRFHeader(t, daemonClient))
-	require.NoError(t, err)
+	resp := requestWipe(t)
 	require.Equal(t, resp.Payload.Data[0], "ButtonRequest")
 
 	buttonResp, err := daemonClient.Operations.PostIntermediateButton(nil, addCSRFHeader(t, daemonClient))
@@ -488,11 +544,28 @@ func TestWalletConnected(t *testing.T) {
 	require.Equal(t, resp.Payload.Data[0], true)
 }
 
+// requestWipe confirms the wipe operation by sending back the confirmation token
+func requestWipe(t *testing.T) *operations.DeleteWipeOK {
+	resp, accepted, err := daemonClient.Operations.DeleteWipe(nil, addCSRFHeader(t, daemonClient))
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	require.NotNil(t, accepted)
+
+	params := operations.NewDeleteWipeParams()
+	params.ConfirmationToken = &accepted.Payload.Data.ConfirmationToken
+
+	resp, accepted, err = daemonClient.Operations.DeleteWipe(params, addCSRFHeader(t, daemonClient))
+	require.NoError(t, err)
+	require.Nil(t, accepted)
+	require.NotNil(t, resp)
+
+	return resp
+}
+
 func bootstrap(t *testing.T) {
 	if enabled() {
 		// wipe existing data
-		resp, err := daemonClient.Operations.DeleteWipe(nil, addCSRFHeader(t, daemonClient))
-		require.NoError(t, err)
+		resp := requestWipe(t)
 		require.Equal(t, resp.Payload.Data[0], "ButtonRequest")
 
 		buttonResp, err := daemonClient.Operations.PostIntermediateButton(nil, addCSRFHeader(t, daemonClient))
@@ -202,7 +202,7 @@ func buttonRequestHandler(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("button ack failed: %s", err.Error())
+			requestLogger(r).Errorf("button ack failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseIPAllowlist parses a comma separated list of IP addresses and CIDR networks
+func ParseIPAllowlist(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{
+				IP:   ip,
+				Mask: net.CIDRMask(bits, bits),
+			})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR network %q", entry)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// ipAllowlist refuses the requests whose source address is not in the allowed networks with a 403. The loopback
+// addresses are always allowed, so the local wallets and the daemon's own commands keep working. Without networks
+// every address is allowed.
+func ipAllowlist(allowed []*net.IPNet, handler http.Handler) http.Handler {
+	if len(allowed) == 0 {
+		return handler
+	}
+
+	isAllowed := func(ip net.IP) bool {
+		if ip.IsLoopback() {
+			return true
+		}
+		for _, network := range allowed {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		if ip := net.ParseIP(host); ip == nil || !isAllowed(ip) {
+			criticalRequestLogger(r).Errorf("Refused a request from an address out of the IP allowlist - remote-addr=%s", r.RemoteAddr)
+			resp := NewHTTPErrorResponse(http.StatusForbidden, "Address not allowed")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	allowed, err := ParseIPAllowlist("192.0.2.0/24, 198.51.100.7,2001:db8::/32")
+	require.NoError(t, err)
+	require.Len(t, allowed, 3)
+
+	handler := ipAllowlist(allowed, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		remoteAddr string
+		status     int
+	}{
+		{"192.0.2.1:50000", http.StatusOK},
+		{"198.51.100.7:50000", http.StatusOK},
+		{"198.51.100.8:50000", http.StatusForbidden},
+		{"[2001:db8::1]:50000", http.StatusOK},
+		{"[2001:db9::1]:50000", http.StatusForbidden},
+		{"203.0.113.1:50000", http.StatusForbidden},
+		// the loopback addresses are always allowed
+		{"127.0.0.1:50000", http.StatusOK},
+		{"[::1]:50000", http.StatusOK},
+		{"invalid", http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.remoteAddr, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/v2/version", nil)
+			require.NoError(t, err)
+			req.RemoteAddr = tc.remoteAddr
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+		})
+	}
+}
+
+func TestParseIPAllowlist(t *testing.T) {
+	allowed, err := ParseIPAllowlist("")
+	require.NoError(t, err)
+	require.Empty(t, allowed)
+
+	allowed, err = ParseIPAllowlist("10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1/32", allowed[0].String())
+
+	_, err = ParseIPAllowlist("10.0.0.256")
+	require.EqualError(t, err, `invalid IP address "10.0.0.256"`)
+
+	_, err = ParseIPAllowlist("10.0.0.0/33")
+	require.EqualError(t, err, `invalid CIDR network "10.0.0.0/33"`)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/i18n"
+)
+
+const (
+	// AcceptLanguageHeaderName is the header of the languages preferred by the client for the messages
+	AcceptLanguageHeaderName = "Accept-Language"
+	// ContentLanguageHeaderName is the header of the language the messages of the response are written in
+	ContentLanguageHeaderName = "Content-Language"
+)
+
+// The descriptions of the prompts of the device, translated to the language of the request
+const (
+	promptPinMatrix  = "Enter the PIN with the layout of the matrix shown on the device"
+	promptPassphrase = "Enter the passphrase"
+	promptWord       = "Enter the word of the seed asked by the device"
+	promptButton     = "Confirm the operation on the device"
+)
+
+// negotiateLanguage writes the error messages and the prompt descriptions of the responses in the language preferred
+// by the Accept-Language header, so the frontends in other languages show them as they are. Without the header the
+// messages are in English.
+func negotiateLanguage(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h := r.Header.Get(AcceptLanguageHeaderName); h != "" {
+			w.Header().Set(ContentLanguageHeaderName, i18n.Negotiate(h))
+			w.Header().Add("Vary", AcceptLanguageHeaderName)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// responseLanguage returns the language negotiated for the response
+func responseLanguage(w http.ResponseWriter) string {
+	if language := w.Header().Get(ContentLanguageHeaderName); language != "" {
+		return language
+	}
+	return i18n.DefaultLanguage
+}
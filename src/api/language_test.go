@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+	"github.com/skycoin/hardware-wallet-daemon/src/i18n"
+	"github.com/skycoin/hardware-wallet-daemon/src/session"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	cases := []struct {
+		name            string
+		method          string
+		acceptLanguage  string
+		modelVersion    string
+		status          int
+		contentLanguage string
+		message         string
+		prompt          string
+	}{
+		{
+			name:    "error in English without the header",
+			method:  http.MethodGet,
+			status:  http.StatusMethodNotAllowed,
+			message: "Method Not Allowed",
+		},
+		{
+			name:            "error in Spanish",
+			method:          http.MethodGet,
+			acceptLanguage:  "es-ES,es;q=0.9,en;q=0.8",
+			status:          http.StatusMethodNotAllowed,
+			contentLanguage: "es",
+			message:         "Método no permitido",
+		},
+		{
+			name:            "unsupported language",
+			method:          http.MethodGet,
+			acceptLanguage:  "de",
+			status:          http.StatusMethodNotAllowed,
+			contentLanguage: "en",
+			message:         "Method Not Allowed",
+		},
+		{
+			name:    "prompt in English without the header",
+			method:  http.MethodPost,
+			status:  http.StatusOK,
+			message: "",
+			prompt:  "Enter the PIN with the layout of the matrix shown on the device",
+		},
+		{
+			name:            "prompt in Chinese",
+			method:          http.MethodPost,
+			acceptLanguage:  "zh-CN",
+			status:          http.StatusOK,
+			contentLanguage: "zh",
+			prompt:          "请按照设备上显示的矩阵布局输入 PIN 码",
+		},
+		{
+			name:            "prompt left out of model version 3",
+			method:          http.MethodPost,
+			acceptLanguage:  "zh-CN",
+			modelVersion:    "3",
+			status:          http.StatusOK,
+			contentLanguage: "zh",
+		},
+	}
+
+	gateway := &MockGatewayer{}
+	gateway.On("AddressGen", uint32(1), uint32(0), false).Return(newReply(t, messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{}), nil)
+	handler := newServerMux(defaultMuxConfig(), gateway)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, "/api/v2/generate_addresses", strings.NewReader(`{"address_n": 1}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			if tc.acceptLanguage != "" {
+				req.Header.Set(AcceptLanguageHeaderName, tc.acceptLanguage)
+			}
+			if tc.modelVersion != "" {
+				req.Header.Set(ModelVersionHeaderName, tc.modelVersion)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.contentLanguage, rr.Header().Get(ContentLanguageHeaderName))
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.prompt, rsp.Prompt)
+			if tc.message == "" {
+				require.Nil(t, rsp.Error)
+				require.JSONEq(t, `["PinMatrixRequest"]`, string(rsp.Data))
+				return
+			}
+
+			// the category is not translated, the clients decide from it
+			require.Equal(t, tc.message, rsp.Error.Message)
+			require.Equal(t, ErrorCategoryMethodNotAllowed, rsp.Error.Category)
+		})
+	}
+}
+
+// TestCatalogMessages checks that the catalogs translate the errors of the daemon and the prompts
+func TestCatalogMessages(t *testing.T) {
+	messages := []string{
+		promptPinMatrix,
+		promptPassphrase,
+		promptWord,
+		promptButton,
+	}
+	for _, err := range []error{
+		skyWallet.ErrNoDeviceConnected,
+		usb.ErrNotFound,
+		usb.ErrDisconnect,
+		deadline.ErrTimeout,
+		deadline.ErrCanceled,
+		drain.ErrDraining,
+		session.ErrLocked,
+		session.ErrInvalidSession,
+		session.ErrSessionRequired,
+		devicelock.ErrBusy,
+		approval.ErrPending,
+		approval.ErrNotFound,
+		approval.ErrRejected,
+		amount.ErrScientificNotation,
+		amount.ErrInvalidCoins,
+		cipher.ErrAddressInvalidChecksum,
+		cipher.ErrAddressInvalidLength,
+		cipher.ErrAddressInvalidVersion,
+		droplet.ErrNegativeValue,
+		droplet.ErrTooManyDecimals,
+		droplet.ErrTooLarge,
+	} {
+		messages = append(messages, err.Error())
+	}
+
+	for _, language := range i18n.Languages() {
+		if language == i18n.DefaultLanguage {
+			continue
+		}
+		for _, msg := range messages {
+			require.NotEqual(t, msg, i18n.Translate(language, msg), "%s: %s", language, msg)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/stats"
+)
+
+// ContentTypeMetrics is the content type of the Prometheus text exposition format
+const ContentTypeMetrics = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsPrefix prefixes the names of the metrics
+const metricsPrefix = "hardware_wallet_daemon_"
+
+// metricsHandler returns the statistics of the messages exchanged with the device, per message type,
+// in the Prometheus text exposition format
+// URI: /api/v1/metrics
+// Method: GET
+func metricsHandler(collector *stats.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentTypeMetrics)
+		if _, err := w.Write(renderMetrics(collector.Snapshot())); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write the metrics")
+		}
+	}
+}
+
+// renderMetrics renders the message statistics in the Prometheus text exposition format
+func renderMetrics(messages []stats.MessageStats) []byte {
+	var b bytes.Buffer
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(m stats.MessageStats) uint64
+	}{
+		{"device_messages_total", "Messages sent to the device, by message type.", func(m stats.MessageStats) uint64 { return m.Count }},
+		{"device_message_errors_total", "Messages sent to the device which failed, by message type.", func(m stats.MessageStats) uint64 { return m.Errors }},
+		{"device_message_sent_bytes_total", "Bytes sent to the device, by message type.", func(m stats.MessageStats) uint64 { return m.BytesSent }},
+		{"device_message_received_bytes_total", "Bytes received from the device, by message type.", func(m stats.MessageStats) uint64 { return m.BytesReceived }},
+	}
+
+	for _, c := range counters {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n", metricsPrefix, c.name, c.help)
+		fmt.Fprintf(&b, "# TYPE %s%s counter\n", metricsPrefix, c.name)
+		for _, m := range messages {
+			fmt.Fprintf(&b, "%s%s{type=%q} %d\n", metricsPrefix, c.name, m.Type, c.value(m))
+		}
+	}
+
+	name := metricsPrefix + "device_message_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Time taken by the device to answer, including the user confirming on the device, by message type.\n", name)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+	for _, m := range messages {
+		// the failed messages are not in the histogram
+		count := m.Count - m.Errors
+		for _, bucket := range m.Latency.Buckets {
+			fmt.Fprintf(&b, "%s_bucket{type=%q,le=%q} %d\n", name, m.Type, formatSeconds(bucket.LeMs), bucket.Count)
+		}
+		fmt.Fprintf(&b, "%s_bucket{type=%q,le=\"+Inf\"} %d\n", name, m.Type, count)
+		fmt.Fprintf(&b, "%s_sum{type=%q} %s\n", name, m.Type, formatSeconds(m.Latency.SumMs))
+		fmt.Fprintf(&b, "%s_count{type=%q} %d\n", name, m.Type, count)
+	}
+
+	name = metricsPrefix + "device_message_latency_seconds"
+	fmt.Fprintf(&b, "# HELP %s Percentiles of the time taken by the device to answer the %d most recent messages, by message type.\n", name, stats.LatencyWindow)
+	fmt.Fprintf(&b, "# TYPE %s summary\n", name)
+	for _, m := range messages {
+		for _, p := range m.Percentiles {
+			fmt.Fprintf(&b, "%s{type=%q,quantile=%q} %s\n", name, m.Type, strconv.FormatFloat(p.Quantile, 'g', -1, 64), formatSeconds(p.Ms))
+		}
+		if len(m.Percentiles) != 0 {
+			fmt.Fprintf(&b, "%s_sum{type=%q} %s\n", name, m.Type, formatSeconds(m.Latency.SumMs))
+			fmt.Fprintf(&b, "%s_count{type=%q} %d\n", name, m.Type, m.Count-m.Errors)
+		}
+	}
+
+	return b.Bytes()
+}
+
+func formatSeconds(ms float64) string {
+	return strconv.FormatFloat(ms/1000, 'g', -1, 64)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/stats"
+)
+
+func TestMetrics(t *testing.T) {
+	collector := stats.NewCollector()
+	collector.Observe(uint16(messages.MessageType_MessageType_Ping), 20, 18, 30*time.Millisecond, nil)
+	collector.Observe(uint16(messages.MessageType_MessageType_Ping), 20, 0, time.Second, errors.New("timeout"))
+
+	cfg := defaultMuxConfig()
+	cfg.stats = collector
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/metrics", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	req, err = http.NewRequest(http.MethodGet, "/api/v2/metrics", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, ContentTypeMetrics, rr.Header().Get("Content-Type"))
+
+	body := rr.Body.String()
+	for _, line := range []string{
+		"# TYPE hardware_wallet_daemon_device_messages_total counter\n",
+		"hardware_wallet_daemon_device_messages_total{type=\"Ping\"} 2\n",
+		"hardware_wallet_daemon_device_message_errors_total{type=\"Ping\"} 1\n",
+		"hardware_wallet_daemon_device_message_sent_bytes_total{type=\"Ping\"} 40\n",
+		"hardware_wallet_daemon_device_message_received_bytes_total{type=\"Ping\"} 18\n",
+		"# TYPE hardware_wallet_daemon_device_message_duration_seconds histogram\n",
+		"hardware_wallet_daemon_device_message_duration_seconds_bucket{type=\"Ping\",le=\"0.01\"} 0\n",
+		"hardware_wallet_daemon_device_message_duration_seconds_bucket{type=\"Ping\",le=\"0.05\"} 1\n",
+		"hardware_wallet_daemon_device_message_duration_seconds_bucket{type=\"Ping\",le=\"+Inf\"} 1\n",
+		"hardware_wallet_daemon_device_message_duration_seconds_sum{type=\"Ping\"} 0.03\n",
+		"hardware_wallet_daemon_device_message_duration_seconds_count{type=\"Ping\"} 1\n",
+		"# TYPE hardware_wallet_daemon_device_message_latency_seconds summary\n",
+		"hardware_wallet_daemon_device_message_latency_seconds{type=\"Ping\",quantile=\"0.5\"} 0.03\n",
+		"hardware_wallet_daemon_device_message_latency_seconds{type=\"Ping\",quantile=\"0.99\"} 0.03\n",
+		"hardware_wallet_daemon_device_message_latency_seconds_count{type=\"Ping\"} 1\n",
+	} {
+		require.Contains(t, body, line)
+	}
+
+	// the endpoint is not served without collector
+	rr = httptest.NewRecorder()
+	newServerMux(defaultMuxConfig(), &MockGatewayer{}).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestDiagnosticsMessages(t *testing.T) {
+	collector := stats.NewCollector()
+	collector.Observe(uint16(messages.MessageType_MessageType_Ping), 20, 18, 30*time.Millisecond, nil)
+
+	gateway := &MockGatewayer{}
+	gateway.On("Ping", mock.Anything).Return(wire.Message{}, skyWallet.ErrNoDeviceConnected)
+
+	cfg := defaultMuxConfig()
+	cfg.mode = skyWallet.DeviceTypeEmulator
+	cfg.stats = collector
+	handler := newServerMux(cfg, gateway)
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/diagnostics", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var rsp struct {
+		Data DiagnosticsResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, collector.Snapshot(), rsp.Data.Messages)
+}
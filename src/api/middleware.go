@@ -61,7 +61,7 @@ func hostCheck(host string, hostWhitelist []string, handler http.Handler) http.H
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// NOTE: The "Host" header is not in http.Request.Header, it's put in the http.Request.Host field
 		if isLocalhost && r.Host != "" && !isWhiteListed(r.Host) {
-			logger.Critical().Errorf("Detected DNS rebind attempt - configured-host=%s header-host=%s", host, r.Host)
+			criticalRequestLogger(r).Errorf("Detected DNS rebind attempt - configured-host=%s header-host=%s", host, r.Host)
 			resp := NewHTTPErrorResponse(http.StatusForbidden, "Invalid Host")
 			writeHTTPResponse(w, resp)
 			return
@@ -72,15 +72,16 @@ func hostCheck(host string, hostWhitelist []string, handler http.Handler) http.H
 }
 
 // OriginRefererCheck checks the Origin header if present, falling back on Referer.
-// The Origin or Referer hostname must match the configured host.
+// The Origin or Referer hostname must match the configured host, or the origin must be one of the DefaultCORSOrigins.
 // If neither are present, the request is allowed.  All major browsers will set
 // at least one of these values. If neither are set, assume it is a request
 // from curl/wget.
 func OriginRefererCheck(host string, hostWhitelist []string, handler http.Handler) http.Handler {
-	return originRefererCheck(host, hostWhitelist, handler)
+	return originRefererCheck(host, hostWhitelist, newCORSOrigins(DefaultCORSOrigins).match, handler)
 }
 
-func originRefererCheck(host string, hostWhitelist []string, handler http.Handler) http.Handler {
+// originRefererCheck is OriginRefererCheck, also accepting the origins allowed by allowOrigin
+func originRefererCheck(host string, hostWhitelist []string, allowOrigin func(origin string) bool, handler http.Handler) http.Handler {
 	hostWhitelistMap := make(map[string]struct{}, len(hostWhitelist)+2)
 	for _, k := range hostWhitelist {
 		hostWhitelistMap[k] = struct{}{}
@@ -89,9 +90,6 @@ func originRefererCheck(host string, hostWhitelist []string, handler http.Handle
 	if addr, port, _ := iputil.SplitAddr(host); iputil.IsLocalhost(addr) { // nolint: errcheck
 		hostWhitelistMap[fmt.Sprintf("127.0.0.1:%d", port)] = struct{}{}
 		hostWhitelistMap[fmt.Sprintf("localhost:%d", port)] = struct{}{}
-		hostWhitelistMap["staging.wallet.skycoin.net"] = struct{}{}
-		hostWhitelistMap["wallet.skycoin.net"] = struct{}{}
-
 	} else {
 		hostWhitelistMap[host] = struct{}{}
 	}
@@ -121,14 +119,22 @@ func originRefererCheck(host string, hostWhitelist []string, handler http.Handle
 		if toCheck != "" {
 			u, err := url.Parse(toCheck)
 			if err != nil {
-				logger.Critical().Errorf("Invalid URL in %s header: %s %v", toCheckHeader, toCheck, err)
+				criticalRequestLogger(r).Errorf("Invalid URL in %s header: %s %v", toCheckHeader, toCheck, err)
 				resp := NewHTTPErrorResponse(http.StatusForbidden, "Invalid URL in Origin or Referer header")
 				writeHTTPResponse(w, resp)
 				return
 			}
 
-			if !isWhiteListed(u.Host) {
-				logger.Critical().Errorf("%s header value %s does not match host and is not whitelisted", toCheckHeader, toCheck)
+			if !isWhiteListed(u.Host) && !allowOrigin(u.Scheme+"://"+u.Host) {
+				criticalRequestLogger(r).Errorf("%s header value %s does not match host and is not whitelisted", toCheckHeader, toCheck)
+				resp := NewHTTPErrorResponse(http.StatusForbidden, "Invalid Origin or Referer")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			// behind a TLS reverse proxy, the pages of the daemon's own hosts are not served over http
+			if requestScheme(r) == "https" && u.Scheme != "https" && !allowOrigin(u.Scheme+"://"+u.Host) {
+				criticalRequestLogger(r).Errorf("%s header value %s does not match the forwarded scheme https", toCheckHeader, toCheck)
 				resp := NewHTTPErrorResponse(http.StatusForbidden, "Invalid Origin or Referer")
 				writeHTTPResponse(w, resp)
 				return
@@ -138,3 +144,22 @@ func originRefererCheck(host string, hostWhitelist []string, handler http.Handle
 		handler.ServeHTTP(w, r)
 	})
 }
+
+// statusWriter records the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	return &statusWriter{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -5,6 +5,7 @@ package api
 import messages "github.com/skycoin/hardware-wallet-protob/go"
 import mock "github.com/stretchr/testify/mock"
 import skywallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+import usb "github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
 import wire "github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
 // MockGatewayer is an autogenerated mock type for the Gatewayer type
@@ -54,6 +55,27 @@ func (_m *MockGatewayer) ApplySettings(usePassphrase *bool, label string, langua
 	return r0, r1
 }
 
+// ApplySettingsHomescreen provides a mock function with given fields: usePassphrase, label, language, homescreen
+func (_m *MockGatewayer) ApplySettingsHomescreen(usePassphrase *bool, label string, language string, homescreen []byte) (wire.Message, error) {
+	ret := _m.Called(usePassphrase, label, language, homescreen)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(*bool, string, string, []byte) wire.Message); ok {
+		r0 = rf(usePassphrase, label, language, homescreen)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*bool, string, string, []byte) error); ok {
+		r1 = rf(usePassphrase, label, language, homescreen)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Available provides a mock function with given fields:
 func (_m *MockGatewayer) Available() bool {
 	ret := _m.Called()
@@ -220,6 +242,29 @@ func (_m *MockGatewayer) Disconnect() error {
 	return r0
 }
 
+// Enumerate provides a mock function with given fields:
+func (_m *MockGatewayer) Enumerate() ([]usb.Info, error) {
+	ret := _m.Called()
+
+	var r0 []usb.Info
+	if rf, ok := ret.Get(0).(func() []usb.Info); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]usb.Info)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FirmwareUpload provides a mock function with given fields: payload, hash
 func (_m *MockGatewayer) FirmwareUpload(payload []byte, hash [32]byte) error {
 	ret := _m.Called(payload, hash)
@@ -255,6 +300,27 @@ func (_m *MockGatewayer) GenerateMnemonic(wordCount uint32, usePassphrase bool)
 	return r0, r1
 }
 
+// GenerateMnemonicWithEntropy provides a mock function with given fields: wordCount, usePassphrase, entropy
+func (_m *MockGatewayer) GenerateMnemonicWithEntropy(wordCount uint32, usePassphrase bool, entropy []byte) (wire.Message, error) {
+	ret := _m.Called(wordCount, usePassphrase, entropy)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(uint32, bool, []byte) wire.Message); ok {
+		r0 = rf(wordCount, usePassphrase, entropy)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint32, bool, []byte) error); ok {
+		r1 = rf(wordCount, usePassphrase, entropy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFeatures provides a mock function with given fields:
 func (_m *MockGatewayer) GetFeatures() (wire.Message, error) {
 	ret := _m.Called()
@@ -276,6 +342,73 @@ func (_m *MockGatewayer) GetFeatures() (wire.Message, error) {
 	return r0, r1
 }
 
+// GetRawEntropy provides a mock function with given fields: size
+func (_m *MockGatewayer) GetRawEntropy(size uint32) ([]byte, error) {
+	ret := _m.Called(size)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(uint32) []byte); ok {
+		r0 = rf(size)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint32) error); ok {
+		r1 = rf(size)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsbInfo provides a mock function with given fields:
+func (_m *MockGatewayer) GetUsbInfo() ([]usb.Info, error) {
+	ret := _m.Called()
+
+	var r0 []usb.Info
+	if rf, ok := ret.Get(0).(func() []usb.Info); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]usb.Info)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Initialize provides a mock function with given fields:
+func (_m *MockGatewayer) Initialize() (wire.Message, error) {
+	ret := _m.Called()
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func() wire.Message); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PassphraseAck provides a mock function with given fields: passphrase
 func (_m *MockGatewayer) PassphraseAck(passphrase string) (wire.Message, error) {
 	ret := _m.Called(passphrase)
@@ -318,6 +451,27 @@ func (_m *MockGatewayer) PinMatrixAck(p string) (wire.Message, error) {
 	return r0, r1
 }
 
+// Ping provides a mock function with given fields: message
+func (_m *MockGatewayer) Ping(message string) (wire.Message, error) {
+	ret := _m.Called(message)
+
+	var r0 wire.Message
+	if rf, ok := ret.Get(0).(func(string) wire.Message); ok {
+		r0 = rf(message)
+	} else {
+		r0 = ret.Get(0).(wire.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Recovery provides a mock function with given fields: wordCount, usePassphrase, dryRun
 func (_m *MockGatewayer) Recovery(wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
 	ret := _m.Called(wordCount, usePassphrase, dryRun)
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+const (
+	// ModelVersionHeaderName is the header negotiating the version of the response models. Clients send the version
+	// of their models, the response is written in that version and the header returns it.
+	ModelVersionHeaderName = "X-Model-Version"
+
+	// ModelVersion is the version of the response models in swagger.yml, increased when the models change
+	ModelVersion = 4
+
+	// minModelVersion is the oldest model version the responses can be written in
+	minModelVersion = 1
+)
+
+// modelChange converts a response of its model version to the previous model version
+type modelChange func(resp HTTPResponse) HTTPResponse
+
+// modelChanges are the changes of the response models by model version, the responses are converted back
+// through them for the clients sending an older model version
+var modelChanges = map[int][]modelChange{
+	4: {
+		// prompt is added to the prompts of the device
+		func(resp HTTPResponse) HTTPResponse {
+			resp.Prompt = ""
+			return resp
+		},
+	},
+	3: {
+		// category is added to the errors
+		func(resp HTTPResponse) HTTPResponse {
+			if resp.Error != nil && resp.Error.Category != "" {
+				httpErr := *resp.Error
+				httpErr.Category = ""
+				resp.Error = &httpErr
+			}
+			return resp
+		},
+	},
+	2: {
+		// model_version is added to the responses
+		func(resp HTTPResponse) HTTPResponse {
+			resp.ModelVersion = 0
+			return resp
+		},
+		// request_id is added to the errors
+		func(resp HTTPResponse) HTTPResponse {
+			if resp.Error != nil && resp.Error.RequestID != "" {
+				httpErr := *resp.Error
+				httpErr.RequestID = ""
+				resp.Error = &httpErr
+			}
+			return resp
+		},
+		// unfinished_backup is added to the features
+		func(resp HTTPResponse) HTTPResponse {
+			if features, ok := resp.Data.(*messages.Features); ok && features.UnfinishedBackup != nil {
+				f := *features
+				f.UnfinishedBackup = nil
+				resp.Data = &f
+			}
+			return resp
+		},
+		// the generate_mnemonic response describes the seed instead of only returning the message of the device
+		func(resp HTTPResponse) HTTPResponse {
+			if mnemonic, ok := resp.Data.(GenerateMnemonicResponse); ok {
+				resp.Data = []string{mnemonic.Message}
+			}
+			return resp
+		},
+	},
+}
+
+// negotiateModelVersion writes the responses in the model version sent by the client in the X-Model-Version header,
+// so the generated clients of older models keep working when the models change. Without the header the responses of
+// the deprecated v1 endpoints are written in the oldest model version, which their clients were written for, and the
+// other responses in the current model version. A version newer than the daemon knows writes the current one.
+func negotiateModelVersion(endpoint string, handler http.Handler) http.Handler {
+	defaultVersion := ModelVersion
+	if strings.HasPrefix(endpoint, "/api/"+apiVersion1+"/") {
+		defaultVersion = minModelVersion
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := defaultVersion
+		if s := r.Header.Get(ModelVersionHeaderName); s != "" {
+			version = ModelVersion
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid "+ModelVersionHeaderName+" header")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if v < minModelVersion {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "unsupported model version "+s)
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if v < version {
+				version = v
+			}
+		}
+
+		w.Header().Set(ModelVersionHeaderName, strconv.Itoa(version))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// responseModelVersion returns the model version negotiated for the response
+func responseModelVersion(w http.ResponseWriter) int {
+	v, err := strconv.Atoi(w.Header().Get(ModelVersionHeaderName))
+	if err != nil || v < minModelVersion || v > ModelVersion {
+		return ModelVersion
+	}
+	return v
+}
+
+// convertModelVersion converts a response of the current model version to the model version
+func convertModelVersion(resp HTTPResponse, version int) HTTPResponse {
+	resp.ModelVersion = version
+	for v := ModelVersion; v > version; v-- {
+		for _, change := range modelChanges[v] {
+			resp = change(resp)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateModelVersion(t *testing.T) {
+	featuresMsg := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor:           newStrPtr("Skycoin Foundation"),
+		NeedsBackup:      newBoolPtr(true),
+		UnfinishedBackup: newBoolPtr(false),
+	})
+
+	cases := []struct {
+		name             string
+		endpoint         string
+		header           string
+		status           int
+		err              string
+		version          string
+		unfinishedBackup bool
+	}{
+		{
+			name:             "no header",
+			status:           http.StatusOK,
+			version:          "4",
+			unfinishedBackup: true,
+		},
+		{
+			name:     "no header v1",
+			endpoint: "/api/v1/features",
+			status:   http.StatusOK,
+			version:  "1",
+		},
+		{
+			name:             "current version v1",
+			endpoint:         "/api/v1/features",
+			header:           "4",
+			status:           http.StatusOK,
+			version:          "4",
+			unfinishedBackup: true,
+		},
+		{
+			name:             "current version",
+			header:           "4",
+			status:           http.StatusOK,
+			version:          "4",
+			unfinishedBackup: true,
+		},
+		{
+			name:             "newer version",
+			header:           "5",
+			status:           http.StatusOK,
+			version:          "4",
+			unfinishedBackup: true,
+		},
+		{
+			name:             "version 3",
+			header:           "3",
+			status:           http.StatusOK,
+			version:          "3",
+			unfinishedBackup: true,
+		},
+		{
+			name:             "version 2",
+			header:           "2",
+			status:           http.StatusOK,
+			version:          "2",
+			unfinishedBackup: true,
+		},
+		{
+			name:    "version 1",
+			header:  "1",
+			status:  http.StatusOK,
+			version: "1",
+		},
+		{
+			name:   "unsupported version",
+			header: "0",
+			status: http.StatusBadRequest,
+			err:    "unsupported model version 0",
+		},
+		{
+			name:   "invalid version",
+			header: "v2",
+			status: http.StatusBadRequest,
+			err:    "invalid X-Model-Version header",
+		},
+	}
+
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(featuresMsg, nil)
+	handler := newServerMux(defaultMuxConfig(), gateway)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := tc.endpoint
+			if endpoint == "" {
+				endpoint = "/api/v2/features"
+			}
+
+			req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+			require.NoError(t, err)
+			if tc.header != "" {
+				req.Header.Set(ModelVersionHeaderName, tc.header)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, tc.version, rr.Header().Get(ModelVersionHeaderName))
+
+			var body map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+
+			if tc.err != "" {
+				require.Equal(t, NewHTTPErrorResponse(tc.status, tc.err).Error, rsp.Error)
+				return
+			}
+
+			// model_version is added in model version 2
+			_, ok := body["model_version"]
+			require.Equal(t, tc.version != "1", ok)
+			if ok {
+				require.Equal(t, tc.version, strconv.Itoa(rsp.ModelVersion))
+			}
+
+			var features map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Data, &features))
+			require.Equal(t, "Skycoin Foundation", features["vendor"])
+			require.Equal(t, true, features["needs_backup"])
+			_, ok = features["unfinished_backup"]
+			require.Equal(t, tc.unfinishedBackup, ok)
+		})
+	}
+}
+
+func TestConvertModelVersion(t *testing.T) {
+	mnemonic := HTTPResponse{
+		Data: GenerateMnemonicResponse{
+			Message:     "Mnemonic successfully configured",
+			WordCount:   12,
+			EntropyBits: 128,
+		},
+	}
+
+	resp := convertModelVersion(mnemonic, ModelVersion)
+	require.Equal(t, ModelVersion, resp.ModelVersion)
+	require.Equal(t, mnemonic.Data, resp.Data)
+
+	resp = convertModelVersion(mnemonic, 1)
+	require.Zero(t, resp.ModelVersion)
+	require.Equal(t, []string{"Mnemonic successfully configured"}, resp.Data)
+
+	failure := newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryPinInvalid, "PIN invalid")
+	failure.Error.RequestID = "flow-42"
+
+	resp = convertModelVersion(failure, ModelVersion)
+	require.Equal(t, *failure.Error, *resp.Error)
+
+	resp = convertModelVersion(failure, 2)
+	require.Equal(t, 2, resp.ModelVersion)
+	require.Equal(t, &HTTPError{
+		Code:      http.StatusConflict,
+		Message:   "PIN invalid",
+		RequestID: "flow-42",
+	}, resp.Error)
+
+	resp = convertModelVersion(failure, 1)
+	require.Equal(t, &HTTPError{
+		Code:    http.StatusConflict,
+		Message: "PIN invalid",
+	}, resp.Error)
+	// the response of the handler is not changed
+	require.Equal(t, "flow-42", failure.Error.RequestID)
+	require.Equal(t, ErrorCategoryPinInvalid, failure.Error.Category)
+}
+
+func TestAPIV1ModelVersion(t *testing.T) {
+	successMsg := newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: newStrPtr("Mnemonic successfully configured"),
+	})
+	buttonRequestMsg := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ButtonRequest),
+	}
+
+	cases := []struct {
+		name   string
+		body   string
+		reply  wire.Message
+		status int
+		v1Body string
+		v2Body string
+	}{
+		{
+			name:   "generate mnemonic",
+			body:   `{"word_count":12}`,
+			reply:  successMsg,
+			status: http.StatusOK,
+			v1Body: `{"data":["Mnemonic successfully configured"]}`,
+			v2Body: `{"data":{"message":"Mnemonic successfully configured","word_count":12,"entropy_bits":128,"host_entropy":false},"model_version":4}`,
+		},
+		{
+			name:   "prompt",
+			body:   `{"word_count":12}`,
+			reply:  buttonRequestMsg,
+			status: http.StatusOK,
+			v1Body: `{"data":["ButtonRequest"]}`,
+			v2Body: `{"data":["ButtonRequest"],"model_version":4,"prompt":"Confirm the operation on the device"}`,
+		},
+		{
+			name:   "error",
+			body:   `{"word_count":18}`,
+			status: http.StatusUnprocessableEntity,
+			v1Body: `{"error":{"message":"word count must be 12 or 24","code":422}}`,
+			v2Body: `{"error":{"message":"word count must be 12 or 24","code":422,"category":"invalid_request"},"model_version":4}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GenerateMnemonic", uint32(12), false).Return(tc.reply, nil)
+			handler := newServerMux(defaultMuxConfig(), gateway)
+
+			post := func(endpoint string) *httptest.ResponseRecorder {
+				req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(tc.body))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", ContentTypeJSON)
+
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				require.Equal(t, tc.status, rr.Code)
+				return rr
+			}
+
+			// without the X-Model-Version header the v1 endpoints write the models of model version 1
+			rr := post("/api/v1/generate_mnemonic")
+			require.Equal(t, "1", rr.Header().Get(ModelVersionHeaderName))
+			require.JSONEq(t, tc.v1Body, rr.Body.String())
+
+			rr = post("/api/v2/generate_mnemonic")
+			require.Equal(t, "4", rr.Header().Get(ModelVersionHeaderName))
+			require.JSONEq(t, tc.v2Body, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/auditlog"
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+)
+
+// outcomeEventTypes maps the device endpoints whose outcome is published to the events of their outcomes
+var outcomeEventTypes = map[string]map[string]events.Type{
+	"/transaction_sign": {
+		auditlog.OutcomeSuccess:   events.TypeTransactionSigned,
+		auditlog.OutcomeCancelled: events.TypeTransactionRejected,
+	},
+	"/partial_transaction/sign": {
+		auditlog.OutcomeSuccess:   events.TypeTransactionSigned,
+		auditlog.OutcomeCancelled: events.TypeTransactionRejected,
+	},
+	"/firmware_update": {
+		auditlog.OutcomeSuccess: events.TypeFirmwareUpdated,
+	},
+}
+
+// OutcomeEventData is the data of the transaction_signed, transaction_rejected and firmware_updated events
+type OutcomeEventData struct {
+	// Endpoint is the endpoint of the operation, the intermediate requests finishing it are not reported
+	Endpoint string `json:"endpoint"`
+	// Status is the status of the last response of the operation
+	Status int `json:"status"`
+	// RequestID is the ID of the request finishing the operation
+	RequestID string `json:"request_id,omitempty"`
+	// Error is the error message of a rejected operation
+	Error string `json:"error,omitempty"`
+}
+
+// outcomeEvents holds the operation of outcomeEventTypes waiting for the user input, such as the PIN of a signature,
+// until an intermediate request finishes it
+type outcomeEvents struct {
+	bus *events.Bus
+
+	mu sync.Mutex
+	// held is the endpoint of the operation waiting for the user input, empty if none
+	held string
+}
+
+func newOutcomeEvents(bus *events.Bus) *outcomeEvents {
+	if bus == nil {
+		return nil
+	}
+	return &outcomeEvents{
+		bus: bus,
+	}
+}
+
+// swap holds endpoint, empty to stop holding, and returns the endpoint held before
+func (o *outcomeEvents) swap(endpoint string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	held := o.held
+	o.held = endpoint
+	return held
+}
+
+// operationOutcomeEvents publishes the outcome of the operations of outcomeEventTypes, once the intermediate requests
+// finished them. An operation waiting for the user input is rejected by a cancel request.
+func operationOutcomeEvents(o *outcomeEvents, endpoint string, handler http.Handler) http.Handler {
+	if o == nil {
+		return handler
+	}
+
+	_, published := outcomeEventTypes[endpoint]
+	intermediate := strings.HasPrefix(endpoint, "/intermediate/")
+	if !published && !intermediate && endpoint != "/cancel" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operation := endpoint
+
+		switch {
+		case published:
+			o.swap("")
+
+		case endpoint == "/cancel":
+			held := o.swap("")
+			handler.ServeHTTP(w, r)
+			if held != "" {
+				o.publish(r, held, auditlog.OutcomeCancelled, OutcomeEventData{
+					Endpoint:  held,
+					Status:    http.StatusOK,
+					RequestID: requestIDFromRequest(r),
+					Error:     "Action cancelled by user",
+				})
+			}
+			return
+
+		default:
+			operation = o.swap("")
+			if operation == "" {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		sw := &sessionWriter{
+			statusWriter: newStatusWriter(w),
+			size:         maxOutcomeResponseSize,
+		}
+		handler.ServeHTTP(sw, r)
+
+		if sw.awaitsInput() {
+			o.swap(operation)
+			return
+		}
+
+		outcome, msg := operationOutcome(sw)
+		data := OutcomeEventData{
+			Endpoint:  operation,
+			Status:    sw.status,
+			RequestID: requestIDFromRequest(r),
+		}
+		if outcome != auditlog.OutcomeSuccess {
+			data.Error = msg
+		}
+		o.publish(r, operation, outcome, data)
+	})
+}
+
+// publish publishes the event of the outcome of the operation of endpoint, if any
+func (o *outcomeEvents) publish(r *http.Request, endpoint, outcome string, data OutcomeEventData) {
+	typ, ok := outcomeEventTypes[endpoint][outcome]
+	if !ok {
+		return
+	}
+
+	if _, err := o.bus.Publish(typ, "", data); err != nil {
+		requestLogger(r).WithError(err).Errorf("failed to publish %s event", typ)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+)
+
+func TestOperationOutcomeEvents(t *testing.T) {
+	bus := newTestBus(t)
+
+	gateway := &MockGatewayer{}
+	gateway.On("TransactionSign", mock.Anything, mock.Anything).Return(newReply(t, messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{}), nil).Twice()
+	gateway.On("TransactionSign", mock.Anything, mock.Anything).Return(newReply(t, messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    messages.FailureType_Failure_ActionCancelled.Enum(),
+		Message: newStrPtr("Action cancelled by user"),
+	}), nil)
+	gateway.On("PinMatrixAck", "1234").Return(newReply(t, messages.MessageType_MessageType_ButtonRequest, &messages.ButtonRequest{}), nil)
+	gateway.On("ButtonAck").Return(newReply(t, messages.MessageType_MessageType_ResponseTransactionSign, &messages.ResponseTransactionSign{
+		Signatures: []string{"signature"},
+		Padding:    newBoolPtr(false),
+	}), nil)
+	gateway.On("Cancel").Return(newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: newStrPtr("Action cancelled by user"),
+	}), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.events = bus
+	cfg.requestID = true
+	handler := newServerMux(cfg, gateway)
+
+	transaction := toJSON(t, &TransactionSignRequest{
+		TransactionInputs: []TransactionInput{
+			{newUint32Ptr(0), "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+		},
+		TransactionOutputs: []TransactionOutput{
+			{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "2", Hours: "2"},
+		},
+	})
+
+	do := func(method, endpoint, body string) int {
+		req, err := http.NewRequest(method, "/api/v2"+endpoint, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		req.Header.Set(RequestIDHeaderName, strings.Trim(strings.Replace(endpoint, "/", "-", -1), "-"))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// signed once the PIN is entered and the transaction is confirmed on the device
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/transaction_sign", transaction))
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/intermediate/pin_matrix", `{"pin":"1234"}`))
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/intermediate/button", ""))
+
+	// the intermediate requests outside of a signature are not published
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/intermediate/button", ""))
+
+	// canceled while waiting for the PIN
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/transaction_sign", transaction))
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/cancel", ""))
+
+	// rejected on the device
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "/transaction_sign", transaction))
+
+	evs, err := bus.Since(0, 0, events.Filter{
+		Types: []events.Type{events.TypeTransactionSigned, events.TypeTransactionRejected, events.TypeFirmwareUpdated},
+	})
+	require.NoError(t, err)
+
+	var types []events.Type
+	var data []OutcomeEventData
+	for _, ev := range evs {
+		types = append(types, ev.Type)
+
+		var d OutcomeEventData
+		require.NoError(t, json.Unmarshal(ev.Data, &d))
+		data = append(data, d)
+	}
+
+	require.Equal(t, []events.Type{
+		events.TypeTransactionSigned,
+		events.TypeTransactionRejected,
+		events.TypeTransactionRejected,
+	}, types)
+	require.Equal(t, []OutcomeEventData{
+		{Endpoint: "/transaction_sign", Status: http.StatusOK, RequestID: "intermediate-button"},
+		{Endpoint: "/transaction_sign", Status: http.StatusOK, RequestID: "cancel", Error: "Action cancelled by user"},
+		{Endpoint: "/transaction_sign", Status: http.StatusConflict, RequestID: "transaction_sign", Error: "Action cancelled by user"},
+	}, data)
+}
@@ -0,0 +1,477 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+// PartialTransactionCreateRequest is request data for POST /api/v2/partial_transaction/create
+type PartialTransactionCreateRequest struct {
+	TransactionInputs  []PartialTransactionInput  `json:"transaction_inputs"`
+	TransactionOutputs []PartialTransactionOutput `json:"transaction_outputs"`
+}
+
+// PartialTransactionInput is an input of a partial transaction
+type PartialTransactionInput struct {
+	Hash string `json:"hash"`
+	// Address is the address owning the unspent output of the input, it signs the input
+	Address string `json:"address"`
+	Signed  bool   `json:"signed,omitempty"`
+}
+
+// PartialTransactionOutput is an output of a partial transaction
+type PartialTransactionOutput struct {
+	Address string `json:"address"`
+	Coins   string `json:"coins"`
+	Hours   string `json:"hours"`
+}
+
+// PartialTransactionImportRequest is request data for POST /api/v2/partial_transaction/import
+type PartialTransactionImportRequest struct {
+	// PartialTransactions are the copies of the same partial transaction signed by the parties
+	PartialTransactions []string `json:"partial_transactions"`
+	// Signatures are the signatures returned by the device for the inputs of the partial transaction, "" for the inputs it did not sign
+	Signatures []string `json:"signatures"`
+}
+
+// PartialTransactionSignRequest is request data for POST /api/v2/partial_transaction/sign
+type PartialTransactionSignRequest struct {
+	PartialTransaction string `json:"partial_transaction"`
+	// InputIndexes are the indexes of the addresses of the device signing the inputs, null for the inputs of the other parties
+	InputIndexes []*uint32 `json:"input_indexes"`
+	// OutputIndexes are the indexes of the addresses of the device receiving the change outputs, null for the other outputs
+	OutputIndexes []*uint32 `json:"output_indexes"`
+}
+
+// PartialTransactionExportRequest is request data for POST /api/v2/partial_transaction/export
+type PartialTransactionExportRequest struct {
+	PartialTransaction string `json:"partial_transaction"`
+}
+
+// PartialTransactionResponse is data returned by the partial transaction endpoints
+type PartialTransactionResponse struct {
+	// PartialTransaction is the canonical serialization of the partial transaction, hex encoded
+	PartialTransaction string                     `json:"partial_transaction"`
+	InnerHash          string                     `json:"inner_hash"`
+	Inputs             []PartialTransactionInput  `json:"inputs"`
+	Outputs            []PartialTransactionOutput `json:"outputs"`
+	Signed             int                        `json:"signed"`
+	Complete           bool                       `json:"complete"`
+}
+
+// PartialTransactionExportResponse is data returned by POST /api/v2/partial_transaction/export
+type PartialTransactionExportResponse struct {
+	// Transaction is the serialization of the fully signed transaction, hex encoded, as injected in the network
+	Transaction string `json:"transaction"`
+	TxID        string `json:"txid"`
+}
+
+const partialTransactionSignAction = "partial_transaction_sign"
+
+// decodeJSONRequest decodes the JSON body of a POST request into v, writing the error response if it fails
+func decodeJSONRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+		writeHTTPResponse(w, resp)
+		return false
+	}
+
+	if r.Header.Get("Content-Type") != ContentTypeJSON {
+		resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+		writeHTTPResponse(w, resp)
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		writeHTTPResponse(w, resp)
+		return false
+	}
+
+	return true
+}
+
+// URI: /api/v2/partial_transaction/create
+// Method: POST
+// Args: JSON Body
+func partialTransactionCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTransactionCreateRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		txn, err := req.transaction()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writePartialTransaction(w, txn)
+	}
+}
+
+// URI: /api/v2/partial_transaction/import
+// Method: POST
+// Args: JSON Body
+func partialTransactionImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTransactionImportRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		if len(req.PartialTransactions) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "partial_transactions are required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txn, err := req.transaction()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writePartialTransaction(w, txn)
+	}
+}
+
+// URI: /api/v2/partial_transaction/export
+// Method: POST
+// Args: JSON Body
+func partialTransactionExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTransactionExportRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		txn, err := partialtx.Decode(req.PartialTransaction)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		data, err := txn.Serialize()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: PartialTransactionExportResponse{
+				Transaction: hex.EncodeToString(data),
+				TxID:        cipher.SumSHA256(data).Hex(),
+			},
+		})
+	}
+}
+
+// URI: /api/v2/partial_transaction/sign
+// Method: POST
+// Args: JSON Body, approval_id of the approval of a transaction spending more than the approval threshold [optional]
+func partialTransactionSign(gateway Gatewayer, approvals *approval.Manager, approvalThreshold uint64, policy *txpolicy.Engine, limits TransactionLimits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTransactionSignRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		txn, err := partialtx.Decode(req.PartialTransaction)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txnInputs, txnOutputs, err := req.transactionParams(txn)
+		if err != nil {
+			requestLogger(r).WithError(err).Error("invalid partial transaction sign request")
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// the device signs the whole transaction, its inputs and outputs are bounded as well
+		if !checkTransactionLimits(w, limits, len(txnInputs), len(txnOutputs)) {
+			return
+		}
+
+		// the co-signatures are held to the same rules as the transactions signed alone
+		if policy != nil && !checkTransactionPolicy(w, r, policy, txnOutputs, false) {
+			return
+		}
+
+		if approvals != nil && spentCoins(txnOutputs) > approvalThreshold {
+			if !useApproval(w, r, approvals, partialTransactionSignAction, req) {
+				return
+			}
+		}
+
+		if policy != nil && !checkTransactionPolicy(w, r, policy, txnOutputs, true) {
+			return
+		}
+
+		// for integration tests
+		if autoPressEmulatorButtons {
+			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
+			if err != nil {
+				requestLogger(r).Errorf("partialTransactionSign failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		var msg wire.Message
+		retCH := make(chan int)
+		errCH := make(chan int)
+		ctx := r.Context()
+
+		go func() {
+			msg, err = gateway.TransactionSign(txnInputs, txnOutputs)
+			if err != nil {
+				errCH <- 1
+				return
+			}
+			retCH <- 1
+		}()
+
+		select {
+		case <-retCH:
+			// the device asking for the user input answers the signatures to the intermediate requests,
+			// they are added to the partial transaction with /partial_transaction/import
+			if msg.Kind != uint16(messages.MessageType_MessageType_ResponseTransactionSign) {
+				HandleFirmwareResponseMessages(w, msg)
+				return
+			}
+
+			signatures, err := skyWallet.DecodeResponseTransactionSign(msg)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if err := addSignatures(txn, signatures); err != nil {
+				requestLogger(r).Errorf("partialTransactionSign failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writePartialTransaction(w, txn)
+		case <-errCH:
+			requestLogger(r).Errorf("partialTransactionSign failed: %s", err.Error())
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+		case <-ctx.Done():
+			disConnErr := gateway.Disconnect()
+			if disConnErr != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, disConnErr.Error())
+				writeHTTPResponse(w, resp)
+			} else {
+				resp := NewHTTPErrorResponse(499, "Client Closed Request")
+				writeHTTPResponse(w, resp)
+			}
+		}
+	}
+}
+
+// writePartialTransaction writes txn as a PartialTransactionResponse
+func writePartialTransaction(w http.ResponseWriter, txn *partialtx.Transaction) {
+	rsp := PartialTransactionResponse{
+		PartialTransaction: txn.Encode(),
+		InnerHash:          txn.InnerHash().Hex(),
+		Signed:             txn.Signed(),
+		Complete:           txn.Complete(),
+	}
+
+	for _, in := range txn.Inputs {
+		rsp.Inputs = append(rsp.Inputs, PartialTransactionInput{
+			Hash:    in.Hash.Hex(),
+			Address: in.Address.String(),
+			Signed:  in.Signed(),
+		})
+	}
+
+	for _, o := range txn.Outputs {
+		coins, err := amount.FormatCoins(o.Coins)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp.Outputs = append(rsp.Outputs, PartialTransactionOutput{
+			Address: o.Address.String(),
+			Coins:   coins,
+			Hours:   strconv.FormatUint(o.Hours, 10),
+		})
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: rsp,
+	})
+}
+
+// addSignatures adds the signatures of the inputs of txn, the inputs not signed have an empty signature
+func addSignatures(txn *partialtx.Transaction, signatures []string) error {
+	if len(signatures) > len(txn.Inputs) {
+		return fmt.Errorf("%d signatures for %d inputs", len(signatures), len(txn.Inputs))
+	}
+
+	for i, s := range signatures {
+		if s == "" {
+			continue
+		}
+
+		sig, err := cipher.SigFromHex(s)
+		if err != nil {
+			return fmt.Errorf("invalid signature of input %d: %v", i, err)
+		}
+		if err := txn.Sign(i, sig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *PartialTransactionCreateRequest) transaction() (*partialtx.Transaction, error) {
+	inputs := make([]partialtx.Input, len(r.TransactionInputs))
+	for i, input := range r.TransactionInputs {
+		hash, err := cipher.SHA256FromHex(input.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input %d hash: %v", i, err)
+		}
+
+		address, err := cipher.DecodeBase58Address(input.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input %d address: %v", i, err)
+		}
+
+		inputs[i] = partialtx.Input{
+			Hash:    hash,
+			Address: address,
+		}
+	}
+
+	outputs := make([]partialtx.Output, len(r.TransactionOutputs))
+	for i, output := range r.TransactionOutputs {
+		address, err := cipher.DecodeBase58Address(output.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %d address: %v", i, err)
+		}
+
+		coins, err := amount.ParseCoins(output.Coins)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %d coins: %v", i, err)
+		}
+
+		hours, err := amount.ParseHours(output.Hours)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %d hours: %v", i, err)
+		}
+
+		outputs[i] = partialtx.Output{
+			Address: address,
+			Coins:   coins,
+			Hours:   hours,
+		}
+	}
+
+	return partialtx.New(inputs, outputs)
+}
+
+func (r *PartialTransactionImportRequest) transaction() (*partialtx.Transaction, error) {
+	txn, err := partialtx.Decode(r.PartialTransactions[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range r.PartialTransactions[1:] {
+		other, err := partialtx.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if err := txn.Combine(other); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addSignatures(txn, r.Signatures); err != nil {
+		return nil, err
+	}
+
+	return txn, nil
+}
+
+// transactionParams returns params for the transaction of txn signing the inputs of the device
+func (r *PartialTransactionSignRequest) transactionParams(txn *partialtx.Transaction) ([]*messages.SkycoinTransactionInput, []*messages.SkycoinTransactionOutput, error) {
+	if len(r.InputIndexes) > len(txn.Inputs) {
+		return nil, nil, fmt.Errorf("%d input indexes for %d inputs", len(r.InputIndexes), len(txn.Inputs))
+	}
+	if len(r.OutputIndexes) > len(txn.Outputs) {
+		return nil, nil, fmt.Errorf("%d output indexes for %d outputs", len(r.OutputIndexes), len(txn.Outputs))
+	}
+
+	var signing bool
+	var transactionInputs []*messages.SkycoinTransactionInput
+	for i, in := range txn.Inputs {
+		transactionInput := messages.SkycoinTransactionInput{
+			HashIn: proto.String(in.Hash.Hex()),
+		}
+
+		if i < len(r.InputIndexes) && r.InputIndexes[i] != nil {
+			if in.Signed() {
+				return nil, nil, fmt.Errorf("input %d is already signed", i)
+			}
+			transactionInput.Index = proto.Uint32(*r.InputIndexes[i])
+			signing = true
+		}
+
+		transactionInputs = append(transactionInputs, &transactionInput)
+	}
+
+	if !signing {
+		return nil, nil, errors.New("input_indexes has no input signed by the device")
+	}
+
+	var transactionOutputs []*messages.SkycoinTransactionOutput
+	for i, o := range txn.Outputs {
+		transactionOutput := messages.SkycoinTransactionOutput{
+			Address: proto.String(o.Address.String()),
+			Coin:    proto.Uint64(o.Coins),
+			Hour:    proto.Uint64(o.Hours),
+		}
+
+		if i < len(r.OutputIndexes) && r.OutputIndexes[i] != nil {
+			transactionOutput.AddressIndex = proto.Uint32(*r.OutputIndexes[i])
+		}
+
+		transactionOutputs = append(transactionOutputs, &transactionOutput)
+	}
+
+	return transactionInputs, transactionOutputs, nil
+}
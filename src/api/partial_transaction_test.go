@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+)
+
+func TestPartialTransaction(t *testing.T) {
+	alicePubKey, aliceSecKey := cipher.MustGenerateDeterministicKeyPair([]byte("alice"))
+	bobPubKey, bobSecKey := cipher.MustGenerateDeterministicKeyPair([]byte("bob"))
+	alice, bob := cipher.AddressFromPubKey(alicePubKey), cipher.AddressFromPubKey(bobPubKey)
+
+	gateway := &MockGatewayer{}
+	handler := newServerMux(defaultMuxConfig(), gateway)
+
+	post := func(endpoint string, req interface{}, data interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		r, err := http.NewRequest(http.MethodPost, "/api/v2"+endpoint, bytes.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+
+		if rr.Code == http.StatusOK && data != nil {
+			var rsp HTTPResponse
+			rsp.Data = data
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		}
+		return rr
+	}
+
+	var created PartialTransactionResponse
+	rr := post("/partial_transaction/create", PartialTransactionCreateRequest{
+		TransactionInputs: []PartialTransactionInput{
+			{Hash: "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663", Address: alice.String()},
+			{Hash: "4f7250b0b1f588c4dd4b0d5f4d8ec6a3f6b7f6e2dc6a2a1bd4e86a7b1ea6e7b6", Address: bob.String()},
+		},
+		TransactionOutputs: []PartialTransactionOutput{
+			{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "12.5", Hours: "10"},
+		},
+	}, &created)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 0, created.Signed)
+	require.False(t, created.Complete)
+	require.Equal(t, "12.500000", created.Outputs[0].Coins)
+
+	txn, err := partialtx.Decode(created.PartialTransaction)
+	require.NoError(t, err)
+	require.Equal(t, txn.InnerHash().Hex(), created.InnerHash)
+
+	rr = post("/partial_transaction/create", PartialTransactionCreateRequest{
+		TransactionInputs: []PartialTransactionInput{
+			{Hash: "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+		},
+	}, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// the device of alice signs the first input, the second one is not sent with an index
+	aliceSig := cipher.MustSignHash(txn.SignatureHash(0), aliceSecKey)
+	signResponse := messages.ResponseTransactionSign{
+		Signatures: []string{aliceSig.Hex(), ""},
+		Padding:    newBoolPtr(false),
+	}
+	signResponseBytes, err := signResponse.Marshal()
+	require.NoError(t, err)
+	gateway.On("TransactionSign", mock.MatchedBy(func(inputs []*messages.SkycoinTransactionInput) bool {
+		return len(inputs) == 2 && inputs[0].GetIndex() == 3 && inputs[1].Index == nil
+	}), mock.Anything).Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ResponseTransactionSign),
+		Data: signResponseBytes,
+	}, nil)
+
+	var signed PartialTransactionResponse
+	rr = post("/partial_transaction/sign", PartialTransactionSignRequest{
+		PartialTransaction: created.PartialTransaction,
+		InputIndexes:       []*uint32{newUint32Ptr(3)},
+	}, &signed)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, signed.Signed)
+	require.True(t, signed.Inputs[0].Signed)
+
+	rr = post("/partial_transaction/sign", PartialTransactionSignRequest{
+		PartialTransaction: signed.PartialTransaction,
+		InputIndexes:       []*uint32{newUint32Ptr(3)},
+	}, nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = post("/partial_transaction/export", PartialTransactionExportRequest{
+		PartialTransaction: signed.PartialTransaction,
+	}, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// bob signs the second input of his copy, the signatures of the copies are combined
+	bobSig := cipher.MustSignHash(txn.SignatureHash(1), bobSecKey)
+	var bobTxn PartialTransactionResponse
+	rr = post("/partial_transaction/import", PartialTransactionImportRequest{
+		PartialTransactions: []string{created.PartialTransaction},
+		Signatures:          []string{"", bobSig.Hex()},
+	}, &bobTxn)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, bobTxn.Signed)
+
+	rr = post("/partial_transaction/import", PartialTransactionImportRequest{
+		PartialTransactions: []string{created.PartialTransaction},
+		Signatures:          []string{bobSig.Hex()},
+	}, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	var combined PartialTransactionResponse
+	rr = post("/partial_transaction/import", PartialTransactionImportRequest{
+		PartialTransactions: []string{signed.PartialTransaction, bobTxn.PartialTransaction},
+	}, &combined)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.True(t, combined.Complete)
+
+	var exported PartialTransactionExportResponse
+	rr = post("/partial_transaction/export", PartialTransactionExportRequest{
+		PartialTransaction: combined.PartialTransaction,
+	}, &exported)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	data, err := hex.DecodeString(exported.Transaction)
+	require.NoError(t, err)
+	require.Equal(t, cipher.SumSHA256(data).Hex(), exported.TxID)
+
+	gateway.AssertNumberOfCalls(t, "TransactionSign", 1)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/plugin"
+)
+
+// maxPluginRequestSize is the largest request body forwarded to a plugin
+const maxPluginRequestSize = 1 << 20
+
+// privateHeaders are the request headers of the daemon which are not forwarded to the plugins
+var privateHeaders = []string{
+	"Authorization",
+	"Cookie",
+	CSRFHeaderName,
+	SessionHeaderName,
+}
+
+// URI: /api/v1/plugins
+// Method: GET
+func pluginsHandler(plugins []*plugin.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		manifests := make([]plugin.Manifest, len(plugins))
+		for i, p := range plugins {
+			manifests[i] = p.Manifest()
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: manifests,
+		})
+	}
+}
+
+// URI: /api/v1/plugins/{name}/{path}
+// Method: any, the request is served by the plugin
+func pluginHandler(p *plugin.Client) http.HandlerFunc {
+	prefix := "/plugins/" + p.Name()
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the path is /api/{version}/plugins/{name}/{path}
+		path := r.URL.Path
+		if i := strings.Index(path, prefix); i >= 0 {
+			path = path[i+len(prefix):]
+		}
+		if path == "" {
+			path = "/"
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPluginRequestSize))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusRequestEntityTooLarge, fmt.Sprintf("the request body is larger than %d bytes", maxPluginRequestSize))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		header := make(http.Header, len(r.Header))
+		for k, v := range r.Header {
+			header[k] = v
+		}
+		for _, h := range privateHeaders {
+			header.Del(h)
+		}
+
+		rsp, err := p.ServeRequest(r.Context(), plugin.HTTPRequest{
+			Method:   r.Method,
+			Path:     path,
+			RawQuery: r.URL.RawQuery,
+			Header:   header,
+			Body:     body,
+		})
+		switch err {
+		case nil:
+		case context.Canceled:
+			resp := NewHTTPErrorResponse(499, "Client Closed Request")
+			writeHTTPResponse(w, resp)
+			return
+		default:
+			requestLogger(r).WithError(err).Errorf("the plugin %s failed", p.Name())
+			resp := NewHTTPErrorResponse(http.StatusBadGateway, fmt.Sprintf("the plugin %s failed: %v", p.Name(), err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// net/http panics on a status outside of 100-999
+		if rsp.Status < 100 || rsp.Status > 599 {
+			requestLogger(r).Errorf("the plugin %s returned an invalid status %d", p.Name(), rsp.Status)
+			resp := NewHTTPErrorResponse(http.StatusBadGateway, "plugin returned an invalid status")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		for k, v := range rsp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rsp.Status)
+		if _, err := w.Write(rsp.Body); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write the response of the plugin")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/plugin"
+)
+
+type echoPlugin struct{}
+
+func (echoPlugin) Name() string {
+	return "echo"
+}
+
+func (echoPlugin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/invalid_status" {
+		w.WriteHeader(42)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body) // nolint: errcheck
+	w.Header().Set("X-Echo-Path", r.URL.Path)
+	w.Header().Set("X-Echo-Token", r.Header.Get(CSRFHeaderName))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body) // nolint: errcheck
+}
+
+func TestPlugins(t *testing.T) {
+	server, client := net.Pipe()
+	go plugin.ServeConn(echoPlugin{}, server) // nolint: errcheck
+
+	p, err := plugin.Connect(client)
+	require.NoError(t, err)
+	defer p.Close()
+
+	c := defaultMuxConfig()
+	c.plugins = []*plugin.Client{p}
+	handler := newServerMux(c, &MockGatewayer{})
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set(CSRFHeaderName, "token")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := do(http.MethodGet, "/api/v2/plugins", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var manifests []plugin.Manifest
+	rsp := HTTPResponse{
+		Data: &manifests,
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, []plugin.Manifest{{Name: "echo", APIVersion: plugin.APIVersion, Endpoints: true}}, manifests)
+
+	// the plugin serves the requests under its path, without the private headers of the daemon
+	rr = do(http.MethodPut, "/api/v2/plugins/echo/items/1", "hello")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	require.Equal(t, "/items/1", rr.Header().Get("X-Echo-Path"))
+	require.Empty(t, rr.Header().Get("X-Echo-Token"))
+	require.Equal(t, "hello", rr.Body.String())
+
+	rr = do(http.MethodPost, "/api/v2/plugins/echo/", strings.Repeat("a", maxPluginRequestSize+1))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	rr = do(http.MethodGet, "/api/v2/plugins/echo/invalid_status", "")
+	require.Equal(t, http.StatusBadGateway, rr.Code)
+	require.Contains(t, rr.Body.String(), "plugin returned an invalid status")
+
+	rr = do(http.MethodGet, "/api/v2/plugins/other/", "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	// the requests fail once the plugin is gone
+	require.NoError(t, p.Close())
+	rr = do(http.MethodGet, "/api/v2/plugins/echo/", "")
+	require.Equal(t, http.StatusBadGateway, rr.Code)
+}
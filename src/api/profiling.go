@@ -0,0 +1,198 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/profiling"
+)
+
+// ProfilesResponse is returned by /api/v1/admin/profiles
+type ProfilesResponse struct {
+	CPU profiling.CPUStatus `json:"cpu"`
+	// Profiles are the profiles captured, newest first
+	Profiles []profiling.Profile `json:"profiles"`
+}
+
+// adminProfilesHandler lists the profiles captured and the CPU profile running, or removes a profile
+// URI: /api/v1/admin/profiles
+// Method: GET, DELETE
+// Args:
+//  name: name of the profile removed [required for DELETE]
+func adminProfilesHandler(profiler *profiling.Profiler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			profiles, err := profiler.List()
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: ProfilesResponse{
+					CPU:      profiler.CPU(),
+					Profiles: profiles,
+				},
+			})
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "name is required")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			if err := profiler.Remove(name); err != nil {
+				writeProfilingError(w, r, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+// adminCPUProfileHandler starts or stops a CPU profile. A CPU profile is stopped after its duration if it is not
+// stopped before.
+// URI: /api/v1/admin/profiles/cpu/start, /api/v1/admin/profiles/cpu/stop
+// Method: POST
+// Args:
+//  duration: how long the CPU profile runs, e.g. 1m [optional for start, defaults to 30s, at most 10m]
+func adminCPUProfileHandler(name string, profiler *profiling.Profiler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if name == "stop" {
+			profile, err := profiler.StopCPU()
+			if err != nil {
+				writeProfilingError(w, r, err)
+				return
+			}
+
+			requestLogger(r).Infof("Stopped the CPU profile %s", profile.Name)
+			writeHTTPResponse(w, HTTPResponse{
+				Data: profile,
+			})
+			return
+		}
+
+		duration := profiling.DefaultCPUDuration
+		if v := r.URL.Query().Get("duration"); v != "" {
+			var err error
+			duration, err = time.ParseDuration(v)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid value for duration %q", v))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		status, err := profiler.StartCPU(duration)
+		if err != nil {
+			writeProfilingError(w, r, err)
+			return
+		}
+
+		requestLogger(r).Infof("Started the CPU profile %s for %s", status.Name, duration)
+		writeHTTPResponse(w, HTTPResponse{
+			Data: status,
+		})
+	}
+}
+
+// adminProfileCaptureHandler captures a dump of the runtime: heap, allocs, goroutine, threadcreate, block or mutex
+// URI: /api/v1/admin/profiles/capture
+// Method: POST
+// Args:
+//  type: type of the dump [required]
+func adminProfileCaptureHandler(profiler *profiling.Profiler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		typ := r.URL.Query().Get("type")
+		if typ == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "type is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		profile, err := profiler.Capture(typ)
+		if err != nil {
+			writeProfilingError(w, r, err)
+			return
+		}
+
+		requestLogger(r).Infof("Captured the %s profile %s", typ, profile.Name)
+		writeHTTPResponse(w, HTTPResponse{
+			Data: profile,
+		})
+	}
+}
+
+// adminProfileDownloadHandler downloads a profile, in the pprof format read by go tool pprof
+// URI: /api/v1/admin/profiles/download
+// Method: GET
+// Args:
+//  name: name of the profile [required]
+func adminProfileDownloadHandler(profiler *profiling.Profiler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "name is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		f, profile, err := profiler.Open(name)
+		if err != nil {
+			writeProfilingError(w, r, err)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", profile.Name))
+		w.Header().Set("Content-Length", fmt.Sprint(profile.Size))
+		if _, err := io.Copy(w, f); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write profile")
+		}
+	}
+}
+
+// writeProfilingError writes the error response of an error of the profiler
+func writeProfilingError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp HTTPResponse
+	switch err {
+	case profiling.ErrNotFound:
+		resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+	case profiling.ErrCPUProfileRunning, profiling.ErrCPUProfileNotRunning:
+		resp = NewHTTPErrorResponse(http.StatusConflict, err.Error())
+	case profiling.ErrUnknownType, profiling.ErrInvalidDuration:
+		resp = NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+	default:
+		requestLogger(r).Errorf("profiling failed: %s", err.Error())
+		resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	writeHTTPResponse(w, resp)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/profiling"
+)
+
+func TestAdminProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	cfg := defaultMuxConfig()
+	cfg.adminToken = testAdminToken
+	cfg.profiler = profiling.New(dir)
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method, endpoint, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, endpoint, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	decode := func(rr *httptest.ResponseRecorder, status int, errMsg string, data interface{}) {
+		require.Equal(t, status, rr.Code, rr.Body.String())
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		if errMsg != "" {
+			require.NotNil(t, rsp.Error)
+			require.Equal(t, errMsg, rsp.Error.Message)
+			return
+		}
+		require.Nil(t, rsp.Error)
+		if data != nil {
+			require.NoError(t, json.Unmarshal(rsp.Data, data))
+		}
+	}
+
+	rr := do(http.MethodGet, "/api/v2/admin/profiles", "")
+	decode(rr, http.StatusUnauthorized, "invalid admin token", nil)
+
+	rr = do(http.MethodPut, "/api/v2/admin/profiles", testAdminToken)
+	decode(rr, http.StatusMethodNotAllowed, "Method Not Allowed", nil)
+
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/capture", testAdminToken)
+	decode(rr, http.StatusBadRequest, "type is required", nil)
+
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/capture?type=cpu", testAdminToken)
+	decode(rr, http.StatusUnprocessableEntity, profiling.ErrUnknownType.Error(), nil)
+
+	var heap profiling.Profile
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/capture?type=heap", testAdminToken)
+	decode(rr, http.StatusOK, "", &heap)
+	require.Equal(t, "heap", heap.Type)
+
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/cpu/stop", testAdminToken)
+	decode(rr, http.StatusConflict, profiling.ErrCPUProfileNotRunning.Error(), nil)
+
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/cpu/start?duration=forever", testAdminToken)
+	decode(rr, http.StatusBadRequest, `invalid value for duration "forever"`, nil)
+
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/cpu/start?duration=1h", testAdminToken)
+	decode(rr, http.StatusUnprocessableEntity, profiling.ErrInvalidDuration.Error(), nil)
+
+	var status profiling.CPUStatus
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/cpu/start?duration=1m", testAdminToken)
+	decode(rr, http.StatusOK, "", &status)
+	require.True(t, status.Running)
+
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/cpu/start", testAdminToken)
+	decode(rr, http.StatusConflict, profiling.ErrCPUProfileRunning.Error(), nil)
+
+	var profiles ProfilesResponse
+	rr = do(http.MethodGet, "/api/v2/admin/profiles", testAdminToken)
+	decode(rr, http.StatusOK, "", &profiles)
+	require.True(t, profiles.CPU.Running)
+	require.Len(t, profiles.Profiles, 1)
+
+	var cpu profiling.Profile
+	rr = do(http.MethodPost, "/api/v2/admin/profiles/cpu/stop", testAdminToken)
+	decode(rr, http.StatusOK, "", &cpu)
+	require.Equal(t, status.Name, cpu.Name)
+
+	rr = do(http.MethodGet, "/api/v2/admin/profiles/download?name="+heap.Name, testAdminToken)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="`+heap.Name+`"`, rr.Header().Get("Content-Disposition"))
+	require.Len(t, rr.Body.Bytes(), int(heap.Size))
+
+	rr = do(http.MethodGet, "/api/v2/admin/profiles/download?name=../daemon.db", testAdminToken)
+	decode(rr, http.StatusNotFound, profiling.ErrNotFound.Error(), nil)
+
+	rr = do(http.MethodDelete, "/api/v2/admin/profiles?name="+heap.Name, testAdminToken)
+	decode(rr, http.StatusOK, "", nil)
+
+	rr = do(http.MethodGet, "/api/v2/admin/profiles", testAdminToken)
+	decode(rr, http.StatusOK, "", &profiles)
+	require.False(t, profiles.CPU.Running)
+	require.Equal(t, []profiling.Profile{cpu}, profiles.Profiles)
+}
+
+func TestAdminProfilesDisabled(t *testing.T) {
+	// the profiling endpoints are only served with a profiler and an admin token
+	dir, err := ioutil.TempDir("", "profiling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	cfg := defaultMuxConfig()
+	cfg.profiler = profiling.New(dir)
+	cfgNoProfiler := defaultMuxConfig()
+	cfgNoProfiler.adminToken = testAdminToken
+
+	for _, c := range []muxConfig{cfg, cfgNoProfiler} {
+		req, err := http.NewRequest(http.MethodGet, "/api/v2/admin/profiles", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		rr := httptest.NewRecorder()
+		newServerMux(c, &MockGatewayer{}).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// forwardedProtoKey is the context key of the scheme forwarded by the reverse proxy
+type forwardedProtoKey struct{}
+
+// behindProxy takes the client address and the scheme of the requests from the X-Forwarded-For and
+// X-Forwarded-Proto headers set by the reverse proxy in front of the daemon. The last address of X-Forwarded-For is
+// the one the proxy received the request from, the addresses before it are set by the client and are not trusted.
+// The headers are only taken from the requests of a loopback address or of the trusted networks, a client reaching
+// the daemon directly cannot pass for another address.
+func behindProxy(enabled bool, trusted []*net.IPNet, handler http.Handler) http.Handler {
+	if !enabled {
+		return handler
+	}
+
+	isTrusted := func(r *http.Request) bool {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return false
+		}
+		if ip.IsLoopback() {
+			return true
+		}
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isTrusted(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			addrs := strings.Split(forwardedFor, ",")
+			if ip := net.ParseIP(strings.TrimSpace(addrs[len(addrs)-1])); ip != nil {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+		}
+
+		switch proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto {
+		case "http", "https":
+			r = r.WithContext(context.WithValue(r.Context(), forwardedProtoKey{}, proto))
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// requestScheme returns the scheme the client sent the request with, the one forwarded by the reverse proxy if any
+func requestScheme(r *http.Request) string {
+	if proto, ok := r.Context().Value(forwardedProtoKey{}).(string); ok {
+		return proto
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBehindProxy(t *testing.T) {
+	var remoteAddr, scheme string
+	record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		scheme = requestScheme(r)
+	})
+
+	trusted, err := ParseIPAllowlist("10.0.0.0/8")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		enabled    bool
+		peer       string
+		forwarded  string
+		proto      string
+		remoteAddr string
+		scheme     string
+	}{
+		{
+			name:       "disabled",
+			forwarded:  "203.0.113.7",
+			proto:      "https",
+			remoteAddr: "127.0.0.1:50000",
+			scheme:     "http",
+		},
+		{
+			name:       "forwarded",
+			enabled:    true,
+			forwarded:  "203.0.113.7",
+			proto:      "https",
+			remoteAddr: "203.0.113.7:0",
+			scheme:     "https",
+		},
+		{
+			name:       "the addresses set by the client are not trusted",
+			enabled:    true,
+			forwarded:  "192.0.2.1, 203.0.113.7",
+			remoteAddr: "203.0.113.7:0",
+			scheme:     "http",
+		},
+		{
+			name:       "ipv6",
+			enabled:    true,
+			forwarded:  "2001:db8::1",
+			remoteAddr: "[2001:db8::1]:0",
+			scheme:     "http",
+		},
+		{
+			name:       "invalid",
+			enabled:    true,
+			forwarded:  "unknown",
+			proto:      "gopher",
+			remoteAddr: "127.0.0.1:50000",
+			scheme:     "http",
+		},
+		{
+			name:       "trusted proxy",
+			enabled:    true,
+			peer:       "10.1.2.3:40000",
+			forwarded:  "203.0.113.7",
+			proto:      "https",
+			remoteAddr: "203.0.113.7:0",
+			scheme:     "https",
+		},
+		{
+			name:       "the headers of a client which is not a proxy are not trusted",
+			enabled:    true,
+			peer:       "198.51.100.9:40000",
+			forwarded:  "127.0.0.1",
+			proto:      "https",
+			remoteAddr: "198.51.100.9:40000",
+			scheme:     "http",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/v2/version", nil)
+			require.NoError(t, err)
+			req.RemoteAddr = "127.0.0.1:50000"
+			if tc.peer != "" {
+				req.RemoteAddr = tc.peer
+			}
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+			if tc.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+
+			behindProxy(tc.enabled, trusted, record).ServeHTTP(httptest.NewRecorder(), req)
+			require.Equal(t, tc.remoteAddr, remoteAddr)
+			require.Equal(t, tc.scheme, scheme)
+		})
+	}
+}
+
+func TestBehindProxyAllowlist(t *testing.T) {
+	allowed, err := ParseIPAllowlist("192.168.1.0/24")
+	require.NoError(t, err)
+	trusted, err := ParseIPAllowlist("10.0.0.5")
+	require.NoError(t, err)
+
+	handler := behindProxy(true, trusted, ipAllowlist(allowed, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	check := func(peer, forwarded string) int {
+		req, err := http.NewRequest(http.MethodGet, "/api/v2/version", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = peer
+		req.Header.Set("X-Forwarded-For", forwarded)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, check("10.0.0.5:40000", "192.168.1.20"))
+	require.Equal(t, http.StatusForbidden, check("10.0.0.5:40000", "203.0.113.7"))
+	// a client connecting directly does not pass for a loopback or allowed address
+	require.Equal(t, http.StatusForbidden, check("203.0.113.7:40000", "127.0.0.1"))
+	require.Equal(t, http.StatusForbidden, check("203.0.113.7:40000", "192.168.1.20"))
+}
+
+func TestBehindProxyOriginCheck(t *testing.T) {
+	handler := behindProxy(true, nil, originRefererCheck("127.0.0.1:9510", []string{"wallet.example.com"}, newCORSOrigins(nil).match, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	check := func(proto, origin string) int {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/sign_message", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = "127.0.0.1:50000"
+		req.Host = "wallet.example.com"
+		req.Header.Set("X-Forwarded-Proto", proto)
+		req.Header.Set("Origin", origin)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, check("https", "https://wallet.example.com"))
+	require.Equal(t, http.StatusOK, check("http", "http://wallet.example.com"))
+	// the page of the https host is not served over http
+	require.Equal(t, http.StatusForbidden, check("https", "http://wallet.example.com"))
+	require.Equal(t, http.StatusForbidden, check("https", "https://evil.example.com"))
+}
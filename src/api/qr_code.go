@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+	"github.com/skycoin/hardware-wallet-daemon/src/qrcode"
+)
+
+const (
+	// QRCodeFormatPNG and QRCodeFormatSVG are the image formats of the QR codes
+	QRCodeFormatPNG = "png"
+	QRCodeFormatSVG = "svg"
+
+	// maxQRCodeScale is the largest width of a module of a QR code, in pixels
+	maxQRCodeScale = 32
+)
+
+// QRCodeTransactionRequest is request data for POST /api/v1/qrcode/transaction
+type QRCodeTransactionRequest struct {
+	// RawTransaction is the serialization of the fully signed transaction, hex encoded
+	RawTransaction string `json:"raw_transaction"`
+	// Format is png or svg, png if empty
+	Format string `json:"format"`
+	// Level is the error correction level L, M, Q or H, M if empty
+	Level string `json:"level"`
+	// Scale is the width of a module in pixels, 8 if 0
+	Scale int `json:"scale"`
+}
+
+// qrCodeOptions are the options of a QR code image
+type qrCodeOptions struct {
+	format string
+	level  qrcode.Level
+	scale  int
+}
+
+// URI: /api/v1/qrcode/address
+// Method: GET
+// Args:
+//
+//	index: index of the address of the device [optional, defaults to 0]
+//	confirm_address: show the address on the device before the code is returned [optional]
+//	format: png or svg [optional, defaults to png]
+//	level: error correction level L, M, Q or H [optional, defaults to M]
+//	scale: width of a module in pixels [optional, defaults to 8]
+func qrCodeAddress(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		query := r.URL.Query()
+		index := 0
+		if s := query.Get("index"); s != "" {
+			var ok bool
+			if index, ok = queryInt(w, s, "index", 0, 0, int(^uint32(0)>>1)); !ok {
+				return
+			}
+		}
+
+		var confirmAddress bool
+		if s := query.Get("confirm_address"); s != "" {
+			var err error
+			confirmAddress, err = strconv.ParseBool(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid confirm_address")
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		scale := 0
+		if s := query.Get("scale"); s != "" {
+			var ok bool
+			if scale, ok = queryInt(w, s, "scale", 0, 1, maxQRCodeScale); !ok {
+				return
+			}
+		}
+
+		opts, err := parseQRCodeOptions(query.Get("format"), query.Get("level"), scale)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		addresses, ok := deviceAddresses(w, r, gateway, 1, index, confirmAddress)
+		if !ok {
+			return
+		}
+		if len(addresses) != 1 {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, fmt.Sprintf("the device returned %d addresses", len(addresses)))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeQRCode(w, r, []byte(addresses[0]), opts)
+	}
+}
+
+// URI: /api/v1/qrcode/transaction
+// Method: POST
+// Args: JSON Body
+func qrCodeTransaction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req QRCodeTransactionRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		opts, err := parseQRCodeOptions(req.Format, req.Level, req.Scale)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		data, err := hex.DecodeString(req.RawTransaction)
+		if err != nil || len(data) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid raw_transaction, it must be hex encoded")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		// only the transactions ready to be injected in the network are encoded
+		txn, err := partialtx.Deserialize(data)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		if !txn.Complete() {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, partialtx.ErrIncomplete.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeQRCode(w, r, []byte(req.RawTransaction), opts)
+	}
+}
+
+// parseQRCodeOptions parses the options of a QR code image, the empty ones take their default
+func parseQRCodeOptions(format, level string, scale int) (qrCodeOptions, error) {
+	opts := qrCodeOptions{
+		format: format,
+		level:  qrcode.Medium,
+		scale:  scale,
+	}
+
+	switch format {
+	case "":
+		opts.format = QRCodeFormatPNG
+	case QRCodeFormatPNG, QRCodeFormatSVG:
+	default:
+		return qrCodeOptions{}, fmt.Errorf("invalid format %q, choices are: %s or %s", format, QRCodeFormatPNG, QRCodeFormatSVG)
+	}
+
+	if level != "" {
+		var err error
+		opts.level, err = qrcode.ParseLevel(level)
+		if err != nil {
+			return qrCodeOptions{}, err
+		}
+	}
+
+	if scale == 0 {
+		opts.scale = qrCodeScale
+	} else if scale < 1 || scale > maxQRCodeScale {
+		return qrCodeOptions{}, fmt.Errorf("scale must be between 1 and %d", maxQRCodeScale)
+	}
+
+	return opts, nil
+}
+
+// writeQRCode writes the image of the QR code of data
+func writeQRCode(w http.ResponseWriter, r *http.Request, data []byte, opts qrCodeOptions) {
+	code, err := qrcode.EncodeLevel(data, opts.level)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	var image []byte
+	contentType := "image/png"
+	if opts.format == QRCodeFormatSVG {
+		image, err = code.SVG(opts.scale)
+		contentType = "image/svg+xml"
+	} else {
+		image, err = code.PNG(opts.scale)
+	}
+	if err != nil {
+		requestLogger(r).WithError(err).Error("failed to draw the QR code")
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(image); err != nil {
+		requestLogger(r).WithError(err).Error("failed to write the QR code")
+	}
+}
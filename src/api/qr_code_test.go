@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+)
+
+func TestQRCodeAddress(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("AddressGen", uint32(1), uint32(3), false).Return(newReply(t, messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+		Addresses: []string{"2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"},
+	}), nil)
+	gateway.On("AddressGen", uint32(1), uint32(0), true).Return(newReply(t, messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{}), nil)
+
+	handler := newServerMux(defaultMuxConfig(), gateway)
+
+	do := func(url string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := do("/api/v2/qrcode/address?index=3&scale=2&level=H")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	img, err := png.Decode(rr.Body)
+	require.NoError(t, err)
+	// the 35 characters of the address fit in a version 5 code at the high level
+	require.Equal(t, (37+8)*2, img.Bounds().Dx())
+
+	rr = do("/api/v2/qrcode/address?index=3&format=svg")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(rr.Body.String(), "<?xml"))
+
+	// the device asks for the PIN before showing the address
+	rr = do("/api/v2/qrcode/address?confirm_address=true")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, ContentTypeJSON, rr.Header().Get("Content-Type"))
+
+	rr = do("/api/v2/qrcode/address?format=gif")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do("/api/v2/qrcode/address?level=X")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do("/api/v2/qrcode/address?scale=33")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do("/api/v2/qrcode/address?index=-1")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	gateway.AssertNumberOfCalls(t, "AddressGen", 3)
+}
+
+func TestQRCodeTransaction(t *testing.T) {
+	pubKey, secKey := cipher.MustGenerateDeterministicKeyPair([]byte("alice"))
+	alice := cipher.AddressFromPubKey(pubKey)
+
+	txn, err := partialtx.New([]partialtx.Input{
+		{Hash: cipher.MustSHA256FromHex("c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"), Address: alice},
+	}, []partialtx.Output{
+		{Address: cipher.MustDecodeBase58Address("2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"), Coins: 2000000, Hours: 2},
+	})
+	require.NoError(t, err)
+	require.NoError(t, txn.Sign(0, cipher.MustSignHash(txn.SignatureHash(0), secKey)))
+	data, err := txn.Serialize()
+	require.NoError(t, err)
+	raw := hex.EncodeToString(data)
+
+	handler := newServerMux(defaultMuxConfig(), &MockGatewayer{})
+
+	post := func(req QRCodeTransactionRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		r, err := http.NewRequest(http.MethodPost, "/api/v2/qrcode/transaction", bytes.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := post(QRCodeTransactionRequest{RawTransaction: raw})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	_, err = png.Decode(rr.Body)
+	require.NoError(t, err)
+
+	rr = post(QRCodeTransactionRequest{RawTransaction: raw, Format: QRCodeFormatSVG, Level: "L", Scale: 1})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+
+	// the unsigned transactions are not encoded
+	unsigned := append([]byte(nil), data...)
+	sig := 4 + 1 + len(cipher.SHA256{}) + 4
+	copy(unsigned[sig:sig+len(cipher.Sig{})], make([]byte, len(cipher.Sig{})))
+	rr = post(QRCodeTransactionRequest{RawTransaction: hex.EncodeToString(unsigned)})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = post(QRCodeTransactionRequest{RawTransaction: raw[:len(raw)-2]})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = post(QRCodeTransactionRequest{RawTransaction: "zz"})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = post(QRCodeTransactionRequest{RawTransaction: raw, Level: "medium"})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
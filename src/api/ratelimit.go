@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// EndpointClassDevice is the rate limit class of the device endpoints
+	EndpointClassDevice = "device"
+	// EndpointClassDestructive is the rate limit class of the endpoints erasing or replacing the seed or the firmware
+	EndpointClassDestructive = "destructive"
+
+	// rateLimitPruneInterval is how often the buckets of idle clients are removed
+	rateLimitPruneInterval = time.Minute
+)
+
+// destructiveEndpoints are the endpoints in the destructive rate limit class
+var destructiveEndpoints = map[string]struct{}{
+	"/firmware_update": {},
+	"/recovery":        {},
+	"/wipe":            {},
+}
+
+// RateLimit is a token bucket budget: Burst requests at once, refilled at Rate requests per second
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimits are the budgets of each client IP, per endpoint class
+type RateLimits struct {
+	Device      RateLimit
+	Destructive RateLimit
+}
+
+// DefaultRateLimits returns the default budgets. They do not get in the way of a wallet,
+// but keep a buggy client from flooding the device or looping on destructive operations.
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Device: RateLimit{
+			Rate:  10,
+			Burst: 20,
+		},
+		Destructive: RateLimit{
+			Rate:  0.1,
+			Burst: 3,
+		},
+	}
+}
+
+// Validate checks that every class has a positive budget
+func (l RateLimits) Validate() error {
+	classes := []struct {
+		name  string
+		limit RateLimit
+	}{
+		{EndpointClassDevice, l.Device},
+		{EndpointClassDestructive, l.Destructive},
+	}
+
+	for _, c := range classes {
+		if c.limit.Rate <= 0 {
+			return fmt.Errorf("%s rate limit must be greater than 0", c.name)
+		}
+		if c.limit.Burst < 1 {
+			return fmt.Errorf("%s rate limit burst must be at least 1", c.name)
+		}
+	}
+
+	return nil
+}
+
+func (l RateLimits) limit(class string) RateLimit {
+	if class == EndpointClassDestructive {
+		return l.Destructive
+	}
+	return l.Device
+}
+
+// endpointClass returns the rate limit class of endpoint
+func endpointClass(endpoint string) string {
+	if _, ok := destructiveEndpoints[endpoint]; ok {
+		return EndpointClassDestructive
+	}
+	return EndpointClassDevice
+}
+
+// bucketKey identifies the bucket of a client for an endpoint class
+type bucketKey struct {
+	ip    string
+	class string
+}
+
+// tokenBucket is the budget left to a client for an endpoint class
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps a token bucket per client IP and endpoint class
+type rateLimiter struct {
+	limits RateLimits
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	return &rateLimiter{
+		limits:  limits,
+		buckets: make(map[bucketKey]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the bucket of ip for class.
+// If the bucket is empty, it returns false with the time until a token is available.
+func (l *rateLimiter) allow(ip, class string) (bool, time.Duration) {
+	limit := l.limits.limit(class)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	key := bucketKey{
+		ip:    ip,
+		class: class,
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{
+			tokens: float64(limit.Burst),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// prune removes the buckets refilled since their last request, must be called with the lock held
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		limit := l.limits.limit(key.class)
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientIP returns the IP of the client of the request.
+// Forwarding headers are not trusted, with -behind-proxy the remote address is the one forwarded by the proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit rejects the requests to endpoint exceeding the budget of the client IP with a 429 and a Retry-After header
+func rateLimit(limiter *rateLimiter, endpoint string, handler http.Handler) http.Handler {
+	if limiter == nil {
+		return handler
+	}
+
+	class := endpointClass(endpoint)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if ok, wait := limiter.allow(ip, class); !ok {
+			requestLogger(r).Warningf("Rate limit of the %s endpoints exceeded by %s on %s", class, ip, endpoint)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			resp := NewHTTPErrorResponse(http.StatusTooManyRequests, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitsValidate(t *testing.T) {
+	require.NoError(t, DefaultRateLimits().Validate())
+
+	limits := DefaultRateLimits()
+	limits.Device.Rate = 0
+	require.EqualError(t, limits.Validate(), "device rate limit must be greater than 0")
+
+	limits = DefaultRateLimits()
+	limits.Destructive.Burst = 0
+	require.EqualError(t, limits.Validate(), "destructive rate limit burst must be at least 1")
+}
+
+func TestEndpointClass(t *testing.T) {
+	require.Equal(t, EndpointClassDestructive, endpointClass("/wipe"))
+	require.Equal(t, EndpointClassDestructive, endpointClass("/recovery"))
+	require.Equal(t, EndpointClassDestructive, endpointClass("/firmware_update"))
+	require.Equal(t, EndpointClassDevice, endpointClass("/features"))
+	require.Equal(t, EndpointClassDevice, endpointClass("/intermediate/button"))
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := newRateLimiter(RateLimits{
+		Device: RateLimit{
+			Rate:  2,
+			Burst: 3,
+		},
+		Destructive: RateLimit{
+			Rate:  0.1,
+			Burst: 1,
+		},
+	})
+
+	now := time.Date(2019, 7, 26, 10, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time {
+		return now
+	}
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.allow("127.0.0.1", EndpointClassDevice)
+		require.True(t, ok)
+	}
+
+	ok, wait := limiter.allow("127.0.0.1", EndpointClassDevice)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// the buckets are per client and per class
+	ok, _ = limiter.allow("10.0.0.1", EndpointClassDevice)
+	require.True(t, ok)
+	ok, _ = limiter.allow("127.0.0.1", EndpointClassDestructive)
+	require.True(t, ok)
+	ok, wait = limiter.allow("127.0.0.1", EndpointClassDestructive)
+	require.False(t, ok)
+	require.Equal(t, 10*time.Second, wait)
+
+	// the bucket refills at the rate
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow("127.0.0.1", EndpointClassDevice)
+	require.True(t, ok)
+	ok, _ = limiter.allow("127.0.0.1", EndpointClassDevice)
+	require.False(t, ok)
+
+	// the refill does not exceed the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.allow("127.0.0.1", EndpointClassDevice)
+		require.True(t, ok)
+	}
+	ok, _ = limiter.allow("127.0.0.1", EndpointClassDevice)
+	require.False(t, ok)
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	limiter := newRateLimiter(RateLimits{
+		Device: RateLimit{
+			Rate:  1,
+			Burst: 10,
+		},
+		Destructive: RateLimit{
+			Rate:  0.01,
+			Burst: 3,
+		},
+	})
+
+	now := time.Date(2019, 7, 26, 10, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time {
+		return now
+	}
+
+	limiter.allow("10.0.0.1", EndpointClassDevice)
+	limiter.allow("10.0.0.2", EndpointClassDestructive)
+	require.Len(t, limiter.buckets, 2)
+
+	// the device bucket is refilled, the destructive one is not
+	now = now.Add(rateLimitPruneInterval)
+	limiter.allow("10.0.0.3", EndpointClassDevice)
+	require.Len(t, limiter.buckets, 2)
+	_, ok := limiter.buckets[bucketKey{ip: "10.0.0.2", class: EndpointClassDestructive}]
+	require.True(t, ok)
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := defaultMuxConfig()
+	cfg.rateLimiter = newRateLimiter(RateLimits{
+		Device: RateLimit{
+			Rate:  1,
+			Burst: 2,
+		},
+		Destructive: RateLimit{
+			Rate:  1.0 / 30,
+			Burst: 1,
+		},
+	})
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method, endpoint, remoteAddr string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v2"+endpoint, nil)
+		require.NoError(t, err)
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// requests rejected by the handler take a token too
+	for i := 0; i < 2; i++ {
+		rr := do(http.MethodGet, "/generate_addresses", "127.0.0.1:40000")
+		require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	}
+
+	rr := do(http.MethodGet, "/generate_addresses", "127.0.0.1:40001")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	var rsp ReceivedHTTPResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, NewHTTPErrorResponse(http.StatusTooManyRequests, "").Error, rsp.Error)
+
+	rr = do(http.MethodGet, "/generate_addresses", "10.0.0.1:40000")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	rr = do(http.MethodGet, "/wipe", "127.0.0.1:40000")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	rr = do(http.MethodGet, "/wipe", "127.0.0.1:40000")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.Equal(t, "30", rr.Header().Get("Retry-After"))
+
+	// the endpoints which do not use the device are not rate limited
+	for i := 0; i < 3; i++ {
+		rr = do(http.MethodGet, "/version", "127.0.0.1:40000")
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+}
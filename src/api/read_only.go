@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+)
+
+// readOnlyMessage is the message of the requests refused in read-only mode
+const readOnlyMessage = "the daemon runs with -read-only, the endpoint is disabled"
+
+// readOnlyEndpoints are the device endpoints served in read-only mode: they derive the addresses, read the features
+// and verify, without changing the device nor signing with its keys. The intermediate endpoints answer the PIN and
+// passphrase the device asks for while deriving the addresses, and cannot start an operation.
+var readOnlyEndpoints = map[string]struct{}{
+	"/account_export":          {},
+	"/available":               {},
+	"/cancel":                  {},
+	"/check_message_signature": {},
+	"/device_authenticity":     {},
+	"/diagnostics":             {},
+	"/entropy_check":           {},
+	"/features":                {},
+	"/firmware_check":          {},
+	"/generate_addresses":      {},
+	"/qrcode/address":          {},
+	"/transactions":            {},
+
+	"/intermediate/pin_matrix": {},
+	"/intermediate/passphrase": {},
+	"/intermediate/word":       {},
+	"/intermediate/button":     {},
+}
+
+// readOnlyGate refuses the requests to the device endpoints which are not read-only with a 403 in read-only mode
+func readOnlyGate(readOnly bool, endpoint string, handler http.Handler) http.Handler {
+	if !readOnly {
+		return handler
+	}
+	if _, ok := readOnlyEndpoints[endpoint]; ok {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReadOnlyError(w)
+	})
+}
+
+// readOnlyMethods serves the GET requests only in read-only mode, of the endpoints changing the state of the daemon
+// or the emulator with the other methods
+func readOnlyMethods(readOnly bool, handler http.Handler) http.Handler {
+	if !readOnly {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeReadOnlyError(w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func writeReadOnlyError(w http.ResponseWriter) {
+	resp := newHTTPErrorResponseCategory(http.StatusForbidden, ErrorCategoryReadOnly, readOnlyMessage)
+	writeHTTPResponse(w, resp)
+}
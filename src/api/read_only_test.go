@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/plugin"
+)
+
+func TestReadOnly(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		readOnly bool
+		status   int
+	}{
+		{"/api/v2/wipe", false, http.StatusMethodNotAllowed},
+		{"/api/v2/wipe", true, http.StatusForbidden},
+		{"/api/v2/transaction_sign", true, http.StatusForbidden},
+		{"/api/v2/sign_message", true, http.StatusForbidden},
+		{"/api/v2/recovery", true, http.StatusForbidden},
+		{"/api/v2/apply_settings", true, http.StatusForbidden},
+		{"/api/v2/set_mnemonic", true, http.StatusForbidden},
+		{"/api/v2/firmware_update", true, http.StatusForbidden},
+		{"/api/v2/backup", true, http.StatusForbidden},
+		// the read-only endpoints are reached, and reject the request method
+		{"/api/v2/generate_addresses", true, http.StatusMethodNotAllowed},
+		{"/api/v2/check_message_signature", true, http.StatusMethodNotAllowed},
+		{"/api/v2/intermediate/pin_matrix", true, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range cases {
+		name := tc.endpoint
+		if tc.readOnly {
+			name += " read-only"
+		}
+		t.Run(name, func(t *testing.T) {
+			cfg := defaultMuxConfig()
+			cfg.readOnly = tc.readOnly
+			handler := newServerMux(cfg, &MockGatewayer{})
+
+			req, err := http.NewRequest(http.MethodGet, tc.endpoint, nil)
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			if tc.status == http.StatusForbidden {
+				var rsp ReceivedHTTPResponse
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+				require.Equal(t, readOnlyMessage, rsp.Error.Message)
+				require.Equal(t, ErrorCategoryReadOnly, rsp.Error.Category)
+			}
+		})
+	}
+}
+
+func TestReadOnlyServices(t *testing.T) {
+	server, client := net.Pipe()
+	go plugin.ServeConn(echoPlugin{}, server) // nolint: errcheck
+
+	p, err := plugin.Connect(client)
+	require.NoError(t, err)
+	defer p.Close()
+
+	gateway := &MockGatewayer{}
+	cfg := defaultMuxConfig()
+	cfg.readOnly = true
+	cfg.modeSwitch = newTestModeSwitch()
+	cfg.adminToken = testAdminToken
+	cfg.plugins = []*plugin.Client{p}
+	cfg.sessions = newTestSessions(gateway, false)
+	cfg.relay = newTestRelay()
+	cfg.approvals = approval.NewManager(0)
+	// the approval endpoints take the same bearer token as the admin endpoints
+	cfg.approvalToken = testAdminToken
+	handler := newServerMux(cfg, gateway)
+
+	cases := []struct {
+		method   string
+		endpoint string
+		refused  bool
+	}{
+		// the mode switch changes the device
+		{http.MethodPut, "/api/v2/admin/mode", true},
+		{http.MethodGet, "/api/v2/admin/mode", false},
+		// the plugins drive the device, such as signing
+		{http.MethodPost, "/api/v2/plugins/echo/sign", true},
+		{http.MethodGet, "/api/v2/plugins/echo/status", true},
+		{http.MethodGet, "/api/v2/plugins", false},
+		// the sessions hold the device
+		{http.MethodPost, "/api/v2/session", true},
+		{http.MethodDelete, "/api/v2/session", true},
+		{http.MethodGet, "/api/v2/session", false},
+		// the paired relay clients drive the device remotely
+		{http.MethodPost, "/api/v2/relay/pairing_code", true},
+		{http.MethodPost, "/api/v2/relay/clients", true},
+		{http.MethodDelete, "/api/v2/relay/clients", true},
+		{http.MethodGet, "/api/v2/relay", false},
+		// the approvals release the signatures held for them
+		{http.MethodPost, "/api/v2/approvals/approve", true},
+		{http.MethodPost, "/api/v2/approvals/reject", true},
+		{http.MethodGet, "/api/v2/approvals", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.endpoint, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.endpoint, strings.NewReader(`{}`))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			req.Header.Set(CSRFHeaderName, "token")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			if tc.refused {
+				require.Equal(t, http.StatusForbidden, rr.Code)
+				require.Equal(t, readOnlyMessage, rsp.Error.Message)
+				require.Equal(t, ErrorCategoryReadOnly, rsp.Error.Category)
+			} else if rsp.Error != nil {
+				require.NotEqual(t, ErrorCategoryReadOnly, rsp.Error.Category)
+			}
+		})
+	}
+}
+
+func TestReadOnlyMethods(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		rr := httptest.NewRecorder()
+		readOnlyMethods(false, ok).ServeHTTP(rr, httptest.NewRequest(method, "/", nil))
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	}
+
+	rr := httptest.NewRecorder()
+	readOnlyMethods(true, ok).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	readOnlyMethods(true, ok).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/", nil))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -28,6 +28,21 @@ func TestRecovery(t *testing.T) {
 	successMsgBytes, err := successMsg.Marshal()
 	require.NoError(t, err)
 
+	dryRunSuccessMsg := messages.Success{
+		Message: newStrPtr("The seed is valid and matches the one in the device"),
+	}
+
+	dryRunSuccessMsgBytes, err := dryRunSuccessMsg.Marshal()
+	require.NoError(t, err)
+
+	dryRunFailureMsg := messages.Failure{
+		Code:    messages.FailureType_Failure_DataError.Enum(),
+		Message: newStrPtr("The seed is valid but does not match the one in the device"),
+	}
+
+	dryRunFailureMsgBytes, err := dryRunFailureMsg.Marshal()
+	require.NoError(t, err)
+
 	cases := []struct {
 		name                  string
 		method                string
@@ -64,7 +79,7 @@ func TestRecovery(t *testing.T) {
 			name:         "409 - Failure msg",
 			method:       http.MethodPost,
 			status:       http.StatusConflict,
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
 			httpBody: toJSON(t, &RecoveryRequest{
 				WordCount: 2,
 			}),
@@ -89,6 +104,38 @@ func TestRecovery(t *testing.T) {
 				Data: successMsgBytes,
 			},
 		},
+
+		{
+			name:   "200 - OK dry run",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: []string{"The seed is valid and matches the one in the device"},
+			},
+			httpBody: toJSON(t, &RecoveryRequest{
+				WordCount: 12,
+				DryRun:    true,
+			}),
+			gatewayRecoveryResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Success),
+				Data: dryRunSuccessMsgBytes,
+			},
+		},
+
+		{
+			name:         "409 - dry run mismatch",
+			method:       http.MethodPost,
+			status:       http.StatusConflict,
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryDataError, "The seed is valid but does not match the one in the device"),
+			httpBody: toJSON(t, &RecoveryRequest{
+				WordCount: 24,
+				DryRun:    true,
+			}),
+			gatewayRecoveryResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: dryRunFailureMsgBytes,
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -102,7 +149,7 @@ func TestRecovery(t *testing.T) {
 				gateway.On("Recovery", body.WordCount, body.UsePassphrase, body.DryRun).Return(tc.gatewayRecoveryResult, nil)
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
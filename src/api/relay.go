@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/qrcode"
+	"github.com/skycoin/hardware-wallet-daemon/src/relay"
+)
+
+// qrCodeScale is the width of the modules of the QR codes, in pixels
+const qrCodeScale = 8
+
+// RelayResponse is returned by GET /api/v1/relay
+type RelayResponse struct {
+	relay.Status
+	Clients []relay.Client `json:"clients"`
+}
+
+// RelayClientRequest is request data for POST /api/v1/relay/clients
+type RelayClientRequest struct {
+	// PubKey is the hex encoded public key of the client
+	PubKey string `json:"pubkey"`
+	Name   string `json:"name"`
+}
+
+// RelayPairingCodeResponse is returned by POST /api/v1/relay/pairing_code
+type RelayPairingCodeResponse struct {
+	relay.PairingCode
+	// QRCode is the PNG image of the QR code of the pairing URI, base64 encoded
+	QRCode []byte `json:"qr_code"`
+}
+
+// relayHandler returns the status of the connection to the relay server and the paired clients.
+// The relay endpoints are only served to the local clients, the relay refuses to forward them.
+// URI: /api/v1/relay
+// Method: GET
+func relayHandler(r *relay.Relay) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		clients, err := r.Clients()
+		if err != nil {
+			requestLogger(req).WithError(err).Error("relay.Clients failed")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if clients == nil {
+			clients = []relay.Client{}
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: RelayResponse{
+				Status:  r.Status(),
+				Clients: clients,
+			},
+		})
+	}
+}
+
+// relayClientsHandler pairs a client with its public key, or removes its pairing.
+// The paired client encrypts its requests for the public key of the daemon returned by GET /api/v1/relay.
+// URI: /api/v1/relay/clients
+// Method: POST, DELETE
+// Args:
+//  pubkey: hex encoded public key of the client to unpair, for DELETE
+func relayClientsHandler(r *relay.Relay) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost:
+			if req.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var body RelayClientRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer req.Body.Close()
+
+			pubKey, err := cipher.PubKeyFromHex(body.PubKey)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid pubkey: %v", err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			c, err := r.Pair(pubKey, body.Name)
+			switch err {
+			case nil:
+			case relay.ErrInvalidName:
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			default:
+				requestLogger(req).WithError(err).Error("relay.Pair failed")
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: c,
+			})
+		case http.MethodDelete:
+			pubKey, err := cipher.PubKeyFromHex(req.FormValue("pubkey"))
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid pubkey: %v", err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			switch err := r.Unpair(pubKey); err {
+			case nil:
+			case relay.ErrNotPaired:
+				resp := NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			default:
+				requestLogger(req).WithError(err).Error("relay.Unpair failed")
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: []string{"Client unpaired"},
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+// relayPairingCodeHandler creates a one-time code pairing the client using it through the relay,
+// with the QR code of the pairing URI to scan with the client.
+// URI: /api/v1/relay/pairing_code
+// Method: POST
+func relayPairingCodeHandler(r *relay.Relay) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		pc, err := r.NewPairingCode()
+		if err != nil {
+			requestLogger(req).WithError(err).Error("relay.NewPairingCode failed")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		code, err := qrcode.Encode([]byte(pc.URI))
+		if err != nil {
+			requestLogger(req).WithError(err).Error("qrcode.Encode failed")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		image, err := code.PNG(qrCodeScale)
+		if err != nil {
+			requestLogger(req).WithError(err).Error("qrcode.PNG failed")
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: RelayPairingCodeResponse{
+				PairingCode: pc,
+				QRCode:      image,
+			},
+		})
+	}
+}
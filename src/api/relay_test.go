@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/relay"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func newTestRelay() *relay.Relay {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	return relay.New("https://relay.example.com", &relay.Key{
+		PubKey: pubKey,
+		SecKey: secKey,
+	}, storage.NewMemoryStore(), nil)
+}
+
+func TestRelay(t *testing.T) {
+	gateway := &MockGatewayer{}
+
+	cfg := defaultMuxConfig()
+	cfg.relay = newTestRelay()
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, endpoint, contentType, body string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, endpoint, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	status := func() RelayResponse {
+		rr, rsp := do(http.MethodGet, "/api/v2/relay", "", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var r RelayResponse
+		require.NoError(t, json.Unmarshal(rsp.Data, &r))
+		return r
+	}
+
+	rr, rsp := do(http.MethodPost, "/api/v2/relay", ContentTypeJSON, "")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusMethodNotAllowed, "").Error, rsp.Error)
+
+	r := status()
+	require.Equal(t, "https://relay.example.com", r.URL)
+	require.Equal(t, 66, len(r.PubKey))
+	require.False(t, r.Connected)
+	require.Empty(t, r.Clients)
+
+	pubKey, _ := cipher.GenerateKeyPair()
+
+	rr, rsp = do(http.MethodPut, "/api/v2/relay/clients", ContentTypeJSON, "")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusMethodNotAllowed, "").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/relay/clients", "", `{"pubkey":"`+pubKey.Hex()+`","name":"phone"}`)
+	require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/relay/clients", ContentTypeJSON, `{"pubkey":"00","name":"phone"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, "invalid pubkey: Invalid public key length").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/relay/clients", ContentTypeJSON, `{"pubkey":"`+pubKey.Hex()+`"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, relay.ErrInvalidName.Error()).Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "/api/v2/relay/clients", ContentTypeJSON, `{"pubkey":"`+pubKey.Hex()+`","name":"phone"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var c relay.Client
+	require.NoError(t, json.Unmarshal(rsp.Data, &c))
+	require.Equal(t, pubKey.Hex(), c.PubKey)
+	require.Equal(t, "phone", c.Name)
+	require.Nil(t, c.LastSeen)
+
+	r = status()
+	require.Len(t, r.Clients, 1)
+	require.Equal(t, "phone", r.Clients[0].Name)
+
+	rr, rsp = do(http.MethodDelete, "/api/v2/relay/clients?pubkey=00", "", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusBadRequest, "invalid pubkey: Invalid public key length").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodDelete, "/api/v2/relay/clients?pubkey="+pubKey.Hex(), "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr, rsp = do(http.MethodDelete, "/api/v2/relay/clients?pubkey="+pubKey.Hex(), "", "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusNotFound, relay.ErrNotPaired.Error()).Error, rsp.Error)
+
+	require.Empty(t, status().Clients)
+}
+
+func TestRelayPairingCode(t *testing.T) {
+	cfg := defaultMuxConfig()
+	cfg.relay = newTestRelay()
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, "/api/v2/relay/pairing_code", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	rr, rsp := do(http.MethodGet)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusMethodNotAllowed, "").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var pc RelayPairingCodeResponse
+	require.NoError(t, json.Unmarshal(rsp.Data, &pc))
+	require.NotEmpty(t, pc.Code)
+	require.True(t, strings.HasPrefix(pc.URI, relay.PairingURIScheme+"://pair?"))
+	require.Contains(t, pc.URI, "code="+pc.Code)
+
+	img, err := png.Decode(bytes.NewReader(pc.QRCode))
+	require.NoError(t, err)
+	require.Equal(t, 0, img.Bounds().Dx()%qrCodeScale)
+
+	// each request creates a new code
+	_, rsp = do(http.MethodPost)
+	var other RelayPairingCodeResponse
+	require.NoError(t, json.Unmarshal(rsp.Data, &other))
+	require.NotEqual(t, pc.Code, other.Code)
+}
+
+func TestRelayDisabled(t *testing.T) {
+	handler := newServerMux(defaultMuxConfig(), &MockGatewayer{})
+
+	for _, endpoint := range []string{"/api/v2/relay", "/api/v2/relay/clients", "/api/v2/relay/pairing_code"} {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	}
+}
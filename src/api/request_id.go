@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	wh "github.com/skycoin/skycoin/src/util/http"
+)
+
+const (
+	// RequestIDHeaderName is the header carrying the ID of a request, in the request and its response
+	RequestIDHeaderName = "X-Request-Id"
+
+	// maxRequestIDLength is the maximum length of a client provided request ID, longer IDs are replaced
+	maxRequestIDLength = 128
+)
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// requestID assigns an ID to each request, or honors the one sent by the client in the X-Request-Id header.
+// The ID is returned in the X-Request-Id header and the error of the response, included in the log lines
+// of the request, and recorded in the operation history and events, so multi-step flows can be traced.
+func requestID(enabled bool, handler http.Handler) http.Handler {
+	if !enabled {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeaderName)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeaderName, id)
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// elapsedHandler logs the status and duration of each request with the request logger
+func elapsedHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wh.ElapsedHandler(requestLogger(r), handler).ServeHTTP(w, r)
+	})
+}
+
+// requestIDFromRequest returns the ID of the request, empty if request IDs are disabled
+func requestIDFromRequest(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string) // nolint: errcheck
+	return id
+}
+
+// requestLogger returns the logger of the request, adding its ID to the log lines
+func requestLogger(r *http.Request) logrus.FieldLogger {
+	id := requestIDFromRequest(r)
+	if id == "" {
+		return logger
+	}
+	return logger.WithField("request_id", id)
+}
+
+// criticalRequestLogger returns the critical logger of the request, adding its ID to the log lines
+func criticalRequestLogger(r *http.Request) logrus.FieldLogger {
+	id := requestIDFromRequest(r)
+	if id == "" {
+		return logger.Critical()
+	}
+	return logger.Critical().WithField("request_id", id)
+}
+
+// isValidRequestID returns true if a client provided request ID can be honored.
+// IDs with spaces or control characters are replaced, so clients cannot forge log lines.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logger.Panic(err)
+	}
+	return hex.EncodeToString(b)
+}
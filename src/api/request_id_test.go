@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		name     string
+		clientID string
+		honored  bool
+	}{
+		{
+			name: "assigned",
+		},
+		{
+			name:     "client provided",
+			clientID: "flow-42.step-1",
+			honored:  true,
+		},
+		{
+			name:     "client provided with spaces",
+			clientID: "flow 42\nforged log line",
+		},
+		{
+			name:     "client provided too long",
+			clientID: strings.Repeat("a", maxRequestIDLength+1),
+		},
+	}
+
+	cfg := defaultMuxConfig()
+	cfg.requestID = true
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/v2/features", nil)
+			require.NoError(t, err)
+			if tc.clientID != "" {
+				req.Header.Set(RequestIDHeaderName, tc.clientID)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+			id := rr.Header().Get(RequestIDHeaderName)
+			if tc.honored {
+				require.Equal(t, tc.clientID, id)
+			} else {
+				require.Len(t, id, 32)
+				require.NotEqual(t, tc.clientID, id)
+			}
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, &HTTPError{
+				Code:      http.StatusMethodNotAllowed,
+				Message:   http.StatusText(http.StatusMethodNotAllowed),
+				Category:  ErrorCategoryMethodNotAllowed,
+				RequestID: id,
+			}, rsp.Error)
+		})
+	}
+
+	// the IDs of successive requests differ
+	ids := make(map[string]struct{})
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, "/api/v2/version", nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		ids[rr.Header().Get(RequestIDHeaderName)] = struct{}{}
+	}
+	require.Len(t, ids, 3)
+
+	// request IDs are disabled
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/features", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeaderName, "flow-42")
+	rr := httptest.NewRecorder()
+	newServerMux(defaultMuxConfig(), &MockGatewayer{}).ServeHTTP(rr, req)
+	require.Empty(t, rr.Header().Get(RequestIDHeaderName))
+	require.NotContains(t, rr.Body.String(), "request_id")
+}
+
+func TestRequestIDCorrelation(t *testing.T) {
+	bus := newTestBus(t)
+	recorder := history.NewRecorder(storage.NewMemoryStore(), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.requestID = true
+	cfg.events = bus
+	cfg.history = recorder
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/features", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeaderName, "flow-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	// the assigned ID is recorded as well
+	req, err = http.NewRequest(http.MethodGet, "/api/v2/wipe", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assigned := rr.Header().Get(RequestIDHeaderName)
+	require.NotEmpty(t, assigned)
+
+	recs, err := recorder.Records(storage.HistoryBucket, history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	require.Equal(t, "flow-42", recs[0].RequestID)
+	require.Equal(t, assigned, recs[1].RequestID)
+
+	audit, err := recorder.Records(storage.AuditBucket, history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, audit, 1)
+	require.Equal(t, assigned, audit[0].RequestID)
+
+	evs, err := bus.Since(0, 0, events.Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 2)
+	for i, id := range []string{"flow-42", assigned} {
+		var data OperationEventData
+		require.NoError(t, json.Unmarshal(evs[i].Data, &data))
+		require.Equal(t, id, data.RequestID)
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/features", nil)
+	require.NoError(t, err)
+	require.Equal(t, logger, requestLogger(req))
+
+	var logged *http.Request
+	requestID(true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logged = r
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	entry, ok := requestLogger(logged).(*logrus.Entry)
+	require.True(t, ok)
+	require.Equal(t, requestIDFromRequest(logged), entry.Data["request_id"])
+
+	entry, ok = criticalRequestLogger(logged).(*logrus.Entry)
+	require.True(t, ok)
+	require.Equal(t, requestIDFromRequest(logged), entry.Data["request_id"])
+}
+
+func TestRequestIDCORS(t *testing.T) {
+	cfg := defaultMuxConfig()
+	cfg.requestID = true
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	req, err := http.NewRequest(http.MethodOptions, "/api/v2/features", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://wallet.skycoin.net")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", RequestIDHeaderName)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, RequestIDHeaderName, rr.Header().Get("Access-Control-Allow-Headers"))
+
+	req, err = http.NewRequest(http.MethodGet, "/api/v2/version", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://wallet.skycoin.net")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, "Retry-After, "+ModelVersionHeaderName+", Deprecation, Sunset, Link, "+RequestIDHeaderName+", Etag", rr.Header().Get("Access-Control-Expose-Headers"))
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultContentSecurityPolicy forbids the responses of the daemon to load any content or to be framed
+	DefaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+	// DefaultFrameOptions forbids framing the pages of the daemon, for the browsers without frame-ancestors
+	DefaultFrameOptions = "DENY"
+	// DefaultReferrerPolicy sends no Referer header from the pages of the daemon
+	DefaultReferrerPolicy = "no-referrer"
+)
+
+// SecurityHeaders are the security headers set on all the responses, an empty header is not set.
+// X-Content-Type-Options: nosniff is always set.
+type SecurityHeaders struct {
+	// ContentSecurityPolicy is the Content-Security-Policy header. The Swagger UI page sets its own policy, allowing
+	// the scripts and styles it loads.
+	ContentSecurityPolicy string
+	// FrameOptions is the X-Frame-Options header, DENY or SAMEORIGIN
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy header
+	ReferrerPolicy string
+}
+
+// DefaultSecurityHeaders returns the strictest headers, the API serves no content to load or frame
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		ContentSecurityPolicy: DefaultContentSecurityPolicy,
+		FrameOptions:          DefaultFrameOptions,
+		ReferrerPolicy:        DefaultReferrerPolicy,
+	}
+}
+
+// Validate checks the X-Frame-Options header
+func (h SecurityHeaders) Validate() error {
+	switch strings.ToUpper(h.FrameOptions) {
+	case "", "DENY", "SAMEORIGIN":
+		return nil
+	default:
+		return fmt.Errorf("invalid X-Frame-Options %q, choices are DENY and SAMEORIGIN", h.FrameOptions)
+	}
+}
+
+// securityHeaders sets the security headers on the responses of handler, before the handler writes them
+func securityHeaders(headers SecurityHeaders, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		if headers.ContentSecurityPolicy != "" {
+			h.Set("Content-Security-Policy", headers.ContentSecurityPolicy)
+		}
+		if headers.FrameOptions != "" {
+			h.Set("X-Frame-Options", strings.ToUpper(headers.FrameOptions))
+		}
+		if headers.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", headers.ReferrerPolicy)
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
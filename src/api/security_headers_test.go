@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	c := defaultMuxConfig()
+	c.apiDocsAssetsURL = "https://mirror.example.com/swagger-ui"
+	mux := newServerMux(c, &MockGatewayer{})
+
+	get := func(headers SecurityHeaders, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		securityHeaders(headers, mux).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(DefaultSecurityHeaders(), "/api/v2/version")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	require.Equal(t, DefaultContentSecurityPolicy, rr.Header().Get("Content-Security-Policy"))
+	require.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+	require.Equal(t, "no-referrer", rr.Header().Get("Referrer-Policy"))
+
+	// the errors of the mux carry them too
+	rr = get(DefaultSecurityHeaders(), "/unknown")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, DefaultContentSecurityPolicy, rr.Header().Get("Content-Security-Policy"))
+
+	// the empty headers are not set
+	rr = get(SecurityHeaders{FrameOptions: "sameorigin"}, "/api/v2/version")
+	require.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	require.Equal(t, "SAMEORIGIN", rr.Header().Get("X-Frame-Options"))
+	require.Empty(t, rr.Header().Get("Content-Security-Policy"))
+	require.Empty(t, rr.Header().Get("Referrer-Policy"))
+
+	// the Swagger UI page allows its assets and its script
+	rr = get(DefaultSecurityHeaders(), "/apidocs")
+	require.Equal(t, http.StatusOK, rr.Code)
+	policy := rr.Header().Get("Content-Security-Policy")
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(policy)
+	require.Len(t, nonce, 2)
+	require.Equal(t, apiDocsPolicy("https://mirror.example.com/swagger-ui", nonce[1]), policy)
+	require.Contains(t, policy, "script-src https://mirror.example.com 'nonce-")
+	require.Contains(t, rr.Body.String(), `<script nonce="`+nonce[1]+`">`)
+
+	// each page has its own nonce
+	require.NotEqual(t, policy, get(DefaultSecurityHeaders(), "/apidocs").Header().Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeadersValidate(t *testing.T) {
+	require.NoError(t, DefaultSecurityHeaders().Validate())
+	require.NoError(t, SecurityHeaders{}.Validate())
+	require.NoError(t, SecurityHeaders{FrameOptions: "sameorigin"}.Validate())
+	require.EqualError(t, SecurityHeaders{FrameOptions: "ALLOW-FROM https://example.com"}.Validate(), `invalid X-Frame-Options "ALLOW-FROM https://example.com", choices are DENY and SAMEORIGIN`)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/session"
+)
+
+const (
+	// SessionHeaderName is the name of the header carrying the session ID of a request
+	SessionHeaderName = "X-Session-Id"
+
+	// maxIntermediateResponseSize is the size of the response body kept to detect the intermediate requests
+	maxIntermediateResponseSize = 256
+)
+
+// intermediateRequests are the responses of an operation waiting for the user input
+var intermediateRequests = map[string]struct{}{
+	"PinMatrixRequest":  {},
+	"PassPhraseRequest": {},
+	"WordRequest":       {},
+	"ButtonRequest":     {},
+}
+
+// SessionRequest is request data for POST /api/v1/session
+type SessionRequest struct {
+	// CachePassphrase keeps the passphrase cached by the device between the operations of the session
+	CachePassphrase bool `json:"cache_passphrase"`
+}
+
+// SessionResponse is returned by /api/v1/session
+type SessionResponse struct {
+	session.Session
+	// IdleTimeout is how long the session stays open without operations, in seconds
+	IdleTimeout int64 `json:"idle_timeout"`
+}
+
+// sessionHandler opens, reads and closes the session holding the device.
+// While a session is open the device endpoints only serve the requests carrying its ID in the
+// X-Session-Id header. Closing the session, or leaving it idle, locks the device.
+// URI: /api/v1/session
+// Method: POST, GET, DELETE
+// Args: JSON Body for POST
+func sessionHandler(sessions *session.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if r.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var req SessionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer r.Body.Close()
+
+			s, err := sessions.Open(req.CachePassphrase)
+			if err != nil {
+				writeSessionError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: newSessionResponse(sessions, s),
+			})
+		case http.MethodGet:
+			s, err := sessions.Get(r.Header.Get(SessionHeaderName))
+			if err != nil {
+				writeSessionError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: newSessionResponse(sessions, s),
+			})
+		case http.MethodDelete:
+			if err := sessions.Close(r.Header.Get(SessionHeaderName)); err != nil {
+				writeSessionError(w, err)
+				return
+			}
+
+			writeHTTPResponse(w, HTTPResponse{
+				Data: []string{"Session closed"},
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+func newSessionResponse(sessions *session.Manager, s session.Session) SessionResponse {
+	s.Created = s.Created.UTC()
+	s.LastUsed = s.LastUsed.UTC()
+	s.ExpiresAt = s.ExpiresAt.UTC()
+
+	return SessionResponse{
+		Session:     s,
+		IdleTimeout: int64(sessions.Config().IdleTimeout.Seconds()),
+	}
+}
+
+// writeSessionError writes the response of a session error
+func writeSessionError(w http.ResponseWriter, err error) {
+	var resp HTTPResponse
+	switch err {
+	case session.ErrLocked:
+		resp = NewHTTPErrorResponse(http.StatusLocked, err.Error())
+	case session.ErrInvalidSession, session.ErrSessionRequired:
+		resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+	default:
+		resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	writeHTTPResponse(w, resp)
+}
+
+// lockDevice returns a function locking the device, by resetting its session once the device operation in
+// progress is done
+func lockDevice(gateway Gatewayer, deviceLock *devicelock.Lock) func() error {
+	return func() error {
+		return withDeviceLock(deviceLock, OperationKindSession, func() error {
+			msg, err := gateway.Initialize()
+			if err != nil {
+				return err
+			}
+
+			if msg.Kind != uint16(messages.MessageType_MessageType_Features) {
+				if _, err := successMessage(msg); err != nil {
+					return err
+				}
+				return fmt.Errorf("unexpected response to Initialize: %s", messages.MessageType(msg.Kind))
+			}
+
+			return nil
+		})
+	}
+}
+
+// sessionWriter records the status and the beginning of the body written by a handler
+type sessionWriter struct {
+	*statusWriter
+	body []byte
+	// size is the size of the beginning of the body recorded
+	size int
+}
+
+// Write implements http.ResponseWriter
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	if n := w.size - len(w.body); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		w.body = append(w.body, p[:n]...)
+	}
+	return w.statusWriter.Write(p)
+}
+
+// awaitsInput returns true if the response is an intermediate request, the operation then waits for the user input
+func (w *sessionWriter) awaitsInput() bool {
+	return isIntermediateResponse(w.status, w.body)
+}
+
+// isIntermediateResponse returns true if the response of status and body is an intermediate request of the device,
+// such as a ButtonRequest
+func isIntermediateResponse(status int, body []byte) bool {
+	if status != http.StatusOK {
+		return false
+	}
+
+	var rsp struct {
+		Data []string `json:"data"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil || len(rsp.Data) != 1 {
+		return false
+	}
+
+	_, ok := intermediateRequests[rsp.Data[0]]
+	return ok
+}
+
+// operationSession serves a request to a device endpoint if it belongs to the open session, or if no session is open
+func operationSession(sessions *session.Manager, handler http.Handler) http.Handler {
+	if sessions == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		end, err := sessions.Begin(r.Header.Get(SessionHeaderName))
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+
+		sw := &sessionWriter{
+			statusWriter: newStatusWriter(w),
+			size:         maxIntermediateResponseSize,
+		}
+		handler.ServeHTTP(sw, r)
+
+		end(!sw.awaitsInput())
+	})
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/session"
+)
+
+func newTestSessions(gateway Gatewayer, required bool) *session.Manager {
+	return session.NewManager(session.Config{
+		IdleTimeout: time.Hour,
+		Required:    required,
+	}, lockDevice(gateway, nil))
+}
+
+func TestSession(t *testing.T) {
+	featuresMsg := &messages.Features{}
+	featuresMsgBytes, err := featuresMsg.Marshal()
+	require.NoError(t, err)
+	features := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Features),
+		Data: featuresMsgBytes,
+	}
+
+	gateway := &MockGatewayer{}
+	gateway.On("Initialize").Return(features, nil)
+
+	cfg := defaultMuxConfig()
+	cfg.sessions = newTestSessions(gateway, false)
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, body, id string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, "/api/v2/session", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		if id != "" {
+			req.Header.Set(SessionHeaderName, id)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	rr, rsp := do(http.MethodPut, "", "")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusMethodNotAllowed, "").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, "{", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr, rsp = do(http.MethodGet, "", "")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusForbidden, "invalid or expired session").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodPost, `{"cache_passphrase": true}`, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var opened SessionResponse
+	require.NoError(t, json.Unmarshal(rsp.Data, &opened))
+	require.NotEmpty(t, opened.ID)
+	require.True(t, opened.CachePassphrase)
+	require.Equal(t, int64(3600), opened.IdleTimeout)
+	require.Equal(t, opened.LastUsed.Add(time.Hour), opened.ExpiresAt)
+
+	rr, rsp = do(http.MethodPost, `{}`, "")
+	require.Equal(t, http.StatusLocked, rr.Code)
+	require.Equal(t, NewHTTPErrorResponse(http.StatusLocked, "device is locked by another session").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodGet, "", opened.ID)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var got SessionResponse
+	require.NoError(t, json.Unmarshal(rsp.Data, &got))
+	require.Equal(t, opened.ID, got.ID)
+
+	rr, _ = do(http.MethodDelete, "", "foo")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	gateway.AssertNotCalled(t, "Initialize")
+
+	rr, rsp = do(http.MethodDelete, "", opened.ID)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `["Session closed"]`, string(rsp.Data))
+	gateway.AssertNumberOfCalls(t, "Initialize", 1)
+
+	rr, _ = do(http.MethodGet, "", opened.ID)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestSessionLockFailure(t *testing.T) {
+	gateway := &MockGatewayer{}
+	cfg := defaultMuxConfig()
+	cfg.sessions = newTestSessions(gateway, false)
+	handler := newServerMux(cfg, gateway)
+
+	s, err := cfg.sessions.Open(true)
+	require.NoError(t, err)
+
+	gateway.On("Initialize").Return(wire.Message{}, errors.New("device disconnected"))
+
+	req, err := http.NewRequest(http.MethodDelete, "/api/v2/session", nil)
+	require.NoError(t, err)
+	req.Header.Set(SessionHeaderName, s.ID)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	var rsp ReceivedHTTPResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, NewHTTPErrorResponse(http.StatusInternalServerError, "device disconnected").Error, rsp.Error)
+}
+
+func TestOperationSession(t *testing.T) {
+	featuresMsg := &messages.Features{}
+	featuresMsgBytes, err := featuresMsg.Marshal()
+	require.NoError(t, err)
+	features := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Features),
+		Data: featuresMsgBytes,
+	}
+
+	buttonRequest := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ButtonRequest),
+	}
+
+	cases := []struct {
+		name            string
+		required        bool
+		open            bool
+		cachePassphrase bool
+		sessionID       string
+		applySettings   wire.Message
+		status          int
+		httpResponse    HTTPResponse
+		locks           int
+	}{
+		{
+			name:          "no session",
+			applySettings: features,
+			status:        http.StatusOK,
+		},
+		{
+			name:         "403 - session required",
+			required:     true,
+			status:       http.StatusForbidden,
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, "session required"),
+		},
+		{
+			name:         "403 - invalid session",
+			sessionID:    "foo",
+			status:       http.StatusForbidden,
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, "invalid or expired session"),
+		},
+		{
+			name:         "423 - locked by another session",
+			open:         true,
+			status:       http.StatusLocked,
+			httpResponse: NewHTTPErrorResponse(http.StatusLocked, "device is locked by another session"),
+		},
+		{
+			name:            "200 - cached passphrase",
+			required:        true,
+			open:            true,
+			cachePassphrase: true,
+			applySettings:   features,
+			status:          http.StatusOK,
+		},
+		{
+			name:          "200 - completed operation locks the device",
+			open:          true,
+			applySettings: features,
+			status:        http.StatusOK,
+			locks:         1,
+		},
+		{
+			name:          "200 - operation waiting for user input",
+			open:          true,
+			applySettings: buttonRequest,
+			status:        http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: []string{"ButtonRequest"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("ApplySettings", newBoolPtr(true), "", "").Return(tc.applySettings, nil)
+			gateway.On("Initialize").Return(features, nil)
+
+			cfg := defaultMuxConfig()
+			cfg.sessions = newTestSessions(gateway, tc.required)
+
+			sessionID := tc.sessionID
+			if tc.open {
+				s, err := cfg.sessions.Open(tc.cachePassphrase)
+				require.NoError(t, err)
+				if tc.status == http.StatusOK {
+					sessionID = s.ID
+				}
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v2/apply_settings", bytes.NewBufferString(`{"use_passphrase": true}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			if sessionID != "" {
+				req.Header.Set(SessionHeaderName, sessionID)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, rr.Body.String())
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			if tc.httpResponse.Data != nil {
+				require.JSONEq(t, toJSON(t, tc.httpResponse.Data), string(rsp.Data))
+			}
+
+			if tc.status != http.StatusOK {
+				gateway.AssertNotCalled(t, "ApplySettings", newBoolPtr(true), "", "")
+			}
+			gateway.AssertNumberOfCalls(t, "Initialize", tc.locks)
+		})
+	}
+}
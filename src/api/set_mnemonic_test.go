@@ -36,6 +36,7 @@ func TestSetMnemonic(t *testing.T) {
 		httpBody                 string
 		gatewaySetMnemonicResult wire.Message
 		httpResponse             HTTPResponse
+		apiVersion               string
 	}{
 		{
 			name:         "405",
@@ -99,6 +100,32 @@ func TestSetMnemonic(t *testing.T) {
 				Data: []string{"setmnemonic success msg"},
 			},
 		},
+
+		{
+			name:       "422 - invalid bip39 seed v2",
+			method:     http.MethodPost,
+			status:     http.StatusUnprocessableEntity,
+			apiVersion: apiVersion2,
+			httpBody: toJSON(t, &SetMnemonicRequest{
+				Mnemonic: "foo bar foo bar",
+			}),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusUnprocessableEntity, ErrorCategoryInvalidRequest, "seed is not a valid bip39 seed"),
+		},
+
+		{
+			name:       "409 - Failure msg v2",
+			method:     http.MethodPost,
+			status:     http.StatusConflict,
+			apiVersion: apiVersion2,
+			gatewaySetMnemonicResult: wire.Message{
+				Kind: uint16(messages.MessageType_MessageType_Failure),
+				Data: failureMsgBytes,
+			},
+			httpBody: toJSON(t, &SetMnemonicRequest{
+				Mnemonic: "cloud flower upset remain green metal below cup stem infant art thank",
+			}),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
+		},
 	}
 
 	for _, tc := range cases {
@@ -112,7 +139,7 @@ func TestSetMnemonic(t *testing.T) {
 				gateway.On("SetMnemonic", body.Mnemonic).Return(tc.gatewaySetMnemonicResult, nil)
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, apiPath(tc.apiVersion, endpoint), strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
@@ -133,7 +160,7 @@ func TestSetMnemonic(t *testing.T) {
 			err = json.NewDecoder(rr.Body).Decode(&rsp)
 			require.NoError(t, err)
 
-			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			require.Equal(t, expectedError(tc.apiVersion, tc.httpResponse), rsp.Error)
 
 			if rsp.Data == nil {
 				require.Nil(t, tc.httpResponse.Data)
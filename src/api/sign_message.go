@@ -45,15 +45,8 @@ func signMessage(gateway Gatewayer) http.HandlerFunc {
 		}
 		defer r.Body.Close()
 
-		if req.AddressN < 0 {
-			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "address_n cannot be negative")
-			writeHTTPResponse(w, resp)
-			return
-		}
-
-		if req.Message == "" {
-			resp := NewHTTPErrorResponse(http.StatusBadRequest, "message is required")
-			writeHTTPResponse(w, resp)
+		if err := req.validate(); err != nil {
+			writeValidationError(w, err)
 			return
 		}
 
@@ -61,7 +54,7 @@ func signMessage(gateway Gatewayer) http.HandlerFunc {
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("signMessage failed: %s", err.Error())
+				requestLogger(r).Error("signMessage failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -87,7 +80,7 @@ func signMessage(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("signMessage failed: %s", err.Error())
+			requestLogger(r).Errorf("signMessage failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/signwindow"
+)
+
+// signingWindow rejects the signing requests made outside of the signing windows of policy with a 403
+// and a Retry-After header, the time until the next window opens
+func signingWindow(policy *signwindow.Policy, handler http.Handler) http.Handler {
+	if policy == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		if !policy.Open(now) {
+			next := policy.Next(now)
+			requestLogger(r).Warningf("Signing request outside of the signing window, the next window opens at %s", next.Format(time.RFC3339))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(next.Sub(now).Seconds()))))
+			resp := newHTTPErrorResponseCategory(http.StatusForbidden, ErrorCategorySigningWindow, fmt.Sprintf("signing is not allowed outside of the signing window, the next window opens at %s", next.Format(time.RFC3339)))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
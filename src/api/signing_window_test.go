@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/signwindow"
+)
+
+func TestSigningWindow(t *testing.T) {
+	// windows on every day but today and tomorrow are closed now
+	today := time.Now().UTC().Weekday()
+	var days []string
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if d != today && d != (today+1)%7 {
+			days = append(days, d.String())
+		}
+	}
+	closed, err := signwindow.NewPolicy("UTC", []signwindow.Window{{Days: days, Start: "00:00", End: "24:00"}})
+	require.NoError(t, err)
+
+	open, err := signwindow.NewPolicy("UTC", []signwindow.Window{{Start: "00:00", End: "24:00"}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		policy *signwindow.Policy
+		status int
+	}{
+		{"no policy", nil, http.StatusOK},
+		{"open", open, http.StatusOK},
+		{"closed", closed, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		for _, endpoint := range []string{"/api/v2/sign_message", "/api/v2/transaction_sign"} {
+			t.Run(tc.name+endpoint, func(t *testing.T) {
+				cfg := defaultMuxConfig()
+				cfg.signingWindow = tc.policy
+
+				// the signing handler is reached within the window, and rejects the request method
+				handler := newServerMux(cfg, &MockGatewayer{})
+				req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+				require.NoError(t, err)
+
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+
+				if tc.status == http.StatusOK {
+					require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+					require.Empty(t, rr.Header().Get("Retry-After"))
+					return
+				}
+
+				require.Equal(t, tc.status, rr.Code)
+				var rsp ReceivedHTTPResponse
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+				require.True(t, strings.HasPrefix(rsp.Error.Message, "signing is not allowed outside of the signing window, the next window opens at "), rsp.Error.Message)
+
+				retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+				require.NoError(t, err)
+				require.True(t, retryAfter > 0 && retryAfter <= 2*24*60*60, "Retry-After is %d", retryAfter)
+			})
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+	"github.com/skycoin/hardware-wallet-daemon/src/smoketest"
+)
+
+// mutatingEndpoints are the endpoints changing the device or signing with its keys,
+// refused until the startup checks pass if they block the mutating endpoints
+var mutatingEndpoints = map[string]struct{}{
+	"/apply_settings":     {},
+	"/backup":             {},
+	"/configure_pin_code": {},
+	"/firmware_update":    {},
+	"/generate_mnemonic":  {},
+	"/recovery":           {},
+	"/set_mnemonic":       {},
+	"/sign_message":       {},
+	"/transaction_sign":   {},
+	"/wipe":               {},
+
+	"/partial_transaction/sign": {},
+}
+
+// startupCheckDevice is the device the startup checks run against, through the gateway
+type startupCheckDevice struct {
+	gateway    Gatewayer
+	mode       func() skyWallet.DeviceType
+	deviceLock *devicelock.Lock
+}
+
+// NewStartupCheckDevice returns the device of the gateway for the startup checks, in the current mode.
+// The features are read holding deviceLock, nil reads them right away.
+func NewStartupCheckDevice(gateway Gatewayer, mode func() skyWallet.DeviceType, deviceLock *devicelock.Lock) smoketest.Device {
+	return startupCheckDevice{
+		gateway:    gateway,
+		mode:       mode,
+		deviceLock: deviceLock,
+	}
+}
+
+// readFeatures reads the features of the device, once the device operation in progress is done
+func (d startupCheckDevice) readFeatures() (*messages.Features, error) {
+	var features *messages.Features
+	err := withDeviceLock(d.deviceLock, OperationKindStartupChecks, func() error {
+		var err error
+		features, err = readFeatures(d.gateway)
+		return err
+	})
+	return features, err
+}
+
+// Present enumerates the USB devices, the emulator is only found by reading its features
+func (d startupCheckDevice) Present() (bool, error) {
+	if d.mode() == skyWallet.DeviceTypeUSB {
+		check := checkTransport(d.gateway, skyWallet.DeviceTypeUSB)
+		return check.device == DeviceConnected, check.err
+	}
+
+	if _, err := d.readFeatures(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FirmwareVersion reads the firmware version from the features of the device
+func (d startupCheckDevice) FirmwareVersion() (firmware.Version, bool, error) {
+	features, err := d.readFeatures()
+	if err != nil {
+		return firmware.Version{}, false, err
+	}
+
+	v, ok := firmwareVersion(features)
+	return v, ok, nil
+}
+
+// startupChecksGate refuses the requests to the mutating endpoints with a 503 until the startup checks pass
+func startupChecksGate(suite *smoketest.Suite, endpoint string, handler http.Handler) http.Handler {
+	if suite == nil {
+		return handler
+	}
+	if _, ok := mutatingEndpoints[endpoint]; !ok {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if suite.Blocking() {
+			var failed []string
+			for _, result := range suite.Status().Results {
+				if !result.Passed {
+					failed = append(failed, result.Name)
+				}
+			}
+
+			msg := "the startup checks have not run yet"
+			if len(failed) > 0 {
+				msg = fmt.Sprintf("the startup checks have not passed: %s", strings.Join(failed, ", "))
+			}
+
+			resp := newHTTPErrorResponseCategory(http.StatusServiceUnavailable, ErrorCategoryStartupChecks, msg)
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
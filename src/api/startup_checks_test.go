@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+	"github.com/skycoin/hardware-wallet-daemon/src/smoketest"
+)
+
+func TestStartupChecks(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("Enumerate").Return([]usb.Info{}, nil).Once()
+	gateway.On("Enumerate").Return([]usb.Info{{Path: "1-1.2:1.0"}}, nil)
+
+	suite := smoketest.NewSuite(smoketest.Config{
+		Checks:        []smoketest.Check{{Name: "device", Type: smoketest.CheckDevicePresent}},
+		BlockMutating: true,
+	}, NewStartupCheckDevice(gateway, func() skyWallet.DeviceType {
+		return skyWallet.DeviceTypeUSB
+	}, nil))
+
+	cfg := defaultMuxConfig()
+	cfg.startupChecks = suite
+	handler := newServerMux(cfg, gateway)
+
+	do := func(method, endpoint string) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(method, endpoint, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	rr, rsp := do(http.MethodDelete, "/api/v2/wipe")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, newHTTPErrorResponseCategory(http.StatusServiceUnavailable, ErrorCategoryStartupChecks, "the startup checks have not run yet").Error, rsp.Error)
+
+	// the checks fail while the device is not plugged in
+	require.False(t, suite.RunOnce())
+
+	rr, rsp = do(http.MethodGet, "/api/v2/health")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var health HealthResponse
+	require.NoError(t, json.Unmarshal(rsp.Data, &health))
+	require.Equal(t, HealthStatusDegraded, health.Status)
+	require.NotNil(t, health.StartupChecks)
+	require.True(t, health.StartupChecks.Blocking)
+	require.Equal(t, []smoketest.Result{
+		{Name: "device", Type: smoketest.CheckDevicePresent, Error: "device not found"},
+	}, health.StartupChecks.Results)
+
+	rr, rsp = do(http.MethodGet, "/ready")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, newHTTPErrorResponseCategory(http.StatusServiceUnavailable, ErrorCategoryStartupChecks, "the startup checks have not passed").Error, rsp.Error)
+
+	rr, rsp = do(http.MethodDelete, "/api/v2/wipe")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, newHTTPErrorResponseCategory(http.StatusServiceUnavailable, ErrorCategoryStartupChecks, "the startup checks have not passed: device").Error, rsp.Error)
+
+	// the endpoints not changing the device are served
+	rr, _ = do(http.MethodGet, "/api/v2/check_message_signature")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	// the mutating endpoints are served once the checks pass
+	require.True(t, suite.RunOnce())
+
+	_, rsp = do(http.MethodGet, "/api/v2/health")
+	require.NoError(t, json.Unmarshal(rsp.Data, &health))
+	require.Equal(t, HealthStatusOK, health.Status)
+	require.True(t, health.StartupChecks.Passed)
+
+	rr, _ = do(http.MethodGet, "/ready")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr, _ = do(http.MethodDelete, "/api/v2/wipe")
+	require.Equal(t, http.StatusAccepted, rr.Code)
+}
+
+func TestStartupCheckDevice(t *testing.T) {
+	gateway := &MockGatewayer{}
+	gateway.On("GetFeatures").Return(newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		FwMajor: proto.Uint32(1),
+		FwMinor: proto.Uint32(7),
+		FwPatch: proto.Uint32(2),
+	}), nil).Twice()
+	gateway.On("GetFeatures").Return(newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		BootloaderMode: proto.Bool(true),
+	}), nil)
+
+	// the emulator is found by reading its features
+	device := NewStartupCheckDevice(gateway, func() skyWallet.DeviceType {
+		return skyWallet.DeviceTypeEmulator
+	}, nil)
+	present, err := device.Present()
+	require.NoError(t, err)
+	require.True(t, present)
+
+	v, ok, err := device.FirmwareVersion()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, firmware.Version{Major: 1, Minor: 7, Patch: 2}, v)
+
+	_, ok, err = device.FirmwareVersion()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
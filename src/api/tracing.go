@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/tracing"
+)
+
+// requestTracing traces the requests to endpoint in an HTTP span
+func requestTracing(tracer *tracing.Tracer, endpoint string, handler http.Handler) http.Handler {
+	if tracer == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), r.Method+" "+endpoint,
+			tracing.String("http.method", r.Method),
+			tracing.String("http.route", endpoint))
+		defer span.End()
+
+		if id := requestIDFromRequest(r); id != "" {
+			span.SetAttributes(tracing.String("request.id", id))
+		}
+
+		sw := newStatusWriter(w)
+		handler.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(tracing.Int("http.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.RecordError(errors.New(http.StatusText(sw.status)))
+		}
+	})
+}
+
+// operationTracing makes the HTTP span of the device operations the parent of the spans of their device messages
+func operationTracing(tracer *tracing.Tracer, handler http.Handler) http.Handler {
+	if tracer == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		end := tracer.Operation(r.Context())
+		defer end()
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/tracing"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+type recordingProvider struct {
+	spans []*recordedSpan
+}
+
+func (p *recordingProvider) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	span := &recordedSpan{
+		name:  name,
+		attrs: make(map[string]interface{}),
+	}
+	span.SetAttributes(attrs...)
+	p.spans = append(p.spans, span)
+	return ctx, span
+}
+
+func (p *recordingProvider) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestRequestTracing(t *testing.T) {
+	provider := &recordingProvider{}
+
+	cfg := defaultMuxConfig()
+	cfg.requestID = true
+	cfg.tracer = tracing.NewTracer(provider)
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/features", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeaderName, "flow-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+
+	req, err = http.NewRequest(http.MethodGet, "/api/v2/version", nil)
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	require.Len(t, provider.spans, 2)
+
+	span := provider.spans[0]
+	require.Equal(t, "POST /api/v2/features", span.name)
+	require.Equal(t, map[string]interface{}{
+		"http.method":      http.MethodPost,
+		"http.route":       "/api/v2/features",
+		"http.status_code": http.StatusMethodNotAllowed,
+		"request.id":       "flow-42",
+	}, span.attrs)
+	require.NoError(t, span.err)
+	require.True(t, span.ended)
+
+	span = provider.spans[1]
+	require.Equal(t, "GET /api/v2/version", span.name)
+	require.Equal(t, http.StatusOK, span.attrs["http.status_code"])
+	require.True(t, span.ended)
+}
+
+func TestRequestTracingError(t *testing.T) {
+	provider := &recordingProvider{}
+	handler := requestTracing(tracing.NewTracer(provider), "/api/v2/features", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHTTPResponse(w, NewHTTPErrorResponse(http.StatusInternalServerError, "device failure"))
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v2/features", nil)
+	require.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, provider.spans, 1)
+	require.EqualError(t, provider.spans[0].err, "Internal Server Error")
+	require.NotContains(t, provider.spans[0].attrs, "request.id")
+}
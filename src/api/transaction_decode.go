@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+)
+
+// TransactionDecodeRequest is request data for POST /api/v1/transaction_decode
+type TransactionDecodeRequest struct {
+	// RawTransaction is the serialization of the transaction, hex encoded
+	RawTransaction string `json:"raw_transaction"`
+}
+
+// DecodedInput is an input of a decoded transaction
+type DecodedInput struct {
+	Hash string `json:"hash"`
+	// Address is the address which signed the input, recovered from its signature, empty if it is not signed
+	Address   string `json:"address,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// TransactionDecodeResponse is data returned by POST /api/v1/transaction_decode
+type TransactionDecodeResponse struct {
+	// TxID is the ID of the transaction once it is fully signed, empty if it is not
+	TxID      string `json:"txid,omitempty"`
+	InnerHash string `json:"inner_hash"`
+	// Signed is the number of signed inputs
+	Signed             int                 `json:"signed"`
+	TransactionInputs  []DecodedInput      `json:"transaction_inputs"`
+	TransactionOutputs []TransactionOutput `json:"transaction_outputs"`
+}
+
+// URI: /api/v1/transaction_decode
+// Method: POST
+// Args: JSON Body
+func transactionDecode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TransactionDecodeRequest
+		if !decodeJSONRequest(w, r, &req) {
+			return
+		}
+
+		if req.RawTransaction == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "raw_transaction is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		data, err := hex.DecodeString(req.RawTransaction)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid raw_transaction: "+err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txn, err := partialtx.Deserialize(data)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rsp := TransactionDecodeResponse{
+			InnerHash:          txn.InnerHash().Hex(),
+			Signed:             txn.Signed(),
+			TransactionInputs:  make([]DecodedInput, 0, len(txn.Inputs)),
+			TransactionOutputs: make([]TransactionOutput, 0, len(txn.Outputs)),
+		}
+		if txn.Complete() {
+			hash, err := txn.Hash()
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			rsp.TxID = hash.Hex()
+		}
+
+		for _, in := range txn.Inputs {
+			input := DecodedInput{
+				Hash: in.Hash.Hex(),
+			}
+			if in.Signed() {
+				input.Address = in.Address.String()
+				input.Signature = in.Signature.Hex()
+			}
+			rsp.TransactionInputs = append(rsp.TransactionInputs, input)
+		}
+
+		for _, o := range txn.Outputs {
+			coins, err := amount.FormatCoins(o.Coins)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			rsp.TransactionOutputs = append(rsp.TransactionOutputs, TransactionOutput{
+				Address: o.Address.String(),
+				Coins:   coins,
+				Hours:   strconv.FormatUint(o.Hours, 10),
+			})
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
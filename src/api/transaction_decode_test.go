@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/partialtx"
+)
+
+func TestTransactionDecode(t *testing.T) {
+	pubKey, secKey := cipher.MustGenerateDeterministicKeyPair([]byte("alice"))
+	alice := cipher.AddressFromPubKey(pubKey)
+	to := cipher.MustDecodeBase58Address("2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG")
+
+	txn, err := partialtx.New([]partialtx.Input{
+		{Hash: cipher.MustSHA256FromHex("c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"), Address: alice},
+	}, []partialtx.Output{
+		{Address: to, Coins: 12500000, Hours: 10},
+		{Address: alice, Coins: 500000, Hours: 2},
+	})
+	require.NoError(t, err)
+	require.NoError(t, txn.Sign(0, cipher.MustSignHash(txn.SignatureHash(0), secKey)))
+
+	data, err := txn.Serialize()
+	require.NoError(t, err)
+	txid, err := txn.Hash()
+	require.NoError(t, err)
+
+	handler := newServerMux(defaultMuxConfig(), &MockGatewayer{})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodPost, "/api/v2/transaction_decode", strings.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	decode := func(raw string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(TransactionDecodeRequest{
+			RawTransaction: raw,
+		})
+		require.NoError(t, err)
+		return post(string(body))
+	}
+
+	rr := decode(hex.EncodeToString(data))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var decoded TransactionDecodeResponse
+	rsp := HTTPResponse{
+		Data: &decoded,
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Equal(t, TransactionDecodeResponse{
+		TxID:      txid.Hex(),
+		InnerHash: txn.InnerHash().Hex(),
+		Signed:    1,
+		TransactionInputs: []DecodedInput{
+			{Hash: txn.Inputs[0].Hash.Hex(), Address: alice.String(), Signature: txn.Inputs[0].Signature.Hex()},
+		},
+		TransactionOutputs: []TransactionOutput{
+			{Address: to.String(), Coins: "12.500000", Hours: "10"},
+			{Address: alice.String(), Coins: "0.500000", Hours: "2"},
+		},
+	}, decoded)
+
+	// an unsigned transaction has null signatures, its inputs have no address
+	unsigned := append([]byte(nil), data...)
+	sig := 4 + 1 + len(cipher.SHA256{}) + 4
+	copy(unsigned[sig:sig+len(cipher.Sig{})], make([]byte, len(cipher.Sig{})))
+	rr = decode(hex.EncodeToString(unsigned))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	decoded = TransactionDecodeResponse{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+	require.Empty(t, decoded.TxID)
+	require.Equal(t, 0, decoded.Signed)
+	require.Equal(t, []DecodedInput{{Hash: txn.Inputs[0].Hash.Hex()}}, decoded.TransactionInputs)
+
+	rr = decode("")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = decode("zz")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = decode(hex.EncodeToString(data[:len(data)-1]))
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = post("{")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
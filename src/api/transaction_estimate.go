@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/addressbook"
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/fee"
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+)
+
+// TransactionEstimateRequest is request data for POST /api/v1/transaction_estimate
+type TransactionEstimateRequest struct {
+	TransactionInputs  []EstimateInput     `json:"transaction_inputs"`
+	TransactionOutputs []TransactionOutput `json:"transaction_outputs"`
+}
+
+// EstimateInput is an input of an estimated transaction. Its coins and hours are looked up from the node when they
+// are not set.
+type EstimateInput struct {
+	Hash  string `json:"hash"`
+	Coins string `json:"coins,omitempty"`
+	// Hours are the coin hours of the output at the head block
+	Hours string `json:"hours,omitempty"`
+}
+
+// TransactionEstimateResponse is data returned by POST /api/v1/transaction_estimate
+type TransactionEstimateResponse struct {
+	fee.Estimate
+	// Valid reports whether the network would accept the transaction
+	Valid bool `json:"valid"`
+	// TransactionOutputs are the outputs of the request with their hours, once the remaining hours are distributed
+	TransactionOutputs []TransactionOutput `json:"transaction_outputs"`
+}
+
+// URI: /api/v1/transaction_estimate
+// Method: POST
+// Args: JSON Body, the transaction of /api/v1/transaction_sign, the hours of an output being optional
+func transactionEstimate(client *node.Client, book *addressbook.Book) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req TransactionEstimateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if !resolveContacts(w, r, book, req.TransactionOutputs) {
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		outputs, err := req.outputs()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		inputs, lookup, err := req.inputs()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(lookup) > 0 && !lookupInputs(w, r, client, inputs, lookup) {
+			return
+		}
+
+		e := fee.Compute(inputs, outputs)
+		rsp := TransactionEstimateResponse{
+			Estimate:           e,
+			Valid:              e.Valid(),
+			TransactionOutputs: req.TransactionOutputs,
+		}
+		for i := range rsp.TransactionOutputs {
+			rsp.TransactionOutputs[i].Hours = strconv.FormatUint(e.Hours[i], 10)
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
+
+func (r *TransactionEstimateRequest) validate() error {
+	if len(r.TransactionInputs) == 0 {
+		return errors.New("inputs are required")
+	}
+
+	for _, input := range r.TransactionInputs {
+		if input.Hash == "" {
+			return errors.New("input hash cannot be empty")
+		}
+
+		if (input.Coins == "") != (input.Hours == "") {
+			return errors.New("input coins and hours must both be set, or both be looked up from the node")
+		}
+	}
+
+	if len(r.TransactionOutputs) == 0 {
+		return errors.New("outputs are required")
+	}
+
+	for _, output := range r.TransactionOutputs {
+		if output.Address == "" {
+			return errors.New("address cannot be empty")
+		}
+
+		if output.Coins == "" {
+			return errors.New("coins cannot be empty")
+		}
+	}
+
+	return nil
+}
+
+// outputs returns the outputs of the request, the outputs without hours are sent the remaining hours
+func (r *TransactionEstimateRequest) outputs() ([]fee.Output, error) {
+	outputs := make([]fee.Output, 0, len(r.TransactionOutputs))
+	for _, output := range r.TransactionOutputs {
+		if _, err := cipher.DecodeBase58Address(output.Address); err != nil {
+			return nil, err
+		}
+
+		coins, err := amount.ParseCoins(output.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		o := fee.Output{
+			Address: output.Address,
+			Coins:   coins,
+		}
+		if output.Hours != "" {
+			hours, err := amount.ParseHours(output.Hours)
+			if err != nil {
+				return nil, err
+			}
+			o.Hours = &hours
+		}
+
+		outputs = append(outputs, o)
+	}
+
+	return outputs, nil
+}
+
+// inputs returns the inputs of the request, with the indexes of the inputs to look up from the node
+func (r *TransactionEstimateRequest) inputs() ([]fee.Input, []int, error) {
+	inputs := make([]fee.Input, 0, len(r.TransactionInputs))
+	var lookup []int
+	for i, input := range r.TransactionInputs {
+		in := fee.Input{
+			Hash: input.Hash,
+		}
+
+		if input.Coins == "" {
+			lookup = append(lookup, i)
+		} else {
+			var err error
+			in.Coins, err = amount.ParseCoins(input.Coins)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			in.Hours, err = amount.ParseHours(input.Hours)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		inputs = append(inputs, in)
+	}
+
+	return inputs, lookup, nil
+}
+
+// lookupInputs sets the coins and hours of the inputs at the indexes of lookup from the unspent outputs of the node,
+// writing the error response if they cannot be looked up
+func lookupInputs(w http.ResponseWriter, r *http.Request, client *node.Client, inputs []fee.Input, lookup []int) bool {
+	if client == nil {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, "input coins and hours are required, no node is configured to look them up")
+		writeHTTPResponse(w, resp)
+		return false
+	}
+
+	hashes := make([]string, 0, len(lookup))
+	for _, i := range lookup {
+		hashes = append(hashes, inputs[i].Hash)
+	}
+
+	outputs, err := client.UnspentOutputs(hashes)
+	if err != nil {
+		writeNodeError(w, r, err)
+		return false
+	}
+
+	unspent := make(map[string]node.UnspentOutput, len(outputs))
+	for _, o := range outputs {
+		unspent[o.Hash] = o
+	}
+
+	for _, i := range lookup {
+		o, ok := unspent[inputs[i].Hash]
+		if !ok {
+			resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, fmt.Sprintf("input %s is not an unspent output", inputs[i].Hash))
+			writeHTTPResponse(w, resp)
+			return false
+		}
+
+		coins, err := amount.ParseCoins(o.Coins)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadGateway, fmt.Sprintf("invalid coins of output %s returned by the node: %v", o.Hash, err))
+			writeHTTPResponse(w, resp)
+			return false
+		}
+
+		inputs[i].Coins = coins
+		inputs[i].Hours = o.CalculatedHours
+	}
+
+	return true
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/fee"
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+)
+
+func TestTransactionEstimate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/outputs", r.URL.Path)
+		require.Equal(t, "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663", r.URL.Query().Get("hashes"))
+		w.Write([]byte(`{"head_outputs": [{
+			"hash": "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663",
+			"coins": "3.000000",
+			"hours": 80,
+			"calculated_hours": 101
+		}]}`))
+	}))
+	defer server.Close()
+
+	client, err := node.New(node.Config{
+		URL: server.URL,
+	})
+	require.NoError(t, err)
+
+	do := func(cfg muxConfig, req TransactionEstimateRequest) *httptest.ResponseRecorder {
+		data, err := json.Marshal(req)
+		require.NoError(t, err)
+		r, err := http.NewRequest(http.MethodPost, "/api/v2/transaction_estimate", bytes.NewReader(data))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		newServerMux(cfg, &MockGatewayer{}).ServeHTTP(rr, r)
+		return rr
+	}
+
+	estimate := func(cfg muxConfig, req TransactionEstimateRequest) TransactionEstimateResponse {
+		rr := do(cfg, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var rsp struct {
+			Data TransactionEstimateResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rsp.Data
+	}
+
+	outputs := []TransactionOutput{
+		{Address: "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", Coins: "1"},
+		{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "2", AddressIndex: newUint32Ptr(1)},
+	}
+
+	cfg := defaultMuxConfig()
+	rsp := estimate(cfg, TransactionEstimateRequest{
+		TransactionInputs: []EstimateInput{
+			{Hash: "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663", Coins: "3", Hours: "101"},
+		},
+		TransactionOutputs: outputs,
+	})
+	require.True(t, rsp.Valid)
+	require.Equal(t, uint64(11), rsp.Fee)
+	require.Equal(t, "30", rsp.TransactionOutputs[0].Hours)
+	require.Equal(t, "60", rsp.TransactionOutputs[1].Hours)
+	require.Equal(t, uint32(1), *rsp.TransactionOutputs[1].AddressIndex)
+
+	// the hours of the inputs are looked up from the node
+	cfg.node = client
+	rsp = estimate(cfg, TransactionEstimateRequest{
+		TransactionInputs: []EstimateInput{
+			{Hash: "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+		},
+		TransactionOutputs: []TransactionOutput{
+			{Address: "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", Coins: "1.0001", Hours: "100"},
+			{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "1.9999"},
+		},
+	})
+	require.False(t, rsp.Valid)
+	require.Equal(t, uint64(101), rsp.InputHours)
+	codes := []string{}
+	for _, p := range rsp.Problems {
+		codes = append(codes, p.Code)
+	}
+	require.Equal(t, []string{fee.ProblemDust, fee.ProblemDust, fee.ProblemInsufficientHours}, codes)
+
+	// without a node the inputs carry their coins and hours
+	rr := do(defaultMuxConfig(), TransactionEstimateRequest{
+		TransactionInputs: []EstimateInput{
+			{Hash: "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+		},
+		TransactionOutputs: outputs,
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do(defaultMuxConfig(), TransactionEstimateRequest{
+		TransactionInputs: []EstimateInput{
+			{Hash: "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663", Coins: "3"},
+		},
+		TransactionOutputs: outputs,
+	})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
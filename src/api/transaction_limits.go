@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// DefaultMaxTransactionInputs is how many inputs the firmware signs in a transaction
+	DefaultMaxTransactionInputs = 8
+	// DefaultMaxTransactionOutputs is how many outputs the firmware signs in a transaction
+	DefaultMaxTransactionOutputs = 8
+	// DefaultMaxTransactionRequestSize is the largest body of a signing request by default
+	DefaultMaxTransactionRequestSize = 64 * 1024
+)
+
+// TransactionLimits bound the transactions sent to the device for signing, so a transaction the firmware cannot sign
+// is refused before the device flow starts. A limit of 0 leaves it unbounded.
+type TransactionLimits struct {
+	// MaxInputs is how many inputs a transaction has at most
+	MaxInputs int
+	// MaxOutputs is how many outputs a transaction has at most
+	MaxOutputs int
+	// MaxRequestSize is the largest body of a signing request, in bytes
+	MaxRequestSize int64
+}
+
+// DefaultTransactionLimits returns the limits of the firmware
+func DefaultTransactionLimits() TransactionLimits {
+	return TransactionLimits{
+		MaxInputs:      DefaultMaxTransactionInputs,
+		MaxOutputs:     DefaultMaxTransactionOutputs,
+		MaxRequestSize: DefaultMaxTransactionRequestSize,
+	}
+}
+
+// Validate checks that the limits are not negative
+func (l TransactionLimits) Validate() error {
+	if l.MaxInputs < 0 {
+		return errors.New("max transaction inputs must not be negative")
+	}
+	if l.MaxOutputs < 0 {
+		return errors.New("max transaction outputs must not be negative")
+	}
+	if l.MaxRequestSize < 0 {
+		return errors.New("max transaction size must not be negative")
+	}
+	return nil
+}
+
+// check returns an error if the transaction of inputs and outputs exceeds the limits
+func (l TransactionLimits) check(inputs, outputs int) error {
+	if l.MaxInputs > 0 && inputs > l.MaxInputs {
+		return fmt.Errorf("the transaction has %d inputs, at most %d are signed", inputs, l.MaxInputs)
+	}
+	if l.MaxOutputs > 0 && outputs > l.MaxOutputs {
+		return fmt.Errorf("the transaction has %d outputs, at most %d are signed", outputs, l.MaxOutputs)
+	}
+	return nil
+}
+
+// checkTransactionLimits writes a 422 response and returns false if the transaction exceeds the limits
+func checkTransactionLimits(w http.ResponseWriter, limits TransactionLimits, inputs, outputs int) bool {
+	if err := limits.check(inputs, outputs); err != nil {
+		resp := newHTTPErrorResponseCategory(http.StatusUnprocessableEntity, ErrorCategoryTransactionTooLarge, err.Error())
+		writeHTTPResponse(w, resp)
+		return false
+	}
+	return true
+}
+
+// transactionRequestSize refuses the signing requests whose body is larger than the limit with a 413
+func transactionRequestSize(limits TransactionLimits, handler http.Handler) http.Handler {
+	if limits.MaxRequestSize == 0 {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tooLarge := func() {
+			msg := fmt.Sprintf("the request body is larger than %d bytes", limits.MaxRequestSize)
+			resp := newHTTPErrorResponseCategory(http.StatusRequestEntityTooLarge, ErrorCategoryTransactionTooLarge, msg)
+			writeHTTPResponse(w, resp)
+		}
+
+		if r.ContentLength > limits.MaxRequestSize {
+			tooLarge()
+			return
+		}
+
+		// the body is read before the handler, whose decoding errors are answered with a 400
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, limits.MaxRequestSize+1))
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		if int64(len(body)) > limits.MaxRequestSize {
+			tooLarge()
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionLimits(t *testing.T) {
+	signResponse := messages.ResponseTransactionSign{
+		Signatures: []string{"signature"},
+		Padding:    newBoolPtr(false),
+	}
+	signResponseBytes, err := signResponse.Marshal()
+	require.NoError(t, err)
+
+	gateway := &MockGatewayer{}
+	gateway.On("TransactionSign", mock.Anything, mock.Anything).Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ResponseTransactionSign),
+		Data: signResponseBytes,
+	}, nil)
+
+	cfg := defaultMuxConfig()
+	cfg.transactionLimits = TransactionLimits{
+		MaxInputs:      2,
+		MaxOutputs:     1,
+		MaxRequestSize: 1024,
+	}
+	handler := newServerMux(cfg, gateway)
+
+	post := func(body []byte) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/transaction_sign", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	sign := func(inputs, outputs int) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		var req TransactionSignRequest
+		for i := 0; i < inputs; i++ {
+			req.TransactionInputs = append(req.TransactionInputs, TransactionInput{newUint32Ptr(0), "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"})
+		}
+		for i := 0; i < outputs; i++ {
+			req.TransactionOutputs = append(req.TransactionOutputs, TransactionOutput{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: "1", Hours: "2"})
+		}
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		return post(body)
+	}
+
+	rr, _ := sign(2, 1)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr, rsp := sign(3, 1)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.Equal(t, newHTTPErrorResponseCategory(http.StatusUnprocessableEntity, ErrorCategoryTransactionTooLarge, "the transaction has 3 inputs, at most 2 are signed").Error, rsp.Error)
+
+	rr, rsp = sign(1, 2)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.Equal(t, ErrorCategoryTransactionTooLarge, rsp.Error.Category)
+
+	rr, rsp = post([]byte(`{"transaction_inputs":[],"padding":"` + strings.Repeat("x", 1024) + `"}`))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	require.Equal(t, newHTTPErrorResponseCategory(http.StatusRequestEntityTooLarge, ErrorCategoryTransactionTooLarge, "the request body is larger than 1024 bytes").Error, rsp.Error)
+
+	// the device is not prompted with the transactions refused
+	gateway.AssertNumberOfCalls(t, "TransactionSign", 1)
+}
+
+func TestTransactionLimitsValidate(t *testing.T) {
+	require.NoError(t, DefaultTransactionLimits().Validate())
+	require.NoError(t, TransactionLimits{}.Validate())
+
+	require.EqualError(t, TransactionLimits{MaxInputs: -1}.Validate(), "max transaction inputs must not be negative")
+	require.EqualError(t, TransactionLimits{MaxOutputs: -1}.Validate(), "max transaction outputs must not be negative")
+	require.EqualError(t, TransactionLimits{MaxRequestSize: -1}.Validate(), "max transaction size must not be negative")
+
+	// the zero limits leave the transactions unbounded
+	require.NoError(t, TransactionLimits{}.check(100, 100))
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+// checkTransactionPolicy evaluates the transaction sending outputs with policy, writing a 403 if the policy rejects it.
+// With authorize, the coins of the allowed transaction are recorded as spent, it is about to be forwarded to the device.
+func checkTransactionPolicy(w http.ResponseWriter, r *http.Request, policy *txpolicy.Engine, outputs []*messages.SkycoinTransactionOutput, authorize bool) bool {
+	// the change outputs to the addresses of the device are not spent
+	var destinations []txpolicy.Output
+	for _, o := range outputs {
+		if o.AddressIndex != nil {
+			continue
+		}
+		destinations = append(destinations, txpolicy.Output{
+			Address: o.GetAddress(),
+			Coins:   o.GetCoin(),
+		})
+	}
+
+	now := time.Now()
+	var err error
+	if authorize {
+		err = policy.Authorize(destinations, now)
+	} else {
+		err = policy.Check(destinations, now)
+	}
+
+	switch v := err.(type) {
+	case nil:
+		return true
+	case *txpolicy.Violation:
+		requestLogger(r).Warningf("Transaction rejected by the %s rule of the policy", v.Rule)
+		if !v.RetryAt.IsZero() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(v.RetryAt.Sub(now).Seconds()))))
+		}
+		resp := newHTTPErrorResponseCategory(http.StatusForbidden, ErrorCategoryPolicyRejected, v.Error())
+		writeHTTPResponse(w, resp)
+	default:
+		requestLogger(r).Errorf("transaction policy failed: %s", err.Error())
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+	}
+	return false
+}
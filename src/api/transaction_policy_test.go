@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+func TestTransactionSignPolicy(t *testing.T) {
+	signResponse := messages.ResponseTransactionSign{
+		Signatures: []string{"signature"},
+		Padding:    newBoolPtr(false),
+	}
+	signResponseBytes, err := signResponse.Marshal()
+	require.NoError(t, err)
+
+	gateway := &MockGatewayer{}
+	gateway.On("TransactionSign", mock.Anything, mock.Anything).Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_ResponseTransactionSign),
+		Data: signResponseBytes,
+	}, nil)
+
+	policy, err := txpolicy.NewPolicy(txpolicy.Policy{
+		DailyLimit: "10",
+		Denylist:   []string{"2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"},
+	})
+	require.NoError(t, err)
+
+	cfg := defaultMuxConfig()
+	cfg.transactionPolicy = txpolicy.NewEngine(policy, storage.NewMemoryStore())
+	cfg.approvals = approval.NewManager(0)
+	cfg.approvalThreshold = 5e6
+	handler := newServerMux(cfg, gateway)
+
+	sign := func(coins, address string, change bool) (*httptest.ResponseRecorder, ReceivedHTTPResponse) {
+		output := TransactionOutput{Address: address, Coins: coins, Hours: "2"}
+		if change {
+			output.AddressIndex = newUint32Ptr(0)
+		}
+		body, err := json.Marshal(TransactionSignRequest{
+			TransactionInputs: []TransactionInput{
+				{newUint32Ptr(0), "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"},
+			},
+			TransactionOutputs: []TransactionOutput{output},
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v2/transaction_sign", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		return rr, rsp
+	}
+
+	rr, _ := sign("4", "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", false)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// the change outputs are neither limited nor denied
+	rr, _ = sign("1000", "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", true)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr, rsp := sign("1", "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", false)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, newHTTPErrorResponseCategory(http.StatusForbidden, ErrorCategoryPolicyRejected, "transaction rejected by the policy, the address 2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8 is denied").Error, rsp.Error)
+	require.Empty(t, rr.Header().Get("Retry-After"))
+
+	// the transactions pending approval are not spent yet
+	rr, _ = sign("6", "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", false)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	rr, _ = sign("6", "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", false)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+
+	// the limit is checked before the approval
+	rr, rsp = sign("7", "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", false)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, ErrorCategoryPolicyRejected, rsp.Error.Category)
+	retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.True(t, retryAfter > 0 && retryAfter <= 24*3600, retryAfter)
+
+	gateway.AssertNumberOfCalls(t, "TransactionSign", 2)
+}
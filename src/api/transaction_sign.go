@@ -2,9 +2,8 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
+	"math"
 	"net/http"
-	"strconv"
 
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
@@ -12,7 +11,12 @@ import (
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 	messages "github.com/skycoin/hardware-wallet-protob/go"
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/util/droplet"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/addressbook"
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
 )
 
 // TransactionSignRequest is request data for /api/v1/transaction_sign
@@ -33,6 +37,8 @@ type TransactionOutput struct {
 	Address      string  `json:"address"`
 	Coins        string  `json:"coins"`
 	Hours        string  `json:"hours"`
+	// Contact is the name of a contact of the address book, resolved to its address instead of Address
+	Contact string `json:"contact,omitempty"`
 }
 
 // TransactionSignResponse is data returned by POST /api/v1/transaction_sign
@@ -40,10 +46,12 @@ type TransactionSignResponse struct {
 	Signatures *[]string `json:"signatures"`
 }
 
+const transactionSignAction = "transaction_sign"
+
 // URI: /api/v1/transactionSign
 // Method: POST
-// Args: JSON Body
-func transactionSign(gateway Gatewayer) http.HandlerFunc {
+// Args: JSON Body, approval_id of the approval of a transaction spending more than the approval threshold [optional]
+func transactionSign(gateway Gatewayer, approvals *approval.Manager, approvalThreshold uint64, policy *txpolicy.Engine, book *addressbook.Book, limits TransactionLimits, backend coin.Backend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -64,10 +72,14 @@ func transactionSign(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
-		if err := req.validate(); err != nil {
-			logger.WithError(err).Error("invalid sign transaction request")
-			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
-			writeHTTPResponse(w, resp)
+		// the approvals and the policy see the addresses of the contacts
+		if !resolveContacts(w, r, book, req.TransactionOutputs) {
+			return
+		}
+
+		if err := req.validate(backend); err != nil {
+			requestLogger(r).WithError(err).Error("invalid sign transaction request")
+			writeValidationError(w, err)
 			return
 		}
 
@@ -78,11 +90,33 @@ func transactionSign(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
+		// the transactions the firmware cannot sign are refused before the device flow starts
+		if !checkTransactionLimits(w, limits, len(txnInputs), len(txnOutputs)) {
+			return
+		}
+
+		// the transactions rejected by the policy are not sent for approval
+		if policy != nil && !checkTransactionPolicy(w, r, policy, txnOutputs, false) {
+			return
+		}
+
+		// the device is only prompted once the transactions spending more than the threshold are approved
+		if approvals != nil && spentCoins(txnOutputs) > approvalThreshold {
+			if !useApproval(w, r, approvals, transactionSignAction, req) {
+				return
+			}
+		}
+
+		// the policy is evaluated again, another transaction may have been signed during the approval
+		if policy != nil && !checkTransactionPolicy(w, r, policy, txnOutputs, true) {
+			return
+		}
+
 		// for integration tests
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("transactionSign failed: %s", err.Error())
+				requestLogger(r).Error("transactionSign failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -107,7 +141,7 @@ func transactionSign(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("transactionSign failed: %s", err.Error())
+			requestLogger(r).Errorf("transactionSign failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
@@ -123,32 +157,20 @@ func transactionSign(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
-func (r *TransactionSignRequest) validate() error {
-	if len(r.TransactionInputs) == 0 {
-		return errors.New("inputs are required")
-	}
-
-	for _, input := range r.TransactionInputs {
-		if input.Hash == "" {
-			return errors.New("input hash cannot be empty")
-		}
-	}
-
-	for _, output := range r.TransactionOutputs {
-		if output.Address == "" {
-			return errors.New("address cannot be empty")
+// spentCoins returns the coins sent by the outputs, the change outputs to the addresses of the device are not spent
+func spentCoins(outputs []*messages.SkycoinTransactionOutput) uint64 {
+	var coins uint64
+	for _, o := range outputs {
+		if o.AddressIndex != nil {
+			continue
 		}
 
-		if output.Coins == "" {
-			return errors.New("coins cannot be empty")
-		}
-
-		if output.Hours == "" {
-			return errors.New("hours cannot be empty")
+		coins += o.GetCoin()
+		if coins < o.GetCoin() {
+			return math.MaxUint64
 		}
 	}
-
-	return nil
+	return coins
 }
 
 // TransactionParams returns params for a transaction from the request data
@@ -177,12 +199,12 @@ func (r *TransactionSignRequest) TransactionParams() ([]*messages.SkycoinTransac
 			return nil, nil, err
 		}
 
-		coins, err := droplet.FromString(output.Coins)
+		coins, err := amount.ParseCoins(output.Coins)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		hours, err := strconv.ParseUint(output.Hours, 10, 64)
+		hours, err := amount.ParseHours(output.Hours)
 		if err != nil {
 			return nil, nil, err
 		}
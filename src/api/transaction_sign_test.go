@@ -211,7 +211,7 @@ func TestSignTransaction(t *testing.T) {
 				Data: failureMsgBytes,
 			},
 			err:          "failure msg",
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
 		},
 
 		{
@@ -255,7 +255,7 @@ func TestSignTransaction(t *testing.T) {
 				}
 			}
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, strings.NewReader(tc.httpBody))
+			req, err := http.NewRequest(tc.method, "/api/v2"+endpoint, strings.NewReader(tc.httpBody))
 			require.NoError(t, err)
 
 			contentType := tc.contentType
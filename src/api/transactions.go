@@ -0,0 +1,211 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+)
+
+const (
+	// defaultTransactionsLimit is the number of transactions of a page by default
+	defaultTransactionsLimit = 20
+	// maxTransactionsLimit is the largest number of transactions of a page
+	maxTransactionsLimit = 100
+)
+
+// TransactionsResponse is returned by /api/v1/transactions
+type TransactionsResponse struct {
+	// Addresses are the addresses the transactions are queried for
+	Addresses []string `json:"addresses"`
+	// Transactions are the transactions of the page, in chronological order
+	Transactions []node.Transaction `json:"transactions"`
+	Page         int                `json:"page"`
+	Limit        int                `json:"limit"`
+	// Total is the number of transactions of all the pages
+	Total int `json:"total"`
+}
+
+// URI: /api/v1/transactions
+// Method: GET
+// Args:
+//
+//	address_n: number of addresses of the device the transactions are returned for, from the first one [required without addresses]
+//	addresses: comma separated addresses the transactions are returned for, instead of the addresses of the device [optional]
+//	page: page of the transactions, from 1 [optional, defaults to 1]
+//	limit: number of transactions of a page [optional, defaults to 20]
+func transactions(gateway Gatewayer, client *node.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		query := r.URL.Query()
+		page, ok := queryInt(w, query.Get("page"), "page", 1, 1, int(^uint(0)>>1))
+		if !ok {
+			return
+		}
+		limit, ok := queryInt(w, query.Get("limit"), "limit", defaultTransactionsLimit, 1, maxTransactionsLimit)
+		if !ok {
+			return
+		}
+
+		var addrs []string
+		if s := query.Get("addresses"); s != "" {
+			if query.Get("address_n") != "" {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, "address_n and addresses cannot both be set")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var err error
+			addrs, err = node.ParseAddresses(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+		} else {
+			addressN, ok := queryInt(w, query.Get("address_n"), "address_n", 0, 1, node.MaxAddresses)
+			if !ok {
+				return
+			}
+
+			addrs, ok = deriveAddresses(w, r, gateway, addressN)
+			if !ok {
+				return
+			}
+		}
+
+		txns, err := client.Transactions(addrs)
+		if err != nil {
+			writeNodeError(w, r, err)
+			return
+		}
+
+		// the pages past the last one are empty
+		start, end := len(txns), len(txns)
+		if page-1 <= len(txns)/limit {
+			start = (page - 1) * limit
+			if start > len(txns) {
+				start = len(txns)
+			}
+			end = start + limit
+			if end > len(txns) {
+				end = len(txns)
+			}
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: TransactionsResponse{
+				Addresses:    addrs,
+				Transactions: txns[start:end],
+				Page:         page,
+				Limit:        limit,
+				Total:        len(txns),
+			},
+		})
+	}
+}
+
+// queryInt parses the query parameter name between lo and hi, def if it is empty, writing the error response if it
+// is invalid. A def of 0 makes the parameter required.
+func queryInt(w http.ResponseWriter, s, name string, def, lo, hi int) (int, bool) {
+	if s == "" {
+		if def == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, name+" is required")
+			writeHTTPResponse(w, resp)
+			return 0, false
+		}
+		return def, true
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, "invalid "+name)
+		writeHTTPResponse(w, resp)
+		return 0, false
+	}
+
+	if v < lo || v > hi {
+		resp := NewHTTPErrorResponse(http.StatusUnprocessableEntity, name+" must be between "+strconv.Itoa(lo)+" and "+strconv.Itoa(hi))
+		writeHTTPResponse(w, resp)
+		return 0, false
+	}
+
+	return v, true
+}
+
+// deriveAddresses returns the first addressN addresses of the device, without showing them, writing the response
+// if the device does not return them, such as a PIN request
+func deriveAddresses(w http.ResponseWriter, r *http.Request, gateway Gatewayer, addressN int) ([]string, bool) {
+	addresses, ok := deviceAddresses(w, r, gateway, addressN, 0, false)
+	if !ok {
+		return nil, false
+	}
+
+	addrs, err := node.Addresses(addresses)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	}
+	return addrs, true
+}
+
+// deviceAddresses returns addressN addresses of the device from startIndex, writing the response if the device
+// does not return them, such as a PIN request
+func deviceAddresses(w http.ResponseWriter, r *http.Request, gateway Gatewayer, addressN, startIndex int, confirmAddress bool) ([]string, bool) {
+	var msg wire.Message
+	var err error
+	retCH := make(chan int)
+	errCH := make(chan int)
+	ctx := r.Context()
+
+	go func() {
+		msg, err = gateway.AddressGen(uint32(addressN), uint32(startIndex), confirmAddress)
+		if err != nil {
+			errCH <- 1
+			return
+		}
+		retCH <- 1
+	}()
+
+	select {
+	case <-retCH:
+		// the device asks for the PIN or refuses, the request is sent again once the PIN is entered
+		if msg.Kind != uint16(messages.MessageType_MessageType_ResponseSkycoinAddress) {
+			HandleFirmwareResponseMessages(w, msg)
+			return nil, false
+		}
+
+		addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return nil, false
+		}
+		return addresses, true
+	case <-errCH:
+		requestLogger(r).Errorf("deviceAddresses failed: %s", err.Error())
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return nil, false
+	case <-ctx.Done():
+		disConnErr := gateway.Disconnect()
+		if disConnErr != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, disConnErr.Error())
+			writeHTTPResponse(w, resp)
+		} else {
+			resp := NewHTTPErrorResponse(499, "Client Closed Request")
+			writeHTTPResponse(w, resp)
+		}
+		return nil, false
+	}
+}
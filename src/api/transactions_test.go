@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/node"
+)
+
+func TestTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/transactions", r.URL.Path)
+		switch r.URL.Query().Get("addrs") {
+		case "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG,2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8":
+			w.Write([]byte(`[
+				{"status": {"confirmed": true, "block_seq": 20}, "txn": {"txid": "c2"}},
+				{"status": {"confirmed": false}, "txn": {"txid": "c3"}},
+				{"status": {"confirmed": true, "block_seq": 10}, "txn": {"txid": "c1"}}
+			]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := node.New(node.Config{
+		URL: server.URL,
+	})
+	require.NoError(t, err)
+
+	gateway := &MockGatewayer{}
+	gateway.On("AddressGen", uint32(2), uint32(0), false).Return(newReply(t, messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+		Addresses: []string{"2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"},
+	}), nil)
+	gateway.On("AddressGen", uint32(3), uint32(0), false).Return(newReply(t, messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{}), nil)
+
+	cfg := defaultMuxConfig()
+	cfg.node = client
+	handler := newServerMux(cfg, gateway)
+
+	do := func(url string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr
+	}
+
+	list := func(url string) TransactionsResponse {
+		rr := do(url)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var rsp struct {
+			Data TransactionsResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+		return rsp.Data
+	}
+
+	rsp := list("/api/v2/transactions?address_n=2&limit=2")
+	require.Equal(t, 3, rsp.Total)
+	require.Len(t, rsp.Transactions, 2)
+	require.Equal(t, "c1", rsp.Transactions[0].Txn.Hash)
+	require.Equal(t, "c2", rsp.Transactions[1].Txn.Hash)
+
+	rsp = list("/api/v2/transactions?address_n=2&limit=2&page=2")
+	require.Len(t, rsp.Transactions, 1)
+	require.Equal(t, "c3", rsp.Transactions[0].Txn.Hash)
+
+	rsp = list("/api/v2/transactions?address_n=2&limit=2&page=1000")
+	require.Empty(t, rsp.Transactions)
+
+	// the addresses are taken instead of the device ones
+	rsp = list("/api/v2/transactions?addresses=2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8,2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG")
+	require.Equal(t, 3, rsp.Total)
+
+	rsp = list("/api/v2/transactions?addresses=2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8")
+	require.Equal(t, 0, rsp.Total)
+	require.NotNil(t, rsp.Transactions)
+
+	// the device asks for the PIN first
+	rr := do("/api/v2/transactions?address_n=3")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "PinMatrixRequest")
+
+	rr = do("/api/v2/transactions")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do("/api/v2/transactions?address_n=2&limit=101")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do("/api/v2/transactions?address_n=2&addresses=2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	gateway.AssertNumberOfCalls(t, "AddressGen", 4)
+}
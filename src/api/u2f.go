@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/u2f"
+)
+
+const (
+	// U2FVersion is the version of the U2F protocol of the device
+	U2FVersion = "U2F_V2"
+
+	// u2fTypRegister and u2fTypAuthenticate are the types of the client data of the registration and authentication
+	u2fTypRegister     = "navigator.id.finishEnrollment"
+	u2fTypAuthenticate = "navigator.id.getAssertion"
+)
+
+// U2FRelay runs the U2F operations on the U2F interface of the device
+type U2FRelay interface {
+	Register(ctx context.Context, challenge, application [32]byte) ([]byte, error)
+	Authenticate(ctx context.Context, challenge, application [32]byte, keyHandle []byte) ([]byte, error)
+}
+
+// U2FRegisterRequest is request data for /api/v1/u2f/register
+type U2FRegisterRequest struct {
+	// AppID is the https URL of the application the key is registered for
+	AppID string `json:"app_id"`
+	// Challenge is the websafe base64 challenge of the relying party
+	Challenge string `json:"challenge"`
+}
+
+// U2FRegisterResponse is returned by /api/v1/u2f/register, the fields of the RegisterResponse of the FIDO U2F
+// JavaScript API
+type U2FRegisterResponse struct {
+	Version string `json:"version"`
+	// RegistrationData is the websafe base64 raw registration response of the device
+	RegistrationData string `json:"registration_data"`
+	// ClientData is the websafe base64 client data whose hash the device signed
+	ClientData string `json:"client_data"`
+}
+
+// U2FAuthenticateRequest is request data for /api/v1/u2f/authenticate
+type U2FAuthenticateRequest struct {
+	// AppID is the https URL of the application the key was registered for
+	AppID string `json:"app_id"`
+	// Challenge is the websafe base64 challenge of the relying party
+	Challenge string `json:"challenge"`
+	// KeyHandle is the websafe base64 key handle returned by the registration
+	KeyHandle string `json:"key_handle"`
+}
+
+// U2FAuthenticateResponse is returned by /api/v1/u2f/authenticate, the fields of the SignResponse of the FIDO U2F
+// JavaScript API
+type U2FAuthenticateResponse struct {
+	KeyHandle string `json:"key_handle"`
+	// SignatureData is the websafe base64 raw authentication response of the device
+	SignatureData string `json:"signature_data"`
+	// ClientData is the websafe base64 client data whose hash the device signed
+	ClientData string `json:"client_data"`
+}
+
+// u2fClientData is the client data of the FIDO U2F JavaScript API, its hash is the challenge parameter
+type u2fClientData struct {
+	Typ       string `json:"typ"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// u2fFacet returns the origin of the app ID, the facet of the client data. A browser request must come from the
+// origin of the app ID, so a web page cannot use the keys of another site.
+func u2fFacet(r *http.Request, appID string) (string, HTTPResponse, bool) {
+	if appID == "" {
+		return "", NewHTTPErrorResponse(http.StatusBadRequest, "app_id is required"), false
+	}
+
+	u, err := url.Parse(appID)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", NewHTTPErrorResponse(http.StatusBadRequest, "app_id must be an https URL"), false
+	}
+	facet := u.Scheme + "://" + u.Host
+
+	if origin := r.Header.Get("Origin"); origin != "" && origin != facet {
+		return "", NewHTTPErrorResponse(http.StatusForbidden, "app_id does not match the origin of the request"), false
+	}
+
+	return facet, HTTPResponse{}, true
+}
+
+// u2fParams returns the client data and the challenge and application parameters of the U2F request
+func u2fParams(typ, appID, challenge, facet string) (string, [32]byte, [32]byte, error) {
+	clientData, err := json.Marshal(u2fClientData{
+		Typ:       typ,
+		Challenge: challenge,
+		Origin:    facet,
+	})
+	if err != nil {
+		return "", [32]byte{}, [32]byte{}, err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(clientData), sha256.Sum256(clientData), sha256.Sum256([]byte(appID)), nil
+}
+
+// writeU2FError writes the error of the U2F operation
+func writeU2FError(w http.ResponseWriter, r *http.Request, err error) {
+	var resp HTTPResponse
+	switch err {
+	case u2f.ErrNotFound:
+		resp = NewHTTPErrorResponse(http.StatusServiceUnavailable, err.Error())
+	case u2f.ErrUnsupported:
+		resp = NewHTTPErrorResponse(http.StatusNotImplemented, err.Error())
+	case u2f.ErrWrongKeyHandle:
+		resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+	case u2f.ErrTimeout:
+		resp = NewHTTPErrorResponse(http.StatusRequestTimeout, err.Error())
+	case context.Canceled:
+		resp = NewHTTPErrorResponse(499, "Client Closed Request")
+	default:
+		requestLogger(r).Errorf("u2f failed: %s", err.Error())
+		resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	writeHTTPResponse(w, resp)
+}
+
+// u2fRegister registers a new key of the app ID on the device, once the user confirms its presence on the device
+// URI: /api/v1/u2f/register
+// Method: POST
+// Content-Type: application/json
+// Args: JSON Body
+func u2fRegister(relay U2FRelay) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req U2FRegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer r.Body.Close()
+
+		facet, resp, ok := u2fFacet(r, req.AppID)
+		if !ok {
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Challenge == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "challenge is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		clientData, challenge, application, err := u2fParams(u2fTypRegister, req.AppID, req.Challenge, facet)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		data, err := relay.Register(r.Context(), challenge, application)
+		if err != nil {
+			writeU2FError(w, r, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: U2FRegisterResponse{
+				Version:          U2FVersion,
+				RegistrationData: base64.RawURLEncoding.EncodeToString(data),
+				ClientData:       clientData,
+			},
+		})
+	}
+}
+
+// u2fAuthenticate signs the challenge with the key of the key handle, once the user confirms its presence on the
+// device
+// URI: /api/v1/u2f/authenticate
+// Method: POST
+// Content-Type: application/json
+// Args: JSON Body
+func u2fAuthenticate(relay U2FRelay) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req U2FAuthenticateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+		defer r.Body.Close()
+
+		facet, resp, ok := u2fFacet(r, req.AppID)
+		if !ok {
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Challenge == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "challenge is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		keyHandle, err := base64.RawURLEncoding.DecodeString(req.KeyHandle)
+		if err != nil || len(keyHandle) == 0 || len(keyHandle) > 255 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "key_handle must be websafe base64 encoded, of 1 to 255 bytes")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		clientData, challenge, application, err := u2fParams(u2fTypAuthenticate, req.AppID, req.Challenge, facet)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		data, err := relay.Authenticate(r.Context(), challenge, application, keyHandle)
+		if err != nil {
+			writeU2FError(w, r, err)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: U2FAuthenticateResponse{
+				KeyHandle:     req.KeyHandle,
+				SignatureData: base64.RawURLEncoding.EncodeToString(data),
+				ClientData:    clientData,
+			},
+		})
+	}
+}
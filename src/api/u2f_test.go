@@ -0,0 +1,276 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/u2f"
+)
+
+// fakeU2FRelay records the parameters of the U2F operations and answers rsp or err
+type fakeU2FRelay struct {
+	challenge   [32]byte
+	application [32]byte
+	keyHandle   []byte
+	rsp         []byte
+	err         error
+}
+
+func (r *fakeU2FRelay) Register(ctx context.Context, challenge, application [32]byte) ([]byte, error) {
+	r.challenge, r.application = challenge, application
+	return r.rsp, r.err
+}
+
+func (r *fakeU2FRelay) Authenticate(ctx context.Context, challenge, application [32]byte, keyHandle []byte) ([]byte, error) {
+	r.challenge, r.application, r.keyHandle = challenge, application, keyHandle
+	return r.rsp, r.err
+}
+
+func TestU2FRegister(t *testing.T) {
+	cases := []struct {
+		name          string
+		method        string
+		contentType   string
+		origin        string
+		body          string
+		err           error
+		status        int
+		httpResponse  HTTPResponse
+		reachesDevice bool
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "415",
+			method:       http.MethodPost,
+			contentType:  ContentTypeForm,
+			status:       http.StatusUnsupportedMediaType,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+		{
+			name:         "400 - missing app_id",
+			method:       http.MethodPost,
+			contentType:  ContentTypeJSON,
+			body:         `{"challenge": "Y2hhbGxlbmdl"}`,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "app_id is required"),
+		},
+		{
+			name:         "400 - app_id not https",
+			method:       http.MethodPost,
+			contentType:  ContentTypeJSON,
+			body:         `{"app_id": "http://example.com", "challenge": "Y2hhbGxlbmdl"}`,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "app_id must be an https URL"),
+		},
+		{
+			name:         "400 - missing challenge",
+			method:       http.MethodPost,
+			contentType:  ContentTypeJSON,
+			body:         `{"app_id": "https://example.com"}`,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "challenge is required"),
+		},
+		{
+			name:         "403 - origin of another site",
+			method:       http.MethodPost,
+			contentType:  ContentTypeJSON,
+			origin:       "https://attacker.example",
+			body:         `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl"}`,
+			status:       http.StatusForbidden,
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, "app_id does not match the origin of the request"),
+		},
+		{
+			name:          "408 - user presence not confirmed",
+			method:        http.MethodPost,
+			contentType:   ContentTypeJSON,
+			body:          `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl"}`,
+			err:           u2f.ErrTimeout,
+			status:        http.StatusRequestTimeout,
+			httpResponse:  NewHTTPErrorResponse(http.StatusRequestTimeout, u2f.ErrTimeout.Error()),
+			reachesDevice: true,
+		},
+		{
+			name:          "503 - no U2F device",
+			method:        http.MethodPost,
+			contentType:   ContentTypeJSON,
+			body:          `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl"}`,
+			err:           u2f.ErrNotFound,
+			status:        http.StatusServiceUnavailable,
+			httpResponse:  NewHTTPErrorResponse(http.StatusServiceUnavailable, u2f.ErrNotFound.Error()),
+			reachesDevice: true,
+		},
+		{
+			name:          "500",
+			method:        http.MethodPost,
+			contentType:   ContentTypeJSON,
+			body:          `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl"}`,
+			err:           errors.New("failure"),
+			status:        http.StatusInternalServerError,
+			httpResponse:  NewHTTPErrorResponse(http.StatusInternalServerError, "failure"),
+			reachesDevice: true,
+		},
+		{
+			name:        "200",
+			method:      http.MethodPost,
+			contentType: ContentTypeJSON,
+			origin:      "https://example.com",
+			body:        `{"app_id": "https://example.com/app", "challenge": "Y2hhbGxlbmdl"}`,
+			status:      http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: U2FRegisterResponse{
+					Version:          U2FVersion,
+					RegistrationData: base64.RawURLEncoding.EncodeToString([]byte("registration")),
+					ClientData:       base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"navigator.id.finishEnrollment","challenge":"Y2hhbGxlbmdl","origin":"https://example.com"}`)),
+				},
+			},
+			reachesDevice: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			relay := &fakeU2FRelay{
+				rsp: []byte("registration"),
+				err: tc.err,
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/u2f/register", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+
+			cfg := defaultMuxConfig()
+			cfg.u2f = relay
+			// the origin of the web page is checked against the app ID, the page is allowed by the header check
+			cfg.disableHeaderCheck = true
+
+			rr := httptest.NewRecorder()
+			newServerMux(cfg, &MockGatewayer{}).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			if rsp.Data != nil {
+				var data U2FRegisterResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &data))
+				require.Equal(t, tc.httpResponse.Data, data)
+
+				clientData, err := base64.RawURLEncoding.DecodeString(data.ClientData)
+				require.NoError(t, err)
+				require.Equal(t, sha256.Sum256(clientData), relay.challenge)
+				require.Equal(t, sha256.Sum256([]byte("https://example.com/app")), relay.application)
+			}
+
+			if !tc.reachesDevice {
+				require.Equal(t, [32]byte{}, relay.application)
+			}
+		})
+	}
+}
+
+func TestU2FAuthenticate(t *testing.T) {
+	keyHandle := base64.RawURLEncoding.EncodeToString([]byte("key handle"))
+
+	cases := []struct {
+		name         string
+		body         string
+		err          error
+		status       int
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "400 - invalid key_handle",
+			body:         `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl", "key_handle": "+/+/"}`,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "key_handle must be websafe base64 encoded, of 1 to 255 bytes"),
+		},
+		{
+			name:         "400 - missing key_handle",
+			body:         `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl"}`,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "key_handle must be websafe base64 encoded, of 1 to 255 bytes"),
+		},
+		{
+			name:         "400 - key handle of another device",
+			body:         `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl", "key_handle": "` + keyHandle + `"}`,
+			err:          u2f.ErrWrongKeyHandle,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, u2f.ErrWrongKeyHandle.Error()),
+		},
+		{
+			name:         "501",
+			body:         `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl", "key_handle": "` + keyHandle + `"}`,
+			err:          u2f.ErrUnsupported,
+			status:       http.StatusNotImplemented,
+			httpResponse: NewHTTPErrorResponse(http.StatusNotImplemented, u2f.ErrUnsupported.Error()),
+		},
+		{
+			name:   "200",
+			body:   `{"app_id": "https://example.com", "challenge": "Y2hhbGxlbmdl", "key_handle": "` + keyHandle + `"}`,
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: U2FAuthenticateResponse{
+					KeyHandle:     keyHandle,
+					SignatureData: base64.RawURLEncoding.EncodeToString([]byte("signature")),
+					ClientData:    base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"navigator.id.getAssertion","challenge":"Y2hhbGxlbmdl","origin":"https://example.com"}`)),
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			relay := &fakeU2FRelay{
+				rsp: []byte("signature"),
+				err: tc.err,
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v2/u2f/authenticate", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			cfg := defaultMuxConfig()
+			cfg.u2f = relay
+
+			rr := httptest.NewRecorder()
+			newServerMux(cfg, &MockGatewayer{}).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+			if rsp.Data != nil {
+				var data U2FAuthenticateResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &data))
+				require.Equal(t, tc.httpResponse.Data, data)
+				require.Equal(t, []byte("key handle"), relay.keyHandle)
+			}
+		})
+	}
+}
+
+func TestU2FDisabled(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/api/v2/u2f/register", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newServerMux(defaultMuxConfig(), &MockGatewayer{}).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
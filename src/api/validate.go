@@ -0,0 +1,257 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/base58"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+)
+
+// The requests reaching the device are validated before any device I/O, the firmware only parses well formed fields:
+// the hashes are 32 bytes of hex, the indexes are in the bounds of the firmware, the addresses have a valid checksum
+// and the amounts are in range.
+
+// maxAddressN is the most addresses the firmware generates in a request
+const maxAddressN = 99
+
+// signatureSize is the size of the base58 recoverable signatures of the firmware
+const signatureSize = 65
+
+// validationError is an invalid field of a request, answered with its status
+type validationError struct {
+	status int
+	msg    string
+}
+
+func (e validationError) Error() string {
+	return e.msg
+}
+
+// errMissing is returned when a required field is missing, answered with 400
+func errMissing(msg string) error {
+	return validationError{
+		status: http.StatusBadRequest,
+		msg:    msg,
+	}
+}
+
+// errInvalid is returned when a field is malformed or out of range, answered with 422
+func errInvalid(msg string) error {
+	return validationError{
+		status: http.StatusUnprocessableEntity,
+		msg:    msg,
+	}
+}
+
+// writeValidationError writes the error of a request validation, 400 for the errors without a status
+func writeValidationError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if e, ok := err.(validationError); ok {
+		status = e.status
+	}
+	writeHTTPResponse(w, NewHTTPErrorResponse(status, err.Error()))
+}
+
+// validateAddressN validates the number of addresses and the index of the first address of an address generation
+func validateAddressN(addressN, startIndex int) error {
+	if addressN == 0 {
+		return errInvalid("address_n cannot be 0")
+	}
+
+	if addressN < 0 {
+		return errInvalid("address_n cannot be negative")
+	}
+
+	if addressN > maxAddressN {
+		return errInvalid(fmt.Sprintf("address_n cannot be greater than %d", maxAddressN))
+	}
+
+	return validateStartIndex(addressN, startIndex)
+}
+
+// validateStartIndex validates the index of the first of the addressN addresses, the last index is a uint32
+func validateStartIndex(addressN, startIndex int) error {
+	if startIndex < 0 {
+		return errInvalid("start_index cannot be negative")
+	}
+
+	if uint64(startIndex)+uint64(addressN) > math.MaxUint32 {
+		return errInvalid("start_index is out of range")
+	}
+
+	return nil
+}
+
+// validateAddressIndex validates the index of the address of the device signing a message
+func validateAddressIndex(addressN int) error {
+	if addressN < 0 {
+		return errInvalid("address_n cannot be negative")
+	}
+
+	if uint64(addressN) > math.MaxUint32 {
+		return errInvalid("address_n is out of range")
+	}
+
+	return nil
+}
+
+// validateHash validates the hex hash of a transaction input
+func validateHash(hash string) error {
+	if hash == "" {
+		return errMissing("input hash cannot be empty")
+	}
+
+	if _, err := cipher.SHA256FromHex(hash); err != nil {
+		return errInvalid(fmt.Sprintf("invalid input hash: %v", err))
+	}
+
+	return nil
+}
+
+// validateAddress validates the format, the version and the checksum of an address of the coin of backend
+func validateAddress(backend coin.Backend, address string) error {
+	if address == "" {
+		return errMissing("address cannot be empty")
+	}
+
+	if err := coin.ValidateAddress(backend, address); err != nil {
+		return errInvalid(err.Error())
+	}
+
+	return nil
+}
+
+// validateCoins validates the decimal coins of an output, a positive number of droplets
+func validateCoins(coins string) error {
+	if coins == "" {
+		return errMissing("coins cannot be empty")
+	}
+
+	n, err := amount.ParseCoins(coins)
+	if err != nil {
+		return errInvalid(err.Error())
+	}
+
+	if n == 0 {
+		return errInvalid("coins must be greater than 0")
+	}
+
+	return nil
+}
+
+// validateHours validates the coin hours of an output
+func validateHours(hours string) error {
+	if hours == "" {
+		return errMissing("hours cannot be empty")
+	}
+
+	if _, err := amount.ParseHours(hours); err != nil {
+		return errInvalid(err.Error())
+	}
+
+	return nil
+}
+
+// validateSignature validates a base58 recoverable signature
+func validateSignature(signature string) error {
+	if signature == "" {
+		return errMissing("signature is required")
+	}
+
+	b, err := base58.Decode(signature)
+	if err != nil || len(b) != signatureSize {
+		return errInvalid("signature must be a base58 encoded recoverable signature")
+	}
+
+	return nil
+}
+
+func (r *GenerateAddressesRequest) validate() error {
+	return validateAddressN(r.AddressN, r.StartIndex)
+}
+
+func (r *SignMessageRequest) validate() error {
+	if err := validateAddressIndex(r.AddressN); err != nil {
+		return err
+	}
+
+	if r.Message == "" {
+		return errMissing("message is required")
+	}
+
+	return nil
+}
+
+func (r *CheckMessageSignatureRequest) validate(backend coin.Backend) error {
+	if r.Address == "" {
+		return errMissing("address is required")
+	}
+
+	if err := validateAddress(backend, r.Address); err != nil {
+		return err
+	}
+
+	if err := validateSignature(r.Signature); err != nil {
+		return err
+	}
+
+	if r.Message == "" {
+		return errMissing("message is required")
+	}
+
+	return nil
+}
+
+func (r *TransactionSignRequest) validate(backend coin.Backend) error {
+	if len(r.TransactionInputs) == 0 {
+		return errMissing("inputs are required")
+	}
+
+	// the missing fields are reported before the malformed ones
+	for _, input := range r.TransactionInputs {
+		if input.Hash == "" {
+			return errMissing("input hash cannot be empty")
+		}
+	}
+
+	for _, output := range r.TransactionOutputs {
+		if output.Address == "" {
+			return errMissing("address cannot be empty")
+		}
+
+		if output.Coins == "" {
+			return errMissing("coins cannot be empty")
+		}
+
+		if output.Hours == "" {
+			return errMissing("hours cannot be empty")
+		}
+	}
+
+	for _, input := range r.TransactionInputs {
+		if err := validateHash(input.Hash); err != nil {
+			return err
+		}
+	}
+
+	for _, output := range r.TransactionOutputs {
+		if err := validateAddress(backend, output.Address); err != nil {
+			return err
+		}
+
+		if err := validateCoins(output.Coins); err != nil {
+			return err
+		}
+
+		if err := validateHours(output.Hours); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+// +build gofuzz
+
+package api
+
+import "encoding/json"
+
+// Fuzz is the go-fuzz entry point of the request validation, it panics when a validated transaction cannot be
+// converted to the device messages.
+// Run with: go-fuzz-build github.com/skycoin/hardware-wallet-daemon/src/api && go-fuzz -bin api-fuzz.zip -workdir fuzz
+func Fuzz(data []byte) int {
+	var txn TransactionSignRequest
+	if err := json.Unmarshal(data, &txn); err != nil {
+		return 0
+	}
+
+	if txn.validate(nil) != nil {
+		return 0
+	}
+
+	if _, _, err := txn.TransactionParams(); err != nil {
+		panic(err)
+	}
+
+	var addresses GenerateAddressesRequest
+	if json.Unmarshal(data, &addresses) == nil && addresses.validate() == nil {
+		if addresses.AddressN <= 0 || addresses.AddressN > maxAddressN {
+			panic("address_n out of bounds")
+		}
+	}
+
+	return 1
+}
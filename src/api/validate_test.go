@@ -0,0 +1,275 @@
+package api
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	validHash      = "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663"
+	validAddress   = "2EVNa4CK9SKosT4j1GEn8SuuUUEAXaHAMbM"
+	validSignature = "GvKS4S3CA2YTpEPFA47yFdC5CP3y3qB18jwiX1URXqWQTvMjokd3A4upPz4wyeAyKJEtRdRDGUvUgoGASpsTTUeMn"
+)
+
+func TestValidateFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		validate func() error
+		status   int
+		err      string
+	}{
+		{
+			name:     "address_n in bounds",
+			validate: func() error { return validateAddressN(maxAddressN, 10) },
+		},
+		{
+			name:     "address_n 0",
+			validate: func() error { return validateAddressN(0, 0) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "address_n cannot be 0",
+		},
+		{
+			name:     "address_n negative",
+			validate: func() error { return validateAddressN(-1, 0) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "address_n cannot be negative",
+		},
+		{
+			name:     "address_n too large",
+			validate: func() error { return validateAddressN(maxAddressN+1, 0) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "address_n cannot be greater than 99",
+		},
+		{
+			name:     "start_index negative",
+			validate: func() error { return validateAddressN(1, -1) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "start_index cannot be negative",
+		},
+		{
+			name:     "start_index out of range",
+			validate: func() error { return validateAddressN(2, 1<<32-2) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "start_index is out of range",
+		},
+		{
+			name:     "hash empty",
+			validate: func() error { return validateHash("") },
+			status:   http.StatusBadRequest,
+			err:      "input hash cannot be empty",
+		},
+		{
+			name:     "hash too short",
+			validate: func() error { return validateHash(validHash[:62]) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "invalid input hash: Invalid hex length",
+		},
+		{
+			name:     "hash not hex",
+			validate: func() error { return validateHash("zz" + validHash[2:]) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "invalid input hash: encoding/hex: invalid byte: U+007A 'z'",
+		},
+		{
+			name:     "hash valid",
+			validate: func() error { return validateHash(validHash) },
+		},
+		{
+			name:     "address invalid checksum",
+			validate: func() error { return validateAddress(nil, validAddress[:len(validAddress)-1] + "N") },
+			status:   http.StatusUnprocessableEntity,
+			err:      "Invalid checksum",
+		},
+		{
+			name:     "address valid",
+			validate: func() error { return validateAddress(nil, validAddress) },
+		},
+		{
+			name:     "coins 0",
+			validate: func() error { return validateCoins("0.000") },
+			status:   http.StatusUnprocessableEntity,
+			err:      "coins must be greater than 0",
+		},
+		{
+			name:     "coins negative",
+			validate: func() error { return validateCoins("-1") },
+			status:   http.StatusUnprocessableEntity,
+			err:      "Droplet string conversion failed: Negative balance",
+		},
+		{
+			name:     "coins too many decimals",
+			validate: func() error { return validateCoins("0.0000001") },
+			status:   http.StatusUnprocessableEntity,
+			err:      "Droplet string conversion failed: Too many decimal places",
+		},
+		{
+			name:     "coins in scientific notation",
+			validate: func() error { return validateCoins("1e6") },
+			status:   http.StatusUnprocessableEntity,
+			err:      "coins must be a decimal number, the scientific notation is not allowed",
+		},
+		{
+			name:     "coins valid",
+			validate: func() error { return validateCoins("0.000001") },
+		},
+		{
+			name:     "hours overflow",
+			validate: func() error { return validateHours("18446744073709551616") },
+			status:   http.StatusUnprocessableEntity,
+			err:      `strconv.ParseUint: parsing "18446744073709551616": value out of range`,
+		},
+		{
+			name:     "hours valid",
+			validate: func() error { return validateHours("0") },
+		},
+		{
+			name:     "signature invalid",
+			validate: func() error { return validateSignature(validSignature[:40]) },
+			status:   http.StatusUnprocessableEntity,
+			err:      "signature must be a base58 encoded recoverable signature",
+		},
+		{
+			name:     "signature valid",
+			validate: func() error { return validateSignature(validSignature) },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tc.err)
+			require.Equal(t, validationError{status: tc.status, msg: tc.err}, err)
+		})
+	}
+}
+
+func TestTransactionSignRequestValidate(t *testing.T) {
+	index := uint32(1)
+	valid := func() TransactionSignRequest {
+		return TransactionSignRequest{
+			TransactionInputs: []TransactionInput{
+				{Hash: validHash, Index: &index},
+			},
+			TransactionOutputs: []TransactionOutput{
+				{Address: validAddress, Coins: "1", Hours: "2"},
+			},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		change func(r *TransactionSignRequest)
+		err    error
+	}{
+		{
+			name:   "valid",
+			change: func(r *TransactionSignRequest) {},
+		},
+		{
+			name: "missing inputs",
+			change: func(r *TransactionSignRequest) {
+				r.TransactionInputs = nil
+			},
+			err: errMissing("inputs are required"),
+		},
+		{
+			name: "missing field reported before a malformed field",
+			change: func(r *TransactionSignRequest) {
+				r.TransactionInputs[0].Hash = "00"
+				r.TransactionOutputs[0].Hours = ""
+			},
+			err: errMissing("hours cannot be empty"),
+		},
+		{
+			name: "malformed hash",
+			change: func(r *TransactionSignRequest) {
+				r.TransactionInputs[0].Hash = "00"
+			},
+			err: errInvalid("invalid input hash: Invalid hex length"),
+		},
+		{
+			name: "destination address with an invalid checksum",
+			change: func(r *TransactionSignRequest) {
+				r.TransactionOutputs[0].Address = validAddress[:len(validAddress)-1] + "N"
+			},
+			err: errInvalid("Invalid checksum"),
+		},
+		{
+			name: "zero coins",
+			change: func(r *TransactionSignRequest) {
+				r.TransactionOutputs[0].Coins = "0"
+			},
+			err: errInvalid("coins must be greater than 0"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid()
+			tc.change(&req)
+			require.Equal(t, tc.err, req.validate(nil))
+		})
+	}
+}
+
+// TestValidateRandomRequests checks on random mutations of valid requests that the validation never panics and that
+// the fields of a validated request are converted to the device messages
+func TestValidateRandomRequests(t *testing.T) {
+	seed := []string{
+		`{"transaction_inputs":[{"hash":"` + validHash + `","index":0}],"transaction_outputs":[{"address":"` + validAddress + `","coins":"1.5","hours":"2"}]}`,
+		`{"address_n":2,"start_index":7,"message":"hello","signature":"` + validSignature + `","address":"` + validAddress + `"}`,
+	}
+	alphabet := []byte(`0123456789abcdefz."-e,:{}[]` + "\x00\xff")
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		data := []byte(seed[rnd.Intn(len(seed))])
+		for n := rnd.Intn(4) + 1; n > 0; n-- {
+			switch pos := rnd.Intn(len(data)); rnd.Intn(3) {
+			case 0:
+				data[pos] = alphabet[rnd.Intn(len(alphabet))]
+			case 1:
+				data = append(data[:pos], data[pos+1:]...)
+			default:
+				data = append(data[:pos], append([]byte{alphabet[rnd.Intn(len(alphabet))]}, data[pos:]...)...)
+			}
+		}
+
+		require.NotPanics(t, func() {
+			validateRequestData(t, data)
+		}, "%s", data)
+	}
+}
+
+// validateRequestData validates the data decoded as the requests reaching the device
+func validateRequestData(t *testing.T, data []byte) {
+	var txn TransactionSignRequest
+	if json.Unmarshal(data, &txn) == nil && txn.validate(nil) == nil {
+		_, _, err := txn.TransactionParams()
+		require.NoError(t, err, "%s", data)
+	}
+
+	var addresses GenerateAddressesRequest
+	if json.Unmarshal(data, &addresses) == nil && addresses.validate() == nil {
+		require.True(t, addresses.AddressN > 0 && addresses.AddressN <= maxAddressN, "%s", data)
+	}
+
+	var sign SignMessageRequest
+	if json.Unmarshal(data, &sign) == nil {
+		sign.validate() // nolint: errcheck
+	}
+
+	var check CheckMessageSignatureRequest
+	if json.Unmarshal(data, &check) == nil {
+		check.validate(nil) // nolint: errcheck
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/blang/semver"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/updatecheck"
 )
 
 // BuildInfo represents the build info
@@ -11,9 +13,20 @@ type BuildInfo struct {
 	Version string `json:"version"` // version number
 	Commit  string `json:"commit"`  // git commit id
 	Branch  string `json:"branch"`  // git branch name
+	// BuildTime is the RFC3339 time the binary was built at, empty if not set at build time
+	BuildTime string `json:"build_time"`
+	// GoVersion is the version of Go the binary was built with
+	GoVersion string `json:"go_version"`
+}
+
+// VersionResponse is returned by /api/v1/version
+type VersionResponse struct {
+	BuildInfo
+	// Update is the outcome of the daemon update check, if enabled
+	Update *updatecheck.Status `json:"update,omitempty"`
 }
 
-// versionHandler returns app version data
+// versionHandler returns app version data, with the daemon update available if the update check is enabled
 // URI: /api/v1/version
 // Method: GET
 func versionHandler(c muxConfig) http.HandlerFunc {
@@ -24,8 +37,16 @@ func versionHandler(c muxConfig) http.HandlerFunc {
 			return
 		}
 
+		version := VersionResponse{
+			BuildInfo: c.build,
+		}
+		if c.updateCheck != nil {
+			update := c.updateCheck.Status()
+			version.Update = &update
+		}
+
 		writeHTTPResponse(w, HTTPResponse{
-			Data: c.build,
+			Data: version,
 		})
 	}
 }
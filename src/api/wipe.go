@@ -2,15 +2,28 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 
 	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
 )
 
+const wipeAction = "wipe"
+
+// WipeConfirmation is returned by the first wipe call, the token must be sent back to wipe the device
+type WipeConfirmation struct {
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// Wipe is a two-step operation. The first call returns a one-time confirmation token,
+// the device is only wiped by a second call echoing that token before it expires.
 // URI: /api/v1/wipe
 // Method: DELETE
-func wipe(gateway Gatewayer) http.HandlerFunc {
+// Args:
+//  confirmation_token: token returned by the first call [optional]
+func wipe(gateway Gatewayer, confirmations *confirmationTokens) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
 			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
@@ -18,11 +31,36 @@ func wipe(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
+		token := r.URL.Query().Get("confirmation_token")
+		if token == "" {
+			token, expires, err := confirmations.issue(wipeAction)
+			if err != nil {
+				requestLogger(r).Errorf("wipe failed: %s", err.Error())
+				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			writeHTTPResponseStatus(w, http.StatusAccepted, HTTPResponse{
+				Data: WipeConfirmation{
+					ConfirmationToken: token,
+					ExpiresAt:         expires.UTC(),
+				},
+			})
+			return
+		}
+
+		if !confirmations.redeem(wipeAction, token) {
+			resp := NewHTTPErrorResponse(http.StatusForbidden, "invalid or expired confirmation token")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
 		// for integration tests
 		if autoPressEmulatorButtons {
 			err := gateway.SetAutoPressButton(true, skyWallet.ButtonRight)
 			if err != nil {
-				logger.Error("wipe failed: %s", err.Error())
+				requestLogger(r).Error("wipe failed: %s", err.Error())
 				resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 				writeHTTPResponse(w, resp)
 				return
@@ -48,7 +86,7 @@ func wipe(gateway Gatewayer) http.HandlerFunc {
 		case <-retCH:
 			HandleFirmwareResponseMessages(w, msg)
 		case <-errCH:
-			logger.Errorf("wipe failed: %s", err.Error())
+			requestLogger(r).Errorf("wipe failed: %s", err.Error())
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
 			writeHTTPResponse(w, resp)
 		case <-ctx.Done():
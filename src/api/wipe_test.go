@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
 	messages "github.com/skycoin/hardware-wallet-protob/go"
@@ -31,6 +32,8 @@ func TestWipe(t *testing.T) {
 		name              string
 		method            string
 		status            int
+		token             string
+		confirm           bool
 		gatewayWipeResult wire.Message
 		httpResponse      HTTPResponse
 	}{
@@ -42,20 +45,30 @@ func TestWipe(t *testing.T) {
 		},
 
 		{
-			name:   "409 - Failure msg",
-			method: http.MethodDelete,
-			status: http.StatusConflict,
+			name:         "403 - invalid confirmation token",
+			method:       http.MethodDelete,
+			status:       http.StatusForbidden,
+			token:        "foo",
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, "invalid or expired confirmation token"),
+		},
+
+		{
+			name:    "409 - Failure msg",
+			method:  http.MethodDelete,
+			status:  http.StatusConflict,
+			confirm: true,
 			gatewayWipeResult: wire.Message{
 				Kind: uint16(messages.MessageType_MessageType_Failure),
 				Data: failureMsgBytes,
 			},
-			httpResponse: NewHTTPErrorResponse(http.StatusConflict, "failure msg"),
+			httpResponse: newHTTPErrorResponseCategory(http.StatusConflict, ErrorCategoryNotInitialized, "failure msg"),
 		},
 
 		{
-			name:   "200 - OK",
-			method: http.MethodDelete,
-			status: http.StatusOK,
+			name:    "200 - OK",
+			method:  http.MethodDelete,
+			status:  http.StatusOK,
+			confirm: true,
 			gatewayWipeResult: wire.Message{
 				Kind: uint16(messages.MessageType_MessageType_Success),
 				Data: successMsgBytes,
@@ -73,11 +86,22 @@ func TestWipe(t *testing.T) {
 
 			gateway.On("Wipe").Return(tc.gatewayWipeResult, nil)
 
-			req, err := http.NewRequest(tc.method, "/api/v1"+endpoint, nil)
+			handler := newServerMux(defaultMuxConfig(), gateway)
+
+			token := tc.token
+			if tc.confirm {
+				token = requestWipeConfirmation(t, handler)
+			}
+
+			url := "/api/v2" + endpoint
+			if token != "" {
+				url += "?confirmation_token=" + token
+			}
+
+			req, err := http.NewRequest(tc.method, url, nil)
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
-			handler := newServerMux(defaultMuxConfig(), gateway)
 			handler.ServeHTTP(rr, req)
 
 			status := rr.Code
@@ -103,3 +127,62 @@ func TestWipe(t *testing.T) {
 		})
 	}
 }
+
+func requestWipeConfirmation(t *testing.T, handler http.Handler) string {
+	req, err := http.NewRequest(http.MethodDelete, "/api/v2/wipe", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+
+	var rsp ReceivedHTTPResponse
+	err = json.NewDecoder(rr.Body).Decode(&rsp)
+	require.NoError(t, err)
+	require.Nil(t, rsp.Error)
+
+	var confirmation WipeConfirmation
+	err = json.Unmarshal(rsp.Data, &confirmation)
+	require.NoError(t, err)
+	require.NotEmpty(t, confirmation.ConfirmationToken)
+	require.True(t, confirmation.ExpiresAt.After(time.Now()))
+
+	return confirmation.ConfirmationToken
+}
+
+func TestWipeConfirmationToken(t *testing.T) {
+	successMsg := messages.Success{
+		Message: newStrPtr("wipe success msg"),
+	}
+
+	successMsgBytes, err := successMsg.Marshal()
+	require.NoError(t, err)
+
+	gateway := &MockGatewayer{}
+	gateway.On("Wipe").Return(wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Success),
+		Data: successMsgBytes,
+	}, nil)
+
+	handler := newServerMux(defaultMuxConfig(), gateway)
+
+	wipeWithToken := func(token string) int {
+		req, err := http.NewRequest(http.MethodDelete, "/api/v2/wipe?confirmation_token="+token, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// the first call does not touch the device
+	token := requestWipeConfirmation(t, handler)
+	gateway.AssertNotCalled(t, "Wipe")
+
+	require.Equal(t, http.StatusOK, wipeWithToken(token))
+	gateway.AssertNumberOfCalls(t, "Wipe", 1)
+
+	// tokens are single use
+	require.Equal(t, http.StatusForbidden, wipeWithToken(token))
+	gateway.AssertNumberOfCalls(t, "Wipe", 1)
+}
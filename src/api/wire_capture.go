@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/wirecapture"
+)
+
+// WireCaptureRequest is the request data for PUT /api/v1/admin/wirecapture
+type WireCaptureRequest struct {
+	Enabled bool `json:"enabled"`
+	// Payloads captures the payloads of the messages, the daemon must be started with -wire-capture-payloads
+	Payloads bool `json:"payloads"`
+}
+
+// WireCaptureDownload is the file returned by /api/v1/admin/wirecapture/download
+type WireCaptureDownload struct {
+	Status wirecapture.Status `json:"status"`
+	// Exchanges are the messages exchanged with the device, oldest first
+	Exchanges []wirecapture.Exchange `json:"exchanges"`
+}
+
+// adminWireCaptureHandler returns the status of the wire capture, starts or stops it, or clears the exchanges
+// captured. Starting the capture clears the exchanges captured before.
+// URI: /api/v1/admin/wirecapture
+// Method: GET, PUT, DELETE
+// Body (PUT): WireCaptureRequest
+func adminWireCaptureHandler(capture *wirecapture.Capture) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeHTTPResponse(w, HTTPResponse{
+				Data: capture.Status(),
+			})
+		case http.MethodPut:
+			if r.Header.Get("Content-Type") != ContentTypeJSON {
+				resp := NewHTTPErrorResponse(http.StatusUnsupportedMediaType, "")
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			var req WireCaptureRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+			defer r.Body.Close()
+
+			if !req.Enabled {
+				status := capture.Stop()
+				criticalRequestLogger(r).Infof("Wire capture stopped, %d exchanges captured", status.Captured)
+				writeHTTPResponse(w, HTTPResponse{
+					Data: status,
+				})
+				return
+			}
+
+			status, err := capture.Start(req.Payloads)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+				writeHTTPResponse(w, resp)
+				return
+			}
+
+			criticalRequestLogger(r).Infof("Wire capture started, payloads %v", status.Payloads)
+			writeHTTPResponse(w, HTTPResponse{
+				Data: status,
+			})
+		case http.MethodDelete:
+			writeHTTPResponse(w, HTTPResponse{
+				Data: capture.Clear(),
+			})
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+// adminWireCaptureDownloadHandler downloads the exchanges captured as a JSON file
+// URI: /api/v1/admin/wirecapture/download
+// Method: GET
+func adminWireCaptureDownloadHandler(capture *wirecapture.Capture) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		download := WireCaptureDownload{
+			Status:    capture.Status(),
+			Exchanges: capture.Exchanges(),
+		}
+
+		filename := fmt.Sprintf("wire-capture-%s.json", time.Now().UTC().Format("20060102T150405Z"))
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := json.NewEncoder(w).Encode(download); err != nil {
+			requestLogger(r).WithError(err).Error("failed to write wire capture")
+		}
+	}
+}
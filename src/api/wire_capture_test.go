@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/wirecapture"
+)
+
+func TestAdminWireCapture(t *testing.T) {
+	capture := wirecapture.New(10, false)
+
+	cfg := defaultMuxConfig()
+	cfg.adminToken = testAdminToken
+	cfg.wireCapture = capture
+	handler := newServerMux(cfg, &MockGatewayer{})
+
+	do := func(method, endpoint, token, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, endpoint, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	decode := func(rr *httptest.ResponseRecorder, status int, errMsg string) wirecapture.Status {
+		require.Equal(t, status, rr.Code, rr.Body.String())
+		var rsp ReceivedHTTPResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rsp))
+		if errMsg != "" {
+			require.NotNil(t, rsp.Error)
+			require.Equal(t, errMsg, rsp.Error.Message)
+			return wirecapture.Status{}
+		}
+
+		var s wirecapture.Status
+		require.NoError(t, json.Unmarshal(rsp.Data, &s))
+		return s
+	}
+
+	decode(do(http.MethodGet, "/api/v2/admin/wirecapture", "", ""), http.StatusUnauthorized, "invalid admin token")
+	decode(do(http.MethodGet, "/api/v2/admin/wirecapture/download", "", ""), http.StatusUnauthorized, "invalid admin token")
+	decode(do(http.MethodPost, "/api/v2/admin/wirecapture", testAdminToken, ""), http.StatusMethodNotAllowed, "Method Not Allowed")
+
+	s := decode(do(http.MethodGet, "/api/v2/admin/wirecapture", testAdminToken, ""), http.StatusOK, "")
+	require.Equal(t, wirecapture.Status{Size: 10}, s)
+
+	// the payloads need -wire-capture-payloads
+	decode(do(http.MethodPut, "/api/v2/admin/wirecapture", testAdminToken, `{"enabled": true, "payloads": true}`),
+		http.StatusForbidden, wirecapture.ErrPayloadsNotAllowed.Error())
+	decode(do(http.MethodPut, "/api/v2/admin/wirecapture", testAdminToken, `{"enabled": true`),
+		http.StatusBadRequest, "unexpected EOF")
+
+	s = decode(do(http.MethodPut, "/api/v2/admin/wirecapture", testAdminToken, `{"enabled": true}`), http.StatusOK, "")
+	require.True(t, s.Enabled)
+	require.False(t, s.Payloads)
+	require.NotNil(t, s.StartedAt)
+
+	drv := wirecapture.NewDriver(&fakeDriver{
+		replies: []wire.Message{newReply(t, messages.MessageType_MessageType_Success, &messages.Success{})},
+	}, capture)
+	chunks, err := skyWallet.MessageCancel()
+	require.NoError(t, err)
+	_, err = drv.SendToDevice(nil, chunks)
+	require.NoError(t, err)
+
+	s = decode(do(http.MethodPut, "/api/v2/admin/wirecapture", testAdminToken, `{"enabled": false}`), http.StatusOK, "")
+	require.False(t, s.Enabled)
+	require.Equal(t, 1, s.Captured)
+
+	rr := do(http.MethodGet, "/api/v2/admin/wirecapture/download", testAdminToken, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Header().Get("Content-Disposition"), `attachment; filename="wire-capture-`)
+	var download WireCaptureDownload
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&download))
+	require.Equal(t, 1, download.Status.Captured)
+	require.Len(t, download.Exchanges, 1)
+	require.Equal(t, "MessageType_Cancel", download.Exchanges[0].Request.Type)
+	require.Equal(t, "MessageType_Success", download.Exchanges[0].Response.Type)
+
+	s = decode(do(http.MethodDelete, "/api/v2/admin/wirecapture", testAdminToken, ""), http.StatusOK, "")
+	require.Equal(t, 0, s.Captured)
+}
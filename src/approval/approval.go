@@ -0,0 +1,225 @@
+// Package approval enforces a two-person rule on the operations above a policy threshold: an operation requested
+// by a client is held pending until a second person, the approver, approves it. The client then sends the same
+// operation again with the ID of the approval, and only then is the device prompted.
+package approval
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+const (
+	// DefaultTimeout is how long an approval can be decided and used by default
+	DefaultTimeout = 15 * time.Minute
+
+	// MaxApprovals is the number of approvals held at once
+	MaxApprovals = 100
+
+	idSize = 16
+)
+
+var (
+	logger = logging.MustGetLogger("approval")
+
+	// ErrNotFound is returned for an approval ID which was not issued, has expired or was used
+	ErrNotFound = errors.New("invalid or expired approval")
+	// ErrPending is returned when using an approval which was not decided yet
+	ErrPending = errors.New("the operation is pending approval")
+	// ErrRejected is returned when using an approval which was rejected
+	ErrRejected = errors.New("the operation was rejected by the approver")
+	// ErrDecided is returned when deciding an approval which was already decided
+	ErrDecided = errors.New("the approval was already decided")
+	// ErrMismatch is returned when using an approval for another operation than the approved one
+	ErrMismatch = errors.New("the approval was given for another operation")
+	// ErrTooMany is returned when requesting an approval while MaxApprovals approvals are held
+	ErrTooMany = errors.New("too many operations pending approval")
+)
+
+// State is the state of an approval
+type State string
+
+const (
+	// StatePending is the state of an approval waiting for the decision of the approver
+	StatePending State = "pending"
+	// StateApproved is the state of an approved approval, until the operation is performed
+	StateApproved State = "approved"
+	// StateRejected is the state of a rejected approval
+	StateRejected State = "rejected"
+)
+
+// Approval is an operation held for approval
+type Approval struct {
+	ID string `json:"id"`
+	// Operation names the operation, such as transaction_sign
+	Operation string `json:"operation"`
+	// Details are the parameters of the operation, reviewed by the approver
+	Details json.RawMessage `json:"details"`
+	State   State           `json:"state"`
+	// RequestID is the ID of the request of the operation
+	RequestID   string     `json:"request_id,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	// ExpiresAt is when the approval expires if it is not used, decided or not
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Manager holds the approvals of the operations
+type Manager struct {
+	timeout time.Duration
+
+	mu        sync.Mutex
+	approvals map[string]*Approval
+	now       func() time.Time
+}
+
+// NewManager creates a Manager whose approvals expire after timeout
+func NewManager(timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Manager{
+		timeout:   timeout,
+		approvals: make(map[string]*Approval),
+		now:       time.Now,
+	}
+}
+
+// Request holds the operation with its details for approval
+func (m *Manager) Request(operation string, details interface{}, requestID string) (Approval, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return Approval{}, err
+	}
+
+	b := make([]byte, idSize)
+	if _, err := rand.Read(b); err != nil {
+		return Approval{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+	if len(m.approvals) >= MaxApprovals {
+		return Approval{}, ErrTooMany
+	}
+
+	now := m.now().UTC()
+	a := &Approval{
+		ID:          hex.EncodeToString(b),
+		Operation:   operation,
+		Details:     data,
+		State:       StatePending,
+		RequestID:   requestID,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(m.timeout),
+	}
+	m.approvals[a.ID] = a
+
+	logger.Infof("Operation %s held for approval %s", operation, a.ID)
+
+	return *a, nil
+}
+
+// Approvals returns the approvals which were not used nor expired, in the order they were requested
+func (m *Manager) Approvals() []Approval {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+
+	approvals := make([]Approval, 0, len(m.approvals))
+	for _, a := range m.approvals {
+		approvals = append(approvals, *a)
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].RequestedAt.Before(approvals[j].RequestedAt)
+	})
+	return approvals
+}
+
+// Approve approves the pending approval with this id
+func (m *Manager) Approve(id string) (Approval, error) {
+	return m.decide(id, StateApproved)
+}
+
+// Reject rejects the pending approval with this id
+func (m *Manager) Reject(id string) (Approval, error) {
+	return m.decide(id, StateRejected)
+}
+
+// Use consumes the approval with this id for the operation with its details. The approval is used once the
+// operation is approved, or removed once it is rejected. An approval given for other details is kept.
+func (m *Manager) Use(id, operation string, details interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+
+	a, ok := m.approvals[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if a.Operation != operation || !bytes.Equal(a.Details, data) {
+		return ErrMismatch
+	}
+
+	switch a.State {
+	case StatePending:
+		return ErrPending
+	case StateRejected:
+		delete(m.approvals, id)
+		return ErrRejected
+	}
+
+	delete(m.approvals, id)
+	logger.Infof("Operation %s performed with approval %s", operation, id)
+	return nil
+}
+
+func (m *Manager) decide(id string, state State) (Approval, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+
+	a, ok := m.approvals[id]
+	if !ok {
+		return Approval{}, ErrNotFound
+	}
+	if a.State != StatePending {
+		return Approval{}, ErrDecided
+	}
+
+	now := m.now().UTC()
+	a.State = state
+	a.DecidedAt = &now
+
+	logger.Infof("Approval %s of operation %s %s", id, a.Operation, state)
+
+	return *a, nil
+}
+
+// prune removes the expired approvals, must be called with the lock held
+func (m *Manager) prune() {
+	now := m.now()
+	for id, a := range m.approvals {
+		if !now.Before(a.ExpiresAt) {
+			delete(m.approvals, id)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package approval
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testDetails struct {
+	Address string `json:"address"`
+	Coins   string `json:"coins"`
+}
+
+// newTestManager creates a Manager with a clock advanced by the returned function
+func newTestManager(timeout time.Duration) (*Manager, func(time.Duration)) {
+	m := NewManager(timeout)
+
+	var mu sync.Mutex
+	now := time.Date(2019, 7, 26, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	return m, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	m, advance := newTestManager(0)
+	details := testDetails{Address: "2M755W9o7933roLASK9PZTmqRsjQUsVen9y", Coins: "1000"}
+
+	a, err := m.Request("transaction_sign", details, "7f3c9a")
+	require.NoError(t, err)
+	require.Len(t, a.ID, 2*idSize)
+	require.Equal(t, "transaction_sign", a.Operation)
+	require.JSONEq(t, `{"address":"2M755W9o7933roLASK9PZTmqRsjQUsVen9y","coins":"1000"}`, string(a.Details))
+	require.Equal(t, StatePending, a.State)
+	require.Equal(t, "7f3c9a", a.RequestID)
+	require.Nil(t, a.DecidedAt)
+	require.Equal(t, a.RequestedAt.Add(DefaultTimeout), a.ExpiresAt)
+	require.Equal(t, []Approval{a}, m.Approvals())
+
+	require.Equal(t, ErrPending, m.Use(a.ID, "transaction_sign", details))
+	require.Equal(t, ErrNotFound, m.Use("foo", "transaction_sign", details))
+
+	advance(time.Minute)
+	approved, err := m.Approve(a.ID)
+	require.NoError(t, err)
+	require.Equal(t, StateApproved, approved.State)
+	require.Equal(t, a.RequestedAt.Add(time.Minute), *approved.DecidedAt)
+
+	_, err = m.Approve(a.ID)
+	require.Equal(t, ErrDecided, err)
+	_, err = m.Reject(a.ID)
+	require.Equal(t, ErrDecided, err)
+	_, err = m.Approve("foo")
+	require.Equal(t, ErrNotFound, err)
+
+	// the approval is only used for the approved operation
+	other := details
+	other.Coins = "2000"
+	require.Equal(t, ErrMismatch, m.Use(a.ID, "transaction_sign", other))
+	require.Equal(t, ErrMismatch, m.Use(a.ID, "sign_message", details))
+
+	require.NoError(t, m.Use(a.ID, "transaction_sign", details))
+	require.Equal(t, ErrNotFound, m.Use(a.ID, "transaction_sign", details))
+	require.Empty(t, m.Approvals())
+}
+
+func TestReject(t *testing.T) {
+	m, _ := newTestManager(0)
+	details := testDetails{Coins: "1000"}
+
+	a, err := m.Request("transaction_sign", details, "")
+	require.NoError(t, err)
+
+	rejected, err := m.Reject(a.ID)
+	require.NoError(t, err)
+	require.Equal(t, StateRejected, rejected.State)
+	require.Equal(t, []Approval{rejected}, m.Approvals())
+
+	require.Equal(t, ErrRejected, m.Use(a.ID, "transaction_sign", details))
+	require.Equal(t, ErrNotFound, m.Use(a.ID, "transaction_sign", details))
+	require.Empty(t, m.Approvals())
+}
+
+func TestExpiry(t *testing.T) {
+	m, advance := newTestManager(time.Minute)
+	details := testDetails{Coins: "1000"}
+
+	pending, err := m.Request("transaction_sign", details, "")
+	require.NoError(t, err)
+
+	advance(30 * time.Second)
+	approved, err := m.Request("transaction_sign", details, "")
+	require.NoError(t, err)
+	_, err = m.Approve(approved.ID)
+	require.NoError(t, err)
+
+	advance(30 * time.Second)
+	_, err = m.Approve(pending.ID)
+	require.Equal(t, ErrNotFound, err)
+	require.Len(t, m.Approvals(), 1)
+
+	// an approved operation must be performed before the approval expires
+	advance(30 * time.Second)
+	require.Equal(t, ErrNotFound, m.Use(approved.ID, "transaction_sign", details))
+	require.Empty(t, m.Approvals())
+}
+
+func TestTooMany(t *testing.T) {
+	m, advance := newTestManager(time.Minute)
+
+	for i := 0; i < MaxApprovals; i++ {
+		_, err := m.Request("transaction_sign", testDetails{}, "")
+		require.NoError(t, err)
+	}
+
+	_, err := m.Request("transaction_sign", testDetails{}, "")
+	require.Equal(t, ErrTooMany, err)
+
+	// the expired approvals make room for new ones
+	advance(time.Minute)
+	_, err = m.Request("transaction_sign", testDetails{}, "")
+	require.NoError(t, err)
+
+	_, err = m.Request("transaction_sign", json.RawMessage("{"), "")
+	require.Error(t, err)
+}
@@ -0,0 +1,361 @@
+// Package auditlog writes the security relevant operations of the device, such as wipes and transaction signatures,
+// to an append-only file, one JSON line per operation. With a key, each line carries an HMAC-SHA256 chained to the
+// previous line, so lines altered, removed or reordered after they were written are detected by Verify.
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of the operations
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	// OutcomeCancelled is an operation cancelled on the device, by a cancel request or by its client leaving
+	OutcomeCancelled = "cancelled"
+	// OutcomeAbandoned is an operation left waiting for the user input, another operation started instead
+	OutcomeAbandoned = "abandoned"
+	// OutcomePendingApproval is a transaction waiting for the approval of a second person, it is signed by a later request
+	OutcomePendingApproval = "pending_approval"
+)
+
+// keySize is the size of the generated keys
+const keySize = 32
+
+// ErrKeyMismatch is returned by Open when the key does not match the log: the existing lines are chained and
+// no key is given, or the other way around
+var ErrKeyMismatch = errors.New("the audit log key does not match the existing log, start a new log to add or remove the key")
+
+// Source is where the request of an operation came from
+type Source struct {
+	// IP is the IP of the client, or relay and native-messaging for the requests served through them
+	IP        string `json:"ip"`
+	Origin    string `json:"origin,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// Entry is an operation of the audit log
+type Entry struct {
+	// Seq is the sequence number of the entry, from 1
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Operation is wipe, recovery, pin_change, firmware_update, transaction_sign or partial_transaction_sign
+	Operation string `json:"operation"`
+	Method    string `json:"method"`
+	Endpoint  string `json:"endpoint"`
+	Source    Source `json:"source"`
+	// DeviceID is the ID of the device, empty if it could not be read
+	DeviceID  string `json:"device_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Outcome   string `json:"outcome"`
+	// Status is the status of the last response of the operation
+	Status int `json:"status"`
+	// Error is the error message of a failed operation
+	Error string `json:"error,omitempty"`
+}
+
+// line is a line of the file. The MAC is the hex encoded HMAC-SHA256 of the MAC of the previous line followed by
+// the entry bytes, as they appear in the line. The first line is chained to an empty MAC.
+type line struct {
+	Entry json.RawMessage `json:"entry"`
+	MAC   string          `json:"mac,omitempty"`
+}
+
+// Log appends entries to an audit log file
+type Log struct {
+	path string
+	key  []byte
+
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	mac  []byte
+	now  func() time.Time
+	// held is the operation waiting for the user input, written once it finishes
+	held *Entry
+}
+
+// Open opens or creates the audit log at path, appending to it. A nil key writes the entries without MAC.
+func Open(path string, key []byte) (*Log, error) {
+	seq, mac, err := lastLine(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty log can start with or without a key
+	if seq > 0 && (mac == nil) != (key == nil) {
+		return nil, ErrKeyMismatch
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{
+		path: path,
+		key:  key,
+		file: f,
+		seq:  seq,
+		mac:  mac,
+		now:  time.Now,
+	}, nil
+}
+
+// lastLine returns the sequence number and the MAC of the last line of the file at path, zero if it does not exist
+func lastLine(path string) (uint64, []byte, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	var last []byte
+	var n int
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for i := 1; s.Scan(); i++ {
+		if len(bytes.TrimSpace(s.Bytes())) > 0 {
+			last = append(last[:0], s.Bytes()...)
+			n = i
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to read audit log %s: %v", path, err)
+	}
+	if last == nil {
+		return 0, nil, nil
+	}
+
+	l, e, err := parseLine(last)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid audit log %s, line %d: %v", path, n, err)
+	}
+
+	var mac []byte
+	if l.MAC != "" {
+		mac, err = hex.DecodeString(l.MAC)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid audit log %s, line %d: invalid mac: %v", path, n, err)
+		}
+	}
+
+	return e.Seq, mac, nil
+}
+
+// parseLine parses a line of the file
+func parseLine(b []byte) (line, Entry, error) {
+	var l line
+	if err := json.Unmarshal(b, &l); err != nil {
+		return line{}, Entry{}, err
+	}
+
+	var e Entry
+	if err := json.Unmarshal(l.Entry, &e); err != nil {
+		return line{}, Entry{}, err
+	}
+
+	return l, e, nil
+}
+
+// Path returns the path of the file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Write appends e to the log, numbering it and setting its time if zero. The file is synced before Write returns.
+func (l *Log) Write(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.write(e)
+}
+
+// Hold holds e, an operation waiting for the user input such as the PIN, until it is released to be written once
+// finished. An operation already held is written as abandoned, the device runs a single operation.
+func (l *Log) Hold(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.abandon()
+	l.held = &e
+	return err
+}
+
+// Release returns the operation held, if any, and stops holding it
+func (l *Log) Release() (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held == nil {
+		return Entry{}, false
+	}
+	e := *l.held
+	l.held = nil
+	return e, true
+}
+
+// Abandon writes the operation held, if any, as abandoned
+func (l *Log) Abandon() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.abandon()
+}
+
+func (l *Log) abandon() error {
+	if l.held == nil {
+		return nil
+	}
+	e := *l.held
+	l.held = nil
+
+	e.Outcome = OutcomeAbandoned
+	return l.write(e)
+}
+
+func (l *Log) write(e Entry) error {
+	e.Seq = l.seq + 1
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+	e.Time = e.Time.UTC()
+
+	entry, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ln := line{
+		Entry: entry,
+	}
+	var mac []byte
+	if l.key != nil {
+		mac = chainMAC(l.key, l.mac, entry)
+		ln.MAC = hex.EncodeToString(mac)
+	}
+
+	b, err := json.Marshal(ln)
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+
+	l.seq = e.Seq
+	l.mac = mac
+	return nil
+}
+
+// Close writes the operation held, if any, as abandoned and closes the file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.abandon()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// chainMAC returns the MAC of an entry chained to prev
+func chainMAC(key, prev, entry []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(prev)  // nolint: errcheck
+	h.Write(entry) // nolint: errcheck
+	return h.Sum(nil)
+}
+
+// Verify checks the entries of the log read from r, returning their number. The entries must be numbered from 1
+// without gaps, and chained by their MAC with the key. A nil key only checks the numbering.
+// Lines removed from the end of the log cannot be detected, compare the number of entries with a copy kept elsewhere.
+func Verify(r io.Reader, key []byte) (int, error) {
+	var n int
+	var prev []byte
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for i := 1; s.Scan(); i++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+
+		l, e, err := parseLine(s.Bytes())
+		if err != nil {
+			return n, fmt.Errorf("line %d: %v", i, err)
+		}
+
+		if e.Seq != uint64(n+1) {
+			return n, fmt.Errorf("line %d: entry %d follows entry %d", i, e.Seq, n)
+		}
+
+		if key != nil {
+			mac, err := hex.DecodeString(l.MAC)
+			if err != nil || len(mac) == 0 {
+				return n, fmt.Errorf("line %d: missing or invalid mac", i)
+			}
+			if !hmac.Equal(mac, chainMAC(key, prev, l.Entry)) {
+				return n, fmt.Errorf("line %d: the mac does not match, the entry was altered or an entry before it removed", i)
+			}
+			prev = mac
+		}
+
+		n++
+	}
+	if err := s.Err(); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// LoadKey loads the hex encoded key of the file at path, generating it if the file does not exist
+func LoadKey(path string) ([]byte, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write audit log key: %v", err)
+		}
+		return key, nil
+	}
+
+	return ReadKey(path)
+}
+
+// ReadKey reads the hex encoded key of the file at path
+func ReadKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log key: %v", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid audit log key %s", path)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,248 @@
+package auditlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "auditlog")
+	require.NoError(t, err)
+	return dir
+}
+
+func writeEntries(t *testing.T, l *Log, operations ...string) {
+	for _, op := range operations {
+		require.NoError(t, l.Write(Entry{
+			Operation: op,
+			Method:    "POST",
+			Endpoint:  "/" + op,
+			Source: Source{
+				IP: "127.0.0.1",
+			},
+			DeviceID: "687576E45325EDC184C3B968",
+			Outcome:  OutcomeSuccess,
+			Status:   200,
+		}))
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func verify(lines []string, key []byte) (int, error) {
+	return Verify(strings.NewReader(strings.Join(lines, "\n")+"\n"), key)
+}
+
+func TestLog(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	key := []byte("key")
+
+	l, err := Open(path, key)
+	require.NoError(t, err)
+	l.now = func() time.Time {
+		return time.Date(2026, 10, 14, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	}
+	writeEntries(t, l, "wipe", "recovery")
+	require.NoError(t, l.Close())
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[0], `{"entry":{"seq":1,"time":"2026-10-14T10:00:00Z","operation":"wipe",`), lines[0])
+	require.Contains(t, lines[0], `"mac":"`)
+
+	// the log is appended to after a restart, continuing the chain
+	l, err = Open(path, key)
+	require.NoError(t, err)
+	writeEntries(t, l, "transaction_sign")
+	require.NoError(t, l.Close())
+
+	lines = readLines(t, path)
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[2], `"seq":3`)
+
+	n, err := verify(lines, key)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	// the numbering is checked without the key
+	n, err = verify(lines, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	n, err = verify(lines, []byte("other key"))
+	require.Error(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestVerifyTampering(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	key := []byte("key")
+
+	l, err := Open(path, key)
+	require.NoError(t, err)
+	writeEntries(t, l, "wipe", "pin_change", "transaction_sign")
+	require.NoError(t, l.Close())
+	lines := readLines(t, path)
+
+	cases := []struct {
+		name  string
+		lines []string
+		n     int
+		err   string
+	}{
+		{
+			name:  "altered entry",
+			lines: []string{lines[0], strings.Replace(lines[1], OutcomeSuccess, OutcomeFailure, 1), lines[2]},
+			n:     1,
+			err:   "line 2: the mac does not match",
+		},
+		{
+			name:  "removed entry",
+			lines: []string{lines[0], lines[2]},
+			n:     1,
+			err:   "line 2: entry 3 follows entry 1",
+		},
+		{
+			name:  "reordered entries",
+			lines: []string{lines[1], lines[0], lines[2]},
+			n:     0,
+			err:   "line 1: entry 2 follows entry 0",
+		},
+		{
+			name:  "removed mac",
+			lines: []string{lines[0], strings.Split(lines[1], `,"mac"`)[0] + "}", lines[2]},
+			n:     1,
+			err:   "line 2: missing or invalid mac",
+		},
+		{
+			name:  "invalid line",
+			lines: []string{lines[0], "{"},
+			n:     1,
+			err:   "line 2: unexpected end of JSON input",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := verify(tc.lines, key)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+			require.Equal(t, tc.n, n)
+		})
+	}
+}
+
+func TestHold(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	l, err := Open(path, nil)
+	require.NoError(t, err)
+
+	_, ok := l.Release()
+	require.False(t, ok)
+
+	// the signature waits for the PIN, and is written once the PIN is entered
+	require.NoError(t, l.Hold(Entry{Operation: "transaction_sign"}))
+	e, ok := l.Release()
+	require.True(t, ok)
+	require.Equal(t, "transaction_sign", e.Operation)
+	_, ok = l.Release()
+	require.False(t, ok)
+
+	e.Outcome = OutcomeSuccess
+	require.NoError(t, l.Write(e))
+
+	// the operations held are abandoned by the next one, and at close
+	require.NoError(t, l.Hold(Entry{Operation: "wipe"}))
+	require.NoError(t, l.Hold(Entry{Operation: "recovery"}))
+	require.NoError(t, l.Hold(Entry{Operation: "pin_change"}))
+	require.NoError(t, l.Abandon())
+	require.NoError(t, l.Abandon())
+	require.NoError(t, l.Hold(Entry{Operation: "firmware_update"}))
+	require.NoError(t, l.Close())
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 5)
+	for i, expected := range []string{
+		`"operation":"transaction_sign",.*"outcome":"success"`,
+		`"operation":"wipe",.*"outcome":"abandoned"`,
+		`"operation":"recovery",.*"outcome":"abandoned"`,
+		`"operation":"pin_change",.*"outcome":"abandoned"`,
+		`"operation":"firmware_update",.*"outcome":"abandoned"`,
+	} {
+		require.Regexp(t, expected, lines[i])
+	}
+}
+
+func TestOpenKeyMismatch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	chained := filepath.Join(dir, "chained.log")
+	l, err := Open(chained, []byte("key"))
+	require.NoError(t, err)
+	writeEntries(t, l, "wipe")
+	require.NoError(t, l.Close())
+
+	_, err = Open(chained, nil)
+	require.Equal(t, ErrKeyMismatch, err)
+
+	plain := filepath.Join(dir, "plain.log")
+	l, err = Open(plain, nil)
+	require.NoError(t, err)
+	writeEntries(t, l, "wipe")
+	require.NoError(t, l.Close())
+	require.NotContains(t, readLines(t, plain)[0], `"mac"`)
+
+	_, err = Open(plain, []byte("key"))
+	require.Equal(t, ErrKeyMismatch, err)
+
+	// a truncated last line, such as a write interrupted by a crash, is reported
+	require.NoError(t, ioutil.WriteFile(plain, append([]byte(readLines(t, plain)[0]+"\n"), `{"entry":{"seq":2`...), 0600))
+	_, err = Open(plain, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 2")
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.key")
+	key, err := LoadKey(path)
+	require.NoError(t, err)
+	require.Len(t, key, keySize)
+
+	loaded, err := LoadKey(path)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(key, loaded))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not hex\n"), 0600))
+	_, err = LoadKey(path)
+	require.Error(t, err)
+
+	// the key is not generated when only read
+	_, err = ReadKey(filepath.Join(dir, "missing.key"))
+	require.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "missing.key"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,198 @@
+// Package chaos injects transport faults between the daemon and the device, so wallet clients
+// can be tested against the failures users hit: a device unplugged in the middle of an operation,
+// a slow USB stack and corrupted frames. It is a developer tool, never enable it with funds at stake.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+var (
+	logger = logging.MustGetLogger("chaos")
+
+	// ErrDisconnected is returned by the reads and writes failed by an injected disconnect
+	ErrDisconnected = errors.New("chaos: device disconnected")
+)
+
+// Fault is a kind of injected fault
+type Fault string
+
+const (
+	// FaultDisconnect fails a device lookup, read or write as if the device was unplugged
+	FaultDisconnect Fault = "disconnect"
+	// FaultDelay delays a read from the device
+	FaultDelay Fault = "delay"
+	// FaultMalformed corrupts a frame read from the device
+	FaultMalformed Fault = "malformed"
+)
+
+// Config sets the probability of each fault, between 0 and 1, per device lookup, read or write
+type Config struct {
+	DisconnectRate float64
+	DelayRate      float64
+	// MaxDelay is the maximum delay of a read, the delay is random between 0 and MaxDelay
+	MaxDelay      time.Duration
+	MalformedRate float64
+	// Seed seeds the fault decisions, runs with the same seed and requests inject the same faults
+	Seed int64
+}
+
+// DefaultConfig returns the fault rates used when chaos is enabled without tuning them
+func DefaultConfig() Config {
+	return Config{
+		DisconnectRate: 0.02,
+		DelayRate:      0.1,
+		MaxDelay:       2 * time.Second,
+		MalformedRate:  0.02,
+		Seed:           time.Now().UnixNano(),
+	}
+}
+
+// Validate checks that the rates are probabilities
+func (c Config) Validate() error {
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"disconnect", c.DisconnectRate},
+		{"delay", c.DelayRate},
+		{"malformed", c.MalformedRate},
+	}
+
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			return fmt.Errorf("chaos %s rate must be between 0 and 1", r.name)
+		}
+	}
+
+	if c.MaxDelay < 0 {
+		return errors.New("chaos max delay must not be negative")
+	}
+
+	return nil
+}
+
+// Driver is a skyWallet.DeviceDriver injecting faults in the devices of the wrapped driver
+type Driver struct {
+	skyWallet.DeviceDriver
+	config Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	// sleep is replaced in tests
+	sleep func(time.Duration)
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps drv with fault injection
+func NewDriver(drv skyWallet.DeviceDriver, config Config) *Driver {
+	return &Driver{
+		DeviceDriver: drv,
+		config:       config,
+		rand:         rand.New(rand.NewSource(config.Seed)), // nolint: gosec
+		sleep:        time.Sleep,
+	}
+}
+
+// GetDevice returns the device of the wrapped driver, whose reads and writes inject faults.
+// An injected disconnect returns skyWallet.ErrNoDeviceConnected.
+func (d *Driver) GetDevice() (usb.Device, error) {
+	if d.inject(FaultDisconnect, d.config.DisconnectRate) {
+		return nil, skyWallet.ErrNoDeviceConnected
+	}
+
+	dev, err := d.DeviceDriver.GetDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	return &device{
+		Device: dev,
+		driver: d,
+	}, nil
+}
+
+// inject rolls the dice for fault and logs the injected one
+func (d *Driver) inject(fault Fault, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	injected := d.rand.Float64() < rate
+	d.mu.Unlock()
+
+	if injected {
+		logger.Warningf("Injecting %s fault", fault)
+	}
+	return injected
+}
+
+// delay returns a random delay, up to the configured maximum
+func (d *Driver) delay() time.Duration {
+	if d.config.MaxDelay <= 0 {
+		return 0
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return time.Duration(d.rand.Int63n(int64(d.config.MaxDelay) + 1))
+}
+
+// corrupt flips a random bit in a few random bytes of frame
+func (d *Driver) corrupt(frame []byte) {
+	if len(frame) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for n := 1 + d.rand.Intn(3); n > 0; n-- {
+		frame[d.rand.Intn(len(frame))] ^= 1 << uint(d.rand.Intn(8))
+	}
+}
+
+// device injects faults in the reads and writes of a usb.Device
+type device struct {
+	usb.Device
+	driver *Driver
+}
+
+func (dev *device) Write(p []byte) (int, error) {
+	if dev.driver.inject(FaultDisconnect, dev.driver.config.DisconnectRate) {
+		return 0, ErrDisconnected
+	}
+
+	return dev.Device.Write(p)
+}
+
+func (dev *device) Read(p []byte) (int, error) {
+	if dev.driver.inject(FaultDisconnect, dev.driver.config.DisconnectRate) {
+		return 0, ErrDisconnected
+	}
+
+	if dev.driver.inject(FaultDelay, dev.driver.config.DelayRate) {
+		dev.driver.sleep(dev.driver.delay())
+	}
+
+	n, err := dev.Device.Read(p)
+	if err != nil {
+		return n, err
+	}
+
+	if dev.driver.inject(FaultMalformed, dev.driver.config.MalformedRate) {
+		dev.driver.corrupt(p[:n])
+	}
+
+	return n, nil
+}
@@ -0,0 +1,188 @@
+package chaos
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// fakeDevice returns the same frame on every read
+type fakeDevice struct {
+	frame   []byte
+	written bytes.Buffer
+}
+
+func (d *fakeDevice) Read(p []byte) (int, error) {
+	return copy(p, d.frame), nil
+}
+
+func (d *fakeDevice) Write(p []byte) (int, error) {
+	return d.written.Write(p)
+}
+
+func (d *fakeDevice) Close(disconnected bool) error {
+	return nil
+}
+
+type fakeDriver struct {
+	dev *fakeDevice
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return wire.Message{}, nil
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return nil
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return d.dev, nil
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeUSB
+}
+
+func (d *fakeDriver) Close() {}
+
+func newFakeDriver() *fakeDriver {
+	frame := make([]byte, 64)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+
+	return &fakeDriver{
+		dev: &fakeDevice{
+			frame: frame,
+		},
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	require.NoError(t, DefaultConfig().Validate())
+	require.NoError(t, Config{}.Validate())
+
+	require.EqualError(t, Config{DisconnectRate: 1.5}.Validate(), "chaos disconnect rate must be between 0 and 1")
+	require.EqualError(t, Config{DelayRate: -0.1}.Validate(), "chaos delay rate must be between 0 and 1")
+	require.EqualError(t, Config{MalformedRate: 2}.Validate(), "chaos malformed rate must be between 0 and 1")
+	require.EqualError(t, Config{MaxDelay: -time.Second}.Validate(), "chaos max delay must not be negative")
+}
+
+func TestNoFaults(t *testing.T) {
+	fake := newFakeDriver()
+	drv := NewDriver(fake, Config{})
+
+	dev, err := drv.GetDevice()
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		p := make([]byte, 64)
+		n, err := dev.Read(p)
+		require.NoError(t, err)
+		require.Equal(t, fake.dev.frame, p[:n])
+
+		_, err = dev.Write([]byte{byte(i)})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 100, fake.dev.written.Len())
+
+	require.Equal(t, skyWallet.DeviceTypeUSB, drv.DeviceType())
+}
+
+func TestDisconnect(t *testing.T) {
+	fake := newFakeDriver()
+	drv := NewDriver(fake, Config{
+		DisconnectRate: 1,
+	})
+
+	_, err := drv.GetDevice()
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	dev := &device{
+		Device: fake.dev,
+		driver: drv,
+	}
+
+	_, err = dev.Read(make([]byte, 64))
+	require.Equal(t, ErrDisconnected, err)
+
+	_, err = dev.Write([]byte{1})
+	require.Equal(t, ErrDisconnected, err)
+	require.Zero(t, fake.dev.written.Len())
+}
+
+func TestDelay(t *testing.T) {
+	fake := newFakeDriver()
+	drv := NewDriver(fake, Config{
+		DelayRate: 1,
+		MaxDelay:  time.Second,
+	})
+
+	var delays []time.Duration
+	drv.sleep = func(d time.Duration) {
+		delays = append(delays, d)
+	}
+
+	dev, err := drv.GetDevice()
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		p := make([]byte, 64)
+		n, err := dev.Read(p)
+		require.NoError(t, err)
+		require.Equal(t, fake.dev.frame, p[:n])
+	}
+
+	require.Len(t, delays, 10)
+	for _, d := range delays {
+		require.True(t, d >= 0 && d <= time.Second, d)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	fake := newFakeDriver()
+	drv := NewDriver(fake, Config{
+		MalformedRate: 1,
+	})
+
+	dev, err := drv.GetDevice()
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		p := make([]byte, 64)
+		n, err := dev.Read(p)
+		require.NoError(t, err)
+		require.Equal(t, len(fake.dev.frame), n)
+		require.NotEqual(t, fake.dev.frame, p[:n])
+	}
+}
+
+func TestSeed(t *testing.T) {
+	faults := func(seed int64) []bool {
+		drv := NewDriver(newFakeDriver(), Config{
+			DisconnectRate: 0.5,
+			Seed:           seed,
+		})
+
+		var injected []bool
+		for i := 0; i < 50; i++ {
+			_, err := drv.GetDevice()
+			injected = append(injected, err != nil)
+		}
+		return injected
+	}
+
+	require.Equal(t, faults(42), faults(42))
+	require.NotEqual(t, faults(42), faults(43))
+}
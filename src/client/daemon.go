@@ -0,0 +1,175 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+const (
+	// ModelVersion is the model version of the responses the Client decodes, sent in the X-Model-Version header
+	// so the responses keep their shape when the daemon models change
+	ModelVersion = 4
+
+	apiPath = "/api/v2"
+
+	csrfHeaderName         = "X-CSRF-Token"
+	modelVersionHeaderName = "X-Model-Version"
+	sessionHeaderName      = "X-Session-Id"
+)
+
+// Config configures a Client
+type Config struct {
+	// Addr is the host:port of the daemon, defaults to DefaultHost
+	Addr string
+	// HTTPClient sends the requests, defaults to http.DefaultClient. Its timeout must leave the user the time to
+	// confirm on the device, bound the operations with their context instead.
+	HTTPClient *http.Client
+	// Prompter answers the PIN, passphrase and word requests of the device, and is told about its button requests.
+	// Nil fails the operations requesting a PIN, a passphrase or a word with ErrNoPrompter.
+	Prompter Prompter
+	// SessionID is sent in the X-Session-Id header, for the daemons requiring a session
+	SessionID string
+}
+
+// Client calls the API of a daemon. The device operations run the intermediate requests of the device,
+// such as entering the PIN, to completion with the Prompter.
+type Client struct {
+	config Config
+
+	csrfMu       sync.Mutex
+	csrfDisabled bool
+}
+
+// NewClient creates a Client
+func NewClient(c Config) *Client {
+	if c.Addr == "" {
+		c.Addr = DefaultHost
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+
+	return &Client{
+		config: c,
+	}
+}
+
+// response is the envelope of the responses of the daemon
+type response struct {
+	Data  json.RawMessage `json:"data"`
+	Error *Error          `json:"error"`
+}
+
+// do sends a request to the endpoint and decodes the data of the response into data, if not nil.
+// The mutating requests carry a CSRF token when the daemon checks them.
+func (c *Client) do(ctx context.Context, method, endpoint string, query url.Values, body, data interface{}) error {
+	u := "http://" + c.config.Addr + apiPath + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set(modelVersionHeaderName, strconv.Itoa(ModelVersion))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.SessionID != "" {
+		req.Header.Set(sessionHeaderName, c.config.SessionID)
+	}
+
+	if method != http.MethodGet {
+		token, err := c.csrfToken(ctx)
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			req.Header.Set(csrfHeaderName, token)
+		}
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var rsp response
+	if err := json.Unmarshal(b, &rsp); err != nil {
+		if resp.StatusCode >= 400 {
+			return &Error{
+				StatusCode: resp.StatusCode,
+				Message:    http.StatusText(resp.StatusCode),
+			}
+		}
+		return fmt.Errorf("invalid response of %s %s: %v", method, endpoint, err)
+	}
+
+	if resp.StatusCode >= 400 || rsp.Error != nil {
+		if rsp.Error == nil {
+			rsp.Error = &Error{
+				Message: http.StatusText(resp.StatusCode),
+			}
+		}
+		rsp.Error.StatusCode = resp.StatusCode
+		return rsp.Error
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(rsp.Data, data); err != nil {
+		return fmt.Errorf("invalid data of %s %s: %v", method, endpoint, err)
+	}
+	return nil
+}
+
+// csrfToken returns a new CSRF token, empty once the daemon was found to not check them
+func (c *Client) csrfToken(ctx context.Context) (string, error) {
+	c.csrfMu.Lock()
+	disabled := c.csrfDisabled
+	c.csrfMu.Unlock()
+	if disabled {
+		return "", nil
+	}
+
+	var token string
+	if err := c.do(ctx, http.MethodGet, "/csrf", nil, nil, &token); err != nil {
+		if e, ok := err.(*Error); ok && e.StatusCode == http.StatusNotFound {
+			c.csrfMu.Lock()
+			c.csrfDisabled = true
+			c.csrfMu.Unlock()
+			return "", nil
+		}
+		return "", err
+	}
+
+	return token, nil
+}
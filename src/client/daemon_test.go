@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/api"
+)
+
+type testPrompter struct {
+	pin        string
+	passphrase string
+	err        error
+	buttons    int
+}
+
+func (p *testPrompter) PinMatrix(ctx context.Context) (string, error) {
+	return p.pin, p.err
+}
+
+func (p *testPrompter) Passphrase(ctx context.Context) (string, error) {
+	return p.passphrase, p.err
+}
+
+func (p *testPrompter) Word(ctx context.Context) (string, error) {
+	return "", p.err
+}
+
+func (p *testPrompter) Button(ctx context.Context) error {
+	p.buttons++
+	return nil
+}
+
+// testDaemon serves the sign message flow of a device protected by a PIN and a passphrase
+type testDaemon struct {
+	t    *testing.T
+	csrf bool
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (d *testDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	d.requests = append(d.requests, r.Method+" "+r.URL.Path)
+	d.mu.Unlock()
+
+	require.Equal(d.t, "4", r.Header.Get(api.ModelVersionHeaderName))
+
+	write := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		require.NoError(d.t, json.NewEncoder(w).Encode(v))
+	}
+	data := func(v interface{}) {
+		write(http.StatusOK, map[string]interface{}{"data": v})
+	}
+
+	if r.URL.Path == "/api/v2/csrf" {
+		if !d.csrf {
+			write(http.StatusNotFound, map[string]interface{}{"error": map[string]interface{}{"code": 404, "message": "Not Found"}})
+			return
+		}
+		data("token")
+		return
+	}
+
+	if r.Method != http.MethodGet && d.csrf {
+		require.Equal(d.t, "token", r.Header.Get(api.CSRFHeaderName))
+	}
+
+	var body map[string]string
+	if r.Body != nil && r.Method == http.MethodPost {
+		json.NewDecoder(r.Body).Decode(&body) // nolint: errcheck
+	}
+
+	switch r.URL.Path {
+	case "/api/v2/version":
+		data(map[string]string{"version": "0.1.0"})
+	case "/api/v2/sign_message":
+		data([]string{"PinMatrixRequest"})
+	case "/api/v2/intermediate/pin_matrix":
+		if body["pin"] != "1234" {
+			write(http.StatusConflict, map[string]interface{}{"error": map[string]interface{}{
+				"code":       409,
+				"message":    "PIN invalid",
+				"category":   CategoryPinInvalid,
+				"request_id": "7f3c9a",
+			}})
+			return
+		}
+		data([]string{"PassPhraseRequest"})
+	case "/api/v2/intermediate/passphrase":
+		require.Equal(d.t, "secret", body["passphrase"])
+		data([]string{"ButtonRequest"})
+	case "/api/v2/intermediate/button":
+		data([]string{"signature"})
+	case "/api/v2/features":
+		// blocks until the client leaves
+		<-r.Context().Done()
+	default:
+		write(http.StatusNotFound, map[string]interface{}{"error": map[string]interface{}{"code": 404, "message": "Not Found"}})
+	}
+}
+
+func newTestClient(t *testing.T, csrf bool, p Prompter) (*Client, *testDaemon, *httptest.Server) {
+	d := &testDaemon{
+		t:    t,
+		csrf: csrf,
+	}
+	s := httptest.NewServer(d)
+
+	return NewClient(Config{
+		Addr:     strings.TrimPrefix(s.URL, "http://"),
+		Prompter: p,
+	}), d, s
+}
+
+func TestSignMessageFlow(t *testing.T) {
+	for _, csrf := range []bool{false, true} {
+		p := &testPrompter{
+			pin:        "1234",
+			passphrase: "secret",
+		}
+		c, d, s := newTestClient(t, csrf, p)
+		defer s.Close()
+
+		signature, err := c.SignMessage(context.Background(), 0, "hello")
+		require.NoError(t, err)
+		require.Equal(t, "signature", signature)
+		require.Equal(t, 1, p.buttons)
+
+		requests := []string{
+			"POST /api/v2/sign_message",
+			"POST /api/v2/intermediate/pin_matrix",
+			"POST /api/v2/intermediate/passphrase",
+			"POST /api/v2/intermediate/button",
+		}
+		if csrf {
+			var withCSRF []string
+			for _, r := range requests {
+				withCSRF = append(withCSRF, "GET /api/v2/csrf", r)
+			}
+			requests = withCSRF
+		} else {
+			// the client stops asking for a token once the daemon has no CSRF check
+			requests = append([]string{"GET /api/v2/csrf"}, requests...)
+		}
+		require.Equal(t, requests, d.requests)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _, s := newTestClient(t, false, &testPrompter{
+		pin: "0000",
+	})
+	defer s.Close()
+
+	_, err := c.SignMessage(context.Background(), 0, "hello")
+	require.Equal(t, &Error{
+		StatusCode: http.StatusConflict,
+		Message:    "PIN invalid",
+		Category:   CategoryPinInvalid,
+		RequestID:  "7f3c9a",
+	}, err)
+	require.True(t, IsCategory(err, CategoryPinInvalid))
+	require.False(t, IsCategory(errors.New("PIN invalid"), CategoryPinInvalid))
+	require.Equal(t, "409 pin_invalid: PIN invalid", err.Error())
+
+	// the device requests a PIN and the client has no prompter
+	c, _, s = newTestClient(t, false, nil)
+	defer s.Close()
+	_, err = c.SignMessage(context.Background(), 0, "hello")
+	require.Equal(t, ErrNoPrompter, err)
+
+	// the prompter gives up
+	promptErr := errors.New("PIN entry cancelled")
+	c, _, s = newTestClient(t, false, &testPrompter{
+		err: promptErr,
+	})
+	defer s.Close()
+	_, err = c.SignMessage(context.Background(), 0, "hello")
+	require.Equal(t, promptErr, err)
+}
+
+func TestContext(t *testing.T) {
+	c, _, s := newTestClient(t, false, nil)
+	defer s.Close()
+
+	v, err := c.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "0.1.0", v.Version)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.Features(ctx)
+	require.Error(t, err)
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestCategories(t *testing.T) {
+	// the categories of the client are the categories of the daemon
+	for category, apiCategory := range map[string]string{
+		CategoryInvalidRequest:     api.ErrorCategoryInvalidRequest,
+		CategoryRateLimited:        api.ErrorCategoryRateLimited,
+		CategoryCSRFInvalid:        api.ErrorCategoryCSRFInvalid,
+		CategoryCSRFExpired:        api.ErrorCategoryCSRFExpired,
+		CategoryDeviceDisconnected: api.ErrorCategoryDeviceDisconnected,
+		CategoryDeviceTimeout:      api.ErrorCategoryDeviceTimeout,
+		CategoryDeviceLocked:       api.ErrorCategoryDeviceLocked,
+		CategoryShuttingDown:       api.ErrorCategoryShuttingDown,
+		CategoryActionCancelled:    api.ErrorCategoryActionCancelled,
+		CategoryPinInvalid:         api.ErrorCategoryPinInvalid,
+		CategoryPinMismatch:        api.ErrorCategoryPinMismatch,
+		CategoryNotInitialized:     api.ErrorCategoryNotInitialized,
+		CategoryDeviceFailure:      api.ErrorCategoryDeviceFailure,
+	} {
+		require.Equal(t, apiCategory, category)
+	}
+
+	require.Equal(t, api.ModelVersion, ModelVersion)
+	require.Equal(t, api.CSRFHeaderName, csrfHeaderName)
+	require.Equal(t, api.SessionHeaderName, sessionHeaderName)
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/models"
+)
+
+// TransactionInput is an input of a transaction to sign
+type TransactionInput struct {
+	// Hash is the hash of the unspent output spent by the input
+	Hash string `json:"hash"`
+	// Index is the index of the address of the wallet owning the unspent output
+	Index uint32 `json:"index"`
+}
+
+// TransactionOutput is an output of a transaction to sign
+type TransactionOutput struct {
+	Address string `json:"address"`
+	// AddressIndex is the index of the address if it belongs to the wallet, such as a change output, nil otherwise
+	AddressIndex *uint32 `json:"address_index"`
+	// Coins and Hours are decimal strings, the coins with up to 6 decimals
+	Coins string `json:"coins"`
+	Hours string `json:"hours"`
+}
+
+// Version returns the build of the daemon
+func (c *Client) Version(ctx context.Context) (*models.VersionResponseData, error) {
+	var v models.VersionResponseData
+	if err := c.do(ctx, http.MethodGet, "/version", nil, nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Features returns the features of the device
+func (c *Client) Features(ctx context.Context) (*models.FeaturesResponseData, error) {
+	var f models.FeaturesResponseData
+	if err := c.operation(ctx, http.MethodGet, "/features", nil, nil, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GenerateAddresses returns n addresses of the wallet from startIndex, confirmed on the device if confirm is true
+func (c *Client) GenerateAddresses(ctx context.Context, n, startIndex int, confirm bool) ([]string, error) {
+	var addresses []string
+	if err := c.operation(ctx, http.MethodPost, "/generate_addresses", nil, map[string]interface{}{
+		"address_n":       n,
+		"start_index":     startIndex,
+		"confirm_address": confirm,
+	}, &addresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// ApplySettings sets the label, the language and the passphrase protection of the device
+func (c *Client) ApplySettings(ctx context.Context, label, language string, usePassphrase bool) (string, error) {
+	return c.message(ctx, http.MethodPost, "/apply_settings", nil, map[string]interface{}{
+		"label":          label,
+		"language":       language,
+		"use_passphrase": usePassphrase,
+	})
+}
+
+// Backup shows the seed of the device on its screen for the user to write it down
+func (c *Client) Backup(ctx context.Context) (string, error) {
+	return c.message(ctx, http.MethodPost, "/backup", nil, nil)
+}
+
+// Cancel cancels the operation in progress on the device
+func (c *Client) Cancel(ctx context.Context) (string, error) {
+	return c.message(ctx, http.MethodPut, "/cancel", nil, nil)
+}
+
+// CheckMessageSignature checks the signature of message by address
+func (c *Client) CheckMessageSignature(ctx context.Context, address, message, signature string) (string, error) {
+	return c.message(ctx, http.MethodPost, "/check_message_signature", nil, map[string]string{
+		"address":   address,
+		"message":   message,
+		"signature": signature,
+	})
+}
+
+// GenerateMnemonic generates the seed of the device, of 12 or 24 words
+func (c *Client) GenerateMnemonic(ctx context.Context, wordCount int, usePassphrase bool) (*models.GenerateMnemonicResponseData, error) {
+	var m models.GenerateMnemonicResponseData
+	if err := c.operation(ctx, http.MethodPost, "/generate_mnemonic", nil, map[string]interface{}{
+		"word_count":     wordCount,
+		"use_passphrase": usePassphrase,
+	}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Recovery recovers the seed of the device from its words, entered with the Prompter, or only checks them if dryRun
+func (c *Client) Recovery(ctx context.Context, wordCount int, usePassphrase, dryRun bool) (string, error) {
+	return c.message(ctx, http.MethodPost, "/recovery", nil, map[string]interface{}{
+		"word_count":     wordCount,
+		"use_passphrase": usePassphrase,
+		"dry_run":        dryRun,
+	})
+}
+
+// SetMnemonic sets the seed of the device
+func (c *Client) SetMnemonic(ctx context.Context, mnemonic string) (string, error) {
+	return c.message(ctx, http.MethodPost, "/set_mnemonic", nil, map[string]string{
+		"mnemonic": mnemonic,
+	})
+}
+
+// ConfigurePinCode sets or changes the PIN of the device, entered with the Prompter, or removes it if removePin
+func (c *Client) ConfigurePinCode(ctx context.Context, removePin bool) (string, error) {
+	return c.message(ctx, http.MethodPost, "/configure_pin_code", nil, map[string]bool{
+		"remove_pin": removePin,
+	})
+}
+
+// SignMessage signs message with the address of the wallet at addressN
+func (c *Client) SignMessage(ctx context.Context, addressN int, message string) (string, error) {
+	return c.message(ctx, http.MethodPost, "/sign_message", nil, map[string]interface{}{
+		"address_n": addressN,
+		"message":   message,
+	})
+}
+
+// TransactionSign signs the inputs of a transaction, returning a signature per input
+func (c *Client) TransactionSign(ctx context.Context, inputs []TransactionInput, outputs []TransactionOutput) ([]string, error) {
+	var signatures []string
+	if err := c.operation(ctx, http.MethodPost, "/transaction_sign", nil, map[string]interface{}{
+		"transaction_inputs":  inputs,
+		"transaction_outputs": outputs,
+	}, &signatures); err != nil {
+		return nil, err
+	}
+	return signatures, nil
+}
+
+// Wipe wipes the device, getting the confirmation token of the wipe first
+func (c *Client) Wipe(ctx context.Context) (string, error) {
+	var confirmation models.WipeConfirmationResponseData
+	if err := c.do(ctx, http.MethodDelete, "/wipe", nil, nil, &confirmation); err != nil {
+		return "", err
+	}
+
+	return c.message(ctx, http.MethodDelete, "/wipe", url.Values{
+		"confirmation_token": []string{confirmation.ConfirmationToken},
+	}, nil)
+}
+
+// message runs a device operation returning a message, such as the success message of the device
+func (c *Client) message(ctx context.Context, method, endpoint string, query url.Values, body interface{}) (string, error) {
+	var msgs []string
+	if err := c.operation(ctx, method, endpoint, query, body, &msgs); err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "", errors.New("empty response of " + endpoint)
+	}
+	return msgs[0], nil
+}
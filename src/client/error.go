@@ -0,0 +1,50 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Categories of the errors of the daemon, the Category of an Error. See the API documentation for the others.
+const (
+	CategoryInvalidRequest     = "invalid_request"
+	CategoryRateLimited        = "rate_limited"
+	CategoryCSRFInvalid        = "csrf_invalid"
+	CategoryCSRFExpired        = "csrf_expired"
+	CategoryDeviceDisconnected = "device_disconnected"
+	CategoryDeviceTimeout      = "device_timeout"
+	CategoryDeviceLocked       = "device_locked"
+	CategoryShuttingDown       = "shutting_down"
+	CategoryActionCancelled    = "action_cancelled"
+	CategoryPinInvalid         = "pin_invalid"
+	CategoryPinMismatch        = "pin_mismatch"
+	CategoryNotInitialized     = "not_initialized"
+	CategoryDeviceFailure      = "device_failure"
+)
+
+// ErrNoPrompter is returned when the device requests a PIN, a passphrase or a word and the Client has no Prompter
+var ErrNoPrompter = errors.New("the device requested an input and the client has no prompter")
+
+// Error is an error response of the daemon
+type Error struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int    `json:"code"`
+	Message    string `json:"message"`
+	// Category is the machine-readable category of the error, such as pin_invalid
+	Category string `json:"category"`
+	// RequestID is the ID of the request in the logs of the daemon
+	RequestID string `json:"request_id"`
+}
+
+func (e *Error) Error() string {
+	if e.Category == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Category, e.Message)
+}
+
+// IsCategory returns true if err is an error response of the daemon in the category
+func IsCategory(err error, category string) bool {
+	e, ok := err.(*Error)
+	return ok && e.Category == category
+}
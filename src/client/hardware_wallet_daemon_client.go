@@ -23,7 +23,7 @@ const (
 	DefaultHost string = "127.0.0.1:9510"
 	// DefaultBasePath is the default BasePath
 	// found in Meta (info) section of spec file
-	DefaultBasePath string = "/api/v1"
+	DefaultBasePath string = "/api/v2"
 )
 
 // DefaultSchemes are the default schemes found in Meta (info) section of spec file
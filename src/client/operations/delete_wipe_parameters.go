@@ -60,6 +60,13 @@ func NewDeleteWipeParamsWithHTTPClient(client *http.Client) *DeleteWipeParams {
 for the delete wipe operation typically these are written to a http.Request
 */
 type DeleteWipeParams struct {
+
+	/*ConfirmationToken
+	  token returned by the first call, the device is wiped only when it is valid
+
+	*/
+	ConfirmationToken *string
+
 	timeout    time.Duration
 	Context    context.Context
 	HTTPClient *http.Client
@@ -98,6 +105,17 @@ func (o *DeleteWipeParams) SetHTTPClient(client *http.Client) {
 	o.HTTPClient = client
 }
 
+// WithConfirmationToken adds the confirmationToken to the delete wipe params
+func (o *DeleteWipeParams) WithConfirmationToken(confirmationToken *string) *DeleteWipeParams {
+	o.SetConfirmationToken(confirmationToken)
+	return o
+}
+
+// SetConfirmationToken adds the confirmationToken to the delete wipe params
+func (o *DeleteWipeParams) SetConfirmationToken(confirmationToken *string) {
+	o.ConfirmationToken = confirmationToken
+}
+
 // WriteToRequest writes these params to a swagger request
 func (o *DeleteWipeParams) WriteToRequest(r runtime.ClientRequest, reg strfmt.Registry) error {
 
@@ -106,6 +124,22 @@ func (o *DeleteWipeParams) WriteToRequest(r runtime.ClientRequest, reg strfmt.Re
 	}
 	var res []error
 
+	if o.ConfirmationToken != nil {
+
+		// query param confirmation_token
+		var qrConfirmationToken string
+		if o.ConfirmationToken != nil {
+			qrConfirmationToken = *o.ConfirmationToken
+		}
+		qConfirmationToken := qrConfirmationToken
+		if qConfirmationToken != "" {
+			if err := r.SetQueryParam("confirmation_token", qConfirmationToken); err != nil {
+				return err
+			}
+		}
+
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -31,6 +31,13 @@ func (o *DeleteWipeReader) ReadResponse(response runtime.ClientResponse, consume
 		}
 		return result, nil
 
+	case 202:
+		result := NewDeleteWipeAccepted()
+		if err := result.readResponse(response, consumer, o.formats); err != nil {
+			return nil, err
+		}
+		return result, nil
+
 	default:
 		result := NewDeleteWipeDefault(response.Code())
 		if err := result.readResponse(response, consumer, o.formats); err != nil {
@@ -68,6 +75,31 @@ func (o *DeleteWipeOK) readResponse(response runtime.ClientResponse, consumer ru
 	return nil
 }
 
+// NewDeleteWipeAccepted creates a DeleteWipeAccepted with default headers values
+func NewDeleteWipeAccepted() *DeleteWipeAccepted {
+	return &DeleteWipeAccepted{}
+}
+
+/*DeleteWipeAccepted handles this case with default header values.
+
+confirmation required, call again with the confirmation token
+*/
+type DeleteWipeAccepted struct {
+	Payload *models.WipeConfirmationResponse
+}
+
+func (o *DeleteWipeAccepted) readResponse(response runtime.ClientResponse, consumer runtime.Consumer, formats strfmt.Registry) error {
+
+	o.Payload = new(models.WipeConfirmationResponse)
+
+	// response payload
+	if err := consumer.Consume(response.Body(), o.Payload); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
 // NewDeleteWipeDefault creates a DeleteWipeDefault with default headers values
 func NewDeleteWipeDefault(code int) *DeleteWipeDefault {
 	return &DeleteWipeDefault{
@@ -25,9 +25,9 @@ type Client struct {
 }
 
 /*
-DeleteWipe clean all the configurations.
+DeleteWipe clean all the configurations. The first call returns a one-time confirmation token, the device is only wiped by a second call sending back that token before it expires.
 */
-func (a *Client) DeleteWipe(params *DeleteWipeParams, authInfo runtime.ClientAuthInfoWriter) (*DeleteWipeOK, error) {
+func (a *Client) DeleteWipe(params *DeleteWipeParams, authInfo runtime.ClientAuthInfoWriter) (*DeleteWipeOK, *DeleteWipeAccepted, error) {
 	// TODO: Validate the params before sending
 	if params == nil {
 		params = NewDeleteWipeParams()
@@ -47,9 +47,15 @@ func (a *Client) DeleteWipe(params *DeleteWipeParams, authInfo runtime.ClientAut
 		Client:             params.HTTPClient,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	switch value := result.(type) {
+	case *DeleteWipeOK:
+		return value, nil, nil
+	case *DeleteWipeAccepted:
+		return nil, value, nil
 	}
-	return result.(*DeleteWipeOK), nil
+	return nil, nil, nil
 
 }
 
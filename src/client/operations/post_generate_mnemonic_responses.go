@@ -53,12 +53,12 @@ func NewPostGenerateMnemonicOK() *PostGenerateMnemonicOK {
 successful operation
 */
 type PostGenerateMnemonicOK struct {
-	Payload *models.HttpsuccessResponse
+	Payload *models.GenerateMnemonicResponse
 }
 
 func (o *PostGenerateMnemonicOK) readResponse(response runtime.ClientResponse, consumer runtime.Consumer, formats strfmt.Registry) error {
 
-	o.Payload = new(models.HttpsuccessResponse)
+	o.Payload = new(models.GenerateMnemonicResponse)
 
 	// response payload
 	if err := consumer.Consume(response.Body(), o.Payload); err != nil && err != io.EOF {
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// The intermediate requests of the device, returned by the device endpoints until the operation completes
+const (
+	pinMatrixRequest  = "PinMatrixRequest"
+	passphraseRequest = "PassPhraseRequest"
+	wordRequest       = "WordRequest"
+	buttonRequest     = "ButtonRequest"
+)
+
+// Prompter answers the intermediate requests of the device during an operation
+type Prompter interface {
+	// PinMatrix returns the PIN, each digit being the position of the digit in the matrix shown on the device
+	PinMatrix(ctx context.Context) (string, error)
+	// Passphrase returns the passphrase of the wallet
+	Passphrase(ctx context.Context) (string, error)
+	// Word returns the word of the seed requested on the device during a recovery
+	Word(ctx context.Context) (string, error)
+	// Button is called when the device waits for the user to confirm on it, the operation continues once it returns
+	Button(ctx context.Context) error
+}
+
+// operation runs a device operation to completion, answering the intermediate requests of the device with the
+// Prompter, and decodes the data of its last response into data
+func (c *Client) operation(ctx context.Context, method, endpoint string, query url.Values, body, data interface{}) error {
+	var raw json.RawMessage
+	if err := c.do(ctx, method, endpoint, query, body, &raw); err != nil {
+		return err
+	}
+
+	for {
+		next, nextBody, err := c.prompt(ctx, raw)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			break
+		}
+
+		raw = nil
+		if err := c.do(ctx, http.MethodPost, next, nil, nextBody, &raw); err != nil {
+			return err
+		}
+	}
+
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, data)
+}
+
+// prompt returns the intermediate endpoint answering the intermediate request of the device in raw and its body,
+// an empty endpoint if raw is the result of the operation
+func (c *Client) prompt(ctx context.Context, raw json.RawMessage) (string, interface{}, error) {
+	var msgs []string
+	if err := json.Unmarshal(raw, &msgs); err != nil || len(msgs) != 1 {
+		return "", nil, nil
+	}
+
+	p := c.config.Prompter
+
+	switch msgs[0] {
+	case pinMatrixRequest:
+		if p == nil {
+			return "", nil, ErrNoPrompter
+		}
+		pin, err := p.PinMatrix(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		return "/intermediate/pin_matrix", map[string]string{"pin": pin}, nil
+
+	case passphraseRequest:
+		if p == nil {
+			return "", nil, ErrNoPrompter
+		}
+		passphrase, err := p.Passphrase(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		return "/intermediate/passphrase", map[string]string{"passphrase": passphrase}, nil
+
+	case wordRequest:
+		if p == nil {
+			return "", nil, ErrNoPrompter
+		}
+		word, err := p.Word(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		return "/intermediate/word", map[string]string{"word": word}, nil
+
+	case buttonRequest:
+		if p != nil {
+			if err := p.Button(ctx); err != nil {
+				return "", nil, err
+			}
+		}
+		return "/intermediate/button", nil, nil
+
+	default:
+		return "", nil, nil
+	}
+}
@@ -0,0 +1,92 @@
+// Package coin holds the registry of the coin backends deriving the addresses and signing with the device.
+// A backend of a fiber coin registers itself in the init function of its package, and is linked
+// by importing the package in the daemon, the API handlers are not modified.
+package coin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// DefaultName is the backend used when none is selected
+const DefaultName = SkycoinName
+
+// Backend derives the addresses and signs the messages and transactions of a coin with the device
+type Backend interface {
+	// Name is the name the backend is registered and selected with
+	Name() string
+	// AddressGen derives addresses of the device seed
+	AddressGen(device skyWallet.Devicer, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error)
+	// SignMessage signs message with the address at addressIndex
+	SignMessage(device skyWallet.Devicer, addressIndex int, message string) (wire.Message, error)
+	// CheckMessageSignature checks that signature of message was made by address
+	CheckMessageSignature(device skyWallet.Devicer, message, signature, address string) (wire.Message, error)
+	// TransactionSign signs the inputs of a transaction
+	TransactionSign(device skyWallet.Devicer, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error)
+}
+
+// AddressValidator is implemented by the backends whose addresses are not Skycoin base58 addresses
+type AddressValidator interface {
+	// ValidateAddress checks the format, the version and the checksum of address
+	ValidateAddress(address string) error
+}
+
+// ValidateAddress checks address with the AddressValidator of backend, or as a Skycoin address if backend has none.
+// The destinations of the transactions are checked before the device is involved.
+func ValidateAddress(backend Backend, address string) error {
+	if v, ok := backend.(AddressValidator); ok {
+		return v.ValidateAddress(address)
+	}
+	return Skycoin{}.ValidateAddress(address)
+}
+
+var (
+	backendsLock sync.Mutex
+	backends     = make(map[string]Backend)
+)
+
+// Register registers backend under its name, it is called by the init function of the backend package.
+// It panics if a backend of the same name is already registered.
+func Register(backend Backend) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	name := backend.Name()
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("coin: backend %q is registered twice", name))
+	}
+	backends[name] = backend
+}
+
+// Get returns the backend registered under name
+func Get(name string) (Backend, error) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	backend, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown coin %q, the registered coins are: %v", name, names())
+	}
+	return backend, nil
+}
+
+// Names returns the names of the registered backends, sorted
+func Names() []string {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	return names()
+}
+
+func names() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,74 @@
+package coin
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+type fiberCoin struct {
+	Skycoin
+}
+
+func (fiberCoin) Name() string {
+	return "fibercoin"
+}
+
+func TestRegister(t *testing.T) {
+	backend, err := Get(DefaultName)
+	require.NoError(t, err)
+	require.Equal(t, SkycoinName, backend.Name())
+
+	_, err = Get("fibercoin")
+	require.EqualError(t, err, `unknown coin "fibercoin", the registered coins are: [skycoin]`)
+
+	Register(fiberCoin{})
+	defer func() {
+		backendsLock.Lock()
+		delete(backends, "fibercoin")
+		backendsLock.Unlock()
+	}()
+
+	backend, err = Get("fibercoin")
+	require.NoError(t, err)
+	require.Equal(t, fiberCoin{}, backend)
+	require.Equal(t, []string{"fibercoin", "skycoin"}, Names())
+
+	require.Panics(t, func() {
+		Register(fiberCoin{})
+	})
+}
+
+// prefixCoin is a backend whose addresses carry a prefix
+type prefixCoin struct {
+	Skycoin
+}
+
+func (prefixCoin) ValidateAddress(address string) error {
+	if !strings.HasPrefix(address, "fiber:") {
+		return errors.New("the address must start with fiber:")
+	}
+	return nil
+}
+
+func TestValidateAddress(t *testing.T) {
+	require.NoError(t, ValidateAddress(Skycoin{}, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"))
+	require.Equal(t, cipher.ErrAddressInvalidChecksum, ValidateAddress(Skycoin{}, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzx"))
+	require.Equal(t, cipher.ErrAddressInvalidLength, ValidateAddress(Skycoin{}, "2EU3Jbve"))
+
+	// an address of another version, the prefix of the address of another coin
+	address := cipher.MustAddressFromSecKey(cipher.MustNewSecKey(bytes.Repeat([]byte{1}, 32)))
+	address.Version = 1
+	require.Equal(t, cipher.ErrAddressInvalidVersion, ValidateAddress(Skycoin{}, address.String()))
+
+	// the backends without a validator have Skycoin addresses, nil is the default backend
+	require.NoError(t, ValidateAddress(fiberCoin{}, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"))
+	require.NoError(t, ValidateAddress(nil, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"))
+
+	require.NoError(t, ValidateAddress(prefixCoin{}, "fiber:abc"))
+	require.EqualError(t, ValidateAddress(prefixCoin{}, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"), "the address must start with fiber:")
+}
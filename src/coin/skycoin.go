@@ -0,0 +1,49 @@
+package coin
+
+import (
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// SkycoinName is the name of the Skycoin backend
+const SkycoinName = "skycoin"
+
+func init() {
+	Register(Skycoin{})
+}
+
+// Skycoin is the backend of Skycoin, it sends the Skycoin messages of the firmware
+type Skycoin struct{}
+
+// Name returns SkycoinName
+func (Skycoin) Name() string {
+	return SkycoinName
+}
+
+// AddressGen sends a SkycoinAddress request
+func (Skycoin) AddressGen(device skyWallet.Devicer, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return device.AddressGen(addressN, startIndex, confirmAddress)
+}
+
+// SignMessage sends a SkycoinSignMessage request
+func (Skycoin) SignMessage(device skyWallet.Devicer, addressIndex int, message string) (wire.Message, error) {
+	return device.SignMessage(addressIndex, message)
+}
+
+// CheckMessageSignature sends a SkycoinCheckMessageSignature request
+func (Skycoin) CheckMessageSignature(device skyWallet.Devicer, message, signature, address string) (wire.Message, error) {
+	return device.CheckMessageSignature(message, signature, address)
+}
+
+// TransactionSign sends a TransactionSign request
+func (Skycoin) TransactionSign(device skyWallet.Devicer, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	return device.TransactionSign(inputs, outputs)
+}
+
+// ValidateAddress checks that address is a base58 Skycoin address of version 0 with a valid checksum
+func (Skycoin) ValidateAddress(address string) error {
+	_, err := cipher.DecodeBase58Address(address)
+	return err
+}
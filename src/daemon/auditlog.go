@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/skycoin/skycoin/src/util/file"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/auditlog"
+)
+
+// AuditVerifyOutput is the JSON output of audit verify
+type AuditVerifyOutput struct {
+	Path    string `json:"path"`
+	Entries int    `json:"entries"`
+	// Chained is true if the HMAC chaining of the entries was verified with the key of -audit-log-key
+	Chained bool `json:"chained"`
+}
+
+// auditVerify verifies the audit log file of the config c, with the key of -audit-log-key if set.
+// The key is not generated, a missing key fails the verification.
+func auditVerify(c AppConfig, w io.Writer, jsonOutput bool) error {
+	if c.AuditLog == "" {
+		return errors.New("no audit log, set it with -audit-log")
+	}
+	home := file.UserHome()
+	path := replaceHome(c.AuditLog, home)
+
+	var key []byte
+	if c.AuditLogKey != "" {
+		var err error
+		key, err = auditlog.ReadKey(replaceHome(c.AuditLogKey, home))
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := auditlog.Verify(f, key)
+	if err != nil {
+		return fmt.Errorf("the audit log %s failed the verification after %d entries: %v", path, n, err)
+	}
+
+	if jsonOutput {
+		return writeJSON(w, AuditVerifyOutput{
+			Path:    path,
+			Entries: n,
+			Chained: key != nil,
+		})
+	}
+
+	chaining := "without HMAC chaining, only their numbering was checked"
+	if key != nil {
+		chaining = "with their HMAC chaining"
+	}
+	_, err = fmt.Fprintf(w, "The %d entries of the audit log %s are verified, %s\n", n, path, chaining)
+	return err
+}
@@ -0,0 +1,313 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/api"
+	"github.com/skycoin/hardware-wallet-daemon/src/client"
+	"github.com/skycoin/hardware-wallet-daemon/src/models"
+)
+
+// daemonFlags are the flags of the daemon and keyring commands
+type daemonFlags struct {
+	// file is the transaction signed by daemon sign, - for the standard input
+	file string
+	// n, startIndex and confirm are the addresses generated by daemon address
+	n          int
+	startIndex int
+	confirm    bool
+	// name is the secret stored by keyring set
+	name string
+}
+
+// registerDaemonFlags registers the flags of the daemon and keyring commands on fs
+func registerDaemonFlags(fs *flag.FlagSet, command string, f *daemonFlags) {
+	switch command {
+	case "daemon sign":
+		fs.StringVar(&f.file, "f", "", "JSON file of the transaction to sign, - for the standard input")
+	case "daemon address":
+		fs.IntVar(&f.n, "n", 1, "Number of addresses to generate")
+		fs.IntVar(&f.startIndex, "start-index", 0, "Index of the first address")
+		fs.BoolVar(&f.confirm, "confirm", false, "Confirm the address on the device")
+	case "keyring set":
+		fs.StringVar(&f.name, "name", "", "Name of the secret, the settings refer to it with keyring:<name>")
+	}
+}
+
+// daemonClient returns a client of the daemon running with the config c, prompting the user on the terminal
+func daemonClient(c AppConfig) *client.Client {
+	return client.NewClient(client.Config{
+		Addr:     fmt.Sprintf("%s:%d", c.WebInterfaceAddr, c.WebInterfacePort),
+		Prompter: newTerminalPrompter(os.Stdin, os.Stderr),
+	})
+}
+
+// commandContext returns a context canceled on interrupt, the daemon cancels the operation of a request
+// canceled by its client
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(quit)
+	}()
+
+	return ctx, cancel
+}
+
+// daemonStatus writes the health of the daemon running with the config c.
+// The JSON output is the response of the health endpoint.
+func daemonStatus(c AppConfig, w io.Writer, jsonOutput bool) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/api/v2/health", c.WebInterfaceAddr, c.WebInterfacePort), nil)
+	if err != nil {
+		return err
+	}
+
+	var health api.HealthResponse
+	if err := apiRequest(&http.Client{Timeout: 10 * time.Second}, req, &health); err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return writeJSON(w, health)
+	}
+
+	transport := health.Transport.Status
+	if health.Transport.Error != "" {
+		transport += ": " + health.Transport.Error
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Status\t%s\n", health.Status)
+	fmt.Fprintf(tw, "Version\t%s\n", health.Daemon.Version)
+	fmt.Fprintf(tw, "Uptime\t%s\n", time.Duration(health.Uptime)*time.Second)
+	fmt.Fprintf(tw, "Mode\t%s\n", health.Mode)
+	fmt.Fprintf(tw, "Transport\t%s %s\n", health.Transport.Name, transport)
+	fmt.Fprintf(tw, "Device\t%s\n", health.Device)
+	if health.StartupChecks != nil {
+		checks := "passed"
+		if !health.StartupChecks.Passed {
+			checks = "failed"
+		}
+		fmt.Fprintf(tw, "Startup checks\t%s\n", checks)
+	}
+	return tw.Flush()
+}
+
+// daemonFeatures writes the features of the device of the daemon running with the config c.
+// The JSON output is the data of the features response.
+func daemonFeatures(c AppConfig, w io.Writer, jsonOutput bool) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	f, err := daemonClient(c).Features(ctx)
+	if err != nil {
+		return commandError(err)
+	}
+
+	if jsonOutput {
+		return writeJSON(w, f)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Label\t%s\n", f.Label)
+	fmt.Fprintf(tw, "Device ID\t%s\n", f.DeviceID)
+	fmt.Fprintf(tw, "Model\t%s\n", f.Model)
+	fmt.Fprintf(tw, "Firmware\t%s\n", firmwareVersion(f))
+	fmt.Fprintf(tw, "Initialized\t%s\n", yesNo(f.Initialized))
+	fmt.Fprintf(tw, "Needs backup\t%s\n", yesNo(f.NeedsBackup))
+	fmt.Fprintf(tw, "PIN protection\t%s\n", yesNo(f.PinProtection))
+	fmt.Fprintf(tw, "Passphrase protection\t%s\n", yesNo(f.PassphraseProtection))
+	return tw.Flush()
+}
+
+// transactionFile is the transaction signed by daemon sign, the body of the transaction sign endpoint
+type transactionFile struct {
+	Inputs  []client.TransactionInput  `json:"transaction_inputs"`
+	Outputs []client.TransactionOutput `json:"transaction_outputs"`
+}
+
+// daemonSign signs the transaction of the file with the device of the daemon running with the config c,
+// writing a signature per input. The JSON output is the list of signatures.
+func daemonSign(c AppConfig, f daemonFlags, w io.Writer, jsonOutput bool) error {
+	if f.file == "" {
+		return errors.New("daemon sign: the transaction file is required, set it with -f")
+	}
+
+	var b []byte
+	var err error
+	if f.file == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(f.file)
+	}
+	if err != nil {
+		return err
+	}
+
+	var tx transactionFile
+	if err := json.Unmarshal(b, &tx); err != nil {
+		return fmt.Errorf("invalid transaction file %s: %v", f.file, err)
+	}
+	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+		return fmt.Errorf("invalid transaction file %s: the transaction_inputs and transaction_outputs are required", f.file)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	signatures, err := daemonClient(c).TransactionSign(ctx, tx.Inputs, tx.Outputs)
+	if err != nil {
+		return commandError(err)
+	}
+
+	return writeLines(w, signatures, jsonOutput)
+}
+
+// daemonAddress writes the addresses generated by the device of the daemon running with the config c.
+// The JSON output is the list of addresses.
+func daemonAddress(c AppConfig, f daemonFlags, w io.Writer, jsonOutput bool) error {
+	if f.n < 1 {
+		return errors.New("daemon address: -n must be at least 1")
+	}
+	if f.startIndex < 0 {
+		return errors.New("daemon address: -start-index can't be negative")
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	addresses, err := daemonClient(c).GenerateAddresses(ctx, f.n, f.startIndex, f.confirm)
+	if err != nil {
+		return commandError(err)
+	}
+
+	return writeLines(w, addresses, jsonOutput)
+}
+
+// commandError returns the error of a daemon request, explaining the errors of the daemon not running
+func commandError(err error) error {
+	switch e := err.(type) {
+	case *client.Error:
+		return fmt.Errorf("daemon returned %v", err)
+	case *url.Error:
+		if e.Err == context.Canceled {
+			return context.Canceled
+		}
+		return fmt.Errorf("failed to reach the daemon, is it running? %v", err)
+	default:
+		return err
+	}
+}
+
+// writeLines writes the lines of a command output, one per line or as a JSON list
+func writeLines(w io.Writer, lines []string, jsonOutput bool) error {
+	if jsonOutput {
+		if lines == nil {
+			lines = []string{}
+		}
+		return writeJSON(w, lines)
+	}
+
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func firmwareVersion(f *models.FeaturesResponseData) string {
+	if f.FwMajor == nil || f.FwMinor == nil || f.FwPatch == nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", *f.FwMajor, *f.FwMinor, *f.FwPatch)
+}
+
+func yesNo(b *bool) string {
+	switch {
+	case b == nil:
+		return "unknown"
+	case *b:
+		return "yes"
+	default:
+		return "no"
+	}
+}
+
+// terminalPrompter prompts the user for the intermediate requests of the device on the terminal
+type terminalPrompter struct {
+	in  *os.File
+	r   *bufio.Reader
+	out io.Writer
+}
+
+func newTerminalPrompter(in *os.File, out io.Writer) *terminalPrompter {
+	return &terminalPrompter{
+		in:  in,
+		r:   bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// PinMatrix reads the PIN, the positions of its digits in the matrix shown on the device
+func (p *terminalPrompter) PinMatrix(ctx context.Context) (string, error) {
+	fmt.Fprintln(p.out, "Enter the PIN with the layout of the matrix shown on the device:")
+	fmt.Fprintln(p.out, "  7 8 9\n  4 5 6\n  1 2 3")
+	return p.read("PIN: ", true)
+}
+
+// Passphrase reads the passphrase of the wallet
+func (p *terminalPrompter) Passphrase(ctx context.Context) (string, error) {
+	return p.read("Passphrase: ", true)
+}
+
+// Word reads the word of the seed requested on the device
+func (p *terminalPrompter) Word(ctx context.Context) (string, error) {
+	return p.read("Word: ", false)
+}
+
+// Button tells the user to confirm on the device
+func (p *terminalPrompter) Button(ctx context.Context) error {
+	fmt.Fprintln(p.out, "Confirm on the device")
+	return nil
+}
+
+// read reads a line, without echoing it if secret and the input is a terminal
+func (p *terminalPrompter) read(prompt string, secret bool) (string, error) {
+	fmt.Fprint(p.out, prompt)
+
+	if secret && terminal.IsTerminal(int(p.in.Fd())) {
+		b, err := terminal.ReadPassword(int(p.in.Fd()))
+		fmt.Fprintln(p.out)
+		return string(b), err
+	}
+
+	line, err := p.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -1,11 +1,18 @@
 package daemon
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime/pprof"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,13 +20,53 @@ import (
 	"github.com/skycoin/skycoin/src/util/apputil"
 	"github.com/skycoin/skycoin/src/util/logging"
 
+	"github.com/skycoin/hardware-wallet-daemon/src/addressbook"
+	"github.com/skycoin/hardware-wallet-daemon/src/allowlist"
 	"github.com/skycoin/hardware-wallet-daemon/src/api"
+	"github.com/skycoin/hardware-wallet-daemon/src/approval"
+	"github.com/skycoin/hardware-wallet-daemon/src/auditlog"
+	"github.com/skycoin/hardware-wallet-daemon/src/chaos"
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/datadir"
+	"github.com/skycoin/hardware-wallet-daemon/src/deadline"
+	"github.com/skycoin/hardware-wallet-daemon/src/devicelock"
+	"github.com/skycoin/hardware-wallet-daemon/src/devicepool"
+	"github.com/skycoin/hardware-wallet-daemon/src/drain"
+	"github.com/skycoin/hardware-wallet-daemon/src/emulator"
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/history"
+	"github.com/skycoin/hardware-wallet-daemon/src/hotplug"
+	"github.com/skycoin/hardware-wallet-daemon/src/idempotency"
+	"github.com/skycoin/hardware-wallet-daemon/src/inventory"
+	"github.com/skycoin/hardware-wallet-daemon/src/keyring"
+	"github.com/skycoin/hardware-wallet-daemon/src/logfile"
+	"github.com/skycoin/hardware-wallet-daemon/src/loglevel"
+	"github.com/skycoin/hardware-wallet-daemon/src/modeswitch"
+	"github.com/skycoin/hardware-wallet-daemon/src/nativemsg"
+	"github.com/skycoin/hardware-wallet-daemon/src/plugin"
+	"github.com/skycoin/hardware-wallet-daemon/src/recording"
+	"github.com/skycoin/hardware-wallet-daemon/src/relay"
+	"github.com/skycoin/hardware-wallet-daemon/src/simulator"
+	"github.com/skycoin/hardware-wallet-daemon/src/smoketest"
+	"github.com/skycoin/hardware-wallet-daemon/src/stats"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+	"github.com/skycoin/hardware-wallet-daemon/src/tracing"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+	"github.com/skycoin/hardware-wallet-daemon/src/u2f"
+	"github.com/skycoin/hardware-wallet-daemon/src/updatecheck"
+	"github.com/skycoin/hardware-wallet-daemon/src/webhook"
+	"github.com/skycoin/hardware-wallet-daemon/src/wirecapture"
 )
 
+// tracingShutdownTimeout is how long the daemon waits for the pending traces to be exported when it stops
+const tracingShutdownTimeout = 5 * time.Second
+
 // Daemon represents a hardware wallet daemon instance
 type Daemon struct {
-	config Config
-	logger *logging.Logger
+	config   Config
+	logger   *logging.Logger
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewDaemon returns a new hardware wallet daemon instance
@@ -27,12 +74,45 @@ func NewDaemon(config Config, logger *logging.Logger) *Daemon {
 	return &Daemon{
 		config: config,
 		logger: logger,
+		stop:   make(chan struct{}),
 	}
 }
 
+// Stop shuts down the running daemon as if interrupted, Run returns once it is shut down
+func (d *Daemon) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+}
+
 // Run starts the daemon
 func (d *Daemon) Run() error {
 	var apiServer *api.Server
+	var store storage.Store
+	var bus *events.Bus
+	var recorder *history.Recorder
+	var exportKey *history.ExportKey
+	var auditLog *auditlog.Log
+	var gateway api.Gatewayer
+	var modeSwitch *modeswitch.Switch
+	var adminToken string
+	var tracer *tracing.Tracer
+	var listener net.Listener
+	var relayClient *relay.Relay
+	var notifier *webhook.Notifier
+	var emu *emulator.Emulator
+	var approvals *approval.Manager
+	var approvalToken string
+	var startupChecks *smoketest.Suite
+	var updateCheck *updatecheck.Checker
+	var logLevelController *loglevel.Controller
+	var messageRecorder *recording.Recorder
+	var deviceDeadline *deadline.Driver
+	var plugins []*plugin.Client
+	var featuresCache *api.FeaturesCache
+	tracker := drain.NewTracker()
+	deviceLock := devicelock.New(d.config.App.DeviceLockTimeout)
+	collector := stats.NewCollector()
 	var retErr error
 	errC := make(chan error, 10)
 
@@ -44,6 +124,15 @@ func (d *Daemon) Run() error {
 	}
 
 	logging.SetLevel(logLevel)
+	// the admin endpoints change the level at runtime
+	if d.config.App.EnableAdmin {
+		logLevelController = loglevel.New(logLevel)
+	}
+
+	// stdout carries the native messaging responses, the browser forwards stderr to its log
+	if d.config.App.NativeMessaging {
+		logging.SetOutputTo(os.Stderr)
+	}
 
 	if d.config.App.ColorLog {
 		logging.EnableColors()
@@ -51,7 +140,17 @@ func (d *Daemon) Run() error {
 		logging.DisableColors()
 	}
 
-	var logFile *os.File
+	// the legacy log files are moved before the log file is opened
+	problems, err := d.prepareDataDir()
+	if err != nil {
+		d.logger.Error(err)
+		return err
+	}
+	for _, p := range problems {
+		d.logger.Warning(p)
+	}
+
+	var logFile *logfile.File
 	if d.config.App.LogToFile {
 		var err error
 		logFile, err = d.initLogFile()
@@ -61,6 +160,10 @@ func (d *Daemon) Run() error {
 		}
 	}
 
+	if d.config.App.Airgap {
+		d.logger.Info("Airgap mode, the outbound requests are refused")
+	}
+
 	host := fmt.Sprintf("%s:%d", d.config.App.WebInterfaceAddr, d.config.App.WebInterfacePort)
 
 	if d.config.App.ProfileCPU {
@@ -88,6 +191,7 @@ func (d *Daemon) Run() error {
 	var wg sync.WaitGroup
 
 	quit := make(chan struct{})
+	watchQuit := make(chan struct{})
 
 	// Catch SIGINT (CTRL-C) (closes the quit channel)
 	go apputil.CatchInterrupt(quit)
@@ -95,70 +199,528 @@ func (d *Daemon) Run() error {
 	// Catch SIGUSR1 (prints runtime stack to stdout)
 	go apputil.CatchDebug()
 
-	apiServer, err = d.createServer(host, api.NewGateway(skyWallet.NewDevice(d.config.App.daemonMode)))
+	store, err = d.openStore()
 	if err != nil {
 		d.logger.Error(err)
 		retErr = err
 		goto earlyShutdown
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	bus, err = events.NewBus(store, d.config.App.MaxEvents)
+	if err != nil {
+		d.logger.Error(err)
+		retErr = err
+		goto earlyShutdown
+	}
+
+	if logFile != nil {
+		logFile.OnFailure(func(failure logfile.Failure) {
+			if _, err := bus.Publish(events.TypeLogFileFailed, "", failure); err != nil {
+				d.logger.WithError(err).Errorf("failed to publish %s event", events.TypeLogFileFailed)
+			}
+		})
+	}
+
+	if d.config.App.TracingEndpoint != "" {
+		tracer, err = tracing.New(d.config.App.TracingEndpoint)
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		d.logger.Infof("Exporting traces to %s", d.config.App.TracingEndpoint)
+	}
+
+	if d.config.App.PluginsDir != "" {
+		plugins, err = d.loadPlugins()
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+	}
+
+	if d.config.App.SimulateAPI {
+		d.logger.Info("Simulating the API, no device is used")
+		gateway = d.newSimulator()
+	} else {
+		var device *skyWallet.Device
+		if d.config.App.replayPlayer != nil {
+			d.logger.Infof("Replaying the device messages of %s, no device is used", d.config.App.ReplayMessages)
+			device = &skyWallet.Device{Driver: d.config.App.replayPlayer}
+		} else if d.config.App.mockDevice {
+			d.logger.Info("Serving the API with a mock device answering as the simulated device, no device is used")
+			device = &skyWallet.Device{Driver: simulator.NewDriver(d.newSimulator())}
+		} else {
+			device = skyWallet.NewDevice(d.config.App.daemonMode)
+			// the handle of the device is kept open across the requests, the replayed device has none
+			device.Driver = d.newDevicePool(device.Driver)
+		}
+		if d.config.App.EnableAdmin {
+			modeSwitch = modeswitch.New(device.Driver, d.newModeDriver)
+			device.Driver = modeSwitch
+		}
+		if d.config.App.Chaos {
+			d.logger.Warningf("Injecting transport faults, chaos seed %d", d.config.App.chaosConfig.Seed)
+			device.Driver = chaos.NewDriver(device.Driver, d.config.App.chaosConfig)
+		}
+		// the injected delays are bounded as well
+		deviceDeadline = deadline.NewDriver(device.Driver, d.config.App.deadlineConfig)
+		device.Driver = deviceDeadline
+		// each attempt is logged, including the ones the reconnects retry
+		if logLevelController != nil {
+			device.Driver = loglevel.NewDriver(device.Driver, logLevelController)
+		}
+		if d.config.App.wireCapture != nil {
+			device.Driver = wirecapture.NewDriver(device.Driver, d.config.App.wireCapture)
+		}
+		// the replayed device does not reconnect, a recording holds the exchanges after the reconnects
+		if d.config.App.replayPlayer == nil && d.config.App.ReconnectTimeout > 0 {
+			device.Driver = hotplug.NewDriver(device.Driver, d.config.App.ReconnectTimeout)
+		}
+		// the recordings reproduce the injected faults the reconnects did not recover from
+		if d.config.App.RecordMessages != "" {
+			messageRecorder, err = recording.NewRecorder(device.Driver, d.config.App.RecordMessages)
+			if err != nil {
+				d.logger.Error(err)
+				retErr = err
+				goto earlyShutdown
+			}
+			device.Driver = messageRecorder
+			d.logger.Warningf("Recording the device messages to %s", d.config.App.RecordMessages)
+		}
+		// the injected faults are counted and traced as well
+		device.Driver = stats.NewDriver(device.Driver, collector)
+		if tracer != nil {
+			device.Driver = tracing.NewDriver(device.Driver, tracer)
+		}
+		gateway = api.NewGateway(device)
+	}
+	if d.config.App.Coin != coin.DefaultName {
+		d.logger.Infof("Deriving the addresses and signing with the %s backend", d.config.App.Coin)
+	}
+	gateway = api.NewCoinGateway(gateway, d.config.App.coinBackend)
+	if d.config.App.Deterministic {
+		d.logger.Warning("Deterministic mode, the generated seeds are the public test mnemonics, never store coins on them")
+		mode := func() skyWallet.DeviceType {
+			return d.config.App.daemonMode
+		}
+		if modeSwitch != nil {
+			mode = modeSwitch.Mode
+		}
+		gateway = api.NewDeterministicGateway(gateway, mode, d.config.App.SimulateAPI || d.config.App.mockDevice)
+	}
+	if d.config.App.ReadOnly {
+		d.logger.Info("Read-only mode, the requests changing the device or signing with its keys are refused")
+	}
+	if d.config.App.FeaturesCacheTTL > 0 {
+		featuresCache = api.NewFeaturesCache(gateway, d.config.App.FeaturesCacheTTL)
+		gateway = featuresCache
+		if modeSwitch != nil {
+			featuresCache.InvalidateOnSwitch(modeSwitch)
+		}
+	}
+
+	recorder = history.NewRecorder(store, d.config.App.traceHeaders)
+
+	exportKey, err = d.loadExportKey()
+	if err != nil {
+		d.logger.Error(err)
+		retErr = err
+		goto earlyShutdown
+	}
+	d.logger.Infof("History archives are signed by %s", exportKey.PubKey.Hex())
+
+	if d.config.App.AuditLog != "" {
+		auditLog, err = d.openAuditLog()
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		d.logger.Infof("Writing the audit log to %s", auditLog.Path())
+	}
+
+	if d.config.App.EnableAdmin {
+		var path string
+		adminToken, path, err = d.loadToken(d.config.App.AdminTokenFile, "admin")
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		d.logger.Infof("Admin endpoints enabled, the token is in %s", path)
+	}
+
+	if d.config.App.ApprovalThreshold != "" {
+		var path string
+		approvalToken, path, err = d.loadToken(d.config.App.ApprovalTokenFile, "approval")
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		// the approver is a second person, the admin token does not approve
+		if approvalToken == adminToken {
+			err = errors.New("the approval token must differ from the admin token")
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		approvals = approval.NewManager(d.config.App.ApprovalTimeout)
+		d.logger.Infof("Transactions spending more than %s coins require approval, the approval token is in %s", d.config.App.ApprovalThreshold, path)
+	}
+
+	if d.config.App.RelayURL != "" {
+		relayClient, err = d.createRelay(store)
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		d.logger.Infof("Relay mode enabled, the public key of the daemon is %s", relayClient.Status().PubKey)
+	}
+
+	if len(d.config.App.webhookURLs) > 0 {
+		secret, path, err := d.loadToken(d.config.App.WebhookSecretFile, "webhook")
+		if err != nil {
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		notifier = webhook.New(webhook.Config{
+			URLs:        d.config.App.webhookURLs,
+			Secret:      []byte(secret),
+			MaxAttempts: d.config.App.WebhookMaxAttempts,
+			Transport:   d.config.App.outboundTransport(),
+		})
+		d.logger.Infof("Sending the events to %d webhooks, the requests are signed with the secret in %s", len(d.config.App.webhookURLs), path)
+	}
 
-		if err := apiServer.Serve(); err != nil {
+	if d.config.App.EmulatorBinary != "" {
+		emu = emulator.New(emulator.Config{
+			Binary: d.config.App.EmulatorBinary,
+			Dir:    d.config.App.EmulatorDir,
+		})
+
+		// the emulator switched to at runtime is started with the emulator endpoints
+		if d.config.App.daemonMode == skyWallet.DeviceTypeEmulator {
+			if _, err = emu.Start(); err != nil {
+				err = fmt.Errorf("failed to start the emulator: %v", err)
+				d.logger.Error(err)
+				retErr = err
+				goto earlyShutdown
+			}
+		}
+	}
+
+	if d.config.App.startupChecks != nil {
+		mode := func() skyWallet.DeviceType {
+			return d.config.App.daemonMode
+		}
+		if modeSwitch != nil {
+			mode = modeSwitch.Mode
+		}
+		checks := *d.config.App.startupChecks
+		checks.Transport = d.config.App.outboundTransport()
+		startupChecks = smoketest.NewSuite(checks, api.NewStartupCheckDevice(gateway, mode, deviceLock))
+	}
+
+	if d.config.App.UpdateCheck {
+		updateCheck, err = updatecheck.NewChecker(d.config.App.UpdateFeed, d.config.Build.Version, d.config.App.UpdateCheckInterval, d.config.App.outboundTransport())
+		if err != nil {
 			d.logger.Error(err)
-			errC <- err
+			retErr = err
+			goto earlyShutdown
 		}
+		d.logger.Infof("Checking %s for daemon updates every %s", d.config.App.UpdateFeed, d.config.App.UpdateCheckInterval)
+	}
+
+	listener, err = systemdListener()
+	if err != nil {
+		d.logger.Error(err)
+		retErr = err
+		goto earlyShutdown
+	}
+	if listener != nil {
+		if addr, ok := listener.Addr().(*net.TCPAddr); ok && !addr.IP.IsLoopback() && !d.config.App.EnableRemoteAccess {
+			err = fmt.Errorf("the socket passed by systemd, %s, is not on a loopback address, set -enable-remote-access to serve it", addr)
+			d.logger.Error(err)
+			retErr = err
+			goto earlyShutdown
+		}
+		host = listener.Addr().String()
+		d.logger.Infof("Serving the web interface on the socket passed by systemd, %s", host)
+	}
+
+	apiServer, err = d.createServer(host, listener, gateway, serverComponents{
+		Store:            store,
+		Events:           bus,
+		History:          recorder,
+		HistoryExportKey: exportKey,
+		AuditLog:         auditLog,
+		ModeSwitch:       modeSwitch,
+		AdminToken:       adminToken,
+		Tracer:           tracer,
+		Stats:            collector,
+		Relay:            relayClient,
+		Emulator:         emu,
+		Approvals:        approvals,
+		ApprovalToken:    approvalToken,
+		StartupChecks:    startupChecks,
+		UpdateCheck:      updateCheck,
+		LogLevel:         logLevelController,
+		DeviceDeadline:   deviceDeadline,
+		Drain:            tracker,
+		DeviceLock:       deviceLock,
+		Plugins:          plugins,
+	})
+	if err != nil {
+		d.logger.Error(err)
+		retErr = err
+		goto earlyShutdown
+	}
+
+	wg.Add(1)
+	if d.config.App.NativeMessaging {
+		go func() {
+			defer wg.Done()
+
+			// the browser starts the daemon for an extension, it stops once the extension disconnects
+			if err := nativemsg.Serve(apiServer.Handler(), host, os.Stdin, os.Stdout, watchQuit); err != nil {
+				d.logger.Error(err)
+				errC <- err
+				return
+			}
+			d.Stop()
+		}()
+	} else {
+		go func() {
+			defer wg.Done()
+
+			if err := apiServer.Serve(); err != nil {
+				d.logger.Error(err)
+				errC <- err
+			}
+		}()
+	}
+
+	// the web interface is listening, the requests are served from now on
+	if ok, err := notifySystemd(systemdReady); err != nil {
+		d.logger.WithError(err).Error("Failed to notify systemd")
+	} else if ok {
+		d.logger.Info("Notified systemd the daemon is ready")
+	}
+
+	if interval, err := systemdWatchdogInterval(); err != nil {
+		d.logger.WithError(err).Error("Invalid WATCHDOG_USEC, the systemd watchdog is not notified")
+	} else if interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.systemdWatchdog(interval, liveCheck(host), watchQuit)
+		}()
+	}
+
+	// serve the requests of the paired clients forwarded by the relay server
+	if relayClient != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relayClient.Run(apiServer.Handler(), host, watchQuit)
+		}()
+	}
+
+	// send the device and signing events to the webhooks
+	if notifier != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notifier.Run(bus, watchQuit)
+		}()
+	}
+
+	// watch for the device being plugged in and out
+	if modeSwitch != nil {
+		// the USB device is reported as unplugged while the emulator is used
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			events.WatchDevice(bus, func() bool {
+				return modeSwitch.Mode() == skyWallet.DeviceTypeUSB && gateway.Available()
+			}, events.DefaultWatchInterval, watchQuit)
+		}()
+	} else if d.config.App.daemonMode == skyWallet.DeviceTypeUSB {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			events.WatchDevice(bus, gateway.Available, events.DefaultWatchInterval, watchQuit)
+		}()
+	}
+
+	if featuresCache != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			featuresCache.Watch(bus, watchQuit)
+		}()
+	}
+
+	// reopen the log file when it is rotated
+	if logFile != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logFile.Watch(logfile.DefaultCheckInterval, watchQuit)
+		}()
+	}
+
+	// run the startup checks until they pass
+	if startupChecks != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			startupChecks.Run(watchQuit)
+		}()
+	}
+
+	// check the release feed for a newer daemon release
+	if updateCheck != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updateCheck.Run(watchQuit)
+		}()
+	}
+
+	// remove the history and audit records past their retention
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		history.ApplyRetention(recorder, d.config.App.retentionPolicy, d.config.App.RetentionInterval, watchQuit)
 	}()
 
 	select {
 	case <-quit:
+	case <-d.stop:
 	case retErr = <-errC:
 		d.logger.Error(retErr)
 	}
 
 	d.logger.Info("Shutting down...")
 
+	if _, err := notifySystemd(systemdStopping); err != nil {
+		d.logger.WithError(err).Error("Failed to notify systemd")
+	}
+
+	// the device is left idle, not in the middle of an operation
+	d.drainOperations(tracker, gateway)
+
 	if apiServer != nil {
 		d.logger.Info("Closing api server")
 		apiServer.Shutdown()
 	}
 
+	close(watchQuit)
+
 	d.logger.Info("Waiting for goroutines to finish")
 	wg.Wait()
 
 earlyShutdown:
+	if len(plugins) > 0 {
+		d.logger.Info("Stopping the plugins")
+		plugin.Close(plugins)
+	}
+
+	if messageRecorder != nil {
+		d.logger.Info("Closing the message recording")
+		messageRecorder.Close()
+	}
+
+	if emu != nil {
+		d.logger.Info("Stopping the emulator")
+		if err := emu.Close(); err != nil {
+			d.logger.WithError(err).Error("emulator.Close failed")
+		}
+	}
+
+	if tracer != nil {
+		d.logger.Info("Exporting the pending traces")
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		if err := tracer.Shutdown(ctx); err != nil {
+			d.logger.WithError(err).Error("tracer.Shutdown failed")
+		}
+		cancel()
+	}
+
+	if auditLog != nil {
+		d.logger.Info("Closing the audit log")
+		if err := auditLog.Close(); err != nil {
+			d.logger.WithError(err).Error("auditLog.Close failed")
+		}
+	}
+
+	if store != nil {
+		d.logger.Info("Closing storage")
+		if err := store.Close(); err != nil {
+			d.logger.WithError(err).Error("store.Close failed")
+		}
+	}
+
 	d.logger.Info("Goodbye")
 
 	if logFile != nil {
 		if err := logFile.Close(); err != nil {
-			fmt.Println("Failed to close log file")
+			fmt.Fprintf(os.Stderr, "Failed to close log file: %v\n", err)
 		}
 	}
 
 	return retErr
 }
 
-func (d *Daemon) initLogFile() (*os.File, error) {
-	logDir := filepath.Join(d.config.App.DataDirectory, "logs")
+// drainOperations refuses the new device operations and waits for the ones in flight to finish, up to the shutdown
+// timeout. The device is sent a Cancel if they do not finish in time, so a signing flow is not left mid-message.
+func (d *Daemon) drainOperations(tracker *drain.Tracker, gateway api.Gatewayer) {
+	if n := tracker.InFlight(); n > 0 {
+		d.logger.Infof("Waiting up to %s for %d device operations to finish", d.config.App.ShutdownTimeout, n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.App.ShutdownTimeout)
+	err := tracker.Drain(ctx)
+	cancel()
+	if err == nil {
+		return
+	}
+
+	d.logger.Warningf("%d device operations did not finish in time, canceling them", tracker.InFlight())
+	if _, err := gateway.Cancel(); err != nil {
+		d.logger.WithError(err).Error("Failed to cancel the device operations")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), drain.CancelTimeout)
+	defer cancel()
+	if err := tracker.Drain(ctx); err != nil {
+		d.logger.Warningf("%d device operations are still in flight, shutting down anyway", tracker.InFlight())
+	}
+}
+
+func (d *Daemon) initLogFile() (*logfile.File, error) {
+	logDir := d.dataDir().Logs()
 	if err := createDirIfNotExist(logDir); err != nil {
 		d.logger.Errorf("createDirIfNotExist(%s) failed: %v", logDir, err)
 		return nil, fmt.Errorf("createDirIfNotExist(%s) failed: %v", logDir, err)
 	}
 
 	// open log file
-	tf := "2006-01-02-030405"
-	logfile := filepath.Join(logDir, fmt.Sprintf("%s.log", time.Now().Format(tf)))
+	logfilePath := filepath.Join(logDir, fmt.Sprintf("%s.log", time.Now().Format(datadir.LogFileTimeFormat)))
 
-	f, err := os.OpenFile(logfile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	f, err := logfile.Open(logfilePath)
 	if err != nil {
-		d.logger.Errorf("os.OpenFile(%s) failed: %v", logfile, err)
+		d.logger.Errorf("logfile.Open(%s) failed: %v", logfilePath, err)
 		return nil, err
 	}
 
-	hook := logging.NewWriteHook(f)
-	logging.AddHook(hook)
+	logging.AddHook(f)
 
 	return f, nil
 }
@@ -171,13 +733,269 @@ func createDirIfNotExist(dir string) error {
 	return os.Mkdir(dir, 0750)
 }
 
-func (d *Daemon) createServer(host string, gateway *api.Gateway) (*api.Server, error) {
+// openStore opens the configured storage backend in the data directory
+func (d *Daemon) openStore() (storage.Store, error) {
+	dbDir := d.dataDir().DB()
+	store, err := storage.Open(d.config.App.storageBackend, dbDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s storage in %s: %v", d.config.App.storageBackend, dbDir, err)
+	}
+
+	return store, nil
+}
+
+// loadExportKey loads the key signing the history archives, generating it if needed
+func (d *Daemon) loadExportKey() (*history.ExportKey, error) {
+	path := d.config.App.HistoryExportKey
+	if path == "" {
+		path = filepath.Join(d.dataDir().Keys(), datadir.HistoryExportKeyFilename)
+	}
+
+	return history.LoadExportKey(path)
+}
+
+// loadPlugins starts the plugins of the plugins directory, registers their coin backends and selects the coin backend
+func (d *Daemon) loadPlugins() ([]*plugin.Client, error) {
+	plugins, err := plugin.Load(d.config.App.PluginsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range plugins {
+		backend := p.Backend()
+		if backend == nil {
+			continue
+		}
+		if _, err := coin.Get(backend.Name()); err == nil {
+			plugin.Close(plugins)
+			return nil, fmt.Errorf("the coin %s of the plugin %s is already registered", backend.Name(), p.Name())
+		}
+		coin.Register(backend)
+	}
+
+	backend, err := coin.Get(d.config.App.Coin)
+	if err != nil {
+		plugin.Close(plugins)
+		return nil, err
+	}
+	d.config.App.coinBackend = backend
+
+	d.logger.Infof("Started %d plugins of %s", len(plugins), d.config.App.PluginsDir)
+	return plugins, nil
+}
+
+// openAuditLog opens the audit log file, chaining its entries with the key of -audit-log-key if set
+func (d *Daemon) openAuditLog() (*auditlog.Log, error) {
+	var key []byte
+	if d.config.App.AuditLogKey != "" {
+		var err error
+		key, err = auditlog.LoadKey(d.config.App.AuditLogKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := auditlog.Open(d.config.App.AuditLog, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %v", err)
+	}
+	return l, nil
+}
+
+// createRelay creates the relay client, loading the key encrypting the relayed requests or generating it if needed
+func (d *Daemon) createRelay(store storage.Store) (*relay.Relay, error) {
+	path := d.config.App.RelayKey
+	if path == "" {
+		path = filepath.Join(d.dataDir().Keys(), datadir.RelayKeyFilename)
+	}
+
+	key, err := relay.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return relay.New(d.config.App.RelayURL, key, store, d.config.App.outboundTransport()), nil
+}
+
+// loadToken loads the token of the admin or approval endpoints from path and returns it with the path of its file.
+// A random token is generated if the file does not exist, path defaults to <name>.token in the data directory.
+// A keyring:<name> path reads the token from the OS keyring, where it is never generated.
+func (d *Daemon) loadToken(path, name string) (string, string, error) {
+	if keyring.IsReference(path) {
+		token, err := keyring.Resolve(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read %s token: %v", name, err)
+		}
+		return token, path, nil
+	}
+
+	if path == "" {
+		path = filepath.Join(d.config.App.DataDirectory, name+".token")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", "", err
+		}
+		token := hex.EncodeToString(b)
+		if err := ioutil.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			return "", "", fmt.Errorf("failed to write %s token: %v", name, err)
+		}
+		return token, path, nil
+	case err != nil:
+		return "", "", fmt.Errorf("failed to read %s token: %v", name, err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", "", fmt.Errorf("%s token file %s is empty", name, path)
+	}
+
+	return token, path, nil
+}
+
+// newModeDriver creates the driver of the mode the admin endpoints switch to, its handle is kept open as well
+func (d *Daemon) newModeDriver(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error) {
+	drv, err := modeswitch.NewDriver(mode)
+	if err != nil {
+		return nil, err
+	}
+	return d.newDevicePool(drv), nil
+}
+
+// newDevicePool keeps the handle of drv open across the requests. The allowed devices are checked below the handle,
+// each time the device is opened.
+func (d *Daemon) newDevicePool(drv skyWallet.DeviceDriver) skyWallet.DeviceDriver {
+	if len(d.config.App.allowedDevices) != 0 {
+		drv = allowlist.NewDriver(drv, d.config.App.allowedDevices)
+	}
+	return devicepool.NewDriver(drv, d.config.App.DeviceIdleTimeout)
+}
+
+// serverComponents are the components created by Run which the API server is configured with, the fields are named
+// after the fields of api.Config
+type serverComponents struct {
+	Store            storage.Store
+	Events           *events.Bus
+	History          *history.Recorder
+	HistoryExportKey *history.ExportKey
+	AuditLog         *auditlog.Log
+	ModeSwitch       *modeswitch.Switch
+	AdminToken       string
+	Tracer           *tracing.Tracer
+	Stats            *stats.Collector
+	Relay            *relay.Relay
+	Emulator         *emulator.Emulator
+	Approvals        *approval.Manager
+	ApprovalToken    string
+	StartupChecks    *smoketest.Suite
+	UpdateCheck      *updatecheck.Checker
+	LogLevel         *loglevel.Controller
+	DeviceDeadline   *deadline.Driver
+	Drain            *drain.Tracker
+	DeviceLock       *devicelock.Lock
+	Plugins          []*plugin.Client
+}
+
+func (d *Daemon) createServer(host string, listener net.Listener, gateway api.Gatewayer, c serverComponents) (*api.Server, error) {
 	apiConfig := api.Config{
-		EnableCSRF:         d.config.App.EnableCSRF,
-		DisableHeaderCheck: d.config.App.DisableHeaderCheck,
-		HostWhitelist:      d.config.App.hostWhitelist,
-		Mode:               d.config.App.daemonMode,
-		Build:              d.config.Build,
+		EnableCSRF:          d.config.App.EnableCSRF,
+		CSRF:                d.config.App.csrf,
+		DisableHeaderCheck:  d.config.App.DisableHeaderCheck,
+		HostWhitelist:       d.config.App.hostWhitelist,
+		AllowedIPs:          d.config.App.allowedIPs,
+		BasicAuth:           d.config.App.basicAuth,
+		BehindProxy:         d.config.App.BehindProxy,
+		TrustedProxies:      d.config.App.trustedProxies,
+		Mode:                d.config.App.daemonMode,
+		Build:               d.config.Build,
+		Attestation:         d.config.App.attestation,
+		Store:               c.Store,
+		Events:              c.Events,
+		History:             c.History,
+		HistoryExportKey:    c.HistoryExportKey,
+		AuditLog:            c.AuditLog,
+		ConfirmationTimeout: d.config.App.ConfirmationTimeout,
+		FirmwareChannel:     d.config.App.firmwareChannel,
+		FirmwareRollout:     d.config.App.firmwareRollout,
+		FirmwareCache:       d.config.App.firmwareCache,
+		UpdateCheck:         c.UpdateCheck,
+		Profiler:            d.config.App.profiler,
+		LogLevel:            c.LogLevel,
+		WireCapture:         d.config.App.wireCapture,
+		TrustedBootloaders:  d.config.App.trustedBootloaders,
+		Transport:           d.transportName(),
+		FaultInjection:      d.config.App.Chaos,
+		SessionIdleTimeout:  d.config.App.SessionIdleTimeout,
+		RequireSession:      d.config.App.RequireSession,
+		RateLimits:          d.config.App.rateLimits,
+		CORS:                d.config.App.corsConfig,
+		DisableRequestID:    d.config.App.DisableRequestID,
+		ModeSwitch:          c.ModeSwitch,
+		AdminToken:          c.AdminToken,
+		Tracer:              c.Tracer,
+		Stats:               c.Stats,
+		Relay:               c.Relay,
+		Emulator:            c.Emulator,
+		Approvals:           c.Approvals,
+		ApprovalThreshold:   d.config.App.approvalThreshold,
+		ApprovalToken:       c.ApprovalToken,
+		SigningWindow:       d.config.App.signingWindow,
+		TransactionLimits:   d.config.App.transactionLimits,
+		StartupChecks:       c.StartupChecks,
+		DeviceDeadline:      c.DeviceDeadline,
+		Drain:               c.Drain,
+		DeviceLock:          c.DeviceLock,
+		ReadOnly:            d.config.App.ReadOnly,
+		DisableAPIV1:        d.config.App.DisableAPIV1,
+		APIV1Sunset:         d.config.App.apiV1Sunset,
+		DisableAPIDocs:      d.config.App.DisableAPIDocs,
+		APIDocsAssetsURL:    d.config.App.APIDocsAssetsURL,
+		SecurityHeaders:     d.config.App.securityHeaders,
+		AddressBook:         addressbook.New(c.Store),
+		Inventory:           inventory.New(c.Store),
+		Node:                d.config.App.nodeClient,
+		Coin:                d.config.App.coinBackend,
+	}
+
+	// the responses returned to the retries of the requests are kept in the storage, they survive a restart
+	if d.config.App.IdempotencyTTL > 0 {
+		apiConfig.Idempotency = idempotency.New(c.Store, d.config.App.IdempotencyTTL)
+	}
+
+	// the coins spent under the transaction policy are kept in the storage, a restart does not reset the limits
+	if d.config.App.transactionPolicy != nil {
+		apiConfig.TransactionPolicy = txpolicy.NewEngine(d.config.App.transactionPolicy, c.Store)
+	}
+
+	// the U2F requests are relayed to the U2F interface of the device, beside the wallet protocol
+	if d.config.App.EnableU2F {
+		apiConfig.U2F = u2f.NewRelay(d.config.App.U2FTimeout)
+	}
+
+	// the rules of the plugins are evaluated after the rules of the transaction policy, if there is one
+	for _, p := range c.Plugins {
+		if !p.Manifest().PolicyHook {
+			continue
+		}
+		if apiConfig.TransactionPolicy == nil {
+			apiConfig.TransactionPolicy = txpolicy.NewEngine(nil, c.Store)
+		}
+		apiConfig.TransactionPolicy.AddHook(p)
+	}
+	apiConfig.Plugins = c.Plugins
+
+	// the native messaging host serves the API on stdin and stdout, without listening
+	if d.config.App.NativeMessaging {
+		return api.CreateWithoutListener(host, apiConfig, gateway), nil
+	}
+
+	// a socket activated daemon serves the socket passed by systemd
+	if listener != nil {
+		return api.CreateWithListener(listener, apiConfig, gateway), nil
 	}
 
 	var s *api.Server
@@ -196,3 +1014,25 @@ func (d *Daemon) createServer(host string, gateway *api.Gateway) (*api.Server, e
 func (d *Daemon) ParseConfig() error {
 	return d.config.postProcess()
 }
+
+// newSimulator creates the simulated device of simulate-api and of the mock device
+func (d *Daemon) newSimulator() *simulator.Device {
+	if d.config.App.Deterministic {
+		return simulator.NewDeterministic(d.config.App.simulatorScript)
+	}
+	return simulator.New(d.config.App.simulatorScript)
+}
+
+// transportName names the transport to the device
+func (d *Daemon) transportName() string {
+	if d.config.App.SimulateAPI {
+		return "simulator"
+	}
+	if d.config.App.replayPlayer != nil {
+		return "replay"
+	}
+	if d.config.App.mockDevice {
+		return "mock"
+	}
+	return modeswitch.TransportName(d.config.App.daemonMode)
+}
@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/datadir"
+)
+
+// IsDataDirCheck returns true if the daemon was started with -datadir-check, to be run with CheckDataDir
+func (d *Daemon) IsDataDirCheck() bool {
+	return d.config.App.DataDirCheck
+}
+
+// CheckDataDir runs the -datadir-check command: migrates the data directory to its layout and checks it, returning an
+// error if it has problems
+func (d *Daemon) CheckDataDir() error {
+	problems, err := d.prepareDataDir()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range problems {
+		d.logger.Error(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the data directory %s has %d problems", d.config.App.DataDirectory, len(problems))
+	}
+
+	d.logger.Infof("The data directory %s is ready", d.config.App.DataDirectory)
+	return nil
+}
+
+// dataDir returns the layout of the data directory
+func (d *Daemon) dataDir() datadir.Layout {
+	return datadir.New(d.config.App.DataDirectory)
+}
+
+// prepareDataDir moves the legacy files of the data directory to their directory of the layout and returns the
+// problems of the data directory. The files the daemon is configured with are not moved.
+func (d *Daemon) prepareDataDir() ([]datadir.Problem, error) {
+	layout := d.dataDir()
+	moves, err := layout.Migrate(d.config.App.AuditLog, d.config.App.RecordMessages, d.config.App.ReplayMessages,
+		d.config.App.ProfileCPUFile, d.config.App.HistoryExportKey, d.config.App.RelayKey)
+	for _, m := range moves {
+		d.logger.Infof("Moved %s to %s", m.From, m.To)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate the data directory %s: %v", layout.Root, err)
+	}
+
+	return layout.Check(datadir.MinFreeSpace), nil
+}
@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/keyring"
+)
+
+// KeyringSetOutput is the JSON output of keyring set
+type KeyringSetOutput struct {
+	Name string `json:"name"`
+	// Reference is the value of the setting referring to the secret
+	Reference string `json:"reference"`
+}
+
+// keyringSet stores the secret read from the standard input in the OS keyring under the name of -name.
+// The secret is read without echo from a terminal, or up to the end of the input with its trailing newline removed.
+func keyringSet(name string, w io.Writer, jsonOutput bool) error {
+	if name == "" {
+		return errors.New("keyring set: the secret name is missing, set it with -name")
+	}
+	if err := keyring.ValidateName(name); err != nil {
+		return err
+	}
+
+	secret, err := readSecret(os.Stdin, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to read the secret: %v", err)
+	}
+	if err := keyring.Set(name, secret); err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return writeJSON(w, KeyringSetOutput{
+			Name:      name,
+			Reference: keyring.Reference(name),
+		})
+	}
+
+	_, err = fmt.Fprintf(w, "The secret %s is stored in the keyring, refer to it with %s\n", name, keyring.Reference(name))
+	return err
+}
+
+// readSecret reads the secret from in, prompting on out if in is a terminal
+func readSecret(in *os.File, out io.Writer) (string, error) {
+	if terminal.IsTerminal(int(in.Fd())) {
+		fmt.Fprint(out, "Secret: ")
+		b, err := terminal.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(out)
+		return string(b), err
+	}
+
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/skycoin/skycoin/src/util/file"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/nativemsg"
+)
+
+// nativeMessagingDir is the directory of the launcher started by the browsers, in the data directory
+const nativeMessagingDir = "native-messaging"
+
+// NativeMessagingOutput is the JSON output of native-messaging install and uninstall
+type NativeMessagingOutput struct {
+	Host      string `json:"host"`
+	Installed bool   `json:"installed"`
+	// Launcher is the launcher started by the browsers, empty once uninstalled
+	Launcher string `json:"launcher,omitempty"`
+	// Manifests are the paths of the manifests installed or removed
+	Manifests []string `json:"manifests"`
+}
+
+// installNativeMessaging installs the native messaging manifests of the browsers, allowing the extensions of c
+// to start the daemon with the flags set on the command line
+func installNativeMessaging(c AppConfig, w io.Writer, jsonOutput bool) error {
+	extensions, err := nativemsg.ParseExtensions(c.NativeMessagingExtensions)
+	if err != nil {
+		return err
+	}
+	if len(extensions.ChromeOrigins) == 0 && len(extensions.FirefoxIDs) == 0 {
+		return errors.New("no extension is allowed, set the extensions with -native-messaging-extensions")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return err
+	}
+
+	// the browsers start the launcher with their own arguments, it runs the daemon with these flags instead
+	args := []string{"-native-messaging"}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "native-messaging", "native-messaging-extensions":
+		default:
+			args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+		}
+	})
+
+	home := file.UserHome()
+	dir := filepath.Join(replaceHome(c.DataDirectory, home), nativeMessagingDir)
+	paths, err := nativemsg.Install(home, dir, exe, args, extensions)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return writeJSON(w, NativeMessagingOutput{
+			Host:      nativemsg.HostName,
+			Installed: true,
+			Launcher:  nativemsg.LauncherPath(dir),
+			Manifests: paths,
+		})
+	}
+
+	fmt.Fprintf(w, "Installed the %s native messaging host, started with %s\n", nativemsg.HostName, nativemsg.LauncherPath(dir))
+	for _, p := range paths {
+		fmt.Fprintln(w, p)
+	}
+	return nil
+}
+
+// uninstallNativeMessaging removes the native messaging manifests and the launcher
+func uninstallNativeMessaging(c AppConfig, w io.Writer, jsonOutput bool) error {
+	home := file.UserHome()
+	dir := filepath.Join(replaceHome(c.DataDirectory, home), nativeMessagingDir)
+	paths, err := nativemsg.Uninstall(home, dir)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		if paths == nil {
+			paths = []string{}
+		}
+		return writeJSON(w, NativeMessagingOutput{
+			Host:      nativemsg.HostName,
+			Manifests: paths,
+		})
+	}
+
+	if len(paths) == 0 {
+		_, err := fmt.Fprintf(w, "The %s native messaging host is not installed\n", nativemsg.HostName)
+		return err
+	}
+
+	fmt.Fprintf(w, "Uninstalled the %s native messaging host\n", nativemsg.HostName)
+	for _, p := range paths {
+		fmt.Fprintln(w, p)
+	}
+	return nil
+}
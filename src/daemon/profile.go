@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/skycoin/skycoin/src/util/file"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/profile"
+)
+
+// defaultPort is the port of the daemon running without profile, the profiles are given the next ones
+const defaultPort = 9510
+
+// ApplyProfile applies the settings of the profile selected with -profile, creating the profile on first use.
+// The profile directory, in the data directory, becomes the data directory of the daemon.
+// Must be called after the flags are parsed, the flags set on the command line take precedence over the profile settings.
+func (c *AppConfig) ApplyProfile() error {
+	if c.Profile == "" {
+		return nil
+	}
+
+	p, err := profile.Open(replaceHome(c.DataDirectory, file.UserHome()), c.Profile, defaultPort)
+	if err != nil {
+		return fmt.Errorf("failed to open profile %s: %v", c.Profile, err)
+	}
+
+	if err := p.Apply(flag.CommandLine, "profile", "data-dir", "help"); err != nil {
+		return err
+	}
+
+	c.DataDirectory = p.Dir
+	return nil
+}
+
+// commands are the commands run by RunCommand
+var commands = []string{
+	"profiles list",
+	"relay pair",
+	"native-messaging install",
+	"native-messaging uninstall",
+	"daemon status",
+	"daemon features",
+	"daemon sign",
+	"daemon address",
+	"audit verify",
+	"keyring set",
+}
+
+// RunCommand runs the command of args, writing its output to w. The commands are "profiles list",
+// "relay pair" creating a pairing code on the running daemon, "native-messaging install" and
+// "native-messaging uninstall" installing the daemon as the native messaging host of the browsers, and
+// "daemon status", "daemon features", "daemon sign" and "daemon address" using the device of the running daemon,
+// "audit verify" verifying the audit log file, and "keyring set" storing a secret of the settings in the OS keyring.
+// The --json flag after the command writes its output as JSON instead, for scripts.
+func RunCommand(c AppConfig, args []string, w io.Writer) error {
+	// the commands are two words, followed by their flags
+	n := len(args)
+	if n > 2 {
+		n = 2
+	}
+	command := strings.Join(args[:n], " ")
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	jsonOutput := fs.Bool("json", false, "Write the output as JSON")
+	var df daemonFlags
+	registerDaemonFlags(fs, command, &df)
+	if err := fs.Parse(args[n:]); err != nil {
+		return fmt.Errorf("%s: %v", command, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%s: unexpected arguments %s", command, strings.Join(fs.Args(), " "))
+	}
+
+	switch command {
+	case "profiles list":
+		return listProfiles(c, w, *jsonOutput)
+	case "relay pair":
+		return relayPair(c, w, *jsonOutput)
+	case "native-messaging install":
+		return installNativeMessaging(c, w, *jsonOutput)
+	case "native-messaging uninstall":
+		return uninstallNativeMessaging(c, w, *jsonOutput)
+	case "daemon status":
+		return daemonStatus(c, w, *jsonOutput)
+	case "daemon features":
+		return daemonFeatures(c, w, *jsonOutput)
+	case "daemon sign":
+		return daemonSign(c, df, w, *jsonOutput)
+	case "daemon address":
+		return daemonAddress(c, df, w, *jsonOutput)
+	case "audit verify":
+		return auditVerify(c, w, *jsonOutput)
+	case "keyring set":
+		return keyringSet(df.name, w, *jsonOutput)
+	default:
+		return fmt.Errorf("unknown command %q, the commands are: %s", command, strings.Join(commands, ", "))
+	}
+}
+
+// writeJSON writes the JSON output of a command, indented as the API responses
+func writeJSON(w io.Writer, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+// ProfileOutput is a profile in the JSON output of profiles list
+type ProfileOutput struct {
+	Name          string `json:"name"`
+	Port          int    `json:"port"`
+	DataDirectory string `json:"data_directory"`
+}
+
+func listProfiles(c AppConfig, w io.Writer, jsonOutput bool) error {
+	profiles, err := profile.List(replaceHome(c.DataDirectory, file.UserHome()))
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		out := make([]ProfileOutput, 0, len(profiles))
+		for _, p := range profiles {
+			out = append(out, ProfileOutput{
+				Name:          p.Name,
+				Port:          p.Port(),
+				DataDirectory: p.Dir,
+			})
+		}
+		return writeJSON(w, out)
+	}
+
+	if len(profiles) == 0 {
+		_, err := fmt.Fprintln(w, "No profiles, run the daemon with -profile <name> to create one")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPORT\tDATA DIRECTORY")
+	for _, p := range profiles {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", p.Name, p.Port(), p.Dir)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/api"
+	"github.com/skycoin/hardware-wallet-daemon/src/qrcode"
+)
+
+// errNotServed is returned by apiRequest for an endpoint which is not served by the daemon
+var errNotServed = errors.New("endpoint not served")
+
+// relayPair creates a pairing code on the daemon running with the config c, writing the QR code of its pairing URI.
+// The JSON output is the response of the pairing code endpoint.
+func relayPair(c AppConfig, w io.Writer, jsonOutput bool) error {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	base := fmt.Sprintf("http://%s:%d/api/v2", c.WebInterfaceAddr, c.WebInterfacePort)
+
+	req, err := http.NewRequest(http.MethodPost, base+"/relay/pairing_code", nil)
+	if err != nil {
+		return err
+	}
+
+	if c.EnableCSRF {
+		tokenReq, err := http.NewRequest(http.MethodGet, base+"/csrf", nil)
+		if err != nil {
+			return err
+		}
+
+		var token string
+		if err := apiRequest(client, tokenReq, &token); err != nil {
+			return err
+		}
+		req.Header.Set(api.CSRFHeaderName, token)
+	}
+
+	var pc api.RelayPairingCodeResponse
+	switch err := apiRequest(client, req, &pc); err {
+	case nil:
+	case errNotServed:
+		return errors.New("the relay mode of the daemon is not enabled, run it with -relay-url")
+	default:
+		return err
+	}
+
+	if jsonOutput {
+		return writeJSON(w, pc)
+	}
+
+	code, err := qrcode.Encode([]byte(pc.URI))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\nScan the QR code with the mobile wallet, or enter the pairing code %s\nThe code can be used once, until %s\n",
+		code.Text(), pc.Code, pc.ExpiresAt.Local().Format(time.RFC1123))
+	return err
+}
+
+// apiRequest sends the request to the daemon API, decoding the data of its response
+func apiRequest(client *http.Client, req *http.Request, data interface{}) error {
+	rsp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the daemon, is it running? %v", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return errNotServed
+	}
+
+	var r api.ReceivedHTTPResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("invalid daemon response: %v", err)
+	}
+	if r.Error != nil {
+		return fmt.Errorf("daemon returned %s: %s", rsp.Status, r.Error.Message)
+	}
+
+	return json.Unmarshal(r.Data, data)
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/winservice"
+)
+
+// IsServiceCommand returns true if the daemon was started with a -service command, to be run with RunService
+func (d *Daemon) IsServiceCommand() bool {
+	return d.config.App.service != ""
+}
+
+// RunService runs the -service command: installs the Windows service running the daemon with args,
+// the command line arguments of the daemon, uninstalls it, or runs the daemon as the service
+func (d *Daemon) RunService(args []string) error {
+	switch d.config.App.service {
+	case winservice.CommandInstall:
+		if err := winservice.Install(winservice.ServiceArgs(args)); err != nil {
+			return err
+		}
+		d.logger.Infof("Installed the %s service, start it with: sc start %s", winservice.Name, winservice.Name)
+		return nil
+
+	case winservice.CommandUninstall:
+		if err := winservice.Uninstall(); err != nil {
+			return err
+		}
+		d.logger.Infof("Uninstalled the %s service", winservice.Name)
+		return nil
+
+	default:
+		// the service has no console, the logs are written to the event log
+		eventLog, err := winservice.OpenEventLog()
+		if err != nil {
+			return err
+		}
+		defer eventLog.Close() // nolint: errcheck
+		logging.AddHook(eventLog)
+
+		return winservice.Run(func(stop <-chan struct{}) error {
+			done := make(chan struct{})
+			defer close(done)
+
+			go func() {
+				select {
+				case <-stop:
+					d.Stop()
+				case <-done:
+				}
+			}()
+
+			return d.Run()
+		})
+	}
+}
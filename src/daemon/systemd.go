@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemd notification states, see sd_notify(3)
+const (
+	systemdReady    = "READY=1"
+	systemdStopping = "STOPPING=1"
+	systemdWatchdog = "WATCHDOG=1"
+)
+
+// systemdListenFdsStart is the first file descriptor passed by systemd socket activation, see sd_listen_fds(3)
+const systemdListenFdsStart = 3
+
+// systemdListener returns the socket passed by systemd when the daemon is started by a socket unit,
+// nil if the daemon is not socket activated and has to bind the web interface address itself
+func systemdListener() (net.Listener, error) {
+	pid := os.Getenv("LISTEN_PID")
+	fds := os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+
+	// the sockets are not passed on to the processes the daemon starts
+	defer os.Unsetenv("LISTEN_PID")     // nolint: errcheck
+	defer os.Unsetenv("LISTEN_FDS")     // nolint: errcheck
+	defer os.Unsetenv("LISTEN_FDNAMES") // nolint: errcheck
+
+	// the sockets are meant for another process
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q: %v", fds, err)
+	}
+	switch {
+	case n == 0:
+		return nil, nil
+	case n > 1:
+		return nil, fmt.Errorf("systemd passed %d sockets, the daemon serves a single socket", n)
+	}
+
+	f := os.NewFile(uintptr(systemdListenFdsStart), "LISTEN_FD_3")
+	defer f.Close()
+
+	// the listener uses a duplicate of the file descriptor
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket passed by systemd: %v", err)
+	}
+
+	// the Host header check compares the requests to a TCP host:port
+	if _, ok := listener.Addr().(*net.TCPAddr); !ok {
+		listener.Close() // nolint: errcheck
+		return nil, errors.New("the socket passed by systemd must be a TCP stream socket")
+	}
+
+	return listener, nil
+}
+
+// notifySystemd sends state to the service manager of a systemd Type=notify service.
+// It returns false without error if the daemon is not run by systemd.
+func notifySystemd(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// a leading @ names an abstract socket
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{
+		Name: socket,
+		Net:  "unixgram",
+	})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// systemdWatchdogInterval returns the watchdog timeout of the service set by WatchdogSec, 0 if it has none
+func systemdWatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+
+	// the watchdog is meant for another process
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, err
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// liveCheck requests the liveness endpoint of the web interface at host. The request has its own transport,
+// it is not an outbound request of the daemon and is sent in airgap mode too.
+func liveCheck(host string) func() error {
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{},
+	}
+
+	return func() error {
+		rsp, err := client.Get(fmt.Sprintf("http://%s/live", host))
+		if err != nil {
+			return err
+		}
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusOK {
+			return fmt.Errorf("/live returned %s", rsp.Status)
+		}
+		return nil
+	}
+}
+
+// systemdWatchdog notifies the systemd watchdog twice per timeout until quit is closed,
+// alive checks that the daemon still serves requests before each notification
+func (d *Daemon) systemdWatchdog(interval time.Duration, alive func() error, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			if err := alive(); err != nil {
+				d.logger.WithError(err).Error("Liveness check failed, not notifying the systemd watchdog")
+				continue
+			}
+			if _, err := notifySystemd(systemdWatchdog); err != nil {
+				d.logger.WithError(err).Error("Failed to notify the systemd watchdog")
+			}
+		}
+	}
+}
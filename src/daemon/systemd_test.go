@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/airgap"
+)
+
+func TestLiveCheckAirgap(t *testing.T) {
+	live := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/live", r.URL.Path)
+		if !live {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	c := AppConfig{
+		Airgap: true,
+	}
+
+	// the outbound requests are refused
+	client := &http.Client{
+		Transport: c.outboundTransport(),
+	}
+	_, err := client.Get(srv.URL + "/live")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), airgap.ErrAirgapped.Error())
+
+	// the request of the watchdog to the web interface is not
+	check := liveCheck(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, check())
+
+	live = false
+	require.EqualError(t, check(), "/live returned 503 Service Unavailable")
+}
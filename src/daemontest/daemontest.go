@@ -0,0 +1,199 @@
+// Package daemontest runs a daemon inside the Go tests, for the black-box tests of the API of the downstream
+// projects. The daemon listens on a random port of the loopback interface, keeps its data in a temporary directory
+// and serves the real handlers and middlewares, with the simulated device or the emulator.
+package daemontest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/api"
+	"github.com/skycoin/hardware-wallet-daemon/src/client"
+	"github.com/skycoin/hardware-wallet-daemon/src/daemon"
+)
+
+const (
+	// ModeMock serves the API with the mock device, answering as the simulated device
+	ModeMock = "MOCK"
+	// ModeEmulator serves the API with the emulator, run separately or with -emulator-binary
+	ModeEmulator = "EMULATOR"
+
+	// DefaultStartTimeout is how long Start waits for the daemon to serve the API
+	DefaultStartTimeout = 10 * time.Second
+
+	// startAttempts is the number of ports tried, another process may take the port picked before the daemon listens
+	startAttempts = 3
+)
+
+var logger = logging.MustGetLogger("daemontest")
+
+// Options configures the daemon of a test
+type Options struct {
+	// Mode is the daemon mode, ModeMock or ModeEmulator. Defaults to ModeMock
+	Mode string
+	// Configure changes the settings of the daemon before it starts, such as enabling the CSRF check or
+	// -deterministic. The address, the port and the data directory are set by Start
+	Configure func(c *daemon.AppConfig)
+	// Prompter answers the PIN, passphrase and word requests of the device for the client
+	Prompter client.Prompter
+	// StartTimeout is how long Start waits for the daemon to serve the API, defaults to DefaultStartTimeout
+	StartTimeout time.Duration
+}
+
+// Daemon is a daemon running in a test
+type Daemon struct {
+	// Addr is the host:port the daemon serves the API on
+	Addr string
+	// DataDirectory is the temporary data directory of the daemon, removed by Close
+	DataDirectory string
+	// Client calls the API of the daemon
+	Client *client.Client
+
+	d         *daemon.Daemon
+	done      chan error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Start starts a daemon and returns once it serves the API. The test must Close it.
+func Start(opts Options) (*Daemon, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeMock
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = DefaultStartTimeout
+	}
+
+	dir, err := ioutil.TempDir("", "daemontest")
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		td, err := start(opts, dir)
+		if err == nil {
+			return td, nil
+		}
+		if attempt == startAttempts || !isAddrInUse(err) {
+			os.RemoveAll(dir) // nolint: errcheck
+			return nil, err
+		}
+		logger.WithError(err).Warning("The port is taken, retrying on another port")
+	}
+}
+
+// start starts a daemon on a free port
+func start(opts Options, dir string) (*Daemon, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	c := daemon.NewAppConfig(port, dir)
+	c.DaemonMode = opts.Mode
+	c.ColorLog = false
+	c.LogLevel = "ERROR"
+	if opts.Configure != nil {
+		opts.Configure(&c)
+	}
+	c.WebInterfaceAddr = "127.0.0.1"
+	c.WebInterfacePort = port
+	c.DataDirectory = dir
+
+	d := daemon.NewDaemon(daemon.Config{
+		App: c,
+		Build: api.BuildInfo{
+			Version: "daemontest",
+		},
+	}, logger)
+	if err := d.ParseConfig(); err != nil {
+		return nil, err
+	}
+
+	td := &Daemon{
+		Addr:          fmt.Sprintf("127.0.0.1:%d", port),
+		DataDirectory: dir,
+		d:             d,
+		done:          make(chan error, 1),
+	}
+	td.Client = client.NewClient(client.Config{
+		Addr:     td.Addr,
+		Prompter: opts.Prompter,
+	})
+
+	go func() {
+		td.done <- d.Run()
+	}()
+
+	if err := td.wait(opts.StartTimeout); err != nil {
+		d.Stop()
+		<-td.done
+		return nil, err
+	}
+
+	return td, nil
+}
+
+// wait waits until the daemon serves the API, it stops or the timeout expires
+func (td *Daemon) wait(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		// the version is answered without the device
+		_, err := td.Client.Version(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case runErr := <-td.done:
+			// Run returns the errors of the startup, such as the port being taken
+			td.done <- runErr
+			if runErr == nil {
+				runErr = errors.New("the daemon stopped")
+			}
+			return runErr
+		case <-ctx.Done():
+			return fmt.Errorf("the daemon did not serve the API within %s: %v", timeout, err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// Close stops the daemon and removes its data directory
+func (td *Daemon) Close() error {
+	td.closeOnce.Do(func() {
+		td.d.Stop()
+		td.closeErr = <-td.done
+		if err := os.RemoveAll(td.DataDirectory); err != nil && td.closeErr == nil {
+			td.closeErr = err
+		}
+	})
+	return td.closeErr
+}
+
+// freePort returns a port of the loopback interface no process listens on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close() // nolint: errcheck
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// isAddrInUse returns true if the daemon could not listen on its port
+func isAddrInUse(err error) bool {
+	return strings.Contains(err.Error(), "address already in use")
+}
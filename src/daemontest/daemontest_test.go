@@ -0,0 +1,51 @@
+package daemontest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/daemon"
+)
+
+func TestStart(t *testing.T) {
+	d, err := Start(Options{
+		Configure: func(c *daemon.AppConfig) {
+			c.Deterministic = true
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = os.Stat(d.DataDirectory)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	version, err := d.Client.Version(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "daemontest", version.Version)
+
+	// the simulated device is seeded with the deterministic mnemonic
+	addresses, err := d.Client.GenerateAddresses(ctx, 2, 0, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2EFSW8YqFDG3x6mwfbDjBk6M9eMc6WUoFHZ", "24qhX1ZC9F8iDaT6R13j4fgJyQvumW2izpm"}, addresses)
+
+	// another daemon runs beside
+	other, err := Start(Options{})
+	require.NoError(t, err)
+	require.NotEqual(t, d.Addr, other.Addr)
+	require.NoError(t, other.Close())
+
+	require.NoError(t, d.Close())
+	require.NoError(t, d.Close())
+	_, err = os.Stat(d.DataDirectory)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestStartInvalidConfig(t *testing.T) {
+	_, err := Start(Options{
+		Mode: "INVALID",
+	})
+	require.EqualError(t, err, "invalid device type")
+}
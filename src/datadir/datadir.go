@@ -0,0 +1,272 @@
+// Package datadir lays out the data directory of the daemon: the log files in logs/, the storage in db/, the cached
+// firmware in firmware-cache/, the audit log in audit/, the profiles of the admin profiling endpoints in pprof/ and
+// the keys generated by the daemon in keys/. The tokens generated by the daemon stay in the root of the data
+// directory, where the clients read them. Migrate moves the files left in the root by the earlier releases to their
+// directory, and Check validates the permissions and the free space of the data directory before the daemon serves.
+package datadir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// LogsDir is the directory of the log files written with -logtofile
+	LogsDir = "logs"
+	// DBDir is the directory of the storage backend
+	DBDir = "db"
+	// FirmwareCacheDir is the directory of the cached firmware images
+	FirmwareCacheDir = "firmware-cache"
+	// AuditDir is the directory of the audit log
+	AuditDir = "audit"
+	// PprofDir is the directory of the profiles captured by the admin profiling endpoints
+	PprofDir = "pprof"
+	// KeysDir is the directory of the keys generated by the daemon
+	KeysDir = "keys"
+
+	// HistoryExportKeyFilename is the name of the key signing the history archives in KeysDir
+	HistoryExportKeyFilename = "history-export.key"
+	// RelayKeyFilename is the name of the key encrypting the relayed requests in KeysDir
+	RelayKeyFilename = "relay.key"
+	// LogFileTimeFormat is the time format of the names of the log files in LogsDir, followed by the .log extension
+	LogFileTimeFormat = "2006-01-02-030405"
+
+	// MinFreeSpace is the free space Check requires on the disk of the data directory, 64 MiB
+	MinFreeSpace = 64 << 20
+
+	// dirPerm is the permission of the directories of the layout
+	dirPerm = 0750
+	// auditLogFilename is the name of the audit log left in the root of the data directory
+	auditLogFilename = "audit.log"
+	// logFileExt is the extension of the log files
+	logFileExt = ".log"
+)
+
+// dirs are the directories of the layout
+var dirs = []string{LogsDir, DBDir, FirmwareCacheDir, AuditDir, PprofDir, KeysDir}
+
+// Layout is the layout of a data directory
+type Layout struct {
+	// Root is the data directory
+	Root string
+}
+
+// New returns the layout of the data directory root
+func New(root string) Layout {
+	return Layout{
+		Root: root,
+	}
+}
+
+// Logs returns the directory of the log files
+func (l Layout) Logs() string {
+	return filepath.Join(l.Root, LogsDir)
+}
+
+// DB returns the directory of the storage
+func (l Layout) DB() string {
+	return filepath.Join(l.Root, DBDir)
+}
+
+// FirmwareCache returns the directory of the cached firmware images
+func (l Layout) FirmwareCache() string {
+	return filepath.Join(l.Root, FirmwareCacheDir)
+}
+
+// Audit returns the directory of the audit log
+func (l Layout) Audit() string {
+	return filepath.Join(l.Root, AuditDir)
+}
+
+// Pprof returns the directory of the profiles
+func (l Layout) Pprof() string {
+	return filepath.Join(l.Root, PprofDir)
+}
+
+// Keys returns the directory of the keys generated by the daemon
+func (l Layout) Keys() string {
+	return filepath.Join(l.Root, KeysDir)
+}
+
+// Dirs returns the directories of the layout
+func (l Layout) Dirs() []string {
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		paths[i] = filepath.Join(l.Root, dir)
+	}
+	return paths
+}
+
+// Create creates the directories of the layout missing in the data directory
+func (l Layout) Create() error {
+	for _, dir := range l.Dirs() {
+		if err := os.MkdirAll(dir, dirPerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move is a legacy file moved by Migrate
+type Move struct {
+	From string
+	To   string
+}
+
+// legacyDir returns the directory of the layout a file left in the root of the data directory belongs to, or "" if it
+// stays in the root. Only the files named as the daemon names them are moved, the data directory can be shared with
+// the Skycoin node, whose files stay in place.
+func legacyDir(name string) string {
+	switch {
+	case name == auditLogFilename:
+		return AuditDir
+	case name == HistoryExportKeyFilename, name == RelayKeyFilename:
+		return KeysDir
+	case isLogFilename(name):
+		return LogsDir
+	default:
+		return ""
+	}
+}
+
+// isLogFilename reports whether name is the name of a log file written by the daemon
+func isLogFilename(name string) bool {
+	if !strings.HasSuffix(name, logFileExt) {
+		return false
+	}
+	_, err := time.Parse(LogFileTimeFormat, strings.TrimSuffix(name, logFileExt))
+	return err == nil
+}
+
+// Migrate creates the layout and moves the files left in the root of the data directory to their directory: the log
+// files to logs/, audit.log to audit/ and the generated keys to keys/. The paths of keep,
+// the files the daemon is configured to use, and the files whose destination exists are left in place, so Migrate
+// can run on every start. The files moved are returned.
+func (l Layout) Migrate(keep ...string) ([]Move, error) {
+	if err := l.Create(); err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]struct{}, len(keep))
+	for _, path := range keep {
+		if path != "" {
+			kept[filepath.Clean(path)] = struct{}{}
+		}
+	}
+
+	files, err := ioutil.ReadDir(l.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []Move
+	for _, f := range files {
+		dir := legacyDir(f.Name())
+		if f.IsDir() || dir == "" {
+			continue
+		}
+
+		from := filepath.Join(l.Root, f.Name())
+		if _, ok := kept[from]; ok {
+			continue
+		}
+
+		to := filepath.Join(l.Root, dir, f.Name())
+		if _, err := os.Lstat(to); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return moves, err
+		}
+
+		if err := os.Rename(from, to); err != nil {
+			return moves, fmt.Errorf("failed to move %s to %s: %v", from, to, err)
+		}
+		moves = append(moves, Move{
+			From: from,
+			To:   to,
+		})
+	}
+
+	return moves, nil
+}
+
+// Problem is a problem of the data directory found by Check
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Check checks that the data directory and the directories of the layout exist, are writable by the daemon and not by
+// the other users, and that the disk of the data directory has minFree bytes free. The problems found are returned.
+func (l Layout) Check(minFree uint64) []Problem {
+	var problems []Problem
+	for _, dir := range append([]string{l.Root}, l.Dirs()...) {
+		if msg := checkDir(dir); msg != "" {
+			problems = append(problems, Problem{
+				Path:    dir,
+				Message: msg,
+			})
+		}
+	}
+
+	free, err := freeSpace(l.Root)
+	switch {
+	case err == errUnsupported:
+	case err != nil:
+		problems = append(problems, Problem{
+			Path:    l.Root,
+			Message: fmt.Sprintf("failed to read the free space: %v", err),
+		})
+	case free < minFree:
+		problems = append(problems, Problem{
+			Path:    l.Root,
+			Message: fmt.Sprintf("%d MiB free, at least %d MiB are needed", free>>20, minFree>>20),
+		})
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Path < problems[j].Path
+	})
+	return problems
+}
+
+// checkDir returns the problem of a directory of the data directory, or "" if it has none
+func checkDir(dir string) string {
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		return "does not exist"
+	case err != nil:
+		return err.Error()
+	case !info.IsDir():
+		return "is not a directory"
+	}
+
+	// the data directory holds the keys and the tokens of the daemon, the permissions are not checked on Windows where
+	// they are set by the ACLs of the user profile
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0022 != 0 {
+		return fmt.Sprintf("is writable by the other users (%04o), run chmod go-w", info.Mode().Perm())
+	}
+
+	f, err := ioutil.TempFile(dir, ".datadir-check")
+	if err != nil {
+		return fmt.Sprintf("is not writable: %v", err)
+	}
+	name := f.Name()
+	f.Close() // nolint: errcheck
+	if err := os.Remove(name); err != nil {
+		return fmt.Sprintf("failed to remove %s: %v", name, err)
+	}
+
+	return ""
+}
@@ -0,0 +1,144 @@
+package datadir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "datadir")
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(dir, 0700))
+	return dir
+}
+
+func writeFile(t *testing.T, path, data string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLayout(t *testing.T) {
+	l := New(filepath.Join("data"))
+	require.Equal(t, filepath.Join("data", "logs"), l.Logs())
+	require.Equal(t, filepath.Join("data", "db"), l.DB())
+	require.Equal(t, filepath.Join("data", "firmware-cache"), l.FirmwareCache())
+	require.Equal(t, filepath.Join("data", "audit"), l.Audit())
+	require.Equal(t, filepath.Join("data", "pprof"), l.Pprof())
+	require.Equal(t, filepath.Join("data", "keys"), l.Keys())
+	require.Equal(t, []string{l.Logs(), l.DB(), l.FirmwareCache(), l.Audit(), l.Pprof(), l.Keys()}, l.Dirs())
+}
+
+func TestMigrate(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root) // nolint: errcheck
+	l := New(root)
+
+	writeFile(t, filepath.Join(root, "2019-01-02-030405.log"), "log")
+	writeFile(t, filepath.Join(root, "audit.log"), "audit")
+	writeFile(t, filepath.Join(root, "admin.token"), "token")
+	writeFile(t, filepath.Join(root, "history-export.key"), "key")
+	writeFile(t, filepath.Join(root, "relay.key"), "relay")
+	writeFile(t, filepath.Join(root, "recording.log"), "kept")
+	// the files of the Skycoin node sharing the data directory are not moved
+	writeFile(t, filepath.Join(root, "skycoin.log"), "skycoin")
+	writeFile(t, filepath.Join(root, "2019-01-02.log"), "other")
+	require.NoError(t, os.Mkdir(filepath.Join(root, "profiles"), 0700))
+
+	// the destination exists, the legacy file is left in place
+	require.NoError(t, os.Mkdir(l.Logs(), 0700))
+	writeFile(t, filepath.Join(l.Logs(), "2019-05-06-070809.log"), "new")
+	writeFile(t, filepath.Join(root, "2019-05-06-070809.log"), "old")
+
+	moves, err := l.Migrate(filepath.Join(root, "recording.log"), filepath.Join(root, "relay.key"), "")
+	require.NoError(t, err)
+	require.Equal(t, []Move{
+		{From: filepath.Join(root, "2019-01-02-030405.log"), To: filepath.Join(l.Logs(), "2019-01-02-030405.log")},
+		{From: filepath.Join(root, "audit.log"), To: filepath.Join(l.Audit(), "audit.log")},
+		{From: filepath.Join(root, "history-export.key"), To: filepath.Join(l.Keys(), "history-export.key")},
+	}, moves)
+
+	for _, dir := range l.Dirs() {
+		info, err := os.Stat(dir)
+		require.NoError(t, err)
+		require.True(t, info.IsDir())
+	}
+	require.Equal(t, "log", readFile(t, filepath.Join(l.Logs(), "2019-01-02-030405.log")))
+	require.Equal(t, "audit", readFile(t, filepath.Join(l.Audit(), "audit.log")))
+	require.Equal(t, "token", readFile(t, filepath.Join(root, "admin.token")))
+	require.Equal(t, "key", readFile(t, filepath.Join(l.Keys(), "history-export.key")))
+	require.Equal(t, "relay", readFile(t, filepath.Join(root, "relay.key")))
+	require.Equal(t, "kept", readFile(t, filepath.Join(root, "recording.log")))
+	require.Equal(t, "skycoin", readFile(t, filepath.Join(root, "skycoin.log")))
+	require.Equal(t, "other", readFile(t, filepath.Join(root, "2019-01-02.log")))
+	require.Equal(t, "new", readFile(t, filepath.Join(l.Logs(), "2019-05-06-070809.log")))
+	require.Equal(t, "old", readFile(t, filepath.Join(root, "2019-05-06-070809.log")))
+
+	// nothing is left to move
+	moves, err = l.Migrate(filepath.Join(root, "recording.log"), filepath.Join(root, "relay.key"))
+	require.NoError(t, err)
+	require.Empty(t, moves)
+}
+
+func TestCheck(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root) // nolint: errcheck
+	l := New(root)
+
+	require.Equal(t, []Problem{
+		{Path: l.Audit(), Message: "does not exist"},
+		{Path: l.DB(), Message: "does not exist"},
+		{Path: l.FirmwareCache(), Message: "does not exist"},
+		{Path: l.Keys(), Message: "does not exist"},
+		{Path: l.Logs(), Message: "does not exist"},
+		{Path: l.Pprof(), Message: "does not exist"},
+	}, l.Check(0))
+
+	require.NoError(t, l.Create())
+	require.Empty(t, l.Check(0))
+
+	// no disk has that much free space
+	problems := l.Check(1 << 62)
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		require.Len(t, problems, 1)
+		require.Equal(t, root, problems[0].Path)
+		require.Contains(t, problems[0].Message, "MiB are needed")
+	}
+
+	require.NoError(t, os.RemoveAll(l.FirmwareCache()))
+	writeFile(t, l.FirmwareCache(), "")
+	require.Equal(t, []Problem{
+		{Path: l.FirmwareCache(), Message: "is not a directory"},
+	}, l.Check(0))
+	require.NoError(t, os.Remove(l.FirmwareCache()))
+	require.NoError(t, l.Create())
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	require.NoError(t, os.Chmod(l.DB(), 0777))
+	require.Equal(t, []Problem{
+		{Path: l.DB(), Message: "is writable by the other users (0777), run chmod go-w"},
+	}, l.Check(0))
+	require.NoError(t, os.Chmod(l.DB(), 0750))
+
+	// root can write in a read-only directory
+	if os.Geteuid() != 0 {
+		require.NoError(t, os.Chmod(l.Logs(), 0500))
+		defer os.Chmod(l.Logs(), 0700) // nolint: errcheck
+		problems := l.Check(0)
+		require.Len(t, problems, 1)
+		require.Equal(t, l.Logs(), problems[0].Path)
+		require.Contains(t, problems[0].Message, "is not writable")
+	}
+}
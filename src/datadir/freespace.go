@@ -0,0 +1,6 @@
+package datadir
+
+import "errors"
+
+// errUnsupported is returned by freeSpace when the free space cannot be read on the platform
+var errUnsupported = errors.New("reading the free space is not supported on this platform")
@@ -0,0 +1,8 @@
+// +build !linux,!darwin,!freebsd,!windows
+
+package datadir
+
+// freeSpace is not supported on the platform, Check skips the free space
+func freeSpace(path string) (uint64, error) {
+	return 0, errUnsupported
+}
@@ -0,0 +1,297 @@
+// Package deadline bounds the time the device has to answer the messages, and cancels them with the HTTP request
+// of the operation in progress, so a wedged device cannot hold a request forever. The device is sent a Cancel when
+// the request is canceled, and closed when it does not answer in time, unblocking the pending read.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+const (
+	// DefaultDeviceTimeout is how long the device has to answer a message by default
+	DefaultDeviceTimeout = time.Minute
+	// DefaultButtonAckTimeout is how long the user has to confirm on the device by default
+	DefaultButtonAckTimeout = 5 * time.Minute
+	// DefaultRetries is how many more times opening the device is tried by default
+	DefaultRetries = 2
+
+	// retryInterval is the pause before opening the device again
+	retryInterval = 200 * time.Millisecond
+	// cancelGrace is how long the device has to answer the pending message once it was sent a Cancel
+	cancelGrace = 2 * time.Second
+)
+
+var (
+	logger = logging.MustGetLogger("deadline")
+
+	// ErrTimeout is returned when the device does not answer in time
+	ErrTimeout = errors.New("the device did not answer in time")
+	// ErrCanceled is returned when the request of the operation is canceled, such as when the client disconnects
+	ErrCanceled = errors.New("the request was canceled")
+)
+
+// Config sets the timeouts of the messages and the retries of opening the device
+type Config struct {
+	// DeviceTimeout is how long the device has to answer a message, 0 waits as long as the operation
+	DeviceTimeout time.Duration
+	// ButtonAckTimeout is how long the device has to answer a button ack, while the user confirms on the device.
+	// 0 waits as long as the operation.
+	ButtonAckTimeout time.Duration
+	// Retries is how many more times opening the device is tried when it fails, unless no device is connected
+	Retries int
+}
+
+// DefaultConfig returns the default timeouts and retries
+func DefaultConfig() Config {
+	return Config{
+		DeviceTimeout:    DefaultDeviceTimeout,
+		ButtonAckTimeout: DefaultButtonAckTimeout,
+		Retries:          DefaultRetries,
+	}
+}
+
+// Validate checks that the timeouts and retries are not negative
+func (c Config) Validate() error {
+	if c.DeviceTimeout < 0 {
+		return errors.New("device timeout must not be negative")
+	}
+	if c.ButtonAckTimeout < 0 {
+		return errors.New("button ack timeout must not be negative")
+	}
+	if c.Retries < 0 {
+		return errors.New("usb retries must not be negative")
+	}
+	return nil
+}
+
+// Driver is a skyWallet.DeviceDriver bounding the time of the messages sent to the device
+type Driver struct {
+	skyWallet.DeviceDriver
+	config Config
+
+	mu        sync.Mutex
+	operation context.Context
+	// sends are how many messages of each operation wait for their answer, sent signals when one is answered
+	sends map[context.Context]int
+	sent  *sync.Cond
+
+	// sleep and cancelGrace are replaced in tests
+	sleep       func(time.Duration)
+	cancelGrace time.Duration
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps drv with the timeouts and retries of config
+func NewDriver(drv skyWallet.DeviceDriver, config Config) *Driver {
+	d := &Driver{
+		DeviceDriver: drv,
+		config:       config,
+		sends:        make(map[context.Context]int),
+		sleep:        time.Sleep,
+		cancelGrace:  cancelGrace,
+	}
+	d.sent = sync.NewCond(&d.mu)
+	return d
+}
+
+// Operation bounds the messages sent to the device by the deadline of ctx, and cancels them with ctx, until end
+// is called. It is called by the device operations with the context of their HTTP request, the device serves one
+// operation at a time. end waits for the messages of the operation to end, they end within the cancel grace once
+// ctx is canceled, so the next operation does not talk to the device meanwhile.
+func (d *Driver) Operation(ctx context.Context) (end func()) {
+	d.mu.Lock()
+	d.operation = ctx
+	d.mu.Unlock()
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		// a later operation may have replaced this one
+		if d.operation == ctx {
+			d.operation = nil
+		}
+		for d.sends[ctx] > 0 {
+			d.sent.Wait()
+		}
+	}
+}
+
+// beginSend returns the context of the operation in progress, done must be called once its message is answered
+func (d *Driver) beginSend() (ctx context.Context, done func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ctx = d.operation
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d.sends[ctx]++
+
+	return ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.sends[ctx]--; d.sends[ctx] == 0 {
+			delete(d.sends, ctx)
+		}
+		d.sent.Broadcast()
+	}
+}
+
+// currentOperation returns the context of the operation in progress
+func (d *Driver) currentOperation() context.Context {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.operation == nil {
+		return context.Background()
+	}
+	return d.operation
+}
+
+// GetDevice opens the device of the wrapped driver, trying again when it fails unless no device is connected
+func (d *Driver) GetDevice() (usb.Device, error) {
+	dev, err := d.DeviceDriver.GetDevice()
+	for i := 0; i < d.config.Retries && err != nil && err != skyWallet.ErrNoDeviceConnected; i++ {
+		logger.WithError(err).Warning("Opening the device failed, trying again")
+		d.sleep(retryInterval)
+		dev, err = d.DeviceDriver.GetDevice()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &device{
+		Device: dev,
+	}, nil
+}
+
+// SendToDevice sends the message, failing with ErrTimeout if the device does not answer in time
+// and ErrCanceled if the operation is canceled
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return d.bound(dev, chunks, func() (wire.Message, error) {
+		return d.DeviceDriver.SendToDevice(dev, chunks)
+	})
+}
+
+// SendToDeviceNoAnswer sends the message, failing with ErrTimeout if it cannot be written in time
+// and ErrCanceled if the operation is canceled
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	_, err := d.bound(dev, chunks, func() (wire.Message, error) {
+		return wire.Message{}, d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+	})
+	return err
+}
+
+// bound runs send until its timeout or the end of the operation. When the operation is canceled the device is sent a
+// Cancel, leaving the flow in progress such as a confirmation on its screen. dev is closed if send does not return by
+// then.
+func (d *Driver) bound(dev usb.Device, chunks [][64]byte, send func() (wire.Message, error)) (wire.Message, error) {
+	ctx, sent := d.beginSend()
+	defer sent()
+	if ctx.Err() != nil {
+		return wire.Message{}, ErrCanceled
+	}
+
+	timeout := d.config.DeviceTimeout
+	if header.Kind(chunks) == messages.MessageType_MessageType_ButtonAck {
+		timeout = d.config.ButtonAckTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if ctx.Done() == nil {
+		return send()
+	}
+
+	// the result of a send which did not return in time is dropped
+	done := make(chan result, 1)
+	go func() {
+		msg, err := send()
+		done <- result{msg, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.msg, r.err
+	case <-ctx.Done():
+	}
+
+	err := ErrCanceled
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ErrTimeout
+	}
+
+	// the device answers the pending message once canceled, the answer of the operation canceled is dropped
+	if err == ErrCanceled && header.Kind(chunks) != messages.MessageType_MessageType_Cancel && d.cancel(dev, done) {
+		logger.Infof("%s canceled with the request", header.Kind(chunks))
+		return wire.Message{}, err
+	}
+	logger.WithError(err).Errorf("%s not answered, closing the device", header.Kind(chunks))
+
+	// closing the device unblocks the pending read
+	if closeErr := dev.Close(true); closeErr != nil {
+		logger.WithError(closeErr).Error("Closing the device failed")
+	}
+	return wire.Message{}, err
+}
+
+// cancel sends a Cancel to dev and returns true if the pending message, whose result is sent on done, is answered
+// within the cancel grace
+func (d *Driver) cancel(dev usb.Device, done <-chan result) bool {
+	chunks, err := skyWallet.MessageCancel()
+	if err != nil {
+		logger.WithError(err).Error("Creating the Cancel message failed")
+		return false
+	}
+
+	// the Cancel is written while the pending message waits for its answer, the write is not waited for more than
+	// the grace either
+	go func() {
+		if err := d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks); err != nil {
+			logger.WithError(err).Error("Sending the Cancel to the device failed")
+		}
+	}()
+
+	timer := time.NewTimer(d.cancelGrace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// result is the result of a message sent to the device
+type result struct {
+	msg wire.Message
+	err error
+}
+
+// device is a usb.Device closed at most once, when it does not answer in time and when the operation closes it
+type device struct {
+	usb.Device
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (dev *device) Close(disconnected bool) error {
+	dev.closeOnce.Do(func() {
+		dev.closeErr = dev.Device.Close(disconnected)
+	})
+	return dev.closeErr
+}
@@ -0,0 +1,353 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+// fakeDevice answers after delay, a pending answer is unblocked by closing the device, and by a Cancel if
+// answersCancel is set
+type fakeDevice struct {
+	delay         time.Duration
+	answersCancel bool
+	canceled      chan struct{}
+	closed        chan struct{}
+	closes        int
+}
+
+func (d *fakeDevice) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func (d *fakeDevice) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *fakeDevice) Close(disconnected bool) error {
+	d.closes++
+	close(d.closed)
+	return nil
+}
+
+// fakeDriver fails to open the device with the errors of openErrs first
+type fakeDriver struct {
+	dev      *fakeDevice
+	openErrs []error
+	opens    int
+	// sends is incremented by the sends which did not return in time as well
+	sends int32
+	// cancels is incremented by the Cancel messages
+	cancels int32
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	atomic.AddInt32(&d.sends, 1)
+	select {
+	case <-time.After(d.dev.delay):
+		return wire.Message{Kind: 1}, nil
+	case <-d.dev.canceled:
+		return wire.Message{Kind: uint16(messages.MessageType_MessageType_Failure)}, nil
+	case <-d.dev.closed:
+		return wire.Message{}, errors.New("device closed")
+	}
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	if header.Kind(chunks) == messages.MessageType_MessageType_Cancel {
+		atomic.AddInt32(&d.cancels, 1)
+		if d.dev.answersCancel {
+			close(d.dev.canceled)
+		}
+		return nil
+	}
+
+	_, err := d.SendToDevice(dev, chunks)
+	return err
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	d.opens++
+	if len(d.openErrs) > 0 {
+		err := d.openErrs[0]
+		d.openErrs = d.openErrs[1:]
+		return nil, err
+	}
+	return d.dev, nil
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeUSB
+}
+
+func (d *fakeDriver) Close() {}
+
+func TestConfigValidate(t *testing.T) {
+	require.NoError(t, DefaultConfig().Validate())
+	require.NoError(t, Config{}.Validate())
+
+	require.EqualError(t, Config{DeviceTimeout: -time.Second}.Validate(), "device timeout must not be negative")
+	require.EqualError(t, Config{ButtonAckTimeout: -time.Second}.Validate(), "button ack timeout must not be negative")
+	require.EqualError(t, Config{Retries: -1}.Validate(), "usb retries must not be negative")
+}
+
+func TestDriverSendToDevice(t *testing.T) {
+	features, err := skyWallet.MessageGetFeatures()
+	require.NoError(t, err)
+	buttonAck, err := skyWallet.MessageButtonAck()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		chunks        [][64]byte
+		config        Config
+		delay         time.Duration
+		answersCancel bool
+		cancel        bool
+		canceled      bool
+		err           error
+		sends         int32
+		cancels       int32
+		closes        int
+	}{
+		{
+			name:   "answered in time",
+			chunks: features,
+			config: Config{DeviceTimeout: time.Second},
+			sends:  1,
+		},
+		{
+			name:   "no timeout",
+			chunks: features,
+			delay:  10 * time.Millisecond,
+			sends:  1,
+		},
+		{
+			name:   "not answered in time",
+			chunks: features,
+			config: Config{DeviceTimeout: 10 * time.Millisecond},
+			delay:  time.Minute,
+			err:    ErrTimeout,
+			sends:  1,
+			closes: 1,
+		},
+		{
+			name:   "button ack waits for the user",
+			chunks: buttonAck,
+			config: Config{DeviceTimeout: time.Millisecond, ButtonAckTimeout: time.Second},
+			delay:  10 * time.Millisecond,
+			sends:  1,
+		},
+		{
+			name:   "button ack not answered in time",
+			chunks: buttonAck,
+			config: Config{DeviceTimeout: time.Minute, ButtonAckTimeout: 10 * time.Millisecond},
+			delay:  time.Minute,
+			err:    ErrTimeout,
+			sends:  1,
+			closes: 1,
+		},
+		{
+			name:          "operation canceled",
+			chunks:        features,
+			config:        Config{DeviceTimeout: time.Minute},
+			delay:         time.Minute,
+			answersCancel: true,
+			cancel:        true,
+			err:           ErrCanceled,
+			sends:         1,
+			cancels:       1,
+		},
+		{
+			name:    "operation canceled, the device does not answer the cancel",
+			chunks:  features,
+			config:  Config{DeviceTimeout: time.Minute},
+			delay:   time.Minute,
+			cancel:  true,
+			err:     ErrCanceled,
+			sends:   1,
+			cancels: 1,
+			closes:  1,
+		},
+		{
+			name:     "operation canceled before the message",
+			chunks:   features,
+			delay:    time.Minute,
+			canceled: true,
+			err:      ErrCanceled,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			drv := &fakeDriver{
+				dev: &fakeDevice{
+					delay:         tc.delay,
+					answersCancel: tc.answersCancel,
+					canceled:      make(chan struct{}),
+					closed:        make(chan struct{}),
+				},
+			}
+			d := NewDriver(drv, tc.config)
+			d.cancelGrace = 50 * time.Millisecond
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			end := d.Operation(ctx)
+			defer end()
+
+			if tc.canceled {
+				cancel()
+			}
+			if tc.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+
+			dev, err := d.GetDevice()
+			require.NoError(t, err)
+
+			msg, err := d.SendToDevice(dev, tc.chunks)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				// the device which did not answer in time is closed, the device is sent a Cancel with the operation
+				require.Equal(t, tc.closes, drv.dev.closes)
+				require.Equal(t, tc.cancels, atomic.LoadInt32(&drv.cancels))
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, uint16(1), msg.Kind)
+			}
+			require.Equal(t, tc.sends, atomic.LoadInt32(&drv.sends))
+
+			// the device is closed once
+			require.NoError(t, dev.Close(false))
+			require.Equal(t, 1, drv.dev.closes)
+		})
+	}
+}
+
+func TestDriverOperation(t *testing.T) {
+	d := NewDriver(&fakeDriver{}, Config{})
+	require.Equal(t, context.Background(), d.currentOperation())
+
+	first, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	endFirst := d.Operation(first)
+	require.Equal(t, first, d.currentOperation())
+
+	// a later operation replaces the first one, which does not end it
+	second, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	endSecond := d.Operation(second)
+	endFirst()
+	require.Equal(t, second, d.currentOperation())
+
+	endSecond()
+	require.Equal(t, context.Background(), d.currentOperation())
+}
+
+func TestDriverGetDevice(t *testing.T) {
+	errBusy := errors.New("device busy")
+
+	cases := []struct {
+		name     string
+		retries  int
+		openErrs []error
+		err      error
+		opens    int
+	}{
+		{
+			name:  "opened",
+			opens: 1,
+		},
+		{
+			name:     "opened again",
+			retries:  2,
+			openErrs: []error{errBusy, errBusy},
+			opens:    3,
+		},
+		{
+			name:     "retries exhausted",
+			retries:  1,
+			openErrs: []error{errBusy, errBusy},
+			err:      errBusy,
+			opens:    2,
+		},
+		{
+			name:     "no device connected",
+			retries:  2,
+			openErrs: []error{skyWallet.ErrNoDeviceConnected},
+			err:      skyWallet.ErrNoDeviceConnected,
+			opens:    1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			drv := &fakeDriver{
+				dev: &fakeDevice{
+					closed: make(chan struct{}),
+				},
+				openErrs: tc.openErrs,
+			}
+			d := NewDriver(drv, Config{Retries: tc.retries})
+			d.sleep = func(time.Duration) {}
+
+			dev, err := d.GetDevice()
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				require.Nil(t, dev)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, dev)
+			}
+			require.Equal(t, tc.opens, drv.opens)
+		})
+	}
+}
+
+func TestDriverOperationEndWaitsForMessages(t *testing.T) {
+	features, err := skyWallet.MessageGetFeatures()
+	require.NoError(t, err)
+
+	drv := &fakeDriver{
+		dev: &fakeDevice{
+			delay:    time.Minute,
+			canceled: make(chan struct{}),
+			closed:   make(chan struct{}),
+		},
+	}
+	d := NewDriver(drv, Config{})
+	d.cancelGrace = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	end := d.Operation(ctx)
+
+	dev, err := d.GetDevice()
+	require.NoError(t, err)
+
+	go d.SendToDevice(dev, features) // nolint: errcheck
+	for atomic.LoadInt32(&drv.sends) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the operation ends once its message does, the device which did not answer the cancel is closed
+	cancel()
+	end()
+	require.Equal(t, int32(1), atomic.LoadInt32(&drv.cancels))
+	require.Equal(t, 1, drv.dev.closes)
+}
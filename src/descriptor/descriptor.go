@@ -0,0 +1,84 @@
+// Package descriptor writes the output descriptors of BIP 380 describing the addresses of the device, so the
+// watch-only software supporting the descriptors imports them without the user entering the addresses.
+// The device derives the addresses with the deterministic key chain of the skycoin wallets and exports no extended
+// public key, an address is described by an addr() descriptor.
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// inputCharset are the characters of a descriptor, in the order of the checksum algorithm
+	inputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	// checksumCharset are the characters of a checksum
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// polymodGenerators are the generators of the checksum polynomial
+var polymodGenerators = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+// Address returns the addr() descriptor of address, with its checksum
+func Address(address string) (string, error) {
+	return WithChecksum(fmt.Sprintf("addr(%s)", address))
+}
+
+// WithChecksum appends the checksum to the descriptor desc, such as "addr(...)#evqnm3ye"
+func WithChecksum(desc string) (string, error) {
+	checksum, err := Checksum(desc)
+	if err != nil {
+		return "", err
+	}
+	return desc + "#" + checksum, nil
+}
+
+// Checksum returns the 8 character checksum of the descriptor desc
+func Checksum(desc string) (string, error) {
+	c := uint64(1)
+	class := 0
+	classCount := 0
+	for _, ch := range desc {
+		pos := strings.IndexRune(inputCharset, ch)
+		if pos < 0 {
+			return "", fmt.Errorf("invalid descriptor character %q", ch)
+		}
+
+		// the low bits of the position are checksummed as they are, the high bits of 3 characters together
+		c = polymod(c, uint64(pos&31))
+		class = class*3 + pos>>5
+		classCount++
+		if classCount == 3 {
+			c = polymod(c, uint64(class))
+			class = 0
+			classCount = 0
+		}
+	}
+	if classCount > 0 {
+		c = polymod(c, uint64(class))
+	}
+
+	for i := 0; i < 8; i++ {
+		c = polymod(c, 0)
+	}
+	c ^= 1
+
+	checksum := make([]byte, 8)
+	for i := range checksum {
+		checksum[i] = checksumCharset[(c>>(5*uint(7-i)))&31]
+	}
+	return string(checksum), nil
+}
+
+func polymod(c, val uint64) uint64 {
+	c0 := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ val
+	for i, g := range polymodGenerators {
+		if c0>>uint(i)&1 != 0 {
+			c ^= g
+		}
+	}
+	return c
+}
@@ -0,0 +1,52 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	cases := []struct {
+		name     string
+		desc     string
+		checksum string
+		err      string
+	}{
+		{
+			name: "BIP 380 test vector",
+			desc: "raw(deadbeef)",
+			// the valid checksum of the BIP 380 test vectors
+			checksum: "89f8spxm",
+		},
+		{
+			name:     "address",
+			desc:     "addr(2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG)",
+			checksum: "evqnm3ye",
+		},
+		{
+			name: "invalid character",
+			desc: "addr(é)",
+			err:  `invalid descriptor character 'é'`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checksum, err := Checksum(tc.desc)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.checksum, checksum)
+		})
+	}
+}
+
+func TestAddress(t *testing.T) {
+	desc, err := Address("2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG")
+	require.NoError(t, err)
+	require.Equal(t, "addr(2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG)#evqnm3ye", desc)
+}
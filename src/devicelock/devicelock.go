@@ -0,0 +1,203 @@
+// Package devicelock serializes the operations of the device, so the messages of concurrent requests are not
+// interleaved. The operations waiting for the device are served in their arrival order.
+package devicelock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long an operation waits for the device by default
+const DefaultTimeout = 30 * time.Second
+
+// ErrBusy is returned when the device is still busy with another operation once the wait timed out
+var ErrBusy = errors.New("the device is busy with another operation")
+
+// Operation is an operation holding or waiting for the device
+type Operation struct {
+	// Kind is the kind of operation, such as signing or recovery
+	Kind string
+	// Endpoint is the endpoint of the request of the operation, empty for the operations of the daemon
+	Endpoint string
+	// RequestID is the ID of the request of the operation
+	RequestID string
+	// Abort aborts the operation, nil if it cannot be aborted
+	Abort func()
+}
+
+// Status is the status of the device
+type Status struct {
+	// Operation is the operation holding the device, nil when the device is idle
+	Operation *Operation
+	// Since is when the operation got the device
+	Since time.Time
+	// Waiting is how many operations wait for the device
+	Waiting int
+}
+
+// Lock is held by the operation using the device
+type Lock struct {
+	timeout time.Duration
+	// now is replaced in tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// holder is the operation holding the lock, nil when the device is idle
+	holder *holder
+	// waiters are the operations waiting for the device, in their arrival order
+	waiters []*waiter
+}
+
+// holder is the operation holding the lock, done is closed when it releases it
+type holder struct {
+	operation Operation
+	since     time.Time
+	done      chan struct{}
+}
+
+// waiter is an operation waiting for the device, ready is closed when the lock is handed to it
+type waiter struct {
+	operation Operation
+	ready     chan struct{}
+}
+
+// New creates a Lock, the operations wait for the device for timeout. 0 waits as long as their context.
+func New(timeout time.Duration) *Lock {
+	return &Lock{
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Acquire waits for the device for op, after the operations which arrived before. release must be called once the
+// operation is done with the device. It returns ErrBusy when the device is not available within the timeout of
+// the lock, and the error of ctx when it is done first.
+func (l *Lock) Acquire(ctx context.Context, op Operation) (release func(), err error) {
+	l.mu.Lock()
+	if l.holder == nil {
+		l.hold(op)
+		l.mu.Unlock()
+		return l.releaseFunc(), nil
+	}
+
+	w := &waiter{
+		operation: op,
+		ready:     make(chan struct{}),
+	}
+	l.waiters = append(l.waiters, w)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-w.ready:
+		return l.releaseFunc(), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrBusy
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, other := range l.waiters {
+		if other == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return nil, err
+		}
+	}
+
+	// the lock was handed over while giving up, it goes to the next operation
+	l.releaseLocked()
+	return nil, err
+}
+
+// Waiting returns how many operations wait for the device
+func (l *Lock) Waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiters)
+}
+
+// Status returns the operation holding the device and how many wait for it
+func (l *Lock) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := Status{
+		Waiting: len(l.waiters),
+	}
+	if h := l.holder; h != nil {
+		op := h.operation
+		s.Operation = &op
+		s.Since = h.since
+	}
+	return s
+}
+
+// Abort aborts the operation holding the device if it is still holding it after grace, such as after the device
+// was sent a Cancel. It returns the operation aborted, nil if none was.
+func (l *Lock) Abort(grace time.Duration) *Operation {
+	l.mu.Lock()
+	h := l.holder
+	l.mu.Unlock()
+	if h == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-h.done:
+		return nil
+	case <-timer.C:
+	}
+
+	if h.operation.Abort == nil {
+		return nil
+	}
+	h.operation.Abort()
+	op := h.operation
+	return &op
+}
+
+// hold hands the lock to op
+func (l *Lock) hold(op Operation) {
+	l.holder = &holder{
+		operation: op,
+		since:     l.now(),
+		done:      make(chan struct{}),
+	}
+}
+
+func (l *Lock) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.releaseLocked()
+		})
+	}
+}
+
+// releaseLocked hands the lock to the first operation waiting, or releases it
+func (l *Lock) releaseLocked() {
+	close(l.holder.done)
+	l.holder = nil
+	if len(l.waiters) == 0 {
+		return
+	}
+
+	next := l.waiters[0]
+	l.waiters = l.waiters[1:]
+	l.hold(next.operation)
+	close(next.ready)
+}
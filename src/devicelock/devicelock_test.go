@@ -0,0 +1,182 @@
+package devicelock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitForWaiters waits until n operations wait for the lock
+func waitForWaiters(t *testing.T, l *Lock, n int) {
+	for i := 0; i < 100 && l.Waiting() != n; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, n, l.Waiting())
+}
+
+func TestLockFIFO(t *testing.T) {
+	l := New(time.Minute)
+
+	release, err := l.Acquire(context.Background(), Operation{})
+	require.NoError(t, err)
+
+	// the waiting operations get the device in their arrival order
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			release, err := l.Acquire(context.Background(), Operation{})
+			if err != nil {
+				order <- -1
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		waitForWaiters(t, l, i+1)
+	}
+
+	// release is idempotent, the lock is handed over once
+	release()
+	release()
+	for i := 0; i < 3; i++ {
+		require.Equal(t, i, <-order)
+	}
+	require.Zero(t, l.Waiting())
+
+	// the device is free again
+	release, err = l.Acquire(context.Background(), Operation{})
+	require.NoError(t, err)
+	release()
+}
+
+func TestLockTimeout(t *testing.T) {
+	l := New(10 * time.Millisecond)
+
+	release, err := l.Acquire(context.Background(), Operation{})
+	require.NoError(t, err)
+
+	_, err = l.Acquire(context.Background(), Operation{})
+	require.Equal(t, ErrBusy, err)
+	require.Zero(t, l.Waiting())
+
+	// the context is done before the timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = acquireHeld(t, New(time.Minute), ctx)
+	require.Equal(t, context.Canceled, err)
+
+	release()
+	release, err = l.Acquire(context.Background(), Operation{})
+	require.NoError(t, err)
+	release()
+}
+
+func TestLockNoTimeout(t *testing.T) {
+	l := New(0)
+
+	release, err := l.Acquire(context.Background(), Operation{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx, Operation{})
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// a waiting operation giving up does not hold the lock
+	done := make(chan error)
+	go func() {
+		release, err := l.Acquire(context.Background(), Operation{})
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+	waitForWaiters(t, l, 1)
+	release()
+	require.NoError(t, <-done)
+}
+
+// acquireHeld acquires the lock with ctx while another operation holds it
+func acquireHeld(t *testing.T, l *Lock, ctx context.Context) (func(), error) {
+	release, err := l.Acquire(context.Background(), Operation{})
+	require.NoError(t, err)
+	defer release()
+	return l.Acquire(ctx, Operation{})
+}
+
+func TestLockStatus(t *testing.T) {
+	l := New(time.Minute)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	l.now = func() time.Time {
+		return now
+	}
+	require.Equal(t, Status{}, l.Status())
+
+	signing := Operation{Kind: "signing", Endpoint: "/transaction_sign", RequestID: "flow-42"}
+	release, err := l.Acquire(context.Background(), signing)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		release, err := l.Acquire(context.Background(), Operation{Kind: "read", Endpoint: "/features"})
+		if err == nil {
+			release()
+		}
+	}()
+	waitForWaiters(t, l, 1)
+
+	require.Equal(t, Status{
+		Operation: &signing,
+		Since:     now,
+		Waiting:   1,
+	}, l.Status())
+
+	// the status of the next operation starts when it gets the device
+	now = now.Add(time.Minute)
+	release()
+	<-done
+	require.Equal(t, Status{}, l.Status())
+}
+
+func TestLockAbort(t *testing.T) {
+	l := New(time.Minute)
+	require.Nil(t, l.Abort(0))
+
+	// the operation done within the grace is not aborted
+	aborted := make(chan struct{})
+	release, err := l.Acquire(context.Background(), Operation{
+		Kind: "signing",
+		Abort: func() {
+			close(aborted)
+		},
+	})
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		release()
+	}()
+	require.Nil(t, l.Abort(time.Minute))
+
+	// the operation still holding the device after the grace is aborted
+	release, err = l.Acquire(context.Background(), Operation{
+		Kind: "recovery",
+		Abort: func() {
+			close(aborted)
+		},
+	})
+	require.NoError(t, err)
+	op := l.Abort(time.Millisecond)
+	require.NotNil(t, op)
+	require.Equal(t, "recovery", op.Kind)
+	<-aborted
+	release()
+
+	// an operation of the daemon cannot be aborted
+	release, err = l.Acquire(context.Background(), Operation{Kind: "session"})
+	require.NoError(t, err)
+	defer release()
+	require.Nil(t, l.Abort(0))
+}
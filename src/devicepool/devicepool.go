@@ -0,0 +1,215 @@
+// Package devicepool keeps the handle of the device open across the requests, instead of enumerating and opening
+// the device for each of them. The handle is reopened when a message fails or the device is no longer listed,
+// and closed once idle, so other applications can open the device.
+package devicepool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+const (
+	// DefaultIdleTimeout is how long the handle stays open without requests by default
+	DefaultIdleTimeout = time.Minute
+
+	// healthCheckInterval is how long the handle is reused without checking the device is still listed
+	healthCheckInterval = 5 * time.Second
+)
+
+var logger = logging.MustGetLogger("devicepool")
+
+// Driver is a skyWallet.DeviceDriver handing out the same open handle of the device to the requests.
+// Closing the handle of a request keeps it open, unless the device is disconnected.
+type Driver struct {
+	skyWallet.DeviceDriver
+	idleTimeout time.Duration
+
+	// now and afterFunc are replaced in tests
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) *time.Timer
+
+	mu sync.Mutex
+	// dev is the open handle, nil when it is closed
+	dev     usb.Device
+	users   int
+	checked time.Time
+	idle    *time.Timer
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps drv, the handle is closed once idle for idleTimeout
+func NewDriver(drv skyWallet.DeviceDriver, idleTimeout time.Duration) *Driver {
+	return &Driver{
+		DeviceDriver: drv,
+		idleTimeout:  idleTimeout,
+		now:          time.Now,
+		afterFunc:    time.AfterFunc,
+	}
+}
+
+// GetDevice returns the open handle of the device, opening it if it is closed.
+// A handle unused for a few seconds is reused once the USB device is checked to be still listed.
+func (d *Driver) GetDevice() (usb.Device, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.idle != nil {
+		d.idle.Stop()
+		d.idle = nil
+	}
+
+	// the emulator is not listed, a failed message reopens its handle
+	if d.dev != nil && d.users == 0 && d.DeviceDriver.DeviceType() == skyWallet.DeviceTypeUSB &&
+		d.now().Sub(d.checked) >= healthCheckInterval {
+		if infos, err := d.DeviceDriver.GetDeviceInfos(); err == nil && len(infos) == 0 {
+			logger.Info("The device is no longer listed, reopening it")
+			d.closeLocked(true) // nolint: errcheck
+		} else {
+			d.checked = d.now()
+		}
+	}
+
+	if d.dev == nil {
+		dev, err := d.DeviceDriver.GetDevice()
+		if err != nil {
+			return nil, err
+		}
+		d.dev = dev
+		d.checked = d.now()
+	}
+
+	d.users++
+	return &handle{
+		Device: d.dev,
+		driver: d,
+	}, nil
+}
+
+// SendToDevice sends the message, the handle is reopened by the next request when it fails
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+	if err != nil {
+		d.discard()
+	}
+	return msg, err
+}
+
+// SendToDeviceNoAnswer sends the message, the handle is reopened by the next request when it fails
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	err := d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+	if err != nil {
+		d.discard()
+	}
+	return err
+}
+
+// GetDeviceInfos lists the devices, the handle is closed when the device is no longer listed
+func (d *Driver) GetDeviceInfos() ([]usb.Info, error) {
+	infos, err := d.DeviceDriver.GetDeviceInfos()
+	if err == nil {
+		d.mu.Lock()
+		if len(infos) == 0 && d.dev != nil {
+			logger.Info("The device is no longer listed, closing its handle")
+			d.closeLocked(true) // nolint: errcheck
+		} else {
+			d.checked = d.now()
+		}
+		d.mu.Unlock()
+	}
+	return infos, err
+}
+
+// Close closes the handle and the wrapped driver
+func (d *Driver) Close() {
+	d.mu.Lock()
+	if d.idle != nil {
+		d.idle.Stop()
+		d.idle = nil
+	}
+	d.closeLocked(false) // nolint: errcheck
+	d.mu.Unlock()
+
+	d.DeviceDriver.Close()
+}
+
+// release is called when a request closes its handle, the handle is closed when the device is disconnected,
+// and once idle otherwise
+func (d *Driver) release(dev usb.Device, disconnected bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if dev != d.dev {
+		// the handle was already discarded
+		return nil
+	}
+
+	d.users--
+	if disconnected {
+		return d.closeLocked(true)
+	}
+
+	if d.users == 0 {
+		if d.idleTimeout <= 0 {
+			return d.closeLocked(false)
+		}
+		d.idle = d.afterFunc(d.idleTimeout, func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.users == 0 && d.dev == dev {
+				logger.Debug("Closing the idle device handle")
+				d.closeLocked(false) // nolint: errcheck
+			}
+		})
+	}
+	return nil
+}
+
+// discard closes the open handle, the next request opens the device again.
+// The drivers above pass their own handle wrapping it, there is a single open handle to discard.
+func (d *Driver) discard() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.dev == nil {
+		return
+	}
+	logger.Debug("A message failed, the device handle is reopened by the next request")
+	d.closeLocked(true) // nolint: errcheck
+}
+
+func (d *Driver) closeLocked(disconnected bool) error {
+	if d.dev == nil {
+		return nil
+	}
+	err := d.dev.Close(disconnected)
+	if err != nil {
+		logger.WithError(err).Error("Closing the device handle failed")
+	}
+	d.dev = nil
+	d.users = 0
+	return err
+}
+
+// handle is the handle of a request, closing it keeps the device open
+type handle struct {
+	usb.Device
+	driver *Driver
+
+	once sync.Once
+	err  error
+}
+
+// Close releases the handle, the device is closed when it is disconnected
+func (h *handle) Close(disconnected bool) error {
+	h.once.Do(func() {
+		h.err = h.driver.release(h.Device, disconnected)
+	})
+	return h.err
+}
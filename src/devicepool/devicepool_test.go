@@ -0,0 +1,226 @@
+package devicepool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+var errUnplugged = errors.New("device unplugged")
+
+// fakeDevice is a handle of the fake device
+type fakeDevice struct {
+	closed       int
+	disconnected bool
+}
+
+func (d *fakeDevice) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func (d *fakeDevice) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *fakeDevice) Close(disconnected bool) error {
+	d.closed++
+	d.disconnected = disconnected
+	return nil
+}
+
+// fakeDriver fails the sends after unplugged is set, the device is listed unless unlisted is set
+type fakeDriver struct {
+	deviceType skyWallet.DeviceType
+	unplugged  bool
+	unlisted   bool
+
+	lookups int
+	closed  bool
+	devices []*fakeDevice
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	if d.unplugged {
+		return wire.Message{}, errUnplugged
+	}
+	return wire.Message{Kind: 1}, nil
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	if d.unplugged {
+		return errUnplugged
+	}
+	return nil
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	if d.unlisted {
+		return nil, skyWallet.ErrNoDeviceConnected
+	}
+	dev := &fakeDevice{}
+	d.devices = append(d.devices, dev)
+	return dev, nil
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	d.lookups++
+	if d.unlisted {
+		return nil, nil
+	}
+	return []usb.Info{{}}, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return d.deviceType
+}
+
+func (d *fakeDriver) Close() {
+	d.closed = true
+}
+
+// newTestDriver returns a pool of drv whose clock is now, idle runs the pending idle timer
+func newTestDriver(t *testing.T, drv *fakeDriver, idleTimeout time.Duration) (d *Driver, now *time.Time, idle func()) {
+	d = NewDriver(drv, idleTimeout)
+
+	clock := time.Now()
+	d.now = func() time.Time {
+		return clock
+	}
+
+	var pending func()
+	d.afterFunc = func(dur time.Duration, f func()) *time.Timer {
+		require.Equal(t, idleTimeout, dur)
+		pending = f
+		return time.NewTimer(time.Hour)
+	}
+
+	return d, &clock, func() {
+		if pending != nil {
+			pending()
+		}
+	}
+}
+
+// request opens the device, sends it a message and closes it, as the skyWallet.Device methods do
+func request(t *testing.T, d *Driver) error {
+	dev, err := d.GetDevice()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		require.NoError(t, dev.Close(false))
+	}()
+
+	_, err = d.SendToDevice(dev, nil)
+	return err
+}
+
+func TestDriverReusesHandle(t *testing.T) {
+	drv := &fakeDriver{deviceType: skyWallet.DeviceTypeUSB}
+	d, _, idle := newTestDriver(t, drv, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, request(t, d))
+	}
+	require.Len(t, drv.devices, 1)
+	require.Equal(t, 0, drv.devices[0].closed)
+	require.Equal(t, 0, drv.lookups)
+
+	// the idle handle is closed, the next request opens the device again
+	idle()
+	require.Equal(t, 1, drv.devices[0].closed)
+	require.False(t, drv.devices[0].disconnected)
+
+	require.NoError(t, request(t, d))
+	require.Len(t, drv.devices, 2)
+
+	d.Close()
+	require.Equal(t, 1, drv.devices[1].closed)
+	require.True(t, drv.closed)
+}
+
+func TestDriverReopens(t *testing.T) {
+	drv := &fakeDriver{deviceType: skyWallet.DeviceTypeUSB}
+	d, now, _ := newTestDriver(t, drv, time.Minute)
+
+	require.NoError(t, request(t, d))
+
+	// a failed message discards the handle
+	drv.unplugged = true
+	require.Equal(t, errUnplugged, request(t, d))
+	require.Equal(t, 1, drv.devices[0].closed)
+	require.True(t, drv.devices[0].disconnected)
+
+	drv.unplugged = false
+	require.NoError(t, request(t, d))
+	require.Len(t, drv.devices, 2)
+
+	// a handle closed as disconnected is discarded
+	dev, err := d.GetDevice()
+	require.NoError(t, err)
+	require.NoError(t, dev.Close(true))
+	require.Equal(t, 1, drv.devices[1].closed)
+	require.True(t, drv.devices[1].disconnected)
+
+	// the handle is reused without a lookup for a few seconds
+	require.NoError(t, request(t, d))
+	require.Len(t, drv.devices, 3)
+	*now = now.Add(healthCheckInterval / 2)
+	require.NoError(t, request(t, d))
+	require.Equal(t, 0, drv.lookups)
+
+	// then the device is checked to be still listed
+	*now = now.Add(healthCheckInterval)
+	require.NoError(t, request(t, d))
+	require.Equal(t, 1, drv.lookups)
+	require.Len(t, drv.devices, 3)
+
+	// the device was unplugged and plugged in again between the requests
+	*now = now.Add(healthCheckInterval)
+	drv.unlisted = true
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, request(t, d))
+	require.Equal(t, 1, drv.devices[2].closed)
+
+	drv.unlisted = false
+	require.NoError(t, request(t, d))
+	require.Len(t, drv.devices, 4)
+
+	// listing the devices without the device discards the handle
+	drv.unlisted = true
+	infos, err := d.GetDeviceInfos()
+	require.NoError(t, err)
+	require.Empty(t, infos)
+	require.Equal(t, 1, drv.devices[3].closed)
+}
+
+func TestDriverEmulator(t *testing.T) {
+	drv := &fakeDriver{deviceType: skyWallet.DeviceTypeEmulator}
+	d, now, _ := newTestDriver(t, drv, time.Minute)
+
+	// the emulator is not listed, its handle is reused without a lookup
+	require.NoError(t, request(t, d))
+	*now = now.Add(2 * healthCheckInterval)
+	require.NoError(t, request(t, d))
+	require.Equal(t, 0, drv.lookups)
+	require.Len(t, drv.devices, 1)
+}
+
+func TestDriverNoIdleTimeout(t *testing.T) {
+	drv := &fakeDriver{deviceType: skyWallet.DeviceTypeUSB}
+	d, _, _ := newTestDriver(t, drv, 0)
+
+	// the device is opened and closed for each request
+	require.NoError(t, request(t, d))
+	require.NoError(t, request(t, d))
+	require.Len(t, drv.devices, 2)
+	for _, dev := range drv.devices {
+		require.Equal(t, 1, dev.closed)
+		require.False(t, dev.disconnected)
+	}
+}
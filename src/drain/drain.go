@@ -0,0 +1,100 @@
+// Package drain tracks the device operations in flight, so the daemon stops accepting new operations when it
+// shuts down and waits for the in-flight ones to finish instead of interrupting a signing flow mid-message.
+package drain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is how long the daemon waits for the in-flight operations by default when it shuts down
+	DefaultTimeout = 30 * time.Second
+
+	// CancelTimeout is how long the daemon waits for the in-flight operations once the device was sent a Cancel
+	CancelTimeout = 5 * time.Second
+)
+
+// ErrDraining is returned for the operations started while the daemon shuts down
+var ErrDraining = errors.New("the daemon is shutting down")
+
+// Tracker tracks the operations in flight
+type Tracker struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	// idle is closed when the last operation in flight ends while draining
+	idle chan struct{}
+}
+
+// NewTracker creates a Tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		idle: make(chan struct{}),
+	}
+}
+
+// Begin starts an operation, end must be called once it finishes. It returns ErrDraining once Drain was called.
+func (t *Tracker) Begin() (end func(), err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return nil, ErrDraining
+	}
+
+	t.inFlight++
+
+	var once sync.Once
+	return func() {
+		once.Do(t.end)
+	}, nil
+}
+
+func (t *Tracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	if t.draining && t.inFlight == 0 {
+		close(t.idle)
+	}
+}
+
+// InFlight returns the number of operations in flight
+func (t *Tracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.inFlight
+}
+
+// Draining returns true once Drain was called
+func (t *Tracker) Draining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.draining
+}
+
+// Drain refuses the new operations and waits for the operations in flight to finish, or ctx to be done.
+// It can be called again to wait longer, it returns the context error if operations are still in flight.
+func (t *Tracker) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		if t.inFlight == 0 {
+			close(t.idle)
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
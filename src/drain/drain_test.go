@@ -0,0 +1,62 @@
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerDrain(t *testing.T) {
+	tr := NewTracker()
+	require.False(t, tr.Draining())
+
+	end1, err := tr.Begin()
+	require.NoError(t, err)
+	end2, err := tr.Begin()
+	require.NoError(t, err)
+	require.Equal(t, 2, tr.InFlight())
+
+	// the operations are still in flight when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, tr.Drain(ctx))
+	require.True(t, tr.Draining())
+
+	// no new operation is accepted while draining
+	_, err = tr.Begin()
+	require.Equal(t, ErrDraining, err)
+	require.Equal(t, 2, tr.InFlight())
+
+	// end is idempotent
+	end1()
+	end1()
+	require.Equal(t, 1, tr.InFlight())
+
+	done := make(chan error)
+	go func() {
+		done <- tr.Drain(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Drain returned with an operation in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	end2()
+	require.NoError(t, <-done)
+	require.Zero(t, tr.InFlight())
+
+	// drained already
+	require.NoError(t, tr.Drain(context.Background()))
+}
+
+func TestTrackerDrainIdle(t *testing.T) {
+	tr := NewTracker()
+	require.NoError(t, tr.Drain(context.Background()))
+
+	_, err := tr.Begin()
+	require.Equal(t, ErrDraining, err)
+}
@@ -0,0 +1,259 @@
+// Package emulator runs the process of the Skywallet emulator used in emulator mode, so the daemon starts, stops,
+// resets and wipes it instead of the developers and the CI pipelines running it alongside the daemon.
+package emulator
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+// State is the state of the emulator process
+type State string
+
+const (
+	// StateRunning is the state of the running emulator
+	StateRunning State = "running"
+	// StateStopped is the state of the emulator which was not started, was stopped or exited
+	StateStopped State = "stopped"
+
+	// DefaultFlashFile is the file the emulator stores its flash memory in, in its working directory
+	DefaultFlashFile = "emulator.img"
+
+	// stopTimeout is how long the emulator has to exit once terminated, before it is killed
+	stopTimeout = 5 * time.Second
+)
+
+var (
+	logger = logging.MustGetLogger("emulator")
+
+	// ErrRunning is returned when starting the emulator which is running
+	ErrRunning = errors.New("the emulator is running")
+	// ErrNotRunning is returned when stopping the emulator which is not running
+	ErrNotRunning = errors.New("the emulator is not running")
+)
+
+// Config configures the emulator process
+type Config struct {
+	// Binary is the path of the emulator binary
+	Binary string
+	// Dir is the working directory of the emulator, it holds its flash file. Empty uses the directory of the binary.
+	Dir string
+	// FlashFile is the file the emulator stores its flash memory in, relative to Dir
+	FlashFile string
+}
+
+// Status is the status of the emulator process
+type Status struct {
+	State     State      `json:"state"`
+	Binary    string     `json:"binary"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// ExitError is the error the emulator exited with, when it exited without being stopped
+	ExitError string `json:"exit_error,omitempty"`
+}
+
+// Emulator runs the emulator process
+type Emulator struct {
+	config Config
+
+	// opMu serializes the operations
+	opMu sync.Mutex
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	done      chan struct{}
+	startedAt time.Time
+	stopping  bool
+	exitErr   error
+}
+
+// New creates an Emulator running the emulator configured by c
+func New(c Config) *Emulator {
+	if c.Dir == "" {
+		c.Dir = filepath.Dir(c.Binary)
+	}
+	if c.FlashFile == "" {
+		c.FlashFile = DefaultFlashFile
+	}
+
+	return &Emulator{
+		config: c,
+	}
+}
+
+// Status returns the status of the emulator process
+func (e *Emulator) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := Status{
+		State:  StateStopped,
+		Binary: e.config.Binary,
+	}
+	if e.cmd != nil {
+		startedAt := e.startedAt
+		s.State = StateRunning
+		s.PID = e.cmd.Process.Pid
+		s.StartedAt = &startedAt
+	} else if e.exitErr != nil {
+		s.ExitError = e.exitErr.Error()
+	}
+	return s
+}
+
+// Start starts the emulator
+func (e *Emulator) Start() (Status, error) {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+
+	if err := e.start(); err != nil {
+		return Status{}, err
+	}
+	return e.Status(), nil
+}
+
+// Stop stops the emulator, killing it if it does not exit within a few seconds once terminated
+func (e *Emulator) Stop() (Status, error) {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+
+	if err := e.stop(); err != nil {
+		return Status{}, err
+	}
+	return e.Status(), nil
+}
+
+// Reset restarts the emulator, as if the device was unplugged and plugged in, keeping its flash memory
+func (e *Emulator) Reset() (Status, error) {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+
+	if err := e.stop(); err != nil && err != ErrNotRunning {
+		return Status{}, err
+	}
+	if err := e.start(); err != nil {
+		return Status{}, err
+	}
+	return e.Status(), nil
+}
+
+// Wipe restarts the emulator with its flash memory erased, as a new device
+func (e *Emulator) Wipe() (Status, error) {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+
+	if err := e.stop(); err != nil && err != ErrNotRunning {
+		return Status{}, err
+	}
+
+	path := filepath.Join(e.config.Dir, e.config.FlashFile)
+	switch err := os.Remove(path); {
+	case err == nil:
+		logger.Infof("Removed the emulator flash file %s", path)
+	case !os.IsNotExist(err):
+		return Status{}, err
+	}
+
+	if err := e.start(); err != nil {
+		return Status{}, err
+	}
+	return e.Status(), nil
+}
+
+// Close stops the emulator if it is running
+func (e *Emulator) Close() error {
+	_, err := e.Stop()
+	if err == ErrNotRunning {
+		return nil
+	}
+	return err
+}
+
+func (e *Emulator) start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cmd != nil {
+		return ErrRunning
+	}
+
+	cmd := exec.Command(e.config.Binary)
+	cmd.Dir = e.config.Dir
+
+	// the output of the emulator is logged, the writer is closed once the process exited
+	log := logger.WithField("binary", e.config.Binary)
+	out := log.WriterLevel(logrus.DebugLevel)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := cmd.Start(); err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+
+	done := make(chan struct{})
+	e.cmd = cmd
+	e.done = done
+	e.startedAt = time.Now().UTC()
+	e.stopping = false
+	e.exitErr = nil
+
+	logger.Infof("Started the emulator %s, pid %d", e.config.Binary, cmd.Process.Pid)
+
+	go func() {
+		err := cmd.Wait()
+		out.Close() // nolint: errcheck
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if !e.stopping {
+			if err == nil {
+				err = errors.New("exited")
+			}
+			e.exitErr = err
+			logger.WithError(err).Errorf("The emulator %s exited", e.config.Binary)
+		}
+		e.cmd = nil
+		close(done)
+	}()
+
+	return nil
+}
+
+func (e *Emulator) stop() error {
+	e.mu.Lock()
+	cmd, done := e.cmd, e.done
+	if cmd == nil {
+		e.mu.Unlock()
+		return ErrNotRunning
+	}
+	e.stopping = true
+	e.mu.Unlock()
+
+	// SIGINT is ignored by the processes started in the background, and the processes cannot be signaled on Windows
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		cmd.Process.Kill() // nolint: errcheck
+	}
+
+	select {
+	case <-done:
+	case <-time.After(stopTimeout):
+		logger.Warningf("The emulator did not exit within %s, killing it", stopTimeout)
+		if err := cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-done
+	}
+
+	logger.Infof("Stopped the emulator %s", e.config.Binary)
+	return nil
+}
@@ -0,0 +1,150 @@
+package emulator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// script is a fake emulator appending its pid to its flash file, it runs until it is interrupted
+const script = `#!/bin/sh
+echo $$ >> emulator.img
+exec sleep 60
+`
+
+func writeEmulator(t *testing.T, dir, script string) string {
+	path := filepath.Join(dir, "emulator.sh")
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func readPIDs(t *testing.T, dir string) []int {
+	data, err := ioutil.ReadFile(filepath.Join(dir, DefaultFlashFile))
+	require.NoError(t, err)
+
+	var pids []int
+	for _, line := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(line)
+		require.NoError(t, err)
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
+// waitForFlashFile waits for the emulator to write its pid, once it is started
+func waitForFlashFile(t *testing.T, dir string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, DefaultFlashFile))
+		if err == nil && len(strings.Fields(string(data))) == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the emulator did not write its pid")
+}
+
+func TestEmulator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emulator")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	binary := writeEmulator(t, dir, script)
+	e := New(Config{
+		Binary: binary,
+	})
+	defer e.Close() // nolint: errcheck
+
+	require.Equal(t, Status{
+		State:  StateStopped,
+		Binary: binary,
+	}, e.Status())
+
+	_, err = e.Stop()
+	require.Equal(t, ErrNotRunning, err)
+
+	status, err := e.Start()
+	require.NoError(t, err)
+	require.Equal(t, StateRunning, status.State)
+	require.NotNil(t, status.StartedAt)
+	require.Equal(t, status, e.Status())
+	waitForFlashFile(t, dir, 1)
+	require.Equal(t, []int{status.PID}, readPIDs(t, dir))
+
+	_, err = e.Start()
+	require.Equal(t, ErrRunning, err)
+
+	// the flash file is kept by a reset
+	reset, err := e.Reset()
+	require.NoError(t, err)
+	require.Equal(t, StateRunning, reset.State)
+	require.NotEqual(t, status.PID, reset.PID)
+	waitForFlashFile(t, dir, 2)
+	require.Equal(t, []int{status.PID, reset.PID}, readPIDs(t, dir))
+
+	// the flash file is removed by a wipe
+	wiped, err := e.Wipe()
+	require.NoError(t, err)
+	require.Equal(t, StateRunning, wiped.State)
+	waitForFlashFile(t, dir, 1)
+	require.Equal(t, []int{wiped.PID}, readPIDs(t, dir))
+
+	status, err = e.Stop()
+	require.NoError(t, err)
+	require.Equal(t, Status{
+		State:  StateStopped,
+		Binary: binary,
+	}, status)
+
+	// a stopped emulator is started by a reset
+	status, err = e.Reset()
+	require.NoError(t, err)
+	require.Equal(t, StateRunning, status.State)
+
+	require.NoError(t, e.Close())
+	require.Equal(t, StateStopped, e.Status().State)
+	require.NoError(t, e.Close())
+}
+
+func TestEmulatorExit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emulator")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := New(Config{
+		Binary: writeEmulator(t, dir, "#!/bin/sh\necho failed\nexit 1\n"),
+	})
+
+	_, err = e.Start()
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for e.Status().State == StateRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	status := e.Status()
+	require.Equal(t, StateStopped, status.State)
+	require.Equal(t, "exit status 1", status.ExitError)
+
+	// the exit error is cleared once started again
+	status, err = e.Start()
+	require.NoError(t, err)
+	require.Empty(t, status.ExitError)
+}
+
+func TestEmulatorNotFound(t *testing.T) {
+	e := New(Config{
+		Binary: "/nonexistent/emulator",
+	})
+
+	_, err := e.Start()
+	require.Error(t, err)
+	require.Equal(t, StateStopped, e.Status().State)
+}
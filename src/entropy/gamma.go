@@ -0,0 +1,73 @@
+package entropy
+
+import (
+	"math"
+)
+
+const (
+	gammaEpsilon  = 1e-15
+	gammaMaxIters = 1000
+)
+
+// igamc is the regularized upper incomplete gamma function Q(a, x)
+func igamc(a, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 1
+	}
+
+	if x < a+1 {
+		return 1 - igamSeries(a, x)
+	}
+	return igamContinuedFraction(a, x)
+}
+
+// igamSeries computes the regularized lower incomplete gamma function P(a, x) by its series expansion, which converges for x < a+1
+func igamSeries(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+
+	sum := 1 / a
+	term := sum
+	for n := 1; n < gammaMaxIters; n++ {
+		term *= x / (a + float64(n))
+		sum += term
+		if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+			break
+		}
+	}
+
+	return sum * math.Exp(-x+a*math.Log(x)-lgamma)
+}
+
+// igamContinuedFraction computes Q(a, x) by its continued fraction, using the modified Lentz method, which converges for x >= a+1
+func igamContinuedFraction(a, x float64) float64 {
+	const tiny = 1e-300
+
+	lgamma, _ := math.Lgamma(a)
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < gammaMaxIters; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+
+	return math.Exp(-x+a*math.Log(x)-lgamma) * h
+}
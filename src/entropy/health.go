@@ -0,0 +1,166 @@
+// Package entropy implements statistical health tests of random bytes produced by the device RNG.
+// The tests follow NIST SP 800-22, they detect a broken generator, not a weak one:
+// passing them does not prove the entropy is unpredictable.
+package entropy
+
+import (
+	"math"
+)
+
+const (
+	// DefaultAlpha is the significance level of the tests, a test fails if its p-value is lower
+	DefaultAlpha = 0.01
+
+	// MinBytes is the minimum number of bytes tested, so each byte value is expected at least 5 times by the chi-square test
+	MinBytes = 5 * 256
+
+	// TestMonobit is the frequency test of the proportion of ones
+	TestMonobit = "monobit"
+	// TestRuns is the test of the number of runs of identical bits
+	TestRuns = "runs"
+	// TestChiSquare is the chi-square goodness of fit test of the byte distribution
+	TestChiSquare = "chi_square"
+)
+
+// Result is the outcome of a health test
+type Result struct {
+	Name      string  `json:"name"`
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"p_value"`
+	Passed    bool    `json:"passed"`
+}
+
+// Report is the outcome of the health tests of a sample
+type Report struct {
+	Bytes  int      `json:"bytes"`
+	Alpha  float64  `json:"alpha"`
+	Passed bool     `json:"passed"`
+	Tests  []Result `json:"tests"`
+}
+
+// Check runs the health tests on data with the significance level alpha
+func Check(data []byte, alpha float64) Report {
+	bits := toBits(data)
+
+	report := Report{
+		Bytes:  len(data),
+		Alpha:  alpha,
+		Passed: true,
+		Tests: []Result{
+			monobit(bits),
+			runs(bits),
+			ChiSquare(data),
+		},
+	}
+
+	for i := range report.Tests {
+		report.Tests[i].Passed = report.Tests[i].PValue >= alpha
+		report.Passed = report.Passed && report.Tests[i].Passed
+	}
+
+	return report
+}
+
+// Monobit runs the frequency test, checking that ones and zeros are about as frequent.
+// The statistic is the normalized difference between the number of ones and zeros.
+func Monobit(data []byte) Result {
+	return monobit(toBits(data))
+}
+
+// Runs runs the runs test, checking that the oscillation between ones and zeros is neither too fast nor too slow.
+// The statistic is the number of runs of identical bits.
+func Runs(data []byte) Result {
+	return runs(toBits(data))
+}
+
+// ChiSquare runs the chi-square goodness of fit test of the distribution of the byte values against the uniform distribution
+func ChiSquare(data []byte) Result {
+	result := Result{
+		Name: TestChiSquare,
+	}
+
+	if len(data) == 0 {
+		return result
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	expected := float64(len(data)) / 256
+	for _, c := range counts {
+		d := float64(c) - expected
+		result.Statistic += d * d / expected
+	}
+
+	// 256 categories, 255 degrees of freedom
+	result.PValue = igamc(255/2.0, result.Statistic/2)
+	return result
+}
+
+func monobit(bits []uint8) Result {
+	result := Result{
+		Name: TestMonobit,
+	}
+
+	n := len(bits)
+	if n == 0 {
+		return result
+	}
+
+	s := 0
+	for _, b := range bits {
+		s += 2*int(b) - 1
+	}
+
+	result.Statistic = math.Abs(float64(s)) / math.Sqrt(float64(n))
+	result.PValue = math.Erfc(result.Statistic / math.Sqrt2)
+	return result
+}
+
+func runs(bits []uint8) Result {
+	result := Result{
+		Name: TestRuns,
+	}
+
+	n := len(bits)
+	if n == 0 {
+		return result
+	}
+
+	ones := 0
+	for _, b := range bits {
+		ones += int(b)
+	}
+	pi := float64(ones) / float64(n)
+
+	v := 1
+	for k := 0; k < n-1; k++ {
+		if bits[k] != bits[k+1] {
+			v++
+		}
+	}
+	result.Statistic = float64(v)
+
+	// the runs test is meaningless if the monobit frequency is too far off, the sample then fails
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(float64(n)) {
+		return result
+	}
+
+	num := math.Abs(float64(v) - 2*float64(n)*pi*(1-pi))
+	den := 2 * math.Sqrt(2*float64(n)) * pi * (1 - pi)
+	result.PValue = math.Erfc(num / den)
+	return result
+}
+
+// toBits expands data to its bits, most significant bit first
+func toBits(data []byte) []uint8 {
+	bits := make([]uint8, 0, 8*len(data))
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, (b>>uint(i))&1)
+		}
+	}
+	return bits
+}
@@ -0,0 +1,130 @@
+package entropy
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nistSample is the 100 bit sample of the NIST SP 800-22 frequency and runs test examples
+const nistSample = "1100100100001111110110101010001000100001011010001100001000110100110001001100011001100010100010111000"
+
+func parseBits(t *testing.T, s string) []uint8 {
+	bits := make([]uint8, len(s))
+	for i, c := range s {
+		require.True(t, c == '0' || c == '1')
+		bits[i] = uint8(c - '0')
+	}
+	return bits
+}
+
+func TestMonobitNIST(t *testing.T) {
+	r := monobit(parseBits(t, "1011010101"))
+	require.InDelta(t, 0.527089, r.PValue, 1e-6)
+
+	r = monobit(parseBits(t, nistSample))
+	require.InDelta(t, 1.6, r.Statistic, 1e-9)
+	require.InDelta(t, 0.109599, r.PValue, 1e-6)
+}
+
+func TestRunsNIST(t *testing.T) {
+	r := runs(parseBits(t, "1001101011"))
+	require.Equal(t, float64(7), r.Statistic)
+	require.InDelta(t, 0.147232, r.PValue, 1e-6)
+
+	r = runs(parseBits(t, nistSample))
+	require.Equal(t, float64(52), r.Statistic)
+	require.InDelta(t, 0.500798, r.PValue, 1e-6)
+}
+
+func TestIgamc(t *testing.T) {
+	// Q(1, x) = exp(-x)
+	for _, x := range []float64{0.1, 1, 2.5, 10, 50} {
+		require.InDelta(t, math.Exp(-x), igamc(1, x), 1e-12)
+	}
+
+	// Q(a, a) for large a is close to 0.5
+	require.InDelta(t, 0.5, igamc(127.5, 127.5), 0.02)
+
+	require.Equal(t, float64(1), igamc(127.5, 0))
+}
+
+func TestCheck(t *testing.T) {
+	random := make([]byte, 4096)
+	_, err := rand.New(rand.NewSource(1)).Read(random)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		data   []byte
+		passed map[string]bool
+	}{
+		{
+			name: "random",
+			data: random,
+			passed: map[string]bool{
+				TestMonobit:   true,
+				TestRuns:      true,
+				TestChiSquare: true,
+			},
+		},
+		{
+			name: "zeros",
+			data: make([]byte, 4096),
+			passed: map[string]bool{
+				TestMonobit:   false,
+				TestRuns:      false,
+				TestChiSquare: false,
+			},
+		},
+		{
+			// balanced bits alternating too often, a single byte value
+			name: "alternating",
+			data: bytes.Repeat([]byte{0x55}, 4096),
+			passed: map[string]bool{
+				TestMonobit:   true,
+				TestRuns:      false,
+				TestChiSquare: false,
+			},
+		},
+		{
+			// a predictable sample can pass the tests, they only detect a broken generator
+			name: "counter",
+			data: bytes.Repeat(counter(), 16),
+			passed: map[string]bool{
+				TestMonobit:   true,
+				TestRuns:      true,
+				TestChiSquare: true,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Check(tc.data, DefaultAlpha)
+			require.Equal(t, len(tc.data), report.Bytes)
+			require.Equal(t, DefaultAlpha, report.Alpha)
+			require.Len(t, report.Tests, len(tc.passed))
+
+			passed := true
+			for _, r := range report.Tests {
+				expected, ok := tc.passed[r.Name]
+				require.True(t, ok, r.Name)
+				require.Equal(t, expected, r.Passed, "%s: p-value %f", r.Name, r.PValue)
+				passed = passed && expected
+			}
+			require.Equal(t, passed, report.Passed)
+		})
+	}
+}
+
+func counter() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
@@ -0,0 +1,311 @@
+// Package events implements the daemon event stream.
+// Events are persisted with monotonically increasing sequence numbers,
+// so clients that reconnect can catch up from the last sequence number they received.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// Type is an event type
+type Type string
+
+const (
+	// TypeDeviceConnected is published when a device becomes reachable
+	TypeDeviceConnected Type = "device_connected"
+	// TypeDeviceDisconnected is published when a device stops being reachable
+	TypeDeviceDisconnected Type = "device_disconnected"
+	// TypeOperationFinished is published when an API operation that talks to the device completes
+	TypeOperationFinished Type = "operation_finished"
+	// TypeTransactionSigned is published when the device signed a transaction
+	TypeTransactionSigned Type = "transaction_signed"
+	// TypeTransactionRejected is published when the signature of a transaction was rejected on the device or canceled
+	TypeTransactionRejected Type = "transaction_rejected"
+	// TypeFirmwareUpdated is published when the firmware of the device was updated
+	TypeFirmwareUpdated Type = "firmware_updated"
+	// TypeLogFileFailed is published when the log file cannot be written anymore and the daemon logs to stderr
+	TypeLogFileFailed Type = "log_file_failed"
+	// TypeOverflow notifies a subscriber that events were dropped because it did not keep up.
+	// It is not persisted nor published on the bus.
+	TypeOverflow Type = "overflow"
+)
+
+// Types are the event types published on the bus
+var Types = []Type{
+	TypeDeviceConnected,
+	TypeDeviceDisconnected,
+	TypeOperationFinished,
+	TypeTransactionSigned,
+	TypeTransactionRejected,
+	TypeFirmwareUpdated,
+	TypeLogFileFailed,
+}
+
+// ParseType parses an event type published on the bus
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid event type %q", s)
+}
+
+const (
+	// Bucket is the storage bucket holding persisted events
+	Bucket = "events"
+
+	// DefaultMaxEvents is the default number of events kept for catch-up
+	DefaultMaxEvents = 1000
+
+	subscriberBufferSize = 64
+)
+
+var (
+	logger = logging.MustGetLogger("events")
+
+	errStopIteration = errors.New("stop iteration")
+)
+
+// Event is an entry of the event stream
+type Event struct {
+	Seq      uint64          `json:"seq"`
+	Type     Type            `json:"type"`
+	DeviceID string          `json:"device_id,omitempty"`
+	Time     time.Time       `json:"time"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// OverflowData is the data of an overflow notice
+type OverflowData struct {
+	// Dropped is the number of events dropped
+	Dropped uint64 `json:"dropped"`
+	// LastSeq is the sequence number of the last event delivered, events after it can be fetched with Bus.Since
+	LastSeq uint64 `json:"last_seq"`
+}
+
+// Filter selects events. Empty fields match all events.
+type Filter struct {
+	DeviceID string
+	Types    []Type
+}
+
+// Match returns true if the event is selected by the filter
+func (f Filter) Match(ev Event) bool {
+	if f.DeviceID != "" && ev.DeviceID != f.DeviceID {
+		return false
+	}
+
+	if len(f.Types) == 0 {
+		return true
+	}
+
+	for _, t := range f.Types {
+		if ev.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bus persists published events and fans them out to subscribers
+type Bus struct {
+	mu        sync.Mutex
+	store     storage.Store
+	maxEvents int
+	// sequence numbers of the persisted events, oldest first
+	persisted   []uint64
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events matching its filter published after it was created.
+// Events are dropped if the subscriber does not keep up, so a stalled subscriber never blocks
+// the delivery to others. Overflow is signaled when events are dropped, the subscriber can
+// catch up with Bus.Since from the last sequence number it received.
+type Subscription struct {
+	C <-chan Event
+	// Overflow receives a value when events have been dropped since the last call to Dropped
+	Overflow <-chan struct{}
+
+	c        chan Event
+	overflow chan struct{}
+	filter   Filter
+	dropped  uint64
+	bus      *Bus
+}
+
+// NewBus creates a Bus persisting events to store, keeping at most maxEvents of them
+func NewBus(store storage.Store, maxEvents int) (*Bus, error) {
+	if maxEvents <= 0 {
+		maxEvents = DefaultMaxEvents
+	}
+
+	b := &Bus{
+		store:       store,
+		maxEvents:   maxEvents,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+
+	if err := store.ForEach(Bucket, func(k string, _ []byte) error {
+		seq, err := storage.ParseSequenceKey(k)
+		if err != nil {
+			return err
+		}
+		b.persisted = append(b.persisted, seq)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Publish persists an event and delivers it to the subscribers.
+// data is encoded to JSON and may be nil.
+func (b *Bus) Publish(typ Type, deviceID string, data interface{}) (Event, error) {
+	ev := Event{
+		Type:     typ,
+		DeviceID: deviceID,
+		Time:     time.Now().UTC(),
+	}
+
+	if data != nil {
+		d, err := json.Marshal(data)
+		if err != nil {
+			return Event{}, err
+		}
+		ev.Data = d
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	value, err := json.Marshal(ev)
+	if err != nil {
+		return Event{}, err
+	}
+
+	seq, err := b.store.Append(Bucket, value)
+	if err != nil {
+		return Event{}, err
+	}
+	ev.Seq = seq
+
+	b.persisted = append(b.persisted, seq)
+	b.prune()
+
+	for s := range b.subscribers {
+		if !s.filter.Match(ev) {
+			continue
+		}
+
+		select {
+		case s.c <- ev:
+		default:
+			logger.Warningf("subscriber buffer full, dropping event %d", ev.Seq)
+			s.dropped++
+			select {
+			case s.overflow <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	return ev, nil
+}
+
+// prune removes the oldest persisted events beyond maxEvents
+func (b *Bus) prune() {
+	for len(b.persisted) > b.maxEvents {
+		if err := b.store.Delete(Bucket, storage.SequenceKey(b.persisted[0])); err != nil {
+			logger.WithError(err).Error("failed to prune event")
+			return
+		}
+		b.persisted = b.persisted[1:]
+	}
+}
+
+// Since returns the persisted events matching filter with a sequence number greater than cursor, oldest first.
+// At most limit events are returned, limit <= 0 means no limit.
+func (b *Bus) Since(cursor uint64, limit int, filter Filter) ([]Event, error) {
+	var evs []Event
+
+	err := b.store.ForEach(Bucket, func(k string, v []byte) error {
+		seq, err := storage.ParseSequenceKey(k)
+		if err != nil {
+			return err
+		}
+
+		if seq <= cursor {
+			return nil
+		}
+
+		var ev Event
+		if err := json.Unmarshal(v, &ev); err != nil {
+			return err
+		}
+		ev.Seq = seq
+
+		if !filter.Match(ev) {
+			return nil
+		}
+
+		evs = append(evs, ev)
+		if limit > 0 && len(evs) >= limit {
+			return errStopIteration
+		}
+		return nil
+	})
+
+	if err != nil && err != errStopIteration {
+		return nil, err
+	}
+
+	return evs, nil
+}
+
+// Subscribe registers a new subscription receiving the events matching filter.
+// Unsubscribe must be called when it is no longer used.
+func (b *Bus) Subscribe(filter Filter) *Subscription {
+	c := make(chan Event, subscriberBufferSize)
+	overflow := make(chan struct{}, 1)
+	s := &Subscription{
+		C:        c,
+		Overflow: overflow,
+		c:        c,
+		overflow: overflow,
+		filter:   filter,
+		bus:      b,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Dropped returns the number of events dropped since the last call and resets the count
+func (s *Subscription) Dropped() uint64 {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
+
+// Unsubscribe removes the subscription from the bus
+func (s *Subscription) Unsubscribe() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	delete(s.bus.subscribers, s)
+}
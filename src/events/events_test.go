@@ -0,0 +1,273 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestBusPublishSince(t *testing.T) {
+	store := storage.NewMemoryStore()
+	bus, err := NewBus(store, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		ev, err := bus.Publish(TypeOperationFinished, "dev", map[string]int{"i": i})
+		require.NoError(t, err)
+		require.Equal(t, uint64(i+1), ev.Seq)
+	}
+
+	evs, err := bus.Since(0, 0, Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 3)
+	for i, ev := range evs {
+		require.Equal(t, uint64(i+1), ev.Seq)
+		require.Equal(t, TypeOperationFinished, ev.Type)
+		require.Equal(t, "dev", ev.DeviceID)
+	}
+
+	evs, err = bus.Since(1, 0, Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 2)
+	require.Equal(t, uint64(2), evs[0].Seq)
+	require.JSONEq(t, `{"i":1}`, string(evs[0].Data))
+
+	evs, err = bus.Since(1, 1, Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 1)
+	require.Equal(t, uint64(2), evs[0].Seq)
+
+	evs, err = bus.Since(3, 0, Filter{})
+	require.NoError(t, err)
+	require.Empty(t, evs)
+}
+
+func TestBusPrune(t *testing.T) {
+	store := storage.NewMemoryStore()
+	bus, err := NewBus(store, 2)
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err := bus.Publish(TypeDeviceConnected, "", nil)
+		require.NoError(t, err)
+	}
+
+	evs, err := bus.Since(0, 0, Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 2)
+	require.Equal(t, uint64(3), evs[0].Seq)
+	require.Equal(t, uint64(4), evs[1].Seq)
+}
+
+func TestBusReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := storage.NewFileStore(dir)
+	require.NoError(t, err)
+
+	bus, err := NewBus(store, 2)
+	require.NoError(t, err)
+	_, err = bus.Publish(TypeDeviceConnected, "", nil)
+	require.NoError(t, err)
+	_, err = bus.Publish(TypeDeviceDisconnected, "", nil)
+	require.NoError(t, err)
+
+	// sequence numbers continue and pruning accounts for the events persisted previously
+	store, err = storage.NewFileStore(dir)
+	require.NoError(t, err)
+
+	bus, err = NewBus(store, 2)
+	require.NoError(t, err)
+	ev, err := bus.Publish(TypeDeviceConnected, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), ev.Seq)
+
+	evs, err := bus.Since(0, 0, Filter{})
+	require.NoError(t, err)
+	require.Len(t, evs, 2)
+	require.Equal(t, uint64(2), evs[0].Seq)
+	require.Equal(t, TypeDeviceDisconnected, evs[0].Type)
+}
+
+func TestBusSubscribe(t *testing.T) {
+	bus, err := NewBus(storage.NewMemoryStore(), 0)
+	require.NoError(t, err)
+
+	sub := bus.Subscribe(Filter{})
+
+	_, err = bus.Publish(TypeDeviceConnected, "", nil)
+	require.NoError(t, err)
+
+	select {
+	case ev := <-sub.C:
+		require.Equal(t, uint64(1), ev.Seq)
+		require.Equal(t, TypeDeviceConnected, ev.Type)
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	sub.Unsubscribe()
+
+	_, err = bus.Publish(TypeDeviceDisconnected, "", nil)
+	require.NoError(t, err)
+
+	select {
+	case ev := <-sub.C:
+		t.Fatalf("unexpected event %d after unsubscribe", ev.Seq)
+	default:
+	}
+}
+
+func TestWatchDevice(t *testing.T) {
+	bus, err := NewBus(storage.NewMemoryStore(), 0)
+	require.NoError(t, err)
+
+	states := make(chan bool, 3)
+	states <- false
+	states <- true
+	states <- false
+
+	last := false
+	available := func() bool {
+		select {
+		case last = <-states:
+		default:
+		}
+		return last
+	}
+
+	sub := bus.Subscribe(Filter{})
+	defer sub.Unsubscribe()
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		WatchDevice(bus, available, time.Millisecond, quit)
+	}()
+
+	for _, typ := range []Type{TypeDeviceConnected, TypeDeviceDisconnected} {
+		select {
+		case ev := <-sub.C:
+			require.Equal(t, typ, ev.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("%s event not published", typ)
+		}
+	}
+
+	close(quit)
+	<-done
+}
+
+func TestFilter(t *testing.T) {
+	ev := Event{
+		Type:     TypeOperationFinished,
+		DeviceID: "dev",
+	}
+
+	cases := []struct {
+		name   string
+		filter Filter
+		match  bool
+	}{
+		{
+			name:  "empty",
+			match: true,
+		},
+		{
+			name:   "device",
+			filter: Filter{DeviceID: "dev"},
+			match:  true,
+		},
+		{
+			name:   "other device",
+			filter: Filter{DeviceID: "other"},
+		},
+		{
+			name:   "types",
+			filter: Filter{Types: []Type{TypeDeviceConnected, TypeOperationFinished}},
+			match:  true,
+		},
+		{
+			name:   "other types",
+			filter: Filter{Types: []Type{TypeDeviceConnected}},
+		},
+		{
+			name:   "device and other types",
+			filter: Filter{DeviceID: "dev", Types: []Type{TypeDeviceConnected}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.match, tc.filter.Match(ev))
+		})
+	}
+}
+
+func TestBusFilters(t *testing.T) {
+	bus, err := NewBus(storage.NewMemoryStore(), 0)
+	require.NoError(t, err)
+
+	filter := Filter{Types: []Type{TypeDeviceDisconnected}}
+	sub := bus.Subscribe(filter)
+	defer sub.Unsubscribe()
+
+	_, err = bus.Publish(TypeDeviceConnected, "", nil)
+	require.NoError(t, err)
+	_, err = bus.Publish(TypeDeviceDisconnected, "", nil)
+	require.NoError(t, err)
+
+	ev := <-sub.C
+	require.Equal(t, uint64(2), ev.Seq)
+	require.Empty(t, sub.C)
+
+	evs, err := bus.Since(0, 0, filter)
+	require.NoError(t, err)
+	require.Len(t, evs, 1)
+	require.Equal(t, uint64(2), evs[0].Seq)
+}
+
+func TestBusOverflow(t *testing.T) {
+	bus, err := NewBus(storage.NewMemoryStore(), 0)
+	require.NoError(t, err)
+
+	stalled := bus.Subscribe(Filter{})
+	defer stalled.Unsubscribe()
+
+	for i := 0; i < subscriberBufferSize+3; i++ {
+		// a stalled subscriber does not block the others
+		other := bus.Subscribe(Filter{})
+		_, err := bus.Publish(TypeOperationFinished, "", nil)
+		require.NoError(t, err)
+		require.Len(t, other.C, 1)
+		other.Unsubscribe()
+	}
+
+	select {
+	case <-stalled.Overflow:
+	default:
+		t.Fatal("overflow not signaled")
+	}
+	require.Equal(t, uint64(3), stalled.Dropped())
+	require.Equal(t, uint64(0), stalled.Dropped())
+	require.Len(t, stalled.C, subscriberBufferSize)
+}
+
+func TestParseType(t *testing.T) {
+	for _, typ := range Types {
+		parsed, err := ParseType(string(typ))
+		require.NoError(t, err)
+		require.Equal(t, typ, parsed)
+	}
+
+	_, err := ParseType(string(TypeOverflow))
+	require.Error(t, err)
+}
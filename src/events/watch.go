@@ -0,0 +1,39 @@
+package events
+
+import (
+	"time"
+)
+
+// DefaultWatchInterval is how often device availability is polled
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchDevice polls available every interval and publishes device_connected and device_disconnected
+// events when the availability of the device changes. It returns when quit is closed.
+func WatchDevice(bus *Bus, available func() bool, interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	connected := available()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			now := available()
+			if now == connected {
+				continue
+			}
+			connected = now
+
+			typ := TypeDeviceDisconnected
+			if connected {
+				typ = TypeDeviceConnected
+			}
+
+			if _, err := bus.Publish(typ, "", nil); err != nil {
+				logger.WithError(err).Errorf("failed to publish %s event", typ)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package fee estimates the coin hours burned by the Skycoin transactions, distributes the remaining hours across
+// their outputs and flags the transactions the network would reject, before the user is asked to confirm them on the
+// device.
+package fee
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	// BurnFactor is the inverse of the fraction of the input coin hours the user transactions burn at least
+	BurnFactor = 10
+	// MaxDropletPrecision is the number of decimal places of the coins of an output
+	MaxDropletPrecision = 3
+
+	// dropletsPrecision is the number of droplets the coins of an output are a multiple of, by MaxDropletPrecision
+	dropletsPrecision = 1000
+)
+
+// Problems flagged on the transactions the network would reject
+const (
+	ProblemInsufficientHours = "insufficient_hours"
+	ProblemNoFee             = "no_fee"
+	ProblemDust              = "dust"
+	ProblemZeroCoins         = "zero_coins"
+	ProblemCoinsMismatch     = "coins_mismatch"
+	ProblemDuplicateInput    = "duplicate_input"
+	ProblemDuplicateOutput   = "duplicate_output"
+	ProblemOverflow          = "overflow"
+)
+
+// Input is an unspent output spent by a transaction
+type Input struct {
+	Hash  string
+	Coins uint64
+	// Hours are the coin hours of the output at the head block
+	Hours uint64
+}
+
+// Output is an output of a transaction
+type Output struct {
+	Address string
+	Coins   uint64
+	// Hours are the coin hours sent to the address, nil distributes the remaining hours
+	Hours *uint64
+}
+
+// Problem is a reason the network would reject a transaction
+type Problem struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Output is the index of the output of the problem, nil if the problem is not about an output
+	Output *int `json:"output,omitempty"`
+}
+
+// Estimate is the estimation of a transaction
+type Estimate struct {
+	InputCoins  uint64 `json:"input_coins"`
+	InputHours  uint64 `json:"input_hours"`
+	OutputCoins uint64 `json:"output_coins"`
+	// OutputHours are the hours sent to the outputs, once the remaining hours are distributed
+	OutputHours uint64 `json:"output_hours"`
+	// RequiredFee is the least number of hours burned accepted by the network
+	RequiredFee uint64 `json:"required_fee"`
+	// Fee is the number of hours burned
+	Fee uint64 `json:"fee"`
+	// Hours are the hours of each output, once the remaining hours are distributed
+	Hours []uint64 `json:"hours"`
+	// Problems are the reasons the network would reject the transaction, empty if it would accept it
+	Problems []Problem `json:"problems"`
+}
+
+// Valid reports whether the network would accept the transaction
+func (e Estimate) Valid() bool {
+	return len(e.Problems) == 0
+}
+
+// RequiredFee returns the least number of hours a user transaction spending hours burns
+func RequiredFee(hours uint64) uint64 {
+	fee := hours / BurnFactor
+	if hours%BurnFactor != 0 {
+		fee++
+	}
+	return fee
+}
+
+// Compute estimates the transaction spending inputs to outputs. The hours remaining after the required fee and the
+// hours of the outputs are distributed to the outputs without hours, in proportion to their coins.
+func Compute(inputs []Input, outputs []Output) Estimate {
+	e := Estimate{
+		Hours:    make([]uint64, len(outputs)),
+		Problems: []Problem{},
+	}
+
+	problem := func(code string, output int, format string, args ...interface{}) {
+		p := Problem{
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+		}
+		if output >= 0 {
+			p.Output = &output
+		}
+		e.Problems = append(e.Problems, p)
+	}
+
+	overflow := false
+	add := func(a, b uint64) uint64 {
+		if a+b < a {
+			overflow = true
+		}
+		return a + b
+	}
+
+	spent := make(map[string]struct{}, len(inputs))
+	for _, in := range inputs {
+		if _, ok := spent[in.Hash]; ok {
+			problem(ProblemDuplicateInput, -1, "input %s is spent twice", in.Hash)
+		}
+		spent[in.Hash] = struct{}{}
+
+		e.InputCoins = add(e.InputCoins, in.Coins)
+		e.InputHours = add(e.InputHours, in.Hours)
+	}
+
+	var setHours, unsetCoins uint64
+	for i, o := range outputs {
+		if o.Coins == 0 {
+			problem(ProblemZeroCoins, i, "output %d sends no coins", i)
+		} else if o.Coins%dropletsPrecision != 0 {
+			problem(ProblemDust, i, "output %d coins have more than %d decimal places", i, MaxDropletPrecision)
+		}
+
+		e.OutputCoins = add(e.OutputCoins, o.Coins)
+		if o.Hours != nil {
+			e.Hours[i] = *o.Hours
+			setHours = add(setHours, *o.Hours)
+		} else {
+			unsetCoins = add(unsetCoins, o.Coins)
+		}
+	}
+
+	if overflow {
+		problem(ProblemOverflow, -1, "the coins or the hours overflow")
+		return e
+	}
+
+	if e.InputCoins != e.OutputCoins {
+		problem(ProblemCoinsMismatch, -1, "the inputs have %d droplets and the outputs %d, they must be equal", e.InputCoins, e.OutputCoins)
+	}
+
+	e.RequiredFee = RequiredFee(e.InputHours)
+	if e.InputHours == 0 {
+		problem(ProblemNoFee, -1, "the inputs have no coin hours to burn")
+	}
+
+	if setHours > e.InputHours-e.RequiredFee {
+		problem(ProblemInsufficientHours, -1, "the outputs send %d hours, at most %d are left after the fee of %d", setHours, e.InputHours-e.RequiredFee, e.RequiredFee)
+	} else if unsetCoins > 0 {
+		distribute(e.Hours, outputs, e.InputHours-e.RequiredFee-setHours, unsetCoins)
+	}
+
+	for i, h := range e.Hours {
+		e.OutputHours += h
+		// the outputs sent the remaining hours are told apart once distributed
+		for j := 0; j < i; j++ {
+			if outputs[i].Address == outputs[j].Address && outputs[i].Coins == outputs[j].Coins && e.Hours[i] == e.Hours[j] {
+				problem(ProblemDuplicateOutput, i, "output %d is the same as output %d", i, j)
+				break
+			}
+		}
+	}
+
+	if e.OutputHours <= e.InputHours {
+		e.Fee = e.InputHours - e.OutputHours
+	}
+
+	return e
+}
+
+// distribute shares hours across the outputs without hours, in proportion to their coins. The hours left by the
+// rounding go to the first ones.
+func distribute(dst []uint64, outputs []Output, hours, coins uint64) {
+	var given uint64
+	for i, o := range outputs {
+		if o.Hours != nil {
+			continue
+		}
+
+		share := new(big.Int).Mul(new(big.Int).SetUint64(hours), new(big.Int).SetUint64(o.Coins))
+		share.Div(share, new(big.Int).SetUint64(coins))
+		dst[i] = share.Uint64()
+		given += dst[i]
+	}
+
+	for i, o := range outputs {
+		if given == hours {
+			break
+		}
+		if o.Hours != nil || o.Coins == 0 {
+			continue
+		}
+		dst[i]++
+		given++
+	}
+}
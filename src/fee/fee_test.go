@@ -0,0 +1,109 @@
+package fee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newUint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func problemCodes(e Estimate) []string {
+	codes := []string{}
+	for _, p := range e.Problems {
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
+
+func TestRequiredFee(t *testing.T) {
+	require.Equal(t, uint64(0), RequiredFee(0))
+	require.Equal(t, uint64(1), RequiredFee(1))
+	require.Equal(t, uint64(1), RequiredFee(10))
+	require.Equal(t, uint64(2), RequiredFee(11))
+}
+
+func TestCompute(t *testing.T) {
+	inputs := []Input{
+		{Hash: "a", Coins: 2000000, Hours: 60},
+		{Hash: "b", Coins: 1000000, Hours: 41},
+	}
+
+	cases := []struct {
+		name     string
+		inputs   []Input
+		outputs  []Output
+		hours    []uint64
+		fee      uint64
+		problems []string
+	}{
+		{
+			name:   "distributed",
+			inputs: inputs,
+			outputs: []Output{
+				{Address: "x", Coins: 1000000},
+				{Address: "y", Coins: 2000000},
+			},
+			// 101 hours, 11 burned, 90 shared 1:2
+			hours:    []uint64{30, 60},
+			fee:      11,
+			problems: []string{},
+		},
+		{
+			name:   "rounding",
+			inputs: inputs,
+			outputs: []Output{
+				{Address: "x", Coins: 1000000, Hours: newUint64Ptr(1)},
+				{Address: "y", Coins: 1000000},
+				{Address: "z", Coins: 1000000},
+			},
+			// 89 hours shared 1:1, the hour left by the rounding goes to the first output
+			hours:    []uint64{1, 45, 44},
+			fee:      11,
+			problems: []string{},
+		},
+		{
+			name:   "insufficient hours",
+			inputs: inputs,
+			outputs: []Output{
+				{Address: "x", Coins: 3000000, Hours: newUint64Ptr(91)},
+			},
+			hours:    []uint64{91},
+			fee:      10,
+			problems: []string{ProblemInsufficientHours},
+		},
+		{
+			name:   "dust and mismatch",
+			inputs: inputs,
+			outputs: []Output{
+				{Address: "x", Coins: 1000001},
+				{Address: "y", Coins: 0},
+			},
+			hours:    []uint64{90, 0},
+			fee:      11,
+			problems: []string{ProblemDust, ProblemZeroCoins, ProblemCoinsMismatch},
+		},
+		{
+			name:   "no fee",
+			inputs: []Input{{Hash: "a", Coins: 1000000}, {Hash: "a", Coins: 1000000}},
+			outputs: []Output{
+				{Address: "x", Coins: 1000000, Hours: newUint64Ptr(0)},
+				{Address: "x", Coins: 1000000, Hours: newUint64Ptr(0)},
+			},
+			hours:    []uint64{0, 0},
+			problems: []string{ProblemDuplicateInput, ProblemNoFee, ProblemDuplicateOutput},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Compute(tc.inputs, tc.outputs)
+			require.Equal(t, tc.hours, e.Hours)
+			require.Equal(t, tc.fee, e.Fee)
+			require.Equal(t, tc.problems, problemCodes(e))
+			require.Equal(t, len(tc.problems) == 0, e.Valid())
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package firmware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// cacheManifestFilename is the name of the manifest of the cached images in the cache directory
+	cacheManifestFilename = "manifest.json"
+	// imageExt is the extension of the cached images, named after their hash
+	imageExt = ".bin"
+)
+
+var (
+	// ErrNotCached is returned when the firmware release is not in the cache
+	ErrNotCached = errors.New("firmware release is not cached")
+	// ErrCacheCorrupted is returned when a cached image does not match the manifest of the cache
+	ErrCacheCorrupted = errors.New("cached firmware image does not match the cache manifest, purge it and download it again")
+)
+
+// CacheEntry is a firmware image in the cache
+type CacheEntry struct {
+	Version string `json:"version"`
+	// SHA256 is the hex encoded hash of the image, the hash of its release
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+	URL    string `json:"url"`
+	// Channel and ManifestHash are the release channel and the hash of the manifest the image was downloaded from
+	Channel      string `json:"channel"`
+	ManifestHash string `json:"manifest_hash"`
+	// Signature is the hex encoded signature of the manifest, empty if it is pinned and not signed
+	Signature string    `json:"signature,omitempty"`
+	CachedAt  time.Time `json:"cached_at"`
+}
+
+// cacheRecord is an entry of the manifest of the cache, with the signed manifest listing its release verified again
+// when the image is read. The signed manifest is kept as a string, its bytes are hashed.
+type cacheRecord struct {
+	CacheEntry
+	SignedManifest string `json:"signed_manifest"`
+}
+
+// Cache holds the firmware images downloaded from the release channel in a directory, so the later updates do not
+// download them again and the devices can be updated offline. The manifest.json file of the directory lists the images
+// with their hash and the signed manifest of their release.
+type Cache struct {
+	dir    string
+	pubKey cipher.PubKey
+	mu     sync.Mutex
+}
+
+// NewCache creates a Cache in dir. The signed manifests of the cached images are verified with pubKey if it is not
+// null, their hash is verified in any case.
+func NewCache(dir string, pubKey cipher.PubKey) *Cache {
+	return &Cache{
+		dir:    dir,
+		pubKey: pubKey,
+	}
+}
+
+// Dir returns the directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// List returns the cached images, oldest version first
+func (c *Cache) List() ([]CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records, err := c.readManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, len(records))
+	for i, r := range records {
+		entries[i] = r.CacheEntry
+	}
+	return entries, nil
+}
+
+// Get returns the cached image of the release version and its entry, after checking the image against its hash and
+// the signed manifest of its release. ErrNotCached is returned if the release is not cached.
+func (c *Cache) Get(version Version) ([]byte, CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records, err := c.readManifest()
+	if err != nil {
+		return nil, CacheEntry{}, err
+	}
+
+	i := findRecord(records, version)
+	if i < 0 {
+		return nil, CacheEntry{}, ErrNotCached
+	}
+	r := records[i]
+
+	data, err := ioutil.ReadFile(c.imagePath(r.SHA256))
+	if os.IsNotExist(err) {
+		return nil, CacheEntry{}, ErrCacheCorrupted
+	} else if err != nil {
+		return nil, CacheEntry{}, err
+	}
+
+	if err := c.verify(r, data); err != nil {
+		return nil, CacheEntry{}, err
+	}
+
+	return data, r.CacheEntry, nil
+}
+
+// verify checks the image of a record against its hash and its release in the signed manifest
+func (c *Cache) verify(r cacheRecord, data []byte) error {
+	if !strings.EqualFold(cipher.SumSHA256(data).Hex(), r.SHA256) {
+		return ErrCacheCorrupted
+	}
+
+	manifest, hash, err := ParseSignedManifest([]byte(r.SignedManifest), c.pubKey, "")
+	if err != nil {
+		return fmt.Errorf("cached firmware image %s: %v", r.Version, err)
+	}
+	if hash.Hex() != r.ManifestHash {
+		return ErrCacheCorrupted
+	}
+
+	for _, release := range manifest.Releases {
+		if release.Version == r.Version && strings.EqualFold(release.SHA256, r.SHA256) {
+			return nil
+		}
+	}
+	return ErrCacheCorrupted
+}
+
+// put adds the image of release, listed in the signed manifest document, to the cache
+func (c *Cache) put(release Release, data []byte, document []byte, now time.Time) (CacheEntry, error) {
+	var sm signedManifest
+	if err := json.Unmarshal(document, &sm); err != nil {
+		return CacheEntry{}, fmt.Errorf("invalid firmware manifest: %v", err)
+	}
+
+	version, err := ParseVersion(release.Version)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	r := cacheRecord{
+		CacheEntry: CacheEntry{
+			Version:      release.Version,
+			SHA256:       strings.ToLower(release.SHA256),
+			Size:         len(data),
+			URL:          release.URL,
+			ManifestHash: cipher.SumSHA256(sm.Manifest).Hex(),
+			Signature:    sm.Signature,
+			CachedAt:     now.UTC(),
+		},
+		SignedManifest: string(document),
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(sm.Manifest, &m); err == nil {
+		r.Channel = m.Channel
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0750); err != nil {
+		return CacheEntry{}, err
+	}
+
+	if err := writeFile(c.imagePath(r.SHA256), data); err != nil {
+		return CacheEntry{}, err
+	}
+
+	records, err := c.readManifest()
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	if i := findRecord(records, version); i >= 0 {
+		records[i] = r
+	} else {
+		records = append(records, r)
+	}
+
+	if err := c.writeManifest(records); err != nil {
+		return CacheEntry{}, err
+	}
+
+	return r.CacheEntry, nil
+}
+
+// Purge removes the cached image of the release version, or all the cached images if version is nil, and returns the
+// entries removed. ErrNotCached is returned if the release is not cached.
+func (c *Cache) Purge(version *Version) ([]CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records, err := c.readManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged, kept []cacheRecord
+	if version == nil {
+		purged = records
+	} else {
+		i := findRecord(records, *version)
+		if i < 0 {
+			return nil, ErrNotCached
+		}
+		purged = records[i : i+1]
+		kept = append(kept, records[:i]...)
+		kept = append(kept, records[i+1:]...)
+	}
+
+	if err := c.writeManifest(kept); err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, len(purged))
+	for i, r := range purged {
+		entries[i] = r.CacheEntry
+		if err := c.removeImage(r.SHA256, kept); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// removeImage removes the image of hash unless a kept record uses it
+func (c *Cache) removeImage(hash string, kept []cacheRecord) error {
+	for _, r := range kept {
+		if r.SHA256 == hash {
+			return nil
+		}
+	}
+
+	if err := os.Remove(c.imagePath(hash)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) imagePath(hash string) string {
+	return filepath.Join(c.dir, hash+imageExt)
+}
+
+// readManifest reads the records of the manifest of the cache, sorted by version
+func (c *Cache) readManifest() ([]cacheRecord, error) {
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, cacheManifestFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var records []cacheRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid firmware cache manifest: %v", err)
+	}
+
+	for _, r := range records {
+		if _, err := ParseVersion(r.Version); err != nil {
+			return nil, fmt.Errorf("invalid firmware cache manifest: %v", err)
+		}
+		if _, err := cipher.SHA256FromHex(r.SHA256); err != nil {
+			return nil, fmt.Errorf("invalid firmware cache manifest: %v", err)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		a, _ := ParseVersion(records[i].Version) // nolint: errcheck
+		b, _ := ParseVersion(records[j].Version) // nolint: errcheck
+		return a.Compare(b) < 0
+	})
+	return records, nil
+}
+
+func (c *Cache) writeManifest(records []cacheRecord) error {
+	if records == nil {
+		records = []cacheRecord{}
+	}
+
+	data, err := json.MarshalIndent(records, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(c.dir, cacheManifestFilename), data)
+}
+
+// findRecord returns the index of the record of version, -1 if there is none
+func findRecord(records []cacheRecord, version Version) int {
+	for i, r := range records {
+		if v, err := ParseVersion(r.Version); err == nil && v.Compare(version) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeFile writes a file of the cache atomically, so a crash does not leave a partial image or manifest
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
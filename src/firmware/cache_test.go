@@ -0,0 +1,136 @@
+package firmware
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRelease writes the image of a release to dir
+func newTestRelease(t *testing.T, dir, version string) (Release, []byte) {
+	image := []byte("firmware " + version)
+	path := filepath.Join(dir, "skyfirmware-"+version+".bin")
+	require.NoError(t, ioutil.WriteFile(path, image, 0600))
+
+	return Release{
+		Version: version,
+		URL:     path,
+		SHA256:  cipher.SumSHA256(image).Hex(),
+	}, image
+}
+
+// newTestChannel writes the manifest of releases signed by secKey to dir and returns its channel
+func newTestChannel(t *testing.T, dir string, pubKey cipher.PubKey, secKey cipher.SecKey, releases ...Release) *Channel {
+	manifest := `{"channel":"stable","releases":[`
+	for i, r := range releases {
+		if i > 0 {
+			manifest += ","
+		}
+		manifest += fmt.Sprintf(`{"version":%q,"url":%q,"sha256":%q}`, r.Version, r.URL, r.SHA256)
+	}
+	manifest += `]}`
+
+	path := filepath.Join(dir, "manifest.json")
+	require.NoError(t, ioutil.WriteFile(path, signManifest(t, []byte(manifest), secKey), 0600))
+
+	c, err := NewChannel(ChannelConfig{
+		Source: path,
+		PubKey: pubKey,
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firmware")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	pubKey, secKey := cipher.GenerateKeyPair()
+	r170, _ := newTestRelease(t, dir, "1.7.0")
+	r180, image180 := newTestRelease(t, dir, "1.8.0")
+	r190, _ := newTestRelease(t, dir, "1.9.0")
+	r190.SHA256 = r170.SHA256
+	channel := newTestChannel(t, dir, pubKey, secKey, r170, r180, r190)
+	cache := NewCache(filepath.Join(dir, "cache"), pubKey)
+
+	entries, err := cache.List()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, _, err = Image(nil, cache, Version{1, 8, 0})
+	require.Equal(t, ErrNoChannel, err)
+
+	_, _, err = Image(channel, cache, Version{2, 0, 0})
+	require.Equal(t, ErrReleaseNotFound, err)
+
+	_, _, err = Image(channel, cache, Version{1, 9, 0})
+	require.Equal(t, ErrImageHash, err)
+
+	data, entry, err := Image(channel, cache, Version{1, 8, 0})
+	require.NoError(t, err)
+	require.Equal(t, image180, data)
+	require.Equal(t, "1.8.0", entry.Version)
+	require.Equal(t, r180.SHA256, entry.SHA256)
+	require.Equal(t, len(image180), entry.Size)
+	require.Equal(t, "stable", entry.Channel)
+	require.NotEmpty(t, entry.Signature)
+
+	_, _, err = Image(channel, cache, Version{1, 7, 0})
+	require.NoError(t, err)
+
+	// the cached images are served without the channel and their download
+	require.NoError(t, os.Remove(r180.URL))
+	data, cached, err := Image(nil, cache, Version{1, 8, 0})
+	require.NoError(t, err)
+	require.Equal(t, image180, data)
+	require.Equal(t, entry, cached)
+
+	entries, err = cache.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "1.7.0", entries[0].Version)
+	require.Equal(t, "1.8.0", entries[1].Version)
+
+	// an image altered in the cache is refused
+	path := filepath.Join(cache.Dir(), r170.SHA256+imageExt)
+	require.NoError(t, ioutil.WriteFile(path, []byte("altered"), 0600))
+	_, _, err = cache.Get(Version{1, 7, 0})
+	require.Equal(t, ErrCacheCorrupted, err)
+
+	// so is an image of a manifest not signed by the key
+	otherPubKey, _ := cipher.GenerateKeyPair()
+	_, _, err = NewCache(cache.Dir(), otherPubKey).Get(Version{1, 8, 0})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid firmware manifest signature")
+
+	purged, err := cache.Purge(&Version{1, 7, 0})
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+	require.Equal(t, "1.7.0", purged[0].Version)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	_, err = cache.Purge(&Version{1, 7, 0})
+	require.Equal(t, ErrNotCached, err)
+
+	purged, err = cache.Purge(nil)
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+
+	entries, err = cache.List()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	_, _, err = cache.Get(Version{1, 8, 0})
+	require.Equal(t, ErrNotCached, err)
+
+	files, err := ioutil.ReadDir(cache.Dir())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, cacheManifestFilename, files[0].Name())
+}
@@ -0,0 +1,178 @@
+package firmware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	fetchTimeout    = 30 * time.Second
+	maxManifestSize = 1024 * 1024 // 1 MB
+	// MaxImageSize is the size of the largest firmware image
+	MaxImageSize = 1024 * 1024 // 1 MB
+)
+
+var (
+	// ErrReleaseNotFound is returned when the manifest has no release of the version requested
+	ErrReleaseNotFound = errors.New("firmware release not found in the manifest")
+	// ErrImageHash is returned when a downloaded firmware image does not match the hash of its release
+	ErrImageHash = errors.New("firmware image does not match the hash of its release")
+	// ErrNoChannel is returned when a firmware image is neither cached nor downloadable without release channel
+	ErrNoChannel = errors.New("firmware release is not cached and the firmware release channel is not configured")
+)
+
+// ChannelConfig configures a release channel
+type ChannelConfig struct {
+	// Source is the URL or the path of the signed manifest
+	Source string
+	// PubKey verifies the manifest signature
+	PubKey cipher.PubKey
+	// PinnedHash is the hex encoded SHA256 hash the manifest must match
+	PinnedHash string
+	// Transport fetches the manifest of an http(s) source, nil means http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Channel fetches the manifest of a release channel
+type Channel struct {
+	config ChannelConfig
+	client *http.Client
+}
+
+// NewChannel creates a Channel. The manifest must be signed by the configured public key
+// or pinned to a manifest hash, or both.
+func NewChannel(c ChannelConfig) (*Channel, error) {
+	if c.Source == "" {
+		return nil, errors.New("firmware manifest source is empty")
+	}
+
+	if c.PubKey.Null() && c.PinnedHash == "" {
+		return nil, errors.New("firmware manifest must be verified by a public key or pinned to a manifest hash")
+	}
+
+	if c.PinnedHash != "" {
+		if _, err := cipher.SHA256FromHex(c.PinnedHash); err != nil {
+			return nil, fmt.Errorf("invalid firmware manifest hash: %v", err)
+		}
+	}
+
+	return &Channel{
+		config: c,
+		client: &http.Client{
+			Timeout:   fetchTimeout,
+			Transport: c.Transport,
+		},
+	}, nil
+}
+
+// Manifest fetches and verifies the manifest, returning it with its hash
+func (c *Channel) Manifest() (*Manifest, cipher.SHA256, error) {
+	m, hash, _, err := c.signedManifest()
+	return m, hash, err
+}
+
+// signedManifest fetches and verifies the manifest, returning it with its hash and the signed manifest document
+func (c *Channel) signedManifest() (*Manifest, cipher.SHA256, []byte, error) {
+	data, err := c.fetch(c.config.Source, "firmware manifest", maxManifestSize)
+	if err != nil {
+		return nil, cipher.SHA256{}, nil, err
+	}
+
+	m, hash, err := ParseSignedManifest(data, c.config.PubKey, c.config.PinnedHash)
+	if err != nil {
+		return nil, cipher.SHA256{}, nil, err
+	}
+	return m, hash, data, nil
+}
+
+// download downloads the firmware image of release and checks it against the hash of the release
+func (c *Channel) download(release Release) ([]byte, error) {
+	data, err := c.fetch(release.URL, "firmware image", MaxImageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(cipher.SumSHA256(data).Hex(), release.SHA256) {
+		return nil, ErrImageHash
+	}
+	return data, nil
+}
+
+// fetch reads the file of source, a URL or a path, of at most maxSize bytes
+func (c *Channel) fetch(source, name string, maxSize int64) ([]byte, error) {
+	var r io.Reader
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		resp, err := c.client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s failed: %s", name, resp.Status)
+		}
+		r = resp.Body
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+
+	return data, nil
+}
+
+// Image returns the firmware image of the release version and its cache entry. The image is read from cache if it
+// holds it, otherwise the release is looked up in the manifest of channel, downloaded, checked against its hash and
+// added to cache. channel can be nil to only read the cached images, ErrNoChannel is then returned for the others.
+func Image(channel *Channel, cache *Cache, version Version) ([]byte, CacheEntry, error) {
+	data, entry, err := cache.Get(version)
+	switch {
+	case err == nil:
+		return data, entry, nil
+	case err != ErrNotCached:
+		return nil, CacheEntry{}, err
+	case channel == nil:
+		return nil, CacheEntry{}, ErrNoChannel
+	}
+
+	manifest, _, document, err := channel.signedManifest()
+	if err != nil {
+		return nil, CacheEntry{}, err
+	}
+
+	release, err := manifest.Release(version)
+	if err != nil {
+		return nil, CacheEntry{}, err
+	}
+
+	data, err = channel.download(release)
+	if err != nil {
+		return nil, CacheEntry{}, err
+	}
+
+	entry, err = cache.put(release, data, document, time.Now())
+	if err != nil {
+		return nil, CacheEntry{}, err
+	}
+	return data, entry, nil
+}
@@ -0,0 +1,180 @@
+// Package firmware implements the firmware release channel.
+// Releases are published in a signed manifest, operators can pin the channel
+// to a specific manifest hash so devices only see the releases they approved.
+package firmware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+var (
+	// ErrManifestNotPinned is returned when the manifest does not match the pinned manifest hash
+	ErrManifestNotPinned = errors.New("firmware manifest does not match the pinned manifest hash")
+	// ErrManifestUnsigned is returned when the manifest has no signature
+	ErrManifestUnsigned = errors.New("firmware manifest is not signed")
+	// ErrNoRelease is returned when the manifest has no release
+	ErrNoRelease = errors.New("firmware manifest has no release")
+)
+
+// Version is a firmware version
+type Version struct {
+	Major uint32
+	Minor uint32
+	Patch uint32
+}
+
+// ParseVersion parses a version in the major.minor.patch format, with an optional v prefix
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid firmware version %q", s)
+	}
+
+	var n [3]uint32
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid firmware version %q", s)
+		}
+		n[i] = uint32(v)
+	}
+
+	return Version{
+		Major: n[0],
+		Minor: n[1],
+		Patch: n[2],
+	}, nil
+}
+
+// Compare returns -1, 0 or 1 if v is respectively older, equal or newer than o
+func (v Version) Compare(o Version) int {
+	a := [3]uint32{v.Major, v.Minor, v.Patch}
+	b := [3]uint32{o.Major, o.Minor, o.Patch}
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Release is a firmware release published in the manifest
+type Release struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	// SHA256 is the hex encoded hash of the firmware file
+	SHA256 string `json:"sha256"`
+	Notes  string `json:"notes,omitempty"`
+	// ReleasedAt is when the release was published, used by staged rollouts
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// Manifest lists the firmware releases of a channel
+type Manifest struct {
+	Channel  string    `json:"channel"`
+	Releases []Release `json:"releases"`
+}
+
+// Latest returns the newest release of the manifest
+func (m *Manifest) Latest() (Release, Version, error) {
+	var latest Release
+	var latestVersion Version
+	found := false
+
+	for _, r := range m.Releases {
+		v, err := ParseVersion(r.Version)
+		if err != nil {
+			return Release{}, Version{}, err
+		}
+
+		if !found || v.Compare(latestVersion) > 0 {
+			latest = r
+			latestVersion = v
+			found = true
+		}
+	}
+
+	if !found {
+		return Release{}, Version{}, ErrNoRelease
+	}
+
+	return latest, latestVersion, nil
+}
+
+// Release returns the release of version, ErrReleaseNotFound if the manifest has none
+func (m *Manifest) Release(version Version) (Release, error) {
+	for _, r := range m.Releases {
+		v, err := ParseVersion(r.Version)
+		if err != nil {
+			return Release{}, err
+		}
+
+		if v.Compare(version) == 0 {
+			return r, nil
+		}
+	}
+
+	return Release{}, ErrReleaseNotFound
+}
+
+// signedManifest is the document published on the release channel.
+// Signature is the hex encoded signature of the SHA256 hash of the manifest bytes, as they appear in the document.
+type signedManifest struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature string          `json:"signature"`
+}
+
+// ParseSignedManifest parses a signed manifest document, returning the manifest and its hash.
+// The signature is verified if pubKey is not null and the hash is checked if pinnedHash is not empty.
+func ParseSignedManifest(data []byte, pubKey cipher.PubKey, pinnedHash string) (*Manifest, cipher.SHA256, error) {
+	var sm signedManifest
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return nil, cipher.SHA256{}, fmt.Errorf("invalid firmware manifest: %v", err)
+	}
+
+	if len(sm.Manifest) == 0 {
+		return nil, cipher.SHA256{}, errors.New("invalid firmware manifest: manifest is missing")
+	}
+
+	hash := cipher.SumSHA256(sm.Manifest)
+
+	if pinnedHash != "" && !strings.EqualFold(hash.Hex(), pinnedHash) {
+		return nil, cipher.SHA256{}, ErrManifestNotPinned
+	}
+
+	if !pubKey.Null() {
+		if sm.Signature == "" {
+			return nil, cipher.SHA256{}, ErrManifestUnsigned
+		}
+
+		sig, err := cipher.SigFromHex(sm.Signature)
+		if err != nil {
+			return nil, cipher.SHA256{}, fmt.Errorf("invalid firmware manifest signature: %v", err)
+		}
+
+		if err := cipher.VerifyPubKeySignedHash(pubKey, sig, hash); err != nil {
+			return nil, cipher.SHA256{}, fmt.Errorf("invalid firmware manifest signature: %v", err)
+		}
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(sm.Manifest, &m); err != nil {
+		return nil, cipher.SHA256{}, fmt.Errorf("invalid firmware manifest: %v", err)
+	}
+
+	return &m, hash, nil
+}
@@ -0,0 +1,211 @@
+package firmware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+var testManifest = []byte(`{"channel":"stable","releases":[` +
+	`{"version":"1.7.0","url":"https://example.com/skyfirmware-1.7.0.bin","sha256":"aa"},` +
+	`{"version":"1.10.0","url":"https://example.com/skyfirmware-1.10.0.bin","sha256":"cc"},` +
+	`{"version":"1.8.1","url":"https://example.com/skyfirmware-1.8.1.bin","sha256":"bb"}]}`)
+
+func signManifest(t *testing.T, manifest []byte, secKey cipher.SecKey) []byte {
+	sig, err := cipher.SignHash(cipher.SumSHA256(manifest), secKey)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(signedManifest{
+		Manifest:  manifest,
+		Signature: sig.Hex(),
+	})
+	require.NoError(t, err)
+
+	return data
+}
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		version string
+		err     bool
+		expect  Version
+	}{
+		{version: "1.7.0", expect: Version{1, 7, 0}},
+		{version: "v2.10.3", expect: Version{2, 10, 3}},
+		{version: "1.7", err: true},
+		{version: "1.7.x", err: true},
+		{version: "", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.version, func(t *testing.T) {
+			v, err := ParseVersion(tc.version)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, v)
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	require.Equal(t, 0, Version{1, 7, 0}.Compare(Version{1, 7, 0}))
+	require.Equal(t, -1, Version{1, 7, 0}.Compare(Version{1, 7, 1}))
+	require.Equal(t, -1, Version{1, 9, 9}.Compare(Version{1, 10, 0}))
+	require.Equal(t, 1, Version{2, 0, 0}.Compare(Version{1, 10, 0}))
+}
+
+func TestManifestLatest(t *testing.T) {
+	var m Manifest
+	require.NoError(t, json.Unmarshal(testManifest, &m))
+
+	r, v, err := m.Latest()
+	require.NoError(t, err)
+	require.Equal(t, Version{1, 10, 0}, v)
+	require.Equal(t, "cc", r.SHA256)
+
+	_, _, err = (&Manifest{}).Latest()
+	require.Equal(t, ErrNoRelease, err)
+}
+
+func TestParseSignedManifest(t *testing.T) {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	otherPubKey, _ := cipher.GenerateKeyPair()
+
+	data := signManifest(t, testManifest, secKey)
+	hash := cipher.SumSHA256(testManifest)
+
+	unsigned, err := json.Marshal(signedManifest{
+		Manifest: testManifest,
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		data       []byte
+		pubKey     cipher.PubKey
+		pinnedHash string
+		err        string
+	}{
+		{
+			name:   "signed",
+			data:   data,
+			pubKey: pubKey,
+		},
+		{
+			name:       "pinned",
+			data:       unsigned,
+			pinnedHash: hash.Hex(),
+		},
+		{
+			name:       "signed and pinned",
+			data:       data,
+			pubKey:     pubKey,
+			pinnedHash: hash.Hex(),
+		},
+		{
+			name:       "not pinned",
+			data:       data,
+			pubKey:     pubKey,
+			pinnedHash: cipher.SumSHA256([]byte("other")).Hex(),
+			err:        ErrManifestNotPinned.Error(),
+		},
+		{
+			name:   "unsigned",
+			data:   unsigned,
+			pubKey: pubKey,
+			err:    ErrManifestUnsigned.Error(),
+		},
+		{
+			name:   "wrong key",
+			data:   data,
+			pubKey: otherPubKey,
+			err:    "invalid firmware manifest signature",
+		},
+		{
+			name:   "invalid document",
+			data:   []byte("foo"),
+			pubKey: pubKey,
+			err:    "invalid firmware manifest",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, h, err := ParseSignedManifest(tc.data, tc.pubKey, tc.pinnedHash)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, hash, h)
+			require.Equal(t, "stable", m.Channel)
+			require.Len(t, m.Releases, 3)
+		})
+	}
+}
+
+func TestChannel(t *testing.T) {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	data := signManifest(t, testManifest, secKey)
+
+	_, err := NewChannel(ChannelConfig{
+		Source: "manifest.json",
+	})
+	require.Error(t, err)
+
+	_, err = NewChannel(ChannelConfig{
+		Source:     "manifest.json",
+		PinnedHash: "foo",
+	})
+	require.Error(t, err)
+
+	dir, err := ioutil.TempDir("", "firmware")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.json")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manifest.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	for _, source := range []string{path, server.URL + "/manifest.json"} {
+		c, err := NewChannel(ChannelConfig{
+			Source: source,
+			PubKey: pubKey,
+		})
+		require.NoError(t, err)
+
+		m, hash, err := c.Manifest()
+		require.NoError(t, err)
+		require.Equal(t, cipher.SumSHA256(testManifest), hash)
+		require.Equal(t, "stable", m.Channel)
+	}
+
+	c, err := NewChannel(ChannelConfig{
+		Source: server.URL + "/missing.json",
+		PubKey: pubKey,
+	})
+	require.NoError(t, err)
+
+	_, _, err = c.Manifest()
+	require.EqualError(t, err, "fetching firmware manifest failed: 404 Not Found")
+}
@@ -0,0 +1,173 @@
+package firmware
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Duration is a time.Duration encoded in JSON as a string such as "72h"
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// RolloutGroup is the rollout policy of a group of devices.
+// A release is offered to the group Delay after it was released, to Percentage of its devices.
+// If Ramp is set, the percentage grows linearly from 0 to Percentage over Ramp after the delay.
+type RolloutGroup struct {
+	Name    string   `json:"name"`
+	Devices []string `json:"devices,omitempty"`
+	// Percentage of the devices of the group the release is offered to, defaults to 100
+	Percentage *float64 `json:"percentage,omitempty"`
+	Delay      Duration `json:"delay,omitempty"`
+	Ramp       Duration `json:"ramp,omitempty"`
+}
+
+// RolloutPolicy assigns devices to rollout groups, devices in no group use the default policy
+type RolloutPolicy struct {
+	Groups  []RolloutGroup `json:"groups"`
+	Default RolloutGroup   `json:"default"`
+
+	devices map[string]int
+}
+
+// Rollout is the rollout state of a release for a device
+type Rollout struct {
+	Group string `json:"group"`
+	// Percentage of the devices of the group the release is currently offered to
+	Percentage float64 `json:"percentage"`
+	// Held is true if the release is not offered to the device yet
+	Held bool `json:"held"`
+}
+
+// NewRolloutPolicy creates a RolloutPolicy
+func NewRolloutPolicy(groups []RolloutGroup, defaultGroup RolloutGroup) (*RolloutPolicy, error) {
+	p := &RolloutPolicy{
+		Groups:  groups,
+		Default: defaultGroup,
+	}
+
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// LoadRolloutPolicy loads a rollout policy from a JSON file
+func LoadRolloutPolicy(path string) (*RolloutPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p RolloutPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid firmware rollout policy %s: %v", path, err)
+	}
+
+	if err := p.init(); err != nil {
+		return nil, fmt.Errorf("invalid firmware rollout policy %s: %v", path, err)
+	}
+
+	return &p, nil
+}
+
+func (p *RolloutPolicy) init() error {
+	if p.Default.Name == "" {
+		p.Default.Name = "default"
+	}
+
+	p.devices = make(map[string]int)
+	for i, g := range append(p.Groups, p.Default) {
+		if g.Name == "" {
+			return errors.New("rollout group name is empty")
+		}
+
+		if g.Percentage != nil && (*g.Percentage < 0 || *g.Percentage > 100) {
+			return fmt.Errorf("rollout group %s percentage must be between 0 and 100", g.Name)
+		}
+
+		if g.Delay < 0 || g.Ramp < 0 {
+			return fmt.Errorf("rollout group %s delay and ramp must not be negative", g.Name)
+		}
+
+		if i == len(p.Groups) {
+			break
+		}
+
+		for _, d := range g.Devices {
+			if j, ok := p.devices[d]; ok {
+				return fmt.Errorf("device %s is in rollout groups %s and %s", d, p.Groups[j].Name, g.Name)
+			}
+			p.devices[d] = i
+		}
+	}
+
+	return nil
+}
+
+// Group returns the rollout group of the device
+func (p *RolloutPolicy) Group(deviceID string) RolloutGroup {
+	if i, ok := p.devices[deviceID]; ok {
+		return p.Groups[i]
+	}
+	return p.Default
+}
+
+// Rollout returns the rollout state of the release for the device at time now.
+// Releases without release date are not held by the delay and ramp.
+func (p *RolloutPolicy) Rollout(deviceID string, r Release, now time.Time) Rollout {
+	g := p.Group(deviceID)
+
+	percentage := 100.0
+	if g.Percentage != nil {
+		percentage = *g.Percentage
+	}
+
+	if r.ReleasedAt != nil {
+		elapsed := now.Sub(*r.ReleasedAt) - time.Duration(g.Delay)
+		switch {
+		case elapsed < 0:
+			percentage = 0
+		case g.Ramp > 0 && elapsed < time.Duration(g.Ramp):
+			percentage *= float64(elapsed) / float64(g.Ramp)
+		}
+	}
+
+	return Rollout{
+		Group:      g.Name,
+		Percentage: percentage,
+		Held:       rolloutBucket(deviceID, r.Version) >= percentage,
+	}
+}
+
+// rolloutBucket deterministically places a device in [0, 100) for a release,
+// so a device keeps the release once it was offered while the percentage grows
+func rolloutBucket(deviceID, version string) float64 {
+	h := sha256.Sum256([]byte(deviceID + ":" + version))
+	return float64(binary.BigEndian.Uint64(h[:8])%10000) / 100
+}
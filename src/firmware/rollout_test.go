@@ -0,0 +1,143 @@
+package firmware
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newFloat64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestLoadRolloutPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name   string
+		policy string
+		err    string
+		expect *RolloutPolicy
+	}{
+		{
+			name: "valid",
+			policy: `{
+				"groups": [{"name": "canary", "devices": ["dev1", "dev2"]}],
+				"default": {"percentage": 10, "delay": "24h", "ramp": "72h"}
+			}`,
+			expect: &RolloutPolicy{
+				Groups: []RolloutGroup{
+					{Name: "canary", Devices: []string{"dev1", "dev2"}},
+				},
+				Default: RolloutGroup{
+					Name:       "default",
+					Percentage: newFloat64Ptr(10),
+					Delay:      Duration(24 * time.Hour),
+					Ramp:       Duration(72 * time.Hour),
+				},
+				devices: map[string]int{"dev1": 0, "dev2": 0},
+			},
+		},
+		{
+			name:   "invalid duration",
+			policy: `{"default": {"delay": "1 day"}}`,
+			err:    "invalid firmware rollout policy",
+		},
+		{
+			name:   "invalid percentage",
+			policy: `{"default": {"percentage": 110}}`,
+			err:    "rollout group default percentage must be between 0 and 100",
+		},
+		{
+			name:   "missing group name",
+			policy: `{"groups": [{"devices": ["dev1"]}]}`,
+			err:    "rollout group name is empty",
+		},
+		{
+			name:   "device in two groups",
+			policy: `{"groups": [{"name": "a", "devices": ["dev1"]}, {"name": "b", "devices": ["dev1"]}]}`,
+			err:    "device dev1 is in rollout groups a and b",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("rollout%d.json", i))
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.policy), 0600))
+
+			p, err := LoadRolloutPolicy(path)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, p)
+		})
+	}
+}
+
+func TestRollout(t *testing.T) {
+	releasedAt := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	release := Release{
+		Version:    "1.8.0",
+		ReleasedAt: &releasedAt,
+	}
+
+	p, err := NewRolloutPolicy([]RolloutGroup{
+		{Name: "canary", Devices: []string{"canary-dev"}},
+		{Name: "frozen", Devices: []string{"frozen-dev"}, Percentage: newFloat64Ptr(0)},
+	}, RolloutGroup{
+		Percentage: newFloat64Ptr(50),
+		Delay:      Duration(24 * time.Hour),
+		Ramp:       Duration(100 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	// canary devices get the release immediately
+	r := p.Rollout("canary-dev", release, releasedAt)
+	require.Equal(t, Rollout{Group: "canary", Percentage: 100}, r)
+
+	r = p.Rollout("frozen-dev", release, releasedAt.Add(1000*time.Hour))
+	require.Equal(t, Rollout{Group: "frozen", Percentage: 0, Held: true}, r)
+
+	// other devices wait for the delay
+	r = p.Rollout("dev", release, releasedAt.Add(23*time.Hour))
+	require.Equal(t, Rollout{Group: "default", Percentage: 0, Held: true}, r)
+
+	// then the percentage ramps up
+	r = p.Rollout("dev", release, releasedAt.Add(74*time.Hour))
+	require.Equal(t, "default", r.Group)
+	require.InDelta(t, 25, r.Percentage, 0.001)
+
+	r = p.Rollout("dev", release, releasedAt.Add(200*time.Hour))
+	require.Equal(t, 50.0, r.Percentage)
+
+	// without release date only the percentage applies
+	r = p.Rollout("dev", Release{Version: "1.8.0"}, releasedAt)
+	require.Equal(t, 50.0, r.Percentage)
+
+	// about half of the devices get the release once fully rolled out
+	// and a device keeps the release while the percentage grows
+	offered := 0
+	for i := 0; i < 1000; i++ {
+		dev := fmt.Sprintf("dev%d", i)
+		early := p.Rollout(dev, release, releasedAt.Add(74*time.Hour))
+		late := p.Rollout(dev, release, releasedAt.Add(200*time.Hour))
+		if !early.Held {
+			require.False(t, late.Held)
+		}
+		if !late.Held {
+			offered++
+		}
+	}
+	require.InDelta(t, 500, offered, 60)
+}
@@ -0,0 +1,25 @@
+// Package header reads the header of the messages the drivers write to the device. The first chunk of a message
+// starts with the "?##" marker, followed by the message type and the payload size in big endian.
+package header
+
+import (
+	"encoding/binary"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// Kind reads the message type from the header of the first chunk, 0 if there is no chunk
+func Kind(chunks [][64]byte) messages.MessageType {
+	if len(chunks) == 0 {
+		return 0
+	}
+	return messages.MessageType(binary.BigEndian.Uint16(chunks[0][3:5]))
+}
+
+// Size reads the payload size from the header of the first chunk, 0 if there is no chunk
+func Size(chunks [][64]byte) int {
+	if len(chunks) == 0 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(chunks[0][5:9]))
+}
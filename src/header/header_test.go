@@ -0,0 +1,19 @@
+package header
+
+import (
+	"testing"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeader(t *testing.T) {
+	chunks, err := skyWallet.MessageSignMessage(0, "Hello World")
+	require.NoError(t, err)
+	require.Equal(t, messages.MessageType_MessageType_SkycoinSignMessage, Kind(chunks))
+	require.Equal(t, 15, Size(chunks))
+
+	require.Equal(t, messages.MessageType(0), Kind(nil))
+	require.Equal(t, 0, Size(nil))
+}
@@ -0,0 +1,181 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// ExportKey is the daemon key signing the history archives
+type ExportKey struct {
+	PubKey cipher.PubKey
+	SecKey cipher.SecKey
+}
+
+// LoadExportKey loads the hex encoded secret key of the file at path, generating it if the file does not exist
+func LoadExportKey(path string) (*ExportKey, error) {
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		pubKey, secKey := cipher.GenerateKeyPair()
+		if err := ioutil.WriteFile(path, []byte(secKey.Hex()+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write history export key: %v", err)
+		}
+		return &ExportKey{
+			PubKey: pubKey,
+			SecKey: secKey,
+		}, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read history export key: %v", err)
+	}
+
+	secKey, err := cipher.SecKeyFromHex(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid history export key %s: %v", path, err)
+	}
+
+	pubKey, err := cipher.PubKeyFromSecKey(secKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history export key %s: %v", path, err)
+	}
+
+	return &ExportKey{
+		PubKey: pubKey,
+		SecKey: secKey,
+	}, nil
+}
+
+// Archive is a tamper evident export of the records of a bucket
+type Archive struct {
+	// Bucket is the exported bucket, history or audit
+	Bucket     string    `json:"bucket"`
+	ExportedAt time.Time `json:"exported_at"`
+	// PubKey is the hex encoded public key of the daemon signing the archive
+	PubKey  string          `json:"pubkey"`
+	Records []ArchiveRecord `json:"records"`
+	// Head is the hash of the last record, an empty archive has a zero head
+	Head string `json:"head"`
+}
+
+// ArchiveRecord is an archived record chained to the previous one
+type ArchiveRecord struct {
+	Record json.RawMessage `json:"record"`
+	// Hash is the hex encoded SHA256 of the hash of the previous record followed by the record bytes.
+	// The first record is chained to a zero hash.
+	Hash string `json:"hash"`
+}
+
+// signedArchive is the exported document.
+// Signature is the hex encoded signature of the SHA256 hash of the archive bytes, as they appear in the document.
+type signedArchive struct {
+	Archive   json.RawMessage `json:"archive"`
+	Signature string          `json:"signature"`
+}
+
+// chainHash returns the hash of a record chained to prev
+func chainHash(prev cipher.SHA256, record []byte) cipher.SHA256 {
+	return cipher.SumSHA256(append(prev[:], record...))
+}
+
+// Export returns the records of bucket selected by filter as an archive signed by key
+func (r *Recorder) Export(bucket string, filter Filter, key *ExportKey) ([]byte, error) {
+	records, err := r.Records(bucket, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := Archive{
+		Bucket:     bucket,
+		ExportedAt: r.now().UTC(),
+		PubKey:     key.PubKey.Hex(),
+		Records:    make([]ArchiveRecord, 0, len(records)),
+	}
+
+	var head cipher.SHA256
+	for _, rec := range records {
+		value, err := json.Marshal(rec)
+		if err != nil {
+			return nil, err
+		}
+
+		head = chainHash(head, value)
+		archive.Records = append(archive.Records, ArchiveRecord{
+			Record: value,
+			Hash:   head.Hex(),
+		})
+	}
+	archive.Head = head.Hex()
+
+	archiveBytes, err := json.Marshal(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := cipher.SignHash(cipher.SumSHA256(archiveBytes), key.SecKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(signedArchive{
+		Archive:   archiveBytes,
+		Signature: sig.Hex(),
+	})
+}
+
+// VerifyArchive checks the signature and the hash chain of an exported archive and returns it.
+// The signature is checked against pubKey if it is not null, otherwise against the public key of the archive,
+// which then only proves that the archive was not modified after its export.
+func VerifyArchive(data []byte, pubKey cipher.PubKey) (*Archive, error) {
+	var sa signedArchive
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("invalid history archive: %v", err)
+	}
+
+	if len(sa.Archive) == 0 {
+		return nil, errors.New("invalid history archive: archive is missing")
+	}
+
+	var archive Archive
+	if err := json.Unmarshal(sa.Archive, &archive); err != nil {
+		return nil, fmt.Errorf("invalid history archive: %v", err)
+	}
+
+	if pubKey.Null() {
+		var err error
+		pubKey, err = cipher.PubKeyFromHex(archive.PubKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history archive public key: %v", err)
+		}
+	} else if archive.PubKey != pubKey.Hex() {
+		return nil, errors.New("history archive was not signed by this key")
+	}
+
+	sig, err := cipher.SigFromHex(sa.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history archive signature: %v", err)
+	}
+
+	if err := cipher.VerifyPubKeySignedHash(pubKey, sig, cipher.SumSHA256(sa.Archive)); err != nil {
+		return nil, fmt.Errorf("invalid history archive signature: %v", err)
+	}
+
+	var head cipher.SHA256
+	for i, rec := range archive.Records {
+		head = chainHash(head, rec.Record)
+		if rec.Hash != head.Hex() {
+			return nil, fmt.Errorf("history archive hash chain broken at record %d", i)
+		}
+	}
+
+	if archive.Head != head.Hex() {
+		return nil, errors.New("history archive head does not match its records")
+	}
+
+	return &archive, nil
+}
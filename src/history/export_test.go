@@ -0,0 +1,132 @@
+package history
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func newTestExportKey() *ExportKey {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	return &ExportKey{
+		PubKey: pubKey,
+		SecKey: secKey,
+	}
+}
+
+func TestLoadExportKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export.key")
+
+	key, err := LoadExportKey(path)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadExportKey(path)
+	require.NoError(t, err)
+	require.Equal(t, key, loaded)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("foo"), 0600))
+	_, err = LoadExportKey(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid history export key")
+}
+
+func TestExport(t *testing.T) {
+	r, _ := newTestRecorder()
+	recordN(t, r, 3, true)
+	key := newTestExportKey()
+
+	data, err := r.Export(storage.AuditBucket, Filter{Since: 1}, key)
+	require.NoError(t, err)
+
+	archive, err := VerifyArchive(data, key.PubKey)
+	require.NoError(t, err)
+	require.Equal(t, storage.AuditBucket, archive.Bucket)
+	require.Equal(t, r.now(), archive.ExportedAt)
+	require.Equal(t, key.PubKey.Hex(), archive.PubKey)
+	require.Len(t, archive.Records, 2)
+	require.Equal(t, archive.Records[1].Hash, archive.Head)
+
+	var rec Record
+	require.NoError(t, json.Unmarshal(archive.Records[0].Record, &rec))
+	require.Equal(t, uint64(2), rec.Seq)
+	require.Equal(t, "/features", rec.Endpoint)
+
+	// the archive public key is used if none is given
+	_, err = VerifyArchive(data, cipher.PubKey{})
+	require.NoError(t, err)
+
+	other := newTestExportKey()
+	_, err = VerifyArchive(data, other.PubKey)
+	require.EqualError(t, err, "history archive was not signed by this key")
+
+	// an empty archive
+	data, err = r.Export(storage.AuditBucket, Filter{Since: 3}, key)
+	require.NoError(t, err)
+	archive, err = VerifyArchive(data, key.PubKey)
+	require.NoError(t, err)
+	require.Empty(t, archive.Records)
+	require.Equal(t, cipher.SHA256{}.Hex(), archive.Head)
+}
+
+func TestVerifyArchiveTampered(t *testing.T) {
+	r, _ := newTestRecorder()
+	recordN(t, r, 3, false)
+	key := newTestExportKey()
+
+	data, err := r.Export(storage.HistoryBucket, Filter{}, key)
+	require.NoError(t, err)
+
+	// a modified record breaks the signature
+	tampered := strings.Replace(string(data), `"status":200`, `"status":500`, 1)
+	require.NotEqual(t, string(data), tampered)
+	_, err = VerifyArchive([]byte(tampered), key.PubKey)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid history archive signature")
+
+	// a record removed and the archive signed again breaks the hash chain
+	var sa signedArchive
+	require.NoError(t, json.Unmarshal(data, &sa))
+	var archive Archive
+	require.NoError(t, json.Unmarshal(sa.Archive, &archive))
+	archive.Records = append(archive.Records[:1], archive.Records[2:]...)
+	resign := func(archive Archive) []byte {
+		archiveBytes, err := json.Marshal(archive)
+		require.NoError(t, err)
+		sig, err := cipher.SignHash(cipher.SumSHA256(archiveBytes), key.SecKey)
+		require.NoError(t, err)
+		data, err := json.Marshal(signedArchive{
+			Archive:   archiveBytes,
+			Signature: sig.Hex(),
+		})
+		require.NoError(t, err)
+		return data
+	}
+	_, err = VerifyArchive(resign(archive), key.PubKey)
+	require.EqualError(t, err, "history archive hash chain broken at record 1")
+
+	// the last records removed do not match the head
+	var truncated Archive
+	require.NoError(t, json.Unmarshal(sa.Archive, &truncated))
+	truncated.Records = truncated.Records[:2]
+	_, err = VerifyArchive(resign(truncated), key.PubKey)
+	require.EqualError(t, err, "history archive head does not match its records")
+
+	_, err = VerifyArchive([]byte(`{"signature": ""}`), key.PubKey)
+	require.EqualError(t, err, "invalid history archive: archive is missing")
+}
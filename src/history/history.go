@@ -0,0 +1,200 @@
+// Package history records the operations served by the daemon in the history and audit buckets of the store.
+// Records carry the trace and correlation headers sent by clients, so they can be joined with client side logs.
+// Records are kept according to a retention policy, and can be purged on demand.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const (
+	// maxTraceValueLength is the maximum length of a recorded trace header value, longer values are truncated
+	maxTraceValueLength = 256
+)
+
+// DefaultTraceHeaders are the trace and correlation headers recorded by default:
+// the W3C trace context headers and the common request and correlation ID headers
+var DefaultTraceHeaders = []string{
+	"Traceparent",
+	"Tracestate",
+	"X-Request-Id",
+	"X-Correlation-Id",
+}
+
+var (
+	logger = logging.MustGetLogger("history")
+
+	errStopIteration = errors.New("stop iteration")
+)
+
+// Record is an operation history or audit record
+type Record struct {
+	// Seq is the sequence number of the record in its bucket
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+	Method   string    `json:"method"`
+	Status   int       `json:"status"`
+	// Duration is the duration of the operation in milliseconds
+	Duration int64 `json:"duration_ms"`
+	// RequestID is the ID the daemon assigned to the request, or the one sent by the client
+	RequestID string `json:"request_id,omitempty"`
+	// Trace maps the lowercase names of the trace headers sent by the client to their values
+	Trace map[string]string `json:"trace,omitempty"`
+	// DeletedAt is when the record was purged, purged records are removed after the purge grace period
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Filter selects records. Empty fields match all records.
+type Filter struct {
+	// Since selects the records with a sequence number greater than Since
+	Since uint64
+	// Trace selects the records with a trace header of this value
+	Trace string
+	// Limit is the maximum number of records returned, the oldest first
+	Limit int
+	// Deleted selects the purged records as well
+	Deleted bool
+}
+
+// Match returns true if the record is selected by the filter, ignoring Limit
+func (f Filter) Match(rec Record) bool {
+	if rec.Seq <= f.Since {
+		return false
+	}
+
+	if rec.DeletedAt != nil && !f.Deleted {
+		return false
+	}
+
+	if f.Trace == "" {
+		return true
+	}
+
+	for _, v := range rec.Trace {
+		if v == f.Trace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Recorder appends operation records to the history and audit buckets of a store
+type Recorder struct {
+	store        storage.Store
+	traceHeaders []string
+
+	// mu serializes the purges and the pruning, which rewrite and remove records
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewRecorder creates a Recorder recording the traceHeaders of requests in store
+func NewRecorder(store storage.Store, traceHeaders []string) *Recorder {
+	headers := make([]string, 0, len(traceHeaders))
+	for _, h := range traceHeaders {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+
+	return &Recorder{
+		store:        store,
+		traceHeaders: headers,
+		now:          time.Now,
+	}
+}
+
+// TraceHeaders returns the canonical names of the recorded trace headers
+func (r *Recorder) TraceHeaders() []string {
+	return r.traceHeaders
+}
+
+// Trace returns the recorded trace headers of h, nil if there is none.
+// Values with control characters are dropped and long values are truncated, so clients cannot forge records.
+func (r *Recorder) Trace(h http.Header) map[string]string {
+	var trace map[string]string
+	for _, name := range r.traceHeaders {
+		v := strings.TrimSpace(h.Get(name))
+		if v == "" || strings.IndexFunc(v, isControl) != -1 {
+			continue
+		}
+
+		if len(v) > maxTraceValueLength {
+			v = v[:maxTraceValueLength]
+		}
+
+		if trace == nil {
+			trace = make(map[string]string)
+		}
+		trace[strings.ToLower(name)] = v
+	}
+
+	return trace
+}
+
+// Record appends rec to the history bucket, and to the audit bucket if audit is set
+func (r *Recorder) Record(rec Record, audit bool) error {
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.store.Append(storage.HistoryBucket, value); err != nil {
+		return err
+	}
+
+	if audit {
+		if _, err := r.store.Append(storage.AuditBucket, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Records returns the records of bucket selected by filter, the oldest first
+func (r *Recorder) Records(bucket string, filter Filter) ([]Record, error) {
+	records := []Record{}
+	err := r.store.ForEach(bucket, func(k string, v []byte) error {
+		seq, err := storage.ParseSequenceKey(k)
+		if err != nil {
+			return err
+		}
+
+		var rec Record
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return err
+		}
+		rec.Seq = seq
+
+		if !filter.Match(rec) {
+			return nil
+		}
+
+		records = append(records, rec)
+		if filter.Limit > 0 && len(records) >= filter.Limit {
+			return errStopIteration
+		}
+		return nil
+	})
+	if err != nil && err != errStopIteration {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
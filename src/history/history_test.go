@@ -0,0 +1,157 @@
+package history
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestNewRecorder(t *testing.T) {
+	r := NewRecorder(storage.NewMemoryStore(), []string{"x-request-id", " traceparent ", ""})
+	require.Equal(t, []string{"X-Request-Id", "Traceparent"}, r.TraceHeaders())
+}
+
+func TestTrace(t *testing.T) {
+	r := NewRecorder(storage.NewMemoryStore(), DefaultTraceHeaders)
+
+	cases := []struct {
+		name   string
+		header http.Header
+		trace  map[string]string
+	}{
+		{
+			name:   "no trace headers",
+			header: http.Header{"Content-Type": []string{"application/json"}},
+		},
+		{
+			name: "trace context and request id",
+			header: http.Header{
+				"Traceparent":  []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+				"X-Request-Id": []string{" req-1 "},
+				"X-Other":      []string{"ignored"},
+			},
+			trace: map[string]string{
+				"traceparent":  "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+				"x-request-id": "req-1",
+			},
+		},
+		{
+			name: "control characters",
+			header: http.Header{
+				"X-Request-Id":     []string{"req-1\nforged"},
+				"X-Correlation-Id": []string{"corr-1"},
+			},
+			trace: map[string]string{
+				"x-correlation-id": "corr-1",
+			},
+		},
+		{
+			name: "long value",
+			header: http.Header{
+				"X-Request-Id": []string{strings.Repeat("a", 300)},
+			},
+			trace: map[string]string{
+				"x-request-id": strings.Repeat("a", maxTraceValueLength),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.trace, r.Trace(tc.header))
+		})
+	}
+}
+
+func TestRecordRecords(t *testing.T) {
+	store := storage.NewMemoryStore()
+	r := NewRecorder(store, DefaultTraceHeaders)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	records := []struct {
+		rec   Record
+		audit bool
+	}{
+		{
+			rec: Record{
+				Time:     now,
+				Endpoint: "/features",
+				Method:   http.MethodGet,
+				Status:   http.StatusOK,
+			},
+		},
+		{
+			rec: Record{
+				Time:     now,
+				Endpoint: "/sign_message",
+				Method:   http.MethodPost,
+				Status:   http.StatusOK,
+				Duration: 1200,
+				Trace:    map[string]string{"x-request-id": "req-1"},
+			},
+			audit: true,
+		},
+		{
+			rec: Record{
+				Time:     now,
+				Endpoint: "/intermediate/button",
+				Method:   http.MethodPost,
+				Status:   http.StatusOK,
+				Trace:    map[string]string{"x-request-id": "req-1"},
+			},
+		},
+		{
+			rec: Record{
+				Time:     now,
+				Endpoint: "/wipe",
+				Method:   http.MethodDelete,
+				Status:   http.StatusConflict,
+				Trace:    map[string]string{"x-correlation-id": "corr-2"},
+			},
+			audit: true,
+		},
+	}
+
+	for _, tc := range records {
+		require.NoError(t, r.Record(tc.rec, tc.audit))
+	}
+
+	all, err := r.Records(storage.HistoryBucket, Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	for i, rec := range all {
+		require.Equal(t, uint64(i+1), rec.Seq)
+		require.Equal(t, records[i].rec.Endpoint, rec.Endpoint)
+		require.Equal(t, records[i].rec.Trace, rec.Trace)
+		require.True(t, now.Equal(rec.Time))
+	}
+
+	audit, err := r.Records(storage.AuditBucket, Filter{})
+	require.NoError(t, err)
+	require.Len(t, audit, 2)
+	require.Equal(t, uint64(1), audit[0].Seq)
+	require.Equal(t, "/sign_message", audit[0].Endpoint)
+	require.Equal(t, int64(1200), audit[0].Duration)
+	require.Equal(t, "/wipe", audit[1].Endpoint)
+
+	traced, err := r.Records(storage.HistoryBucket, Filter{Trace: "req-1"})
+	require.NoError(t, err)
+	require.Len(t, traced, 2)
+	require.Equal(t, "/sign_message", traced[0].Endpoint)
+	require.Equal(t, "/intermediate/button", traced[1].Endpoint)
+
+	since, err := r.Records(storage.HistoryBucket, Filter{Since: 2, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, since, 1)
+	require.Equal(t, uint64(3), since[0].Seq)
+
+	none, err := r.Records(storage.AuditBucket, Filter{Trace: "unknown"})
+	require.NoError(t, err)
+	require.Empty(t, none)
+	require.NotNil(t, none)
+}
@@ -0,0 +1,208 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const (
+	// DefaultRetentionInterval is how often the retention policy is applied
+	DefaultRetentionInterval = time.Hour
+	// DefaultPurgeGrace is how long the purged records are kept before being removed
+	DefaultPurgeGrace = 24 * time.Hour
+)
+
+// Retention limits the records kept in a bucket. Zero fields are unlimited.
+type Retention struct {
+	// MaxAge removes the records older than MaxAge
+	MaxAge time.Duration
+	// MaxRecords removes the oldest records beyond MaxRecords
+	MaxRecords int
+}
+
+// Validate checks that the limits are not negative
+func (r Retention) Validate() error {
+	if r.MaxAge < 0 {
+		return errors.New("max age must not be negative")
+	}
+	if r.MaxRecords < 0 {
+		return errors.New("max records must not be negative")
+	}
+	return nil
+}
+
+// RetentionPolicy is the retention of the history and audit buckets
+type RetentionPolicy struct {
+	History Retention
+	Audit   Retention
+	// PurgeGrace is how long the purged records are kept, hidden, before being removed
+	PurgeGrace time.Duration
+}
+
+// DefaultRetentionPolicy returns the default policy: a month or 10000 records of history,
+// audit records are kept for good up to 100000 records, purged records are removed after a day
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		History: Retention{
+			MaxAge:     30 * 24 * time.Hour,
+			MaxRecords: 10000,
+		},
+		Audit: Retention{
+			MaxRecords: 100000,
+		},
+		PurgeGrace: DefaultPurgeGrace,
+	}
+}
+
+// Validate checks the retention of each bucket and the purge grace period
+func (p RetentionPolicy) Validate() error {
+	if err := p.History.Validate(); err != nil {
+		return errors.New("history " + err.Error())
+	}
+	if err := p.Audit.Validate(); err != nil {
+		return errors.New("audit " + err.Error())
+	}
+	if p.PurgeGrace < 0 {
+		return errors.New("purge grace must not be negative")
+	}
+	return nil
+}
+
+// Purge soft deletes the records of bucket with a sequence number up to through, 0 purges all records.
+// Purged records are hidden from Records unless requested, and removed by Prune once the purge grace period is over.
+// It returns the number of records purged.
+func (r *Recorder) Purge(bucket string, through uint64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now().UTC()
+	n := 0
+	err := r.store.ForEach(bucket, func(k string, v []byte) error {
+		seq, err := storage.ParseSequenceKey(k)
+		if err != nil {
+			return err
+		}
+
+		if through != 0 && seq > through {
+			return errStopIteration
+		}
+
+		var rec Record
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return err
+		}
+
+		if rec.DeletedAt != nil {
+			return nil
+		}
+		rec.DeletedAt = &now
+
+		value, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+
+		if err := r.store.Put(bucket, k, value); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil && err != errStopIteration {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// Prune applies policy: it removes the records past the retention of their bucket
+// and the purged records past the grace period. It returns the number of records removed.
+func (r *Recorder) Prune(policy RetentionPolicy) (int, error) {
+	buckets := []struct {
+		name      string
+		retention Retention
+	}{
+		{storage.HistoryBucket, policy.History},
+		{storage.AuditBucket, policy.Audit},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for _, b := range buckets {
+		n, err := r.prune(b.name, b.retention, policy.PurgeGrace)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// prune applies retention and the purge grace period to bucket, must be called with the lock held
+func (r *Recorder) prune(bucket string, retention Retention, grace time.Duration) (int, error) {
+	now := r.now()
+
+	// the keys of the records which are not removed by age, the oldest first
+	var kept []string
+	var expired []string
+	err := r.store.ForEach(bucket, func(k string, v []byte) error {
+		var rec Record
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return err
+		}
+
+		switch {
+		case rec.DeletedAt != nil:
+			if now.Sub(*rec.DeletedAt) >= grace {
+				expired = append(expired, k)
+			}
+		case retention.MaxAge > 0 && now.Sub(rec.Time) > retention.MaxAge:
+			expired = append(expired, k)
+		default:
+			kept = append(kept, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if retention.MaxRecords > 0 && len(kept) > retention.MaxRecords {
+		expired = append(expired, kept[:len(kept)-retention.MaxRecords]...)
+	}
+
+	for i, k := range expired {
+		if err := r.store.Delete(bucket, k); err != nil {
+			return i, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// ApplyRetention prunes the records of recorder according to policy at startup and then every interval,
+// until quit is closed
+func ApplyRetention(recorder *Recorder, policy RetentionPolicy, interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := recorder.Prune(policy); err != nil {
+			logger.WithError(err).Error("failed to apply the retention policy")
+		} else if n > 0 {
+			logger.Infof("Retention policy removed %d records", n)
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package history
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// newTestRecorder creates a Recorder with a clock advanced by the returned function
+func newTestRecorder() (*Recorder, func(time.Duration)) {
+	r := NewRecorder(storage.NewMemoryStore(), DefaultTraceHeaders)
+
+	now := time.Date(2019, 7, 26, 10, 0, 0, 0, time.UTC)
+	r.now = func() time.Time {
+		return now
+	}
+
+	return r, func(d time.Duration) {
+		now = now.Add(d)
+	}
+}
+
+func recordN(t *testing.T, r *Recorder, n int, audit bool) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, r.Record(Record{
+			Time:     r.now(),
+			Endpoint: "/features",
+			Method:   http.MethodGet,
+			Status:   http.StatusOK,
+		}, audit))
+	}
+}
+
+func seqs(t *testing.T, r *Recorder, bucket string, filter Filter) []uint64 {
+	records, err := r.Records(bucket, filter)
+	require.NoError(t, err)
+
+	s := []uint64{}
+	for _, rec := range records {
+		s = append(s, rec.Seq)
+	}
+	return s
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	require.NoError(t, DefaultRetentionPolicy().Validate())
+	require.NoError(t, RetentionPolicy{}.Validate())
+
+	policy := DefaultRetentionPolicy()
+	policy.History.MaxAge = -time.Second
+	require.EqualError(t, policy.Validate(), "history max age must not be negative")
+
+	policy = DefaultRetentionPolicy()
+	policy.Audit.MaxRecords = -1
+	require.EqualError(t, policy.Validate(), "audit max records must not be negative")
+
+	policy = DefaultRetentionPolicy()
+	policy.PurgeGrace = -time.Second
+	require.EqualError(t, policy.Validate(), "purge grace must not be negative")
+}
+
+func TestPurge(t *testing.T) {
+	r, advance := newTestRecorder()
+	recordN(t, r, 4, false)
+
+	n, err := r.Purge(storage.HistoryBucket, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	require.Equal(t, []uint64{3, 4}, seqs(t, r, storage.HistoryBucket, Filter{}))
+	require.Equal(t, []uint64{1, 2, 3, 4}, seqs(t, r, storage.HistoryBucket, Filter{Deleted: true}))
+
+	records, err := r.Records(storage.HistoryBucket, Filter{Deleted: true, Limit: 1})
+	require.NoError(t, err)
+	require.NotNil(t, records[0].DeletedAt)
+	require.Equal(t, r.now(), *records[0].DeletedAt)
+
+	// the records already purged keep their purge time
+	advance(time.Minute)
+	n, err = r.Purge(storage.HistoryBucket, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Empty(t, seqs(t, r, storage.HistoryBucket, Filter{}))
+
+	records, err = r.Records(storage.HistoryBucket, Filter{Deleted: true})
+	require.NoError(t, err)
+	require.Equal(t, r.now().Add(-time.Minute), *records[0].DeletedAt)
+	require.Equal(t, r.now(), *records[3].DeletedAt)
+
+	// purging an empty bucket
+	n, err = r.Purge(storage.AuditBucket, 0)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestPrune(t *testing.T) {
+	r, advance := newTestRecorder()
+	policy := RetentionPolicy{
+		History: Retention{
+			MaxAge:     time.Hour,
+			MaxRecords: 3,
+		},
+		Audit: Retention{
+			MaxRecords: 2,
+		},
+		PurgeGrace: 10 * time.Minute,
+	}
+
+	recordN(t, r, 2, true)
+	advance(30 * time.Minute)
+	recordN(t, r, 3, true)
+
+	// the oldest records beyond MaxRecords are removed
+	n, err := r.Prune(policy)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, []uint64{3, 4, 5}, seqs(t, r, storage.HistoryBucket, Filter{}))
+	require.Equal(t, []uint64{4, 5}, seqs(t, r, storage.AuditBucket, Filter{}))
+
+	// the purged records are removed after the grace period and do not count towards MaxRecords
+	_, err = r.Purge(storage.HistoryBucket, 3)
+	require.NoError(t, err)
+
+	n, err = r.Prune(policy)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Equal(t, []uint64{3, 4, 5}, seqs(t, r, storage.HistoryBucket, Filter{Deleted: true}))
+
+	advance(10 * time.Minute)
+	recordN(t, r, 1, false)
+	n, err = r.Prune(policy)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []uint64{4, 5, 6}, seqs(t, r, storage.HistoryBucket, Filter{Deleted: true}))
+
+	// the records older than MaxAge are removed, audit records have no max age
+	advance(55 * time.Minute)
+	n, err = r.Prune(policy)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []uint64{6}, seqs(t, r, storage.HistoryBucket, Filter{}))
+	require.Equal(t, []uint64{4, 5}, seqs(t, r, storage.AuditBucket, Filter{}))
+
+	// a zero policy keeps everything but the purged records
+	_, err = r.Purge(storage.AuditBucket, 4)
+	require.NoError(t, err)
+	n, err = r.Prune(RetentionPolicy{})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []uint64{6}, seqs(t, r, storage.HistoryBucket, Filter{}))
+	require.Equal(t, []uint64{5}, seqs(t, r, storage.AuditBucket, Filter{Deleted: true}))
+}
+
+func TestApplyRetention(t *testing.T) {
+	r := NewRecorder(storage.NewMemoryStore(), DefaultTraceHeaders)
+	recordN(t, r, 3, false)
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ApplyRetention(r, RetentionPolicy{
+			History: Retention{
+				MaxRecords: 1,
+			},
+		}, time.Hour, quit)
+	}()
+
+	// the policy is applied at startup
+	deadline := time.Now().Add(time.Second)
+	for len(seqs(t, r, storage.HistoryBucket, Filter{})) != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, []uint64{3}, seqs(t, r, storage.HistoryBucket, Filter{}))
+
+	close(quit)
+	<-done
+}
@@ -0,0 +1,187 @@
+// Package hotplug reconnects to the device when it is re-seated, so the requests which are safe to send again are
+// retried once instead of failing. The device being plugged in and out is published by events.WatchDevice.
+package hotplug
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+const (
+	// DefaultReconnectTimeout is how long a failed request waits for the device to reconnect by default
+	DefaultReconnectTimeout = 10 * time.Second
+
+	// retryInterval is how often the device is looked up while waiting for it to reconnect
+	retryInterval = 250 * time.Millisecond
+)
+
+var logger = logging.MustGetLogger("hotplug")
+
+// idempotentMessages are the messages which do not change the state of the device, sent again after a reconnect.
+// The replies to the device requests, such as a PIN matrix ack, are not sent again: the reconnected device lost them.
+var idempotentMessages = map[messages.MessageType]struct{}{
+	messages.MessageType_MessageType_Initialize:                   {},
+	messages.MessageType_MessageType_GetFeatures:                  {},
+	messages.MessageType_MessageType_Ping:                         {},
+	messages.MessageType_MessageType_SkycoinAddress:               {},
+	messages.MessageType_MessageType_SkycoinCheckMessageSignature: {},
+	messages.MessageType_MessageType_GetRawEntropy:                {},
+	messages.MessageType_MessageType_GetMixedEntropy:              {},
+}
+
+// Driver is a skyWallet.DeviceDriver reconnecting to the device when an idempotent message fails, such as when
+// the cable is re-seated, and sending the message once more to the reconnected device
+type Driver struct {
+	skyWallet.DeviceDriver
+	timeout time.Duration
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps drv, a failed idempotent message waits up to timeout for the device to reconnect
+func NewDriver(drv skyWallet.DeviceDriver, timeout time.Duration) *Driver {
+	return &Driver{
+		DeviceDriver: drv,
+		timeout:      timeout,
+		now:          time.Now,
+		sleep:        time.Sleep,
+	}
+}
+
+// GetDevice returns the device of the wrapped driver, whose handle is replaced when it reconnects
+func (d *Driver) GetDevice() (usb.Device, error) {
+	dev, err := d.DeviceDriver.GetDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	return &device{
+		handle: dev,
+	}, nil
+}
+
+// SendToDevice sends the message to the device. An idempotent message which fails is sent again once,
+// after the device reconnects.
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+	if err == nil {
+		return msg, nil
+	}
+
+	hd, ok := dev.(*device)
+	if !ok {
+		return msg, err
+	}
+
+	kind, ok := idempotent(chunks)
+	if !ok {
+		return msg, err
+	}
+
+	logger.WithError(err).Warningf("%s failed, waiting for the device to reconnect", kind)
+
+	if reconnectErr := d.reconnect(hd); reconnectErr != nil {
+		logger.WithError(reconnectErr).Errorf("Device not reconnected within %s", d.timeout)
+		return msg, err
+	}
+
+	logger.Infof("Device reconnected, sending %s again", kind)
+	return d.DeviceDriver.SendToDevice(hd, chunks)
+}
+
+// reconnect replaces the handle of dev by a handle of the reconnected device
+func (d *Driver) reconnect(dev *device) error {
+	// the handle of the unplugged device is dead
+	dev.close(true)
+
+	deadline := d.now().Add(d.timeout)
+	for {
+		next, err := d.DeviceDriver.GetDevice()
+		if err == nil {
+			dev.setHandle(next)
+			return nil
+		}
+
+		if !d.now().Before(deadline) {
+			return err
+		}
+
+		d.sleep(retryInterval)
+	}
+}
+
+// idempotent returns the type of the message of chunks, and true if it is idempotent
+func idempotent(chunks [][64]byte) (messages.MessageType, bool) {
+	if len(chunks) == 0 {
+		return 0, false
+	}
+
+	kind := header.Kind(chunks)
+	_, ok := idempotentMessages[kind]
+	return kind, ok
+}
+
+// device is a usb.Device whose handle is replaced when the device reconnects, closed once the reconnect failed.
+// The handle may be closed while it is read, when the device does not answer in time.
+type device struct {
+	mu     sync.Mutex
+	handle usb.Device
+}
+
+func (dev *device) current() usb.Device {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return dev.handle
+}
+
+func (dev *device) setHandle(handle usb.Device) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.handle = handle
+}
+
+func (dev *device) Write(p []byte) (int, error) {
+	handle := dev.current()
+	if handle == nil {
+		return 0, skyWallet.ErrNoDeviceConnected
+	}
+	return handle.Write(p)
+}
+
+func (dev *device) Read(p []byte) (int, error) {
+	handle := dev.current()
+	if handle == nil {
+		return 0, skyWallet.ErrNoDeviceConnected
+	}
+	return handle.Read(p)
+}
+
+func (dev *device) Close(disconnected bool) error {
+	return dev.close(disconnected)
+}
+
+// close closes the handle, at most once
+func (dev *device) close(disconnected bool) error {
+	dev.mu.Lock()
+	handle := dev.handle
+	dev.handle = nil
+	dev.mu.Unlock()
+
+	if handle == nil {
+		return nil
+	}
+	return handle.Close(disconnected)
+}
@@ -0,0 +1,181 @@
+package hotplug
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+var errUnplugged = errors.New("device unplugged")
+
+// fakeDevice is a handle of the fake device
+type fakeDevice struct {
+	closed int
+}
+
+func (d *fakeDevice) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func (d *fakeDevice) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *fakeDevice) Close(disconnected bool) error {
+	d.closed++
+	return nil
+}
+
+// fakeDriver unplugs the device on the sends in unplugOn, it is plugged in again after lookups failed lookups
+type fakeDriver struct {
+	unplugOn map[int]bool
+	lookups  int
+
+	sends   int
+	unplugs int
+	devices []*fakeDevice
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	d.sends++
+	if d.unplugOn[d.sends] {
+		d.unplugs = d.lookups
+		return wire.Message{}, errUnplugged
+	}
+	return wire.Message{Kind: uint16(d.sends)}, nil
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return nil
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	if d.unplugs > 0 {
+		d.unplugs--
+		return nil, skyWallet.ErrNoDeviceConnected
+	}
+
+	dev := &fakeDevice{}
+	d.devices = append(d.devices, dev)
+	return dev, nil
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeUSB
+}
+
+func (d *fakeDriver) Close() {}
+
+func TestDriverSendToDevice(t *testing.T) {
+	initialize, err := skyWallet.MessageInitialize()
+	require.NoError(t, err)
+	wipe, err := skyWallet.MessageWipe()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		chunks   [][64]byte
+		unplugOn map[int]bool
+		lookups  int
+		timeout  time.Duration
+		kind     uint16
+		err      error
+		sends    int
+		devices  int
+	}{
+		{
+			name:    "sent",
+			chunks:  initialize,
+			kind:    1,
+			sends:   1,
+			devices: 1,
+		},
+		{
+			name:     "idempotent message sent again after the reconnect",
+			chunks:   initialize,
+			unplugOn: map[int]bool{1: true},
+			lookups:  3,
+			timeout:  time.Second,
+			kind:     2,
+			sends:    2,
+			devices:  2,
+		},
+		{
+			name:     "idempotent message sent again once",
+			chunks:   initialize,
+			unplugOn: map[int]bool{1: true, 2: true},
+			timeout:  time.Second,
+			err:      errUnplugged,
+			sends:    2,
+			devices:  2,
+		},
+		{
+			name:     "device not reconnected",
+			chunks:   initialize,
+			unplugOn: map[int]bool{1: true},
+			lookups:  10,
+			timeout:  time.Second,
+			err:      errUnplugged,
+			sends:    1,
+			devices:  1,
+		},
+		{
+			name:     "message changing the device not sent again",
+			chunks:   wipe,
+			unplugOn: map[int]bool{1: true},
+			timeout:  time.Second,
+			err:      errUnplugged,
+			sends:    1,
+			devices:  1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			drv := &fakeDriver{
+				unplugOn: tc.unplugOn,
+				lookups:  tc.lookups,
+			}
+			d := NewDriver(drv, tc.timeout)
+
+			// the device is looked up 5 times within a second, every retryInterval
+			now := time.Now()
+			d.now = func() time.Time {
+				return now
+			}
+			d.sleep = func(dur time.Duration) {
+				now = now.Add(dur)
+			}
+
+			dev, err := d.GetDevice()
+			require.NoError(t, err)
+
+			msg, err := d.SendToDevice(dev, tc.chunks)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.kind, msg.Kind)
+			}
+			require.Equal(t, tc.sends, drv.sends)
+			require.Len(t, drv.devices, tc.devices)
+
+			// every handle is closed once
+			require.NoError(t, dev.Close(false))
+			require.NoError(t, dev.Close(false))
+			for _, h := range drv.devices {
+				require.Equal(t, 1, h.closed)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package i18n
+
+// es is the Spanish catalog
+var es = map[string]string{
+	// the descriptions of the prompts of the device
+	"Enter the PIN with the layout of the matrix shown on the device": "Introduzca el PIN con la disposición de la matriz que muestra el dispositivo",
+	"Enter the passphrase":                           "Introduzca la frase de contraseña",
+	"Enter the word of the seed asked by the device": "Introduzca la palabra de la semilla que pide el dispositivo",
+	"Confirm the operation on the device":            "Confirme la operación en el dispositivo",
+
+	// the status texts of the errors without a message
+	"Client Closed Request":  "El cliente cerró la solicitud",
+	"Bad Request":            "Solicitud incorrecta",
+	"Unauthorized":           "No autorizado",
+	"Forbidden":              "Prohibido",
+	"Not Found":              "No encontrado",
+	"Method Not Allowed":     "Método no permitido",
+	"Unsupported Media Type": "Tipo de contenido no admitido",
+	"Too Many Requests":      "Demasiadas solicitudes",
+	"Internal Server Error":  "Error interno del servidor",
+	"Service Unavailable":    "Servicio no disponible",
+
+	// the errors of the device and the daemon
+	"no device connected":                        "No hay ningún dispositivo conectado",
+	"device not found":                           "No se encontró el dispositivo",
+	"device disconnected during action":          "El dispositivo se desconectó durante la operación",
+	"the device did not answer in time":          "El dispositivo no respondió a tiempo",
+	"the request was canceled":                   "La solicitud fue cancelada",
+	"the daemon is shutting down":                "El servicio se está deteniendo",
+	"device is locked by another session":        "El dispositivo está bloqueado por otra sesión",
+	"the device is busy with another operation":  "El dispositivo está ocupado con otra operación",
+	"invalid or expired session":                 "La sesión no es válida o ha caducado",
+	"session required":                           "Se requiere una sesión",
+	"the operation is pending approval":          "La operación está pendiente de aprobación",
+	"invalid or expired approval":                "La aprobación no es válida o ha caducado",
+	"the operation was rejected by the approver": "La operación fue rechazada por el aprobador",
+
+	// the validation errors of the requests
+	"inputs are required":                        "Las entradas son obligatorias",
+	"input hash cannot be empty":                 "El hash de la entrada no puede estar vacío",
+	"address cannot be empty":                    "La dirección no puede estar vacía",
+	"coins cannot be empty":                      "Las monedas no pueden estar vacías",
+	"hours cannot be empty":                      "Las horas no pueden estar vacías",
+	"coins must be greater than 0":               "Las monedas deben ser mayores que 0",
+	"address is required":                        "La dirección es obligatoria",
+	"signature is required":                      "La firma es obligatoria",
+	"message is required":                        "El mensaje es obligatorio",
+	"address_n cannot be 0":                      "address_n no puede ser 0",
+	"address_n cannot be negative":               "address_n no puede ser negativo",
+	"start_index cannot be negative":             "start_index no puede ser negativo",
+	"Invalid checksum":                           "La suma de verificación de la dirección no es válida",
+	"Invalid address length":                     "La longitud de la dirección no es válida",
+	"Address version invalid":                    "La versión de la dirección no es válida",
+	"coins must be a decimal number such as 1.5": "Las monedas deben ser un número decimal como 1.5",
+	"coins must be a decimal number, the scientific notation is not allowed": "Las monedas deben ser un número decimal, no se admite la notación científica",
+	"Droplet string conversion failed: Negative balance":                     "Las monedas no pueden ser negativas",
+	"Droplet string conversion failed: Too many decimal places":              "Las monedas tienen más de 6 decimales",
+	"Droplet string conversion failed: Value is too large":                   "Las monedas superan el valor máximo",
+}
@@ -0,0 +1,89 @@
+// Package i18n translates the messages shown to the users, the error messages and the descriptions of the prompts of
+// the device, to the language preferred by the Accept-Language header of a request. The messages of the daemon are in
+// English, the catalogs of the other languages are compiled in the binary and map the English messages to their
+// translations.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages of the daemon, used when no language of the request is supported
+const DefaultLanguage = "en"
+
+// catalogs are the translations of the English messages, by language
+var catalogs = map[string]map[string]string{
+	"es": es,
+	"zh": zh,
+}
+
+// Languages returns the supported languages, sorted
+func Languages() []string {
+	languages := []string{DefaultLanguage}
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Translate returns the translation of the English message in language, or the message if it has no translation
+func Translate(language, message string) string {
+	if t, ok := catalogs[language][message]; ok {
+		return t
+	}
+	return message
+}
+
+// Negotiate returns the supported language preferred by the Accept-Language header, such as "es" for
+// "es-AR,es;q=0.9,en;q=0.8", or DefaultLanguage if none is supported
+func Negotiate(acceptLanguage string) string {
+	type tag struct {
+		language string
+		q        float64
+	}
+
+	var tags []tag
+	for _, s := range strings.Split(acceptLanguage, ",") {
+		parts := strings.Split(s, ";")
+		t := tag{
+			language: strings.ToLower(strings.TrimSpace(parts[0])),
+			q:        1,
+		}
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				q, err := strconv.ParseFloat(p[2:], 64)
+				if err != nil {
+					q = 0
+				}
+				t.q = q
+			}
+		}
+		if t.language != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if t.language == "*" {
+			return DefaultLanguage
+		}
+
+		// the regional variants share the catalog of their language, zh-CN and zh-Hans are zh
+		language := t.language
+		if i := strings.IndexAny(language, "-_"); i >= 0 {
+			language = language[:i]
+		}
+		if _, ok := catalogs[language]; ok || language == DefaultLanguage {
+			return language
+		}
+	}
+
+	return DefaultLanguage
+}
@@ -0,0 +1,56 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		header   string
+		language string
+	}{
+		{header: "", language: "en"},
+		{header: "es", language: "es"},
+		{header: "es-AR,es;q=0.9,en;q=0.8", language: "es"},
+		{header: "zh-CN", language: "zh"},
+		{header: "zh_Hans", language: "zh"},
+		{header: "ZH-TW", language: "zh"},
+		{header: "fr-FR,fr;q=0.9,es;q=0.5", language: "es"},
+		{header: "en;q=0.4, zh;q=0.6", language: "zh"},
+		{header: "es;q=0", language: "en"},
+		{header: "fr, *;q=0.5", language: "en"},
+		{header: "de", language: "en"},
+		{header: "es;q=invalid, zh", language: "zh"},
+		{header: " ,;", language: "en"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.header, func(t *testing.T) {
+			require.Equal(t, tc.language, Negotiate(tc.header))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	require.Equal(t, "No hay ningún dispositivo conectado", Translate("es", "no device connected"))
+	require.Equal(t, "未连接设备", Translate("zh", "no device connected"))
+	require.Equal(t, "no device connected", Translate("en", "no device connected"))
+	require.Equal(t, "an untranslated message", Translate("es", "an untranslated message"))
+	require.Equal(t, "no device connected", Translate("fr", "no device connected"))
+}
+
+func TestCatalogs(t *testing.T) {
+	require.Equal(t, []string{"en", "es", "zh"}, Languages())
+
+	// every catalog translates the messages of the Spanish catalog
+	for language, catalog := range catalogs {
+		require.Len(t, catalog, len(es), language)
+		for message, translation := range catalog {
+			require.NotEmpty(t, translation, "%s: %s", language, message)
+			_, ok := es[message]
+			require.True(t, ok, "%s: %s", language, message)
+		}
+	}
+}
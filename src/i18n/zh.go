@@ -0,0 +1,59 @@
+package i18n
+
+// zh is the Simplified Chinese catalog
+var zh = map[string]string{
+	// the descriptions of the prompts of the device
+	"Enter the PIN with the layout of the matrix shown on the device": "请按照设备上显示的矩阵布局输入 PIN 码",
+	"Enter the passphrase":                           "请输入密码短语",
+	"Enter the word of the seed asked by the device": "请输入设备要求的助记词单词",
+	"Confirm the operation on the device":            "请在设备上确认操作",
+
+	// the status texts of the errors without a message
+	"Client Closed Request":  "客户端关闭了请求",
+	"Bad Request":            "请求错误",
+	"Unauthorized":           "未授权",
+	"Forbidden":              "禁止访问",
+	"Not Found":              "未找到",
+	"Method Not Allowed":     "不允许的方法",
+	"Unsupported Media Type": "不支持的内容类型",
+	"Too Many Requests":      "请求过多",
+	"Internal Server Error":  "服务器内部错误",
+	"Service Unavailable":    "服务不可用",
+
+	// the errors of the device and the daemon
+	"no device connected":                        "未连接设备",
+	"device not found":                           "未找到设备",
+	"device disconnected during action":          "操作过程中设备已断开连接",
+	"the device did not answer in time":          "设备未及时响应",
+	"the request was canceled":                   "请求已取消",
+	"the daemon is shutting down":                "服务正在关闭",
+	"device is locked by another session":        "设备已被另一个会话锁定",
+	"the device is busy with another operation":  "设备正忙于另一项操作",
+	"invalid or expired session":                 "会话无效或已过期",
+	"session required":                           "需要会话",
+	"the operation is pending approval":          "操作正在等待审批",
+	"invalid or expired approval":                "审批无效或已过期",
+	"the operation was rejected by the approver": "操作已被审批人拒绝",
+
+	// the validation errors of the requests
+	"inputs are required":                        "必须提供输入",
+	"input hash cannot be empty":                 "输入哈希不能为空",
+	"address cannot be empty":                    "地址不能为空",
+	"coins cannot be empty":                      "币数不能为空",
+	"hours cannot be empty":                      "币时不能为空",
+	"coins must be greater than 0":               "币数必须大于 0",
+	"address is required":                        "必须提供地址",
+	"signature is required":                      "必须提供签名",
+	"message is required":                        "必须提供消息",
+	"address_n cannot be 0":                      "address_n 不能为 0",
+	"address_n cannot be negative":               "address_n 不能为负数",
+	"start_index cannot be negative":             "start_index 不能为负数",
+	"Invalid checksum":                           "地址校验和无效",
+	"Invalid address length":                     "地址长度无效",
+	"Address version invalid":                    "地址版本无效",
+	"coins must be a decimal number such as 1.5": "币数必须是十进制数，例如 1.5",
+	"coins must be a decimal number, the scientific notation is not allowed": "币数必须是十进制数，不允许使用科学计数法",
+	"Droplet string conversion failed: Negative balance":                     "币数不能为负数",
+	"Droplet string conversion failed: Too many decimal places":              "币数的小数位超过 6 位",
+	"Droplet string conversion failed: Value is too large":                   "币数超过最大值",
+}
@@ -0,0 +1,224 @@
+// Package idempotency records the responses of the state-changing requests sent with an idempotency key, so a
+// client retrying a request after a network failure gets the original result instead of the device prompting the
+// user again. The responses are kept in the storage until their key expires.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// Bucket is the storage bucket holding the responses, keyed by the hash of their endpoint and idempotency key
+const Bucket = "idempotency"
+
+// DefaultTTL is how long the responses are kept by default
+const DefaultTTL = 24 * time.Hour
+
+// MaxKeyLength is the longest idempotency key
+const MaxKeyLength = 255
+
+// pruneInterval is how often the expired responses are removed from the storage
+const pruneInterval = time.Hour
+
+var (
+	// ErrInvalidKey is returned when an idempotency key is not valid
+	ErrInvalidKey = fmt.Errorf("idempotency key must be 1 to %d printable ASCII characters", MaxKeyLength)
+	// ErrMismatch is returned when an idempotency key is sent again with another request
+	ErrMismatch = errors.New("this idempotency key was used with another request")
+)
+
+// Response is the response recorded for an idempotency key
+type Response struct {
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// record is the response of an idempotency key persisted in the storage
+type record struct {
+	// RequestHash is the hash of the request, the key is refused with another request
+	RequestHash string    `json:"request_hash"`
+	Response    Response  `json:"response"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Store keeps the responses of the idempotency keys in a storage
+type Store struct {
+	store storage.Store
+	ttl   time.Duration
+	// now is replaced in tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// inFlight are the requests in progress by storage key, their channel is closed when they end
+	inFlight  map[string]chan struct{}
+	lastPrune time.Time
+}
+
+// New creates a Store keeping the responses in store for ttl
+func New(store storage.Store, ttl time.Duration) *Store {
+	return &Store{
+		store:    store,
+		ttl:      ttl,
+		now:      time.Now,
+		inFlight: make(map[string]chan struct{}),
+	}
+}
+
+// ValidateKey returns ErrInvalidKey if key is not an idempotency key
+func ValidateKey(key string) error {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return ErrInvalidKey
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// storageKey returns the storage key of an idempotency key of endpoint, the same key may be used on other endpoints
+func storageKey(endpoint, key string) string {
+	h := sha256.Sum256([]byte(endpoint + "\x00" + key))
+	return hex.EncodeToString(h[:])
+}
+
+// Begin returns the response recorded for the idempotency key of endpoint. If none was, the key is reserved for the
+// request until end is called with its response, or with nil to release the key without recording a response.
+// While the request of the key is in progress, Begin waits for it to end or for ctx to be done. It returns
+// ErrMismatch when the key was used with another request than the one of requestHash.
+func (s *Store) Begin(ctx context.Context, endpoint, key, requestHash string) (recorded *Response, end func(*Response) error, err error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	id := storageKey(endpoint, key)
+	s.mu.Lock()
+	for {
+		done, ok := s.inFlight[id]
+		if !ok {
+			break
+		}
+		s.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		if _, err := s.pruneLocked(now); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	r, err := s.get(id)
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case r != nil && now.Before(r.ExpiresAt):
+		if r.RequestHash != requestHash {
+			return nil, nil, ErrMismatch
+		}
+		return &r.Response, nil, nil
+	}
+
+	s.inFlight[id] = make(chan struct{})
+	var once sync.Once
+	end = func(rsp *Response) error {
+		var err error
+		once.Do(func() {
+			err = s.end(id, requestHash, rsp)
+		})
+		return err
+	}
+	return nil, end, nil
+}
+
+// end records the response of the request of the storage key id and releases it
+func (s *Store) end(id, requestHash string, rsp *Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.inFlight[id])
+	delete(s.inFlight, id)
+
+	if rsp == nil {
+		return nil
+	}
+
+	now := s.now()
+	r := record{
+		RequestHash: requestHash,
+		Response:    *rsp,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	r.Response.CreatedAt = now
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(Bucket, id, data)
+}
+
+// get returns the record of the storage key id, nil if there is none
+func (s *Store) get(id string) (*record, error) {
+	data, err := s.store.Get(Bucket, id)
+	switch err {
+	case nil:
+	case storage.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid idempotency record: %v", err)
+	}
+	return &r, nil
+}
+
+// Prune removes the expired responses from the storage and returns how many were removed
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pruneLocked(s.now())
+}
+
+func (s *Store) pruneLocked(now time.Time) (int, error) {
+	var expired []string
+	err := s.store.ForEach(Bucket, func(key string, value []byte) error {
+		var r record
+		// the records which cannot be read are removed too
+		if err := json.Unmarshal(value, &r); err != nil || !now.Before(r.ExpiresAt) {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range expired {
+		if err := s.store.Delete(Bucket, key); err != nil {
+			return 0, err
+		}
+	}
+	s.lastPrune = now
+	return len(expired), nil
+}
@@ -0,0 +1,122 @@
+package idempotency
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestStore(t *testing.T) {
+	store := storage.NewMemoryStore()
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	s := New(store, time.Hour)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	recorded, end, err := s.Begin(ctx, "/wipe", "retry-1", "hash")
+	require.NoError(t, err)
+	require.Nil(t, recorded)
+
+	// a retry waits while the request of its key is in progress
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = s.Begin(canceled, "/wipe", "retry-1", "hash")
+	require.Equal(t, context.Canceled, err)
+
+	retried := make(chan *Response)
+	go func() {
+		recorded, end, err := s.Begin(ctx, "/wipe", "retry-1", "hash")
+		if err != nil || end != nil {
+			retried <- nil
+			return
+		}
+		retried <- recorded
+	}()
+
+	rsp := &Response{
+		Status:      200,
+		ContentType: "application/json",
+		Body:        []byte(`{"data":"Device wiped"}`),
+	}
+	require.NoError(t, end(rsp))
+
+	// the retry gets the recorded response
+	require.Equal(t, &Response{
+		Status:      200,
+		ContentType: "application/json",
+		Body:        []byte(`{"data":"Device wiped"}`),
+		CreatedAt:   now,
+	}, <-retried)
+
+	// the key is refused with another request
+	_, _, err = s.Begin(ctx, "/wipe", "retry-1", "other")
+	require.Equal(t, ErrMismatch, err)
+
+	// the same key is another request on another endpoint
+	recorded, end, err = s.Begin(ctx, "/recovery", "retry-1", "other")
+	require.NoError(t, err)
+	require.Nil(t, recorded)
+
+	// an operation ending without a response releases the key
+	require.NoError(t, end(nil))
+	recorded, end, err = s.Begin(ctx, "/recovery", "retry-1", "other")
+	require.NoError(t, err)
+	require.Nil(t, recorded)
+	require.NoError(t, end(nil))
+
+	// the response expires with its key
+	now = now.Add(time.Hour)
+	recorded, end, err = s.Begin(ctx, "/wipe", "retry-1", "other")
+	require.NoError(t, err)
+	require.Nil(t, recorded)
+	require.NoError(t, end(nil))
+}
+
+func TestStorePrune(t *testing.T) {
+	store := storage.NewMemoryStore()
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	s := New(store, time.Hour)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		_, end, err := s.Begin(ctx, "/wipe", key, "hash")
+		require.NoError(t, err)
+		require.NoError(t, end(&Response{Status: 200}))
+		now = now.Add(30 * time.Minute)
+	}
+
+	n, err := s.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = s.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// the expired responses are removed by the requests too
+	now = now.Add(pruneInterval)
+	_, end, err := s.Begin(ctx, "/wipe", "c", "hash")
+	require.NoError(t, err)
+	require.NoError(t, end(nil))
+	count := 0
+	require.NoError(t, store.ForEach(Bucket, func(string, []byte) error {
+		count++
+		return nil
+	}))
+	require.Zero(t, count)
+}
+
+func TestValidateKey(t *testing.T) {
+	require.NoError(t, ValidateKey("8e03978e-40d5-43e8-bc93-6894a57f9324"))
+	require.NoError(t, ValidateKey(strings.Repeat("k", MaxKeyLength)))
+	require.Equal(t, ErrInvalidKey, ValidateKey(""))
+	require.Equal(t, ErrInvalidKey, ValidateKey(strings.Repeat("k", MaxKeyLength+1)))
+	require.Equal(t, ErrInvalidKey, ValidateKey("new\nline"))
+	require.Equal(t, ErrInvalidKey, ValidateKey("clé"))
+}
@@ -0,0 +1,186 @@
+// Package inventory keeps the metadata of the devices used with the daemon, keyed by their device ID: the nickname
+// assigned by the user, when the device was last seen, its firmware version and the highest address index derived,
+// so the hosts restore the context of a device after the daemon restarts.
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// MaxNicknameLength is the longest nickname of a device
+const MaxNicknameLength = 64
+
+var (
+	// ErrNotFound is returned when no metadata of a device is known
+	ErrNotFound = errors.New("device not found")
+	// ErrInvalidDeviceID is returned when a device ID is not valid
+	ErrInvalidDeviceID = errors.New("device ID must be 1 to 64 letters or digits")
+	// ErrNicknameTooLong is returned when a nickname is longer than MaxNicknameLength
+	ErrNicknameTooLong = fmt.Errorf("nickname is longer than %d bytes", MaxNicknameLength)
+
+	deviceIDRegex = regexp.MustCompile(`^[A-Za-z0-9]{1,64}$`)
+)
+
+// Metadata is the metadata of a device
+type Metadata struct {
+	DeviceID string `json:"device_id"`
+	// Nickname is the name of the device assigned by the user
+	Nickname string `json:"nickname,omitempty"`
+	// LastSeen is when the daemon last read the features of the device
+	LastSeen        time.Time `json:"last_seen"`
+	FirmwareVersion string    `json:"firmware_version,omitempty"`
+	// HighestIndex is the highest address index generated by the device, nil if none was
+	HighestIndex *uint32   `json:"highest_index,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Inventory is the metadata of the devices, persisted in a store
+type Inventory struct {
+	store storage.Store
+
+	mu sync.Mutex
+}
+
+// New creates an Inventory of the devices in store
+func New(store storage.Store) *Inventory {
+	return &Inventory{
+		store: store,
+	}
+}
+
+// ValidateDeviceID returns ErrInvalidDeviceID if id is not a device ID
+func ValidateDeviceID(id string) error {
+	if !deviceIDRegex.MatchString(id) {
+		return ErrInvalidDeviceID
+	}
+	return nil
+}
+
+// List returns the metadata of the devices, the last seen first
+func (inv *Inventory) List() ([]Metadata, error) {
+	devices := []Metadata{}
+	err := inv.store.ForEach(storage.InventoryBucket, func(_ string, value []byte) error {
+		var m Metadata
+		if err := json.Unmarshal(value, &m); err != nil {
+			return fmt.Errorf("invalid device metadata: %v", err)
+		}
+		devices = append(devices, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].LastSeen.After(devices[j].LastSeen)
+	})
+	return devices, nil
+}
+
+// Get returns the metadata of the device id
+func (inv *Inventory) Get(id string) (Metadata, error) {
+	if err := ValidateDeviceID(id); err != nil {
+		return Metadata{}, err
+	}
+	return inv.get(id)
+}
+
+func (inv *Inventory) get(id string) (Metadata, error) {
+	var m Metadata
+	data, err := inv.store.Get(storage.InventoryBucket, id)
+	switch err {
+	case nil:
+	case storage.ErrNotFound:
+		return m, ErrNotFound
+	default:
+		return m, err
+	}
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("invalid device metadata: %v", err)
+	}
+	return m, nil
+}
+
+// update applies fn to the metadata of the device id, created if it does not exist and create is set
+func (inv *Inventory) update(id string, create bool, now time.Time, fn func(*Metadata)) (Metadata, error) {
+	if err := ValidateDeviceID(id); err != nil {
+		return Metadata{}, err
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	m, err := inv.get(id)
+	switch {
+	case err == ErrNotFound && create:
+		m = Metadata{
+			DeviceID: id,
+		}
+	case err != nil:
+		return m, err
+	}
+
+	fn(&m)
+	m.UpdatedAt = now.UTC()
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+	return m, inv.store.Put(storage.InventoryBucket, id, data)
+}
+
+// Seen records that the device id running firmwareVersion was seen at time now, an empty version is left unchanged
+func (inv *Inventory) Seen(id, firmwareVersion string, now time.Time) (Metadata, error) {
+	return inv.update(id, true, now, func(m *Metadata) {
+		m.LastSeen = now.UTC()
+		if firmwareVersion != "" {
+			m.FirmwareVersion = firmwareVersion
+		}
+	})
+}
+
+// Derived records that the device id generated the addresses up to index, at time now
+func (inv *Inventory) Derived(id string, index uint32, now time.Time) (Metadata, error) {
+	return inv.update(id, true, now, func(m *Metadata) {
+		m.LastSeen = now.UTC()
+		if m.HighestIndex == nil || index > *m.HighestIndex {
+			m.HighestIndex = &index
+		}
+	})
+}
+
+// SetNickname sets the nickname of the known device id, an empty nickname removes it
+func (inv *Inventory) SetNickname(id, nickname string, now time.Time) (Metadata, error) {
+	if len(nickname) > MaxNicknameLength {
+		return Metadata{}, ErrNicknameTooLong
+	}
+
+	return inv.update(id, false, now, func(m *Metadata) {
+		m.Nickname = nickname
+	})
+}
+
+// Delete forgets the device id
+func (inv *Inventory) Delete(id string) error {
+	if err := ValidateDeviceID(id); err != nil {
+		return err
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if _, err := inv.get(id); err != nil {
+		return err
+	}
+	return inv.store.Delete(storage.InventoryBucket, id)
+}
@@ -0,0 +1,60 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+func TestInventory(t *testing.T) {
+	store := storage.NewMemoryStore()
+	inv := New(store)
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+
+	_, err := inv.SetNickname("8A7F7D2E9C0D9B14D7C2E3B0", "desk", now)
+	require.Equal(t, ErrNotFound, err)
+
+	m, err := inv.Seen("8A7F7D2E9C0D9B14D7C2E3B0", "1.7.0", now)
+	require.NoError(t, err)
+	require.Equal(t, now, m.LastSeen)
+	require.Nil(t, m.HighestIndex)
+
+	_, err = inv.Derived("8A7F7D2E9C0D9B14D7C2E3B0", 9, now.Add(time.Minute))
+	require.NoError(t, err)
+	// a lower index does not lower the highest one
+	_, err = inv.Derived("8A7F7D2E9C0D9B14D7C2E3B0", 4, now.Add(2*time.Minute))
+	require.NoError(t, err)
+
+	_, err = inv.SetNickname("8A7F7D2E9C0D9B14D7C2E3B0", "desk", now.Add(3*time.Minute))
+	require.NoError(t, err)
+
+	// an unknown firmware version is left unchanged
+	_, err = inv.Seen("8A7F7D2E9C0D9B14D7C2E3B0", "", now.Add(4*time.Minute))
+	require.NoError(t, err)
+
+	_, err = inv.Seen("687576E45325EDC184C3B968", "1.8.0", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	// the metadata survive a restart
+	inv = New(store)
+	m, err = inv.Get("8A7F7D2E9C0D9B14D7C2E3B0")
+	require.NoError(t, err)
+	require.Equal(t, "desk", m.Nickname)
+	require.Equal(t, "1.7.0", m.FirmwareVersion)
+	require.Equal(t, uint32(9), *m.HighestIndex)
+	require.Equal(t, now.Add(4*time.Minute), m.LastSeen)
+
+	devices, err := inv.List()
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	require.Equal(t, "687576E45325EDC184C3B968", devices[0].DeviceID)
+
+	require.NoError(t, inv.Delete("687576E45325EDC184C3B968"))
+	require.Equal(t, ErrNotFound, inv.Delete("687576E45325EDC184C3B968"))
+
+	_, err = inv.Get("../history")
+	require.Equal(t, ErrInvalidDeviceID, err)
+}
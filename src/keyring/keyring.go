@@ -0,0 +1,92 @@
+// Package keyring reads the secrets of the settings from the keyring of the operating system: the Keychain on macOS,
+// the Credential Manager on Windows and the Secret Service on Linux. A setting holding a secret refers to it with
+// keyring:<name> instead of holding it in plaintext.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Prefix starts the settings referring to a secret of the keyring
+	Prefix = "keyring:"
+	// Service is the service the secrets of the daemon are stored under
+	Service = "skywallet-daemon"
+)
+
+var (
+	// ErrNotFound is returned when the keyring holds no secret of the name
+	ErrNotFound = errors.New("secret not found in the keyring")
+	// ErrUnsupported is returned on the systems without a supported keyring
+	ErrUnsupported = errors.New("the keyring is not supported on this system")
+)
+
+// provider stores the secrets in the keyring of the system
+type provider interface {
+	get(name string) (string, error)
+	set(name, secret string) error
+}
+
+// keyring is the keyring of the system, replaced in tests
+var keyring provider = systemKeyring{}
+
+// IsReference returns true if value refers to a secret of the keyring
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Reference returns the reference to the secret of name
+func Reference(name string) string {
+	return Prefix + name
+}
+
+// ValidateName checks that name has printable characters and no space
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("the secret name must not be empty")
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] <= ' ' || name[i] > '~' {
+			return fmt.Errorf("invalid secret name %q, it must have printable characters and no space", name)
+		}
+	}
+	return nil
+}
+
+// Get returns the secret of name
+func Get(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+
+	secret, err := keyring.get(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the secret %q from the keyring: %v", name, err)
+	}
+	return secret, nil
+}
+
+// Set stores the secret of name, replacing the previous one
+func Set(name, secret string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if secret == "" {
+		return errors.New("the secret must not be empty")
+	}
+
+	if err := keyring.set(name, secret); err != nil {
+		return fmt.Errorf("failed to store the secret %q in the keyring: %v", name, err)
+	}
+	return nil
+}
+
+// Resolve returns the secret value refers to, or value if it is not a reference
+func Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	return Get(strings.TrimPrefix(value, Prefix))
+}
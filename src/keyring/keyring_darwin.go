@@ -0,0 +1,42 @@
+// +build darwin
+
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errSecItemNotFound is the exit code of security when the Keychain holds no item
+const errSecItemNotFound = 44
+
+// systemKeyring stores the secrets as generic passwords of the login Keychain, with the security command
+type systemKeyring struct{}
+
+func (systemKeyring) get(name string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", Service, "-a", name, "-w")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitCode(exitErr) == errSecItemNotFound {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("security: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+// set passes the secret on the command line of security, which only reads it from there or from a terminal
+func (systemKeyring) set(name, secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", Service, "-a", name, "-w", secret)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("security: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
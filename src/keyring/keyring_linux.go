@@ -0,0 +1,42 @@
+// +build linux
+
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemKeyring stores the secrets in the Secret Service, such as GNOME Keyring or KWallet, with the secret-tool
+// command of libsecret
+type systemKeyring struct{}
+
+func (systemKeyring) get(name string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", Service, "account", name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with 1 and writes nothing when no secret matches
+		if exitErr, ok := err.(*exec.ExitError); ok && exitCode(exitErr) == 1 && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secret-tool: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// set writes the secret on the standard input of secret-tool, so it is not on its command line
+func (systemKeyring) set(name, secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "store", "--label", Service+" "+name, "service", Service, "account", name)
+	cmd.Stdin = strings.NewReader(secret)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("secret-tool: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// +build !darwin,!linux,!windows
+
+package keyring
+
+// systemKeyring is the missing keyring of the other systems
+type systemKeyring struct{}
+
+func (systemKeyring) get(name string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (systemKeyring) set(name, secret string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,16 @@
+// +build darwin linux
+
+package keyring
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// exitCode returns the exit code of the command of err
+func exitCode(err *exec.ExitError) int {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok {
+		return status.ExitStatus()
+	}
+	return -1
+}
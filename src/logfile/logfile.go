@@ -0,0 +1,179 @@
+// Package logfile writes the daemon logs to a file, degrading to stderr when the file cannot be written anymore,
+// for example when the disk is full or the file was rotated away and cannot be recreated, so log lines are not lost.
+package logfile
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+// DefaultCheckInterval is how often the file is checked for rotation
+const DefaultCheckInterval = 10 * time.Second
+
+var logger = logging.MustGetLogger("logfile")
+
+// Failure describes a log file which failed
+type Failure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// File is a logrus hook writing the log entries to a file, or to stderr once the file failed
+type File struct {
+	path string
+
+	mu        sync.Mutex
+	file      *os.File
+	hook      *logging.WriteHook
+	fallback  *logging.WriteHook
+	err       error
+	onFailure func(Failure)
+}
+
+// Open opens or creates the log file at path, appending to it
+func Open(path string) (*File, error) {
+	return open(path, os.Stderr)
+}
+
+func open(path string, fallback io.Writer) (*File, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		path:     path,
+		file:     f,
+		hook:     logging.NewWriteHook(f),
+		fallback: logging.NewWriteHook(fallback),
+	}, nil
+}
+
+func openFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
+
+// Path returns the path of the file
+func (f *File) Path() string {
+	return f.path
+}
+
+// OnFailure sets the function called when the file fails, once
+func (f *File) OnFailure(fn func(Failure)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onFailure = fn
+}
+
+// Err returns the error which made the file fail, nil if the entries are written to the file
+func (f *File) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// Levels implements logrus.Hook
+func (f *File) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook, writing the entry to the file or to stderr once the file failed
+func (f *File) Fire(e *logrus.Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err == nil {
+		err := f.hook.Fire(e)
+		if err == nil {
+			return nil
+		}
+		f.fail(err)
+	}
+
+	return f.fallback.Fire(e)
+}
+
+// fail degrades to stderr and notifies the failure, f.mu must be held.
+// The hooks are fired with the logger locked, the failure is logged and notified asynchronously.
+func (f *File) fail(err error) {
+	f.err = err
+	f.file.Close() // nolint: errcheck
+
+	failure := Failure{
+		Path:  f.path,
+		Error: err.Error(),
+	}
+	onFailure := f.onFailure
+
+	go func() {
+		logger.WithError(err).Errorf("Log file %s failed, logging to stderr", failure.Path)
+		if onFailure != nil {
+			onFailure(failure)
+		}
+	}()
+}
+
+// Check reopens the file if it was removed or replaced, as log rotation does, and fails if it cannot be reopened
+func (f *File) Check() {
+	f.mu.Lock()
+	if f.err != nil {
+		f.mu.Unlock()
+		return
+	}
+
+	info, err := os.Stat(f.path)
+	if err == nil {
+		current, err := f.file.Stat()
+		if err == nil && os.SameFile(info, current) {
+			f.mu.Unlock()
+			return
+		}
+	}
+
+	file, err := openFile(f.path)
+	if err != nil {
+		f.fail(err)
+		f.mu.Unlock()
+		return
+	}
+
+	f.file.Close() // nolint: errcheck
+	f.file = file
+	f.hook = logging.NewWriteHook(file)
+	f.mu.Unlock()
+
+	logger.Infof("Log file %s was rotated, reopened it", f.path)
+}
+
+// Watch checks the file every interval until quit is closed
+func (f *File) Watch(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			f.Check()
+		}
+	}
+}
+
+// Close closes the file. It returns no error if the file already failed, the failure was reported.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil
+	}
+
+	f.err = os.ErrClosed
+	return f.file.Close()
+}
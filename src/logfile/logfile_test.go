@@ -0,0 +1,133 @@
+package logfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func entry(msg string) *logrus.Entry {
+	return &logrus.Entry{
+		Logger:  logrus.New(),
+		Data:    logrus.Fields{},
+		Time:    time.Now(),
+		Level:   logrus.InfoLevel,
+		Message: msg,
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func waitFailure(t *testing.T, c <-chan Failure) Failure {
+	select {
+	case failure := <-c:
+		return failure
+	case <-time.After(time.Second):
+		t.Fatal("failure not notified")
+		return Failure{}
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "daemon.log")
+	var fallback bytes.Buffer
+	f, err := open(path, &fallback)
+	require.NoError(t, err)
+
+	require.NoError(t, f.Fire(entry("before rotation")))
+	require.Contains(t, readFile(t, path), "before rotation")
+
+	// the file is unchanged
+	f.Check()
+	require.NoError(t, f.Err())
+
+	rotated := path + ".1"
+	require.NoError(t, os.Rename(path, rotated))
+	f.Check()
+	require.NoError(t, f.Err())
+
+	require.NoError(t, f.Fire(entry("after rotation")))
+	require.Contains(t, readFile(t, path), "after rotation")
+	require.NotContains(t, readFile(t, rotated), "after rotation")
+	require.Empty(t, fallback.String())
+
+	require.NoError(t, f.Close())
+}
+
+func TestFileReopenFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logDir := filepath.Join(dir, "logs")
+	require.NoError(t, os.Mkdir(logDir, 0750))
+	path := filepath.Join(logDir, "daemon.log")
+
+	var fallback bytes.Buffer
+	f, err := open(path, &fallback)
+	require.NoError(t, err)
+
+	failures := make(chan Failure, 1)
+	f.OnFailure(func(failure Failure) {
+		failures <- failure
+	})
+
+	// the file cannot be recreated
+	require.NoError(t, os.RemoveAll(logDir))
+	f.Check()
+	require.Error(t, f.Err())
+
+	failure := waitFailure(t, failures)
+	require.Equal(t, path, failure.Path)
+	require.Equal(t, f.Err().Error(), failure.Error)
+
+	require.NoError(t, f.Fire(entry("after failure")))
+	require.Contains(t, fallback.String(), "after failure")
+
+	// the failure was reported, closing does not fail again
+	require.NoError(t, f.Close())
+}
+
+func TestFileWriteFailure(t *testing.T) {
+	// writes to /dev/full fail as on a full disk
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full is not available")
+	}
+
+	var fallback bytes.Buffer
+	f, err := open("/dev/full", &fallback)
+	require.NoError(t, err)
+
+	failures := make(chan Failure, 1)
+	f.OnFailure(func(failure Failure) {
+		failures <- failure
+	})
+
+	require.NoError(t, f.Fire(entry("disk full")))
+	require.Contains(t, fallback.String(), "disk full")
+	require.Error(t, f.Err())
+
+	failure := waitFailure(t, failures)
+	require.Equal(t, "/dev/full", failure.Path)
+	require.Contains(t, failure.Error, "no space left on device")
+
+	// the later entries go to the fallback only
+	require.NoError(t, f.Fire(entry("still logged")))
+	require.Contains(t, fallback.String(), "still logged")
+
+	require.NoError(t, f.Close())
+}
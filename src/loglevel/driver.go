@@ -0,0 +1,87 @@
+package loglevel
+
+import (
+	"time"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+var logger = logging.MustGetLogger("usb")
+
+// Driver is a device driver logging the messages exchanged with the device while the controller enables it.
+// The type, size and duration of the messages are logged, not their payload which holds the PINs and the words of
+// the seeds.
+type Driver struct {
+	skyWallet.DeviceDriver
+	controller *Controller
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps driver, logging its messages while controller enables it
+func NewDriver(driver skyWallet.DeviceDriver, controller *Controller) *Driver {
+	return &Driver{
+		DeviceDriver: driver,
+		controller:   controller,
+	}
+}
+
+// SendToDevice implements skyWallet.DeviceDriver
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	if !d.controller.USB() {
+		return d.DeviceDriver.SendToDevice(dev, chunks)
+	}
+
+	kind := messageTypeName(chunks)
+	logger.Debugf("-> %s, %d chunks to the %s device", kind, len(chunks), d.DeviceDriver.DeviceType())
+	start := time.Now()
+
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+	if err != nil {
+		logger.WithError(err).Debugf("%s failed after %s", kind, time.Since(start))
+		return msg, err
+	}
+
+	logger.Debugf("<- %s, %d bytes after %s", messages.MessageType(msg.Kind), len(msg.Data), time.Since(start))
+	return msg, nil
+}
+
+// SendToDeviceNoAnswer implements skyWallet.DeviceDriver
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	if !d.controller.USB() {
+		return d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+	}
+
+	kind := messageTypeName(chunks)
+	logger.Debugf("-> %s, %d chunks to the %s device, no answer", kind, len(chunks), d.DeviceDriver.DeviceType())
+	start := time.Now()
+
+	err := d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+	if err != nil {
+		logger.WithError(err).Debugf("%s failed after %s", kind, time.Since(start))
+	}
+	return err
+}
+
+// GetDevice implements skyWallet.DeviceDriver
+func (d *Driver) GetDevice() (usb.Device, error) {
+	dev, err := d.DeviceDriver.GetDevice()
+	if err != nil && d.controller.USB() {
+		logger.WithError(err).Debugf("Opening the %s device failed", d.DeviceDriver.DeviceType())
+	}
+	return dev, err
+}
+
+// messageTypeName reads the message type from the header of the first chunk
+func messageTypeName(chunks [][64]byte) string {
+	if len(chunks) == 0 {
+		return "empty message"
+	}
+	return header.Kind(chunks).String()
+}
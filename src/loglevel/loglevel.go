@@ -0,0 +1,143 @@
+// Package loglevel changes the log level of the running daemon from the admin endpoints, for a while or until it is
+// changed again, and the debug logging of the messages exchanged with the device, which diagnoses the intermittent
+// device communication failures without restarting the daemon.
+package loglevel
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+var (
+	// ErrUSBRequiresDebug is returned when the device messages are logged at a level other than debug
+	ErrUSBRequiresDebug = errors.New("the device messages are logged at the debug level, usb requires the debug level")
+	// ErrInvalidDuration is returned when the duration of a change is negative
+	ErrInvalidDuration = errors.New("duration must not be negative")
+)
+
+// Status is the log level of the daemon
+type Status struct {
+	// Level is the current log level
+	Level string `json:"level"`
+	// USB is true if the messages exchanged with the device are logged
+	USB bool `json:"usb"`
+	// DefaultLevel is the level of -log-level, restored when the change expires
+	DefaultLevel string `json:"default_level"`
+	// ExpiresAt is when the default level is restored, nil if the change does not expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Controller sets the log level of the daemon
+type Controller struct {
+	defaultLevel logrus.Level
+	// setLevel sets the level of the loggers, replaced by the tests
+	setLevel func(logrus.Level)
+
+	mu        sync.Mutex
+	level     logrus.Level
+	usb       bool
+	expiresAt time.Time
+	timer     *time.Timer
+	seq       uint64
+}
+
+// New creates a Controller of the daemon logging at defaultLevel
+func New(defaultLevel logrus.Level) *Controller {
+	return &Controller{
+		defaultLevel: defaultLevel,
+		setLevel:     logging.SetLevel,
+		level:        defaultLevel,
+	}
+}
+
+// Status returns the current log level
+func (c *Controller) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status()
+}
+
+// USB returns true if the messages exchanged with the device are logged
+func (c *Controller) USB() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usb
+}
+
+// Set sets the log level, and logs the messages exchanged with the device if usb is true. The default level is
+// restored after duration, a duration of 0 keeps the level until it is set again.
+func (c *Controller) Set(level logrus.Level, usb bool, duration time.Duration) (Status, error) {
+	if usb && level != logrus.DebugLevel {
+		return Status{}, ErrUSBRequiresDebug
+	}
+	if duration < 0 {
+		return Status{}, ErrInvalidDuration
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	c.seq++
+	c.level = level
+	c.usb = usb
+	c.expiresAt = time.Time{}
+	c.setLevel(level)
+
+	if duration > 0 {
+		seq := c.seq
+		c.expiresAt = time.Now().UTC().Add(duration)
+		c.timer = time.AfterFunc(duration, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			// a later change replaced the expired one
+			if c.seq == seq {
+				c.reset()
+			}
+		})
+	}
+
+	return c.status(), nil
+}
+
+// Reset restores the default level and stops logging the device messages
+func (c *Controller) Reset() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.seq++
+	c.reset()
+	return c.status()
+}
+
+func (c *Controller) reset() {
+	c.level = c.defaultLevel
+	c.usb = false
+	c.expiresAt = time.Time{}
+	c.timer = nil
+	c.setLevel(c.defaultLevel)
+}
+
+func (c *Controller) status() Status {
+	s := Status{
+		Level:        c.level.String(),
+		USB:          c.usb,
+		DefaultLevel: c.defaultLevel.String(),
+	}
+	if !c.expiresAt.IsZero() {
+		expiresAt := c.expiresAt
+		s.ExpiresAt = &expiresAt
+	}
+	return s
+}
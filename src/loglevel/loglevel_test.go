@@ -0,0 +1,186 @@
+package loglevel
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+type fakeDriver struct {
+	response wire.Message
+	err      error
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return d.response, d.err
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return d.err
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return nil, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeEmulator
+}
+
+func (d *fakeDriver) Close() {}
+
+// messageHook records the messages of the usb logger
+type messageHook struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (h *messageHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.DebugLevel}
+}
+
+func (h *messageHook) Fire(e *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if e.Data["_module"] == "usb" {
+		h.messages = append(h.messages, e.Message)
+	}
+	return nil
+}
+
+func (h *messageHook) take() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	m := h.messages
+	h.messages = nil
+	return m
+}
+
+func newTestController() (*Controller, *logrus.Level) {
+	c := New(logrus.InfoLevel)
+	level := logrus.InfoLevel
+	c.setLevel = func(l logrus.Level) {
+		level = l
+	}
+	return c, &level
+}
+
+func TestController(t *testing.T) {
+	c, level := newTestController()
+	require.Equal(t, Status{
+		Level:        "info",
+		DefaultLevel: "info",
+	}, c.Status())
+
+	_, err := c.Set(logrus.WarnLevel, true, 0)
+	require.Equal(t, ErrUSBRequiresDebug, err)
+	_, err = c.Set(logrus.DebugLevel, false, -time.Second)
+	require.Equal(t, ErrInvalidDuration, err)
+
+	s, err := c.Set(logrus.WarnLevel, false, 0)
+	require.NoError(t, err)
+	require.Equal(t, Status{
+		Level:        "warning",
+		DefaultLevel: "info",
+	}, s)
+	require.Equal(t, logrus.WarnLevel, *level)
+
+	s, err = c.Set(logrus.DebugLevel, true, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, "debug", s.Level)
+	require.True(t, s.USB)
+	require.NotNil(t, s.ExpiresAt)
+	require.True(t, c.USB())
+
+	s = c.Reset()
+	require.Equal(t, Status{
+		Level:        "info",
+		DefaultLevel: "info",
+	}, s)
+	require.Equal(t, logrus.InfoLevel, *level)
+	require.False(t, c.USB())
+}
+
+func TestControllerExpires(t *testing.T) {
+	c, _ := newTestController()
+
+	_, err := c.Set(logrus.DebugLevel, true, 20*time.Millisecond)
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.USB() {
+		require.True(t, time.Now().Before(deadline), "the log level was not restored")
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, Status{
+		Level:        "info",
+		DefaultLevel: "info",
+	}, c.Status())
+
+	// a later change is not reverted by the expiry of the previous one
+	_, err = c.Set(logrus.DebugLevel, false, 20*time.Millisecond)
+	require.NoError(t, err)
+	_, err = c.Set(logrus.ErrorLevel, false, 0)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, "error", c.Status().Level)
+}
+
+func TestDriver(t *testing.T) {
+	hook := &messageHook{}
+	logging.AddHook(hook)
+	logging.SetLevel(logrus.DebugLevel)
+	defer logging.SetLevel(logrus.InfoLevel)
+
+	c, _ := newTestController()
+	drv := &fakeDriver{
+		response: wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_Success),
+			Data: make([]byte, 12),
+		},
+	}
+	d := NewDriver(drv, c)
+
+	var chunk [64]byte
+	copy(chunk[:], "?##")
+	chunk[4] = byte(messages.MessageType_MessageType_Ping)
+	chunks := [][64]byte{chunk}
+
+	// the messages are not logged unless enabled
+	msg, err := d.SendToDevice(nil, chunks)
+	require.NoError(t, err)
+	require.Equal(t, drv.response, msg)
+	require.Empty(t, hook.take())
+
+	_, err = c.Set(logrus.DebugLevel, true, 0)
+	require.NoError(t, err)
+
+	msg, err = d.SendToDevice(nil, chunks)
+	require.NoError(t, err)
+	require.Equal(t, drv.response, msg)
+	logged := hook.take()
+	require.Len(t, logged, 2)
+	require.Equal(t, "-> MessageType_Ping, 1 chunks to the EMULATOR device", logged[0])
+	require.Contains(t, logged[1], "<- MessageType_Success, 12 bytes after")
+
+	drv.err = errors.New("device disconnected")
+	require.Equal(t, drv.err, d.SendToDeviceNoAnswer(nil, chunks))
+	logged = hook.take()
+	require.Len(t, logged, 2)
+	require.Equal(t, "-> MessageType_Ping, 1 chunks to the EMULATOR device, no answer", logged[0])
+	require.Contains(t, logged[1], "MessageType_Ping failed after")
+}
@@ -17,6 +17,10 @@ import (
 // swagger:model ApplySettingsRequest
 type ApplySettingsRequest struct {
 
+	// base64 encoded 128x64 PNG, GIF or JPEG image, or a packed monochrome bitmap
+	// Format: byte
+	Homescreen strfmt.Base64 `json:"homescreen,omitempty"`
+
 	// label
 	Label string `json:"label,omitempty"`
 
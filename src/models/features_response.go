@@ -136,6 +136,9 @@ type FeaturesResponseData struct {
 	// Required: true
 	PinProtection *bool `json:"pin_protection"`
 
+	// unfinished backup
+	UnfinishedBackup bool `json:"unfinished_backup,omitempty"`
+
 	// vendor
 	// Required: true
 	Vendor *string `json:"vendor"`
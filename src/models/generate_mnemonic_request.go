@@ -17,6 +17,9 @@ import (
 // swagger:model GenerateMnemonicRequest
 type GenerateMnemonicRequest struct {
 
+	// hex encoded additional entropy mixed into the host entropy sent to the device
+	Entropy string `json:"entropy,omitempty"`
+
 	// use passphrase
 	UsePassphrase bool `json:"use_passphrase,omitempty"`
 
@@ -74,11 +74,17 @@ func (m *HTTPErrorResponse) UnmarshalBinary(b []byte) error {
 // swagger:model HTTPErrorResponseError
 type HTTPErrorResponseError struct {
 
+	// Machine-readable category of the error, such as device_disconnected or pin_invalid
+	Category string `json:"category,omitempty"`
+
 	// code
 	Code int64 `json:"code,omitempty"`
 
 	// message
 	Message string `json:"message,omitempty"`
+
+	// request id
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Validate validates this HTTP error response error
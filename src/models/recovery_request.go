@@ -17,7 +17,7 @@ import (
 // swagger:model RecoveryRequest
 type RecoveryRequest struct {
 
-	// dry run
+	// only check that the entered mnemonic matches the seed of the device, without modifying it
 	DryRun bool `json:"dry_run,omitempty"`
 
 	// use passphrase
@@ -77,15 +77,51 @@ type VersionResponseData struct {
 	// branch
 	Branch string `json:"branch,omitempty"`
 
+	// build time
+	BuildTime string `json:"build_time,omitempty"`
+
 	// commit
 	Commit string `json:"commit,omitempty"`
 
+	// go version
+	GoVersion string `json:"go_version,omitempty"`
+
+	// update
+	Update *DaemonUpdate `json:"update,omitempty"`
+
 	// version
 	Version string `json:"version,omitempty"`
 }
 
 // Validate validates this version response data
 func (m *VersionResponseData) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateUpdate(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *VersionResponseData) validateUpdate(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Update) { // not required
+		return nil
+	}
+
+	if m.Update != nil {
+		if err := m.Update.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("data" + "." + "update")
+			}
+			return err
+		}
+	}
+
 	return nil
 }
 
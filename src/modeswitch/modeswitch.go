@@ -0,0 +1,138 @@
+// Package modeswitch switches the daemon between a USB device and the emulator at runtime, so firmware developers
+// can alternate between real and emulated devices without restarting the daemon.
+package modeswitch
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+var logger = logging.MustGetLogger("modeswitch")
+
+// NewDriverFunc creates the driver of a mode
+type NewDriverFunc func(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error)
+
+// NewDriver creates the driver of the hardware wallet library for mode
+func NewDriver(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error) {
+	drv, err := skyWallet.NewDriver(mode)
+	if err != nil {
+		return nil, err
+	}
+	return drv, nil
+}
+
+// TransportName names the transport to the device of mode: libusb, hidapi or udp
+func TransportName(mode skyWallet.DeviceType) string {
+	switch {
+	case mode == skyWallet.DeviceTypeEmulator:
+		return "udp"
+	case usb.HIDUse:
+		return "hidapi"
+	default:
+		return "libusb"
+	}
+}
+
+// Switch is a device driver delegating to the driver of the current mode.
+// The operations in progress when the mode is switched fail as if the device was unplugged.
+type Switch struct {
+	mu        sync.RWMutex
+	driver    skyWallet.DeviceDriver
+	newDriver NewDriverFunc
+	onSwitch  func(mode skyWallet.DeviceType)
+}
+
+var _ skyWallet.DeviceDriver = (*Switch)(nil)
+
+// New creates a Switch of the mode of driver, newDriver creates the driver of the other modes
+func New(driver skyWallet.DeviceDriver, newDriver NewDriverFunc) *Switch {
+	return &Switch{
+		driver:    driver,
+		newDriver: newDriver,
+	}
+}
+
+// Mode returns the current mode
+func (s *Switch) Mode() skyWallet.DeviceType {
+	return s.current().DeviceType()
+}
+
+// OnSwitch sets the function called once the mode is switched, such as to clear what was read from the device of
+// the previous mode. It is called before the messages are sent to the new mode, and must not call the Switch.
+func (s *Switch) OnSwitch(fn func(mode skyWallet.DeviceType)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSwitch = fn
+}
+
+// Set switches to mode, closing the driver of the previous mode. Returns false if mode was already set.
+func (s *Switch) Set(mode skyWallet.DeviceType) (bool, error) {
+	if mode != skyWallet.DeviceTypeUSB && mode != skyWallet.DeviceTypeEmulator {
+		return false, fmt.Errorf("invalid mode %s, must be USB or EMULATOR", mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.driver
+	if previous.DeviceType() == mode {
+		return false, nil
+	}
+
+	driver, err := s.newDriver(mode)
+	if err != nil {
+		return false, fmt.Errorf("failed to create the %s driver: %v", mode, err)
+	}
+
+	s.driver = driver
+	previous.Close()
+
+	logger.Infof("Switched from %s to %s mode", previous.DeviceType(), mode)
+	if s.onSwitch != nil {
+		s.onSwitch(mode)
+	}
+	return true, nil
+}
+
+// current returns the driver of the current mode
+func (s *Switch) current() skyWallet.DeviceDriver {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.driver
+}
+
+// SendToDevice implements skyWallet.DeviceDriver
+func (s *Switch) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return s.current().SendToDevice(dev, chunks)
+}
+
+// SendToDeviceNoAnswer implements skyWallet.DeviceDriver
+func (s *Switch) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return s.current().SendToDeviceNoAnswer(dev, chunks)
+}
+
+// GetDevice implements skyWallet.DeviceDriver
+func (s *Switch) GetDevice() (usb.Device, error) {
+	return s.current().GetDevice()
+}
+
+// GetDeviceInfos implements skyWallet.DeviceDriver
+func (s *Switch) GetDeviceInfos() ([]usb.Info, error) {
+	return s.current().GetDeviceInfos()
+}
+
+// DeviceType implements skyWallet.DeviceDriver
+func (s *Switch) DeviceType() skyWallet.DeviceType {
+	return s.Mode()
+}
+
+// Close implements skyWallet.DeviceDriver
+func (s *Switch) Close() {
+	s.current().Close()
+}
@@ -0,0 +1,115 @@
+package modeswitch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+type fakeDriver struct {
+	mode   skyWallet.DeviceType
+	closed bool
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return wire.Message{Kind: uint16(d.mode)}, nil
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return nil
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return nil, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return []usb.Info{{Path: d.mode.String()}}, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return d.mode
+}
+
+func (d *fakeDriver) Close() {
+	d.closed = true
+}
+
+func TestSwitch(t *testing.T) {
+	var created []*fakeDriver
+	newDriver := func(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error) {
+		drv := &fakeDriver{mode: mode}
+		created = append(created, drv)
+		return drv, nil
+	}
+
+	usbDriver := &fakeDriver{mode: skyWallet.DeviceTypeUSB}
+	s := New(usbDriver, newDriver)
+	var switched []skyWallet.DeviceType
+	s.OnSwitch(func(mode skyWallet.DeviceType) {
+		switched = append(switched, mode)
+	})
+	require.Equal(t, skyWallet.DeviceTypeUSB, s.Mode())
+	require.Equal(t, skyWallet.DeviceTypeUSB, s.DeviceType())
+
+	changed, err := s.Set(skyWallet.DeviceTypeUSB)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Empty(t, created)
+
+	changed, err = s.Set(skyWallet.DeviceTypeEmulator)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, skyWallet.DeviceTypeEmulator, s.Mode())
+	require.True(t, usbDriver.closed)
+	require.Len(t, created, 1)
+
+	// the calls go to the driver of the current mode
+	msg, err := s.SendToDevice(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint16(skyWallet.DeviceTypeEmulator), msg.Kind)
+	infos, err := s.GetDeviceInfos()
+	require.NoError(t, err)
+	require.Equal(t, skyWallet.DeviceTypeEmulator.String(), infos[0].Path)
+
+	changed, err = s.Set(skyWallet.DeviceTypeUSB)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, created[0].closed)
+	require.Equal(t, skyWallet.DeviceTypeUSB, s.Mode())
+
+	// the mode already set is not switched again
+	_, err = s.Set(skyWallet.DeviceTypeUSB)
+	require.NoError(t, err)
+	require.Equal(t, []skyWallet.DeviceType{skyWallet.DeviceTypeEmulator, skyWallet.DeviceTypeUSB}, switched)
+
+	_, err = s.Set(skyWallet.DeviceTypeInvalid)
+	require.EqualError(t, err, "invalid mode Invalid, must be USB or EMULATOR")
+
+	s.Close()
+	require.True(t, created[1].closed)
+}
+
+func TestSwitchDriverError(t *testing.T) {
+	usbDriver := &fakeDriver{mode: skyWallet.DeviceTypeUSB}
+	s := New(usbDriver, func(mode skyWallet.DeviceType) (skyWallet.DeviceDriver, error) {
+		return nil, errors.New("port in use")
+	})
+
+	_, err := s.Set(skyWallet.DeviceTypeEmulator)
+	require.EqualError(t, err, "failed to create the EMULATOR driver: port in use")
+
+	// the previous mode is kept
+	require.Equal(t, skyWallet.DeviceTypeUSB, s.Mode())
+	require.False(t, usbDriver.closed)
+}
+
+func TestTransportName(t *testing.T) {
+	require.Equal(t, "udp", TransportName(skyWallet.DeviceTypeEmulator))
+	require.Contains(t, []string{"libusb", "hidapi"}, TransportName(skyWallet.DeviceTypeUSB))
+}
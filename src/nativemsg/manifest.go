@@ -0,0 +1,248 @@
+package nativemsg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// description is shown by the browsers, in the permissions of the extensions using the host for instance
+const description = "Skywallet hardware wallet daemon"
+
+// goos is the platform the manifests are installed for
+var goos = runtime.GOOS
+
+// chromeOrigin matches the origins of the Chrome extensions, the only origins the Chrome manifests accept
+var chromeOrigin = regexp.MustCompile("^chrome-extension://[a-p]{32}/$")
+
+// Manifest is the manifest of the native messaging host, telling the browser how to start it
+type Manifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Path is the absolute path of the program started by the browser
+	Path string `json:"path"`
+	Type string `json:"type"`
+	// AllowedOrigins are the Chrome extensions allowed to connect to the host
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	// AllowedExtensions are the Firefox extensions allowed to connect to the host
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
+
+// Extensions are the extensions allowed to connect to the host
+type Extensions struct {
+	// ChromeOrigins are the origins of the Chrome and Chromium extensions, chrome-extension://<id>/
+	ChromeOrigins []string
+	// FirefoxIDs are the IDs of the Firefox extensions
+	FirefoxIDs []string
+}
+
+// ParseExtensions parses the comma separated extensions, the chrome-extension:// origins are Chrome extensions
+// and the others are the IDs of Firefox extensions
+func ParseExtensions(s string) (Extensions, error) {
+	var e Extensions
+	for _, x := range strings.Split(s, ",") {
+		x = strings.TrimSpace(x)
+		switch {
+		case x == "":
+		case strings.HasPrefix(x, "chrome-extension://"):
+			if !chromeOrigin.MatchString(x) {
+				return Extensions{}, fmt.Errorf("invalid Chrome extension origin %q, must be chrome-extension://<id>/", x)
+			}
+			e.ChromeOrigins = append(e.ChromeOrigins, x)
+		default:
+			e.FirefoxIDs = append(e.FirefoxIDs, x)
+		}
+	}
+	return e, nil
+}
+
+// browser is a browser the manifests are installed for
+type browser struct {
+	name    string
+	firefox bool
+	// dirs are the directories of the user manifests, relative to the home directory, by platform
+	dirs map[string]string
+	// registryKey is the key of the user manifest on Windows
+	registryKey string
+}
+
+var browsers = []browser{
+	{
+		name: "Chrome",
+		dirs: map[string]string{
+			"linux":  ".config/google-chrome/NativeMessagingHosts",
+			"darwin": "Library/Application Support/Google/Chrome/NativeMessagingHosts",
+		},
+		registryKey: `HKCU\Software\Google\Chrome\NativeMessagingHosts\` + HostName,
+	},
+	{
+		name: "Chromium",
+		dirs: map[string]string{
+			"linux":  ".config/chromium/NativeMessagingHosts",
+			"darwin": "Library/Application Support/Chromium/NativeMessagingHosts",
+		},
+		registryKey: `HKCU\Software\Chromium\NativeMessagingHosts\` + HostName,
+	},
+	{
+		name:    "Firefox",
+		firefox: true,
+		dirs: map[string]string{
+			"linux":  ".mozilla/native-messaging-hosts",
+			"darwin": "Library/Application Support/Mozilla/NativeMessagingHosts",
+		},
+		registryKey: `HKCU\Software\Mozilla\NativeMessagingHosts\` + HostName,
+	},
+}
+
+// manifest returns the manifest of the browser starting launcher, ok is false if no extension of the browser is allowed
+func (b browser) manifest(launcher string, e Extensions) (m Manifest, ok bool) {
+	m = Manifest{
+		Name:        HostName,
+		Description: description,
+		Path:        launcher,
+		Type:        "stdio",
+	}
+	if b.firefox {
+		m.AllowedExtensions = e.FirefoxIDs
+		return m, len(e.FirefoxIDs) > 0
+	}
+	m.AllowedOrigins = e.ChromeOrigins
+	return m, len(e.ChromeOrigins) > 0
+}
+
+// manifestPath returns the path of the manifest of the browser. The Windows manifests are written in dir,
+// their path is registered in the registry.
+func (b browser) manifestPath(home, dir string) string {
+	if goos == "windows" {
+		return filepath.Join(dir, fmt.Sprintf("%s.%s.json", HostName, strings.ToLower(b.name)))
+	}
+	return filepath.Join(home, filepath.FromSlash(b.dirs[goos]), HostName+".json")
+}
+
+// LauncherPath returns the path of the launcher written in dir
+func LauncherPath(dir string) string {
+	if goos == "windows" {
+		return filepath.Join(dir, "skyhwd-native-messaging.bat")
+	}
+	return filepath.Join(dir, "skyhwd-native-messaging.sh")
+}
+
+// Install writes the launcher starting exe with args in dir, and installs the manifests of the browsers
+// the extensions are allowed for, for the user with the home directory. It returns the paths of the manifests.
+// The browsers pass their own arguments to the host, the launcher runs exe with args instead.
+func Install(home, dir, exe string, args []string, e Extensions) ([]string, error) {
+	if goos != "windows" && goos != "linux" && goos != "darwin" {
+		return nil, fmt.Errorf("native messaging is not supported on %s", goos)
+	}
+	if len(e.ChromeOrigins) == 0 && len(e.FirefoxIDs) == 0 {
+		return nil, errors.New("no extension is allowed to connect")
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	launcher := LauncherPath(dir)
+	if err := writeLauncher(launcher, exe, args); err != nil {
+		return nil, fmt.Errorf("failed to write the launcher: %v", err)
+	}
+
+	var paths []string
+	for _, b := range browsers {
+		m, ok := b.manifest(launcher, e)
+		if !ok {
+			continue
+		}
+
+		path := b.manifestPath(home, dir)
+		if err := writeManifest(path, m); err != nil {
+			return nil, fmt.Errorf("failed to install the %s manifest: %v", b.name, err)
+		}
+
+		if goos == "windows" {
+			if err := reg("add", b.registryKey, "/ve", "/t", "REG_SZ", "/d", path, "/f"); err != nil {
+				return nil, fmt.Errorf("failed to register the %s manifest: %v", b.name, err)
+			}
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// Uninstall removes the manifests and the launcher installed by Install, it returns the paths of the removed manifests
+func Uninstall(home, dir string) ([]string, error) {
+	var paths []string
+	for _, b := range browsers {
+		path := b.manifestPath(home, dir)
+		switch err := os.Remove(path); {
+		case err == nil:
+			paths = append(paths, path)
+		case os.IsNotExist(err):
+			continue
+		default:
+			return nil, err
+		}
+
+		if goos == "windows" {
+			if err := reg("delete", b.registryKey, "/f"); err != nil {
+				return nil, fmt.Errorf("failed to unregister the %s manifest: %v", b.name, err)
+			}
+		}
+	}
+
+	if err := os.Remove(LauncherPath(dir)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+func writeManifest(path string, m Manifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// writeLauncher writes the script running exe with args
+func writeLauncher(path, exe string, args []string) error {
+	cmd := append([]string{exe}, args...)
+
+	var script string
+	if goos == "windows" {
+		for i, a := range cmd {
+			cmd[i] = `"` + strings.Replace(a, "%", "%%", -1) + `"`
+		}
+		script = "@echo off\r\n" + strings.Join(cmd, " ") + "\r\n"
+	} else {
+		for i, a := range cmd {
+			cmd[i] = "'" + strings.Replace(a, "'", `'\''`, -1) + "'"
+		}
+		script = "#!/bin/sh\n# started by the browser for the " + HostName + " native messaging host\nexec " + strings.Join(cmd, " ") + "\n"
+	}
+
+	return ioutil.WriteFile(path, []byte(script), 0755)
+}
+
+// reg runs the Windows registry tool
+func reg(args ...string) error {
+	out, err := exec.Command("reg", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package nativemsg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const chromeExtension = "chrome-extension://knldjmfmopnpolahpmmgbagdohdnhkik/"
+
+func TestParseExtensions(t *testing.T) {
+	e, err := ParseExtensions(chromeExtension + ", wallet@skycoin.com,")
+	require.NoError(t, err)
+	require.Equal(t, Extensions{
+		ChromeOrigins: []string{chromeExtension},
+		FirefoxIDs:    []string{"wallet@skycoin.com"},
+	}, e)
+
+	e, err = ParseExtensions("")
+	require.NoError(t, err)
+	require.Equal(t, Extensions{}, e)
+
+	_, err = ParseExtensions("chrome-extension://*/")
+	require.Equal(t, `invalid Chrome extension origin "chrome-extension://*/", must be chrome-extension://<id>/`, err.Error())
+}
+
+func TestInstall(t *testing.T) {
+	defer func(g string) {
+		goos = g
+	}(goos)
+	goos = "linux"
+
+	home, err := ioutil.TempDir("", "nativemsg")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	dir := filepath.Join(home, ".skycoin", "native-messaging")
+
+	_, err = Install(home, dir, "/usr/bin/skyhwd", nil, Extensions{})
+	require.Equal(t, "no extension is allowed to connect", err.Error())
+
+	paths, err := Install(home, dir, "/usr/bin/skyhwd", []string{"-data-dir=/home/user/it's"}, Extensions{
+		ChromeOrigins: []string{chromeExtension},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(home, ".config/google-chrome/NativeMessagingHosts/com.skycoin.skyhwd.json"),
+		filepath.Join(home, ".config/chromium/NativeMessagingHosts/com.skycoin.skyhwd.json"),
+	}, paths)
+
+	launcher := filepath.Join(dir, "skyhwd-native-messaging.sh")
+	script, err := ioutil.ReadFile(launcher)
+	require.NoError(t, err)
+	require.Equal(t, `#!/bin/sh
+# started by the browser for the com.skycoin.skyhwd native messaging host
+exec '/usr/bin/skyhwd' '-data-dir=/home/user/it'\''s'
+`, string(script))
+
+	info, err := os.Stat(launcher)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	data, err := ioutil.ReadFile(paths[0])
+	require.NoError(t, err)
+	var m Manifest
+	require.NoError(t, json.Unmarshal(data, &m))
+	require.Equal(t, Manifest{
+		Name:           HostName,
+		Description:    description,
+		Path:           launcher,
+		Type:           "stdio",
+		AllowedOrigins: []string{chromeExtension},
+	}, m)
+
+	paths, err = Install(home, dir, "/usr/bin/skyhwd", nil, Extensions{
+		FirefoxIDs: []string{"wallet@skycoin.com"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(home, ".mozilla/native-messaging-hosts/com.skycoin.skyhwd.json")}, paths)
+
+	data, err = ioutil.ReadFile(paths[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"name": "com.skycoin.skyhwd",
+		"description": "Skywallet hardware wallet daemon",
+		"path": "`+launcher+`",
+		"type": "stdio",
+		"allowed_extensions": ["wallet@skycoin.com"]
+	}`, string(data))
+
+	paths, err = Uninstall(home, dir)
+	require.NoError(t, err)
+	require.Len(t, paths, 3)
+
+	_, err = os.Stat(launcher)
+	require.True(t, os.IsNotExist(err))
+
+	paths, err = Uninstall(home, dir)
+	require.NoError(t, err)
+	require.Empty(t, paths)
+}
+
+func TestWindowsLauncher(t *testing.T) {
+	defer func(g string) {
+		goos = g
+	}(goos)
+	goos = "windows"
+
+	dir, err := ioutil.TempDir("", "nativemsg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	launcher := LauncherPath(dir)
+	require.Equal(t, filepath.Join(dir, "skyhwd-native-messaging.bat"), launcher)
+	require.NoError(t, writeLauncher(launcher, `C:\Program Files\skyhwd\skyhwd.exe`, []string{"-native-messaging", `-data-dir=%APPDATA%\skyhwd`}))
+
+	script, err := ioutil.ReadFile(launcher)
+	require.NoError(t, err)
+	require.Equal(t, "@echo off\r\n\"C:\\Program Files\\skyhwd\\skyhwd.exe\" \"-native-messaging\" \"-data-dir=%%APPDATA%%\\skyhwd\"\r\n", string(script))
+}
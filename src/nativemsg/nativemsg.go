@@ -0,0 +1,286 @@
+// Package nativemsg serves the API to browser extensions with the native messaging protocol of Chrome and Firefox,
+// so the extension wallets use the device without the daemon opening a TCP port.
+//
+// The browser starts the daemon when an extension connects to HostName, and exchanges JSON messages on the stdin
+// and stdout of the daemon, each one preceded by its length as a 32-bit unsigned integer in native byte order.
+// The extension sends Request messages, the daemon answers each one with a Response carrying its ID.
+package nativemsg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+const (
+	// HostName is the name of the native messaging host the extensions connect to
+	HostName = "com.skycoin.skyhwd"
+
+	// MaxMessageSize is the size limit of the messages sent to the browser
+	MaxMessageSize = 1 << 20
+	// RequestTimeout is how long a request is served, including the confirmations on the device
+	RequestTimeout = 5 * time.Minute
+
+	maxRequestSize = 4 << 20
+	// streamEndpoint is the event stream, it does not end so its response cannot be sent in a message
+	streamEndpoint = "/events"
+)
+
+var (
+	logger = logging.MustGetLogger("nativemsg")
+
+	// ErrTooLarge is returned for a message over MaxMessageSize
+	ErrTooLarge = fmt.Errorf("message larger than %d bytes", MaxMessageSize)
+
+	// byteOrder is the byte order of the message lengths, the native byte order of the platforms the daemon is built for
+	byteOrder = binary.LittleEndian
+)
+
+// Request is a message of an extension, a request to the API
+type Request struct {
+	// ID is chosen by the extension, the response carries the ID of its request
+	ID     string            `json:"id"`
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Header map[string]string `json:"header,omitempty"`
+	// Body is the JSON body of the request
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is a message to an extension, the response of the API to a request
+type Response struct {
+	ID     string            `json:"id"`
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	// Body is the JSON response of the API
+	Body json.RawMessage `json:"body,omitempty"`
+	// Error is set when the request was not served
+	Error string `json:"error,omitempty"`
+}
+
+// ReadMessage reads a message of the browser, it returns io.EOF when the browser disconnects the extension
+func ReadMessage(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, byteOrder, &size); err != nil {
+		return nil, err
+	}
+	if size > maxRequestSize {
+		return nil, fmt.Errorf("message of %d bytes larger than %d bytes", size, maxRequestSize)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteMessage writes v as a message to the browser
+func WriteMessage(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMessageSize {
+		return ErrTooLarge
+	}
+
+	msg := make([]byte, 4+len(data))
+	byteOrder.PutUint32(msg, uint32(len(data)))
+	copy(msg[4:], data)
+
+	_, err = w.Write(msg)
+	return err
+}
+
+// Serve serves the requests read from in with handler as if they were sent to host, writing the responses to out.
+// It returns nil once in is closed, when the browser disconnects the extension, or once quit is closed.
+func Serve(handler http.Handler, host string, in io.Reader, out io.Writer, quit <-chan struct{}) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// the requests in progress are canceled when the extension disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := make(chan []byte)
+	errC := make(chan error, 1)
+	go func() {
+		for {
+			data, err := ReadMessage(in)
+			if err != nil {
+				errC <- err
+				return
+			}
+
+			select {
+			case messages <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	write := func(rsp Response) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		err := WriteMessage(out, rsp)
+		if err == ErrTooLarge {
+			err = WriteMessage(out, Response{
+				ID:     rsp.ID,
+				Status: http.StatusInternalServerError,
+				Error:  "response too large, " + err.Error(),
+			})
+		}
+		if err != nil {
+			logger.WithError(err).Error("Failed to write the native messaging response")
+		}
+	}
+
+	logger.Info("Serving the API with native messaging")
+
+	for {
+		select {
+		case <-quit:
+			return nil
+		case err := <-errC:
+			if err == io.EOF {
+				logger.Info("The browser disconnected the extension")
+				return nil
+			}
+			return err
+		case data := <-messages:
+			var req Request
+			if err := json.Unmarshal(data, &req); err != nil {
+				write(Response{
+					Status: http.StatusBadRequest,
+					Error:  fmt.Sprintf("invalid message: %v", err),
+				})
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rsp := handle(ctx, handler, host, req)
+				rsp.ID = req.ID
+				write(rsp)
+			}()
+		}
+	}
+}
+
+// handle serves the request with handler
+func handle(ctx context.Context, handler http.Handler, host string, req Request) Response {
+	if err := check(req); err != nil {
+		logger.WithError(err).Errorf("Refused native messaging request %s %s", req.Method, req.Path)
+		return Response{
+			Status: http.StatusBadRequest,
+			Error:  err.Error(),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+
+	hr, err := http.NewRequest(req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return Response{
+			Status: http.StatusBadRequest,
+			Error:  err.Error(),
+		}
+	}
+	if len(req.Body) > 0 {
+		hr.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.Header {
+		hr.Header.Set(k, v)
+	}
+	hr.Host = host
+	hr.RemoteAddr = "native-messaging"
+
+	w := newResponseBuffer()
+	handler.ServeHTTP(w, hr.WithContext(ctx))
+
+	body := w.body.Bytes()
+	if len(body) > 0 && !json.Valid(body) {
+		return Response{
+			Status: http.StatusInternalServerError,
+			Error:  fmt.Sprintf("the response of %s is not JSON, it cannot be sent with native messaging", req.Path),
+		}
+	}
+
+	rsp := Response{
+		Status: w.status,
+		Header: make(map[string]string, len(w.header)),
+		Body:   body,
+	}
+	for k := range w.header {
+		rsp.Header[k] = w.header.Get(k)
+	}
+	return rsp
+}
+
+// check refuses the requests which are not API requests, and the event stream
+func check(req Request) error {
+	path := req.Path
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	var endpoint string
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		if strings.HasPrefix(path, prefix+"/") {
+			endpoint = strings.TrimPrefix(path, prefix)
+			break
+		}
+	}
+	if endpoint == "" {
+		return fmt.Errorf("%s is not an API endpoint", path)
+	}
+	if endpoint == streamEndpoint {
+		return errors.New("the event stream is not served with native messaging")
+	}
+	return nil
+}
+
+// responseBuffer records the response of the API to a request
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
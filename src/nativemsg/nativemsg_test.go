@@ -0,0 +1,185 @@
+package nativemsg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const apiHost = "127.0.0.1:9510"
+
+func TestMessage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMessage(&buf, map[string]string{"id": "1"}))
+	require.Equal(t, []byte{10, 0, 0, 0}, buf.Bytes()[:4])
+
+	data, err := ReadMessage(&buf)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"1"}`, string(data))
+
+	_, err = ReadMessage(&buf)
+	require.Equal(t, io.EOF, err)
+
+	// the message is truncated
+	buf.Write([]byte{10, 0, 0, 0, '{'})
+	_, err = ReadMessage(&buf)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	buf.Reset()
+	buf.Write([]byte{0, 0, 0, 1})
+	_, err = ReadMessage(&buf)
+	require.Equal(t, "message of 16777216 bytes larger than 4194304 bytes", err.Error())
+
+	err = WriteMessage(&buf, strings.Repeat("a", MaxMessageSize))
+	require.Equal(t, ErrTooLarge, err)
+}
+
+func TestServe(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, apiHost, req.Host)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch req.URL.Path {
+		case "/api/v1/features":
+			w.Write([]byte(`{"data":"` + req.Method + " " + req.URL.RequestURI() + `"}`)) // nolint: errcheck
+		case "/api/v1/generate_addresses":
+			var body map[string]int
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+			require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+			w.Write([]byte(`{"data":["address"]}`)) // nolint: errcheck
+		case "/api/v1/metrics":
+			w.Write([]byte("skyhwd_requests_total 1")) // nolint: errcheck
+		case "/api/v1/large":
+			w.Write([]byte(`"` + strings.Repeat("a", MaxMessageSize) + `"`)) // nolint: errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"404 Not Found","code":404}}`)) // nolint: errcheck
+		}
+	})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	done := make(chan error)
+	go func() {
+		done <- Serve(api, apiHost, inR, outW, nil)
+	}()
+
+	send := func(req interface{}) Response {
+		go func() {
+			require.NoError(t, WriteMessage(inW, req))
+		}()
+
+		data, err := ReadMessage(outR)
+		require.NoError(t, err)
+
+		var rsp Response
+		require.NoError(t, json.Unmarshal(data, &rsp))
+		return rsp
+	}
+
+	rsp := send(Request{
+		ID:     "1",
+		Method: http.MethodGet,
+		Path:   "/api/v1/features?verbose=1",
+	})
+	require.Equal(t, "1", rsp.ID)
+	require.Equal(t, http.StatusOK, rsp.Status)
+	require.Equal(t, "application/json", rsp.Header["Content-Type"])
+	require.Equal(t, `{"data":"GET /api/v1/features?verbose=1"}`, string(rsp.Body))
+
+	rsp = send(Request{
+		ID:     "2",
+		Method: http.MethodPost,
+		Path:   "/api/v1/generate_addresses",
+		Body:   json.RawMessage(`{"address_n":1}`),
+	})
+	require.Equal(t, http.StatusOK, rsp.Status)
+	require.Equal(t, `{"data":["address"]}`, string(rsp.Body))
+
+	rsp = send(Request{
+		ID:     "3",
+		Method: http.MethodGet,
+		Path:   "/api/v1/wipe",
+	})
+	require.Equal(t, http.StatusNotFound, rsp.Status)
+	require.Equal(t, `{"error":{"message":"404 Not Found","code":404}}`, string(rsp.Body))
+
+	rsp = send(Request{
+		ID:     "4",
+		Method: http.MethodGet,
+		Path:   "/api/v1/events",
+	})
+	require.Equal(t, http.StatusBadRequest, rsp.Status)
+	require.Equal(t, "the event stream is not served with native messaging", rsp.Error)
+
+	rsp = send(Request{
+		ID:     "4b",
+		Method: http.MethodGet,
+		Path:   "/api/v2/events",
+	})
+	require.Equal(t, http.StatusBadRequest, rsp.Status)
+	require.Equal(t, "the event stream is not served with native messaging", rsp.Error)
+
+	rsp = send(Request{
+		ID:     "5",
+		Method: http.MethodGet,
+		Path:   "/live",
+	})
+	require.Equal(t, http.StatusBadRequest, rsp.Status)
+	require.Equal(t, "/live is not an API endpoint", rsp.Error)
+
+	rsp = send(Request{
+		ID:     "6",
+		Method: http.MethodGet,
+		Path:   "/api/v1/metrics",
+	})
+	require.Equal(t, http.StatusInternalServerError, rsp.Status)
+	require.Equal(t, "the response of /api/v1/metrics is not JSON, it cannot be sent with native messaging", rsp.Error)
+
+	rsp = send(Request{
+		ID:     "7",
+		Method: http.MethodGet,
+		Path:   "/api/v1/large",
+	})
+	require.Equal(t, "7", rsp.ID)
+	require.Equal(t, http.StatusInternalServerError, rsp.Status)
+	require.Equal(t, "response too large, message larger than 1048576 bytes", rsp.Error)
+
+	rsp = send("not a request")
+	require.Equal(t, http.StatusBadRequest, rsp.Status)
+	require.Equal(t, "invalid message: json: cannot unmarshal string into Go value of type nativemsg.Request", rsp.Error)
+
+	// the browser disconnects the extension
+	require.NoError(t, inW.Close())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
+
+func TestServeQuit(t *testing.T) {
+	inR, _ := io.Pipe()
+
+	quit := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- Serve(http.NotFoundHandler(), apiHost, inR, &bytes.Buffer{}, quit)
+	}()
+
+	close(quit)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
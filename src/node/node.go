@@ -0,0 +1,347 @@
+// Package node queries the balance, the unconfirmed outputs and the transactions of addresses from a Skycoin node or
+// explorer, so the thin wallets only talk to the daemon. The answers are cached for a while and the queries sent to
+// the node are rate limited, a wallet polling its addresses does not flood the node.
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// MaxAddresses is the number of addresses of a query
+	MaxAddresses = 100
+
+	// DefaultCacheTTL is how long an answer of the node is cached by default
+	DefaultCacheTTL = 10 * time.Second
+	// DefaultRate is the number of queries per second sent to the node by default
+	DefaultRate = 2
+	// DefaultBurst is the number of queries sent to the node at once by default
+	DefaultBurst = 10
+	// DefaultTimeout is how long the node has to answer by default
+	DefaultTimeout = 10 * time.Second
+
+	// maxResponseSize is the size of an answer of the node read
+	maxResponseSize = 4 * 1024 * 1024
+	// maxErrorSize is the size of an error answer of the node returned in the error
+	maxErrorSize = 256
+)
+
+var (
+	// ErrNoAddresses is returned when a query has no address
+	ErrNoAddresses = errors.New("no address")
+	// ErrTooManyAddresses is returned when a query has more than MaxAddresses addresses
+	ErrTooManyAddresses = fmt.Errorf("more than %d addresses", MaxAddresses)
+)
+
+// RateLimitError is returned when the query is not sent to the node because of the rate limit
+type RateLimitError struct {
+	// Wait is the time until a query can be sent
+	Wait time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return "too many queries sent to the node"
+}
+
+// Config configures a Client
+type Config struct {
+	// URL is the URL of the node or explorer, serving the node API under /api/v1
+	URL string
+	// CacheTTL is how long an answer is cached, 0 means DefaultCacheTTL
+	CacheTTL time.Duration
+	// Rate is the number of queries per second sent to the node, 0 means DefaultRate
+	Rate float64
+	// Burst is the number of queries sent to the node at once, 0 means DefaultBurst
+	Burst int
+	// Timeout is how long the node has to answer, 0 means DefaultTimeout
+	Timeout time.Duration
+	// Transport sends the requests, nil means http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Balance is an amount of coins, in droplets, and coin hours
+type Balance struct {
+	Coins uint64 `json:"coins"`
+	Hours uint64 `json:"hours"`
+}
+
+// BalancePair is the balance of the confirmed outputs and the balance predicted once the unconfirmed
+// transactions are confirmed
+type BalancePair struct {
+	Confirmed Balance `json:"confirmed"`
+	Predicted Balance `json:"predicted"`
+}
+
+// UnspentOutput is an unspent output, as returned by the node
+type UnspentOutput struct {
+	Hash              string `json:"hash"`
+	Time              uint64 `json:"time"`
+	BlockSeq          uint64 `json:"block_seq"`
+	SourceTransaction string `json:"src_tx"`
+	Address           string `json:"address"`
+	Coins             string `json:"coins"`
+	Hours             uint64 `json:"hours"`
+	CalculatedHours   uint64 `json:"calculated_hours"`
+}
+
+// AddressesBalance is the balance of a set of addresses, with their unconfirmed outputs
+type AddressesBalance struct {
+	BalancePair
+	// Addresses are the balances of each address
+	Addresses map[string]BalancePair `json:"addresses"`
+	// IncomingOutputs are the outputs created by the unconfirmed transactions
+	IncomingOutputs []UnspentOutput `json:"incoming_outputs"`
+	// OutgoingOutputs are the outputs spent by the unconfirmed transactions
+	OutgoingOutputs []UnspentOutput `json:"outgoing_outputs"`
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Client queries a node
+type Client struct {
+	config Config
+	client *http.Client
+
+	mu     sync.Mutex
+	cache  map[string]cacheEntry
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// New creates a Client of the node at c.URL
+func New(c Config) (*Client, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node URL: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid node URL %q, must be an http(s) URL", c.URL)
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = DefaultCacheTTL
+	}
+	if c.Rate <= 0 {
+		c.Rate = DefaultRate
+	}
+	if c.Burst <= 0 {
+		c.Burst = DefaultBurst
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	return &Client{
+		config: c,
+		client: &http.Client{
+			Timeout:   c.Timeout,
+			Transport: c.Transport,
+		},
+		cache:  make(map[string]cacheEntry),
+		tokens: float64(c.Burst),
+		now:    time.Now,
+	}, nil
+}
+
+// ParseAddresses returns the sorted, distinct addresses of a comma separated list
+func ParseAddresses(s string) ([]string, error) {
+	return Addresses(strings.Split(s, ","))
+}
+
+// Addresses returns the sorted, distinct addresses of addrs, the empty ones are skipped
+func Addresses(addrs []string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var distinct []string
+	for _, a := range addrs {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if _, ok := seen[a]; ok {
+			continue
+		}
+		if _, err := cipher.DecodeBase58Address(a); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", a, err)
+		}
+		seen[a] = struct{}{}
+		distinct = append(distinct, a)
+	}
+
+	switch {
+	case len(distinct) == 0:
+		return nil, ErrNoAddresses
+	case len(distinct) > MaxAddresses:
+		return nil, ErrTooManyAddresses
+	}
+
+	sort.Strings(distinct)
+	return distinct, nil
+}
+
+// Balance returns the balance of the addresses returned by ParseAddresses, with their unconfirmed outputs.
+// The answer is cached for CacheTTL, a query not cached returns a RateLimitError when the rate limit is exceeded.
+func (c *Client) Balance(addrs []string) (AddressesBalance, error) {
+	key := strings.Join(addrs, ",")
+
+	v, err := c.cached("balance:"+key, func() (interface{}, error) {
+		var b AddressesBalance
+		query := url.Values{
+			"addrs": []string{key},
+		}
+		if err := c.get("/api/v1/balance", query, &b); err != nil {
+			return nil, err
+		}
+
+		var outputs struct {
+			IncomingOutputs []UnspentOutput `json:"incoming_outputs"`
+			OutgoingOutputs []UnspentOutput `json:"outgoing_outputs"`
+		}
+		if err := c.get("/api/v1/outputs", query, &outputs); err != nil {
+			return nil, err
+		}
+		b.IncomingOutputs = outputs.IncomingOutputs
+		b.OutgoingOutputs = outputs.OutgoingOutputs
+		if b.Addresses == nil {
+			b.Addresses = make(map[string]BalancePair)
+		}
+		if b.IncomingOutputs == nil {
+			b.IncomingOutputs = []UnspentOutput{}
+		}
+		if b.OutgoingOutputs == nil {
+			b.OutgoingOutputs = []UnspentOutput{}
+		}
+		return b, nil
+	})
+	if err != nil {
+		return AddressesBalance{}, err
+	}
+
+	return v.(AddressesBalance), nil
+}
+
+// UnspentOutputs returns the unspent outputs of hashes with their coin hours at the head block, the outputs spent or
+// unknown are left out. The answer is cached like the balances.
+func (c *Client) UnspentOutputs(hashes []string) ([]UnspentOutput, error) {
+	key := strings.Join(hashes, ",")
+
+	v, err := c.cached("outputs:"+key, func() (interface{}, error) {
+		var outputs struct {
+			HeadOutputs []UnspentOutput `json:"head_outputs"`
+		}
+		query := url.Values{
+			"hashes": []string{key},
+		}
+		if err := c.get("/api/v1/outputs", query, &outputs); err != nil {
+			return nil, err
+		}
+		return outputs.HeadOutputs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]UnspentOutput), nil
+}
+
+// cached returns the answer cached for key, or the answer of fetch once a query is allowed by the rate limit.
+// The answer is cached for CacheTTL.
+func (c *Client) cached(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	now := c.now()
+	entry, ok := c.cache[key]
+	if ok && now.Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	if wait := c.take(now); wait > 0 {
+		c.mu.Unlock()
+		return nil, RateLimitError{
+			Wait: wait,
+		}
+	}
+	c.mu.Unlock()
+
+	v, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now = c.now()
+	for k, e := range c.cache {
+		if !now.Before(e.expires) {
+			delete(c.cache, k)
+		}
+	}
+	c.cache[key] = cacheEntry{
+		value:   v,
+		expires: now.Add(c.config.CacheTTL),
+	}
+
+	return v, nil
+}
+
+// take takes a token to query the node, returning the time until a token is available if there is none.
+// Must be called with the lock held.
+func (c *Client) take(now time.Time) time.Duration {
+	if !c.last.IsZero() {
+		c.tokens = math.Min(float64(c.config.Burst), c.tokens+now.Sub(c.last).Seconds()*c.config.Rate)
+	}
+	c.last = now
+
+	if c.tokens < 1 {
+		return time.Duration((1 - c.tokens) / c.config.Rate * float64(time.Second))
+	}
+
+	c.tokens--
+	return 0
+}
+
+func (c *Client) get(path string, query url.Values, data interface{}) error {
+	resp, err := c.client.Get(c.config.URL + path + "?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("node request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read the node response: %v", err)
+	}
+	if len(body) > maxResponseSize {
+		return errors.New("node response is too large")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > maxErrorSize {
+			msg = msg[:maxErrorSize]
+		}
+		return fmt.Errorf("node returned %s: %s", resp.Status, msg)
+	}
+
+	if err := json.Unmarshal(body, data); err != nil {
+		return fmt.Errorf("invalid node response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddresses(t *testing.T) {
+	addrs, err := ParseAddresses("2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8, 2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG,2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"}, addrs)
+
+	_, err = ParseAddresses(" , ")
+	require.Equal(t, ErrNoAddresses, err)
+
+	_, err = ParseAddresses("2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvH")
+	require.Error(t, err)
+}
+
+func TestBalance(t *testing.T) {
+	var queries int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", r.URL.Query().Get("addrs"))
+
+		switch r.URL.Path {
+		case "/api/v1/balance":
+			queries++
+			w.Write([]byte(`{
+				"confirmed": {"coins": 2000000, "hours": 10},
+				"predicted": {"coins": 1000000, "hours": 4},
+				"addresses": {
+					"2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8": {
+						"confirmed": {"coins": 2000000, "hours": 10},
+						"predicted": {"coins": 1000000, "hours": 4}
+					}
+				}
+			}`))
+		case "/api/v1/outputs":
+			w.Write([]byte(`{
+				"head_outputs": [],
+				"outgoing_outputs": [{
+					"hash": "c2244e4912330d201d979f80db4df42118e49704e500e2e00a52a61954e8c663",
+					"address": "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8",
+					"coins": "1.000000",
+					"hours": 6
+				}],
+				"incoming_outputs": []
+			}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c, err := New(Config{
+		URL:      server.URL + "/",
+		CacheTTL: time.Minute,
+		Rate:     1,
+		Burst:    1,
+	})
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	c.now = func() time.Time {
+		return now
+	}
+
+	addrs := []string{"2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"}
+	b, err := c.Balance(addrs)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000000), b.Confirmed.Coins)
+	require.Equal(t, uint64(4), b.Addresses["2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"].Predicted.Hours)
+	require.Len(t, b.OutgoingOutputs, 1)
+	require.Empty(t, b.IncomingOutputs)
+
+	// the answer is cached
+	_, err = c.Balance(addrs)
+	require.NoError(t, err)
+	require.Equal(t, 1, queries)
+
+	// once expired, the query waits for the rate limit
+	now = now.Add(time.Minute)
+	c.tokens = 0
+	c.last = now
+	_, err = c.Balance(addrs)
+	require.Equal(t, RateLimitError{Wait: time.Second}, err)
+
+	now = now.Add(time.Second)
+	_, err = c.Balance(addrs)
+	require.NoError(t, err)
+	require.Equal(t, 2, queries)
+
+	_, err = New(Config{URL: "ftp://node.example.com"})
+	require.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package node
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// TransactionStatus is the status of a transaction in the blockchain
+type TransactionStatus struct {
+	Confirmed bool `json:"confirmed"`
+	// Height is the number of blocks since the block of the transaction, including it
+	Height   uint64 `json:"height"`
+	BlockSeq uint64 `json:"block_seq"`
+}
+
+// TransactionOutput is an output of a transaction
+type TransactionOutput struct {
+	Hash    string `json:"uxid"`
+	Address string `json:"dst"`
+	Coins   string `json:"coins"`
+	Hours   uint64 `json:"hours"`
+}
+
+// TransactionBody is a transaction, as returned by the node
+type TransactionBody struct {
+	Length    uint32              `json:"length"`
+	Type      uint8               `json:"type"`
+	Hash      string              `json:"txid"`
+	InnerHash string              `json:"inner_hash"`
+	Timestamp uint64              `json:"timestamp,omitempty"`
+	Sigs      []string            `json:"sigs"`
+	In        []string            `json:"inputs"`
+	Out       []TransactionOutput `json:"outputs"`
+}
+
+// Transaction is a transaction of an address with its status
+type Transaction struct {
+	Status TransactionStatus `json:"status"`
+	// Time is the time of the block of a confirmed transaction, or when an unconfirmed one was received
+	Time uint64          `json:"time"`
+	Txn  TransactionBody `json:"txn"`
+}
+
+// Transactions returns the transactions of the addresses returned by ParseAddresses in chronological order,
+// the confirmed ones by block and the unconfirmed ones last, by the time they were received. A transaction of
+// several addresses is returned once. The answer is cached like the balances.
+func (c *Client) Transactions(addrs []string) ([]Transaction, error) {
+	key := strings.Join(addrs, ",")
+
+	v, err := c.cached("transactions:"+key, func() (interface{}, error) {
+		var txns []Transaction
+		query := url.Values{
+			"addrs": []string{key},
+		}
+		if err := c.get("/api/v1/transactions", query, &txns); err != nil {
+			return nil, err
+		}
+
+		seen := make(map[string]struct{}, len(txns))
+		merged := make([]Transaction, 0, len(txns))
+		for _, txn := range txns {
+			if _, ok := seen[txn.Txn.Hash]; ok {
+				continue
+			}
+			seen[txn.Txn.Hash] = struct{}{}
+			merged = append(merged, txn)
+		}
+
+		sort.SliceStable(merged, func(i, j int) bool {
+			a, b := merged[i].Status, merged[j].Status
+			switch {
+			case a.Confirmed != b.Confirmed:
+				return a.Confirmed
+			case a.Confirmed:
+				return a.BlockSeq < b.BlockSeq
+			default:
+				return merged[i].Time < merged[j].Time
+			}
+		})
+		return merged, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]Transaction), nil
+}
@@ -0,0 +1,35 @@
+package node
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/transactions", r.URL.Path)
+		require.Equal(t, "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG,2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8", r.URL.Query().Get("addrs"))
+		w.Write([]byte(`[
+			{"status": {"confirmed": false}, "time": 1564137900, "txn": {"txid": "c3"}},
+			{"status": {"confirmed": true, "block_seq": 20}, "time": 1564137800, "txn": {"txid": "c2"}},
+			{"status": {"confirmed": true, "block_seq": 10}, "time": 1564137700, "txn": {"txid": "c1"}},
+			{"status": {"confirmed": true, "block_seq": 20}, "time": 1564137800, "txn": {"txid": "c2"}}
+		]`))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{
+		URL: server.URL,
+	})
+	require.NoError(t, err)
+
+	txns, err := c.Transactions([]string{"2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"})
+	require.NoError(t, err)
+	require.Len(t, txns, 3)
+	for i, hash := range []string{"c1", "c2", "c3"} {
+		require.Equal(t, hash, txns[i].Txn.Hash)
+	}
+}
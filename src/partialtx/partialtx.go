@@ -0,0 +1,423 @@
+// Package partialtx is the partially signed Skycoin transaction exchanged by the parties assembling a transaction
+// spending the outputs of several wallets, each party signing the inputs it owns on its own device.
+//
+// A Skycoin address is owned by a single key, so a transaction is fully signed once the owner of every input has
+// signed it. A partial transaction carries the owner address of each input, so the signatures are verified as they
+// are added, and its canonical serialization lets the parties exchange it through their own daemons.
+package partialtx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// Version is the version of the serialization of the partial transactions
+const Version = 1
+
+// magic starts the serialization of a partial transaction
+var magic = []byte("SPST")
+
+const (
+	// MaxInputs and MaxOutputs are the most inputs and outputs of a partial transaction
+	MaxInputs  = 256
+	MaxOutputs = 256
+
+	// addressSize is the size of a serialized address, its version then its key
+	addressSize = 1 + 20
+)
+
+var (
+	// ErrIncomplete is returned when a transaction is serialized before all its inputs are signed
+	ErrIncomplete = errors.New("partial transaction is not fully signed")
+	// ErrMismatch is returned when the partial transactions combined are not the same transaction
+	ErrMismatch = errors.New("partial transactions are not the same transaction")
+)
+
+// Input is an input of a partial transaction
+type Input struct {
+	// Hash is the hash of the unspent output spent by the input
+	Hash cipher.SHA256
+	// Address is the address owning the unspent output, its key signs the input
+	Address cipher.Address
+	// Signature is the signature of the input, null until the owner signs it
+	Signature cipher.Sig
+}
+
+// Signed returns true if the input is signed
+func (in Input) Signed() bool {
+	return !in.Signature.Null()
+}
+
+// Output is an output of a partial transaction
+type Output struct {
+	Address cipher.Address
+	// Coins are the droplets sent to the address
+	Coins uint64
+	Hours uint64
+}
+
+// Transaction is a partially signed transaction
+type Transaction struct {
+	Inputs  []Input
+	Outputs []Output
+}
+
+// New creates an unsigned partial transaction
+func New(inputs []Input, outputs []Output) (*Transaction, error) {
+	txn := &Transaction{
+		Inputs:  make([]Input, len(inputs)),
+		Outputs: append([]Output(nil), outputs...),
+	}
+	for i, in := range inputs {
+		txn.Inputs[i] = Input{
+			Hash:    in.Hash,
+			Address: in.Address,
+		}
+	}
+
+	if err := txn.validate(); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+func (txn *Transaction) validate() error {
+	if len(txn.Inputs) == 0 {
+		return errors.New("inputs are required")
+	}
+	if len(txn.Inputs) > MaxInputs {
+		return fmt.Errorf("too many inputs, at most %d", MaxInputs)
+	}
+	if len(txn.Outputs) == 0 {
+		return errors.New("outputs are required")
+	}
+	if len(txn.Outputs) > MaxOutputs {
+		return fmt.Errorf("too many outputs, at most %d", MaxOutputs)
+	}
+
+	hashes := make(map[cipher.SHA256]struct{}, len(txn.Inputs))
+	for i, in := range txn.Inputs {
+		if in.Hash.Null() {
+			return fmt.Errorf("input %d hash cannot be empty", i)
+		}
+		if _, ok := hashes[in.Hash]; ok {
+			return fmt.Errorf("input %d spends %s twice", i, in.Hash.Hex())
+		}
+		hashes[in.Hash] = struct{}{}
+
+		if in.Address.Null() || in.Address.Version != 0 {
+			return fmt.Errorf("input %d address is invalid", i)
+		}
+	}
+
+	for i, o := range txn.Outputs {
+		if o.Address.Null() || o.Address.Version != 0 {
+			return fmt.Errorf("output %d address is invalid", i)
+		}
+		if o.Coins == 0 {
+			return fmt.Errorf("output %d coins cannot be zero", i)
+		}
+	}
+
+	return nil
+}
+
+// InnerHash returns the hash of the inputs and outputs of the transaction, the same for all the parties
+func (txn *Transaction) InnerHash() cipher.SHA256 {
+	var b bytes.Buffer
+	writeUint32(&b, uint32(len(txn.Inputs)))
+	for _, in := range txn.Inputs {
+		b.Write(in.Hash[:])
+	}
+	txn.writeOutputs(&b)
+	return cipher.SumSHA256(b.Bytes())
+}
+
+// SignatureHash returns the hash signed by the owner of input i
+func (txn *Transaction) SignatureHash(i int) cipher.SHA256 {
+	return cipher.AddSHA256(txn.InnerHash(), txn.Inputs[i].Hash)
+}
+
+// Sign adds the signature of input i, once it is verified with the address of the input
+func (txn *Transaction) Sign(i int, sig cipher.Sig) error {
+	if i < 0 || i >= len(txn.Inputs) {
+		return fmt.Errorf("input %d does not exist", i)
+	}
+	if err := cipher.VerifyAddressSignedHash(txn.Inputs[i].Address, sig, txn.SignatureHash(i)); err != nil {
+		return fmt.Errorf("invalid signature of input %d: %v", i, err)
+	}
+
+	txn.Inputs[i].Signature = sig
+	return nil
+}
+
+// Combine adds the signatures of other, the same transaction signed by another party. The inputs signed by both
+// keep their signature.
+func (txn *Transaction) Combine(other *Transaction) error {
+	if len(txn.Inputs) != len(other.Inputs) || txn.InnerHash() != other.InnerHash() {
+		return ErrMismatch
+	}
+	for i, in := range other.Inputs {
+		if in.Address != txn.Inputs[i].Address {
+			return ErrMismatch
+		}
+	}
+
+	for i, in := range other.Inputs {
+		if !in.Signed() || txn.Inputs[i].Signed() {
+			continue
+		}
+		if err := txn.Sign(i, in.Signature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Signed returns the number of signed inputs
+func (txn *Transaction) Signed() int {
+	var n int
+	for _, in := range txn.Inputs {
+		if in.Signed() {
+			n++
+		}
+	}
+	return n
+}
+
+// Complete returns true if all the inputs are signed
+func (txn *Transaction) Complete() bool {
+	return txn.Signed() == len(txn.Inputs)
+}
+
+// Encode returns the canonical serialization of the partial transaction, hex encoded
+func (txn *Transaction) Encode() string {
+	var b bytes.Buffer
+	b.Write(magic)
+	b.WriteByte(Version)
+
+	writeUint32(&b, uint32(len(txn.Inputs)))
+	for _, in := range txn.Inputs {
+		b.Write(in.Hash[:])
+		writeAddress(&b, in.Address)
+		if in.Signed() {
+			b.WriteByte(1)
+			b.Write(in.Signature[:])
+		} else {
+			b.WriteByte(0)
+		}
+	}
+
+	txn.writeOutputs(&b)
+	return hex.EncodeToString(b.Bytes())
+}
+
+// Decode decodes a partial transaction serialized by Encode, verifying its signatures.
+// Any other serialization of the same transaction is refused, so its encoding is unique.
+func Decode(s string) (*Transaction, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partial transaction: %v", err)
+	}
+
+	txn, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid partial transaction: %v", err)
+	}
+
+	if txn.Encode() != s {
+		return nil, errors.New("invalid partial transaction: not canonically encoded")
+	}
+	return txn, nil
+}
+
+func decode(r *bytes.Reader) (*Transaction, error) {
+	prefix := make([]byte, len(magic)+1)
+	if err := readFull(r, prefix); err != nil || !bytes.Equal(prefix[:len(magic)], magic) {
+		return nil, errors.New("not a partial transaction")
+	}
+	if prefix[len(magic)] != Version {
+		return nil, fmt.Errorf("unsupported version %d", prefix[len(magic)])
+	}
+
+	var txn Transaction
+	n, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxInputs {
+		return nil, fmt.Errorf("too many inputs, at most %d", MaxInputs)
+	}
+	var signatures []cipher.Sig
+	for i := uint32(0); i < n; i++ {
+		var in Input
+		if err := readFull(r, in.Hash[:]); err != nil {
+			return nil, err
+		}
+		if in.Address, err = readAddress(r); err != nil {
+			return nil, err
+		}
+
+		var sig cipher.Sig
+		signed, err := r.ReadByte()
+		switch {
+		case err != nil:
+			return nil, err
+		case signed == 1:
+			if err := readFull(r, sig[:]); err != nil {
+				return nil, err
+			}
+		case signed != 0:
+			return nil, fmt.Errorf("input %d has an invalid signature flag", i)
+		}
+
+		txn.Inputs = append(txn.Inputs, in)
+		signatures = append(signatures, sig)
+	}
+
+	if n, err = readUint32(r); err != nil {
+		return nil, err
+	}
+	if n > MaxOutputs {
+		return nil, fmt.Errorf("too many outputs, at most %d", MaxOutputs)
+	}
+	for i := uint32(0); i < n; i++ {
+		var o Output
+		if o.Address, err = readAddress(r); err != nil {
+			return nil, err
+		}
+		if o.Coins, err = readUint64(r); err != nil {
+			return nil, err
+		}
+		if o.Hours, err = readUint64(r); err != nil {
+			return nil, err
+		}
+		txn.Outputs = append(txn.Outputs, o)
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("trailing data")
+	}
+
+	if err := txn.validate(); err != nil {
+		return nil, err
+	}
+
+	for i, sig := range signatures {
+		if sig.Null() {
+			continue
+		}
+		if err := txn.Sign(i, sig); err != nil {
+			return nil, err
+		}
+	}
+
+	return &txn, nil
+}
+
+// Serialize returns the serialization of the fully signed transaction, as injected in the network
+func (txn *Transaction) Serialize() ([]byte, error) {
+	if !txn.Complete() {
+		return nil, ErrIncomplete
+	}
+
+	innerHash := txn.InnerHash()
+
+	var b bytes.Buffer
+	// the length is set once the transaction is serialized
+	writeUint32(&b, 0)
+	// the transaction type
+	b.WriteByte(0)
+	b.Write(innerHash[:])
+
+	writeUint32(&b, uint32(len(txn.Inputs)))
+	for _, in := range txn.Inputs {
+		b.Write(in.Signature[:])
+	}
+	writeUint32(&b, uint32(len(txn.Inputs)))
+	for _, in := range txn.Inputs {
+		b.Write(in.Hash[:])
+	}
+	txn.writeOutputs(&b)
+
+	data := b.Bytes()
+	binary.LittleEndian.PutUint32(data, uint32(len(data)))
+	return data, nil
+}
+
+// Hash returns the ID of the fully signed transaction
+func (txn *Transaction) Hash() (cipher.SHA256, error) {
+	data, err := txn.Serialize()
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+	return cipher.SumSHA256(data), nil
+}
+
+// writeOutputs writes the outputs as serialized in a transaction, the partial transactions serialize them the same way
+func (txn *Transaction) writeOutputs(b *bytes.Buffer) {
+	writeUint32(b, uint32(len(txn.Outputs)))
+	for _, o := range txn.Outputs {
+		writeAddress(b, o.Address)
+		writeUint64(b, o.Coins)
+		writeUint64(b, o.Hours)
+	}
+}
+
+func writeUint32(b *bytes.Buffer, n uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], n)
+	b.Write(buf[:])
+}
+
+func writeUint64(b *bytes.Buffer, n uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], n)
+	b.Write(buf[:])
+}
+
+// writeAddress writes the version then the key of addr, as serialized in a transaction
+func writeAddress(b *bytes.Buffer, addr cipher.Address) {
+	b.WriteByte(addr.Version)
+	b.Write(addr.Key[:])
+}
+
+func readFull(r *bytes.Reader, b []byte) error {
+	if n, _ := r.Read(b); n != len(b) { // nolint: errcheck
+		return errors.New("unexpected end of data")
+	}
+	return nil
+}
+
+func readUint32(r *bytes.Reader) (uint32, error) {
+	var buf [4]byte
+	if err := readFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+func readUint64(r *bytes.Reader) (uint64, error) {
+	var buf [8]byte
+	if err := readFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+func readAddress(r *bytes.Reader) (cipher.Address, error) {
+	var buf [addressSize]byte
+	if err := readFull(r, buf[:]); err != nil {
+		return cipher.Address{}, err
+	}
+
+	var addr cipher.Address
+	addr.Version = buf[0]
+	copy(addr.Key[:], buf[1:])
+	return addr, nil
+}
@@ -0,0 +1,140 @@
+package partialtx
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+type party struct {
+	address cipher.Address
+	secKey  cipher.SecKey
+}
+
+func newParty(seed string) party {
+	pubKey, secKey := cipher.MustGenerateDeterministicKeyPair([]byte(seed))
+	return party{
+		address: cipher.AddressFromPubKey(pubKey),
+		secKey:  secKey,
+	}
+}
+
+func newTransaction(t *testing.T, alice, bob party) *Transaction {
+	txn, err := New([]Input{
+		{Hash: cipher.SumSHA256([]byte("uxout 1")), Address: alice.address},
+		{Hash: cipher.SumSHA256([]byte("uxout 2")), Address: bob.address},
+		{Hash: cipher.SumSHA256([]byte("uxout 3")), Address: alice.address},
+	}, []Output{
+		{Address: newParty("carol").address, Coins: 10e6, Hours: 5},
+		{Address: alice.address, Coins: 1e6, Hours: 1},
+	})
+	require.NoError(t, err)
+	return txn
+}
+
+func sign(t *testing.T, txn *Transaction, i int, p party) cipher.Sig {
+	sig := cipher.MustSignHash(txn.SignatureHash(i), p.secKey)
+	require.NoError(t, txn.Sign(i, sig))
+	return sig
+}
+
+func TestNew(t *testing.T) {
+	alice := newParty("alice")
+	hash := cipher.SumSHA256([]byte("uxout"))
+	output := Output{Address: alice.address, Coins: 1e6}
+
+	_, err := New([]Input{{Hash: hash, Address: alice.address}}, []Output{output})
+	require.NoError(t, err)
+
+	_, err = New(nil, []Output{output})
+	require.EqualError(t, err, "inputs are required")
+
+	_, err = New([]Input{{Hash: hash, Address: alice.address}}, nil)
+	require.EqualError(t, err, "outputs are required")
+
+	_, err = New([]Input{{Hash: hash}}, []Output{output})
+	require.EqualError(t, err, "input 0 address is invalid")
+
+	_, err = New([]Input{{Hash: hash, Address: alice.address}, {Hash: hash, Address: alice.address}}, []Output{output})
+	require.EqualError(t, err, "input 1 spends "+hash.Hex()+" twice")
+
+	_, err = New([]Input{{Hash: hash, Address: alice.address}}, []Output{{Address: alice.address}})
+	require.EqualError(t, err, "output 0 coins cannot be zero")
+}
+
+func TestEncodeDecode(t *testing.T) {
+	alice, bob := newParty("alice"), newParty("bob")
+	txn := newTransaction(t, alice, bob)
+
+	decoded, err := Decode(txn.Encode())
+	require.NoError(t, err)
+	require.Equal(t, txn, decoded)
+
+	sign(t, txn, 1, bob)
+	s := txn.Encode()
+	decoded, err = Decode(s)
+	require.NoError(t, err)
+	require.Equal(t, txn, decoded)
+	require.Equal(t, 1, decoded.Signed())
+
+	// the serialization is unique
+	_, err = Decode(strings.ToUpper(s))
+	require.EqualError(t, err, "invalid partial transaction: not canonically encoded")
+
+	_, err = Decode(s + "00")
+	require.EqualError(t, err, "invalid partial transaction: trailing data")
+
+	_, err = Decode(s[:len(s)-2])
+	require.EqualError(t, err, "invalid partial transaction: unexpected end of data")
+
+	_, err = Decode("00" + s[2:])
+	require.EqualError(t, err, "invalid partial transaction: not a partial transaction")
+
+	// the signatures are verified
+	forged := newTransaction(t, alice, bob)
+	forged.Inputs[0].Signature = cipher.MustSignHash(forged.SignatureHash(0), bob.secKey)
+	_, err = Decode(forged.Encode())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid partial transaction: invalid signature of input 0")
+}
+
+func TestCombine(t *testing.T) {
+	alice, bob := newParty("alice"), newParty("bob")
+
+	// each party signs its inputs of its own copy
+	aliceTxn := newTransaction(t, alice, bob)
+	sign(t, aliceTxn, 0, alice)
+	sign(t, aliceTxn, 2, alice)
+	require.False(t, aliceTxn.Complete())
+
+	bobTxn := newTransaction(t, alice, bob)
+	require.Error(t, bobTxn.Sign(1, cipher.MustSignHash(bobTxn.SignatureHash(1), alice.secKey)))
+	bobSig := sign(t, bobTxn, 1, bob)
+
+	_, err := aliceTxn.Serialize()
+	require.Equal(t, ErrIncomplete, err)
+
+	require.NoError(t, aliceTxn.Combine(bobTxn))
+	require.True(t, aliceTxn.Complete())
+	require.Equal(t, bobSig, aliceTxn.Inputs[1].Signature)
+
+	// the transactions with other inputs or outputs are not combined
+	other := newTransaction(t, alice, bob)
+	other.Outputs[0].Hours++
+	require.Equal(t, ErrMismatch, aliceTxn.Combine(other))
+
+	data, err := aliceTxn.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, 4+1+32+4+3*65+4+3*32+4+2*37, len(data))
+	require.Equal(t, uint32(len(data)), binary.LittleEndian.Uint32(data))
+
+	innerHash := aliceTxn.InnerHash()
+	require.Equal(t, innerHash[:], data[5:37])
+
+	hash, err := aliceTxn.Hash()
+	require.NoError(t, err)
+	require.Equal(t, cipher.SumSHA256(data), hash)
+}
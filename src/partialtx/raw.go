@@ -0,0 +1,118 @@
+package partialtx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// Deserialize decodes a raw Skycoin transaction, as serialized by Serialize. It may be unsigned, its inputs
+// having null signatures. The address of a signed input is recovered from its signature, the address of an
+// unsigned input is null.
+func Deserialize(data []byte) (*Transaction, error) {
+	txn, err := deserialize(bytes.NewReader(data), len(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction: %v", err)
+	}
+	return txn, nil
+}
+
+func deserialize(r *bytes.Reader, size int) (*Transaction, error) {
+	length, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if int64(length) != int64(size) {
+		return nil, fmt.Errorf("length is %d, the transaction has %d bytes", length, size)
+	}
+
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if kind != 0 {
+		return nil, fmt.Errorf("unsupported type %d", kind)
+	}
+
+	var innerHash cipher.SHA256
+	if err := readFull(r, innerHash[:]); err != nil {
+		return nil, err
+	}
+
+	n, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxInputs {
+		return nil, fmt.Errorf("too many signatures, at most %d", MaxInputs)
+	}
+	signatures := make([]cipher.Sig, n)
+	for i := range signatures {
+		if err := readFull(r, signatures[i][:]); err != nil {
+			return nil, err
+		}
+	}
+
+	if n, err = readUint32(r); err != nil {
+		return nil, err
+	}
+	if int(n) != len(signatures) {
+		return nil, fmt.Errorf("%d signatures for %d inputs", len(signatures), n)
+	}
+
+	var txn Transaction
+	for i := uint32(0); i < n; i++ {
+		var in Input
+		if err := readFull(r, in.Hash[:]); err != nil {
+			return nil, err
+		}
+		txn.Inputs = append(txn.Inputs, in)
+	}
+
+	if n, err = readUint32(r); err != nil {
+		return nil, err
+	}
+	if n > MaxOutputs {
+		return nil, fmt.Errorf("too many outputs, at most %d", MaxOutputs)
+	}
+	for i := uint32(0); i < n; i++ {
+		var o Output
+		if o.Address, err = readAddress(r); err != nil {
+			return nil, err
+		}
+		if o.Coins, err = readUint64(r); err != nil {
+			return nil, err
+		}
+		if o.Hours, err = readUint64(r); err != nil {
+			return nil, err
+		}
+		txn.Outputs = append(txn.Outputs, o)
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("trailing data")
+	}
+
+	if txn.InnerHash() != innerHash {
+		return nil, errors.New("inner hash does not match the inputs and outputs")
+	}
+
+	for i, sig := range signatures {
+		if sig.Null() {
+			continue
+		}
+
+		pubKey, err := cipher.PubKeyFromSig(sig, txn.SignatureHash(i))
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature of input %d: %v", i, err)
+		}
+		txn.Inputs[i].Address = cipher.AddressFromPubKey(pubKey)
+		if err := txn.Sign(i, sig); err != nil {
+			return nil, err
+		}
+	}
+
+	return &txn, nil
+}
@@ -0,0 +1,45 @@
+package partialtx
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeserialize(t *testing.T) {
+	alice, bob := newParty("alice"), newParty("bob")
+	txn := newTransaction(t, alice, bob)
+	sign(t, txn, 0, alice)
+	sign(t, txn, 1, bob)
+	sign(t, txn, 2, alice)
+
+	data, err := txn.Serialize()
+	require.NoError(t, err)
+
+	// the addresses of the inputs are recovered from their signatures
+	decoded, err := Deserialize(data)
+	require.NoError(t, err)
+	require.Equal(t, txn, decoded)
+
+	// an unsigned transaction has null signatures
+	unsigned := append([]byte(nil), data...)
+	sigs := 4 + 1 + len(cipher.SHA256{}) + 4
+	for i := sigs; i < sigs+3*len(cipher.Sig{}); i++ {
+		unsigned[i] = 0
+	}
+	decoded, err = Deserialize(unsigned)
+	require.NoError(t, err)
+	require.Equal(t, 0, decoded.Signed())
+	require.True(t, decoded.Inputs[1].Address.Null())
+	require.Equal(t, txn.InnerHash(), decoded.InnerHash())
+
+	_, err = Deserialize(data[:len(data)-1])
+	require.Error(t, err)
+
+	// the inner hash covers the outputs
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1]++
+	_, err = Deserialize(tampered)
+	require.EqualError(t, err, "invalid transaction: inner hash does not match the inputs and outputs")
+}
@@ -0,0 +1,355 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+const (
+	// handshakeTimeout is how long a started plugin has to return its manifest
+	handshakeTimeout = 10 * time.Second
+	// policyTimeout is how long the rule of a plugin has to check a transaction, the transaction is rejected after it
+	policyTimeout = 10 * time.Second
+	// stopTimeout is how long a plugin has to exit once its stdin is closed, before it is killed
+	stopTimeout = 5 * time.Second
+)
+
+var logger = logging.MustGetLogger("plugin")
+
+// Client is a plugin process started by the daemon
+type Client struct {
+	path     string
+	manifest Manifest
+	cmd      *exec.Cmd
+	rpc      *rpc.Client
+	done     chan struct{}
+
+	// coinMu serializes the calls to the coin backend, a plugin runs one at a time
+	coinMu sync.Mutex
+}
+
+// conn is the connection to a plugin, the reads come from its stdout and the writes go to its stdin
+type conn struct {
+	io.ReadCloser
+	w io.WriteCloser
+}
+
+func (c conn) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+func (c conn) Close() error {
+	err := c.w.Close()
+	if rerr := c.ReadCloser.Close(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// Load starts the plugins of dir, its executable files in the order of their names.
+// The plugins already started are closed if one fails to start.
+func Load(dir string) ([]*Client, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	var clients []*Client
+	fail := func(err error) ([]*Client, error) {
+		Close(clients)
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	for _, f := range files {
+		if !isExecutable(f) {
+			logger.Debugf("Skipping %s in the plugins directory, it is not an executable", f.Name())
+			continue
+		}
+
+		c, err := Start(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return fail(fmt.Errorf("failed to start the plugin %s: %v", f.Name(), err))
+		}
+		clients = append(clients, c)
+
+		if other, ok := names[c.Name()]; ok {
+			return fail(fmt.Errorf("the plugins %s and %s are both named %q", other, f.Name(), c.Name()))
+		}
+		names[c.Name()] = f.Name()
+	}
+
+	return clients, nil
+}
+
+// Close closes the plugins
+func Close(clients []*Client) {
+	for _, c := range clients {
+		if err := c.Close(); err != nil {
+			logger.WithError(err).Errorf("Failed to stop the plugin %s", c.Name())
+		}
+	}
+}
+
+func isExecutable(f os.FileInfo) bool {
+	if !f.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(f.Name()), ".exe")
+	}
+	return f.Mode()&0111 != 0
+}
+
+// Start starts the plugin of the executable at path, and checks it implements the plugin API of the daemon
+func Start(path string) (*Client, error) {
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close() // nolint: errcheck
+		stdinW.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), CookieKey+"="+CookieValue)
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+
+	// the logs of the plugin are written to its stderr, the writer is closed once the process exited
+	out := logger.WithField("plugin", filepath.Base(path)).WriterLevel(logrus.InfoLevel)
+	cmd.Stderr = out
+
+	err = cmd.Start()
+	// the pipe ends of the plugin are held by its process
+	stdinR.Close()  // nolint: errcheck
+	stdoutW.Close() // nolint: errcheck
+	if err != nil {
+		stdinW.Close()  // nolint: errcheck
+		stdoutR.Close() // nolint: errcheck
+		out.Close()     // nolint: errcheck
+		return nil, err
+	}
+
+	c := &Client{
+		path: path,
+		cmd:  cmd,
+		rpc:  jsonrpc.NewClient(conn{ReadCloser: stdoutR, w: stdinW}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			logger.WithError(err).Warningf("The plugin %s exited", filepath.Base(path))
+		}
+		out.Close() // nolint: errcheck
+		close(c.done)
+	}()
+
+	if err := c.handshake(); err != nil {
+		c.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	logger.Infof("Started the plugin %s of %s", c.manifest.Name, path)
+	return c, nil
+}
+
+// Connect connects to a plugin served on conn, such as by ServeConn, and checks it implements the plugin API of the daemon
+func Connect(conn io.ReadWriteCloser) (*Client, error) {
+	c := &Client{
+		rpc: jsonrpc.NewClient(conn),
+	}
+
+	if err := c.handshake(); err != nil {
+		c.Close() // nolint: errcheck
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) handshake() error {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := c.call(ctx, "Handshake", &HandshakeArgs{APIVersion: APIVersion}, &c.manifest); err != nil {
+		return err
+	}
+	return c.manifest.validate()
+}
+
+// Name returns the name of the plugin
+func (c *Client) Name() string {
+	return c.manifest.Name
+}
+
+// Manifest returns the manifest of the plugin
+func (c *Client) Manifest() Manifest {
+	return c.manifest
+}
+
+// Close stops the plugin, by closing its stdin, and kills it if it does not exit within a few seconds.
+// The plugins connected with Connect are disconnected.
+func (c *Client) Close() error {
+	err := c.rpc.Close()
+	if err == rpc.ErrShutdown {
+		err = nil
+	}
+	if c.cmd == nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+	case <-time.After(stopTimeout):
+		logger.Warningf("The plugin %s did not exit, killing it", c.Name())
+		if err := c.cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-c.done
+	}
+
+	return err
+}
+
+// call calls a method of the plugin, it returns when ctx is done without waiting for the reply
+func (c *Client) call(ctx context.Context, method string, args, reply interface{}) error {
+	call := c.rpc.Go(serviceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-call.Done:
+		return call.Error
+	}
+}
+
+// ServeRequest sends a request to the endpoints of the plugin
+func (c *Client) ServeRequest(ctx context.Context, req HTTPRequest) (HTTPResponse, error) {
+	var rsp HTTPResponse
+	if err := c.call(ctx, "ServeHTTP", &req, &rsp); err != nil {
+		return HTTPResponse{}, err
+	}
+	return rsp, nil
+}
+
+// CheckTransaction evaluates the rule of the plugin, it implements txpolicy.Hook
+func (c *Client) CheckTransaction(outputs []txpolicy.Output) error {
+	ctx, cancel := context.WithTimeout(context.Background(), policyTimeout)
+	defer cancel()
+
+	var reply CheckTransactionReply
+	if err := c.call(ctx, "CheckTransaction", &CheckTransactionArgs{Outputs: outputs}, &reply); err != nil {
+		return fmt.Errorf("the rule of the plugin %s failed: %v", c.Name(), err)
+	}
+
+	if v := reply.Violation; v != nil {
+		if v.Rule == "" {
+			v.Rule = c.Name()
+		}
+		return v
+	}
+	return nil
+}
+
+// Backend returns the coin backend of the plugin, nil if it has none
+func (c *Client) Backend() coin.Backend {
+	if c.manifest.Coin == "" {
+		return nil
+	}
+	return &backend{
+		client: c,
+	}
+}
+
+// coinCall calls a method of the coin backend of the plugin, and makes the device calls of the backend
+func (c *Client) coinCall(device skyWallet.Devicer, call CoinCall) (wire.Message, error) {
+	c.coinMu.Lock()
+	defer c.coinMu.Unlock()
+
+	var step CoinStep
+	if err := c.rpc.Call(serviceName+".CoinCall", &call, &step); err != nil {
+		return wire.Message{}, err
+	}
+
+	for step.Device != nil {
+		reply := newDeviceReply(callDevice(device, *step.Device))
+		step = CoinStep{}
+		if err := c.rpc.Call(serviceName+".DeviceReply", &reply, &step); err != nil {
+			return wire.Message{}, err
+		}
+	}
+
+	if step.Result == nil {
+		return wire.Message{}, fmt.Errorf("the coin backend of the plugin %s returned no result", c.Name())
+	}
+	return step.Result.message()
+}
+
+// backend is the coin backend of a plugin
+type backend struct {
+	client *Client
+}
+
+func (b *backend) Name() string {
+	return b.client.manifest.Coin
+}
+
+func (b *backend) AddressGen(device skyWallet.Devicer, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return b.client.coinCall(device, CoinCall{
+		Method:         methodAddressGen,
+		AddressN:       addressN,
+		StartIndex:     startIndex,
+		ConfirmAddress: confirmAddress,
+	})
+}
+
+func (b *backend) SignMessage(device skyWallet.Devicer, addressIndex int, message string) (wire.Message, error) {
+	return b.client.coinCall(device, CoinCall{
+		Method:       methodSignMessage,
+		AddressIndex: addressIndex,
+		Message:      message,
+	})
+}
+
+func (b *backend) CheckMessageSignature(device skyWallet.Devicer, message, signature, address string) (wire.Message, error) {
+	return b.client.coinCall(device, CoinCall{
+		Method:    methodCheckMessageSignature,
+		Message:   message,
+		Signature: signature,
+		Address:   address,
+	})
+}
+
+func (b *backend) TransactionSign(device skyWallet.Devicer, inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	return b.client.coinCall(device, CoinCall{
+		Method:  methodTransactionSign,
+		Inputs:  inputs,
+		Outputs: outputs,
+	})
+}
@@ -0,0 +1,187 @@
+// Package plugin loads the plugins of the daemon, executables started at startup from the plugins directory.
+// A plugin is a subprocess serving a versioned RPC API, JSON-RPC on its stdin and stdout, which adds endpoints
+// served under /api/v2/plugins/<name>/, a rule of the transaction policy or a coin backend. Serve implements
+// the plugin side of the API, the plugins are written against it.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+const (
+	// APIVersion is the version of the plugin API, the plugins implementing another version are not loaded
+	APIVersion = 1
+
+	// CookieKey is the environment variable the plugins are started with, set to CookieValue, so a plugin
+	// run by hand tells it is not started by the daemon
+	CookieKey = "SKYWALLET_DAEMON_PLUGIN"
+	// CookieValue is the value of CookieKey
+	CookieValue = "9d1b4d4596a4b5bd"
+
+	// serviceName is the RPC service of the plugins
+	serviceName = "Plugin"
+)
+
+// Methods of the devices called by the coin backends
+const (
+	methodAddressGen            = "AddressGen"
+	methodSignMessage           = "SignMessage"
+	methodCheckMessageSignature = "CheckMessageSignature"
+	methodTransactionSign       = "TransactionSign"
+)
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Manifest describes a plugin, it is returned by the plugin once it is started
+type Manifest struct {
+	// Name is the name of the plugin, its endpoints are served under /api/v2/plugins/<name>/
+	Name       string `json:"name"`
+	APIVersion int    `json:"api_version"`
+	// Endpoints is set by the plugins serving endpoints
+	Endpoints bool `json:"endpoints"`
+	// PolicyHook is set by the plugins adding a rule to the transaction policy
+	PolicyHook bool `json:"policy_hook"`
+	// Coin is the name of the coin backend of the plugin, empty if it has none
+	Coin string `json:"coin,omitempty"`
+}
+
+func (m Manifest) validate() error {
+	if m.APIVersion != APIVersion {
+		return fmt.Errorf("the plugin implements the plugin API version %d, the daemon implements version %d", m.APIVersion, APIVersion)
+	}
+	if !nameRegex.MatchString(m.Name) {
+		return fmt.Errorf("invalid plugin name %q, it must be lowercase letters, digits, - and _", m.Name)
+	}
+	if m.Coin != "" && !nameRegex.MatchString(m.Coin) {
+		return fmt.Errorf("invalid coin name %q, it must be lowercase letters, digits, - and _", m.Coin)
+	}
+	return nil
+}
+
+// HandshakeArgs are the arguments of the first call to a plugin
+type HandshakeArgs struct {
+	APIVersion int
+}
+
+// HTTPRequest is a request to an endpoint of a plugin, Path is relative to /api/v2/plugins/<name>
+type HTTPRequest struct {
+	Method   string
+	Path     string
+	RawQuery string
+	Header   http.Header
+	Body     []byte
+}
+
+// HTTPResponse is the response of an endpoint of a plugin
+type HTTPResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// CheckTransactionArgs are the destinations of a transaction checked by the rule of a plugin
+type CheckTransactionArgs struct {
+	Outputs []txpolicy.Output
+}
+
+// CheckTransactionReply is the result of the rule of a plugin, a nil Violation allows the transaction
+type CheckTransactionReply struct {
+	Violation *txpolicy.Violation
+}
+
+// CoinCall is a call to a method of a coin backend, or of the device by the backend
+type CoinCall struct {
+	Method         string
+	AddressN       uint32
+	StartIndex     uint32
+	ConfirmAddress bool
+	AddressIndex   int
+	Message        string
+	Signature      string
+	Address        string
+	Inputs         []*messages.SkycoinTransactionInput
+	Outputs        []*messages.SkycoinTransactionOutput
+}
+
+// CoinStep is the reply of a plugin to a coin call: the device call the backend makes,
+// or the result of the backend method once it returned
+type CoinStep struct {
+	Device *CoinCall
+	Result *DeviceReply
+}
+
+// DeviceReply is the result of a call to a device method
+type DeviceReply struct {
+	Message wire.Message
+	Error   string
+}
+
+// knownErrors are errors of the devices the API handlers tell apart, they are recreated from the replies
+var knownErrors = []error{
+	skyWallet.ErrNoDeviceConnected,
+	skyWallet.ErrInvalidWordCount,
+}
+
+func newDeviceReply(msg wire.Message, err error) DeviceReply {
+	r := DeviceReply{
+		Message: msg,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func (r DeviceReply) message() (wire.Message, error) {
+	if r.Error == "" {
+		return r.Message, nil
+	}
+	for _, err := range knownErrors {
+		if err.Error() == r.Error {
+			return wire.Message{}, err
+		}
+	}
+	return wire.Message{}, errors.New(r.Error)
+}
+
+// callDevice calls the device method of c
+func callDevice(device skyWallet.Devicer, c CoinCall) (wire.Message, error) {
+	switch c.Method {
+	case methodAddressGen:
+		return device.AddressGen(c.AddressN, c.StartIndex, c.ConfirmAddress)
+	case methodSignMessage:
+		return device.SignMessage(c.AddressIndex, c.Message)
+	case methodCheckMessageSignature:
+		return device.CheckMessageSignature(c.Message, c.Signature, c.Address)
+	case methodTransactionSign:
+		return device.TransactionSign(c.Inputs, c.Outputs)
+	default:
+		return wire.Message{}, fmt.Errorf("unknown device method %q", c.Method)
+	}
+}
+
+// callBackend calls the backend method of c
+func callBackend(backend coin.Backend, device skyWallet.Devicer, c CoinCall) (wire.Message, error) {
+	switch c.Method {
+	case methodAddressGen:
+		return backend.AddressGen(device, c.AddressN, c.StartIndex, c.ConfirmAddress)
+	case methodSignMessage:
+		return backend.SignMessage(device, c.AddressIndex, c.Message)
+	case methodCheckMessageSignature:
+		return backend.CheckMessageSignature(device, c.Message, c.Signature, c.Address)
+	case methodTransactionSign:
+		return backend.TransactionSign(device, c.Inputs, c.Outputs)
+	default:
+		return wire.Message{}, fmt.Errorf("unknown coin backend method %q", c.Method)
+	}
+}
@@ -0,0 +1,197 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+const flaggedAddress = "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"
+
+// the test binary is the plugin when it is started by the daemon
+func TestMain(m *testing.M) {
+	if os.Getenv(CookieKey) == CookieValue {
+		if err := Serve(testPlugin{name: os.Getenv("TEST_PLUGIN_NAME")}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testPlugin struct {
+	name string
+}
+
+func (p testPlugin) Name() string {
+	return p.name
+}
+
+func (testPlugin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "%s %s?%s", r.Method, r.URL.Path, r.URL.RawQuery)
+}
+
+func (testPlugin) CheckTransaction(outputs []txpolicy.Output) error {
+	for _, o := range outputs {
+		if o.Address == flaggedAddress {
+			return &txpolicy.Violation{
+				Reason: "the address is flagged",
+			}
+		}
+	}
+	return nil
+}
+
+func (testPlugin) CoinBackend() coin.Backend {
+	return fiberCoin{}
+}
+
+// fiberCoin derives the addresses with another account of the device seed
+type fiberCoin struct {
+	coin.Skycoin
+}
+
+func (fiberCoin) Name() string {
+	return "fibercoin"
+}
+
+func (fiberCoin) AddressGen(device skyWallet.Devicer, addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	msg, err := device.AddressGen(addressN, startIndex+1000, confirmAddress)
+	if err != nil {
+		return wire.Message{}, err
+	}
+	// the device is called more than once per backend call
+	return device.AddressGen(uint32(len(msg.Data)), 0, false)
+}
+
+type fakeDevice struct {
+	skyWallet.Devicer
+	calls [][]uint32
+}
+
+func (d *fakeDevice) AddressGen(addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	d.calls = append(d.calls, []uint32{addressN, startIndex})
+	if confirmAddress {
+		return wire.Message{}, skyWallet.ErrNoDeviceConnected
+	}
+	return wire.Message{
+		Kind: 1,
+		Data: []byte("abc"),
+	}, nil
+}
+
+// writePlugin writes to dir an executable starting the test binary as the plugin name
+func writePlugin(t *testing.T, dir, file, name string) {
+	script := fmt.Sprintf("#!/bin/sh\nTEST_PLUGIN_NAME=%q exec %q\n", name, os.Args[0])
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(script), 0700))
+}
+
+func TestPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugins are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "plugin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writePlugin(t, dir, "b-plugin", "fiber")
+	writePlugin(t, dir, "a-plugin", "other")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0600))
+
+	clients, err := Load(dir)
+	require.NoError(t, err)
+	defer Close(clients)
+	require.Len(t, clients, 2)
+	require.Equal(t, "other", clients[0].Name())
+
+	c := clients[1]
+	require.Equal(t, Manifest{
+		Name:       "fiber",
+		APIVersion: APIVersion,
+		Endpoints:  true,
+		PolicyHook: true,
+		Coin:       "fibercoin",
+	}, c.Manifest())
+
+	rsp, err := c.ServeRequest(context.Background(), HTTPRequest{
+		Method:   http.MethodPost,
+		Path:     "/hello",
+		RawQuery: "a=b",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, rsp.Status)
+	require.Equal(t, "text/plain", rsp.Header.Get("Content-Type"))
+	require.Equal(t, "POST /hello?a=b", string(rsp.Body))
+
+	require.NoError(t, c.CheckTransaction([]txpolicy.Output{{Address: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG", Coins: 1e6}}))
+	err = c.CheckTransaction([]txpolicy.Output{{Address: flaggedAddress, Coins: 1e6}})
+	v, ok := err.(*txpolicy.Violation)
+	require.True(t, ok, err)
+	require.Equal(t, "fiber", v.Rule)
+	require.Equal(t, "the address is flagged", v.Reason)
+
+	// the device calls of the coin backend are made by the daemon
+	device := &fakeDevice{}
+	backend := c.Backend()
+	require.Equal(t, "fibercoin", backend.Name())
+	msg, err := backend.AddressGen(device, 2, 3, false)
+	require.NoError(t, err)
+	require.Equal(t, []byte("abc"), msg.Data)
+	require.Equal(t, [][]uint32{{2, 1003}, {3, 0}}, device.calls)
+
+	_, err = backend.AddressGen(device, 1, 0, true)
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	// the plugin exits once closed, the calls fail
+	require.NoError(t, c.Close())
+	_, err = c.ServeRequest(context.Background(), HTTPRequest{Method: http.MethodGet, Path: "/"})
+	require.Error(t, err)
+	require.Error(t, c.CheckTransaction(nil))
+}
+
+func TestLoadErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugins are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "plugin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writePlugin(t, dir, "a-plugin", "fiber")
+	writePlugin(t, dir, "b-plugin", "fiber")
+	_, err = Load(dir)
+	require.EqualError(t, err, `the plugins a-plugin and b-plugin are both named "fiber"`)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "b-plugin")))
+	writePlugin(t, dir, "c-plugin", "Invalid Name")
+	_, err = Load(dir)
+	require.EqualError(t, err, `failed to start the plugin c-plugin: invalid plugin name "Invalid Name", it must be lowercase letters, digits, - and _`)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "c-plugin")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "d-plugin"), []byte("#!/bin/sh\nexit 1\n"), 0700))
+	_, err = Load(dir)
+	require.Error(t, err)
+
+	_, err = Load(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+
+	// a plugin run by hand is not served
+	require.Equal(t, ErrNotStarted, Serve(testPlugin{name: "fiber"}))
+}
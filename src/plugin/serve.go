@@ -0,0 +1,360 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"sync"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/coin"
+	"github.com/skycoin/hardware-wallet-daemon/src/txpolicy"
+)
+
+// ErrNotStarted is returned by Serve when the plugin is not started by the daemon
+var ErrNotStarted = errors.New("this is a plugin of the skywallet daemon, it is started by the daemon from its plugins directory")
+
+// Plugin is implemented by the plugins, along with one or more of http.Handler, PolicyHook and CoinPlugin
+type Plugin interface {
+	// Name is the name of the plugin
+	Name() string
+}
+
+// PolicyHook is implemented by the plugins adding a rule to the transaction policy
+type PolicyHook interface {
+	// CheckTransaction returns a *txpolicy.Violation if the transaction sending outputs breaks the rule,
+	// the transaction is rejected by the other errors as well
+	CheckTransaction(outputs []txpolicy.Output) error
+}
+
+// CoinPlugin is implemented by the plugins adding a coin backend
+type CoinPlugin interface {
+	// CoinBackend returns the backend, it is registered under its name
+	CoinBackend() coin.Backend
+}
+
+// Serve serves the plugin API to the daemon on stdin and stdout, until the daemon closes stdin.
+// stdout is used by the API, the plugin logs to stderr.
+func Serve(p Plugin) error {
+	if os.Getenv(CookieKey) != CookieValue {
+		return ErrNotStarted
+	}
+	return ServeConn(p, stdio{})
+}
+
+// ServeConn serves the plugin API on conn, until it is closed
+func ServeConn(p Plugin, conn io.ReadWriteCloser) error {
+	s := rpc.NewServer()
+	if err := s.RegisterName(serviceName, &server{plugin: p}); err != nil {
+		return err
+	}
+	s.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdio) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdio) Close() error {
+	return os.Stdin.Close()
+}
+
+// server is the RPC service of a plugin
+type server struct {
+	plugin Plugin
+
+	mu  sync.Mutex
+	run *coinRun
+}
+
+// Handshake returns the manifest of the plugin
+func (s *server) Handshake(args *HandshakeArgs, reply *Manifest) error {
+	*reply = Manifest{
+		Name:       s.plugin.Name(),
+		APIVersion: APIVersion,
+	}
+	_, reply.Endpoints = s.plugin.(http.Handler)
+	_, reply.PolicyHook = s.plugin.(PolicyHook)
+	if p, ok := s.plugin.(CoinPlugin); ok {
+		reply.Coin = p.CoinBackend().Name()
+	}
+
+	if args.APIVersion != APIVersion {
+		return fmt.Errorf("the daemon implements the plugin API version %d, the plugin implements version %d", args.APIVersion, APIVersion)
+	}
+	return nil
+}
+
+// ServeHTTP serves a request to the endpoints of the plugin
+func (s *server) ServeHTTP(args *HTTPRequest, reply *HTTPResponse) error {
+	h, ok := s.plugin.(http.Handler)
+	if !ok {
+		return errors.New("the plugin has no endpoints")
+	}
+
+	url := args.Path
+	if args.RawQuery != "" {
+		url += "?" + args.RawQuery
+	}
+	r, err := http.NewRequest(args.Method, url, bytes.NewReader(args.Body))
+	if err != nil {
+		return err
+	}
+	if args.Header != nil {
+		r.Header = args.Header
+	}
+
+	w := &responseWriter{
+		header: make(http.Header),
+	}
+	h.ServeHTTP(w, r)
+
+	*reply = HTTPResponse{
+		Status: w.status,
+		Header: w.header,
+		Body:   w.body.Bytes(),
+	}
+	if reply.Status == 0 {
+		reply.Status = http.StatusOK
+	}
+	return nil
+}
+
+// responseWriter holds the response of an endpoint of the plugin
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// CheckTransaction evaluates the rule of the plugin
+func (s *server) CheckTransaction(args *CheckTransactionArgs, reply *CheckTransactionReply) error {
+	h, ok := s.plugin.(PolicyHook)
+	if !ok {
+		return errors.New("the plugin has no policy hook")
+	}
+
+	switch v := h.CheckTransaction(args.Outputs).(type) {
+	case nil:
+	case *txpolicy.Violation:
+		reply.Violation = v
+	default:
+		return v
+	}
+	return nil
+}
+
+// coinRun is a call to the coin backend in progress, the backend runs in its own goroutine and
+// its device calls are returned to the daemon one at a time
+type coinRun struct {
+	calls   chan CoinCall
+	replies chan DeviceReply
+	done    chan DeviceReply
+}
+
+// CoinCall starts a call to the coin backend, and returns its first device call or its result
+func (s *server) CoinCall(args *CoinCall, reply *CoinStep) error {
+	p, ok := s.plugin.(CoinPlugin)
+	if !ok {
+		return errors.New("the plugin has no coin backend")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run != nil {
+		return errors.New("a call to the coin backend is in progress")
+	}
+
+	run := &coinRun{
+		calls:   make(chan CoinCall),
+		replies: make(chan DeviceReply),
+		done:    make(chan DeviceReply, 1),
+	}
+	s.run = run
+
+	call := *args
+	go func() {
+		run.done <- newDeviceReply(callBackend(p.CoinBackend(), &remoteDevice{run: run}, call))
+	}()
+
+	return s.next(reply)
+}
+
+// DeviceReply returns the result of a device call to the coin backend, and returns its next device call or its result
+func (s *server) DeviceReply(args *DeviceReply, reply *CoinStep) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run == nil {
+		return errors.New("no call to the coin backend is in progress")
+	}
+
+	s.run.replies <- *args
+	return s.next(reply)
+}
+
+func (s *server) next(reply *CoinStep) error {
+	select {
+	case c := <-s.run.calls:
+		reply.Device = &c
+	case r := <-s.run.done:
+		reply.Result = &r
+		s.run = nil
+	}
+	return nil
+}
+
+// errNotForwarded is returned by the device methods the coin backends of the plugins cannot call
+var errNotForwarded = errors.New("the device method is not available to the coin backends of the plugins")
+
+// remoteDevice is the device of the daemon used by a coin backend, its coin methods are called by the daemon
+type remoteDevice struct {
+	run *coinRun
+}
+
+func (d *remoteDevice) call(c CoinCall) (wire.Message, error) {
+	d.run.calls <- c
+	r := <-d.run.replies
+	return r.message()
+}
+
+func (d *remoteDevice) AddressGen(addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return d.call(CoinCall{
+		Method:         methodAddressGen,
+		AddressN:       addressN,
+		StartIndex:     startIndex,
+		ConfirmAddress: confirmAddress,
+	})
+}
+
+func (d *remoteDevice) SignMessage(addressIndex int, message string) (wire.Message, error) {
+	return d.call(CoinCall{
+		Method:       methodSignMessage,
+		AddressIndex: addressIndex,
+		Message:      message,
+	})
+}
+
+func (d *remoteDevice) CheckMessageSignature(message, signature, address string) (wire.Message, error) {
+	return d.call(CoinCall{
+		Method:    methodCheckMessageSignature,
+		Message:   message,
+		Signature: signature,
+		Address:   address,
+	})
+}
+
+func (d *remoteDevice) TransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	return d.call(CoinCall{
+		Method:  methodTransactionSign,
+		Inputs:  inputs,
+		Outputs: outputs,
+	})
+}
+
+func (d *remoteDevice) ApplySettings(*bool, string, string) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) Backup() (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) Cancel() (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) ChangePin(*bool) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) Connected() bool {
+	return true
+}
+
+func (d *remoteDevice) Available() bool {
+	return true
+}
+
+func (d *remoteDevice) FirmwareUpload([]byte, [32]byte) error {
+	return errNotForwarded
+}
+
+func (d *remoteDevice) GetFeatures() (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) GenerateMnemonic(uint32, bool) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) Recovery(uint32, *bool, bool) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) SetMnemonic(string) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) Wipe() (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) PinMatrixAck(string) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) WordAck(string) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) PassphraseAck(string) (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) ButtonAck() (wire.Message, error) {
+	return wire.Message{}, errNotForwarded
+}
+
+func (d *remoteDevice) SetAutoPressButton(bool, skyWallet.ButtonType) error {
+	return errNotForwarded
+}
+
+func (d *remoteDevice) Close() {}
+
+func (d *remoteDevice) Connect() error {
+	return nil
+}
+
+func (d *remoteDevice) Disconnect() error {
+	return nil
+}
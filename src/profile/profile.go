@@ -0,0 +1,256 @@
+// Package profile manages the named daemon profiles. A profile is a directory holding the data of an isolated
+// daemon instance and a config file with its settings, so users who separate their wallets can run a daemon per profile.
+package profile
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ConfigFilename is the name of the config file of a profile
+	ConfigFilename = "daemon.conf"
+	// PortSetting is the setting of the port a profile serves the API on
+	PortSetting = "web-interface-port"
+
+	// dirName is the directory of the profiles in the base data directory
+	dirName = "profiles"
+)
+
+var (
+	// ErrInvalidName is returned when a profile name is not valid
+	ErrInvalidName = errors.New("profile name must match [a-z0-9_-]+")
+	// ErrNotFound is returned when a profile does not exist
+	ErrNotFound = errors.New("profile not found")
+
+	nameRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+)
+
+// Profile is a named daemon profile
+type Profile struct {
+	Name string
+	// Dir is the data directory of the profile, holding its config file
+	Dir string
+	// Settings maps flag names to their values
+	Settings map[string]string
+}
+
+// Port returns the port of the profile, 0 if it is not set
+func (p *Profile) Port() int {
+	port, err := strconv.Atoi(p.Settings[PortSetting])
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+// Apply sets the flags of fs to the profile settings, except the flags already set on the command line.
+// The settings of the flags in reserved are rejected, as are the settings of unknown flags.
+func (p *Profile) Apply(fs *flag.FlagSet, reserved ...string) error {
+	set := make(map[string]struct{})
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = struct{}{}
+	})
+
+	names := make([]string, 0, len(p.Settings))
+	for name := range p.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, r := range reserved {
+			if name == r {
+				return fmt.Errorf("profile %s: %s cannot be set in a profile", p.Name, name)
+			}
+		}
+
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("profile %s: unknown setting %s", p.Name, name)
+		}
+
+		if _, ok := set[name]; ok {
+			continue
+		}
+
+		if err := fs.Set(name, p.Settings[name]); err != nil {
+			return fmt.Errorf("profile %s: invalid value %q for %s: %v", p.Name, p.Settings[name], name, err)
+		}
+	}
+
+	return nil
+}
+
+// Dir returns the data directory of the profile name in baseDir
+func Dir(baseDir, name string) string {
+	return filepath.Join(baseDir, dirName, name)
+}
+
+// Load loads the profile name of baseDir. ErrNotFound is returned if it does not exist.
+func Load(baseDir, name string) (*Profile, error) {
+	if !nameRegex.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	dir := Dir(baseDir, name)
+	f, err := os.Open(filepath.Join(dir, ConfigFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	settings, err := ParseSettings(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of profile %s: %v", name, err)
+	}
+
+	return &Profile{
+		Name:     name,
+		Dir:      dir,
+		Settings: settings,
+	}, nil
+}
+
+// Open loads the profile name of baseDir, creating it if it does not exist.
+// A new profile is given the first port after basePort not used by another profile.
+func Open(baseDir, name string, basePort int) (*Profile, error) {
+	p, err := Load(baseDir, name)
+	if err != ErrNotFound {
+		return p, err
+	}
+
+	profiles, err := List(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[int]struct{}, len(profiles))
+	for _, p := range profiles {
+		used[p.Port()] = struct{}{}
+	}
+
+	port := basePort + 1
+	for {
+		if _, ok := used[port]; !ok {
+			break
+		}
+		port++
+	}
+
+	return Create(baseDir, name, map[string]string{
+		PortSetting: strconv.Itoa(port),
+	})
+}
+
+// Create creates the profile name in baseDir with settings
+func Create(baseDir, name string, settings map[string]string) (*Profile, error) {
+	if !nameRegex.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	p := &Profile{
+		Name:     name,
+		Dir:      Dir(baseDir, name),
+		Settings: settings,
+	}
+
+	if err := os.MkdirAll(p.Dir, 0750); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(p.Dir, ConfigFilename), p.config(), 0600); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// config returns the content of the config file of the profile
+func (p *Profile) config() []byte {
+	names := make([]string, 0, len(p.Settings))
+	for name := range p.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Settings of the %s profile, one flag per line.\n", p.Name)
+	fmt.Fprintf(&b, "# Flags set on the command line take precedence.\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s = %s\n", name, p.Settings[name])
+	}
+	return []byte(b.String())
+}
+
+// List returns the profiles of baseDir sorted by name
+func List(baseDir string) ([]Profile, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(baseDir, dirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var profiles []Profile
+	for _, e := range entries {
+		if !e.IsDir() || !nameRegex.MatchString(e.Name()) {
+			continue
+		}
+
+		p, err := Load(baseDir, e.Name())
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *p)
+	}
+
+	return profiles, nil
+}
+
+// ParseSettings parses a profile config: one "flag = value" setting per line.
+// Blank lines and lines starting with # are ignored, flag names may have a leading -.
+func ParseSettings(r io.Reader) (map[string]string, error) {
+	settings := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: expected flag = value", n)
+		}
+
+		name := strings.TrimLeft(strings.TrimSpace(line[:i]), "-")
+		if name == "" {
+			return nil, fmt.Errorf("line %d: missing flag name", n)
+		}
+		settings[name] = strings.TrimSpace(line[i+1:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
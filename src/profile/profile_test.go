@@ -0,0 +1,141 @@
+package profile
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "profile")
+	require.NoError(t, err)
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	settings, err := ParseSettings(strings.NewReader(`
+# comment
+web-interface-port = 9511
+-log-level=debug
+trace-headers =
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"web-interface-port": "9511",
+		"log-level":          "debug",
+		"trace-headers":      "",
+	}, settings)
+
+	_, err = ParseSettings(strings.NewReader("\nweb-interface-port 9511\n"))
+	require.EqualError(t, err, "line 2: expected flag = value")
+
+	_, err = ParseSettings(strings.NewReader("= 9511"))
+	require.EqualError(t, err, "line 1: missing flag name")
+}
+
+func TestOpen(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	profiles, err := List(dir)
+	require.NoError(t, err)
+	require.Empty(t, profiles)
+
+	work, err := Open(dir, "work", 9510)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "profiles", "work"), work.Dir)
+	require.Equal(t, 9511, work.Port())
+
+	personal, err := Open(dir, "personal", 9510)
+	require.NoError(t, err)
+	require.Equal(t, 9512, personal.Port())
+
+	// an existing profile is loaded
+	loaded, err := Open(dir, "work", 9510)
+	require.NoError(t, err)
+	require.Equal(t, work, loaded)
+
+	// the edited settings are kept
+	config := filepath.Join(work.Dir, ConfigFilename)
+	require.NoError(t, ioutil.WriteFile(config, []byte("web-interface-port = 9600\nlog-level = debug\n"), 0600))
+	loaded, err = Load(dir, "work")
+	require.NoError(t, err)
+	require.Equal(t, 9600, loaded.Port())
+	require.Equal(t, "debug", loaded.Settings["log-level"])
+
+	// the port of a removed profile is reused
+	require.NoError(t, os.RemoveAll(personal.Dir))
+	other, err := Open(dir, "other", 9510)
+	require.NoError(t, err)
+	require.Equal(t, 9511, other.Port())
+
+	profiles, err = List(dir)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	require.Equal(t, "other", profiles[0].Name)
+	require.Equal(t, "work", profiles[1].Name)
+
+	_, err = Load(dir, "personal")
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = Open(dir, "../work", 9510)
+	require.Equal(t, ErrInvalidName, err)
+
+	_, err = Open(dir, "Work", 9510)
+	require.Equal(t, ErrInvalidName, err)
+}
+
+func TestApply(t *testing.T) {
+	newFlagSet := func(args ...string) (*flag.FlagSet, *int, *string, *time.Duration) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		port := fs.Int("web-interface-port", 9510, "")
+		level := fs.String("log-level", "INFO", "")
+		timeout := fs.Duration("session-idle-timeout", time.Minute, "")
+		fs.String("data-dir", "", "")
+		require.NoError(t, fs.Parse(args))
+		return fs, port, level, timeout
+	}
+
+	p := &Profile{
+		Name: "work",
+		Settings: map[string]string{
+			"web-interface-port":   "9511",
+			"log-level":            "debug",
+			"session-idle-timeout": "10m",
+		},
+	}
+
+	fs, port, level, timeout := newFlagSet()
+	require.NoError(t, p.Apply(fs))
+	require.Equal(t, 9511, *port)
+	require.Equal(t, "debug", *level)
+	require.Equal(t, 10*time.Minute, *timeout)
+
+	// the command line takes precedence
+	fs, port, level, _ = newFlagSet("-web-interface-port", "9000")
+	require.NoError(t, p.Apply(fs))
+	require.Equal(t, 9000, *port)
+	require.Equal(t, "debug", *level)
+
+	p.Settings["session-idle-timeout"] = "soon"
+	fs, _, _, _ = newFlagSet()
+	err := p.Apply(fs)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `profile work: invalid value "soon" for session-idle-timeout`)
+
+	p.Settings = map[string]string{"foo": "bar"}
+	fs, _, _, _ = newFlagSet()
+	require.EqualError(t, p.Apply(fs), "profile work: unknown setting foo")
+
+	p.Settings = map[string]string{"data-dir": "/tmp"}
+	fs, _, _, _ = newFlagSet()
+	require.EqualError(t, p.Apply(fs, "data-dir"), "profile work: data-dir cannot be set in a profile")
+}
@@ -0,0 +1,389 @@
+// Package profiling captures the CPU profiles and the heap and goroutine dumps of the running daemon in a directory,
+// for the admin profiling endpoints, so a production daemon is profiled without restarting it and losing the device
+// session. The profiles are written in the pprof format, read with go tool pprof.
+package profiling
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CPU is the type of the CPU profiles
+	CPU = "cpu"
+
+	// DefaultCPUDuration is how long a CPU profile runs when no duration is requested
+	DefaultCPUDuration = 30 * time.Second
+	// MaxCPUDuration is the longest CPU profile, it is stopped after that duration if not stopped before
+	MaxCPUDuration = 10 * time.Minute
+	// MaxProfiles is the number of profiles kept in the directory, the oldest are removed
+	MaxProfiles = 20
+
+	// ext is the extension of the profile files
+	ext = ".pprof"
+	// timeFormat is the format of the time in the name of the profile files, sorting them by time
+	timeFormat = "20060102T150405.000000Z"
+)
+
+var (
+	// ErrCPUProfileRunning is returned when a CPU profile is started while another runs, including the one of -profile-cpu
+	ErrCPUProfileRunning = errors.New("a CPU profile is already running")
+	// ErrCPUProfileNotRunning is returned when no CPU profile started by the profiler runs
+	ErrCPUProfileNotRunning = errors.New("no CPU profile is running")
+	// ErrInvalidDuration is returned when the duration of a CPU profile is out of range
+	ErrInvalidDuration = fmt.Errorf("the CPU profile duration must be between 1s and %s", MaxCPUDuration)
+	// ErrUnknownType is returned when the dump type is not one of Types
+	ErrUnknownType = fmt.Errorf("unknown profile type, it must be one of %s", strings.Join(Types, ", "))
+	// ErrNotFound is returned when the profile does not exist
+	ErrNotFound = errors.New("profile not found")
+)
+
+// Types are the dumps of the runtime captured by Capture
+var Types = []string{"heap", "allocs", "goroutine", "threadcreate", "block", "mutex"}
+
+// Profile is a profile file of the directory
+type Profile struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CPUStatus is the status of the CPU profile started by the profiler
+type CPUStatus struct {
+	Running bool `json:"running"`
+	// Name is the name of the profile file written, empty if no profile runs
+	Name      string     `json:"name,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// StopsAt is when the profile is stopped if it is not stopped before
+	StopsAt *time.Time `json:"stops_at,omitempty"`
+}
+
+// Profiler writes the profiles of the daemon to a directory
+type Profiler struct {
+	dir string
+
+	mu        sync.Mutex
+	cpuFile   *os.File
+	cpuName   string
+	cpuStart  time.Time
+	cpuStop   time.Time
+	cpuTimer  *time.Timer
+	cpuRunSeq uint64
+}
+
+// New creates a Profiler writing the profiles to dir, created when the first profile is written
+func New(dir string) *Profiler {
+	return &Profiler{
+		dir: dir,
+	}
+}
+
+// Dir returns the directory of the profiles
+func (p *Profiler) Dir() string {
+	return p.dir
+}
+
+// StartCPU starts a CPU profile, stopped after duration if StopCPU is not called before
+func (p *Profiler) StartCPU(duration time.Duration) (CPUStatus, error) {
+	if duration < time.Second || duration > MaxCPUDuration {
+		return CPUStatus{}, ErrInvalidDuration
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cpuFile != nil {
+		return CPUStatus{}, ErrCPUProfileRunning
+	}
+
+	now := time.Now().UTC()
+	name := profileName(CPU, now)
+	f, err := p.create(name)
+	if err != nil {
+		return CPUStatus{}, err
+	}
+
+	// fails if the CPU profile of -profile-cpu runs
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()                             // nolint: errcheck
+		os.Remove(filepath.Join(p.dir, name)) // nolint: errcheck
+		return CPUStatus{}, ErrCPUProfileRunning
+	}
+
+	p.cpuRunSeq++
+	seq := p.cpuRunSeq
+	p.cpuFile = f
+	p.cpuName = name
+	p.cpuStart = now
+	p.cpuStop = now.Add(duration)
+	p.cpuTimer = time.AfterFunc(duration, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// the profile timed out is not a later one started after it was stopped
+		if p.cpuFile != nil && p.cpuRunSeq == seq {
+			p.stopCPU() // nolint: errcheck
+		}
+	})
+
+	return p.cpuStatus(), nil
+}
+
+// StopCPU stops the CPU profile and returns its file
+func (p *Profiler) StopCPU() (Profile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cpuFile == nil {
+		return Profile{}, ErrCPUProfileNotRunning
+	}
+
+	p.cpuTimer.Stop()
+	return p.stopCPU()
+}
+
+func (p *Profiler) stopCPU() (Profile, error) {
+	pprof.StopCPUProfile()
+	err := p.cpuFile.Close()
+	name := p.cpuName
+
+	p.cpuFile = nil
+	p.cpuName = ""
+	p.cpuTimer = nil
+
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if err := p.prune(); err != nil {
+		return Profile{}, err
+	}
+
+	return p.stat(name)
+}
+
+// CPU returns the status of the CPU profile
+func (p *Profiler) CPU() CPUStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cpuStatus()
+}
+
+func (p *Profiler) cpuStatus() CPUStatus {
+	if p.cpuFile == nil {
+		return CPUStatus{}
+	}
+
+	start := p.cpuStart
+	stop := p.cpuStop
+	return CPUStatus{
+		Running:   true,
+		Name:      p.cpuName,
+		StartedAt: &start,
+		StopsAt:   &stop,
+	}
+}
+
+// Capture writes a dump of the runtime of typ, one of Types, and returns its file
+func (p *Profiler) Capture(typ string) (Profile, error) {
+	if !isType(typ) {
+		return Profile{}, ErrUnknownType
+	}
+
+	profile := pprof.Lookup(typ)
+	if profile == nil {
+		return Profile{}, ErrUnknownType
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := profileName(typ, time.Now().UTC())
+	f, err := p.create(name)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if err := profile.WriteTo(f, 0); err != nil {
+		f.Close()                             // nolint: errcheck
+		os.Remove(filepath.Join(p.dir, name)) // nolint: errcheck
+		return Profile{}, err
+	}
+
+	if err := f.Close(); err != nil {
+		return Profile{}, err
+	}
+
+	if err := p.prune(); err != nil {
+		return Profile{}, err
+	}
+
+	return p.stat(name)
+}
+
+// List returns the profiles of the directory, newest first. The CPU profile running is not listed.
+func (p *Profiler) List() ([]Profile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.list()
+}
+
+func (p *Profiler) list() ([]Profile, error) {
+	files, err := ioutil.ReadDir(p.dir)
+	if os.IsNotExist(err) {
+		return []Profile{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	profiles := []Profile{}
+	for _, f := range files {
+		if f.IsDir() || f.Name() == p.cpuName {
+			continue
+		}
+
+		typ, createdAt, ok := parseName(f.Name())
+		if !ok {
+			continue
+		}
+
+		profiles = append(profiles, Profile{
+			Name:      f.Name(),
+			Type:      typ,
+			Size:      f.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].CreatedAt.After(profiles[j].CreatedAt)
+	})
+	return profiles, nil
+}
+
+// Open opens the profile file name for reading, the caller closes it
+func (p *Profiler) Open(name string) (*os.File, Profile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, _, ok := parseName(name); !ok || name == p.cpuName {
+		return nil, Profile{}, ErrNotFound
+	}
+
+	profile, err := p.stat(name)
+	if err != nil {
+		return nil, Profile{}, err
+	}
+
+	f, err := os.Open(filepath.Join(p.dir, name))
+	if err != nil {
+		return nil, Profile{}, err
+	}
+	return f, profile, nil
+}
+
+// Remove removes the profile file name
+func (p *Profiler) Remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, _, ok := parseName(name); !ok || name == p.cpuName {
+		return ErrNotFound
+	}
+
+	err := os.Remove(filepath.Join(p.dir, name))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// create creates the profile file name, readable by the daemon user only: the dumps hold the memory of the daemon
+func (p *Profiler) create(name string) (*os.File, error) {
+	if err := os.MkdirAll(p.dir, 0750); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(p.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+}
+
+// stat returns the profile file name
+func (p *Profiler) stat(name string) (Profile, error) {
+	typ, createdAt, ok := parseName(name)
+	if !ok {
+		return Profile{}, ErrNotFound
+	}
+
+	info, err := os.Stat(filepath.Join(p.dir, name))
+	if os.IsNotExist(err) {
+		return Profile{}, ErrNotFound
+	} else if err != nil {
+		return Profile{}, err
+	}
+
+	return Profile{
+		Name:      name,
+		Type:      typ,
+		Size:      info.Size(),
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// prune removes the oldest profiles past MaxProfiles
+func (p *Profiler) prune() error {
+	profiles, err := p.list()
+	if err != nil {
+		return err
+	}
+
+	for i := MaxProfiles; i < len(profiles); i++ {
+		if err := os.Remove(filepath.Join(p.dir, profiles[i].Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func profileName(typ string, t time.Time) string {
+	return typ + "-" + t.Format(timeFormat) + ext
+}
+
+// parseName returns the type and the time of the profile file name, false if it is not the name of a profile
+func parseName(name string) (string, time.Time, bool) {
+	if filepath.Base(name) != name || !strings.HasSuffix(name, ext) {
+		return "", time.Time{}, false
+	}
+
+	i := strings.Index(name, "-")
+	if i < 0 {
+		return "", time.Time{}, false
+	}
+
+	typ := name[:i]
+	if typ != CPU && !isType(typ) {
+		return "", time.Time{}, false
+	}
+
+	t, err := time.Parse(timeFormat, strings.TrimSuffix(name[i+1:], ext))
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	return typ, t, true
+}
+
+func isType(typ string) bool {
+	for _, t := range Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
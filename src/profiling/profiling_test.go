@@ -0,0 +1,101 @@
+package profiling
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	p := New(filepath.Join(dir, "pprof"))
+
+	profiles, err := p.List()
+	require.NoError(t, err)
+	require.Empty(t, profiles)
+
+	_, err = p.Capture("cpu")
+	require.Equal(t, ErrUnknownType, err)
+
+	heap, err := p.Capture("heap")
+	require.NoError(t, err)
+	require.Equal(t, "heap", heap.Type)
+	require.NotZero(t, heap.Size)
+
+	f, opened, err := p.Open(heap.Name)
+	require.NoError(t, err)
+	require.Equal(t, heap, opened)
+	require.NoError(t, f.Close())
+
+	_, err = p.StopCPU()
+	require.Equal(t, ErrCPUProfileNotRunning, err)
+
+	_, err = p.StartCPU(time.Hour)
+	require.Equal(t, ErrInvalidDuration, err)
+
+	status, err := p.StartCPU(time.Minute)
+	require.NoError(t, err)
+	require.True(t, status.Running)
+	require.Equal(t, status, p.CPU())
+
+	_, err = p.StartCPU(time.Minute)
+	require.Equal(t, ErrCPUProfileRunning, err)
+
+	// the running profile is not listed nor served
+	profiles, err = p.List()
+	require.NoError(t, err)
+	require.Equal(t, []Profile{heap}, profiles)
+	_, _, err = p.Open(status.Name)
+	require.Equal(t, ErrNotFound, err)
+
+	cpu, err := p.StopCPU()
+	require.NoError(t, err)
+	require.Equal(t, status.Name, cpu.Name)
+	require.Equal(t, CPU, cpu.Type)
+	require.False(t, p.CPU().Running)
+
+	profiles, err = p.List()
+	require.NoError(t, err)
+	require.Equal(t, []Profile{cpu, heap}, profiles)
+
+	// a CPU profile is stopped after its duration
+	status, err = p.StartCPU(time.Second)
+	require.NoError(t, err)
+	deadline := time.Now().Add(5 * time.Second)
+	for p.CPU().Running {
+		require.True(t, time.Now().Before(deadline), "the CPU profile was not stopped")
+		time.Sleep(10 * time.Millisecond)
+	}
+	f, _, err = p.Open(status.Name)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// only the profiles of the directory are served
+	for _, name := range []string{"../pprof/" + heap.Name, "notes.txt", "heap-yesterday.pprof"} {
+		_, _, err = p.Open(name)
+		require.Equal(t, ErrNotFound, err, name)
+		require.Equal(t, ErrNotFound, p.Remove(name), name)
+	}
+
+	require.NoError(t, p.Remove(heap.Name))
+	require.Equal(t, ErrNotFound, p.Remove(heap.Name))
+
+	// the oldest profiles are removed
+	for i := 0; i < MaxProfiles+2; i++ {
+		_, err = p.Capture("goroutine")
+		require.NoError(t, err)
+	}
+	profiles, err = p.List()
+	require.NoError(t, err)
+	require.Len(t, profiles, MaxProfiles)
+	for _, profile := range profiles {
+		require.Equal(t, "goroutine", profile.Type)
+	}
+}
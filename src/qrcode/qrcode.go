@@ -0,0 +1,716 @@
+// Package qrcode encodes QR codes (model 2), in byte mode at the four error correction levels,
+// such as the pairing URIs scanned by the mobile wallets.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+
+	// quietZone is the width of the light border around the code, in modules
+	quietZone = 4
+
+	// modeByte is the mode indicator of the byte mode
+	modeByte = 0x4
+	// formatMask is xored with the format information, so it is never all light
+	formatMask = 0x5412
+	// formatGenerator and versionGenerator are the generator polynomials of the BCH codes
+	// of the format and version information
+	formatGenerator  = 0x537
+	versionGenerator = 0x1f25
+)
+
+// ErrTooLong is returned for data which does not fit in the largest QR code
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// Level is an error correction level, the share of the codewords which can be restored
+type Level int
+
+// The error correction levels, restoring about 7%, 15%, 25% and 30% of the codewords
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// ParseLevel parses the error correction level L, M, Q or H
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "L":
+		return Low, nil
+	case "M":
+		return Medium, nil
+	case "Q":
+		return Quartile, nil
+	case "H":
+		return High, nil
+	default:
+		return 0, fmt.Errorf("invalid error correction level %q, choices are: L, M, Q or H", s)
+	}
+}
+
+// formatLevels are the bits of the levels in the format information
+var formatLevels = [...]int{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+// eccCodewordsPerBlock and numBlocks are the error correction codewords of each block and the number of blocks
+// of each version, indexed by level and version
+var (
+	eccCodewordsPerBlock = [...][maxVersion + 1]int{
+		Low: {-1,
+			7, 10, 15, 20, 26, 18, 20, 24, 30, 18,
+			20, 24, 26, 30, 22, 24, 28, 30, 28, 28,
+			28, 28, 30, 30, 26, 28, 30, 30, 30, 30,
+			30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+		},
+		Medium: {-1,
+			10, 16, 26, 18, 24, 16, 18, 22, 22, 26,
+			30, 22, 22, 24, 24, 28, 28, 26, 26, 26,
+			26, 28, 28, 28, 28, 28, 28, 28, 28, 28,
+			28, 28, 28, 28, 28, 28, 28, 28, 28, 28,
+		},
+		Quartile: {-1,
+			13, 22, 18, 26, 18, 24, 18, 22, 20, 24,
+			28, 26, 24, 20, 30, 24, 28, 28, 26, 30,
+			28, 30, 30, 30, 30, 28, 30, 30, 30, 30,
+			30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+		},
+		High: {-1,
+			17, 28, 22, 16, 22, 28, 26, 26, 24, 28,
+			24, 28, 22, 24, 24, 30, 28, 28, 26, 28,
+			30, 24, 30, 30, 30, 30, 30, 30, 30, 30,
+			30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+		},
+	}
+	numBlocks = [...][maxVersion + 1]int{
+		Low: {-1,
+			1, 1, 1, 1, 1, 2, 2, 2, 2, 4,
+			4, 4, 4, 4, 6, 6, 6, 6, 7, 8,
+			8, 9, 9, 10, 12, 12, 12, 13, 14, 15,
+			16, 17, 18, 19, 19, 20, 21, 22, 24, 25,
+		},
+		Medium: {-1,
+			1, 1, 1, 2, 2, 4, 4, 4, 5, 5,
+			5, 8, 9, 9, 10, 10, 11, 13, 14, 16,
+			17, 17, 18, 20, 21, 23, 25, 26, 28, 29,
+			31, 33, 35, 37, 38, 40, 43, 45, 47, 49,
+		},
+		Quartile: {-1,
+			1, 1, 2, 2, 4, 4, 6, 6, 8, 8,
+			8, 10, 12, 16, 12, 17, 16, 18, 21, 20,
+			23, 23, 25, 27, 29, 34, 34, 35, 38, 40,
+			43, 45, 48, 51, 53, 56, 59, 62, 65, 68,
+		},
+		High: {-1,
+			1, 1, 2, 4, 4, 4, 5, 6, 8, 8,
+			11, 11, 16, 16, 18, 16, 19, 21, 25, 25,
+			25, 34, 30, 32, 35, 37, 40, 42, 45, 48,
+			51, 54, 57, 60, 63, 66, 70, 74, 77, 81,
+		},
+	}
+)
+
+// Code is a QR code
+type Code struct {
+	Version int
+	Level   Level
+	// Size is the width and height of the code in modules, without the quiet zone
+	Size int
+
+	// modules are the dark modules, by row and column
+	modules [][]bool
+	// isFunction marks the modules of the function patterns, which are not masked
+	isFunction [][]bool
+}
+
+// Encode encodes data in the smallest QR code it fits in, at the medium error correction level
+func Encode(data []byte) (*Code, error) {
+	return EncodeLevel(data, Medium)
+}
+
+// EncodeLevel encodes data in the smallest QR code it fits in, at the error correction level
+func EncodeLevel(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("invalid error correction level %d", level)
+	}
+
+	version := 0
+	for v := minVersion; v <= maxVersion; v++ {
+		if 4+charCountBits(v)+len(data)*8 <= numDataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	var bits bitBuffer
+	bits.append(modeByte, 4)
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	// terminate the data and pad it to the capacity of the version
+	capacity := numDataCodewords(version, level) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	c := newCode(version, level)
+	c.drawFunctionPatterns()
+	c.drawCodewords(addECCAndInterleave(bits.bytes(), version, level))
+
+	// use the mask with the lowest penalty, masking twice is a no-op
+	best, minPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); minPenalty < 0 || p < minPenalty {
+			best, minPenalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+
+	return c, nil
+}
+
+// Dark returns true if the module at column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && x < c.Size && y >= 0 && y < c.Size && c.modules[y][x]
+}
+
+// PNG returns the PNG image of the code with its quiet zone, each module being scale pixels wide
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		return nil, errors.New("scale must be positive")
+	}
+
+	size := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.SetColorIndex((quietZone+x)*scale+px, (quietZone+y)*scale+py, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG returns the SVG image of the code with its quiet zone, each module being scale pixels wide.
+// The dark modules are drawn by a single path, in module units.
+func (c *Code) SVG(scale int) ([]byte, error) {
+	if scale < 1 {
+		return nil, errors.New("scale must be positive")
+	}
+
+	size := c.Size + 2*quietZone
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		size*scale, size*scale, size, size)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/>` + "\n")
+	buf.WriteString(`<path fill="#000000" d="`)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			// the runs of dark modules of a row are drawn at once
+			run := 1
+			for x+run < c.Size && c.modules[y][x+run] {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d,%dh%dv1h-%dz", quietZone+x, quietZone+y, run, run)
+			x += run - 1
+		}
+	}
+	buf.WriteString(`"/>` + "\n</svg>\n")
+
+	return buf.Bytes(), nil
+}
+
+// Text returns the code with its quiet zone drawn with block characters, two rows of modules per line.
+// The light modules are drawn, so it is shown by the terminals with a dark background.
+func (c *Code) Text() string {
+	var sb strings.Builder
+	for y := -quietZone; y < c.Size+quietZone; y += 2 {
+		for x := -quietZone; x < c.Size+quietZone; x++ {
+			top, bottom := !c.Dark(x, y), !c.Dark(x, y+1)
+			if y+1 >= c.Size+quietZone {
+				bottom = false
+			}
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{
+		Version:    version,
+		Level:      level,
+		Size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.isFunction[y] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	// timing patterns
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	// finder patterns, with their separators
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.Size-4, 3)
+	c.drawFinderPattern(3, c.Size-4)
+
+	// alignment patterns, but over the finder patterns
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, y := range positions {
+		for j, x := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+
+	// reserve the format information, drawn once the mask is chosen, and draw the version information
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			d := distance(dx, dy)
+			c.setFunction(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, distance(dx, dy) != 1)
+		}
+	}
+}
+
+// formatBits returns the format information of the error correction level and the mask
+func formatBits(level Level, mask int) int {
+	data := formatLevels[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * formatGenerator)
+	}
+	return (data<<10 | rem) ^ formatMask
+}
+
+// versionBits returns the version information, for the versions 7 and up
+func versionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * versionGenerator)
+	}
+	return version<<12 | rem
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatBits(c.Level, mask)
+
+	// around the top left finder pattern
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	// next to the top right and bottom left finder patterns
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	// the dark module
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersionBits() {
+	if c.Version < 7 {
+		return
+	}
+
+	bits := versionBits(c.Version)
+	for i := 0; i < 18; i++ {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the codewords in the zigzag of two columns wide strips, from the bottom right corner
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// the vertical timing pattern is skipped
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = bit(int(codewords[i/8]), 7-i%8)
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.isFunction[y][x] {
+				continue
+			}
+
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the masked code is to scan, the runs and blocks of a color, the patterns
+// looking like a finder pattern and the imbalance of the dark and light modules are penalized
+func (c *Code) penalty() int {
+	p := 0
+
+	row := make([]bool, c.Size)
+	col := make([]bool, c.Size)
+	for i := 0; i < c.Size; i++ {
+		for j := 0; j < c.Size; j++ {
+			row[j] = c.modules[i][j]
+			col[j] = c.modules[j][i]
+		}
+		p += linePenalty(row) + linePenalty(col)
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			m := c.modules[y][x]
+			if m {
+				dark++
+			}
+			if x < c.Size-1 && y < c.Size-1 && m == c.modules[y][x+1] && m == c.modules[y+1][x] && m == c.modules[y+1][x+1] {
+				p += 3
+			}
+		}
+	}
+
+	// 10 points for each 5% of deviation from half dark modules
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		p += k * 10
+	}
+
+	return p
+}
+
+// finderLike is the 1:1:3:1:1 pattern of the finder patterns
+var finderLike = []bool{true, false, true, true, true, false, true}
+
+func linePenalty(line []bool) int {
+	p := 0
+
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+len(finderLike) <= len(line); i++ {
+		match := true
+		for j, m := range finderLike {
+			if line[i+j] != m {
+				match = false
+				break
+			}
+		}
+		if match && (isLight(line, i-4, i) || isLight(line, i+len(finderLike), i+len(finderLike)+4)) {
+			p += 40
+		}
+	}
+
+	return p
+}
+
+// isLight returns true if the modules of line from start to end are light, the quiet zone around the code is light
+func isLight(line []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// alignmentPositions returns the coordinates of the centers of the alignment patterns of the version
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	n := version/7 + 2
+	step := (version*8 + n*3 + 5) / (n*4 - 4) * 2
+	positions := make([]int, n)
+	positions[0] = 6
+	for i, pos := n-1, version*4+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// numRawDataModules returns the number of modules of the version which are not function modules,
+// including the remainder bits
+func numRawDataModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// numDataCodewords returns the number of data codewords of the version at the error correction level
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*numBlocks[level][version]
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// addECCAndInterleave splits the data in blocks, appends the error correction codewords to each block
+// and interleaves the blocks
+func addECCAndInterleave(data []byte, version int, level Level) []byte {
+	blocks := numBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	// the last blocks have one more data codeword
+	numShortBlocks := blocks - rawCodewords%blocks
+	shortBlockLen := rawCodewords / blocks
+
+	divisor := reedSolomonDivisor(eccLen)
+	all := make([][]byte, blocks)
+	k := 0
+	for i := range all {
+		n := shortBlockLen - eccLen
+		if i >= numShortBlocks {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			// padding, skipped when interleaving
+			block = append(block, 0)
+		}
+		all[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := range all[0] {
+		for j, block := range all {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of the degree, highest to lowest power
+// without the leading term
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	var root byte = 1
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11d)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func bit(x, i int) bool {
+	return (x>>uint(i))&1 != 0
+}
+
+// distance returns the distance of a module from the center of a pattern, the rings of the pattern are at the same distance
+func distance(dx, dy int) int {
+	if abs(dx) > abs(dy) {
+		return abs(dx)
+	}
+	return abs(dy)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, bit(value, i))
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, len(b)/8)
+	for i, v := range b {
+		if v {
+			result[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return result
+}
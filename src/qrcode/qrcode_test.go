@@ -0,0 +1,312 @@
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" in alphanumeric mode, version 1 at the medium level
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	ecc := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	require.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, ecc)
+}
+
+func TestFormatBits(t *testing.T) {
+	require.Equal(t, 0x5412, formatBits(Medium, 0))
+	require.Equal(t, 0x45f9, formatBits(Medium, 4))
+	require.Equal(t, 0x5e7c, formatBits(Medium, 2))
+	require.Equal(t, 0x77c4, formatBits(Low, 0))
+	require.Equal(t, 0x355f, formatBits(Quartile, 0))
+	require.Equal(t, 0x1689, formatBits(High, 0))
+}
+
+func TestVersionBits(t *testing.T) {
+	require.Equal(t, 0x07c94, versionBits(7))
+	require.Equal(t, 0x28c69, versionBits(40))
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	require.Empty(t, alignmentPositions(1))
+	require.Equal(t, []int{6, 18}, alignmentPositions(2))
+	require.Equal(t, []int{6, 22, 38}, alignmentPositions(7))
+	require.Equal(t, []int{6, 26, 48, 70}, alignmentPositions(15))
+	require.Equal(t, []int{6, 34, 60, 86, 112, 138}, alignmentPositions(32))
+	require.Equal(t, []int{6, 24, 50, 76, 102, 128, 154}, alignmentPositions(36))
+}
+
+func TestNumDataCodewords(t *testing.T) {
+	require.Equal(t, 16, numDataCodewords(1, Medium))
+	require.Equal(t, 86, numDataCodewords(5, Medium))
+	require.Equal(t, 216, numDataCodewords(10, Medium))
+	require.Equal(t, 2334, numDataCodewords(40, Medium))
+	require.Equal(t, 19, numDataCodewords(1, Low))
+	require.Equal(t, 2956, numDataCodewords(40, Low))
+	require.Equal(t, 13, numDataCodewords(1, Quartile))
+	require.Equal(t, 1666, numDataCodewords(40, Quartile))
+	require.Equal(t, 9, numDataCodewords(1, High))
+	require.Equal(t, 1276, numDataCodewords(40, High))
+}
+
+func TestFunctionPatterns(t *testing.T) {
+	for v := minVersion; v <= maxVersion; v++ {
+		c := newCode(v, Medium)
+		c.drawFunctionPatterns()
+
+		n := 0
+		for y := range c.isFunction {
+			for _, f := range c.isFunction[y] {
+				if !f {
+					n++
+				}
+			}
+		}
+		require.Equal(t, numRawDataModules(v), n, "version %d", v)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		length  int
+		version int
+	}{
+		{0, 1},
+		{14, 1},
+		{15, 2},
+		{130, 8},
+		{213, 10},
+		{214, 11},
+		{2331, 40},
+	}
+
+	for _, tc := range cases {
+		data := bytes.Repeat([]byte("skywallet-relay://pair?"), tc.length/23+1)[:tc.length]
+
+		c, err := Encode(data)
+		require.NoError(t, err)
+		require.Equal(t, tc.version, c.Version, "length %d", tc.length)
+		require.Equal(t, tc.version*4+17, c.Size)
+		require.Equal(t, data, decode(t, c), "length %d", tc.length)
+	}
+
+	_, err := Encode(make([]byte, 2332))
+	require.Equal(t, ErrTooLong, err)
+}
+
+func TestEncodeLevel(t *testing.T) {
+	cases := []struct {
+		level   Level
+		length  int
+		version int
+	}{
+		{Low, 17, 1},
+		{Low, 18, 2},
+		{Low, 2953, 40},
+		{Quartile, 11, 1},
+		{Quartile, 12, 2},
+		{Quartile, 1663, 40},
+		{High, 7, 1},
+		{High, 8, 2},
+		{High, 1273, 40},
+	}
+
+	for _, tc := range cases {
+		data := bytes.Repeat([]byte("2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"), tc.length/35+1)[:tc.length]
+
+		c, err := EncodeLevel(data, tc.level)
+		require.NoError(t, err)
+		require.Equal(t, tc.level, c.Level)
+		require.Equal(t, tc.version, c.Version, "level %d, length %d", tc.level, tc.length)
+		require.Equal(t, data, decode(t, c), "level %d, length %d", tc.level, tc.length)
+
+		_, err = EncodeLevel(make([]byte, tc.length+1), tc.level)
+		if tc.version == maxVersion {
+			require.Equal(t, ErrTooLong, err)
+		}
+	}
+
+	_, err := EncodeLevel([]byte("skywallet"), High+1)
+	require.Error(t, err)
+
+	for s, level := range map[string]Level{"L": Low, "M": Medium, "Q": Quartile, "H": High} {
+		l, err := ParseLevel(s)
+		require.NoError(t, err)
+		require.Equal(t, level, l)
+	}
+	_, err = ParseLevel("X")
+	require.Error(t, err)
+}
+
+func TestPNG(t *testing.T) {
+	c, err := Encode([]byte("skywallet"))
+	require.NoError(t, err)
+
+	data, err := c.PNG(4)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, (21+8)*4, (21+8)*4), img.Bounds())
+
+	// the quiet zone is light and the corner of the top left finder pattern is dark
+	r, _, _, _ := img.At(15, 15).RGBA()
+	require.Equal(t, uint32(0xffff), r)
+	r, _, _, _ = img.At(16, 16).RGBA()
+	require.Equal(t, uint32(0), r)
+
+	_, err = c.PNG(0)
+	require.Error(t, err)
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode([]byte("skywallet"))
+	require.NoError(t, err)
+
+	data, err := c.SVG(4)
+	require.NoError(t, err)
+
+	s := string(data)
+	require.Contains(t, s, `width="116" height="116" viewBox="0 0 29 29"`)
+	// the top row of the top left finder pattern is a single run
+	require.Contains(t, s, `d="M4,4h7v1h-7z`)
+
+	_, err = c.SVG(0)
+	require.Error(t, err)
+}
+
+func TestText(t *testing.T) {
+	c, err := Encode([]byte("skywallet"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(c.Text(), "\n"), "\n")
+	require.Len(t, lines, (21+8+1)/2)
+	for _, l := range lines {
+		require.Equal(t, 21+8, len([]rune(l)))
+	}
+
+	// the quiet zone is drawn, the top row of the finder patterns is not
+	require.Equal(t, strings.Repeat("█", 29), lines[0])
+	require.Equal(t, "████ ", string([]rune(lines[2])[:5]))
+}
+
+// decode reads the data of the code, checking its format information and error correction codewords
+func decode(t *testing.T, c *Code) []byte {
+	// the format information around the top left finder pattern, and its copy
+	var format, formatCopy int
+	for i := 0; i < 15; i++ {
+		var x, y int
+		switch {
+		case i <= 5:
+			x, y = 8, i
+		case i <= 7:
+			x, y = 8, i+1
+		case i == 8:
+			x, y = 7, 8
+		default:
+			x, y = 14-i, 8
+		}
+		if c.Dark(x, y) {
+			format |= 1 << uint(i)
+		}
+
+		x, y = c.Size-1-i, 8
+		if i >= 8 {
+			x, y = 8, c.Size-15+i
+		}
+		if c.Dark(x, y) {
+			formatCopy |= 1 << uint(i)
+		}
+	}
+	require.Equal(t, format, formatCopy)
+
+	info := format ^ formatMask
+	require.Equal(t, formatLevels[c.Level], info>>13, "error correction level")
+	mask := (info >> 10) & 7
+	require.Equal(t, formatBits(c.Level, mask), format)
+	require.True(t, c.Dark(8, c.Size-8), "dark module")
+
+	d := newCode(c.Version, c.Level)
+	d.drawFunctionPatterns()
+	for y := range c.modules {
+		for x := range c.modules[y] {
+			d.modules[y][x] = c.modules[y][x]
+		}
+	}
+	d.applyMask(mask)
+
+	// read the codewords up and down the strips of two columns
+	var bits bitBuffer
+	upward := true
+	for right := d.Size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for n := 0; n < d.Size; n++ {
+			y := n
+			if upward {
+				y = d.Size - 1 - n
+			}
+			for _, x := range []int{right, right - 1} {
+				if !d.isFunction[y][x] {
+					bits = append(bits, d.modules[y][x])
+				}
+			}
+		}
+		upward = !upward
+	}
+	codewords := bits.bytes()[:numRawDataModules(c.Version)/8]
+
+	// deinterleave the blocks, the short blocks come first
+	blocks := numBlocks[c.Level][c.Version]
+	eccLen := eccCodewordsPerBlock[c.Level][c.Version]
+	numShortBlocks := blocks - len(codewords)%blocks
+	shortDataLen := len(codewords)/blocks - eccLen
+
+	dataBlocks := make([][]byte, blocks)
+	eccBlocks := make([][]byte, blocks)
+	k := 0
+	for i := 0; i <= shortDataLen; i++ {
+		for j := range dataBlocks {
+			if i < shortDataLen || j >= numShortBlocks {
+				dataBlocks[j] = append(dataBlocks[j], codewords[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for j := range eccBlocks {
+			eccBlocks[j] = append(eccBlocks[j], codewords[k])
+			k++
+		}
+	}
+	require.Equal(t, len(codewords), k)
+
+	var data []byte
+	for j := range dataBlocks {
+		require.Equal(t, reedSolomonRemainder(dataBlocks[j], reedSolomonDivisor(eccLen)), eccBlocks[j])
+		data = append(data, dataBlocks[j]...)
+	}
+
+	// the byte mode segment
+	read := func(pos, n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[(pos+i)/8]>>uint(7-(pos+i)%8)&1)
+		}
+		return v
+	}
+	require.Equal(t, modeByte, read(0, 4))
+	length := read(4, charCountBits(c.Version))
+	pos := 4 + charCountBits(c.Version)
+
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = byte(read(pos+i*8, 8))
+	}
+	return out
+}
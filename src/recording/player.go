@@ -0,0 +1,174 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// maxLineSize is the size of the longest exchange of a recording, a firmware update is sent in one message
+const maxLineSize = 4 * 1024 * 1024
+
+// ErrEndOfRecording is returned by the messages sent once all the recorded exchanges were replayed
+var ErrEndOfRecording = errors.New("replay: end of the recording")
+
+// Player is a virtual device driver replaying a recording: each message sent gets the response recorded
+// for the next exchange, which must be for a message of the same type
+type Player struct {
+	deviceType skyWallet.DeviceType
+	exchanges  []Exchange
+
+	mu   sync.Mutex
+	next int
+}
+
+var _ skyWallet.DeviceDriver = (*Player)(nil)
+
+// LoadPlayer loads the recording at path, replayed as a device of deviceType
+func LoadPlayer(path string, deviceType skyWallet.DeviceType) (*Player, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &Player{
+		deviceType: deviceType,
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var ex Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("invalid recording %s line %d: %v", path, line, err)
+		}
+
+		// a recording with a response which cannot be encoded fails to load, rather than failing the replay
+		if ex.Response != nil {
+			if _, err := ex.Response.wireMessage(); err != nil {
+				return nil, fmt.Errorf("invalid recording %s line %d: %v", path, line, err)
+			}
+		}
+
+		p.exchanges = append(p.exchanges, ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %v", path, err)
+	}
+
+	return p, nil
+}
+
+// Remaining returns the number of exchanges left to replay
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.exchanges) - p.next
+}
+
+// SendToDevice returns the recorded response to the message
+func (p *Player) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	ex, err := p.replay(chunks, false)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	if ex.Error != "" {
+		return wire.Message{}, replayError(ex.Error)
+	}
+
+	return ex.Response.wireMessage()
+}
+
+// SendToDeviceNoAnswer returns the recorded error of the message, if any
+func (p *Player) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	ex, err := p.replay(chunks, true)
+	if err != nil {
+		return err
+	}
+
+	if ex.Error != "" {
+		return replayError(ex.Error)
+	}
+	return nil
+}
+
+// replay returns the next exchange if it is the one of the message
+func (p *Player) replay(chunks [][64]byte, noAnswer bool) (Exchange, error) {
+	msg, err := readMessage(chunks)
+	if err != nil {
+		return Exchange{}, err
+	}
+	messageType := messages.MessageType(msg.Kind).String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next >= len(p.exchanges) {
+		return Exchange{}, ErrEndOfRecording
+	}
+
+	ex := p.exchanges[p.next]
+	if ex.Request.Type != messageType || ex.NoAnswer != noAnswer {
+		return Exchange{}, fmt.Errorf("replay: unexpected %s, exchange %d of the recording is %s", messageType, p.next+1, ex.Request.Type)
+	}
+	if ex.Error == "" && ex.Response == nil && !noAnswer {
+		return Exchange{}, fmt.Errorf("replay: exchange %d of the recording has no response", p.next+1)
+	}
+
+	p.next++
+	logger.Debugf("Replaying exchange %d, %s", p.next, messageType)
+	return ex, nil
+}
+
+// GetDevice returns the virtual device
+func (p *Player) GetDevice() (usb.Device, error) {
+	return virtualDevice{}, nil
+}
+
+// GetDeviceInfos returns the virtual device
+func (p *Player) GetDeviceInfos() ([]usb.Info, error) {
+	return []usb.Info{
+		{
+			Path:      "replay",
+			VendorID:  skyWallet.SkycoinVendorID,
+			ProductID: skyWallet.SkycoinHwProductID,
+		},
+	}, nil
+}
+
+// DeviceType returns the device type of the recording
+func (p *Player) DeviceType() skyWallet.DeviceType {
+	return p.deviceType
+}
+
+// Close implements skyWallet.DeviceDriver
+func (p *Player) Close() {}
+
+// virtualDevice is the device of a Player, the messages are answered by the player without reaching it
+type virtualDevice struct{}
+
+func (virtualDevice) Read(b []byte) (int, error) {
+	return 0, errors.New("replay: the virtual device is not read")
+}
+
+func (virtualDevice) Write(b []byte) (int, error) {
+	return 0, errors.New("replay: the virtual device is not written")
+}
+
+func (virtualDevice) Close(disconnected bool) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package recording
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+)
+
+// Recorder is a device driver recording the messages exchanged with the device, one JSON Exchange per line
+type Recorder struct {
+	skyWallet.DeviceDriver
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	// now is replaced in tests
+	now func() time.Time
+}
+
+var _ skyWallet.DeviceDriver = (*Recorder)(nil)
+
+// NewRecorder wraps driver, appending its messages to the recording at path
+func NewRecorder(driver skyWallet.DeviceDriver, path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		DeviceDriver: driver,
+		file:         f,
+		enc:          json.NewEncoder(f),
+		now:          time.Now,
+	}, nil
+}
+
+// SendToDevice records the message and the response of the device
+func (r *Recorder) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	start := r.now()
+	msg, err := r.DeviceDriver.SendToDevice(dev, chunks)
+
+	ex := Exchange{
+		Time: start.UTC(),
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	} else {
+		response := newMessage(msg)
+		ex.Response = &response
+	}
+	r.record(chunks, ex)
+
+	return msg, err
+}
+
+// SendToDeviceNoAnswer records the message
+func (r *Recorder) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	start := r.now()
+	err := r.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+
+	ex := Exchange{
+		Time:     start.UTC(),
+		NoAnswer: true,
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	r.record(chunks, ex)
+
+	return err
+}
+
+// Close closes the wrapped driver and the recording
+func (r *Recorder) Close() {
+	r.DeviceDriver.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.file.Close(); err != nil {
+		logger.WithError(err).Error("Failed to close the recording")
+	}
+}
+
+func (r *Recorder) record(chunks [][64]byte, ex Exchange) {
+	request, err := readMessage(chunks)
+	if err != nil {
+		logger.WithError(err).Error("Failed to record a malformed request")
+		return
+	}
+	ex.Request = newMessage(request)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(ex); err != nil {
+		logger.WithError(err).Error("Failed to record a message exchange")
+	}
+}
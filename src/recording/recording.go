@@ -0,0 +1,203 @@
+// Package recording records the protobuf messages exchanged with the device to a file, with their sensitive
+// fields redacted, and replays them as a virtual device. Recordings reproduce the bugs reported by users,
+// such as a failed transaction signature, and serve as regression fixtures.
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// Redacted replaces the sensitive string fields in the recordings, the sensitive bytes fields are zeroed
+const Redacted = "[redacted]"
+
+var (
+	logger = logging.MustGetLogger("recording")
+
+	// sensitiveFields are the protobuf names of the fields carrying secrets, redacted in the recordings
+	sensitiveFields = map[string]struct{}{
+		"mnemonic":   {},
+		"pin":        {},
+		"passphrase": {},
+		"word":       {},
+		"entropy":    {},
+	}
+
+	// knownErrors are the device errors replayed as themselves, the daemon compares them
+	knownErrors = []error{
+		skyWallet.ErrNoDeviceConnected,
+	}
+)
+
+// Exchange is a message sent to the device and the response of the device
+type Exchange struct {
+	Time    time.Time `json:"time"`
+	Request Message   `json:"request"`
+	// Response is nil if the request failed or expects no answer
+	Response *Message `json:"response,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// NoAnswer is true for the requests which expect no answer, such as a cancel
+	NoAnswer bool `json:"no_answer,omitempty"`
+}
+
+// Message is a protobuf message, such as MessageType_TransactionSign
+type Message struct {
+	Type string `json:"type"`
+	// Fields are the fields of the message, redacted
+	Fields json.RawMessage `json:"fields,omitempty"`
+	// Data is the payload of the messages which could not be decoded, such as a corrupted response
+	Data []byte `json:"data,omitempty"`
+}
+
+// newMessage decodes msg and redacts its sensitive fields
+func newMessage(msg wire.Message) Message {
+	m := Message{
+		Type: messages.MessageType(msg.Kind).String(),
+	}
+
+	pb, err := decode(msg)
+	if err != nil {
+		logger.WithError(err).Warningf("Recording the payload of %s", m.Type)
+		m.Data = msg.Data
+		return m
+	}
+
+	redact(pb)
+
+	fields, err := json.Marshal(pb)
+	if err != nil {
+		logger.WithError(err).Warningf("Recording the payload of %s", m.Type)
+		m.Data = msg.Data
+		return m
+	}
+
+	m.Fields = fields
+	return m
+}
+
+// wireMessage encodes the message
+func (m Message) wireMessage() (wire.Message, error) {
+	kind, ok := messages.MessageType_value[m.Type]
+	if !ok {
+		return wire.Message{}, fmt.Errorf("unknown message type %q", m.Type)
+	}
+
+	if m.Fields == nil {
+		return wire.Message{
+			Kind: uint16(kind),
+			Data: m.Data,
+		}, nil
+	}
+
+	pb, err := newProto(m.Type)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	if err := json.Unmarshal(m.Fields, pb); err != nil {
+		return wire.Message{}, fmt.Errorf("invalid fields of %s: %v", m.Type, err)
+	}
+
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		return wire.Message{}, err
+	}
+
+	return wire.Message{
+		Kind: uint16(kind),
+		Data: data,
+	}, nil
+}
+
+// newProto returns a new protobuf message of the message type, such as MessageType_Features
+func newProto(messageType string) (proto.Message, error) {
+	t := proto.MessageType(strings.TrimPrefix(messageType, "MessageType_"))
+	if t == nil {
+		return nil, fmt.Errorf("no protobuf message for %s", messageType)
+	}
+
+	return reflect.New(t.Elem()).Interface().(proto.Message), nil
+}
+
+func decode(msg wire.Message) (proto.Message, error) {
+	pb, err := newProto(messages.MessageType(msg.Kind).String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := proto.Unmarshal(msg.Data, pb); err != nil {
+		return nil, err
+	}
+
+	return pb, nil
+}
+
+// redact replaces the sensitive fields of pb
+func redact(pb proto.Message) {
+	v := reflect.ValueOf(pb).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := sensitiveFields[protobufName(t.Field(i))]; !ok {
+			continue
+		}
+
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.String:
+			if !f.IsNil() {
+				f.Set(reflect.ValueOf(proto.String(Redacted)))
+			}
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8:
+			if !f.IsNil() {
+				f.SetBytes(make([]byte, f.Len()))
+			}
+		}
+	}
+}
+
+// protobufName returns the protobuf name of the field, from its protobuf tag
+func protobufName(f reflect.StructField) string {
+	for _, part := range strings.Split(f.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+	return ""
+}
+
+// readMessage reassembles the message sent in chunks
+func readMessage(chunks [][64]byte) (wire.Message, error) {
+	var b bytes.Buffer
+	for _, chunk := range chunks {
+		b.Write(chunk[:])
+	}
+
+	msg, err := wire.ReadFrom(&b)
+	if err != nil {
+		return wire.Message{}, err
+	}
+	return *msg, nil
+}
+
+// replayError returns the recorded error, the known device errors are returned as themselves
+func replayError(s string) error {
+	for _, err := range knownErrors {
+		if err.Error() == s {
+			return err
+		}
+	}
+	return errors.New(s)
+}
@@ -0,0 +1,232 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// fakeDriver answers the messages sent to the device with its replies, in order
+type fakeDriver struct {
+	skyWallet.DeviceDriver
+	replies []reply
+	closed  bool
+}
+
+type reply struct {
+	msg wire.Message
+	err error
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return virtualDevice{}, nil
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	r := d.replies[0]
+	d.replies = d.replies[1:]
+	return r.msg, r.err
+}
+
+func (d *fakeDriver) Close() {
+	d.closed = true
+}
+
+func newReply(t *testing.T, kind messages.MessageType, pb proto.Message) wire.Message {
+	data, err := proto.Marshal(pb)
+	require.NoError(t, err)
+	return wire.Message{
+		Kind: uint16(kind),
+		Data: data,
+	}
+}
+
+func readRecording(t *testing.T, path string) []Exchange {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ex Exchange
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ex))
+		exchanges = append(exchanges, ex)
+	}
+	require.NoError(t, scanner.Err())
+	return exchanges
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recording")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "recording.jsonl")
+
+	features := newReply(t, messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor:  proto.String("Skycoin Foundation"),
+		FwMajor: proto.Uint32(1),
+		FwMinor: proto.Uint32(7),
+		FwPatch: proto.Uint32(0),
+	})
+	success := newReply(t, messages.MessageType_MessageType_Success, &messages.Success{
+		Message: proto.String("Mnemonic successfully configured"),
+	})
+	entropy := newReply(t, messages.MessageType_MessageType_Entropy, &messages.Entropy{
+		Entropy: []byte{1, 2, 3, 4},
+	})
+	corrupted := wire.Message{
+		Kind: uint16(messages.MessageType_MessageType_Features),
+		Data: []byte{0xff, 0xff},
+	}
+
+	driver := &fakeDriver{
+		replies: []reply{
+			{msg: features},
+			{msg: success},
+			{msg: entropy},
+			{err: skyWallet.ErrNoDeviceConnected},
+			{msg: corrupted},
+		},
+	}
+	recorder, err := NewRecorder(driver, path)
+	require.NoError(t, err)
+	device := &skyWallet.Device{Driver: recorder}
+
+	msg, err := device.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, features, msg)
+
+	mnemonic := "cloud flower upset remain green metal below cup stem infant art thank"
+	_, err = device.SetMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	entropyChunks, err := skyWallet.MessageDeviceGetRawEntropy(4)
+	require.NoError(t, err)
+	_, err = recorder.SendToDevice(virtualDevice{}, entropyChunks)
+	require.NoError(t, err)
+
+	_, err = device.GetFeatures()
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	_, err = device.GetFeatures()
+	require.NoError(t, err)
+
+	recorder.Close()
+	require.True(t, driver.closed)
+
+	// the secrets are not recorded
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "cloud flower")
+
+	exchanges := readRecording(t, path)
+	require.Len(t, exchanges, 5)
+
+	require.Equal(t, "MessageType_GetFeatures", exchanges[0].Request.Type)
+	require.Equal(t, "MessageType_Features", exchanges[0].Response.Type)
+	require.JSONEq(t, `{"vendor":"Skycoin Foundation","fw_major":1,"fw_minor":7,"fw_patch":0}`, string(exchanges[0].Response.Fields))
+
+	require.Equal(t, "MessageType_SetMnemonic", exchanges[1].Request.Type)
+	require.JSONEq(t, `{"mnemonic":"[redacted]"}`, string(exchanges[1].Request.Fields))
+	require.Equal(t, "MessageType_Success", exchanges[1].Response.Type)
+
+	require.Equal(t, "MessageType_GetRawEntropy", exchanges[2].Request.Type)
+	require.JSONEq(t, `{"entropy":"AAAAAA=="}`, string(exchanges[2].Response.Fields))
+
+	require.Nil(t, exchanges[3].Response)
+	require.Equal(t, skyWallet.ErrNoDeviceConnected.Error(), exchanges[3].Error)
+
+	// the corrupted response is recorded raw
+	require.Nil(t, exchanges[4].Response.Fields)
+	require.Equal(t, corrupted.Data, exchanges[4].Response.Data)
+
+	// the recording is replayed as a virtual device
+	player, err := LoadPlayer(path, skyWallet.DeviceTypeUSB)
+	require.NoError(t, err)
+	require.Equal(t, 5, player.Remaining())
+	device = &skyWallet.Device{Driver: player}
+
+	msg, err = device.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, features, msg)
+
+	// the messages are replayed in the order they were recorded
+	_, err = device.GetFeatures()
+	require.EqualError(t, err, "replay: unexpected MessageType_GetFeatures, exchange 2 of the recording is MessageType_SetMnemonic")
+
+	msg, err = device.SetMnemonic("another mnemonic")
+	require.NoError(t, err)
+	require.Equal(t, success, msg)
+
+	msg, err = player.SendToDevice(virtualDevice{}, entropyChunks)
+	require.NoError(t, err)
+	require.Equal(t, newReply(t, messages.MessageType_MessageType_Entropy, &messages.Entropy{
+		Entropy: []byte{0, 0, 0, 0},
+	}), msg)
+
+	_, err = device.GetFeatures()
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	msg, err = device.GetFeatures()
+	require.NoError(t, err)
+	require.Equal(t, corrupted, msg)
+
+	require.Zero(t, player.Remaining())
+	_, err = device.GetFeatures()
+	require.Equal(t, ErrEndOfRecording, err)
+}
+
+func TestLoadPlayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recording")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name      string
+		recording string
+		err       string
+	}{
+		{
+			name:      "invalid json",
+			recording: `{"request": []}`,
+			err:       "line 1",
+		},
+		{
+			name:      "unknown message type",
+			recording: "\n" + `{"request": {"type": "MessageType_Ping"}, "response": {"type": "MessageType_Foo"}}`,
+			err:       `line 2: unknown message type "MessageType_Foo"`,
+		},
+		{
+			name:      "invalid fields",
+			recording: `{"request": {"type": "MessageType_Ping"}, "response": {"type": "MessageType_Success", "fields": {"message": 1}}}`,
+			err:       "line 1: invalid fields of MessageType_Success",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.Replace(tc.name, " ", "-", -1)+".jsonl")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.recording), 0600))
+
+			_, err := LoadPlayer(path, skyWallet.DeviceTypeUSB)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	_, err = LoadPlayer(filepath.Join(dir, "missing.jsonl"), skyWallet.DeviceTypeUSB)
+	require.True(t, os.IsNotExist(err))
+}
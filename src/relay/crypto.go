@@ -0,0 +1,230 @@
+package relay
+
+import (
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// the keys of both directions are derived from the ECDH secret with distinct labels,
+// so the random nonces of the requests and the responses never collide under the same key
+const (
+	requestLabel  = "skywallet relay request"
+	responseLabel = "skywallet relay response"
+)
+
+// ErrDecrypt is returned for an envelope which is not encrypted for the daemon or was tampered with
+var ErrDecrypt = errors.New("envelope decryption failed")
+
+// Key is the key pair of the daemon, the public key identifies the daemon to its clients and names its relay channel
+type Key struct {
+	PubKey cipher.PubKey
+	SecKey cipher.SecKey
+}
+
+// LoadKey loads the hex encoded secret key of the file at path, generating it if the file does not exist
+func LoadKey(path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		pubKey, secKey := cipher.GenerateKeyPair()
+		if err := ioutil.WriteFile(path, []byte(secKey.Hex()+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write relay key: %v", err)
+		}
+		return &Key{
+			PubKey: pubKey,
+			SecKey: secKey,
+		}, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read relay key: %v", err)
+	}
+
+	secKey, err := cipher.SecKeyFromHex(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay key %s: %v", path, err)
+	}
+
+	pubKey, err := cipher.PubKeyFromSecKey(secKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay key %s: %v", path, err)
+	}
+
+	return &Key{
+		PubKey: pubKey,
+		SecKey: secKey,
+	}, nil
+}
+
+// Envelope is an encrypted request or response, as forwarded by the relay server
+type Envelope struct {
+	// ID is chosen by the client, the response carries the ID of its request
+	ID string `json:"id"`
+	// Client is the hex encoded public key of the client
+	Client string `json:"client"`
+	Nonce  []byte `json:"nonce"`
+	Data   []byte `json:"data"`
+}
+
+// Request is a request of a client to the daemon API, encrypted in an Envelope
+type Request struct {
+	// ID is the ID of the envelope, so the relay cannot swap the requests of the envelopes
+	ID string `json:"id"`
+	// Time is when the client sent the request, the daemon refuses the requests older than MaxRequestAge
+	Time time.Time `json:"time"`
+	// Method and Path, with its query string, of the request to the daemon API, e.g. POST /api/v1/sign_message
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   []byte            `json:"body,omitempty"`
+}
+
+// Response is the response of the daemon API to a Request, encrypted in an Envelope
+type Response struct {
+	ID     string            `json:"id"`
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   []byte            `json:"body,omitempty"`
+	// Error is set when the daemon refused the request without serving it
+	Error string `json:"error,omitempty"`
+}
+
+// sharedKey derives the AES-256 key of a direction from the ECDH secret of the client and the daemon
+func sharedKey(pub cipher.PubKey, sec cipher.SecKey, label string) ([]byte, error) {
+	secret, err := cipher.ECDH(pub, sec)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cipher.SumSHA256(append(secret, label...))
+	return key[:], nil
+}
+
+// additionalData binds an envelope to its daemon, client and ID
+func additionalData(daemon cipher.PubKey, env Envelope) []byte {
+	return []byte(daemon.Hex() + "\n" + env.Client + "\n" + env.ID)
+}
+
+func newAEAD(key []byte) (gocipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return gocipher.NewGCM(block)
+}
+
+// seal encrypts v in env with AES-256-GCM
+func seal(key []byte, daemon cipher.PubKey, env Envelope, v interface{}) (Envelope, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return Envelope{}, err
+	}
+
+	env.Data = aead.Seal(nil, env.Nonce, plaintext, additionalData(daemon, env))
+	return env, nil
+}
+
+// open decrypts env in v
+func open(key []byte, daemon cipher.PubKey, env Envelope, v interface{}) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	if len(env.Nonce) != aead.NonceSize() {
+		return ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Data, additionalData(daemon, env))
+	if err != nil {
+		return ErrDecrypt
+	}
+
+	return json.Unmarshal(plaintext, v)
+}
+
+// SealRequest encrypts req for the daemon, it is used by the clients
+func SealRequest(clientKey cipher.SecKey, daemon cipher.PubKey, req Request) (Envelope, error) {
+	clientPubKey, err := cipher.PubKeyFromSecKey(clientKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	key, err := sharedKey(daemon, clientKey, requestLabel)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return seal(key, daemon, Envelope{
+		ID:     req.ID,
+		Client: clientPubKey.Hex(),
+	}, req)
+}
+
+// OpenResponse decrypts the response of the daemon, it is used by the clients
+func OpenResponse(clientKey cipher.SecKey, daemon cipher.PubKey, env Envelope) (Response, error) {
+	key, err := sharedKey(daemon, clientKey, responseLabel)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var rsp Response
+	if err := open(key, daemon, env, &rsp); err != nil {
+		return Response{}, err
+	}
+
+	if rsp.ID != env.ID {
+		return Response{}, ErrDecrypt
+	}
+	return rsp, nil
+}
+
+// openRequest decrypts the request of the client of env
+func openRequest(key *Key, client cipher.PubKey, env Envelope) (Request, error) {
+	shared, err := sharedKey(client, key.SecKey, requestLabel)
+	if err != nil {
+		return Request{}, err
+	}
+
+	var req Request
+	if err := open(shared, key.PubKey, env, &req); err != nil {
+		return Request{}, err
+	}
+
+	if req.ID != env.ID {
+		return Request{}, ErrDecrypt
+	}
+	return req, nil
+}
+
+// sealResponse encrypts rsp for the client of env
+func sealResponse(key *Key, client cipher.PubKey, env Envelope, rsp Response) (Envelope, error) {
+	shared, err := sharedKey(client, key.SecKey, responseLabel)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	rsp.ID = env.ID
+	return seal(shared, key.PubKey, Envelope{
+		ID:     env.ID,
+		Client: env.Client,
+	}, rsp)
+}
@@ -0,0 +1,100 @@
+package relay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "relay.key")
+	key, err := LoadKey(path)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the key is reloaded
+	reloaded, err := LoadKey(path)
+	require.NoError(t, err)
+	require.Equal(t, key, reloaded)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("invalid"), 0600))
+	_, err = LoadKey(path)
+	require.Error(t, err)
+}
+
+func TestEnvelope(t *testing.T) {
+	daemonPubKey, daemonSecKey := cipher.GenerateKeyPair()
+	key := &Key{
+		PubKey: daemonPubKey,
+		SecKey: daemonSecKey,
+	}
+	clientPubKey, clientSecKey := cipher.GenerateKeyPair()
+
+	req := Request{
+		ID:     "1",
+		Time:   time.Now().UTC(),
+		Method: "GET",
+		Path:   "/api/v1/features",
+	}
+
+	env, err := SealRequest(clientSecKey, daemonPubKey, req)
+	require.NoError(t, err)
+	require.Equal(t, "1", env.ID)
+	require.Equal(t, clientPubKey.Hex(), env.Client)
+
+	opened, err := openRequest(key, clientPubKey, env)
+	require.NoError(t, err)
+	require.Equal(t, req, opened)
+
+	// the envelope cannot be altered
+	tampered := env
+	tampered.Data = append([]byte(nil), env.Data...)
+	tampered.Data[0] ^= 1
+	_, err = openRequest(key, clientPubKey, tampered)
+	require.Equal(t, ErrDecrypt, err)
+
+	tampered = env
+	tampered.ID = "2"
+	_, err = openRequest(key, clientPubKey, tampered)
+	require.Equal(t, ErrDecrypt, err)
+
+	// another daemon cannot read it
+	otherPubKey, otherSecKey := cipher.GenerateKeyPair()
+	_, err = openRequest(&Key{PubKey: otherPubKey, SecKey: otherSecKey}, clientPubKey, env)
+	require.Equal(t, ErrDecrypt, err)
+
+	// the response is only read by the client
+	out, err := sealResponse(key, clientPubKey, env, Response{
+		Status: 200,
+		Body:   []byte(`{"data":{}}`),
+	})
+	require.NoError(t, err)
+
+	rsp, err := OpenResponse(clientSecKey, daemonPubKey, out)
+	require.NoError(t, err)
+	require.Equal(t, Response{
+		ID:     "1",
+		Status: 200,
+		Body:   []byte(`{"data":{}}`),
+	}, rsp)
+
+	// a request is not accepted as a response
+	_, err = OpenResponse(clientSecKey, daemonPubKey, env)
+	require.Equal(t, ErrDecrypt, err)
+
+	_, otherClientSecKey := cipher.GenerateKeyPair()
+	_, err = OpenResponse(otherClientSecKey, daemonPubKey, out)
+	require.Equal(t, ErrDecrypt, err)
+}
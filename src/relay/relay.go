@@ -0,0 +1,741 @@
+// Package relay lets paired clients, such as a mobile wallet, use the daemon through a relay server controlled by
+// the user, so a device on the desk can be used from a phone without opening inbound ports to the daemon.
+//
+// The daemon polls its channel on the relay server with outbound HTTP requests:
+//
+//	GET  <relay>/channels/<daemon pubkey>/requests?wait=<seconds>   long polls the request envelopes, a JSON array
+//	POST <relay>/channels/<daemon pubkey>/responses                 posts a response envelope
+//
+// The requests and responses are end-to-end encrypted between the client and the daemon with a key derived from
+// their ECDH secret, the relay server only forwards envelopes it cannot read nor alter.
+// The daemon only serves the clients paired on its local API, and refuses the requests older than MaxRequestAge
+// or already served, so the relay server cannot replay them.
+//
+// A client can also pair itself with a one-time pairing code created on the local API, usually scanned from
+// the QR code of the pairing URI:
+//
+//	skywallet-relay://pair?code=<code>&pubkey=<daemon pubkey>&url=<relay>
+//
+// The client sends a POST request to PairPath through the relay, with a PairRequest body carrying the code.
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const (
+	// Bucket is the storage bucket of the paired clients
+	Bucket = "relay_clients"
+
+	// MaxRequestAge is how old a request served by the daemon can be, the clock of the client may be that much off
+	MaxRequestAge = 2 * time.Minute
+	// PollWait is how long the relay server holds a poll without requests
+	PollWait = 30 * time.Second
+	// RequestTimeout is how long a request is served, including the confirmations on the device
+	RequestTimeout = 5 * time.Minute
+
+	// PairPath is the path of the requests pairing a client with a pairing code, only served through the relay
+	PairPath = "/relay/pair"
+	// PairingCodeTTL is how long a pairing code can be used
+	PairingCodeTTL = 10 * time.Minute
+	// PairingURIScheme is the scheme of the pairing URIs
+	PairingURIScheme = "skywallet-relay"
+
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+
+	// maxPairingAttempts is how many invalid pairing codes revoke the pending pairing codes,
+	// so they cannot be guessed
+	maxPairingAttempts = 5
+	pairingCodeSize    = 10
+
+	maxClientNameLength = 64
+	maxBodySize         = 1 << 20
+)
+
+var (
+	logger = logging.MustGetLogger("relay")
+
+	// ErrNotPaired is returned for a client which is not paired
+	ErrNotPaired = errors.New("client is not paired")
+	// ErrInvalidName is returned for a client name which is empty or too long
+	ErrInvalidName = fmt.Errorf("client name must have 1 to %d characters", maxClientNameLength)
+	// ErrInvalidPairingCode is returned for a pairing code which was not created, used already or expired
+	ErrInvalidPairingCode = errors.New("invalid or expired pairing code")
+
+	pairingCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	// localPaths are the endpoints not served to the relay clients, the pairing, admin and emulator endpoints
+	// are only served to the local clients and the event stream does not end
+	localPaths = []string{
+		"/relay",
+		"/admin/",
+		"/emulator",
+		"/events",
+	}
+
+	// apiPrefixes are the prefixes of the API endpoints, of each API version
+	apiPrefixes = []string{
+		"/api/v1",
+		"/api/v2",
+	}
+)
+
+// Client is a paired client
+type Client struct {
+	// PubKey is the hex encoded public key of the client
+	PubKey   string     `json:"pubkey"`
+	Name     string     `json:"name"`
+	PairedAt time.Time  `json:"paired_at"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// Status is the status of the connection to the relay server
+type Status struct {
+	URL string `json:"url"`
+	// PubKey is the hex encoded public key of the daemon, it names the channel of the daemon on the relay server
+	PubKey    string `json:"pubkey"`
+	Connected bool   `json:"connected"`
+	// Error is the error of the last poll, when the relay server cannot be reached
+	Error string `json:"error,omitempty"`
+}
+
+// PairingCode is a one-time code pairing the client using it through the relay
+type PairingCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// URI carries the relay server, the public key of the daemon and the code, to be scanned from a QR code
+	URI string `json:"uri"`
+}
+
+// PairRequest is the body of a request to PairPath
+type PairRequest struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Relay serves the requests of the paired clients forwarded by the relay server
+type Relay struct {
+	url    string
+	key    *Key
+	store  storage.Store
+	client *http.Client
+
+	// pairMu serializes the updates of the paired clients
+	pairMu sync.Mutex
+
+	mu        sync.Mutex
+	connected bool
+	err       error
+	// seen holds the requests served within MaxRequestAge, by client and ID
+	seen map[string]time.Time
+	// pairingCodes holds the expiry of the pending pairing codes
+	pairingCodes    map[string]time.Time
+	pairingFailures int
+}
+
+// New creates a Relay using the relay server at relayURL, the clients are paired in store.
+// The requests to the relay server are sent by transport, nil means http.DefaultTransport.
+func New(relayURL string, key *Key, store storage.Store, transport http.RoundTripper) *Relay {
+	return &Relay{
+		url:   strings.TrimSuffix(relayURL, "/"),
+		key:   key,
+		store: store,
+		client: &http.Client{
+			Timeout:   PollWait + 30*time.Second,
+			Transport: transport,
+		},
+		seen:         make(map[string]time.Time),
+		pairingCodes: make(map[string]time.Time),
+	}
+}
+
+// Status returns the status of the connection to the relay server
+func (r *Relay) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Status{
+		URL:       r.url,
+		PubKey:    r.key.PubKey.Hex(),
+		Connected: r.connected,
+	}
+	if r.err != nil {
+		s.Error = r.err.Error()
+	}
+	return s
+}
+
+// Pair pairs the client with pubKey, renaming it if it is paired already
+func (r *Relay) Pair(pubKey cipher.PubKey, name string) (Client, error) {
+	if name == "" || len(name) > maxClientNameLength {
+		return Client{}, ErrInvalidName
+	}
+
+	r.pairMu.Lock()
+	defer r.pairMu.Unlock()
+
+	c, err := r.pairedClient(pubKey)
+	switch err {
+	case nil:
+	case ErrNotPaired:
+		c = Client{
+			PubKey:   pubKey.Hex(),
+			PairedAt: time.Now().UTC(),
+		}
+	default:
+		return Client{}, err
+	}
+	c.Name = name
+
+	if err := r.putClient(c); err != nil {
+		return Client{}, err
+	}
+
+	logger.Infof("Paired relay client %s %s", c.Name, c.PubKey)
+	return c, nil
+}
+
+// Unpair removes the pairing of the client with pubKey, its requests are not served anymore
+func (r *Relay) Unpair(pubKey cipher.PubKey) error {
+	r.pairMu.Lock()
+	defer r.pairMu.Unlock()
+
+	c, err := r.pairedClient(pubKey)
+	if err != nil {
+		return err
+	}
+
+	if err := r.store.Delete(Bucket, c.PubKey); err != nil {
+		return err
+	}
+
+	logger.Infof("Unpaired relay client %s %s", c.Name, c.PubKey)
+	return nil
+}
+
+// Clients returns the paired clients, sorted by pairing time
+func (r *Relay) Clients() ([]Client, error) {
+	var clients []Client
+	if err := r.store.ForEach(Bucket, func(_ string, value []byte) error {
+		var c Client
+		if err := json.Unmarshal(value, &c); err != nil {
+			return err
+		}
+		clients = append(clients, c)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].PairedAt.Before(clients[j].PairedAt)
+	})
+
+	return clients, nil
+}
+
+// NewPairingCode creates a pairing code valid for PairingCodeTTL
+func (r *Relay) NewPairingCode() (PairingCode, error) {
+	b := make([]byte, pairingCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return PairingCode{}, err
+	}
+	code := pairingCodeEncoding.EncodeToString(b)
+
+	now := time.Now()
+	expiresAt := now.Add(PairingCodeTTL).UTC()
+
+	r.mu.Lock()
+	r.removeExpiredPairingCodes(now)
+	r.pairingCodes[code] = expiresAt
+	r.mu.Unlock()
+
+	uri := url.URL{
+		Scheme: PairingURIScheme,
+		Host:   "pair",
+		RawQuery: url.Values{
+			"url":    []string{r.url},
+			"pubkey": []string{r.key.PubKey.Hex()},
+			"code":   []string{code},
+		}.Encode(),
+	}
+
+	logger.Infof("Created a relay pairing code expiring at %s", expiresAt.Format(time.RFC3339))
+	return PairingCode{
+		Code:      code,
+		ExpiresAt: expiresAt,
+		URI:       uri.String(),
+	}, nil
+}
+
+// usePairingCode removes the pairing code, it returns ErrInvalidPairingCode if it is not pending
+func (r *Relay) usePairingCode(code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeExpiredPairingCodes(time.Now())
+
+	if _, ok := r.pairingCodes[code]; !ok {
+		r.pairingFailures++
+		if r.pairingFailures >= maxPairingAttempts {
+			logger.Warningf("Revoked the relay pairing codes after %d invalid pairing codes", r.pairingFailures)
+			r.pairingCodes = make(map[string]time.Time)
+			r.pairingFailures = 0
+		}
+		return ErrInvalidPairingCode
+	}
+
+	delete(r.pairingCodes, code)
+	return nil
+}
+
+func (r *Relay) removeExpiredPairingCodes(now time.Time) {
+	for code, expiresAt := range r.pairingCodes {
+		if now.After(expiresAt) {
+			delete(r.pairingCodes, code)
+		}
+	}
+}
+
+func (r *Relay) pairedClient(pubKey cipher.PubKey) (Client, error) {
+	value, err := r.store.Get(Bucket, pubKey.Hex())
+	switch err {
+	case nil:
+	case storage.ErrNotFound:
+		return Client{}, ErrNotPaired
+	default:
+		return Client{}, err
+	}
+
+	var c Client
+	if err := json.Unmarshal(value, &c); err != nil {
+		return Client{}, err
+	}
+	return c, nil
+}
+
+// touch sets the time the client was last seen, unless it was unpaired meanwhile
+func (r *Relay) touch(pubKey cipher.PubKey) error {
+	r.pairMu.Lock()
+	defer r.pairMu.Unlock()
+
+	c, err := r.pairedClient(pubKey)
+	switch err {
+	case nil:
+	case ErrNotPaired:
+		return nil
+	default:
+		return err
+	}
+
+	now := time.Now().UTC()
+	c.LastSeen = &now
+	return r.putClient(c)
+}
+
+func (r *Relay) putClient(c Client) error {
+	value, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return r.store.Put(Bucket, c.PubKey, value)
+}
+
+// Run polls the relay server until quit is closed, serving the requests with handler as if they were sent to host
+func (r *Relay) Run(handler http.Handler, host string, quit <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-quit
+		cancel()
+	}()
+
+	logger.Infof("Polling the relay server %s", r.url)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		envs, err := r.poll(ctx)
+		r.setStatus(err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			logger.WithError(err).Errorf("Relay server poll failed, retrying in %s", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+
+		backoff = minBackoff
+
+		for _, env := range envs {
+			wg.Add(1)
+			go func(env Envelope) {
+				defer wg.Done()
+				r.serve(ctx, handler, host, env)
+			}(env)
+		}
+	}
+}
+
+func (r *Relay) setStatus(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connected = err == nil
+	r.err = err
+}
+
+func (r *Relay) channelURL(path string) string {
+	return fmt.Sprintf("%s/channels/%s/%s", r.url, r.key.PubKey.Hex(), path)
+}
+
+// poll returns the request envelopes queued on the relay server, waiting up to PollWait for one
+func (r *Relay) poll(ctx context.Context) ([]Envelope, error) {
+	u := r.channelURL("requests") + "?" + url.Values{
+		"wait": []string{fmt.Sprint(int(PollWait / time.Second))},
+	}.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("relay server returned %s", rsp.Status)
+	}
+
+	var envs []Envelope
+	if err := json.NewDecoder(rsp.Body).Decode(&envs); err != nil {
+		return nil, fmt.Errorf("invalid relay server response: %v", err)
+	}
+	return envs, nil
+}
+
+// respond posts a response envelope to the relay server
+func (r *Relay) respond(ctx context.Context, env Envelope) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.channelURL("responses"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("relay server returned %s", rsp.Status)
+	}
+	return nil
+}
+
+// serve serves the request of env and posts its response.
+// The envelopes of clients which are not paired, unless they pair with a pairing code, or which cannot be decrypted
+// are dropped, their response could not be encrypted for a client the daemon trusts.
+func (r *Relay) serve(ctx context.Context, handler http.Handler, host string, env Envelope) {
+	log := logger.WithField("client", env.Client).WithField("id", env.ID)
+
+	pubKey, err := cipher.PubKeyFromHex(env.Client)
+	if err != nil {
+		log.WithError(err).Error("Dropped relay request with an invalid client key")
+		return
+	}
+
+	c, err := r.pairedClient(pubKey)
+	paired := err == nil
+	if err != nil && err != ErrNotPaired {
+		log.WithError(err).Error("Dropped relay request")
+		return
+	}
+
+	req, err := openRequest(r.key, pubKey, env)
+	if err != nil {
+		log.WithError(err).Error("Dropped relay request")
+		return
+	}
+
+	var rsp Response
+	switch {
+	case req.Path == PairPath:
+		rsp = r.pairWithCode(pubKey, req)
+	case !paired:
+		log.WithError(ErrNotPaired).Error("Dropped relay request")
+		return
+	default:
+		rsp = r.handle(ctx, handler, host, c, req)
+
+		if err := r.touch(pubKey); err != nil {
+			log.WithError(err).Error("Failed to store the relay client")
+		}
+	}
+
+	out, err := sealResponse(r.key, pubKey, env, rsp)
+	if err != nil {
+		log.WithError(err).Error("Failed to encrypt the relay response")
+		return
+	}
+
+	if err := r.respond(ctx, out); err != nil {
+		log.WithError(err).Error("Failed to post the relay response")
+	}
+}
+
+// pairWithCode pairs the client with pubKey if its request carries a pending pairing code.
+// The response has the body of the local API responses, with the paired client as data.
+func (r *Relay) pairWithCode(pubKey cipher.PubKey, req Request) Response {
+	log := logger.WithField("client", pubKey.Hex())
+
+	if req.Method != http.MethodPost {
+		return Response{
+			Status: http.StatusMethodNotAllowed,
+			Error:  "pairing requests must be POST requests",
+		}
+	}
+
+	if err := r.checkReplay(pubKey.Hex(), req); err != nil {
+		log.WithError(err).Error("Refused relay pairing request")
+		return Response{
+			Status: http.StatusForbidden,
+			Error:  err.Error(),
+		}
+	}
+
+	var body PairRequest
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return Response{
+			Status: http.StatusBadRequest,
+			Error:  err.Error(),
+		}
+	}
+
+	// an invalid name does not use the pairing code
+	if body.Name == "" || len(body.Name) > maxClientNameLength {
+		return Response{
+			Status: http.StatusBadRequest,
+			Error:  ErrInvalidName.Error(),
+		}
+	}
+
+	if err := r.usePairingCode(body.Code); err != nil {
+		log.WithError(err).Error("Refused relay pairing request")
+		return Response{
+			Status: http.StatusForbidden,
+			Error:  err.Error(),
+		}
+	}
+
+	c, err := r.Pair(pubKey, body.Name)
+	if err != nil {
+		log.WithError(err).Error("Relay pairing failed")
+		return Response{
+			Status: http.StatusInternalServerError,
+			Error:  err.Error(),
+		}
+	}
+
+	data, err := json.Marshal(struct {
+		Data Client `json:"data"`
+	}{c})
+	if err != nil {
+		return Response{
+			Status: http.StatusInternalServerError,
+			Error:  err.Error(),
+		}
+	}
+
+	return Response{
+		Status: http.StatusOK,
+		Header: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: data,
+	}
+}
+
+// handle serves the decrypted request of c with handler
+func (r *Relay) handle(ctx context.Context, handler http.Handler, host string, c Client, req Request) Response {
+	u, err := r.check(c, req)
+	if err != nil {
+		logger.WithField("client", c.PubKey).WithError(err).Errorf("Refused relay request %s %s", req.Method, req.Path)
+		return Response{
+			Status: http.StatusForbidden,
+			Error:  err.Error(),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+
+	// the request is served on the checked path
+	hr, err := http.NewRequest(req.Method, u.String(), bytes.NewReader(req.Body))
+	if err != nil {
+		return Response{
+			Status: http.StatusBadRequest,
+			Error:  err.Error(),
+		}
+	}
+	for k, v := range req.Header {
+		hr.Header.Set(k, v)
+	}
+	hr.Host = host
+	hr.RemoteAddr = "relay"
+
+	logger.Infof("Relay client %s: %s %s", c.Name, req.Method, req.Path)
+
+	w := newResponseBuffer()
+	handler.ServeHTTP(w, hr.WithContext(ctx))
+
+	rsp := Response{
+		Status: w.status,
+		Header: make(map[string]string, len(w.header)),
+		Body:   w.body.Bytes(),
+	}
+	for k := range w.header {
+		rsp.Header[k] = w.header.Get(k)
+	}
+	return rsp
+}
+
+// check refuses the requests to the local endpoints, the stale requests and the requests already served.
+// The endpoint is checked on the path routed by the API, decoded and without dot segments, the returned URL of the
+// request has this path.
+func (r *Relay) check(c Client, req Request) (*url.URL, error) {
+	u, err := url.ParseRequestURI(req.Path)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return nil, fmt.Errorf("%s is not an API endpoint", req.Path)
+	}
+
+	p := path.Clean(u.Path)
+	if strings.HasSuffix(u.Path, "/") && p != "/" {
+		p += "/"
+	}
+	u.Path = p
+	u.RawPath = ""
+
+	endpoint, ok := apiEndpoint(p)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an API endpoint", p)
+	}
+	for _, lp := range localPaths {
+		if strings.HasPrefix(endpoint, lp) {
+			return nil, fmt.Errorf("%s is only served to local clients", p)
+		}
+	}
+	if len(req.Body) > maxBodySize {
+		return nil, errors.New("request body too large")
+	}
+
+	if err := r.checkReplay(c.PubKey, req); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// apiEndpoint returns the endpoint of an API path without its API version, false if path is not an API endpoint
+func apiEndpoint(path string) (string, bool) {
+	for _, prefix := range apiPrefixes {
+		if strings.HasPrefix(path, prefix+"/") {
+			return strings.TrimPrefix(path, prefix), true
+		}
+	}
+	return "", false
+}
+
+// checkReplay refuses the stale requests and the requests of the client already served
+func (r *Relay) checkReplay(client string, req Request) error {
+	now := time.Now()
+	if age := now.Sub(req.Time); age > MaxRequestAge || age < -MaxRequestAge {
+		return errors.New("request expired, check the clock of the client")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, t := range r.seen {
+		if now.Sub(t) > 2*MaxRequestAge {
+			delete(r.seen, id)
+		}
+	}
+
+	id := client + "/" + req.ID
+	if _, ok := r.seen[id]; ok {
+		return errors.New("request already served")
+	}
+	r.seen[id] = now
+
+	return nil
+}
+
+// responseBuffer records the response of the API to a relay request
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
@@ -0,0 +1,458 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const apiHost = "127.0.0.1:9510"
+
+func newTestKey() *Key {
+	pubKey, secKey := cipher.GenerateKeyPair()
+	return &Key{
+		PubKey: pubKey,
+		SecKey: secKey,
+	}
+}
+
+func TestPairing(t *testing.T) {
+	r := New("https://relay.example.com/", newTestKey(), storage.NewMemoryStore(), nil)
+	require.Equal(t, "https://relay.example.com", r.Status().URL)
+
+	clients, err := r.Clients()
+	require.NoError(t, err)
+	require.Empty(t, clients)
+
+	pubKey, _ := cipher.GenerateKeyPair()
+	_, err = r.Pair(pubKey, "")
+	require.Equal(t, ErrInvalidName, err)
+
+	c, err := r.Pair(pubKey, "phone")
+	require.NoError(t, err)
+	require.Equal(t, pubKey.Hex(), c.PubKey)
+	require.Equal(t, "phone", c.Name)
+
+	// pairing again renames the client
+	renamed, err := r.Pair(pubKey, "my phone")
+	require.NoError(t, err)
+	require.Equal(t, c.PairedAt, renamed.PairedAt)
+
+	otherPubKey, _ := cipher.GenerateKeyPair()
+	_, err = r.Pair(otherPubKey, "tablet")
+	require.NoError(t, err)
+
+	clients, err = r.Clients()
+	require.NoError(t, err)
+	require.Len(t, clients, 2)
+	require.Equal(t, "my phone", clients[0].Name)
+	require.Equal(t, "tablet", clients[1].Name)
+
+	require.NoError(t, r.Unpair(pubKey))
+	require.Equal(t, ErrNotPaired, r.Unpair(pubKey))
+
+	clients, err = r.Clients()
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	require.Equal(t, otherPubKey.Hex(), clients[0].PubKey)
+}
+
+func TestPairingCode(t *testing.T) {
+	r := New("https://relay.example.com", newTestKey(), storage.NewMemoryStore(), nil)
+
+	pc, err := r.NewPairingCode()
+	require.NoError(t, err)
+	require.Len(t, pc.Code, 16)
+	require.WithinDuration(t, time.Now().Add(PairingCodeTTL), pc.ExpiresAt, time.Minute)
+
+	u, err := url.Parse(pc.URI)
+	require.NoError(t, err)
+	require.Equal(t, "skywallet-relay", u.Scheme)
+	require.Equal(t, "pair", u.Host)
+	require.Equal(t, url.Values{
+		"url":    []string{"https://relay.example.com"},
+		"pubkey": []string{r.key.PubKey.Hex()},
+		"code":   []string{pc.Code},
+	}, u.Query())
+
+	// a code is used once
+	require.NoError(t, r.usePairingCode(pc.Code))
+	require.Equal(t, ErrInvalidPairingCode, r.usePairingCode(pc.Code))
+
+	// the codes expire
+	pc, err = r.NewPairingCode()
+	require.NoError(t, err)
+	r.pairingCodes[pc.Code] = time.Now().Add(-time.Second)
+	require.Equal(t, ErrInvalidPairingCode, r.usePairingCode(pc.Code))
+
+	// guessing revokes the pending codes
+	r = New("https://relay.example.com", newTestKey(), storage.NewMemoryStore(), nil)
+	pc, err = r.NewPairingCode()
+	require.NoError(t, err)
+	for i := 0; i < maxPairingAttempts; i++ {
+		require.Equal(t, ErrInvalidPairingCode, r.usePairingCode("guess"))
+	}
+	require.Equal(t, ErrInvalidPairingCode, r.usePairingCode(pc.Code))
+}
+
+// fakeRelayServer queues the request envelopes for the daemon and collects its responses
+type fakeRelayServer struct {
+	mu        sync.Mutex
+	requests  []Envelope
+	responses chan Envelope
+}
+
+func (s *fakeRelayServer) push(env Envelope) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, env)
+}
+
+func (s *fakeRelayServer) handler(channel string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/channels/"+channel+"/requests", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		envs := s.requests
+		s.requests = nil
+		s.mu.Unlock()
+
+		if len(envs) == 0 {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		json.NewEncoder(w).Encode(envs) // nolint: errcheck
+	})
+	mux.HandleFunc("/channels/"+channel+"/responses", func(w http.ResponseWriter, r *http.Request) {
+		var env Envelope
+		if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.responses <- env
+	})
+	return mux
+}
+
+func TestRun(t *testing.T) {
+	server := &fakeRelayServer{
+		responses: make(chan Envelope, 10),
+	}
+
+	key := newTestKey()
+	ts := httptest.NewServer(server.handler(key.PubKey.Hex()))
+	defer ts.Close()
+
+	r := New(ts.URL, key, storage.NewMemoryStore(), nil)
+
+	clientPubKey, clientSecKey := cipher.GenerateKeyPair()
+	_, err := r.Pair(clientPubKey, "phone")
+	require.NoError(t, err)
+
+	// the API answers as if the requests were local
+	api := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, apiHost, req.Host)
+		w.Header().Set("Content-Type", "application/json")
+		if req.URL.Path != "/api/v1/features" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(`{"data":"` + req.Method + " " + req.URL.RequestURI() + `"}`)) // nolint: errcheck
+	})
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(api, apiHost, quit)
+	}()
+
+	send := func(req Request) Envelope {
+		env, err := SealRequest(clientSecKey, r.key.PubKey, req)
+		require.NoError(t, err)
+		server.push(env)
+		return env
+	}
+
+	receive := func() Response {
+		select {
+		case env := <-server.responses:
+			rsp, err := OpenResponse(clientSecKey, r.key.PubKey, env)
+			require.NoError(t, err)
+			return rsp
+		case <-time.After(5 * time.Second):
+			t.Fatal("no response")
+			return Response{}
+		}
+	}
+
+	req := Request{
+		ID:     "1",
+		Time:   time.Now(),
+		Method: http.MethodGet,
+		Path:   "/api/v1/features?verbose=1",
+	}
+	send(req)
+
+	rsp := receive()
+	require.Equal(t, "1", rsp.ID)
+	require.Equal(t, http.StatusOK, rsp.Status)
+	require.Equal(t, "application/json", rsp.Header["Content-Type"])
+	require.Equal(t, `{"data":"GET /api/v1/features?verbose=1"}`, string(rsp.Body))
+	require.True(t, r.Status().Connected)
+
+	clients, err := r.Clients()
+	require.NoError(t, err)
+	require.NotNil(t, clients[0].LastSeen)
+
+	// the relay cannot replay a request
+	send(req)
+	rsp = receive()
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+	require.Equal(t, "request already served", rsp.Error)
+
+	send(Request{
+		ID:     "2",
+		Time:   time.Now().Add(-time.Hour),
+		Method: http.MethodGet,
+		Path:   "/api/v1/features",
+	})
+	rsp = receive()
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+	require.Equal(t, "request expired, check the clock of the client", rsp.Error)
+
+	send(Request{
+		ID:     "3",
+		Time:   time.Now(),
+		Method: http.MethodPost,
+		Path:   "/api/v1/relay/clients",
+	})
+	rsp = receive()
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+	require.Equal(t, "/api/v1/relay/clients is only served to local clients", rsp.Error)
+
+	send(Request{
+		ID:     "3b",
+		Time:   time.Now(),
+		Method: http.MethodPost,
+		Path:   "/api/v2/relay/clients",
+	})
+	rsp = receive()
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+	require.Equal(t, "/api/v2/relay/clients is only served to local clients", rsp.Error)
+
+	send(Request{
+		ID:     "3c",
+		Time:   time.Now(),
+		Method: http.MethodGet,
+		Path:   "/api/v3/features",
+	})
+	rsp = receive()
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+	require.Equal(t, "/api/v3/features is not an API endpoint", rsp.Error)
+
+	// the local endpoints are refused whatever the escapes and dot segments of their path
+	for i, tc := range []struct {
+		path string
+		err  string
+	}{
+		{"/api/v2/%72elay/pairing_code", "/api/v2/relay/pairing_code is only served to local clients"},
+		{"/api/v2/%61dmin/mode", "/api/v2/admin/mode is only served to local clients"},
+		{"/api/v2/%65mulator/wipe", "/api/v2/emulator/wipe is only served to local clients"},
+		{"/api/v2/features/../relay/pairing_code", "/api/v2/relay/pairing_code is only served to local clients"},
+		{"/api/v1/./admin//mode", "/api/v1/admin/mode is only served to local clients"},
+		{"/api/v2/../../admin/mode", "/admin/mode is not an API endpoint"},
+		{"http://localhost/api/v2/features", "http://localhost/api/v2/features is not an API endpoint"},
+	} {
+		send(Request{
+			ID:     fmt.Sprintf("3d%d", i),
+			Time:   time.Now(),
+			Method: http.MethodPost,
+			Path:   tc.path,
+		})
+		rsp = receive()
+		require.Equal(t, http.StatusForbidden, rsp.Status, tc.path)
+		require.Equal(t, tc.err, rsp.Error, tc.path)
+	}
+
+	// the API serves the checked path
+	send(Request{
+		ID:     "3e",
+		Time:   time.Now(),
+		Method: http.MethodGet,
+		Path:   "/api/v1/%66eatures?verbose=1",
+	})
+	rsp = receive()
+	require.Equal(t, http.StatusOK, rsp.Status)
+	require.Equal(t, `{"data":"GET /api/v1/features?verbose=1"}`, string(rsp.Body))
+
+	send(Request{
+		ID:     "4",
+		Time:   time.Now(),
+		Method: http.MethodGet,
+		Path:   "/api/v1/wipe",
+	})
+	rsp = receive()
+	require.Equal(t, http.StatusNotFound, rsp.Status)
+
+	// the requests of unpaired clients are dropped
+	require.NoError(t, r.Unpair(clientPubKey))
+	send(Request{
+		ID:     "5",
+		Time:   time.Now(),
+		Method: http.MethodGet,
+		Path:   "/api/v1/features",
+	})
+
+	select {
+	case <-server.responses:
+		t.Fatal("unpaired client served")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	clients, err = r.Clients()
+	require.NoError(t, err)
+	require.Empty(t, clients)
+
+	close(quit)
+	<-done
+}
+
+func TestRunPairWithCode(t *testing.T) {
+	server := &fakeRelayServer{
+		responses: make(chan Envelope, 10),
+	}
+
+	key := newTestKey()
+	ts := httptest.NewServer(server.handler(key.PubKey.Hex()))
+	defer ts.Close()
+
+	r := New(ts.URL, key, storage.NewMemoryStore(), nil)
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(http.NotFoundHandler(), apiHost, quit)
+	}()
+
+	clientPubKey, clientSecKey := cipher.GenerateKeyPair()
+
+	id := 0
+	send := func(method, path string, body interface{}) Response {
+		id++
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		env, err := SealRequest(clientSecKey, r.key.PubKey, Request{
+			ID:     fmt.Sprint(id),
+			Time:   time.Now(),
+			Method: method,
+			Path:   path,
+			Body:   data,
+		})
+		require.NoError(t, err)
+		server.push(env)
+
+		select {
+		case env := <-server.responses:
+			rsp, err := OpenResponse(clientSecKey, r.key.PubKey, env)
+			require.NoError(t, err)
+			return rsp
+		case <-time.After(5 * time.Second):
+			t.Fatal("no response")
+			return Response{}
+		}
+	}
+
+	pc, err := r.NewPairingCode()
+	require.NoError(t, err)
+
+	rsp := send(http.MethodPost, PairPath, PairRequest{
+		Code: "invalid",
+		Name: "phone",
+	})
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+	require.Equal(t, "invalid or expired pairing code", rsp.Error)
+
+	// an invalid name does not use the code
+	rsp = send(http.MethodPost, PairPath, PairRequest{
+		Code: pc.Code,
+	})
+	require.Equal(t, http.StatusBadRequest, rsp.Status)
+	require.Equal(t, ErrInvalidName.Error(), rsp.Error)
+
+	rsp = send(http.MethodGet, PairPath, nil)
+	require.Equal(t, http.StatusMethodNotAllowed, rsp.Status)
+
+	rsp = send(http.MethodPost, PairPath, PairRequest{
+		Code: pc.Code,
+		Name: "phone",
+	})
+	require.Equal(t, http.StatusOK, rsp.Status)
+
+	var body struct {
+		Data Client `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rsp.Body, &body))
+	require.Equal(t, clientPubKey.Hex(), body.Data.PubKey)
+	require.Equal(t, "phone", body.Data.Name)
+
+	clients, err := r.Clients()
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+
+	// the paired client is served
+	rsp = send(http.MethodGet, "/api/v1/features", nil)
+	require.Equal(t, http.StatusNotFound, rsp.Status)
+
+	// the code was used
+	_, clientSecKey = cipher.GenerateKeyPair()
+	rsp = send(http.MethodPost, PairPath, PairRequest{
+		Code: pc.Code,
+		Name: "tablet",
+	})
+	require.Equal(t, http.StatusForbidden, rsp.Status)
+
+	close(quit)
+	<-done
+}
+
+func TestRunUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	r := New(ts.URL, newTestKey(), storage.NewMemoryStore(), nil)
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(http.NotFoundHandler(), apiHost, quit)
+	}()
+
+	// the poll fails and is retried after a backoff
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Status().Error == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	status := r.Status()
+	require.False(t, status.Connected)
+	require.Equal(t, "relay server returned 502 Bad Gateway", status.Error)
+
+	close(quit)
+	<-done
+}
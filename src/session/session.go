@@ -0,0 +1,226 @@
+// Package session lets a client hold the device for a series of operations, for deployments where the
+// daemon runs unattended. While a session is open the device endpoints only serve that session, and the
+// device is locked, forgetting the passphrase it cached, when the session is closed or stays idle too long.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+const (
+	// DefaultIdleTimeout is how long a session stays open without operations by default
+	DefaultIdleTimeout = 5 * time.Minute
+
+	idSize = 16
+)
+
+var (
+	logger = logging.MustGetLogger("session")
+
+	// ErrLocked is returned when the device is requested outside of the open session
+	ErrLocked = errors.New("device is locked by another session")
+	// ErrInvalidSession is returned for a session ID which is not the one of the open session
+	ErrInvalidSession = errors.New("invalid or expired session")
+	// ErrSessionRequired is returned when the device is requested without session and sessions are required
+	ErrSessionRequired = errors.New("session required")
+)
+
+// Config configures the sessions
+type Config struct {
+	// IdleTimeout is how long a session stays open without operations
+	IdleTimeout time.Duration
+	// Required rejects the device requests made outside of a session
+	Required bool
+}
+
+// Session is an open session
+type Session struct {
+	ID string `json:"id"`
+	// CachePassphrase keeps the passphrase cached by the device between the operations of the session,
+	// otherwise the device is locked after each operation
+	CachePassphrase bool      `json:"cache_passphrase"`
+	Created         time.Time `json:"created"`
+	LastUsed        time.Time `json:"last_used"`
+	// ExpiresAt is when the session is closed if it stays idle, it is extended by each operation
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Manager holds the open session and locks the device when it ends
+type Manager struct {
+	config Config
+	// lock locks the device, it is called without holding mu
+	lock func() error
+
+	mu       sync.Mutex
+	current  *Session
+	inFlight int
+	timer    *time.Timer
+	now      func() time.Time
+}
+
+// NewManager creates a Manager locking the device with lock
+func NewManager(config Config, lock func() error) *Manager {
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultIdleTimeout
+	}
+
+	return &Manager{
+		config: config,
+		lock:   lock,
+		now:    time.Now,
+	}
+}
+
+// Config returns the configuration of the sessions
+func (m *Manager) Config() Config {
+	return m.config
+}
+
+// Open opens a session, returning ErrLocked if a session is already open
+func (m *Manager) Open(cachePassphrase bool) (Session, error) {
+	b := make([]byte, idSize)
+	if _, err := rand.Read(b); err != nil {
+		return Session{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil {
+		return Session{}, ErrLocked
+	}
+
+	now := m.now()
+	m.current = &Session{
+		ID:              hex.EncodeToString(b),
+		CachePassphrase: cachePassphrase,
+		Created:         now,
+		LastUsed:        now,
+	}
+	m.schedule(m.config.IdleTimeout)
+
+	return m.session(), nil
+}
+
+// Get returns the open session with this id
+func (m *Manager) Get(id string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current == nil || m.current.ID != id {
+		return Session{}, ErrInvalidSession
+	}
+
+	return m.session(), nil
+}
+
+// Close closes the session with this id and locks the device
+func (m *Manager) Close(id string) error {
+	m.mu.Lock()
+	if m.current == nil || m.current.ID != id {
+		m.mu.Unlock()
+		return ErrInvalidSession
+	}
+	m.end()
+	m.mu.Unlock()
+
+	return m.lock()
+}
+
+// Begin starts an operation of the session with this id, an empty id for an operation outside of a session.
+// The returned function must be called when the operation ends, completed is false if the device waits
+// for the user input of an intermediate request. The device is locked after the completed operations of
+// the sessions which do not cache the passphrase.
+func (m *Manager) Begin(id string) (func(completed bool), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id == "" {
+		switch {
+		case m.current != nil:
+			return nil, ErrLocked
+		case m.config.Required:
+			return nil, ErrSessionRequired
+		default:
+			return func(bool) {}, nil
+		}
+	}
+
+	if m.current == nil || m.current.ID != id {
+		return nil, ErrInvalidSession
+	}
+
+	s := m.current
+	s.LastUsed = m.now()
+	m.inFlight++
+
+	return func(completed bool) {
+		m.mu.Lock()
+		m.inFlight--
+		open := m.current == s
+		if open {
+			s.LastUsed = m.now()
+			m.schedule(m.config.IdleTimeout)
+		}
+		m.mu.Unlock()
+
+		if open && completed && !s.CachePassphrase {
+			if err := m.lock(); err != nil {
+				logger.WithError(err).Error("Failed to lock the device after the session operation")
+			}
+		}
+	}, nil
+}
+
+// autoLock closes the open session and locks the device if the session is idle since the idle timeout
+func (m *Manager) autoLock() {
+	m.mu.Lock()
+	if m.current == nil || m.inFlight > 0 {
+		m.mu.Unlock()
+		return
+	}
+
+	if idle := m.now().Sub(m.current.LastUsed); idle < m.config.IdleTimeout {
+		m.schedule(m.config.IdleTimeout - idle)
+		m.mu.Unlock()
+		return
+	}
+
+	logger.Infof("Session idle for %s, locking the device", m.config.IdleTimeout)
+	m.end()
+	m.mu.Unlock()
+
+	if err := m.lock(); err != nil {
+		logger.WithError(err).Error("Failed to lock the device of the idle session")
+	}
+}
+
+// schedule runs autoLock after d, must be called with the lock held
+func (m *Manager) schedule(d time.Duration) {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(d, m.autoLock)
+}
+
+// end forgets the open session, must be called with the lock held
+func (m *Manager) end() {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.current = nil
+}
+
+// session returns a copy of the open session, must be called with the lock held
+func (m *Manager) session() Session {
+	s := *m.current
+	s.ExpiresAt = s.LastUsed.Add(m.config.IdleTimeout)
+	return s
+}
@@ -0,0 +1,235 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLock counts the device locks
+type fakeLock struct {
+	mu    sync.Mutex
+	locks int
+	err   error
+}
+
+func (l *fakeLock) lock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.locks++
+	return l.err
+}
+
+func (l *fakeLock) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.locks
+}
+
+// newTestManager creates a Manager with a clock advanced by the returned function
+func newTestManager(config Config) (*Manager, *fakeLock, func(time.Duration)) {
+	lock := &fakeLock{}
+	m := NewManager(config, lock.lock)
+
+	var mu sync.Mutex
+	now := time.Date(2019, 7, 26, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	return m, lock, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestOpenClose(t *testing.T) {
+	m, lock, _ := newTestManager(Config{})
+	require.Equal(t, DefaultIdleTimeout, m.Config().IdleTimeout)
+
+	s, err := m.Open(true)
+	require.NoError(t, err)
+	require.Len(t, s.ID, 2*idSize)
+	require.True(t, s.CachePassphrase)
+	require.Equal(t, s.Created, s.LastUsed)
+	require.Equal(t, s.LastUsed.Add(DefaultIdleTimeout), s.ExpiresAt)
+
+	_, err = m.Open(false)
+	require.Equal(t, ErrLocked, err)
+
+	got, err := m.Get(s.ID)
+	require.NoError(t, err)
+	require.Equal(t, s, got)
+
+	_, err = m.Get("foo")
+	require.Equal(t, ErrInvalidSession, err)
+
+	require.Equal(t, ErrInvalidSession, m.Close("foo"))
+	require.Zero(t, lock.count())
+
+	require.NoError(t, m.Close(s.ID))
+	require.Equal(t, 1, lock.count())
+
+	_, err = m.Get(s.ID)
+	require.Equal(t, ErrInvalidSession, err)
+
+	_, err = m.Open(false)
+	require.NoError(t, err)
+}
+
+func TestCloseLockError(t *testing.T) {
+	m, lock, _ := newTestManager(Config{})
+	lock.err = errors.New("device disconnected")
+
+	s, err := m.Open(true)
+	require.NoError(t, err)
+
+	// the session is closed even if the device could not be locked
+	require.Equal(t, lock.err, m.Close(s.ID))
+	_, err = m.Get(s.ID)
+	require.Equal(t, ErrInvalidSession, err)
+}
+
+func TestBegin(t *testing.T) {
+	m, _, _ := newTestManager(Config{})
+
+	end, err := m.Begin("")
+	require.NoError(t, err)
+	end(true)
+
+	_, err = m.Begin("foo")
+	require.Equal(t, ErrInvalidSession, err)
+
+	s, err := m.Open(true)
+	require.NoError(t, err)
+
+	_, err = m.Begin("")
+	require.Equal(t, ErrLocked, err)
+
+	_, err = m.Begin("foo")
+	require.Equal(t, ErrInvalidSession, err)
+
+	end, err = m.Begin(s.ID)
+	require.NoError(t, err)
+	end(true)
+
+	m, _, _ = newTestManager(Config{
+		Required: true,
+	})
+	_, err = m.Begin("")
+	require.Equal(t, ErrSessionRequired, err)
+}
+
+func TestBeginCachePassphrase(t *testing.T) {
+	cases := []struct {
+		name            string
+		cachePassphrase bool
+		completed       bool
+		locks           int
+	}{
+		{
+			name:            "cached passphrase",
+			cachePassphrase: true,
+			completed:       true,
+			locks:           0,
+		},
+		{
+			name:            "no cached passphrase",
+			cachePassphrase: false,
+			completed:       true,
+			locks:           1,
+		},
+		{
+			name:            "no cached passphrase, waiting for user input",
+			cachePassphrase: false,
+			completed:       false,
+			locks:           0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, lock, advance := newTestManager(Config{})
+
+			s, err := m.Open(tc.cachePassphrase)
+			require.NoError(t, err)
+
+			end, err := m.Begin(s.ID)
+			require.NoError(t, err)
+			advance(time.Minute)
+			end(tc.completed)
+
+			require.Equal(t, tc.locks, lock.count())
+
+			// the session stays open and its expiry is extended
+			got, err := m.Get(s.ID)
+			require.NoError(t, err)
+			require.Equal(t, s.LastUsed.Add(time.Minute), got.LastUsed)
+			require.Equal(t, s.ExpiresAt.Add(time.Minute), got.ExpiresAt)
+		})
+	}
+}
+
+func TestAutoLock(t *testing.T) {
+	m, lock, advance := newTestManager(Config{
+		IdleTimeout: time.Hour,
+	})
+
+	s, err := m.Open(true)
+	require.NoError(t, err)
+
+	// not idle long enough
+	advance(30 * time.Minute)
+	m.autoLock()
+	require.Zero(t, lock.count())
+
+	// an operation in progress keeps the session open
+	end, err := m.Begin(s.ID)
+	require.NoError(t, err)
+	advance(2 * time.Hour)
+	m.autoLock()
+	require.Zero(t, lock.count())
+	end(true)
+
+	advance(59 * time.Minute)
+	m.autoLock()
+	require.Zero(t, lock.count())
+
+	advance(time.Minute)
+	m.autoLock()
+	require.Equal(t, 1, lock.count())
+
+	_, err = m.Get(s.ID)
+	require.Equal(t, ErrInvalidSession, err)
+
+	m.autoLock()
+	require.Equal(t, 1, lock.count())
+}
+
+func TestAutoLockTimer(t *testing.T) {
+	lock := &fakeLock{}
+	m := NewManager(Config{
+		IdleTimeout: 10 * time.Millisecond,
+	}, lock.lock)
+
+	s, err := m.Open(true)
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(time.Second)
+	for lock.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, 1, lock.count())
+
+	_, err = m.Begin(s.ID)
+	require.Equal(t, ErrInvalidSession, err)
+
+	_, err = m.Begin("")
+	require.NoError(t, err)
+}
@@ -0,0 +1,187 @@
+// Package signwindow restricts the signing operations to the signing windows of a policy, such as business hours,
+// for organizations enforcing operational controls on treasury signing.
+package signwindow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// minutesPerDay is the end of the last window of a day, "24:00"
+const minutesPerDay = 24 * 60
+
+// weekdays are the days by their names and three letter abbreviations, such as "monday" and "mon"
+var weekdays = func() map[string]time.Weekday {
+	days := make(map[string]time.Weekday)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		days[name] = d
+		days[name[:3]] = d
+	}
+	return days
+}()
+
+// Window is a signing window: the time of day between Start and End, such as "09:00" and "17:00", on Days.
+// Days are names such as "mon" or "monday", empty for every day. End may be "24:00".
+type Window struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+
+	days  [7]bool
+	start int
+	end   int
+}
+
+// Policy allows signing within its windows only, in the time zone Timezone
+type Policy struct {
+	// Timezone is an IANA time zone such as "Europe/Berlin", defaults to the local time zone of the daemon
+	Timezone string   `json:"timezone,omitempty"`
+	Windows  []Window `json:"windows"`
+
+	location *time.Location
+}
+
+// NewPolicy creates a Policy
+func NewPolicy(timezone string, windows []Window) (*Policy, error) {
+	p := &Policy{
+		Timezone: timezone,
+		Windows:  windows,
+	}
+
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// LoadPolicy loads a signing window policy from a JSON file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid signing window policy %s: %v", path, err)
+	}
+
+	if err := p.init(); err != nil {
+		return nil, fmt.Errorf("invalid signing window policy %s: %v", path, err)
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) init() error {
+	p.location = time.Local
+	if p.Timezone != "" {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return err
+		}
+		p.location = loc
+	}
+
+	if len(p.Windows) == 0 {
+		return errors.New("signing window policy has no windows")
+	}
+
+	for i := range p.Windows {
+		if err := p.Windows[i].init(); err != nil {
+			return fmt.Errorf("signing window %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Window) init() error {
+	var err error
+	if w.start, err = parseTimeOfDay(w.Start); err != nil {
+		return fmt.Errorf("invalid start: %v", err)
+	}
+	if w.end, err = parseTimeOfDay(w.End); err != nil {
+		return fmt.Errorf("invalid end: %v", err)
+	}
+	if w.start >= w.end {
+		return errors.New("start must be before end")
+	}
+
+	w.days = [7]bool{}
+	if len(w.Days) == 0 {
+		for d := range w.days {
+			w.days[d] = true
+		}
+	}
+	for _, name := range w.Days {
+		d, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("invalid day %q", name)
+		}
+		w.days[d] = true
+	}
+
+	return nil
+}
+
+// parseTimeOfDay parses a time of day such as "09:30" into minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	if s == "24:00" {
+		return minutesPerDay, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day such as 09:30", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Open returns true if signing is allowed at time t
+func (p *Policy) Open(t time.Time) bool {
+	t = t.In(p.location)
+	minute := t.Hour()*60 + t.Minute()
+
+	for _, w := range p.Windows {
+		if w.days[t.Weekday()] && minute >= w.start && minute < w.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Next returns when the next signing window opens after time t
+func (p *Policy) Next(t time.Time) time.Time {
+	t = t.In(p.location)
+
+	var next time.Time
+	// every window opens within a week
+	for i := 0; i <= 7; i++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, p.location)
+		for _, w := range p.Windows {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+
+			start := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, p.location)
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+
+		if !next.IsZero() {
+			break
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,118 @@
+package signwindow
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signwindow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{
+			name:   "valid",
+			policy: `{"timezone": "Europe/Berlin", "windows": [{"days": ["mon", "Tuesday"], "start": "09:00", "end": "17:30"}, {"start": "20:00", "end": "24:00"}]}`,
+		},
+		{
+			name:   "invalid json",
+			policy: `{"windows": {}}`,
+			err:    "invalid signing window policy",
+		},
+		{
+			name:   "invalid timezone",
+			policy: `{"timezone": "Mars/Olympus", "windows": [{"start": "09:00", "end": "17:00"}]}`,
+			err:    "unknown time zone Mars/Olympus",
+		},
+		{
+			name:   "no windows",
+			policy: `{"timezone": "UTC"}`,
+			err:    "signing window policy has no windows",
+		},
+		{
+			name:   "invalid time of day",
+			policy: `{"windows": [{"start": "9am", "end": "17:00"}]}`,
+			err:    `signing window 0: invalid start: "9am" is not a time of day such as 09:30`,
+		},
+		{
+			name:   "start after end",
+			policy: `{"windows": [{"start": "17:00", "end": "09:00"}]}`,
+			err:    "signing window 0: start must be before end",
+		},
+		{
+			name:   "invalid day",
+			policy: `{"windows": [{"days": ["mo"], "start": "09:00", "end": "17:00"}]}`,
+			err:    `signing window 0: invalid day "mo"`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("signwindow%d.json", i))
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.policy), 0600))
+
+			p, err := LoadPolicy(path)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "Europe/Berlin", p.location.String())
+			require.Equal(t, [7]bool{time.Monday: true, time.Tuesday: true}, p.Windows[0].days)
+			require.Equal(t, 17*60+30, p.Windows[0].end)
+			require.Equal(t, [7]bool{true, true, true, true, true, true, true}, p.Windows[1].days)
+			require.Equal(t, minutesPerDay, p.Windows[1].end)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p, err := NewPolicy("America/New_York", []Window{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"},
+		{Days: []string{"sat"}, Start: "10:00", End: "12:00"},
+	})
+	require.NoError(t, err)
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	at := func(day, hour, min int) time.Time {
+		// July 1st 2019 is a monday
+		return time.Date(2019, 7, day, hour, min, 0, 0, loc)
+	}
+
+	cases := []struct {
+		name string
+		t    time.Time
+		open bool
+		next time.Time
+	}{
+		{"monday before the window", at(1, 8, 59), false, at(1, 9, 0)},
+		{"monday window opens", at(1, 9, 0), true, at(2, 9, 0)},
+		{"monday window closes", at(1, 16, 59), true, at(2, 9, 0)},
+		{"monday after the window", at(1, 17, 0), false, at(2, 9, 0)},
+		{"friday evening", at(5, 20, 0), false, at(6, 10, 0)},
+		{"saturday after the window", at(6, 12, 0), false, at(8, 9, 0)},
+		{"sunday", at(7, 11, 0), false, at(8, 9, 0)},
+		{"other time zone", time.Date(2019, 7, 1, 14, 0, 0, 0, time.UTC), true, at(2, 9, 0)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.open, p.Open(tc.t))
+			require.True(t, tc.next.Equal(p.Next(tc.t)), "next is %s", p.Next(tc.t))
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// ErrNoResponse is returned when the device handle of a Driver is read without a message written to it
+var ErrNoResponse = errors.New("mock device: no message to answer")
+
+// wireTypes are the protobuf wire types of the encodings of the protobuf tags
+var wireTypes = map[string]byte{
+	"varint":   0,
+	"zigzag32": 0,
+	"zigzag64": 0,
+	"fixed64":  1,
+	"bytes":    2,
+	"fixed32":  5,
+}
+
+// Driver is a device driver whose device is a simulated device: the messages written to the device handle are decoded
+// and answered by the simulated device, scripted responses and errors included. The mock device of daemon-mode MOCK
+// is a skywallet device with this driver, so it runs through the device stack of the daemon as a USB device does.
+type Driver struct {
+	dev *virtualDevice
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver creates a Driver answering the messages with sim
+func NewDriver(sim *Device) *Driver {
+	return &Driver{
+		dev: &virtualDevice{
+			sim: sim,
+		},
+	}
+}
+
+// SendToDevice writes the message to the device handle and reads its response
+func (drv *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	if err := drv.SendToDeviceNoAnswer(dev, chunks); err != nil {
+		return wire.Message{}, err
+	}
+
+	msg, err := wire.ReadFrom(dev)
+	if err != nil {
+		return wire.Message{}, err
+	}
+	return *msg, nil
+}
+
+// SendToDeviceNoAnswer writes the message to the device handle, its response is left to read
+func (drv *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	for _, chunk := range chunks {
+		if _, err := dev.Write(chunk[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDevice returns the handle of the simulated device. The handles share the state of the device, as the handles of
+// a USB device do.
+func (drv *Driver) GetDevice() (usb.Device, error) {
+	return drv.dev, nil
+}
+
+// GetDeviceInfos returns the USB information of the simulated device
+func (drv *Driver) GetDeviceInfos() ([]usb.Info, error) {
+	return drv.dev.sim.GetUsbInfo()
+}
+
+// DeviceType returns DeviceTypeUSB, the mock device serves the USB api
+func (drv *Driver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeUSB
+}
+
+// Close implements skyWallet.DeviceDriver
+func (drv *Driver) Close() {}
+
+// virtualDevice is the device handle of a Driver. Once a message is written, its response, or the error of the
+// simulated device, is read back.
+type virtualDevice struct {
+	sim *Device
+
+	mu       sync.Mutex
+	request  bytes.Buffer
+	response bytes.Buffer
+	err      error
+}
+
+func (v *virtualDevice) Write(b []byte) (int, error) {
+	// the button presses of the emulator are not wire packets, the simulated device presses its buttons itself
+	if len(b) == 0 || b[0] != '?' {
+		return len(b), nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.request.Write(b)
+	msg, err := wire.ReadFrom(bytes.NewReader(v.request.Bytes()))
+	switch err {
+	case nil:
+	case io.EOF:
+		// the message continues in the next packets
+		return len(b), nil
+	default:
+		v.request.Reset()
+		return 0, err
+	}
+	v.request.Reset()
+
+	// a new message replaces the response left unread, as on the device
+	v.response.Reset()
+	v.err = nil
+
+	rsp, err := handle(v.sim, *msg)
+	if err != nil {
+		v.err = err
+		return len(b), nil
+	}
+	if _, err := rsp.WriteTo(&v.response); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (v *virtualDevice) Read(b []byte) (int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.err != nil {
+		err := v.err
+		v.err = nil
+		return 0, err
+	}
+	if v.response.Len() == 0 {
+		return 0, ErrNoResponse
+	}
+	return v.response.Read(b)
+}
+
+// Close keeps the state of the simulated device, a pending flow continues on the next handle
+func (v *virtualDevice) Close(disconnected bool) error {
+	return nil
+}
+
+// handle answers a message with the simulated device
+func handle(sim *Device, msg wire.Message) (wire.Message, error) {
+	kind := messages.MessageType(msg.Kind)
+	switch kind {
+	case messages.MessageType_MessageType_Initialize:
+		return sim.Initialize()
+	case messages.MessageType_MessageType_GetFeatures:
+		return sim.GetFeatures()
+	case messages.MessageType_MessageType_Cancel:
+		return sim.Cancel()
+	case messages.MessageType_MessageType_BackupDevice:
+		return sim.Backup()
+	case messages.MessageType_MessageType_WipeDevice:
+		return sim.Wipe()
+	case messages.MessageType_MessageType_ButtonAck:
+		return sim.ButtonAck()
+	case messages.MessageType_MessageType_FirmwareErase:
+		return success("Firmware erased"), nil
+	}
+
+	pb, err := newRequest(kind)
+	if err != nil {
+		return failure(messages.FailureType_Failure_UnexpectedMessage, "Unexpected message"), nil
+	}
+	if err := unmarshalRequest(msg.Data, pb); err != nil {
+		return failure(messages.FailureType_Failure_DataError, "Malformed message"), nil
+	}
+
+	switch m := pb.(type) {
+	case *messages.Ping:
+		return sim.Ping(m.GetMessage())
+	case *messages.SkycoinAddress:
+		return sim.AddressGen(m.GetAddressN(), m.GetStartIndex(), m.GetConfirmAddress())
+	case *messages.ApplySettings:
+		if m.Homescreen != nil {
+			return sim.ApplySettingsHomescreen(m.UsePassphrase, m.GetLabel(), m.GetLanguage(), m.Homescreen)
+		}
+		return sim.ApplySettings(m.UsePassphrase, m.GetLabel(), m.GetLanguage())
+	case *messages.SkycoinCheckMessageSignature:
+		return sim.CheckMessageSignature(m.GetMessage(), m.GetSignature(), m.GetAddress())
+	case *messages.ChangePin:
+		return sim.ChangePin(m.Remove)
+	case *messages.GenerateMnemonic:
+		return sim.GenerateMnemonic(m.GetWordCount(), m.GetPassphraseProtection())
+	case *messages.RecoveryDevice:
+		return sim.Recovery(m.GetWordCount(), m.PassphraseProtection, m.GetDryRun())
+	case *messages.SetMnemonic:
+		return sim.SetMnemonic(m.GetMnemonic())
+	case *messages.SkycoinSignMessage:
+		return sim.SignMessage(int(m.GetAddressN()), m.GetMessage())
+	case *messages.TransactionSign:
+		return sim.TransactionSign(m.TransactionIn, m.TransactionOut)
+	case *messages.PinMatrixAck:
+		return sim.PinMatrixAck(m.GetPin())
+	case *messages.WordAck:
+		return sim.WordAck(m.GetWord())
+	case *messages.PassphraseAck:
+		return sim.PassphraseAck(m.GetPassphrase())
+	case *messages.GetRawEntropy:
+		entropy, err := sim.GetRawEntropy(m.GetSize_())
+		if err != nil {
+			return wire.Message{}, err
+		}
+		return message(messages.MessageType_MessageType_Entropy, &messages.Entropy{
+			Entropy: entropy,
+		}), nil
+	case *messages.FirmwareUpload:
+		return firmwareUpload(sim, m.Payload, m.Hash), nil
+	default:
+		return failure(messages.FailureType_Failure_UnexpectedMessage, "Unexpected message"), nil
+	}
+}
+
+// requestTypes are the messages answered by handle, with the fields decoded
+var requestTypes = map[messages.MessageType]proto.Message{
+	messages.MessageType_MessageType_Ping:                         &messages.Ping{},
+	messages.MessageType_MessageType_SkycoinAddress:               &messages.SkycoinAddress{},
+	messages.MessageType_MessageType_ApplySettings:                &messages.ApplySettings{},
+	messages.MessageType_MessageType_SkycoinCheckMessageSignature: &messages.SkycoinCheckMessageSignature{},
+	messages.MessageType_MessageType_ChangePin:                    &messages.ChangePin{},
+	messages.MessageType_MessageType_GenerateMnemonic:             &messages.GenerateMnemonic{},
+	messages.MessageType_MessageType_RecoveryDevice:               &messages.RecoveryDevice{},
+	messages.MessageType_MessageType_SetMnemonic:                  &messages.SetMnemonic{},
+	messages.MessageType_MessageType_SkycoinSignMessage:           &messages.SkycoinSignMessage{},
+	messages.MessageType_MessageType_TransactionSign:              &messages.TransactionSign{},
+	messages.MessageType_MessageType_PinMatrixAck:                 &messages.PinMatrixAck{},
+	messages.MessageType_MessageType_WordAck:                      &messages.WordAck{},
+	messages.MessageType_MessageType_PassphraseAck:                &messages.PassphraseAck{},
+	messages.MessageType_MessageType_GetRawEntropy:                &messages.GetRawEntropy{},
+	messages.MessageType_MessageType_FirmwareUpload:               &messages.FirmwareUpload{},
+}
+
+// newRequest returns an empty message of kind
+func newRequest(kind messages.MessageType) (proto.Message, error) {
+	pb, ok := requestTypes[kind]
+	if !ok {
+		return nil, errors.New("unknown message " + kind.String())
+	}
+	return reflect.New(reflect.TypeOf(pb).Elem()).Interface().(proto.Message), nil
+}
+
+// unmarshalRequest decodes the data of a message into pb. The skywallet library writes a '\n' over the first byte of
+// the messages it sends, the key of their first field, which is restored from the keys of the fields of pb: the first
+// key whose message encodes back to the same bytes is the one the library replaced.
+func unmarshalRequest(data []byte, pb proto.Message) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := proto.Unmarshal(data, pb); err == nil && encodes(pb, data) {
+		return nil
+	}
+
+	restored := make([]byte, len(data))
+	copy(restored, data)
+	for _, key := range fieldKeys(pb) {
+		restored[0] = key
+		pb.Reset()
+		if err := proto.Unmarshal(restored, pb); err == nil && encodes(pb, restored) {
+			return nil
+		}
+	}
+
+	return errors.New("malformed message")
+}
+
+// encodes reports whether pb encodes to data
+func encodes(pb proto.Message, data []byte) bool {
+	b, err := proto.Marshal(pb)
+	return err == nil && bytes.Equal(b, data)
+}
+
+// fieldKeys returns the one byte keys of the fields of pb, by field number
+func fieldKeys(pb proto.Message) []byte {
+	type field struct {
+		number int
+		key    byte
+	}
+
+	var fields []field
+	t := reflect.TypeOf(pb).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		parts := strings.Split(t.Field(i).Tag.Get("protobuf"), ",")
+		if len(parts) < 2 {
+			continue
+		}
+		wireType, ok := wireTypes[parts[0]]
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(parts[1])
+		if err != nil || number < 1 || number > 15 {
+			continue
+		}
+		fields = append(fields, field{
+			number: number,
+			key:    byte(number<<3) | wireType,
+		})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].number < fields[j].number
+	})
+
+	keys := make([]byte, len(fields))
+	for i, f := range fields {
+		keys[i] = f.key
+	}
+	return keys
+}
+
+// firmwareUpload checks the uploaded firmware and asks to confirm its fingerprint, as the bootloader does
+func firmwareUpload(sim *Device, payload, hash []byte) wire.Message {
+	var h [32]byte
+	if len(hash) != len(h) {
+		return failure(messages.FailureType_Failure_DataError, ErrFirmwareHash.Error())
+	}
+	copy(h[:], hash)
+
+	if err := sim.FirmwareUpload(payload, h); err != nil {
+		return failure(messages.FailureType_Failure_FirmwareError, err.Error())
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return sim.await(messages.MessageType_MessageType_ButtonAck, buttonRequest(messages.ButtonRequestType_ButtonRequest_FirmwareCheck), func(string) wire.Message {
+		return success("Firmware updated")
+	})
+}
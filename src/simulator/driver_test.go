@@ -0,0 +1,122 @@
+package simulator
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockDevice(sim *Device) *skyWallet.Device {
+	return &skyWallet.Device{
+		Driver: NewDriver(sim),
+	}
+}
+
+func TestDriver(t *testing.T) {
+	sim := initializedDevice(t)
+	d := newMockDevice(sim)
+
+	infos, err := d.GetUsbInfo()
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.NoError(t, d.Connect())
+	require.True(t, d.Connected())
+	require.NoError(t, d.Disconnect())
+
+	msg, err := d.GetFeatures()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+	var features messages.Features
+	require.NoError(t, proto.Unmarshal(msg.Data, &features))
+	require.True(t, features.GetInitialized())
+
+	// the library writes a '\n' over the first byte of the messages
+	msg, err = d.AddressGen(2, 0, false)
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+	addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw", "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs"}, addresses)
+
+	msg, err = d.SignMessage(0, "Hello World")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+
+	// the button is acknowledged on the device handle, without the driver
+	msg, err = d.ApplySettings(nil, "mock", "")
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "Settings applied", msg, err)
+	require.Equal(t, "mock", sim.State().Label)
+
+	msg, err = d.Wipe()
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "Device wiped", msg, err)
+
+	msg, err = d.AddressGen(1, 0, false)
+	requireFailure(t, messages.FailureType_Failure_NotInitialized, "Device not initialized", msg, err)
+}
+
+func TestDriverPinMatrix(t *testing.T) {
+	sim := New(&Script{
+		State: &State{
+			Initialized: true,
+			Mnemonic:    testMnemonic,
+			Pin:         "1234",
+		},
+	})
+	d := newMockDevice(sim)
+
+	msg, err := d.SignMessage(0, "Hello World")
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("1234")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+}
+
+func TestDriverScriptedResponses(t *testing.T) {
+	sim := New(&Script{
+		State: &State{
+			Initialized: true,
+			Mnemonic:    testMnemonic,
+		},
+		Responses: map[string][]Response{
+			"AddressGen": {
+				{Type: ResponseButtonRequest},
+			},
+			"ButtonAck": {
+				{Type: ResponsePinMatrixRequest},
+			},
+			"PinMatrixAck": {
+				{Type: ResponseFailure, Code: "Failure_PinInvalid", Message: "PIN invalid"},
+			},
+			"GetFeatures": {
+				{Type: ResponseError, Message: "device disconnected"},
+			},
+		},
+	})
+	d := newMockDevice(sim)
+
+	msg, err := d.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("1234")
+	requireFailure(t, messages.FailureType_Failure_PinInvalid, "PIN invalid", msg, err)
+
+	_, err = d.GetFeatures()
+	require.EqualError(t, err, "device disconnected")
+
+	// the device answers once the scripted responses are exhausted
+	msg, err = d.GetFeatures()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+}
+
+func TestDriverFirmwareUpload(t *testing.T) {
+	d := newMockDevice(New(nil))
+
+	payload := []byte("firmware")
+	require.NoError(t, d.FirmwareUpload(payload, sha256.Sum256(payload)))
+	require.Error(t, d.FirmwareUpload(payload, [32]byte{}))
+}
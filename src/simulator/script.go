@@ -0,0 +1,186 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// Operations are the device operations whose responses can be scripted
+var Operations = []string{
+	"AddressGen",
+	"ApplySettings",
+	"ApplySettingsHomescreen",
+	"Backup",
+	"ButtonAck",
+	"Cancel",
+	"ChangePin",
+	"CheckMessageSignature",
+	"FirmwareUpload",
+	"GenerateMnemonic",
+	"GenerateMnemonicWithEntropy",
+	"GetFeatures",
+	"GetRawEntropy",
+	"Initialize",
+	"PassphraseAck",
+	"PinMatrixAck",
+	"Ping",
+	"Recovery",
+	"SetMnemonic",
+	"SignMessage",
+	"TransactionSign",
+	"WordAck",
+	"Wipe",
+}
+
+// ResponseType is the type of a scripted response
+type ResponseType string
+
+const (
+	// ResponseSuccess is a Success message
+	ResponseSuccess ResponseType = "success"
+	// ResponseFailure is a Failure message
+	ResponseFailure ResponseType = "failure"
+	// ResponseButtonRequest is a ButtonRequest message
+	ResponseButtonRequest ResponseType = "button_request"
+	// ResponsePinMatrixRequest is a PinMatrixRequest message
+	ResponsePinMatrixRequest ResponseType = "pin_matrix_request"
+	// ResponsePassphraseRequest is a PassphraseRequest message
+	ResponsePassphraseRequest ResponseType = "passphrase_request"
+	// ResponseWordRequest is a WordRequest message
+	ResponseWordRequest ResponseType = "word_request"
+	// ResponseError is a communication error with the device
+	ResponseError ResponseType = "error"
+)
+
+// Response is a scripted response of the simulated device
+type Response struct {
+	Type ResponseType `json:"type"`
+	// Message is the message of a success or failure, or the text of an error
+	Message string `json:"message,omitempty"`
+	// Code is the failure type of a failure, such as Failure_PinInvalid. Defaults to Failure_FirmwareError.
+	Code string `json:"code,omitempty"`
+}
+
+// Script sets the initial state of the simulated device and overrides its responses.
+// The responses of an operation are returned in order by its successive calls,
+// the simulated device handles the calls once they are exhausted.
+type Script struct {
+	State     *State                `json:"state,omitempty"`
+	Responses map[string][]Response `json:"responses,omitempty"`
+}
+
+// LoadScript loads a JSON script from path
+func LoadScript(path string) (*Script, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read simulator script: %v", err)
+	}
+
+	var s Script
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid simulator script: %v", err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid simulator script: %v", err)
+	}
+
+	return &s, nil
+}
+
+// Validate checks the operations and responses of the script
+func (s *Script) Validate() error {
+	ops := make([]string, 0, len(s.Responses))
+	for op := range s.Responses {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	for _, op := range ops {
+		if !isOperation(op) {
+			return fmt.Errorf("unknown operation %q", op)
+		}
+
+		for i, r := range s.Responses[op] {
+			if _, err := r.message(); err != nil {
+				return fmt.Errorf("%s response %d: %v", op, i, err)
+			}
+
+			if (op == "FirmwareUpload" || op == "GetRawEntropy") && r.Type != ResponseSuccess && r.Type != ResponseError {
+				return fmt.Errorf("%s response %d: only success and error responses are supported", op, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// next pops the next scripted response of op. ok is false if there is none.
+func (s *Script) next(op string) (Response, bool) {
+	if s == nil || len(s.Responses[op]) == 0 {
+		return Response{}, false
+	}
+
+	r := s.Responses[op][0]
+	s.Responses[op] = s.Responses[op][1:]
+	return r, true
+}
+
+// reply returns the device message of the response, or its error for an error response
+func (r Response) reply() (wire.Message, error) {
+	if r.Type == ResponseError {
+		return wire.Message{}, errors.New(r.Message)
+	}
+	return r.message()
+}
+
+// message returns the device message of the response
+func (r Response) message() (wire.Message, error) {
+	switch r.Type {
+	case ResponseSuccess:
+		return success(r.Message), nil
+	case ResponseFailure:
+		code := messages.FailureType_Failure_FirmwareError
+		if r.Code != "" {
+			c, ok := messages.FailureType_value[r.Code]
+			if !ok {
+				return wire.Message{}, fmt.Errorf("unknown failure code %q", r.Code)
+			}
+			code = messages.FailureType(c)
+		}
+		return failure(code, r.Message), nil
+	case ResponseButtonRequest:
+		return buttonRequest(messages.ButtonRequestType_ButtonRequest_Other), nil
+	case ResponsePinMatrixRequest:
+		return pinMatrixRequest(messages.PinMatrixRequestType_PinMatrixRequestType_Current), nil
+	case ResponsePassphraseRequest:
+		return message(messages.MessageType_MessageType_PassphraseRequest, &messages.PassphraseRequest{
+			OnDevice: proto.Bool(false),
+		}), nil
+	case ResponseWordRequest:
+		return message(messages.MessageType_MessageType_WordRequest, &messages.WordRequest{}), nil
+	case ResponseError:
+		if r.Message == "" {
+			return wire.Message{}, errors.New("error response requires a message")
+		}
+		return wire.Message{}, nil
+	default:
+		return wire.Message{}, fmt.Errorf("unknown response type %q", r.Type)
+	}
+}
+
+func isOperation(op string) bool {
+	for _, o := range Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package simulator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScript(t *testing.T, script string) string {
+	dir, err := ioutil.TempDir("", "simulator")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "script.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0600))
+	return path
+}
+
+func TestLoadScript(t *testing.T) {
+	cases := []struct {
+		name   string
+		script string
+		err    string
+	}{
+		{
+			name:   "valid",
+			script: `{"state": {"initialized": true, "mnemonic": "` + testMnemonic + `"}, "responses": {"Wipe": [{"type": "failure", "code": "Failure_ActionCancelled", "message": "cancelled"}]}}`,
+		},
+		{
+			name:   "invalid json",
+			script: `{`,
+			err:    "invalid simulator script: unexpected end of JSON input",
+		},
+		{
+			name:   "unknown operation",
+			script: `{"responses": {"Foo": [{"type": "success"}]}}`,
+			err:    `invalid simulator script: unknown operation "Foo"`,
+		},
+		{
+			name:   "unknown response type",
+			script: `{"responses": {"Wipe": [{"type": "foo"}]}}`,
+			err:    `invalid simulator script: Wipe response 0: unknown response type "foo"`,
+		},
+		{
+			name:   "unknown failure code",
+			script: `{"responses": {"Wipe": [{"type": "failure", "code": "Failure_Foo"}]}}`,
+			err:    `invalid simulator script: Wipe response 0: unknown failure code "Failure_Foo"`,
+		},
+		{
+			name:   "error without message",
+			script: `{"responses": {"Wipe": [{"type": "error"}]}}`,
+			err:    "invalid simulator script: Wipe response 0: error response requires a message",
+		},
+		{
+			name:   "firmware upload request",
+			script: `{"responses": {"FirmwareUpload": [{"type": "button_request"}]}}`,
+			err:    "invalid simulator script: FirmwareUpload response 0: only success and error responses are supported",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeScript(t, tc.script)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			s, err := LoadScript(path)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, s)
+		})
+	}
+
+	_, err := LoadScript(filepath.Join(os.TempDir(), "simulator-missing-script.json"))
+	require.Error(t, err)
+}
+
+func TestScriptedResponses(t *testing.T) {
+	d := New(&Script{
+		State: &State{
+			Initialized: true,
+			Mnemonic:    testMnemonic,
+			Label:       "scripted",
+		},
+		Responses: map[string][]Response{
+			"Wipe": {
+				{Type: ResponseFailure, Code: "Failure_ActionCancelled", Message: "Action canceled by User"},
+				{Type: ResponseError, Message: "device disconnected"},
+			},
+			"FirmwareUpload": {
+				{Type: ResponseError, Message: "upload failed"},
+			},
+			"GetRawEntropy": {
+				{Type: ResponseError, Message: "device disconnected"},
+			},
+		},
+	})
+
+	require.Equal(t, "scripted", d.State().Label)
+
+	msg, err := d.Wipe()
+	requireFailure(t, messages.FailureType_Failure_ActionCancelled, "Action canceled by User", msg, err)
+
+	_, err = d.Wipe()
+	require.EqualError(t, err, "device disconnected")
+
+	// the simulated device handles the calls once the scripted responses are exhausted
+	msg, err = d.Wipe()
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "Device wiped", msg, err)
+
+	require.EqualError(t, d.FirmwareUpload([]byte("firmware"), [32]byte{}), "upload failed")
+	require.Equal(t, ErrFirmwareHash, d.FirmwareUpload([]byte("firmware"), [32]byte{}))
+
+	_, err = d.GetRawEntropy(32)
+	require.EqualError(t, err, "device disconnected")
+	entropy, err := d.GetRawEntropy(32)
+	require.NoError(t, err)
+	require.Len(t, entropy, 32)
+}
@@ -0,0 +1,899 @@
+// Package simulator implements a simulated skywallet serving the daemon API without a device nor an emulator.
+// The simulated device keeps a state, such as its seed and PIN, and walks through the same interactive
+// flows as the firmware, so clients can be developed against the daemon with zero hardware setup.
+package simulator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/bip39"
+	"github.com/skycoin/skycoin/src/cipher/bip39/wordlists"
+	secp "github.com/skycoin/skycoin/src/cipher/secp256k1-go/secp256k1-go2"
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/api"
+)
+
+const (
+	// DefaultFirmwareVersion is the firmware version reported by the simulated device
+	DefaultFirmwareVersion = "1.7.0"
+
+	// maxAddresses is the maximum number of addresses generated by a single request, as on the firmware
+	maxAddresses = 99
+
+	// DeterministicDeviceID is the device ID of the deterministic simulated device
+	DeterministicDeviceID = "5347B2C2A3EF1A06C6A5D2F1"
+)
+
+var (
+	logger = logging.MustGetLogger("simulator")
+
+	// ErrEmptyFirmware is returned when an empty firmware is uploaded
+	ErrEmptyFirmware = errors.New("firmware payload is empty")
+	// ErrFirmwareHash is returned when the hash of an uploaded firmware does not match its payload
+	ErrFirmwareHash = errors.New("firmware hash does not match the payload")
+)
+
+var _ api.Gatewayer = (*Device)(nil)
+
+// State is the state of the simulated device
+type State struct {
+	Initialized          bool   `json:"initialized"`
+	Label                string `json:"label"`
+	Language             string `json:"language"`
+	Mnemonic             string `json:"mnemonic"`
+	Pin                  string `json:"pin"`
+	PassphraseProtection bool   `json:"passphrase_protection"`
+	NeedsBackup          bool   `json:"needs_backup"`
+	FirmwareVersion      string `json:"firmware_version"`
+}
+
+// flow is an interactive flow waiting for the answer to an intermediate request
+type flow struct {
+	// expects is the kind of the answer expected by the device
+	expects messages.MessageType
+	// next handles the answer and returns the next device message
+	next func(answer string) wire.Message
+}
+
+// Device is a simulated skywallet implementing api.Gatewayer
+type Device struct {
+	mu       sync.Mutex
+	state    State
+	deviceID string
+	script   *Script
+
+	pinCached        bool
+	passphrase       string
+	passphraseCached bool
+	autoPressButton  bool
+
+	// deterministic derives the signing nonces and the entropy instead of drawing them, counter is the block of the
+	// entropy stream
+	deterministic bool
+	counter       uint64
+
+	pending *flow
+}
+
+// New creates a simulated device. script may be nil, the device then starts uninitialized.
+func New(script *Script) *Device {
+	d := &Device{
+		deviceID: strings.ToUpper(hex.EncodeToString(cipher.RandByte(12))),
+		script:   script,
+	}
+
+	if script != nil && script.State != nil {
+		d.state = *script.State
+	}
+
+	if d.state.FirmwareVersion == "" {
+		d.state.FirmwareVersion = DefaultFirmwareVersion
+	}
+
+	return d
+}
+
+// NewDeterministic creates a simulated device whose responses are the same across the runs: its device ID is
+// DeterministicDeviceID, its signatures and entropy are derived instead of random, and without a state in script it
+// starts seeded with api.DeterministicMnemonic.
+func NewDeterministic(script *Script) *Device {
+	d := New(script)
+	d.deviceID = DeterministicDeviceID
+	d.deterministic = true
+
+	if script == nil || script.State == nil {
+		d.state.Initialized = true
+		d.state.Mnemonic = api.DeterministicMnemonic
+	}
+
+	return d
+}
+
+// random returns n random bytes, or the next n bytes of the entropy stream of the deterministic device
+func (d *Device) random(n int) []byte {
+	if !d.deterministic {
+		return cipher.RandByte(n)
+	}
+
+	b := make([]byte, 0, n+sha256.Size)
+	for len(b) < n {
+		var block [8]byte
+		binary.BigEndian.PutUint64(block[:], d.counter)
+		d.counter++
+		sum := sha256.Sum256(append([]byte("simulator entropy"), block[:]...))
+		b = append(b, sum[:]...)
+	}
+	return b[:n]
+}
+
+// State returns the current state of the simulated device
+func (d *Device) State() State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state
+}
+
+// do runs an operation, returning its scripted response if there is one
+func (d *Device) do(op string, f func() wire.Message) (wire.Message, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r, ok := d.script.next(op); ok {
+		logger.Debugf("%s: scripted %s response", op, r.Type)
+		return r.reply()
+	}
+
+	return f(), nil
+}
+
+// await registers the flow waiting for an answer of kind expects and returns request.
+// Button requests are answered right away when button presses are simulated.
+func (d *Device) await(expects messages.MessageType, request wire.Message, next func(string) wire.Message) wire.Message {
+	if expects == messages.MessageType_MessageType_ButtonAck && d.autoPressButton {
+		return next("")
+	}
+
+	d.pending = &flow{
+		expects: expects,
+		next:    next,
+	}
+	return request
+}
+
+// answer hands an intermediate answer to the pending flow
+func (d *Device) answer(kind messages.MessageType, value string) wire.Message {
+	if d.pending == nil || d.pending.expects != kind {
+		d.pending = nil
+		return failure(messages.FailureType_Failure_UnexpectedMessage, "Unexpected message")
+	}
+
+	f := d.pending
+	d.pending = nil
+	return f.next(value)
+}
+
+// unlock asks for the PIN and the passphrase when they are enabled and not cached, then runs then
+func (d *Device) unlock(then func() wire.Message) wire.Message {
+	if d.state.Pin != "" && !d.pinCached {
+		return d.await(messages.MessageType_MessageType_PinMatrixAck, pinMatrixRequest(messages.PinMatrixRequestType_PinMatrixRequestType_Current), func(pin string) wire.Message {
+			if pin != d.state.Pin {
+				return failure(messages.FailureType_Failure_PinInvalid, "PIN invalid")
+			}
+			d.pinCached = true
+			return d.unlock(then)
+		})
+	}
+
+	if d.state.PassphraseProtection && !d.passphraseCached {
+		return d.await(messages.MessageType_MessageType_PassphraseAck, message(messages.MessageType_MessageType_PassphraseRequest, &messages.PassphraseRequest{}), func(passphrase string) wire.Message {
+			d.passphrase = passphrase
+			d.passphraseCached = true
+			return then()
+		})
+	}
+
+	return then()
+}
+
+// confirm asks the user to press the button before running then
+func (d *Device) confirm(kind messages.ButtonRequestType, then func() wire.Message) wire.Message {
+	return d.await(messages.MessageType_MessageType_ButtonAck, buttonRequest(kind), func(string) wire.Message {
+		return then()
+	})
+}
+
+// initialized returns a failure if the device has no seed
+func (d *Device) initialized() (wire.Message, bool) {
+	if !d.state.Initialized {
+		return failure(messages.FailureType_Failure_NotInitialized, "Device not initialized"), false
+	}
+	return wire.Message{}, true
+}
+
+// notInitialized returns a failure if the device already has a seed
+func (d *Device) notInitialized() (wire.Message, bool) {
+	if d.state.Initialized {
+		return failure(messages.FailureType_Failure_UnexpectedMessage, "Device is already initialized. Use Wipe first."), false
+	}
+	return wire.Message{}, true
+}
+
+// keys returns the n first secret keys derived from the seed.
+// A non empty passphrase selects another set of keys.
+func (d *Device) keys(n int) ([]cipher.SecKey, error) {
+	seed := d.state.Mnemonic
+	if d.passphrase != "" {
+		seed += " " + d.passphrase
+	}
+	return cipher.GenerateDeterministicKeyPairs([]byte(seed), n)
+}
+
+// resetSession forgets the cached PIN and passphrase
+func (d *Device) resetSession() {
+	d.pinCached = false
+	d.passphrase = ""
+	d.passphraseCached = false
+}
+
+// AddressGen generates addresses of the seed
+func (d *Device) AddressGen(addressN, startIndex uint32, confirmAddress bool) (wire.Message, error) {
+	return d.do("AddressGen", func() wire.Message {
+		if msg, ok := d.initialized(); !ok {
+			return msg
+		}
+
+		if addressN == 0 || startIndex+addressN > maxAddresses {
+			return failure(messages.FailureType_Failure_AddressGeneration, "Asking for too much addresses")
+		}
+
+		return d.unlock(func() wire.Message {
+			keys, err := d.keys(int(startIndex + addressN))
+			if err != nil {
+				return failure(messages.FailureType_Failure_AddressGeneration, err.Error())
+			}
+
+			addresses := make([]string, 0, addressN)
+			for _, k := range keys[startIndex:] {
+				addresses = append(addresses, cipher.MustAddressFromSecKey(k).String())
+			}
+
+			response := func() wire.Message {
+				return message(messages.MessageType_MessageType_ResponseSkycoinAddress, &messages.ResponseSkycoinAddress{
+					Addresses: addresses,
+				})
+			}
+
+			if confirmAddress {
+				return d.confirm(messages.ButtonRequestType_ButtonRequest_Address, response)
+			}
+			return response()
+		})
+	})
+}
+
+// ApplySettings changes the label, language and passphrase protection of the device
+func (d *Device) ApplySettings(usePassphrase *bool, label string, language string) (wire.Message, error) {
+	return d.do("ApplySettings", func() wire.Message {
+		return d.applySettings(usePassphrase, label, language)
+	})
+}
+
+// ApplySettingsHomescreen applies settings and replaces the homescreen, which is not rendered by the simulator
+func (d *Device) ApplySettingsHomescreen(usePassphrase *bool, label, language string, homescreen []byte) (wire.Message, error) {
+	return d.do("ApplySettingsHomescreen", func() wire.Message {
+		return d.applySettings(usePassphrase, label, language)
+	})
+}
+
+func (d *Device) applySettings(usePassphrase *bool, label string, language string) wire.Message {
+	return d.unlock(func() wire.Message {
+		return d.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() wire.Message {
+			if usePassphrase != nil {
+				d.state.PassphraseProtection = *usePassphrase
+				d.passphraseCached = false
+			}
+			if label != "" {
+				d.state.Label = label
+			}
+			if language != "" {
+				d.state.Language = language
+			}
+			return success("Settings applied")
+		})
+	})
+}
+
+// Backup shows the words of the seed, one button press per word
+func (d *Device) Backup() (wire.Message, error) {
+	return d.do("Backup", func() wire.Message {
+		if msg, ok := d.initialized(); !ok {
+			return msg
+		}
+
+		if !d.state.NeedsBackup {
+			return failure(messages.FailureType_Failure_ProcessError, "Seed already backed up")
+		}
+
+		return d.unlock(func() wire.Message {
+			return d.backupWord(len(strings.Fields(d.state.Mnemonic)))
+		})
+	})
+}
+
+// backupWord shows the remaining words of the seed
+func (d *Device) backupWord(remaining int) wire.Message {
+	if remaining == 0 {
+		d.state.NeedsBackup = false
+		return success("Device backed up!")
+	}
+
+	return d.confirm(messages.ButtonRequestType_ButtonRequest_ConfirmWord, func() wire.Message {
+		return d.backupWord(remaining - 1)
+	})
+}
+
+// Cancel aborts the pending interactive flow
+func (d *Device) Cancel() (wire.Message, error) {
+	return d.do("Cancel", func() wire.Message {
+		d.pending = nil
+		return failure(messages.FailureType_Failure_ActionCancelled, "Action canceled by User")
+	})
+}
+
+// CheckMessageSignature checks that message was signed by address
+func (d *Device) CheckMessageSignature(msg, signature, address string) (wire.Message, error) {
+	return d.do("CheckMessageSignature", func() wire.Message {
+		addr, err := cipher.DecodeBase58Address(address)
+		if err != nil {
+			return failure(messages.FailureType_Failure_DataError, "Invalid address")
+		}
+
+		sig, err := cipher.SigFromHex(signature)
+		if err != nil {
+			return failure(messages.FailureType_Failure_InvalidSignature, "Invalid signature")
+		}
+
+		if err := cipher.VerifyAddressSignedHash(addr, sig, messageHash(msg)); err != nil {
+			return failure(messages.FailureType_Failure_InvalidSignature, "Invalid signature")
+		}
+
+		return success(address)
+	})
+}
+
+// ChangePin sets, changes or removes the PIN
+func (d *Device) ChangePin(removePin *bool) (wire.Message, error) {
+	return d.do("ChangePin", func() wire.Message {
+		if msg, ok := d.initialized(); !ok {
+			return msg
+		}
+
+		remove := removePin != nil && *removePin
+
+		return d.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() wire.Message {
+			if d.state.Pin != "" && !d.pinCached {
+				return d.await(messages.MessageType_MessageType_PinMatrixAck, pinMatrixRequest(messages.PinMatrixRequestType_PinMatrixRequestType_Current), func(pin string) wire.Message {
+					if pin != d.state.Pin {
+						return failure(messages.FailureType_Failure_PinInvalid, "PIN invalid")
+					}
+					d.pinCached = true
+					return d.changePin(remove)
+				})
+			}
+			return d.changePin(remove)
+		})
+	})
+}
+
+func (d *Device) changePin(remove bool) wire.Message {
+	if remove {
+		d.state.Pin = ""
+		return success("PIN removed")
+	}
+
+	return d.await(messages.MessageType_MessageType_PinMatrixAck, pinMatrixRequest(messages.PinMatrixRequestType_PinMatrixRequestType_NewFirst), func(first string) wire.Message {
+		return d.await(messages.MessageType_MessageType_PinMatrixAck, pinMatrixRequest(messages.PinMatrixRequestType_PinMatrixRequestType_NewSecond), func(second string) wire.Message {
+			if first != second {
+				return failure(messages.FailureType_Failure_PinMismatch, "PIN mismatch")
+			}
+			d.state.Pin = first
+			d.pinCached = true
+			return success("PIN changed")
+		})
+	})
+}
+
+// Connected returns true, the simulated device is always connected
+func (d *Device) Connected() bool {
+	return true
+}
+
+// Available returns true, the simulated device is always available
+func (d *Device) Available() bool {
+	return true
+}
+
+// FirmwareUpload checks the firmware hash, the firmware itself is discarded
+func (d *Device) FirmwareUpload(payload []byte, hash [32]byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r, ok := d.script.next("FirmwareUpload"); ok {
+		_, err := r.reply()
+		return err
+	}
+
+	if len(payload) == 0 {
+		return ErrEmptyFirmware
+	}
+
+	if sha256.Sum256(payload) != hash {
+		return ErrFirmwareHash
+	}
+
+	return nil
+}
+
+// GetRawEntropy returns size random bytes
+func (d *Device) GetRawEntropy(size uint32) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r, ok := d.script.next("GetRawEntropy"); ok {
+		if _, err := r.reply(); err != nil {
+			return nil, err
+		}
+	}
+
+	return d.random(int(size)), nil
+}
+
+// GetUsbInfo returns the USB information of the simulated device
+func (d *Device) GetUsbInfo() ([]usb.Info, error) {
+	return []usb.Info{
+		{
+			Path:      "simulator",
+			VendorID:  skyWallet.SkycoinVendorID,
+			ProductID: skyWallet.SkycoinHwProductID,
+		},
+	}, nil
+}
+
+// Enumerate returns the USB information of the simulated device
+func (d *Device) Enumerate() ([]usb.Info, error) {
+	return d.GetUsbInfo()
+}
+
+// Initialize resets the session of the simulated device, forgetting the cached PIN and passphrase, and returns its features
+func (d *Device) Initialize() (wire.Message, error) {
+	return d.do("Initialize", func() wire.Message {
+		d.resetSession()
+		d.pending = nil
+		return d.features()
+	})
+}
+
+// Ping echoes message
+func (d *Device) Ping(msg string) (wire.Message, error) {
+	return d.do("Ping", func() wire.Message {
+		return success(msg)
+	})
+}
+
+// GetFeatures returns the features of the simulated device
+func (d *Device) GetFeatures() (wire.Message, error) {
+	return d.do("GetFeatures", d.features)
+}
+
+// features returns the Features message of the simulated device
+func (d *Device) features() wire.Message {
+	var major, minor, patch uint32
+	if _, err := fmt.Sscanf(d.state.FirmwareVersion, "%d.%d.%d", &major, &minor, &patch); err != nil {
+		logger.WithError(err).Warningf("invalid firmware version %q", d.state.FirmwareVersion)
+	}
+
+	return message(messages.MessageType_MessageType_Features, &messages.Features{
+		Vendor:               proto.String("Skycoin Foundation"),
+		DeviceId:             proto.String(d.deviceID),
+		PinProtection:        proto.Bool(d.state.Pin != ""),
+		PassphraseProtection: proto.Bool(d.state.PassphraseProtection),
+		Language:             proto.String(d.state.Language),
+		Label:                proto.String(d.state.Label),
+		Initialized:          proto.Bool(d.state.Initialized),
+		PinCached:            proto.Bool(d.pinCached),
+		PassphraseCached:     proto.Bool(d.passphraseCached),
+		FirmwarePresent:      proto.Bool(true),
+		NeedsBackup:          proto.Bool(d.state.NeedsBackup),
+		UnfinishedBackup:     proto.Bool(false),
+		Model:                proto.String("1"),
+		FwMajor:              proto.Uint32(major),
+		FwMinor:              proto.Uint32(minor),
+		FwPatch:              proto.Uint32(patch),
+	})
+}
+
+// GenerateMnemonic initializes the device with a random seed
+func (d *Device) GenerateMnemonic(wordCount uint32, usePassphrase bool) (wire.Message, error) {
+	return d.do("GenerateMnemonic", func() wire.Message {
+		return d.generateMnemonic(wordCount, usePassphrase, nil)
+	})
+}
+
+// GenerateMnemonicWithEntropy initializes the device with a random seed mixed with entropy
+func (d *Device) GenerateMnemonicWithEntropy(wordCount uint32, usePassphrase bool, entropy []byte) (wire.Message, error) {
+	return d.do("GenerateMnemonicWithEntropy", func() wire.Message {
+		return d.generateMnemonic(wordCount, usePassphrase, entropy)
+	})
+}
+
+func (d *Device) generateMnemonic(wordCount uint32, usePassphrase bool, hostEntropy []byte) wire.Message {
+	if msg, ok := d.notInitialized(); !ok {
+		return msg
+	}
+
+	if wordCount != 12 && wordCount != 24 {
+		return failure(messages.FailureType_Failure_DataError, skyWallet.ErrInvalidWordCount.Error())
+	}
+
+	h := sha256.New()
+	h.Write(d.random(32)) // nolint: errcheck
+	h.Write(hostEntropy)  // nolint: errcheck
+	entropy := h.Sum(nil)
+	if wordCount == 12 {
+		entropy = entropy[:16]
+	}
+
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return failure(messages.FailureType_Failure_ProcessError, err.Error())
+	}
+
+	d.state.Initialized = true
+	d.state.Mnemonic = mnemonic
+	d.state.PassphraseProtection = usePassphrase
+	d.state.NeedsBackup = true
+	d.resetSession()
+
+	return success("Mnemonic successfully configured")
+}
+
+// Recovery asks for the words of the seed, in order, to initialize the device or to check its seed
+func (d *Device) Recovery(wordCount uint32, usePassphrase *bool, dryRun bool) (wire.Message, error) {
+	return d.do("Recovery", func() wire.Message {
+		if dryRun {
+			if msg, ok := d.initialized(); !ok {
+				return msg
+			}
+		} else if msg, ok := d.notInitialized(); !ok {
+			return msg
+		}
+
+		if wordCount != 12 && wordCount != 24 {
+			return failure(messages.FailureType_Failure_DataError, skyWallet.ErrInvalidWordCount.Error())
+		}
+
+		return d.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() wire.Message {
+			return d.recoveryWord(make([]string, 0, wordCount), int(wordCount), usePassphrase, dryRun)
+		})
+	})
+}
+
+// recoveryWord asks for the next word of the seed being recovered
+func (d *Device) recoveryWord(words []string, wordCount int, usePassphrase *bool, dryRun bool) wire.Message {
+	if len(words) == wordCount {
+		mnemonic := strings.Join(words, " ")
+		if err := bip39.ValidateMnemonic(mnemonic); err != nil {
+			return failure(messages.FailureType_Failure_DataError, "Mnemonic with wrong checksum provided")
+		}
+
+		if dryRun {
+			if mnemonic != d.state.Mnemonic {
+				return failure(messages.FailureType_Failure_DataError, "The seed is valid but does not match the one in the device")
+			}
+			return success("The seed is valid and matches the one in the device")
+		}
+
+		d.state.Initialized = true
+		d.state.Mnemonic = mnemonic
+		d.state.NeedsBackup = false
+		if usePassphrase != nil {
+			d.state.PassphraseProtection = *usePassphrase
+		}
+		d.resetSession()
+
+		return success("Device recovered")
+	}
+
+	return d.await(messages.MessageType_MessageType_WordAck, message(messages.MessageType_MessageType_WordRequest, &messages.WordRequest{}), func(word string) wire.Message {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if !isWord(word) {
+			return failure(messages.FailureType_Failure_DataError, "Word not found in a wordlist")
+		}
+		return d.recoveryWord(append(words, word), wordCount, usePassphrase, dryRun)
+	})
+}
+
+// SetMnemonic initializes the device with mnemonic
+func (d *Device) SetMnemonic(mnemonic string) (wire.Message, error) {
+	return d.do("SetMnemonic", func() wire.Message {
+		if msg, ok := d.notInitialized(); !ok {
+			return msg
+		}
+
+		if err := bip39.ValidateMnemonic(mnemonic); err != nil {
+			return failure(messages.FailureType_Failure_DataError, "Mnemonic with wrong checksum provided")
+		}
+
+		return d.confirm(messages.ButtonRequestType_ButtonRequest_ProtectCall, func() wire.Message {
+			d.state.Initialized = true
+			d.state.Mnemonic = mnemonic
+			d.state.NeedsBackup = false
+			d.resetSession()
+			return success(mnemonic)
+		})
+	})
+}
+
+// TransactionSign signs the inputs of a transaction after the outputs are confirmed.
+// The simulator does not build the transaction, each input is signed over the hash of the transaction
+// data and its own hash, so signatures are deterministic but are not valid on the network.
+func (d *Device) TransactionSign(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput) (wire.Message, error) {
+	return d.do("TransactionSign", func() wire.Message {
+		if msg, ok := d.initialized(); !ok {
+			return msg
+		}
+
+		return d.unlock(func() wire.Message {
+			return d.confirmOutput(inputs, outputs, 0)
+		})
+	})
+}
+
+// confirmOutput asks to confirm the remaining outputs then signs the inputs
+func (d *Device) confirmOutput(inputs []*messages.SkycoinTransactionInput, outputs []*messages.SkycoinTransactionOutput, i int) wire.Message {
+	if i < len(outputs) {
+		return d.confirm(messages.ButtonRequestType_ButtonRequest_ConfirmOutput, func() wire.Message {
+			return d.confirmOutput(inputs, outputs, i+1)
+		})
+	}
+
+	h := sha256.New()
+	for _, in := range inputs {
+		h.Write([]byte(in.GetHashIn())) // nolint: errcheck
+	}
+	for _, out := range outputs {
+		fmt.Fprintf(h, "%s:%d:%d", out.GetAddress(), out.GetCoin(), out.GetHour())
+	}
+	innerHash := cipher.MustSHA256FromBytes(h.Sum(nil))
+
+	signatures := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		inHash, err := cipher.SHA256FromHex(in.GetHashIn())
+		if err != nil {
+			return failure(messages.FailureType_Failure_DataError, "Invalid input hash")
+		}
+
+		sig, err := d.sign(int(in.GetIndex()), cipher.AddSHA256(innerHash, inHash))
+		if err != nil {
+			return failure(messages.FailureType_Failure_ProcessError, err.Error())
+		}
+		signatures = append(signatures, sig)
+	}
+
+	return message(messages.MessageType_MessageType_ResponseTransactionSign, &messages.ResponseTransactionSign{
+		Signatures: signatures,
+		Padding:    proto.Bool(false),
+	})
+}
+
+// SignMessage signs message with the key of the address at addressIndex
+func (d *Device) SignMessage(addressIndex int, msg string) (wire.Message, error) {
+	return d.do("SignMessage", func() wire.Message {
+		if msg, ok := d.initialized(); !ok {
+			return msg
+		}
+
+		return d.unlock(func() wire.Message {
+			sig, err := d.sign(addressIndex, messageHash(msg))
+			if err != nil {
+				return failure(messages.FailureType_Failure_ProcessError, err.Error())
+			}
+
+			return message(messages.MessageType_MessageType_ResponseSkycoinSignMessage, &messages.ResponseSkycoinSignMessage{
+				SignedMessage: proto.String(sig),
+			})
+		})
+	})
+}
+
+// sign signs hash with the key of the address at index and returns the hex encoded signature
+func (d *Device) sign(index int, hash cipher.SHA256) (string, error) {
+	if index < 0 || index >= maxAddresses {
+		return "", fmt.Errorf("invalid address index %d", index)
+	}
+
+	keys, err := d.keys(index + 1)
+	if err != nil {
+		return "", err
+	}
+
+	var sig cipher.Sig
+	if d.deterministic {
+		sig, err = deterministicSignHash(hash, keys[index])
+	} else {
+		sig, err = cipher.SignHash(hash, keys[index])
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return sig.Hex(), nil
+}
+
+// deterministicSignHash signs hash with a nonce derived from the key and the hash with HMAC-SHA256, instead of a random
+// nonce, so the same hash has the same signature
+func deterministicSignHash(hash cipher.SHA256, sec cipher.SecKey) (cipher.Sig, error) {
+	if hash.Null() {
+		return cipher.Sig{}, cipher.ErrNullSignHash
+	}
+
+	var seckey, msg, nonce secp.Number
+	seckey.SetBytes(sec[:])
+	msg.SetBytes(hash[:])
+
+	mac := hmac.New(sha256.New, sec[:])
+	for counter := byte(0); ; counter++ {
+		mac.Reset()
+		mac.Write(hash[:])         // nolint: errcheck
+		mac.Write([]byte{counter}) // nolint: errcheck
+		nonce.SetBytes(mac.Sum(nil))
+		if nonce.Sign() != 0 && nonce.Cmp(&secp.TheCurve.Order.Int) < 0 {
+			break
+		}
+	}
+
+	var sig secp.Signature
+	var recid int
+	if sig.Sign(&seckey, &msg, &nonce, &recid) != 1 {
+		return cipher.Sig{}, errors.New("failed to sign the hash")
+	}
+
+	return cipher.NewSig(append(sig.Bytes(), byte(recid)))
+}
+
+// Wipe erases the seed and settings of the device
+func (d *Device) Wipe() (wire.Message, error) {
+	return d.do("Wipe", func() wire.Message {
+		return d.confirm(messages.ButtonRequestType_ButtonRequest_WipeDevice, func() wire.Message {
+			d.state = State{
+				FirmwareVersion: d.state.FirmwareVersion,
+			}
+			d.resetSession()
+			return success("Device wiped")
+		})
+	})
+}
+
+// PinMatrixAck answers a PinMatrixRequest. The simulated matrix is not scrambled, p is the PIN itself.
+func (d *Device) PinMatrixAck(p string) (wire.Message, error) {
+	return d.do("PinMatrixAck", func() wire.Message {
+		return d.answer(messages.MessageType_MessageType_PinMatrixAck, p)
+	})
+}
+
+// WordAck answers a WordRequest
+func (d *Device) WordAck(word string) (wire.Message, error) {
+	return d.do("WordAck", func() wire.Message {
+		return d.answer(messages.MessageType_MessageType_WordAck, word)
+	})
+}
+
+// PassphraseAck answers a PassphraseRequest
+func (d *Device) PassphraseAck(passphrase string) (wire.Message, error) {
+	return d.do("PassphraseAck", func() wire.Message {
+		return d.answer(messages.MessageType_MessageType_PassphraseAck, passphrase)
+	})
+}
+
+// ButtonAck answers a ButtonRequest
+func (d *Device) ButtonAck() (wire.Message, error) {
+	return d.do("ButtonAck", func() wire.Message {
+		return d.answer(messages.MessageType_MessageType_ButtonAck, "")
+	})
+}
+
+// SetAutoPressButton makes the simulated device answer its button requests by itself
+func (d *Device) SetAutoPressButton(simulateButtonPress bool, simulateButtonType skyWallet.ButtonType) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.autoPressButton = simulateButtonPress
+	return nil
+}
+
+// Close does nothing, there is no connection to close
+func (d *Device) Close() {}
+
+// Connect does nothing, the simulated device is always connected
+func (d *Device) Connect() error {
+	return nil
+}
+
+// Disconnect aborts the pending interactive flow, as unplugging the device does
+func (d *Device) Disconnect() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = nil
+	return nil
+}
+
+// messageHash returns the hash signed for message, a hex encoded SHA256 is signed as is
+func messageHash(message string) cipher.SHA256 {
+	if len(message) == 64 {
+		if h, err := cipher.SHA256FromHex(message); err == nil {
+			return h
+		}
+	}
+	return cipher.SumSHA256([]byte(message))
+}
+
+// isWord returns true if word is in the bip39 wordlist
+func isWord(word string) bool {
+	for _, w := range wordlists.English {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func message(kind messages.MessageType, pb proto.Message) wire.Message {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	return wire.Message{
+		Kind: uint16(kind),
+		Data: data,
+	}
+}
+
+func success(msg string) wire.Message {
+	return message(messages.MessageType_MessageType_Success, &messages.Success{
+		Message: proto.String(msg),
+	})
+}
+
+func failure(code messages.FailureType, msg string) wire.Message {
+	return message(messages.MessageType_MessageType_Failure, &messages.Failure{
+		Code:    code.Enum(),
+		Message: proto.String(msg),
+	})
+}
+
+func buttonRequest(kind messages.ButtonRequestType) wire.Message {
+	return message(messages.MessageType_MessageType_ButtonRequest, &messages.ButtonRequest{
+		Code: kind.Enum(),
+	})
+}
+
+func pinMatrixRequest(kind messages.PinMatrixRequestType) wire.Message {
+	return message(messages.MessageType_MessageType_PinMatrixRequest, &messages.PinMatrixRequest{
+		Type: kind.Enum(),
+	})
+}
@@ -0,0 +1,446 @@
+package simulator
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+	"github.com/stretchr/testify/require"
+)
+
+const testMnemonic = "cloud flower upset remain green metal below cup stem infant art thank"
+
+func requireKind(t *testing.T, kind messages.MessageType, msg wire.Message, err error) {
+	t.Helper()
+	require.NoError(t, err)
+	require.Equal(t, kind.String(), messages.MessageType(msg.Kind).String())
+}
+
+func requireSuccess(t *testing.T, expected string, msg wire.Message, err error) {
+	t.Helper()
+	requireKind(t, messages.MessageType_MessageType_Success, msg, err)
+	s, err := skyWallet.DecodeSuccessMsg(msg)
+	require.NoError(t, err)
+	require.Equal(t, expected, s)
+}
+
+func requireFailure(t *testing.T, code messages.FailureType, expected string, msg wire.Message, err error) {
+	t.Helper()
+	requireKind(t, messages.MessageType_MessageType_Failure, msg, err)
+	var failure messages.Failure
+	require.NoError(t, proto.Unmarshal(msg.Data, &failure))
+	require.Equal(t, code, failure.GetCode())
+	require.Equal(t, expected, failure.GetMessage())
+}
+
+func initializedDevice(t *testing.T) *Device {
+	d := New(nil)
+
+	msg, err := d.SetMnemonic(testMnemonic)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+
+	msg, err = d.ButtonAck()
+	requireSuccess(t, testMnemonic, msg, err)
+
+	return d
+}
+
+func TestFeatures(t *testing.T) {
+	d := New(nil)
+
+	msg, err := d.GetFeatures()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+
+	var features messages.Features
+	require.NoError(t, proto.Unmarshal(msg.Data, &features))
+	require.Equal(t, "Skycoin Foundation", features.GetVendor())
+	require.False(t, features.GetInitialized())
+	require.Len(t, features.GetDeviceId(), 24)
+	require.Equal(t, uint32(1), features.GetFwMajor())
+	require.Equal(t, uint32(7), features.GetFwMinor())
+
+	d = initializedDevice(t)
+	msg, err = d.GetFeatures()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+	require.NoError(t, proto.Unmarshal(msg.Data, &features))
+	require.True(t, features.GetInitialized())
+	require.False(t, features.GetNeedsBackup())
+}
+
+func TestAddressGen(t *testing.T) {
+	d := New(nil)
+
+	msg, err := d.AddressGen(2, 0, false)
+	requireFailure(t, messages.FailureType_Failure_NotInitialized, "Device not initialized", msg, err)
+
+	d = initializedDevice(t)
+
+	// the addresses of the seed are derived as on the firmware
+	msg, err = d.AddressGen(2, 0, false)
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+	addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw", "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs"}, addresses)
+
+	msg, err = d.AddressGen(1, 1, true)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+	addresses, err = skyWallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs"}, addresses)
+
+	msg, err = d.AddressGen(100, 0, false)
+	requireFailure(t, messages.FailureType_Failure_AddressGeneration, "Asking for too much addresses", msg, err)
+}
+
+func TestSignMessage(t *testing.T) {
+	d := initializedDevice(t)
+
+	msg, err := d.SignMessage(0, "Hello World")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+	signature, err := skyWallet.DecodeResponseSkycoinSignMessage(msg)
+	require.NoError(t, err)
+
+	msg, err = d.CheckMessageSignature("Hello World", signature, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw")
+	requireSuccess(t, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw", msg, err)
+
+	msg, err = d.CheckMessageSignature("Hello World", signature, "zC8GAQGQBfwk7vtTxVoRG7iMperHNuyYPs")
+	requireFailure(t, messages.FailureType_Failure_InvalidSignature, "Invalid signature", msg, err)
+
+	// signature created by the firmware
+	msg, err = d.CheckMessageSignature("Hello World", "6ebd63dd5e57cad07b6d229e96b5d2ac7d1bec1466d2a95bd200c21be6a0bf194b5ad5123f6e37c6393ee3635b38b938fcd91bbf1327fc957849a9e5736f6e4300", "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw")
+	requireSuccess(t, "2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw", msg, err)
+}
+
+func TestTransactionSign(t *testing.T) {
+	d := initializedDevice(t)
+
+	inputs := []*messages.SkycoinTransactionInput{
+		{
+			HashIn: proto.String("181bd5656115172fe81451fae4fb56498a97744d89702e73da75ba91ed5200f9"),
+			Index:  proto.Uint32(0),
+		},
+	}
+	outputs := []*messages.SkycoinTransactionOutput{
+		{
+			Address: proto.String("K9TzLrgqz7uXn3QJHGxmzdRByAzH33J2ot"),
+			Coin:    proto.Uint64(100000),
+			Hour:    proto.Uint64(2),
+		},
+		{
+			Address: proto.String("2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"),
+			Coin:    proto.Uint64(200000),
+			Hour:    proto.Uint64(2),
+		},
+	}
+
+	msg, err := d.TransactionSign(inputs, outputs)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_ResponseTransactionSign, msg, err)
+
+	signatures, err := skyWallet.DecodeResponseTransactionSign(msg)
+	require.NoError(t, err)
+	require.Len(t, signatures, 1)
+	require.Len(t, signatures[0], 130)
+}
+
+func TestWipe(t *testing.T) {
+	d := initializedDevice(t)
+
+	msg, err := d.Wipe()
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	require.True(t, d.State().Initialized)
+
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "Device wiped", msg, err)
+	require.Equal(t, State{FirmwareVersion: DefaultFirmwareVersion}, d.State())
+
+	// no flow is pending anymore
+	msg, err = d.ButtonAck()
+	requireFailure(t, messages.FailureType_Failure_UnexpectedMessage, "Unexpected message", msg, err)
+}
+
+func TestGenerateMnemonic(t *testing.T) {
+	for _, wordCount := range []uint32{12, 24} {
+		d := New(nil)
+		msg, err := d.GenerateMnemonic(wordCount, false)
+		requireSuccess(t, "Mnemonic successfully configured", msg, err)
+
+		state := d.State()
+		require.True(t, state.Initialized)
+		require.True(t, state.NeedsBackup)
+		require.Len(t, strings.Fields(state.Mnemonic), int(wordCount))
+
+		msg, err = d.GenerateMnemonic(wordCount, false)
+		requireFailure(t, messages.FailureType_Failure_UnexpectedMessage, "Device is already initialized. Use Wipe first.", msg, err)
+	}
+
+	d := New(nil)
+	msg, err := d.GenerateMnemonicWithEntropy(24, true, []byte("host entropy"))
+	requireSuccess(t, "Mnemonic successfully configured", msg, err)
+	require.Len(t, strings.Fields(d.State().Mnemonic), 24)
+	require.True(t, d.State().PassphraseProtection)
+
+	msg, err = New(nil).GenerateMnemonic(15, false)
+	requireFailure(t, messages.FailureType_Failure_DataError, "word count must be 12 or 24", msg, err)
+}
+
+func TestBackup(t *testing.T) {
+	d := New(nil)
+	msg, err := d.GenerateMnemonic(12, false)
+	requireSuccess(t, "Mnemonic successfully configured", msg, err)
+
+	msg, err = d.Backup()
+	for i := 0; i < 12; i++ {
+		requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+		msg, err = d.ButtonAck()
+	}
+	requireSuccess(t, "Device backed up!", msg, err)
+	require.False(t, d.State().NeedsBackup)
+
+	msg, err = d.Backup()
+	requireFailure(t, messages.FailureType_Failure_ProcessError, "Seed already backed up", msg, err)
+}
+
+func TestRecovery(t *testing.T) {
+	words := strings.Fields(testMnemonic)
+
+	d := New(nil)
+	msg, err := d.Recovery(12, nil, false)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	for _, w := range words {
+		requireKind(t, messages.MessageType_MessageType_WordRequest, msg, err)
+		msg, err = d.WordAck(w)
+	}
+	requireSuccess(t, "Device recovered", msg, err)
+	require.Equal(t, testMnemonic, d.State().Mnemonic)
+
+	// dry run
+	msg, err = d.Recovery(12, nil, true)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	for _, w := range words {
+		requireKind(t, messages.MessageType_MessageType_WordRequest, msg, err)
+		msg, err = d.WordAck(w)
+	}
+	requireSuccess(t, "The seed is valid and matches the one in the device", msg, err)
+
+	// unknown word
+	msg, err = d.Recovery(12, nil, true)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_WordRequest, msg, err)
+	msg, err = d.WordAck("foobar")
+	requireFailure(t, messages.FailureType_Failure_DataError, "Word not found in a wordlist", msg, err)
+}
+
+func TestChangePin(t *testing.T) {
+	d := initializedDevice(t)
+
+	msg, err := d.ChangePin(nil)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("1234")
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("1234")
+	requireSuccess(t, "PIN changed", msg, err)
+	require.Equal(t, "1234", d.State().Pin)
+
+	// the PIN is cached until the device is wiped or initialized again
+	msg, err = d.SignMessage(0, "Hello World")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+
+	// a new session asks for the PIN
+	d.resetSession()
+	msg, err = d.SignMessage(0, "Hello World")
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("4321")
+	requireFailure(t, messages.FailureType_Failure_PinInvalid, "PIN invalid", msg, err)
+
+	msg, err = d.SignMessage(0, "Hello World")
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("1234")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+
+	msg, err = d.ChangePin(proto.Bool(true))
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "PIN removed", msg, err)
+	require.Empty(t, d.State().Pin)
+
+	// mismatch
+	msg, err = d.ChangePin(nil)
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("1234")
+	requireKind(t, messages.MessageType_MessageType_PinMatrixRequest, msg, err)
+	msg, err = d.PinMatrixAck("123")
+	requireFailure(t, messages.FailureType_Failure_PinMismatch, "PIN mismatch", msg, err)
+}
+
+func TestPassphrase(t *testing.T) {
+	d := initializedDevice(t)
+
+	msg, err := d.ApplySettings(proto.Bool(true), "simulated", "")
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "Settings applied", msg, err)
+	require.Equal(t, "simulated", d.State().Label)
+
+	msg, err = d.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_PassphraseRequest, msg, err)
+	msg, err = d.PassphraseAck("")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+	addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"}, addresses)
+
+	// another passphrase selects another wallet
+	d.resetSession()
+	msg, err = d.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_PassphraseRequest, msg, err)
+	msg, err = d.PassphraseAck("secret")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+	addresses, err = skyWallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	require.NotEqual(t, []string{"2EU3JbveHdkxW6z5tdhbbB2kRAWvXC2pLzw"}, addresses)
+}
+
+func TestInitialize(t *testing.T) {
+	d := initializedDevice(t)
+
+	msg, err := d.ApplySettings(proto.Bool(true), "", "")
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.ButtonAck()
+	requireSuccess(t, "Settings applied", msg, err)
+
+	msg, err = d.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_PassphraseRequest, msg, err)
+	msg, err = d.PassphraseAck("secret")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+
+	// the passphrase is cached until the session is reset
+	msg, err = d.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+
+	msg, err = d.Initialize()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+	var features messages.Features
+	require.NoError(t, proto.Unmarshal(msg.Data, &features))
+	require.False(t, features.GetPassphraseCached())
+
+	msg, err = d.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_PassphraseRequest, msg, err)
+
+	// a pending flow is abandoned
+	msg, err = d.Initialize()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+	msg, err = d.PassphraseAck("secret")
+	requireFailure(t, messages.FailureType_Failure_UnexpectedMessage, "Unexpected message", msg, err)
+}
+
+func TestCancelAndDisconnect(t *testing.T) {
+	d := initializedDevice(t)
+
+	msg, err := d.Wipe()
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	msg, err = d.Cancel()
+	requireFailure(t, messages.FailureType_Failure_ActionCancelled, "Action canceled by User", msg, err)
+	msg, err = d.ButtonAck()
+	requireFailure(t, messages.FailureType_Failure_UnexpectedMessage, "Unexpected message", msg, err)
+
+	msg, err = d.Wipe()
+	requireKind(t, messages.MessageType_MessageType_ButtonRequest, msg, err)
+	require.NoError(t, d.Disconnect())
+	msg, err = d.ButtonAck()
+	requireFailure(t, messages.FailureType_Failure_UnexpectedMessage, "Unexpected message", msg, err)
+
+	require.True(t, d.State().Initialized)
+}
+
+func TestAutoPressButton(t *testing.T) {
+	d := initializedDevice(t)
+	require.NoError(t, d.SetAutoPressButton(true, skyWallet.ButtonRight))
+
+	msg, err := d.Wipe()
+	requireSuccess(t, "Device wiped", msg, err)
+}
+
+func TestFirmwareUpload(t *testing.T) {
+	d := New(nil)
+
+	payload := []byte("firmware")
+	require.NoError(t, d.FirmwareUpload(payload, sha256.Sum256(payload)))
+	require.Equal(t, ErrFirmwareHash, d.FirmwareUpload(payload, [32]byte{}))
+	require.Equal(t, ErrEmptyFirmware, d.FirmwareUpload(nil, [32]byte{}))
+}
+
+func TestPing(t *testing.T) {
+	d := New(nil)
+
+	msg, err := d.Ping("diagnostics")
+	requireSuccess(t, "diagnostics", msg, err)
+
+	infos, err := d.GetUsbInfo()
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.Equal(t, "simulator", infos[0].Path)
+}
+
+func TestDeterministic(t *testing.T) {
+	d1 := NewDeterministic(nil)
+	d2 := NewDeterministic(nil)
+
+	msg, err := d1.GetFeatures()
+	requireKind(t, messages.MessageType_MessageType_Features, msg, err)
+	var features messages.Features
+	require.NoError(t, proto.Unmarshal(msg.Data, &features))
+	require.True(t, features.GetInitialized())
+	require.Equal(t, DeterministicDeviceID, features.GetDeviceId())
+
+	// the signatures are the same across the devices, and valid
+	var signatures []string
+	for _, d := range []*Device{d1, d2} {
+		msg, err := d.SignMessage(0, "Hello World")
+		requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+		signature, err := skyWallet.DecodeResponseSkycoinSignMessage(msg)
+		require.NoError(t, err)
+		signatures = append(signatures, signature)
+	}
+	require.Equal(t, signatures[0], signatures[1])
+
+	msg, err = d1.AddressGen(1, 0, false)
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinAddress, msg, err)
+	addresses, err := skyWallet.DecodeResponseSkycoinAddress(msg)
+	require.NoError(t, err)
+	msg, err = d1.CheckMessageSignature("Hello World", signatures[0], addresses[0])
+	requireSuccess(t, addresses[0], msg, err)
+
+	msg, err = d1.SignMessage(0, "Hello World!")
+	requireKind(t, messages.MessageType_MessageType_ResponseSkycoinSignMessage, msg, err)
+	signature, err := skyWallet.DecodeResponseSkycoinSignMessage(msg)
+	require.NoError(t, err)
+	require.NotEqual(t, signatures[0], signature)
+
+	e1, err := d1.GetRawEntropy(100)
+	require.NoError(t, err)
+	e2, err := d2.GetRawEntropy(100)
+	require.NoError(t, err)
+	require.Len(t, e1, 100)
+	require.Equal(t, e1, e2)
+
+	// a script state is kept
+	d := NewDeterministic(&Script{State: &State{}})
+	require.False(t, d.State().Initialized)
+}
@@ -0,0 +1,282 @@
+// Package smoketest runs the startup checks declared in a JSON file, such as the device being present or its
+// firmware being recent enough. The checks are run again until they all pass, and the mutating endpoints can be
+// refused until then.
+package smoketest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+)
+
+const (
+	// CheckDevicePresent passes when the device is found
+	CheckDevicePresent = "device_present"
+	// CheckFirmwareVersion passes when the firmware of the device is MinVersion or newer
+	CheckFirmwareVersion = "firmware_version"
+	// CheckNodeReachable passes when a GET of URL, such as the health endpoint of a skycoin node, returns a 2xx status
+	CheckNodeReachable = "node_reachable"
+
+	// DefaultRetryInterval is how often the failed checks are run again by default
+	DefaultRetryInterval = 10 * time.Second
+
+	// nodeTimeout is how long the node_reachable check waits for the node
+	nodeTimeout = 5 * time.Second
+)
+
+var logger = logging.MustGetLogger("smoketest")
+
+// Device is the device the checks run against
+type Device interface {
+	// Present returns true if the device is found
+	Present() (bool, error)
+	// FirmwareVersion returns the firmware version of the device, false if it runs no firmware
+	FirmwareVersion() (firmware.Version, bool, error)
+}
+
+// Check is a startup check
+type Check struct {
+	// Name defaults to the type of the check
+	Name string `json:"name,omitempty"`
+	// Type is device_present, firmware_version or node_reachable
+	Type string `json:"type"`
+	// MinVersion is the minimum firmware version of the firmware_version check
+	MinVersion string `json:"min_version,omitempty"`
+	// URL is the URL of the node_reachable check
+	URL string `json:"url,omitempty"`
+
+	minVersion firmware.Version
+}
+
+// Config declares the startup checks
+type Config struct {
+	Checks []Check `json:"checks"`
+	// BlockMutating refuses the requests to the mutating endpoints until the checks pass
+	BlockMutating bool `json:"block_mutating"`
+	// RetryInterval is how often the failed checks are run again, defaults to DefaultRetryInterval
+	RetryInterval firmware.Duration `json:"retry_interval,omitempty"`
+	// Transport sends the requests of the node checks, nil means http.DefaultTransport
+	Transport http.RoundTripper `json:"-"`
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Status is the outcome of the last run of the checks
+type Status struct {
+	// Passed is true once all the checks passed, they are not run again then
+	Passed bool `json:"passed"`
+	// Blocking is true while the mutating endpoints are refused
+	Blocking  bool       `json:"blocking"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Results   []Result   `json:"results"`
+}
+
+// Load loads the startup checks from a JSON file
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid startup checks %s: %v", path, err)
+	}
+
+	if err := c.init(); err != nil {
+		return nil, fmt.Errorf("invalid startup checks %s: %v", path, err)
+	}
+
+	return &c, nil
+}
+
+func (c *Config) init() error {
+	if len(c.Checks) == 0 {
+		return errors.New("no startup checks")
+	}
+
+	if c.RetryInterval < 0 {
+		return errors.New("retry_interval must not be negative")
+	}
+	if c.RetryInterval == 0 {
+		c.RetryInterval = firmware.Duration(DefaultRetryInterval)
+	}
+
+	for i := range c.Checks {
+		check := &c.Checks[i]
+		if check.Name == "" {
+			check.Name = check.Type
+		}
+
+		switch check.Type {
+		case CheckDevicePresent:
+		case CheckFirmwareVersion:
+			v, err := firmware.ParseVersion(check.MinVersion)
+			if err != nil {
+				return fmt.Errorf("check %s: %v", check.Name, err)
+			}
+			check.minVersion = v
+		case CheckNodeReachable:
+			if !strings.HasPrefix(check.URL, "http://") && !strings.HasPrefix(check.URL, "https://") {
+				return fmt.Errorf("check %s: url must be an http or https URL", check.Name)
+			}
+		default:
+			return fmt.Errorf("check %s: invalid type %q, choices are: %s, %s or %s", check.Name, check.Type, CheckDevicePresent, CheckFirmwareVersion, CheckNodeReachable)
+		}
+	}
+
+	return nil
+}
+
+// Suite runs the startup checks against the device
+type Suite struct {
+	config Config
+	device Device
+	client *http.Client
+
+	mu        sync.Mutex
+	passed    bool
+	checkedAt *time.Time
+	results   []Result
+}
+
+// NewSuite creates a Suite running the checks of c against device
+func NewSuite(c Config, device Device) *Suite {
+	return &Suite{
+		config: c,
+		device: device,
+		client: &http.Client{
+			Timeout:   nodeTimeout,
+			Transport: c.Transport,
+		},
+	}
+}
+
+// Status returns the outcome of the last run of the checks
+func (s *Suite) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]Result, len(s.results))
+	copy(results, s.results)
+
+	return Status{
+		Passed:    s.passed,
+		Blocking:  s.config.BlockMutating && !s.passed,
+		CheckedAt: s.checkedAt,
+		Results:   results,
+	}
+}
+
+// Blocking returns true while the mutating endpoints are refused
+func (s *Suite) Blocking() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.config.BlockMutating && !s.passed
+}
+
+// Run runs the checks at startup, then every retry interval until they all pass or quit is closed
+func (s *Suite) Run(quit <-chan struct{}) {
+	t := time.NewTicker(time.Duration(s.config.RetryInterval))
+	defer t.Stop()
+
+	for {
+		if s.RunOnce() {
+			logger.Info("All startup checks passed")
+			return
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce runs the checks once and returns true if they all passed
+func (s *Suite) RunOnce() bool {
+	passed := true
+	results := make([]Result, 0, len(s.config.Checks))
+	for _, c := range s.config.Checks {
+		r := Result{
+			Name: c.Name,
+			Type: c.Type,
+		}
+
+		if err := s.run(c); err != nil {
+			logger.WithError(err).Warningf("Startup check %s failed", c.Name)
+			r.Error = err.Error()
+			passed = false
+		} else {
+			r.Passed = true
+		}
+
+		results = append(results, r)
+	}
+
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.passed = passed
+	s.checkedAt = &now
+	s.results = results
+
+	return passed
+}
+
+func (s *Suite) run(c Check) error {
+	switch c.Type {
+	case CheckDevicePresent:
+		present, err := s.device.Present()
+		if err != nil {
+			return err
+		}
+		if !present {
+			return errors.New("device not found")
+		}
+
+	case CheckFirmwareVersion:
+		v, ok, err := s.device.FirmwareVersion()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the device runs no firmware")
+		}
+		if v.Compare(c.minVersion) < 0 {
+			return fmt.Errorf("firmware %s is older than %s", v, c.minVersion)
+		}
+
+	case CheckNodeReachable:
+		resp, err := s.client.Get(c.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("%s returned %s", c.URL, resp.Status)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package smoketest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/firmware"
+)
+
+type fakeDevice struct {
+	mu       sync.Mutex
+	present  bool
+	version  *firmware.Version
+	err      error
+	requests int
+}
+
+func (d *fakeDevice) Present() (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.requests++
+	return d.present, d.err
+}
+
+func (d *fakeDevice) FirmwareVersion() (firmware.Version, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.version == nil {
+		return firmware.Version{}, false, d.err
+	}
+	return *d.version, true, d.err
+}
+
+func (d *fakeDevice) set(present bool, version *firmware.Version) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.present = present
+	d.version = version
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smoketest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name   string
+		config string
+		err    string
+		expect *Config
+	}{
+		{
+			name: "valid",
+			config: `{
+				"checks": [
+					{"type": "device_present"},
+					{"name": "firmware", "type": "firmware_version", "min_version": "1.7.0"},
+					{"type": "node_reachable", "url": "http://127.0.0.1:6420/api/v1/health"}
+				],
+				"block_mutating": true
+			}`,
+			expect: &Config{
+				Checks: []Check{
+					{Name: "device_present", Type: CheckDevicePresent},
+					{Name: "firmware", Type: CheckFirmwareVersion, MinVersion: "1.7.0", minVersion: firmware.Version{Major: 1, Minor: 7}},
+					{Name: "node_reachable", Type: CheckNodeReachable, URL: "http://127.0.0.1:6420/api/v1/health"},
+				},
+				BlockMutating: true,
+				RetryInterval: firmware.Duration(DefaultRetryInterval),
+			},
+		},
+		{
+			name:   "invalid json",
+			config: `{"checks": {}}`,
+			err:    "invalid startup checks",
+		},
+		{
+			name:   "no checks",
+			config: `{"block_mutating": true}`,
+			err:    "no startup checks",
+		},
+		{
+			name:   "invalid type",
+			config: `{"checks": [{"type": "attestation"}]}`,
+			err:    `check attestation: invalid type "attestation", choices are: device_present, firmware_version or node_reachable`,
+		},
+		{
+			name:   "invalid version",
+			config: `{"checks": [{"type": "firmware_version", "min_version": "1.7"}]}`,
+			err:    `check firmware_version: invalid firmware version "1.7"`,
+		},
+		{
+			name:   "invalid url",
+			config: `{"checks": [{"type": "node_reachable", "url": "127.0.0.1:6420"}]}`,
+			err:    "check node_reachable: url must be an http or https URL",
+		},
+		{
+			name:   "negative retry interval",
+			config: `{"checks": [{"type": "device_present"}], "retry_interval": "-1s"}`,
+			err:    "retry_interval must not be negative",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("checks%d.json", i))
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.config), 0600))
+
+			c, err := Load(path)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, c)
+		})
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	nodeStatus := http.StatusServiceUnavailable
+	var mu sync.Mutex
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(nodeStatus)
+	}))
+	defer node.Close()
+
+	c := Config{
+		Checks: []Check{
+			{Name: "device", Type: CheckDevicePresent},
+			{Name: "firmware", Type: CheckFirmwareVersion, MinVersion: "1.7.0"},
+			{Name: "node", Type: CheckNodeReachable, URL: node.URL},
+		},
+		BlockMutating: true,
+	}
+	require.NoError(t, c.init())
+
+	device := &fakeDevice{}
+	s := NewSuite(c, device)
+
+	status := s.Status()
+	require.False(t, status.Passed)
+	require.True(t, status.Blocking)
+	require.Nil(t, status.CheckedAt)
+	require.Empty(t, status.Results)
+
+	require.False(t, s.RunOnce())
+	status = s.Status()
+	require.True(t, s.Blocking())
+	require.NotNil(t, status.CheckedAt)
+	require.Equal(t, []Result{
+		{Name: "device", Type: CheckDevicePresent, Error: "device not found"},
+		{Name: "firmware", Type: CheckFirmwareVersion, Error: "the device runs no firmware"},
+		{Name: "node", Type: CheckNodeReachable, Error: node.URL + " returned 503 Service Unavailable"},
+	}, status.Results)
+
+	device.set(true, &firmware.Version{Major: 1, Minor: 6, Patch: 9})
+	mu.Lock()
+	nodeStatus = http.StatusOK
+	mu.Unlock()
+	require.False(t, s.RunOnce())
+	require.Equal(t, []Result{
+		{Name: "device", Type: CheckDevicePresent, Passed: true},
+		{Name: "firmware", Type: CheckFirmwareVersion, Error: "firmware 1.6.9 is older than 1.7.0"},
+		{Name: "node", Type: CheckNodeReachable, Passed: true},
+	}, s.Status().Results)
+
+	device.set(true, &firmware.Version{Major: 1, Minor: 7, Patch: 0})
+	require.True(t, s.RunOnce())
+	status = s.Status()
+	require.True(t, status.Passed)
+	require.False(t, status.Blocking)
+
+	device.err = errors.New("libusb: device busy")
+	require.False(t, s.RunOnce())
+	require.Equal(t, "libusb: device busy", s.Status().Results[0].Error)
+}
+
+func TestRun(t *testing.T) {
+	c := Config{
+		Checks:        []Check{{Name: "device", Type: CheckDevicePresent}},
+		RetryInterval: firmware.Duration(10 * time.Millisecond),
+	}
+	require.NoError(t, c.init())
+
+	device := &fakeDevice{}
+	s := NewSuite(c, device)
+	require.False(t, s.Blocking())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(make(chan struct{}))
+	}()
+
+	// the checks are run again until they pass
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, s.Status().Passed)
+	device.set(true, nil)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the checks were not run again")
+	}
+	require.True(t, s.Status().Passed)
+
+	// quit stops the retries
+	device.set(false, nil)
+	requests := device.requests
+	s = NewSuite(c, device)
+	quit := make(chan struct{})
+	close(quit)
+	s.Run(quit)
+	require.False(t, s.Status().Passed)
+	require.Equal(t, requests+1, device.requests)
+}
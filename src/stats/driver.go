@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"time"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+// headerSize is the size of the message header: the "?##" magic, the message type and the payload size
+const headerSize = 9
+
+// Driver is a device driver collecting the statistics of the messages sent to the device
+type Driver struct {
+	skyWallet.DeviceDriver
+	collector *Collector
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps driver, collecting the statistics of its messages in collector
+func NewDriver(driver skyWallet.DeviceDriver, collector *Collector) *Driver {
+	return &Driver{
+		DeviceDriver: driver,
+		collector:    collector,
+	}
+}
+
+// SendToDevice implements skyWallet.DeviceDriver
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	kind, size := uint16(header.Kind(chunks)), header.Size(chunks)
+	start := time.Now()
+
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+
+	d.collector.Observe(kind, headerSize+size, headerSize+len(msg.Data), time.Since(start), err)
+	return msg, err
+}
+
+// SendToDeviceNoAnswer implements skyWallet.DeviceDriver
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	kind, size := uint16(header.Kind(chunks)), header.Size(chunks)
+	start := time.Now()
+
+	err := d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+
+	d.collector.Observe(kind, headerSize+size, 0, time.Since(start), err)
+	return err
+}
@@ -0,0 +1,211 @@
+// Package stats collects statistics of the messages exchanged with the device, per protobuf message type:
+// counts, sizes and latencies, to find the chattiest flows and where they spend their time.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets, the latency of a message includes the
+// time taken by the user to confirm on the device
+var LatencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// LatencyWindow is the number of the most recent latencies of a message type the percentiles are computed from, so
+// they follow the regressions of a firmware or USB stack update rather than the averages since the daemon started
+const LatencyWindow = 1000
+
+// Quantiles are the latency percentiles reported per message type
+var Quantiles = []float64{0.5, 0.9, 0.99}
+
+// MessageStats are the statistics of a message type
+type MessageStats struct {
+	// Type is the message type, without the MessageType_ prefix
+	Type string `json:"type"`
+	// Count is the number of messages sent to the device
+	Count uint64 `json:"count"`
+	// Errors is the number of messages which failed, without response from the device
+	Errors uint64 `json:"errors"`
+	// BytesSent is the size of the messages sent, header included
+	BytesSent uint64 `json:"bytes_sent"`
+	// BytesReceived is the size of the responses
+	BytesReceived uint64 `json:"bytes_received"`
+	// Latency is the histogram of the time taken by the device to answer
+	Latency Histogram `json:"latency"`
+	// Percentiles are the Quantiles of the time taken by the device to answer the LatencyWindow most recent
+	// messages, empty if none was answered
+	Percentiles []Percentile `json:"percentiles,omitempty"`
+}
+
+// Percentile is a latency percentile
+type Percentile struct {
+	Quantile float64 `json:"quantile"`
+	Ms       float64 `json:"ms"`
+}
+
+// Histogram is a latency histogram
+type Histogram struct {
+	// Buckets are cumulative, each counts the messages with a latency lower than or equal to its bound
+	Buckets []Bucket `json:"buckets"`
+	SumMs   float64  `json:"sum_ms"`
+}
+
+// Bucket is a histogram bucket
+type Bucket struct {
+	LeMs  float64 `json:"le_ms"`
+	Count uint64  `json:"count"`
+}
+
+// messageCounters are the counters of a message type
+type messageCounters struct {
+	count         uint64
+	errors        uint64
+	bytesSent     uint64
+	bytesReceived uint64
+	// buckets counts the latencies per bucket of LatencyBuckets, the last one counts those above all bounds
+	buckets []uint64
+	sum     time.Duration
+	// recent holds the LatencyWindow most recent latencies, next is where the next one is written once it is full
+	recent []time.Duration
+	next   int
+}
+
+// Collector collects the message statistics
+type Collector struct {
+	mu       sync.Mutex
+	messages map[string]*messageCounters
+}
+
+// NewCollector creates a Collector
+func NewCollector() *Collector {
+	return &Collector{
+		messages: make(map[string]*messageCounters),
+	}
+}
+
+// Observe records a message of kind and its outcome.
+// received is the size of the response, err is the error of a message which failed.
+func (c *Collector) Observe(kind uint16, sent, received int, latency time.Duration, err error) {
+	name := MessageTypeName(kind)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.messages[name]
+	if !ok {
+		m = &messageCounters{
+			buckets: make([]uint64, len(LatencyBuckets)+1),
+		}
+		c.messages[name] = m
+	}
+
+	m.count++
+	m.bytesSent += uint64(sent)
+	if err != nil {
+		m.errors++
+		return
+	}
+
+	m.bytesReceived += uint64(received)
+	m.sum += latency
+	m.buckets[sort.Search(len(LatencyBuckets), func(i int) bool {
+		return latency <= LatencyBuckets[i]
+	})]++
+
+	if len(m.recent) < LatencyWindow {
+		m.recent = append(m.recent, latency)
+		return
+	}
+	m.recent[m.next] = latency
+	m.next = (m.next + 1) % LatencyWindow
+}
+
+// Snapshot returns the statistics of each message type, sorted by type
+func (c *Collector) Snapshot() []MessageStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]MessageStats, 0, len(c.messages))
+	for name, m := range c.messages {
+		h := Histogram{
+			Buckets: make([]Bucket, len(LatencyBuckets)),
+			SumMs:   durationMs(m.sum),
+		}
+
+		var cumulative uint64
+		for i, bound := range LatencyBuckets {
+			cumulative += m.buckets[i]
+			h.Buckets[i] = Bucket{
+				LeMs:  durationMs(bound),
+				Count: cumulative,
+			}
+		}
+
+		stats = append(stats, MessageStats{
+			Type:          name,
+			Count:         m.count,
+			Errors:        m.errors,
+			BytesSent:     m.bytesSent,
+			BytesReceived: m.bytesReceived,
+			Latency:       h,
+			Percentiles:   percentiles(m.recent),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Type < stats[j].Type
+	})
+
+	return stats
+}
+
+// percentiles returns the Quantiles of latencies, with the nearest rank method
+func percentiles(latencies []time.Duration) []Percentile {
+	if len(latencies) == 0 {
+		return nil
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	p := make([]Percentile, len(Quantiles))
+	for i, q := range Quantiles {
+		rank := int(math.Ceil(q * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		p[i] = Percentile{
+			Quantile: q,
+			Ms:       durationMs(sorted[rank-1]),
+		}
+	}
+	return p
+}
+
+// MessageTypeName names the message type kind, without the MessageType_ prefix
+func MessageTypeName(kind uint16) string {
+	return strings.TrimPrefix(messages.MessageType(kind).String(), "MessageType_")
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+type fakeDriver struct {
+	response wire.Message
+	err      error
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return d.response, d.err
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return d.err
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return nil, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeEmulator
+}
+
+func (d *fakeDriver) Close() {}
+
+func messageChunks(kind messages.MessageType, size uint32) [][64]byte {
+	var chunk [64]byte
+	copy(chunk[:], "?##")
+	chunk[3] = byte(kind >> 8)
+	chunk[4] = byte(kind)
+	chunk[8] = byte(size)
+	return [][64]byte{chunk}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	require.Empty(t, c.Snapshot())
+
+	ping := uint16(messages.MessageType_MessageType_Ping)
+	c.Observe(ping, 12, 14, 5*time.Millisecond, nil)
+	c.Observe(ping, 12, 14, 300*time.Millisecond, nil)
+	c.Observe(ping, 12, 0, time.Second, errors.New("device disconnected"))
+	c.Observe(uint16(messages.MessageType_MessageType_Initialize), 9, 0, time.Minute, nil)
+
+	snapshot := c.Snapshot()
+	require.Len(t, snapshot, 2)
+
+	// sorted by type
+	initialize := snapshot[0]
+	require.Equal(t, "Initialize", initialize.Type)
+	require.Equal(t, uint64(1), initialize.Count)
+	// slower than all the buckets
+	for _, b := range initialize.Latency.Buckets {
+		require.Zero(t, b.Count)
+	}
+	require.Equal(t, float64(60000), initialize.Latency.SumMs)
+
+	p := snapshot[1]
+	require.Equal(t, "Ping", p.Type)
+	require.Equal(t, uint64(3), p.Count)
+	require.Equal(t, uint64(1), p.Errors)
+	require.Equal(t, uint64(36), p.BytesSent)
+	require.Equal(t, uint64(28), p.BytesReceived)
+	require.Equal(t, float64(305), p.Latency.SumMs)
+	require.Len(t, p.Latency.Buckets, len(LatencyBuckets))
+
+	// the buckets are cumulative
+	counts := make(map[float64]uint64)
+	for _, b := range p.Latency.Buckets {
+		counts[b.LeMs] = b.Count
+	}
+	require.Equal(t, uint64(1), counts[10])
+	require.Equal(t, uint64(1), counts[250])
+	require.Equal(t, uint64(2), counts[500])
+	require.Equal(t, uint64(2), counts[30000])
+}
+
+func TestDriver(t *testing.T) {
+	c := NewCollector()
+	device := &fakeDriver{
+		response: wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_Success),
+			Data: make([]byte, 20),
+		},
+	}
+	driver := NewDriver(device, c)
+
+	msg, err := driver.SendToDevice(nil, messageChunks(messages.MessageType_MessageType_Ping, 7))
+	require.NoError(t, err)
+	require.Equal(t, device.response, msg)
+
+	device.err = skyWallet.ErrNoDeviceConnected
+	err = driver.SendToDeviceNoAnswer(nil, messageChunks(messages.MessageType_MessageType_Cancel, 0))
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	snapshot := c.Snapshot()
+	require.Len(t, snapshot, 2)
+
+	require.Equal(t, "Cancel", snapshot[0].Type)
+	require.Equal(t, uint64(1), snapshot[0].Errors)
+	require.Equal(t, uint64(headerSize), snapshot[0].BytesSent)
+
+	require.Equal(t, "Ping", snapshot[1].Type)
+	require.Equal(t, uint64(1), snapshot[1].Count)
+	require.Zero(t, snapshot[1].Errors)
+	require.Equal(t, uint64(headerSize+7), snapshot[1].BytesSent)
+	require.Equal(t, uint64(headerSize+20), snapshot[1].BytesReceived)
+}
+
+func TestPercentiles(t *testing.T) {
+	c := NewCollector()
+	getFeatures := uint16(messages.MessageType_MessageType_GetFeatures)
+	for i := 1; i <= 100; i++ {
+		c.Observe(getFeatures, 9, 120, time.Duration(i)*time.Millisecond, nil)
+	}
+	// the failed messages are not in the percentiles
+	c.Observe(getFeatures, 9, 0, time.Hour, errors.New("timeout"))
+
+	snapshot := c.Snapshot()
+	require.Len(t, snapshot, 1)
+	require.Equal(t, []Percentile{
+		{Quantile: 0.5, Ms: 50},
+		{Quantile: 0.9, Ms: 90},
+		{Quantile: 0.99, Ms: 99},
+	}, snapshot[0].Percentiles)
+
+	// the percentiles are computed from the most recent latencies
+	for i := 0; i < LatencyWindow; i++ {
+		c.Observe(getFeatures, 9, 120, 2*time.Second, nil)
+	}
+	for _, p := range c.Snapshot()[0].Percentiles {
+		require.Equal(t, float64(2000), p.Ms)
+	}
+
+	// no percentiles without answers
+	c.Observe(uint16(messages.MessageType_MessageType_Cancel), 9, 0, 0, errors.New("timeout"))
+	require.Empty(t, c.Snapshot()[0].Percentiles)
+}
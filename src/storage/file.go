@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const fileExt = ".json"
+
+// FileStore is a Store that persists every bucket as a JSON file in a directory.
+// The whole bucket is kept in memory and rewritten atomically on each change,
+// which suits the small amount of state kept by the daemon.
+type FileStore struct {
+	*MemoryStore
+	dir string
+}
+
+// NewFileStore opens a FileStore in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	fs := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		dir:         dir,
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || filepath.Ext(name) != fileExt {
+			continue
+		}
+
+		bucketName := strings.TrimSuffix(name, fileExt)
+		if validateBucket(bucketName) != nil {
+			continue
+		}
+
+		b, err := readBucketFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		fs.buckets[bucketName] = b
+	}
+
+	fs.onChange = fs.writeBucket
+
+	return fs, nil
+}
+
+func readBucketFile(path string) (*bucket, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBucket()
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("invalid bucket file %s: %v", path, err)
+	}
+
+	if b.Records == nil {
+		b.Records = make(map[string][]byte)
+	}
+
+	return b, nil
+}
+
+// writeBucket writes the bucket to a temporary file and renames it over the previous one
+func (fs *FileStore) writeBucket(name string, b *bucket) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(fs.dir, name+fileExt)
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// bucket is the in-memory representation of a bucket
+type bucket struct {
+	Seq     uint64            `json:"seq"`
+	Records map[string][]byte `json:"records"`
+}
+
+func newBucket() *bucket {
+	return &bucket{
+		Records: make(map[string][]byte),
+	}
+}
+
+func (b *bucket) clone() *bucket {
+	c := &bucket{
+		Seq:     b.Seq,
+		Records: make(map[string][]byte, len(b.Records)),
+	}
+	for k, v := range b.Records {
+		c.Records[k] = v
+	}
+	return c
+}
+
+// snapshot returns the sorted keys and copied values of the bucket
+func (b *bucket) snapshot() ([]string, map[string][]byte) {
+	keys := make([]string, 0, len(b.Records))
+	values := make(map[string][]byte, len(b.Records))
+	for k, v := range b.Records {
+		keys = append(keys, k)
+		values[k] = copyBytes(v)
+	}
+	sort.Strings(keys)
+	return keys, values
+}
+
+// MemoryStore is a Store that keeps all data in memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]*bucket
+	closed  bool
+	// onChange is called with the lock held with the modified copy of a bucket before it replaces the original
+	onChange func(name string, b *bucket) error
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(bucketName, key string) ([]byte, error) {
+	if err := validateBucket(bucketName); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	b, ok := m.buckets[bucketName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	v, ok := b.Records[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return copyBytes(v), nil
+}
+
+// Put implements Store
+func (m *MemoryStore) Put(bucketName, key string, value []byte) error {
+	return m.update(bucketName, func(b *bucket) error {
+		b.Records[key] = copyBytes(value)
+		return nil
+	})
+}
+
+// Delete implements Store
+func (m *MemoryStore) Delete(bucketName, key string) error {
+	return m.update(bucketName, func(b *bucket) error {
+		delete(b.Records, key)
+		return nil
+	})
+}
+
+// Append implements Store
+func (m *MemoryStore) Append(bucketName string, value []byte) (uint64, error) {
+	var seq uint64
+	err := m.update(bucketName, func(b *bucket) error {
+		b.Seq++
+		seq = b.Seq
+		b.Records[SequenceKey(seq)] = copyBytes(value)
+		return nil
+	})
+	return seq, err
+}
+
+// ForEach implements Store
+func (m *MemoryStore) ForEach(bucketName string, fn func(key string, value []byte) error) error {
+	if err := validateBucket(bucketName); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+
+	b, ok := m.buckets[bucketName]
+	if !ok {
+		m.mu.RUnlock()
+		return nil
+	}
+
+	keys, values := b.snapshot()
+	m.mu.RUnlock()
+
+	for _, k := range keys {
+		if err := fn(k, values[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close implements Store
+func (m *MemoryStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *MemoryStore) update(bucketName string, fn func(b *bucket) error) error {
+	if err := validateBucket(bucketName); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	b, ok := m.buckets[bucketName]
+	if !ok {
+		b = newBucket()
+	}
+
+	// modify a copy when changes must be persisted, so a failed write leaves the bucket untouched
+	if m.onChange != nil {
+		b = b.clone()
+	}
+
+	if err := fn(b); err != nil {
+		return err
+	}
+
+	if m.onChange != nil {
+		if err := m.onChange(bucketName, b); err != nil {
+			return err
+		}
+	}
+
+	m.buckets[bucketName] = b
+	return nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
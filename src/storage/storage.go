@@ -0,0 +1,101 @@
+// Package storage provides the persistence backends used for daemon state
+// such as operation history, audit records and device inventory.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Backend is a storage backend type
+type Backend string
+
+const (
+	// BackendFile stores each bucket as a JSON file in a directory
+	BackendFile Backend = "file"
+	// BackendMemory keeps everything in memory, nothing survives a restart
+	BackendMemory Backend = "memory"
+)
+
+const (
+	// HistoryBucket holds device operation history records
+	HistoryBucket = "history"
+	// AuditBucket holds security relevant audit records
+	AuditBucket = "audit"
+	// InventoryBucket holds per device inventory data
+	InventoryBucket = "inventory"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist in a bucket
+	ErrNotFound = errors.New("key not found")
+	// ErrInvalidBucket is returned when a bucket name is not valid
+	ErrInvalidBucket = errors.New("bucket name must match [a-z0-9_-]+")
+	// ErrClosed is returned when the store is used after Close
+	ErrClosed = errors.New("store is closed")
+
+	bucketRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+)
+
+// Store is a bucketed key-value store.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key in bucket.
+	// ErrNotFound is returned if the key does not exist.
+	Get(bucket, key string) ([]byte, error)
+	// Put stores value under key in bucket, replacing any previous value
+	Put(bucket, key string, value []byte) error
+	// Delete removes key from bucket. Deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// Append stores value under the next sequence number of bucket and returns it.
+	// Sequence numbers start at 1 and are never reused, even after their records are deleted.
+	// The record key is SequenceKey(seq), so appended records iterate in insertion order.
+	Append(bucket string, value []byte) (uint64, error)
+	// ForEach calls fn for every key in bucket in ascending key order.
+	// fn sees a snapshot of the bucket and may modify the store.
+	// Iteration stops at the first error returned by fn, which is returned by ForEach.
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// ParseBackend parses a backend name
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(s); b {
+	case BackendFile, BackendMemory:
+		return b, nil
+	default:
+		return "", fmt.Errorf("invalid storage backend %q, choices are: %s, %s", s, BackendFile, BackendMemory)
+	}
+}
+
+// Open opens a store of the given backend type rooted in dir
+func Open(backend Backend, dir string) (Store, error) {
+	switch backend {
+	case BackendFile:
+		return NewFileStore(dir)
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("invalid storage backend %q", backend)
+	}
+}
+
+// SequenceKey returns the key under which Append stores the record with sequence number seq
+func SequenceKey(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// ParseSequenceKey parses a key created by SequenceKey
+func ParseSequenceKey(key string) (uint64, error) {
+	return strconv.ParseUint(key, 10, 64)
+}
+
+func validateBucket(bucket string) error {
+	if !bucketRegex.MatchString(bucket) {
+		return ErrInvalidBucket
+	}
+	return nil
+}
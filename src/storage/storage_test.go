@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, s Store) {
+	_, err := s.Get("foo", "missing")
+	require.Equal(t, ErrNotFound, err)
+
+	require.Equal(t, ErrInvalidBucket, s.Put("Foo Bar", "k", nil))
+
+	require.NoError(t, s.Put("foo", "b", []byte("2")))
+	require.NoError(t, s.Put("foo", "a", []byte("1")))
+	require.NoError(t, s.Put("bar", "a", []byte("other")))
+
+	v, err := s.Get("foo", "a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), v)
+
+	require.NoError(t, s.Put("foo", "a", []byte("3")))
+	v, err = s.Get("foo", "a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("3"), v)
+
+	var keys []string
+	err = s.ForEach("foo", func(k string, v []byte) error {
+		keys = append(keys, k)
+		// the store can be modified while iterating
+		return s.Put("foo", "c", []byte("4"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, keys)
+
+	errStop := errors.New("stop")
+	n := 0
+	err = s.ForEach("foo", func(k string, v []byte) error {
+		n++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, n)
+
+	require.NoError(t, s.Delete("foo", "a"))
+	require.NoError(t, s.Delete("foo", "a"))
+	_, err = s.Get("foo", "a")
+	require.Equal(t, ErrNotFound, err)
+
+	seq, err := s.Append("log", []byte("x"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), seq)
+
+	seq, err = s.Append("log", []byte("y"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), seq)
+
+	// sequence numbers are not reused after deletion
+	require.NoError(t, s.Delete("log", SequenceKey(2)))
+	seq, err = s.Append("log", []byte("z"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), seq)
+
+	var seqs []uint64
+	err = s.ForEach("log", func(k string, v []byte) error {
+		seq, err := ParseSequenceKey(k)
+		require.NoError(t, err)
+		seqs = append(seqs, seq)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 3}, seqs)
+
+	// iterating a missing bucket is a no-op
+	require.NoError(t, s.ForEach("empty", func(k string, v []byte) error {
+		t.Fatal("unexpected key")
+		return nil
+	}))
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	testStore(t, s)
+
+	require.NoError(t, s.Close())
+	_, err := s.Get("foo", "b")
+	require.Equal(t, ErrClosed, err)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir)
+	require.NoError(t, err)
+	testStore(t, s)
+	require.NoError(t, s.Close())
+
+	// data survives reopening
+	s, err = NewFileStore(dir)
+	require.NoError(t, err)
+	defer s.Close()
+
+	v, err := s.Get("foo", "b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), v)
+
+	seq, err := s.Append("log", nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), seq)
+
+	_, err = os.Stat(filepath.Join(dir, "foo.json"))
+	require.NoError(t, err)
+}
+
+func TestParseBackend(t *testing.T) {
+	for _, b := range []Backend{BackendFile, BackendMemory} {
+		p, err := ParseBackend(string(b))
+		require.NoError(t, err)
+		require.Equal(t, b, p)
+	}
+
+	_, err := ParseBackend("sqlite")
+	require.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"strings"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+// Driver is a device driver tracing the messages sent to the device, in the operation in progress of the tracer
+type Driver struct {
+	skyWallet.DeviceDriver
+	tracer *Tracer
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps driver, tracing its messages with tracer
+func NewDriver(driver skyWallet.DeviceDriver, tracer *Tracer) *Driver {
+	return &Driver{
+		DeviceDriver: driver,
+		tracer:       tracer,
+	}
+}
+
+// SendToDevice traces the message and the response of the device, which include the user confirming on the device
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	span := d.startMessage(chunks)
+	defer span.End()
+
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+	if err != nil {
+		span.RecordError(err)
+		return msg, err
+	}
+
+	span.SetAttributes(String("device.response.type", messages.MessageType(msg.Kind).String()))
+	return msg, nil
+}
+
+// SendToDeviceNoAnswer traces the message
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	span := d.startMessage(chunks)
+	defer span.End()
+
+	err := d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+func (d *Driver) startMessage(chunks [][64]byte) Span {
+	kind := header.Kind(chunks).String()
+	_, span := d.tracer.Start(d.tracer.currentOperation(), "device "+strings.TrimPrefix(kind, "MessageType_"),
+		String("device.message.type", kind),
+		Int("device.message.chunks", len(chunks)),
+		String("device.type", d.DeviceDriver.DeviceType().String()))
+	return span
+}
@@ -0,0 +1,455 @@
+// Package otlp registers the OTLP provider of the tracing package, exporting the spans to the collector with
+// OTLP/HTTP in its JSON encoding. The spans are sent in batches, every ExportInterval or once MaxBatchSize spans ended.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/tracing"
+)
+
+const (
+	// TracesPath is the path of the traces endpoint of the collector
+	TracesPath = "/v1/traces"
+	// ExportInterval is how often the ended spans are exported
+	ExportInterval = 5 * time.Second
+	// MaxBatchSize is the most spans exported in a request
+	MaxBatchSize = 512
+	// MaxQueueSize is the most ended spans waiting to be exported, the spans ending when the queue is full are dropped
+	MaxQueueSize = 2048
+
+	// instrumentationName names the scope of the daemon spans
+	instrumentationName = "github.com/skycoin/hardware-wallet-daemon"
+	// exportTimeout is how long the collector has to accept a batch
+	exportTimeout = 10 * time.Second
+
+	// spanKindInternal and statusCodeError are the OTLP span kind and status code of the spans
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+var logger = logging.MustGetLogger("otlp")
+
+func init() {
+	tracing.RegisterProvider(New)
+}
+
+// spanKey is the context key of the span started by Start
+type spanKey struct{}
+
+// Provider exports the spans to the collector
+type Provider struct {
+	url     string
+	service string
+	client  *http.Client
+
+	mu      sync.Mutex
+	queue   []*span
+	dropped int
+	closed  bool
+
+	flush chan struct{}
+	quit  chan struct{}
+	done  chan struct{}
+}
+
+// New creates a Provider exporting the spans of service to endpoint, a host:port or an http(s) URL of the collector.
+// The spans are sent to TracesPath of the collector, unless the URL has a path.
+func New(endpoint, service string) (tracing.Provider, error) {
+	u, err := tracesURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		url:     u,
+		service: service,
+		client: &http.Client{
+			Timeout: exportTimeout,
+		},
+		flush: make(chan struct{}, 1),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+
+	return p, nil
+}
+
+// tracesURL returns the URL of the traces endpoint of the collector at endpoint
+func tracesURL(endpoint string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid tracing endpoint: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid tracing endpoint %q, must be host:port or an http(s) URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = TracesPath
+	}
+
+	return u.String(), nil
+}
+
+// Start implements tracing.Provider
+func (p *Provider) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	s := &span{
+		provider: p,
+		name:     name,
+		start:    time.Now(),
+		spanID:   randomHex(8),
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	s.SetAttributes(attrs...)
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Shutdown implements tracing.Provider, it exports the spans ended before it was called
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.quit)
+	<-p.done
+
+	for {
+		batch := p.next()
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := p.export(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+// enqueue queues an ended span to be exported
+func (p *Provider) enqueue(s *span) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	if len(p.queue) >= MaxQueueSize {
+		p.dropped++
+		return
+	}
+
+	p.queue = append(p.queue, s)
+	if len(p.queue) >= MaxBatchSize {
+		select {
+		case p.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// next removes the next batch of spans from the queue
+func (p *Provider) next() []*span {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dropped > 0 {
+		logger.Warningf("Dropped %d spans, the export did not keep up", p.dropped)
+		p.dropped = 0
+	}
+
+	n := len(p.queue)
+	if n > MaxBatchSize {
+		n = MaxBatchSize
+	}
+	batch := p.queue[:n:n]
+	p.queue = p.queue[n:]
+	return batch
+}
+
+// run exports the queued spans every ExportInterval or once a batch is full, until Shutdown is called
+func (p *Provider) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(ExportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+		case <-p.flush:
+		}
+
+		for {
+			batch := p.next()
+			if len(batch) == 0 {
+				break
+			}
+			if err := p.export(context.Background(), batch); err != nil {
+				logger.WithError(err).Errorf("Failed to export %d spans", len(batch))
+				break
+			}
+		}
+	}
+}
+
+// export sends a batch of spans to the collector
+func (p *Provider) export(ctx context.Context, batch []*span) error {
+	body, err := json.Marshal(p.request(batch))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(ioutil.Discard, rsp.Body) // nolint: errcheck
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("the collector answered %s", rsp.Status)
+	}
+	return nil
+}
+
+// request returns the ExportTraceServiceRequest of a batch of spans
+func (p *Provider) request(batch []*span) exportRequest {
+	spans := make([]jsonSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = s.json()
+	}
+
+	return exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: []keyValue{
+					newKeyValue(tracing.String("service.name", p.service)),
+				},
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{
+					Name: instrumentationName,
+				},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+// span is a span of the Provider
+type span struct {
+	provider *Provider
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []tracing.Attribute
+	err   error
+	errAt time.Time
+	ended bool
+}
+
+func (s *span) SetAttributes(attrs ...tracing.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	s.errAt = time.Now()
+}
+
+func (s *span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.provider.enqueue(s)
+}
+
+// json returns the OTLP JSON encoding of the ended span
+func (s *span) json() jsonSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	js := jsonSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(s.end),
+	}
+
+	for _, a := range s.attrs {
+		js.Attributes = append(js.Attributes, newKeyValue(a))
+	}
+
+	if s.err != nil {
+		js.Status = &status{
+			Code:    statusCodeError,
+			Message: s.err.Error(),
+		}
+		js.Events = []event{{
+			TimeUnixNano: unixNano(s.errAt),
+			Name:         "exception",
+			Attributes: []keyValue{
+				newKeyValue(tracing.String("exception.message", s.err.Error())),
+			},
+		}}
+	}
+
+	return js
+}
+
+// The types of the OTLP JSON encoding of an ExportTraceServiceRequest, the 64 bit integers are encoded as strings
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []jsonSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type jsonSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Events            []event    `json:"events,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type event struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// newKeyValue returns the OTLP attribute of a, the values of an unsupported type are encoded with fmt
+func newKeyValue(a tracing.Attribute) keyValue {
+	var v anyValue
+	switch value := a.Value.(type) {
+	case string:
+		v.StringValue = &value
+	case int:
+		s := strconv.Itoa(value)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case bool:
+		v.BoolValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+
+	return keyValue{
+		Key:   a.Key,
+		Value: v,
+	}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomHex returns n random bytes encoded in hex, the IDs of the traces and the spans
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		logger.Panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,134 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/tracing"
+)
+
+func TestTracesURL(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		url      string
+		err      string
+	}{
+		{
+			endpoint: "localhost:4318",
+			url:      "http://localhost:4318/v1/traces",
+		},
+		{
+			endpoint: "https://collector.example.com/",
+			url:      "https://collector.example.com/v1/traces",
+		},
+		{
+			endpoint: "http://collector.example.com:4318/otlp/v1/traces",
+			url:      "http://collector.example.com:4318/otlp/v1/traces",
+		},
+		{
+			endpoint: "grpc://collector.example.com:4317",
+			err:      `invalid tracing endpoint "grpc://collector.example.com:4317", must be host:port or an http(s) URL`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			u, err := tracesURL(tc.endpoint)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.url, u)
+		})
+	}
+}
+
+func TestProvider(t *testing.T) {
+	var mu sync.Mutex
+	var requests []exportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, TracesPath, r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req exportRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	p, err := New(strings.TrimPrefix(collector.URL, "http://"), tracing.ServiceName)
+	require.NoError(t, err)
+
+	ctx, parent := p.Start(context.Background(), "POST /api/v2/sign_message", tracing.String("http.method", "POST"))
+	_, child := p.Start(ctx, "SkycoinSignMessage", tracing.Int("device.message", 8))
+	child.RecordError(errors.New("device disconnected"))
+	child.End()
+	parent.SetAttributes(tracing.Attribute{Key: "http.status_code", Value: int64(500)})
+	parent.End()
+	parent.End()
+
+	require.NoError(t, p.Shutdown(context.Background()))
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	// the spans ended after the shutdown are not exported
+	_, late := p.Start(context.Background(), "GET /api/v2/features")
+	late.End()
+
+	require.Len(t, requests, 1)
+	rs := requests[0].ResourceSpans
+	require.Len(t, rs, 1)
+	require.Equal(t, "service.name", rs[0].Resource.Attributes[0].Key)
+	require.Equal(t, tracing.ServiceName, *rs[0].Resource.Attributes[0].Value.StringValue)
+	require.Equal(t, instrumentationName, rs[0].ScopeSpans[0].Scope.Name)
+
+	spans := rs[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	c, s := spans[0], spans[1]
+
+	require.Equal(t, "SkycoinSignMessage", c.Name)
+	require.Equal(t, "POST /api/v2/sign_message", s.Name)
+	require.Len(t, s.TraceID, 32)
+	require.Len(t, s.SpanID, 16)
+	require.Empty(t, s.ParentSpanID)
+	require.Equal(t, s.TraceID, c.TraceID)
+	require.Equal(t, s.SpanID, c.ParentSpanID)
+	require.NotEqual(t, s.SpanID, c.SpanID)
+	require.Nil(t, s.Status)
+
+	require.Equal(t, "http.method", s.Attributes[0].Key)
+	require.Equal(t, "POST", *s.Attributes[0].Value.StringValue)
+	require.Equal(t, "http.status_code", s.Attributes[1].Key)
+	require.Equal(t, "500", *s.Attributes[1].Value.IntValue)
+	require.Equal(t, "8", *c.Attributes[0].Value.IntValue)
+
+	require.Equal(t, &status{Code: statusCodeError, Message: "device disconnected"}, c.Status)
+	require.Len(t, c.Events, 1)
+	require.Equal(t, "exception", c.Events[0].Name)
+}
+
+func TestProviderCollectorError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	p, err := New(collector.URL, tracing.ServiceName)
+	require.NoError(t, err)
+
+	_, span := p.Start(context.Background(), "GET /api/v2/features")
+	span.End()
+
+	require.EqualError(t, p.Shutdown(context.Background()), "the collector answered 503 Service Unavailable")
+}
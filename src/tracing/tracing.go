@@ -0,0 +1,132 @@
+// Package tracing traces the API requests and the messages they exchange with the device, so the latency of an
+// operation can be split between the daemon, the transport and the user confirming on the device.
+// The spans are exported by a Provider; the OTLP provider is registered by importing the otlp package.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ServiceName names the daemon in the exported traces
+const ServiceName = "hardware-wallet-daemon"
+
+// Attribute is a key value pair attached to a span, Value is a string, an int, an int64 or a bool
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an int attribute
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation of a trace
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError records err and marks the span as failed
+	RecordError(err error)
+	End()
+}
+
+// Provider starts the spans and exports them
+type Provider interface {
+	// Start starts a span, child of the span of ctx if any, and returns a context holding it
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+	// Shutdown exports the pending spans
+	Shutdown(ctx context.Context) error
+}
+
+// NewProviderFunc creates a Provider exporting the spans of service to endpoint
+type NewProviderFunc func(endpoint, service string) (Provider, error)
+
+var (
+	providersLock sync.Mutex
+	newProvider   NewProviderFunc
+)
+
+// RegisterProvider registers the Provider used by New, it is called by the init function of the provider package
+func RegisterProvider(f NewProviderFunc) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	newProvider = f
+}
+
+// Tracer traces the API requests and the device messages
+type Tracer struct {
+	provider Provider
+
+	mu sync.Mutex
+	// operation is the context of the operation in progress, parent of the device message spans
+	operation context.Context
+}
+
+// New creates a Tracer exporting to endpoint with the registered Provider
+func New(endpoint string) (*Tracer, error) {
+	providersLock.Lock()
+	f := newProvider
+	providersLock.Unlock()
+
+	if f == nil {
+		return nil, errors.New("tracing is unavailable: no exporter is registered, import the tracing/otlp package")
+	}
+
+	p, err := f(endpoint, ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the tracing exporter: %v", err)
+	}
+
+	return NewTracer(p), nil
+}
+
+// NewTracer creates a Tracer of provider
+func NewTracer(provider Provider) *Tracer {
+	return &Tracer{
+		provider: provider,
+	}
+}
+
+// Start starts a span, child of the span of ctx if any
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return t.provider.Start(ctx, name, attrs...)
+}
+
+// Operation makes the span of ctx the parent of the spans of the messages sent to the device, until end is called.
+// It is called by the device operations, the device serves one operation at a time.
+func (t *Tracer) Operation(ctx context.Context) (end func()) {
+	t.mu.Lock()
+	t.operation = ctx
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// a later operation may have replaced this one
+		if t.operation == ctx {
+			t.operation = nil
+		}
+	}
+}
+
+// currentOperation returns the context of the operation in progress
+func (t *Tracer) currentOperation() context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.operation == nil {
+		return context.Background()
+	}
+	return t.operation
+}
+
+// Shutdown exports the pending spans
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
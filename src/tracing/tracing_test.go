@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+type spanKey struct{}
+
+type fakeSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *fakeSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *fakeSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *fakeSpan) End() {
+	s.ended = true
+}
+
+type fakeProvider struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+func (p *fakeProvider) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &fakeSpan{
+		name:  name,
+		attrs: make(map[string]interface{}),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*fakeSpan); ok {
+		span.parent = parent.name
+	}
+	span.SetAttributes(attrs...)
+
+	p.mu.Lock()
+	p.spans = append(p.spans, span)
+	p.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (p *fakeProvider) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+type fakeDriver struct {
+	response wire.Message
+	err      error
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return d.response, d.err
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return d.err
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return nil, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeEmulator
+}
+
+func (d *fakeDriver) Close() {}
+
+func messageChunks(kind messages.MessageType) [][64]byte {
+	var chunk [64]byte
+	copy(chunk[:], "?##")
+	chunk[3] = byte(kind >> 8)
+	chunk[4] = byte(kind)
+	return [][64]byte{chunk}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("localhost:4318")
+	require.EqualError(t, err, "tracing is unavailable: no exporter is registered, import the tracing/otlp package")
+
+	defer RegisterProvider(nil)
+
+	RegisterProvider(func(endpoint, service string) (Provider, error) {
+		return nil, errors.New("invalid endpoint")
+	})
+	_, err = New("localhost:4318")
+	require.EqualError(t, err, "failed to create the tracing exporter: invalid endpoint")
+
+	RegisterProvider(func(endpoint, service string) (Provider, error) {
+		require.Equal(t, "localhost:4318", endpoint)
+		require.Equal(t, ServiceName, service)
+		return &fakeProvider{}, nil
+	})
+	tracer, err := New("localhost:4318")
+	require.NoError(t, err)
+	require.NotNil(t, tracer)
+}
+
+func TestDriver(t *testing.T) {
+	provider := &fakeProvider{}
+	tracer := NewTracer(provider)
+	device := &fakeDriver{
+		response: wire.Message{Kind: uint16(messages.MessageType_MessageType_ButtonRequest)},
+	}
+	driver := NewDriver(device, tracer)
+
+	// the messages sent outside of an operation have no parent
+	_, err := driver.SendToDevice(nil, messageChunks(messages.MessageType_MessageType_Initialize))
+	require.NoError(t, err)
+
+	ctx, span := tracer.Start(context.Background(), "POST /api/v1/sign_message")
+	end := tracer.Operation(ctx)
+
+	_, err = driver.SendToDevice(nil, messageChunks(messages.MessageType_MessageType_SkycoinSignMessage))
+	require.NoError(t, err)
+
+	device.err = skyWallet.ErrNoDeviceConnected
+	err = driver.SendToDeviceNoAnswer(nil, messageChunks(messages.MessageType_MessageType_Cancel))
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	end()
+	span.End()
+
+	_, err = driver.SendToDevice(nil, messageChunks(messages.MessageType_MessageType_GetFeatures))
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, err)
+
+	require.Len(t, provider.spans, 5)
+
+	initialize := provider.spans[0]
+	require.Equal(t, "device Initialize", initialize.name)
+	require.Empty(t, initialize.parent)
+	require.True(t, initialize.ended)
+
+	sign := provider.spans[2]
+	require.Equal(t, "device SkycoinSignMessage", sign.name)
+	require.Equal(t, "POST /api/v1/sign_message", sign.parent)
+	require.Equal(t, map[string]interface{}{
+		"device.message.type":   "MessageType_SkycoinSignMessage",
+		"device.message.chunks": 1,
+		"device.type":           "EMULATOR",
+		"device.response.type":  "MessageType_ButtonRequest",
+	}, sign.attrs)
+	require.NoError(t, sign.err)
+	require.True(t, sign.ended)
+
+	cancel := provider.spans[3]
+	require.Equal(t, "device Cancel", cancel.name)
+	require.Equal(t, "POST /api/v1/sign_message", cancel.parent)
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, cancel.err)
+	require.True(t, cancel.ended)
+
+	// the operation ended
+	features := provider.spans[4]
+	require.Empty(t, features.parent)
+	require.Equal(t, skyWallet.ErrNoDeviceConnected, features.err)
+	require.NotContains(t, features.attrs, "device.response.type")
+}
+
+func TestOperationReplaced(t *testing.T) {
+	tracer := NewTracer(&fakeProvider{})
+
+	first, _ := tracer.Start(context.Background(), "first")
+	endFirst := tracer.Operation(first)
+	second, _ := tracer.Start(context.Background(), "second")
+	endSecond := tracer.Operation(second)
+
+	// ending the replaced operation keeps the current one
+	endFirst()
+	require.Equal(t, second, tracer.currentOperation())
+
+	endSecond()
+	require.Equal(t, context.Background(), tracer.currentOperation())
+}
@@ -0,0 +1,367 @@
+// Package txpolicy evaluates the transactions before they are forwarded to the device for signing: a daily limit
+// of the coins spent, allowlists and denylists of the destination addresses, and a cool-down between the large
+// transactions. The spent coins are persisted, so a restart does not reset the limits.
+package txpolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/amount"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const (
+	// Bucket is the storage bucket holding the coins spent under the policy
+	Bucket = "txpolicy"
+
+	// stateKey is the key of the state in Bucket
+	stateKey = "state"
+)
+
+// Rules of the policy
+const (
+	RuleDailyLimit        = "daily_limit"
+	RuleAddressDenied     = "address_denied"
+	RuleAddressNotAllowed = "address_not_allowed"
+	RuleCooldown          = "cooldown"
+)
+
+// Violation is returned when a transaction breaks a rule of the policy
+type Violation struct {
+	// Rule is the rule broken
+	Rule   string
+	Reason string
+	// RetryAt is when the transaction is allowed again, zero if it is not
+	RetryAt time.Time
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("transaction rejected by the policy, %s", v.Reason)
+}
+
+// Output is a destination of a transaction, the change outputs to the addresses of the device are left out
+type Output struct {
+	Address string
+	// Coins are the droplets sent to the address
+	Coins uint64
+}
+
+// Policy is the transaction policy. The empty rules are not enforced.
+type Policy struct {
+	// Timezone is an IANA time zone such as "Europe/Berlin", the daily limit resets at its midnight.
+	// Defaults to the local time zone of the daemon.
+	Timezone string `json:"timezone,omitempty"`
+	// DailyLimit is the number of coins spent per day, such as "1000"
+	DailyLimit string `json:"daily_limit,omitempty"`
+	// Allowlist are the only addresses the transactions are sent to
+	Allowlist []string `json:"allowlist,omitempty"`
+	// Denylist are the addresses the transactions are never sent to
+	Denylist []string `json:"denylist,omitempty"`
+	// LargeTransaction is the number of coins from which a transaction is large, such as "100"
+	LargeTransaction string `json:"large_transaction,omitempty"`
+	// Cooldown is how long a large transaction waits after the previous one, such as "1h"
+	Cooldown string `json:"cooldown,omitempty"`
+
+	location         *time.Location
+	dailyLimit       uint64
+	allowlist        map[string]struct{}
+	denylist         map[string]struct{}
+	largeTransaction uint64
+	cooldown         time.Duration
+}
+
+// NewPolicy validates the rules of p and returns the policy
+func NewPolicy(p Policy) (*Policy, error) {
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadPolicy loads a transaction policy from a JSON file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid transaction policy %s: %v", path, err)
+	}
+
+	if err := p.init(); err != nil {
+		return nil, fmt.Errorf("invalid transaction policy %s: %v", path, err)
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) init() error {
+	p.location = time.Local
+	if p.Timezone != "" {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return err
+		}
+		p.location = loc
+	}
+
+	var err error
+	if p.DailyLimit != "" {
+		if p.dailyLimit, err = amount.ParseCoins(p.DailyLimit); err != nil {
+			return fmt.Errorf("invalid daily_limit: %v", err)
+		}
+	}
+
+	if p.allowlist, err = addressSet(p.Allowlist); err != nil {
+		return fmt.Errorf("invalid allowlist: %v", err)
+	}
+	if p.denylist, err = addressSet(p.Denylist); err != nil {
+		return fmt.Errorf("invalid denylist: %v", err)
+	}
+
+	if (p.LargeTransaction == "") != (p.Cooldown == "") {
+		return errors.New("large_transaction and cooldown must be set together")
+	}
+	if p.LargeTransaction != "" {
+		if p.largeTransaction, err = amount.ParseCoins(p.LargeTransaction); err != nil {
+			return fmt.Errorf("invalid large_transaction: %v", err)
+		}
+		if p.cooldown, err = time.ParseDuration(p.Cooldown); err != nil {
+			return fmt.Errorf("invalid cooldown: %v", err)
+		}
+		if p.cooldown <= 0 {
+			return errors.New("cooldown must be greater than 0")
+		}
+	}
+
+	if p.DailyLimit == "" && p.allowlist == nil && p.denylist == nil && p.LargeTransaction == "" {
+		return errors.New("transaction policy has no rules")
+	}
+
+	return nil
+}
+
+// addressSet returns the set of the addresses, nil if there are none
+func addressSet(addresses []string) (map[string]struct{}, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	set := make(map[string]struct{}, len(addresses))
+	for _, a := range addresses {
+		if _, err := cipher.DecodeBase58Address(a); err != nil {
+			return nil, fmt.Errorf("%q: %v", a, err)
+		}
+		set[a] = struct{}{}
+	}
+	return set, nil
+}
+
+// state is the record of the coins spent under the policy
+type state struct {
+	// Day is the day of Spent, such as "2019-07-26"
+	Day   string `json:"day"`
+	Spent uint64 `json:"spent"`
+	// LastLarge is when the last large transaction was forwarded to the device
+	LastLarge time.Time `json:"last_large,omitempty"`
+}
+
+// Hook is a rule evaluated after the rules of the policy, such as a rule of a plugin
+type Hook interface {
+	// CheckTransaction returns a *Violation if the transaction sending outputs breaks the rule
+	CheckTransaction(outputs []Output) error
+}
+
+// Engine evaluates the transactions with a policy, recording the coins they spend in a store
+type Engine struct {
+	policy *Policy
+	store  storage.Store
+
+	mu    sync.Mutex
+	hooks []Hook
+}
+
+// NewEngine creates an Engine, a nil policy only evaluates the hooks
+func NewEngine(policy *Policy, store storage.Store) *Engine {
+	if policy == nil {
+		policy = &Policy{
+			location: time.Local,
+		}
+	}
+
+	return &Engine{
+		policy: policy,
+		store:  store,
+	}
+}
+
+// AddHook adds a rule evaluated after the rules of the policy
+func (e *Engine) AddHook(h Hook) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.hooks = append(e.hooks, h)
+}
+
+// Check returns a *Violation if the transaction sending outputs at time now breaks a rule of the policy
+func (e *Engine) Check(outputs []Output, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	return e.check(s, outputs, now)
+}
+
+// Authorize checks the transaction sending outputs at time now like Check, and records its coins as spent when it
+// is allowed. The transaction is recorded once it is forwarded to the device, whether the device then signs it or not.
+func (e *Engine) Authorize(outputs []Output, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	if err := e.check(s, outputs, now); err != nil {
+		return err
+	}
+
+	coins := total(outputs)
+	day := e.day(now)
+	if s.Day != day {
+		s = state{
+			Day:       day,
+			LastLarge: s.LastLarge,
+		}
+	}
+	s.Spent += coins
+	if e.policy.largeTransaction > 0 && coins >= e.policy.largeTransaction {
+		s.LastLarge = now.UTC()
+	}
+
+	return e.save(s)
+}
+
+func (e *Engine) check(s state, outputs []Output, now time.Time) error {
+	p := e.policy
+
+	for _, o := range outputs {
+		if _, ok := p.denylist[o.Address]; ok {
+			return &Violation{
+				Rule:   RuleAddressDenied,
+				Reason: fmt.Sprintf("the address %s is denied", o.Address),
+			}
+		}
+		if _, ok := p.allowlist[o.Address]; p.allowlist != nil && !ok {
+			return &Violation{
+				Rule:   RuleAddressNotAllowed,
+				Reason: fmt.Sprintf("the address %s is not allowed", o.Address),
+			}
+		}
+	}
+
+	coins := total(outputs)
+
+	if p.DailyLimit != "" {
+		spent := s.Spent
+		if s.Day != e.day(now) {
+			spent = 0
+		}
+
+		if spent+coins < spent || spent+coins > p.dailyLimit {
+			v := &Violation{
+				Rule:   RuleDailyLimit,
+				Reason: fmt.Sprintf("the daily limit of %s coins is exceeded, %s coins were spent today", p.DailyLimit, coinsString(spent)),
+			}
+			// a transaction above the limit is never allowed
+			if coins <= p.dailyLimit {
+				t := now.In(p.location)
+				v.RetryAt = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, p.location)
+			}
+			return v
+		}
+	}
+
+	if p.largeTransaction > 0 && coins >= p.largeTransaction && !s.LastLarge.IsZero() {
+		if next := s.LastLarge.Add(p.cooldown); now.Before(next) {
+			return &Violation{
+				Rule:    RuleCooldown,
+				Reason:  fmt.Sprintf("a transaction of %s coins or more waits %s after the previous one", p.LargeTransaction, p.Cooldown),
+				RetryAt: next,
+			}
+		}
+	}
+
+	for _, h := range e.hooks {
+		if err := h.CheckTransaction(outputs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// day returns the day of t in the time zone of the policy
+func (e *Engine) day(t time.Time) string {
+	return t.In(e.policy.location).Format("2006-01-02")
+}
+
+func (e *Engine) load() (state, error) {
+	var s state
+	data, err := e.store.Get(Bucket, stateKey)
+	switch err {
+	case nil:
+	case storage.ErrNotFound:
+		return s, nil
+	default:
+		return s, err
+	}
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid transaction policy state: %v", err)
+	}
+	return s, nil
+}
+
+func (e *Engine) save(s state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return e.store.Put(Bucket, stateKey, data)
+}
+
+// total returns the coins sent by outputs, saturated on overflow
+func total(outputs []Output) uint64 {
+	var coins uint64
+	for _, o := range outputs {
+		coins += o.Coins
+		if coins < o.Coins {
+			return ^uint64(0)
+		}
+	}
+	return coins
+}
+
+// coinsString returns the coins of droplets, without trailing zeros such as "6" or "6.5"
+func coinsString(droplets uint64) string {
+	s, err := amount.FormatCoins(droplets)
+	if err != nil {
+		return fmt.Sprint(droplets)
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
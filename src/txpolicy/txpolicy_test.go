@@ -0,0 +1,212 @@
+package txpolicy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+const (
+	addressA = "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvG"
+	addressB = "2iNNt6fm9LszSWe51693BeyNUKX34pPaLx8"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txpolicy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{
+			name:   "valid",
+			policy: `{"timezone": "Europe/Berlin", "daily_limit": "1000.5", "denylist": ["` + addressA + `"], "large_transaction": "100", "cooldown": "1h"}`,
+		},
+		{
+			name:   "invalid json",
+			policy: `{"allowlist": "` + addressA + `"}`,
+			err:    "invalid transaction policy",
+		},
+		{
+			name:   "no rules",
+			policy: `{"timezone": "UTC"}`,
+			err:    "transaction policy has no rules",
+		},
+		{
+			name:   "invalid daily limit",
+			policy: `{"daily_limit": "1000 coins"}`,
+			err:    "invalid daily_limit",
+		},
+		{
+			name:   "invalid address",
+			policy: `{"allowlist": ["2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvH"]}`,
+			err:    `invalid allowlist: "2M9hQ4LqEsBF5JZ3uBatnkaMgg9pN965JvH"`,
+		},
+		{
+			name:   "cooldown without large transaction",
+			policy: `{"cooldown": "1h"}`,
+			err:    "large_transaction and cooldown must be set together",
+		},
+		{
+			name:   "invalid cooldown",
+			policy: `{"large_transaction": "100", "cooldown": "1 hour"}`,
+			err:    "invalid cooldown",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("policy%d.json", i))
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.policy), 0600))
+
+			p, err := LoadPolicy(path)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, uint64(1000500000), p.dailyLimit)
+			require.Equal(t, time.Hour, p.cooldown)
+		})
+	}
+}
+
+func newPolicy(t *testing.T, p Policy) *Policy {
+	policy, err := NewPolicy(p)
+	require.NoError(t, err)
+	return policy
+}
+
+func violation(t *testing.T, err error) *Violation {
+	require.Error(t, err)
+	v, ok := err.(*Violation)
+	require.True(t, ok, "%v is not a violation", err)
+	return v
+}
+
+func TestAddressLists(t *testing.T) {
+	e := NewEngine(newPolicy(t, Policy{
+		Allowlist: []string{addressA},
+		Denylist:  []string{addressB},
+	}), storage.NewMemoryStore())
+	now := time.Now()
+
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 1e6}}, now))
+
+	v := violation(t, e.Check([]Output{{Address: addressA, Coins: 1e6}, {Address: addressB, Coins: 1e6}}, now))
+	require.Equal(t, RuleAddressDenied, v.Rule)
+	require.True(t, v.RetryAt.IsZero())
+
+	e = NewEngine(newPolicy(t, Policy{
+		Allowlist: []string{addressA},
+	}), storage.NewMemoryStore())
+	v = violation(t, e.Check([]Output{{Address: addressB, Coins: 1e6}}, now))
+	require.Equal(t, RuleAddressNotAllowed, v.Rule)
+	require.Equal(t, "transaction rejected by the policy, the address "+addressB+" is not allowed", v.Error())
+}
+
+func TestDailyLimit(t *testing.T) {
+	store := storage.NewMemoryStore()
+	p := newPolicy(t, Policy{
+		Timezone:   "Europe/Berlin",
+		DailyLimit: "10",
+	})
+	e := NewEngine(p, store)
+
+	morning := time.Date(2026, 10, 14, 8, 0, 0, 0, p.location)
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 4e6}, {Address: addressB, Coins: 2e6}}, morning))
+
+	// checking does not spend
+	require.NoError(t, e.Check([]Output{{Address: addressA, Coins: 4e6}}, morning))
+	require.NoError(t, e.Check([]Output{{Address: addressA, Coins: 4e6}}, morning))
+
+	v := violation(t, e.Authorize([]Output{{Address: addressA, Coins: 5e6}}, morning.Add(time.Hour)))
+	require.Equal(t, RuleDailyLimit, v.Rule)
+	require.Equal(t, "transaction rejected by the policy, the daily limit of 10 coins is exceeded, 6 coins were spent today", v.Error())
+	require.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, p.location), v.RetryAt)
+
+	// the spent coins survive a restart
+	e = NewEngine(p, store)
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 4e6}}, morning.Add(2*time.Hour)))
+	violation(t, e.Check([]Output{{Address: addressA, Coins: 1}}, morning.Add(2*time.Hour)))
+
+	// the limit resets at midnight in the time zone of the policy
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 10e6}}, time.Date(2026, 10, 14, 22, 30, 0, 0, time.UTC)))
+
+	// a transaction above the limit is never allowed
+	v = violation(t, e.Check([]Output{{Address: addressA, Coins: 11e6}}, morning.Add(48*time.Hour)))
+	require.True(t, v.RetryAt.IsZero())
+}
+
+func TestCooldown(t *testing.T) {
+	e := NewEngine(newPolicy(t, Policy{
+		DailyLimit:       "1000",
+		LargeTransaction: "100",
+		Cooldown:         "1h",
+	}), storage.NewMemoryStore())
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 100e6}}, now))
+
+	// the small transactions do not wait
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 99e6}}, now.Add(time.Minute)))
+
+	v := violation(t, e.Authorize([]Output{{Address: addressA, Coins: 60e6}, {Address: addressB, Coins: 40e6}}, now.Add(30*time.Minute)))
+	require.Equal(t, RuleCooldown, v.Rule)
+	require.Equal(t, now.Add(time.Hour), v.RetryAt)
+
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 100e6}}, now.Add(time.Hour)))
+	v = violation(t, e.Check([]Output{{Address: addressA, Coins: 100e6}}, now.Add(90*time.Minute)))
+	require.Equal(t, now.Add(2*time.Hour), v.RetryAt)
+}
+
+type hookFunc func(outputs []Output) error
+
+func (f hookFunc) CheckTransaction(outputs []Output) error {
+	return f(outputs)
+}
+
+func TestHooks(t *testing.T) {
+	e := NewEngine(nil, storage.NewMemoryStore())
+	now := time.Now()
+
+	require.NoError(t, e.Authorize([]Output{{Address: addressB, Coins: 1e12}}, now))
+
+	e.AddHook(hookFunc(func(outputs []Output) error {
+		for _, o := range outputs {
+			if o.Address == addressB {
+				return &Violation{
+					Rule:   "plugin",
+					Reason: "the address is flagged",
+				}
+			}
+		}
+		return nil
+	}))
+
+	require.NoError(t, e.Authorize([]Output{{Address: addressA, Coins: 1e6}}, now))
+	v := violation(t, e.Check([]Output{{Address: addressA, Coins: 1e6}, {Address: addressB, Coins: 1e6}}, now))
+	require.Equal(t, "plugin", v.Rule)
+
+	// the hooks are evaluated after the rules of the policy
+	e = NewEngine(newPolicy(t, Policy{
+		Denylist: []string{addressB},
+	}), storage.NewMemoryStore())
+	e.AddHook(hookFunc(func(outputs []Output) error {
+		return fmt.Errorf("hook failed")
+	}))
+	v = violation(t, e.Check([]Output{{Address: addressB, Coins: 1e6}}, now))
+	require.Equal(t, RuleAddressDenied, v.Rule)
+	require.EqualError(t, e.Authorize([]Output{{Address: addressA, Coins: 1e6}}, now), "hook failed")
+}
@@ -0,0 +1,61 @@
+// +build darwin,!ios,cgo windows,cgo
+
+package u2f
+
+import (
+	"fmt"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/usb/lowlevel/hidapi"
+)
+
+const (
+	// fidoUsagePage is the usage page of the U2F interface
+	fidoUsagePage = 0xf1d0
+	// readTimeout is how long a read waits for a report, in milliseconds
+	readTimeout = 5000
+)
+
+// hid is the U2F interface opened with hidapi
+type hid struct {
+	dev *hidapi.HidDevice
+}
+
+// openDevice opens the U2F interface of the device, found by its vendor and product IDs and its usage page
+func openDevice() (Device, error) {
+	for _, info := range hidapi.HidEnumerate(usb.VendorT1, usb.ProductT1Firmware) {
+		if info.UsagePage != fidoUsagePage {
+			continue
+		}
+
+		dev, err := info.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the U2F interface: %v", err)
+		}
+		return &hid{dev: dev}, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// WriteReport writes the report, hidapi prepends the report ID 0 on Windows
+func (h *hid) WriteReport(report []byte) error {
+	_, err := h.dev.Write(report, true)
+	return err
+}
+
+func (h *hid) ReadReport() ([]byte, error) {
+	report := make([]byte, ReportSize)
+	n, err := h.dev.Read(report, readTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("u2f: no report read in %d ms", readTimeout)
+	}
+	return report[:n], nil
+}
+
+func (h *hid) Close() error {
+	return h.dev.Close()
+}
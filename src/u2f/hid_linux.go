@@ -0,0 +1,70 @@
+// +build linux
+
+package u2f
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+)
+
+// fidoUsagePage is the start of the report descriptor of the U2F interface, the FIDO usage page 0xf1d0
+var fidoUsagePage = []byte{0x06, 0xd0, 0xf1}
+
+// hidraw is the hidraw character device of the U2F interface
+type hidraw struct {
+	f *os.File
+}
+
+// openDevice opens the hidraw device of the U2F interface of the device, found in sysfs by its vendor and product
+// IDs and the usage page of its report descriptor
+func openDevice() (Device, error) {
+	dirs, err := filepath.Glob("/sys/class/hidraw/hidraw*")
+	if err != nil {
+		return nil, err
+	}
+
+	hidID := fmt.Sprintf("HID_ID=%04X:%08X:%08X", 3, usb.VendorT1, usb.ProductT1Firmware)
+	for _, dir := range dirs {
+		uevent, err := ioutil.ReadFile(filepath.Join(dir, "device", "uevent"))
+		if err != nil || !strings.Contains(strings.ToUpper(string(uevent)), hidID) {
+			continue
+		}
+		descriptor, err := ioutil.ReadFile(filepath.Join(dir, "device", "report_descriptor"))
+		if err != nil || !bytes.HasPrefix(descriptor, fidoUsagePage) {
+			continue
+		}
+
+		f, err := os.OpenFile(filepath.Join("/dev", filepath.Base(dir)), os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the U2F interface: %v", err)
+		}
+		return &hidraw{f: f}, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// WriteReport writes the report after the report ID 0 of the devices without report IDs
+func (h *hidraw) WriteReport(report []byte) error {
+	_, err := h.f.Write(append([]byte{0}, report...))
+	return err
+}
+
+func (h *hidraw) ReadReport() ([]byte, error) {
+	report := make([]byte, ReportSize)
+	n, err := h.f.Read(report)
+	if err != nil {
+		return nil, err
+	}
+	return report[:n], nil
+}
+
+func (h *hidraw) Close() error {
+	return h.f.Close()
+}
@@ -0,0 +1,7 @@
+// +build !linux,!cgo !linux,!darwin,!windows ios
+
+package u2f
+
+func openDevice() (Device, error) {
+	return nil, ErrUnsupported
+}
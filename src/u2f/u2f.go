@@ -0,0 +1,311 @@
+// Package u2f relays the U2F registration and authentication requests to the U2F HID interface of the device.
+// The requests are the raw U2F messages of the FIDO U2F Raw Message Formats, framed with the U2FHID protocol.
+package u2f
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ReportSize is the size of the HID reports of the U2F interface
+const ReportSize = 64
+
+// U2FHID commands
+const (
+	cmdMsg   = 0x83
+	cmdInit  = 0x86
+	cmdError = 0xbf
+)
+
+// U2F instructions and parameters
+const (
+	insRegister     = 0x01
+	insAuthenticate = 0x02
+	insVersion      = 0x03
+
+	authEnforce   = 0x03
+	authCheckOnly = 0x07
+)
+
+// U2F status words
+const (
+	swNoError                = 0x9000
+	swConditionsNotSatisfied = 0x6985
+	swWrongData              = 0x6a80
+)
+
+// initPayloadSize and contPayloadSize are the data bytes of the initialization and continuation packets
+const (
+	initPayloadSize = ReportSize - 7
+	contPayloadSize = ReportSize - 5
+)
+
+// DefaultTimeout is how long the user is given to confirm its presence on the device
+const DefaultTimeout = 30 * time.Second
+
+// broadcastCID is the channel of the INIT command allocating a channel
+var broadcastCID = [4]byte{0xff, 0xff, 0xff, 0xff}
+
+var (
+	// ErrNotFound is returned when no device with a U2F interface is connected
+	ErrNotFound = errors.New("no device with a U2F interface is connected")
+	// ErrUnsupported is returned on the systems without access to the U2F interface
+	ErrUnsupported = errors.New("the U2F interface is not supported on this system")
+	// ErrUserPresenceRequired is returned until the user confirms its presence on the device
+	ErrUserPresenceRequired = errors.New("the user presence is required")
+	// ErrWrongKeyHandle is returned when the key handle was not registered by the device for the application
+	ErrWrongKeyHandle = errors.New("the key handle was not registered by the device for this application")
+	// ErrTimeout is returned when the user does not confirm its presence in time
+	ErrTimeout = errors.New("the user presence was not confirmed on the device in time")
+)
+
+// Device is the U2F HID interface of a device, exchanging reports of ReportSize bytes
+type Device interface {
+	io.Closer
+	WriteReport(report []byte) error
+	ReadReport() ([]byte, error)
+}
+
+// Token exchanges U2F messages with a device over a U2FHID channel
+type Token struct {
+	dev Device
+	cid [4]byte
+}
+
+// NewToken allocates a channel on the device
+func NewToken(dev Device) (*Token, error) {
+	t := &Token{
+		dev: dev,
+		cid: broadcastCID,
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	rsp, err := t.call(cmdInit, nonce)
+	if err != nil {
+		return nil, err
+	}
+	// the response is the nonce, the channel ID, and the protocol and device versions
+	if len(rsp) < 17 || string(rsp[:8]) != string(nonce) {
+		return nil, errors.New("u2f: invalid INIT response")
+	}
+	copy(t.cid[:], rsp[8:12])
+
+	return t, nil
+}
+
+// Close closes the device
+func (t *Token) Close() error {
+	return t.dev.Close()
+}
+
+// Version returns the U2F protocol version of the device, U2F_V2
+func (t *Token) Version() (string, error) {
+	rsp, err := t.apdu(insVersion, 0, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(rsp), nil
+}
+
+// Register registers a new key pair for the application, returning the registration data: the public key, the key
+// handle, the attestation certificate and the signature. It fails with ErrUserPresenceRequired until the user
+// confirms its presence.
+func (t *Token) Register(challenge, application [32]byte) ([]byte, error) {
+	data := make([]byte, 0, 64)
+	data = append(data, challenge[:]...)
+	data = append(data, application[:]...)
+	return t.apdu(insRegister, authEnforce, data)
+}
+
+// Authenticate signs the challenge with the key of the key handle, returning the signature data: the user presence,
+// the counter and the signature. checkOnly only checks that the key handle was registered for the application, the
+// device answers ErrUserPresenceRequired if it was. It fails with ErrUserPresenceRequired until the user confirms
+// its presence.
+func (t *Token) Authenticate(challenge, application [32]byte, keyHandle []byte, checkOnly bool) ([]byte, error) {
+	if len(keyHandle) == 0 || len(keyHandle) > 255 {
+		return nil, errors.New("the key handle must have 1 to 255 bytes")
+	}
+
+	data := make([]byte, 0, 65+len(keyHandle))
+	data = append(data, challenge[:]...)
+	data = append(data, application[:]...)
+	data = append(data, byte(len(keyHandle)))
+	data = append(data, keyHandle...)
+
+	control := byte(authEnforce)
+	if checkOnly {
+		control = authCheckOnly
+	}
+	return t.apdu(insAuthenticate, control, data)
+}
+
+// apdu sends the extended length APDU of the instruction and returns the data of the response
+func (t *Token) apdu(ins, p1 byte, data []byte) ([]byte, error) {
+	req := []byte{0, ins, p1, 0, 0, byte(len(data) >> 8), byte(len(data))}
+	req = append(req, data...)
+	req = append(req, 0, 0)
+
+	rsp, err := t.call(cmdMsg, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(rsp) < 2 {
+		return nil, errors.New("u2f: invalid APDU response")
+	}
+
+	switch sw := binary.BigEndian.Uint16(rsp[len(rsp)-2:]); sw {
+	case swNoError:
+		return rsp[:len(rsp)-2], nil
+	case swConditionsNotSatisfied:
+		return nil, ErrUserPresenceRequired
+	case swWrongData:
+		return nil, ErrWrongKeyHandle
+	default:
+		return nil, fmt.Errorf("u2f: the device answered the status 0x%04x", sw)
+	}
+}
+
+// call sends the U2FHID command with its data, split in packets, and reads the response of the command
+func (t *Token) call(cmd byte, data []byte) ([]byte, error) {
+	if len(data) > initPayloadSize+0x80*contPayloadSize {
+		return nil, errors.New("u2f: the message is too large")
+	}
+
+	report := make([]byte, ReportSize)
+	copy(report, t.cid[:])
+	report[4] = cmd
+	binary.BigEndian.PutUint16(report[5:7], uint16(len(data)))
+	n := copy(report[7:], data)
+	if err := t.dev.WriteReport(report); err != nil {
+		return nil, err
+	}
+
+	for seq := byte(0); n < len(data); seq++ {
+		report = make([]byte, ReportSize)
+		copy(report, t.cid[:])
+		report[4] = seq
+		n += copy(report[5:], data[n:])
+		if err := t.dev.WriteReport(report); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.read(cmd)
+}
+
+// read reads the response of the command, skipping the reports of the other channels
+func (t *Token) read(cmd byte) ([]byte, error) {
+	var rsp []byte
+	size := -1
+	seq := byte(0)
+	for size < 0 || len(rsp) < size {
+		report, err := t.dev.ReadReport()
+		if err != nil {
+			return nil, err
+		}
+		if len(report) < 7 || string(report[:4]) != string(t.cid[:]) {
+			continue
+		}
+
+		if size < 0 {
+			switch report[4] {
+			case cmd:
+			case cmdError:
+				if len(report) < 8 || binary.BigEndian.Uint16(report[5:7]) < 1 {
+					return nil, errors.New("u2f: invalid error response")
+				}
+				return nil, fmt.Errorf("u2f: the device answered the error 0x%02x", report[7])
+			default:
+				return nil, fmt.Errorf("u2f: unexpected response command 0x%02x", report[4])
+			}
+			size = int(binary.BigEndian.Uint16(report[5:7]))
+			rsp = append(rsp, report[7:]...)
+			continue
+		}
+
+		if report[4] != seq {
+			return nil, fmt.Errorf("u2f: unexpected continuation packet %d", report[4])
+		}
+		seq++
+		rsp = append(rsp, report[5:]...)
+	}
+
+	return rsp[:size], nil
+}
+
+// Relay runs the U2F operations on the device, waiting for the user to confirm its presence on the device.
+// The operations are serialized, the device has a single U2F interface.
+type Relay struct {
+	mu       sync.Mutex
+	open     func() (Device, error)
+	timeout  time.Duration
+	interval time.Duration
+}
+
+// NewRelay creates a Relay on the device connected to the computer, waiting up to timeout for the user presence
+func NewRelay(timeout time.Duration) *Relay {
+	return &Relay{
+		open:     openDevice,
+		timeout:  timeout,
+		interval: 200 * time.Millisecond,
+	}
+}
+
+// Register registers a new key pair for the application, returning the registration data
+func (r *Relay) Register(ctx context.Context, challenge, application [32]byte) ([]byte, error) {
+	return r.run(ctx, func(t *Token) ([]byte, error) {
+		return t.Register(challenge, application)
+	})
+}
+
+// Authenticate signs the challenge with the key of the key handle, returning the signature data
+func (r *Relay) Authenticate(ctx context.Context, challenge, application [32]byte, keyHandle []byte) ([]byte, error) {
+	return r.run(ctx, func(t *Token) ([]byte, error) {
+		return t.Authenticate(challenge, application, keyHandle, false)
+	})
+}
+
+// run runs the operation on the device until the user confirms its presence, ctx is canceled or the timeout expires
+func (r *Relay) run(ctx context.Context, op func(t *Token) ([]byte, error)) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dev, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewToken(dev)
+	if err != nil {
+		dev.Close() // nolint: errcheck
+		return nil, err
+	}
+	defer t.Close() // nolint: errcheck
+
+	timeout := time.NewTimer(r.timeout)
+	defer timeout.Stop()
+
+	for {
+		rsp, err := op(t)
+		if err != ErrUserPresenceRequired {
+			return rsp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, ErrTimeout
+		case <-time.After(r.interval):
+		}
+	}
+}
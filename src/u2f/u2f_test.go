@@ -0,0 +1,279 @@
+package u2f
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDevice is the device side of the U2FHID protocol, answering the APDUs with handle
+type fakeDevice struct {
+	cid     [4]byte
+	handle  func(apdu []byte) []byte
+	pending [][]byte
+	req     []byte
+	size    int
+	apdus   [][]byte
+	closed  bool
+}
+
+func newFakeDevice(handle func(apdu []byte) []byte) *fakeDevice {
+	return &fakeDevice{
+		cid:    [4]byte{1, 2, 3, 4},
+		handle: handle,
+	}
+}
+
+func (d *fakeDevice) WriteReport(report []byte) error {
+	if len(report) != ReportSize {
+		return errors.New("invalid report size")
+	}
+
+	if report[4]&0x80 != 0 {
+		d.size = int(binary.BigEndian.Uint16(report[5:7]))
+		d.req = append([]byte{report[4]}, report[7:]...)
+	} else {
+		d.req = append(d.req, report[5:]...)
+	}
+	if len(d.req)-1 < d.size {
+		return nil
+	}
+
+	cmd, data := d.req[0], d.req[1:d.size+1]
+	switch cmd {
+	case cmdInit:
+		rsp := append(append([]byte{}, data...), d.cid[:]...)
+		rsp = append(rsp, 2, 1, 0, 0, 0)
+		d.respond(broadcastCID, cmdInit, rsp)
+	case cmdMsg:
+		d.apdus = append(d.apdus, data)
+		d.respond(d.cid, cmdMsg, d.handle(data))
+	default:
+		d.respond(d.cid, cmdError, []byte{1})
+	}
+	return nil
+}
+
+// respond queues the reports of the response, after a report of another channel
+func (d *fakeDevice) respond(cid [4]byte, cmd byte, data []byte) {
+	d.pending = append(d.pending, make([]byte, ReportSize))
+
+	report := make([]byte, ReportSize)
+	copy(report, cid[:])
+	report[4] = cmd
+	binary.BigEndian.PutUint16(report[5:7], uint16(len(data)))
+	n := copy(report[7:], data)
+	d.pending = append(d.pending, report)
+
+	for seq := byte(0); n < len(data); seq++ {
+		report = make([]byte, ReportSize)
+		copy(report, cid[:])
+		report[4] = seq
+		n += copy(report[5:], data[n:])
+		d.pending = append(d.pending, report)
+	}
+}
+
+func (d *fakeDevice) ReadReport() ([]byte, error) {
+	if len(d.pending) == 0 {
+		return nil, errors.New("no report")
+	}
+	report := d.pending[0]
+	d.pending = d.pending[1:]
+	return report, nil
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func sw(data []byte, sw uint16) []byte {
+	return append(append([]byte{}, data...), byte(sw>>8), byte(sw))
+}
+
+func TestToken(t *testing.T) {
+	registration := bytes.Repeat([]byte{0x05}, 300)
+	dev := newFakeDevice(func(apdu []byte) []byte {
+		switch apdu[1] {
+		case insVersion:
+			return sw([]byte("U2F_V2"), swNoError)
+		case insRegister:
+			return sw(registration, swNoError)
+		case insAuthenticate:
+			if apdu[2] == authCheckOnly {
+				return sw(nil, swConditionsNotSatisfied)
+			}
+			return sw(nil, swWrongData)
+		default:
+			return sw(nil, 0x6d00)
+		}
+	})
+
+	token, err := NewToken(dev)
+	require.NoError(t, err)
+	require.Equal(t, dev.cid, token.cid)
+
+	version, err := token.Version()
+	require.NoError(t, err)
+	require.Equal(t, "U2F_V2", version)
+
+	var challenge, application [32]byte
+	challenge[0] = 0xc0
+	application[0] = 0xa0
+	rsp, err := token.Register(challenge, application)
+	require.NoError(t, err)
+	require.Equal(t, registration, rsp)
+
+	// the extended length APDU of the request
+	apdu := dev.apdus[len(dev.apdus)-1]
+	require.Equal(t, []byte{0, insRegister, authEnforce, 0, 0, 0, 64}, apdu[:7])
+	require.Equal(t, challenge[:], apdu[7:39])
+	require.Equal(t, application[:], apdu[39:71])
+	require.Equal(t, []byte{0, 0}, apdu[71:])
+
+	_, err = token.Authenticate(challenge, application, []byte{1, 2, 3}, true)
+	require.Equal(t, ErrUserPresenceRequired, err)
+	apdu = dev.apdus[len(dev.apdus)-1]
+	require.Equal(t, []byte{3, 1, 2, 3}, apdu[71:75])
+
+	_, err = token.Authenticate(challenge, application, []byte{1, 2, 3}, false)
+	require.Equal(t, ErrWrongKeyHandle, err)
+
+	_, err = token.Authenticate(challenge, application, nil, false)
+	require.Error(t, err)
+
+	// a long key handle spans several continuation packets
+	_, err = token.Authenticate(challenge, application, bytes.Repeat([]byte{7}, 255), false)
+	require.Equal(t, ErrWrongKeyHandle, err)
+	apdu = dev.apdus[len(dev.apdus)-1]
+	require.Len(t, apdu, 7+65+255+2)
+
+	dev.handle = func(apdu []byte) []byte {
+		return sw(nil, 0x6d00)
+	}
+	_, err = token.Version()
+	require.EqualError(t, err, "u2f: the device answered the status 0x6d00")
+
+	require.NoError(t, token.Close())
+	require.True(t, dev.closed)
+}
+
+func TestRelay(t *testing.T) {
+	presses := 0
+	dev := newFakeDevice(func(apdu []byte) []byte {
+		// the user confirms the presence on the third attempt
+		presses++
+		if presses < 3 {
+			return sw(nil, swConditionsNotSatisfied)
+		}
+		return sw([]byte("signature"), swNoError)
+	})
+
+	r := NewRelay(time.Second)
+	r.interval = time.Millisecond
+	r.open = func() (Device, error) {
+		return dev, nil
+	}
+
+	var challenge, application [32]byte
+	rsp, err := r.Authenticate(context.Background(), challenge, application, []byte{1})
+	require.NoError(t, err)
+	require.Equal(t, []byte("signature"), rsp)
+	require.Equal(t, 3, presses)
+	require.True(t, dev.closed)
+
+	// the user never confirms
+	dev = newFakeDevice(func(apdu []byte) []byte {
+		return sw(nil, swConditionsNotSatisfied)
+	})
+	r.timeout = 20 * time.Millisecond
+	_, err = r.Register(context.Background(), challenge, application)
+	require.Equal(t, ErrTimeout, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.timeout = time.Second
+	_, err = r.Register(ctx, challenge, application)
+	require.Equal(t, context.Canceled, err)
+
+	r.open = func() (Device, error) {
+		return nil, ErrNotFound
+	}
+	_, err = r.Register(context.Background(), challenge, application)
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestTokenRead(t *testing.T) {
+	cid := [4]byte{1, 2, 3, 4}
+
+	cases := []struct {
+		name    string
+		reports [][]byte
+		rsp     []byte
+		err     string
+	}{
+		{
+			name: "response",
+			reports: [][]byte{
+				{9, 9, 9, 9, cmdMsg, 0, 1, 0xee},
+				{1, 2, 3, 4, cmdMsg, 0, 2, 0xaa, 0xbb, 0},
+			},
+			rsp: []byte{0xaa, 0xbb},
+		},
+		{
+			name: "error",
+			reports: [][]byte{
+				{1, 2, 3, 4, cmdError, 0, 1, 6},
+			},
+			err: "u2f: the device answered the error 0x06",
+		},
+		{
+			name: "short error",
+			reports: [][]byte{
+				{1, 2, 3, 4, cmdError, 0, 1},
+			},
+			err: "u2f: invalid error response",
+		},
+		{
+			name: "empty error",
+			reports: [][]byte{
+				{1, 2, 3, 4, cmdError, 0, 0, 6},
+			},
+			err: "u2f: invalid error response",
+		},
+		{
+			name: "unexpected command",
+			reports: [][]byte{
+				{1, 2, 3, 4, cmdInit, 0, 1, 0},
+			},
+			err: "u2f: unexpected response command 0x86",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dev := &fakeDevice{
+				cid:     cid,
+				pending: tc.reports,
+			}
+			token := &Token{
+				dev: dev,
+				cid: cid,
+			}
+
+			rsp, err := token.read(cmdMsg)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.rsp, rsp)
+		})
+	}
+}
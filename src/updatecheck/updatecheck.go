@@ -0,0 +1,245 @@
+// Package updatecheck checks the release feed of the project for a newer release of the daemon, in the background
+// when opted in with -update-check. The release is never downloaded nor installed, the health and version endpoints
+// report it so the wallets prompt the users to upgrade.
+package updatecheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/skycoin/skycoin/src/util/logging"
+)
+
+const (
+	// DefaultFeed is the release feed of the project, the latest release of the daemon on GitHub
+	DefaultFeed = "https://api.github.com/repos/skycoin/hardware-wallet-daemon/releases/latest"
+	// DefaultInterval is how often the feed is checked
+	DefaultInterval = 24 * time.Hour
+
+	fetchTimeout = 30 * time.Second
+	maxFeedSize  = 1024 * 1024 // 1 MB
+	// maxNotesSize is the size of the release notes kept, the notes longer are truncated
+	maxNotesSize = 16 * 1024
+)
+
+var logger = logging.MustGetLogger("updatecheck")
+
+// ErrNoRelease is returned when the feed has no stable release
+var ErrNoRelease = errors.New("the release feed has no stable release")
+
+// Release is a release of the daemon published in the feed
+type Release struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	// Notes are the release notes, in Markdown
+	Notes       string    `json:"notes"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Status is the outcome of the last check of the feed
+type Status struct {
+	// UpdateAvailable is true if the latest release is newer than the daemon
+	UpdateAvailable bool   `json:"update_available"`
+	CurrentVersion  string `json:"current_version"`
+	// Latest is the latest stable release of the feed, nil until the feed is read
+	Latest *Release `json:"latest,omitempty"`
+	// CheckedAt is the time of the last check, nil until the feed is checked
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	// Error is the error of the last check, the release of the previous check is kept
+	Error string `json:"error,omitempty"`
+}
+
+// feedRelease is a release of the GitHub releases API, the feed is a release or a list of releases
+type feedRelease struct {
+	TagName     string    `json:"tag_name"`
+	HTMLURL     string    `json:"html_url"`
+	Body        string    `json:"body"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Checker checks the release feed for a release newer than the daemon
+type Checker struct {
+	feed     string
+	current  semver.Version
+	interval time.Duration
+	client   *http.Client
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewChecker creates a Checker of the feed URL for the daemon of version current, checked every interval.
+// The feed is fetched by transport, nil means http.DefaultTransport.
+func NewChecker(feed, current string, interval time.Duration, transport http.RoundTripper) (*Checker, error) {
+	if !strings.HasPrefix(feed, "http://") && !strings.HasPrefix(feed, "https://") {
+		return nil, fmt.Errorf("invalid update feed %q, it must be an http or https URL", feed)
+	}
+
+	if interval <= 0 {
+		return nil, errors.New("update check interval must be greater than 0")
+	}
+
+	v, err := parseVersion(current)
+	if err != nil {
+		return nil, fmt.Errorf("invalid daemon version %q, the update check compares semantic versions: %v", current, err)
+	}
+
+	return &Checker{
+		feed:     feed,
+		current:  v,
+		interval: interval,
+		client: &http.Client{
+			Timeout:   fetchTimeout,
+			Transport: transport,
+		},
+		status: Status{
+			CurrentVersion: v.String(),
+		},
+	}, nil
+}
+
+// Status returns the outcome of the last check
+func (c *Checker) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Run checks the feed now and every interval until quit is closed
+func (c *Checker) Run(quit <-chan struct{}) {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		if err := c.Check(); err != nil {
+			logger.WithError(err).Warning("Daemon update check failed")
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Check reads the feed once and updates the status, the error of the check is returned and kept in the status
+func (c *Checker) Check() error {
+	latest, err := c.latest()
+	now := time.Now().UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.status.CheckedAt = &now
+	if err != nil {
+		c.status.Error = err.Error()
+		return err
+	}
+
+	v, err := parseVersion(latest.Version)
+	if err != nil {
+		c.status.Error = err.Error()
+		return err
+	}
+
+	available := v.GT(c.current)
+	if available && !c.status.UpdateAvailable {
+		logger.Infof("Daemon update available: %s, running %s, see %s", latest.Version, c.current, latest.URL)
+	}
+
+	c.status.Error = ""
+	c.status.Latest = &latest
+	c.status.UpdateAvailable = available
+	return nil
+}
+
+// latest fetches the feed and returns its newest stable release
+func (c *Checker) latest() (Release, error) {
+	req, err := http.NewRequest(http.MethodGet, c.feed, nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Release{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("fetching the release feed failed: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return Release{}, err
+	}
+	if len(data) > maxFeedSize {
+		return Release{}, errors.New("the release feed is too large")
+	}
+
+	return parseFeed(data)
+}
+
+// parseFeed returns the newest stable release of the feed, a release or a list of releases of the GitHub releases API
+func parseFeed(data []byte) (Release, error) {
+	var releases []feedRelease
+	if err := json.Unmarshal(data, &releases); err != nil {
+		var r feedRelease
+		if err := json.Unmarshal(data, &r); err != nil {
+			return Release{}, fmt.Errorf("invalid release feed: %v", err)
+		}
+		releases = []feedRelease{r}
+	}
+
+	var latest *feedRelease
+	var latestVersion semver.Version
+	for i, r := range releases {
+		if r.Draft || r.Prerelease {
+			continue
+		}
+
+		v, err := parseVersion(r.TagName)
+		if err != nil || len(v.Pre) > 0 {
+			continue
+		}
+
+		if latest == nil || v.GT(latestVersion) {
+			latest = &releases[i]
+			latestVersion = v
+		}
+	}
+
+	if latest == nil {
+		return Release{}, ErrNoRelease
+	}
+
+	notes := latest.Body
+	if len(notes) > maxNotesSize {
+		notes = notes[:maxNotesSize]
+	}
+
+	return Release{
+		Version:     latestVersion.String(),
+		URL:         latest.HTMLURL,
+		Notes:       notes,
+		PublishedAt: latest.PublishedAt,
+	}, nil
+}
+
+// parseVersion parses a semantic version, with an optional v prefix as in the tags of the releases
+func parseVersion(s string) (semver.Version, error) {
+	return semver.Make(strings.TrimPrefix(strings.TrimSpace(s), "v"))
+}
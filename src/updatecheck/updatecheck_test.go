@@ -0,0 +1,154 @@
+package updatecheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRelease = `{
+	"tag_name": "v0.2.0",
+	"html_url": "https://github.com/skycoin/hardware-wallet-daemon/releases/tag/v0.2.0",
+	"body": "### Added\n- The firmware cache",
+	"draft": false,
+	"prerelease": false,
+	"published_at": "2019-10-01T00:00:00Z"
+}`
+
+func TestParseFeed(t *testing.T) {
+	cases := []struct {
+		name    string
+		feed    string
+		version string
+		err     string
+	}{
+		{
+			name:    "latest release",
+			feed:    testRelease,
+			version: "0.2.0",
+		},
+		{
+			name: "list of releases",
+			feed: `[{"tag_name": "v0.1.1"}, {"tag_name": "v0.3.0-rc1"}, {"tag_name": "v0.4.0", "prerelease": true},` +
+				`{"tag_name": "v0.5.0", "draft": true}, {"tag_name": "nightly"}, {"tag_name": "0.2.1"}, {"tag_name": "v0.1.0"}]`,
+			version: "0.2.1",
+		},
+		{
+			name: "no stable release",
+			feed: `[{"tag_name": "v0.3.0-rc1"}]`,
+			err:  ErrNoRelease.Error(),
+		},
+		{
+			name: "invalid feed",
+			feed: `<html>`,
+			err:  "invalid release feed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := parseFeed([]byte(tc.feed))
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.version, r.Version)
+		})
+	}
+
+	r, err := parseFeed([]byte(testRelease))
+	require.NoError(t, err)
+	require.Equal(t, Release{
+		Version:     "0.2.0",
+		URL:         "https://github.com/skycoin/hardware-wallet-daemon/releases/tag/v0.2.0",
+		Notes:       "### Added\n- The firmware cache",
+		PublishedAt: time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC),
+	}, r)
+}
+
+func TestNewChecker(t *testing.T) {
+	_, err := NewChecker("/tmp/feed.json", "0.1.0", DefaultInterval, nil)
+	require.Error(t, err)
+
+	_, err = NewChecker(DefaultFeed, "0.1.0", 0, nil)
+	require.Error(t, err)
+
+	_, err = NewChecker(DefaultFeed, "dev", DefaultInterval, nil)
+	require.Error(t, err)
+
+	c, err := NewChecker(DefaultFeed, "v0.1.0", DefaultInterval, nil)
+	require.NoError(t, err)
+	require.Equal(t, Status{
+		CurrentVersion: "0.1.0",
+	}, c.Status())
+}
+
+func TestCheck(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusOK
+	feed := testRelease
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(feed)) // nolint: errcheck
+	}))
+	defer srv.Close()
+
+	set := func(s int, f string) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = s
+		feed = f
+	}
+
+	c, err := NewChecker(srv.URL, "0.1.0", DefaultInterval, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Check())
+	s := c.Status()
+	require.True(t, s.UpdateAvailable)
+	require.Equal(t, "0.1.0", s.CurrentVersion)
+	require.Equal(t, "0.2.0", s.Latest.Version)
+	require.NotNil(t, s.CheckedAt)
+	require.Empty(t, s.Error)
+
+	// the release of the previous check is kept when the feed fails
+	set(http.StatusForbidden, "")
+	require.Error(t, c.Check())
+	s = c.Status()
+	require.True(t, s.UpdateAvailable)
+	require.Equal(t, "0.2.0", s.Latest.Version)
+	require.Equal(t, "fetching the release feed failed: 403 Forbidden", s.Error)
+
+	// the daemon runs the latest release
+	set(http.StatusOK, strings.Replace(testRelease, "v0.2.0", "v0.1.0", -1))
+	require.NoError(t, c.Check())
+	s = c.Status()
+	require.False(t, s.UpdateAvailable)
+	require.Equal(t, "0.1.0", s.Latest.Version)
+	require.Empty(t, s.Error)
+
+	// Run checks before the first interval
+	set(http.StatusOK, testRelease)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(quit)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.Status().UpdateAvailable {
+		require.True(t, time.Now().Before(deadline), "the update was not checked")
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(quit)
+	<-done
+}
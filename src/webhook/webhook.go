@@ -0,0 +1,249 @@
+// Package webhook POSTs the device and signing events of the bus to the configured URLs, so monitoring systems and
+// treasury workflows react to them without polling. Each request is signed with an HMAC-SHA256 of a shared secret,
+// and retried with an exponential backoff until the receiver accepts it.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/util/logging"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+)
+
+const (
+	// EventHeaderName is the header carrying the type of the event
+	EventHeaderName = "X-Webhook-Event"
+	// DeliveryHeaderName is the header carrying the sequence number of the event, the same for the retries of a delivery
+	DeliveryHeaderName = "X-Webhook-Delivery"
+	// TimestampHeaderName is the header carrying the Unix time of the request, in seconds
+	TimestampHeaderName = "X-Webhook-Timestamp"
+	// SignatureHeaderName is the header carrying the signature of the request, see Sign
+	SignatureHeaderName = "X-Webhook-Signature"
+
+	// DefaultMaxAttempts is the number of times an event is sent to a URL by default before it is dropped
+	DefaultMaxAttempts = 5
+	// DefaultTimeout is how long a receiver has to answer a request by default
+	DefaultTimeout = 10 * time.Second
+
+	// minBackoff and maxBackoff bound the wait between the attempts of a delivery
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+
+	// queueSize is the number of events waiting to be sent to a URL, the newer events are dropped beyond it
+	queueSize = 256
+)
+
+var logger = logging.MustGetLogger("webhook")
+
+// Types are the event types sent to the webhooks
+var Types = []events.Type{
+	events.TypeDeviceConnected,
+	events.TypeDeviceDisconnected,
+	events.TypeTransactionSigned,
+	events.TypeTransactionRejected,
+	events.TypeFirmwareUpdated,
+}
+
+// filter selects the events of Types
+var filter = events.Filter{
+	Types: Types,
+}
+
+// Config configures a Notifier
+type Config struct {
+	// URLs are the URLs the events are POSTed to
+	URLs []string
+	// Secret is the shared secret signing the requests
+	Secret []byte
+	// MaxAttempts is the number of times an event is sent to a URL before it is dropped, 0 means DefaultMaxAttempts
+	MaxAttempts int
+	// Timeout is how long a receiver has to answer a request, 0 means DefaultTimeout
+	Timeout time.Duration
+	// Transport sends the requests, nil means http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Notifier POSTs the events of Types to the webhooks. The events are sent to each URL in order, a URL failing
+// does not delay the others.
+type Notifier struct {
+	config Config
+	client *http.Client
+
+	// minBackoff is replaced in tests
+	minBackoff time.Duration
+}
+
+// New creates a Notifier
+func New(c Config) *Notifier {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	return &Notifier{
+		config: c,
+		client: &http.Client{
+			Timeout:   c.Timeout,
+			Transport: c.Transport,
+		},
+		minBackoff: minBackoff,
+	}
+}
+
+// Run sends the events published on bus to the webhooks until quit is closed. The events dropped by the
+// subscription, because a delivery did not keep up, are caught up from the bus.
+func (n *Notifier) Run(bus *events.Bus, quit <-chan struct{}) {
+	n.run(bus, bus.Subscribe(filter), quit)
+}
+
+func (n *Notifier) run(bus *events.Bus, sub *events.Subscription, quit <-chan struct{}) {
+	defer sub.Unsubscribe()
+
+	var wg sync.WaitGroup
+	queues := make([]chan events.Event, len(n.config.URLs))
+	for i, u := range n.config.URLs {
+		queues[i] = make(chan events.Event, queueSize)
+		wg.Add(1)
+		go func(u string, queue <-chan events.Event) {
+			defer wg.Done()
+			n.deliverAll(u, queue, quit)
+		}(u, queues[i])
+	}
+
+	var last uint64
+	enqueue := func(ev events.Event) {
+		// the events caught up may be received again from the subscription
+		if ev.Seq <= last {
+			return
+		}
+		last = ev.Seq
+
+		for i, queue := range queues {
+			select {
+			case queue <- ev:
+			default:
+				logger.Warningf("Webhook %s does not keep up, dropping event %d", n.config.URLs[i], ev.Seq)
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-quit:
+			wg.Wait()
+			return
+		case ev := <-sub.C:
+			enqueue(ev)
+		case <-sub.Overflow:
+			logger.Warningf("%d events dropped by the subscription, catching up from event %d", sub.Dropped(), last)
+			evs, err := bus.Since(last, 0, filter)
+			if err != nil {
+				logger.WithError(err).Error("Failed to catch up with the events")
+				continue
+			}
+			for _, ev := range evs {
+				enqueue(ev)
+			}
+		}
+	}
+}
+
+// deliverAll sends the events of queue to u, in order, until quit is closed
+func (n *Notifier) deliverAll(u string, queue <-chan events.Event, quit <-chan struct{}) {
+	for {
+		select {
+		case <-quit:
+			return
+		case ev := <-queue:
+			if err := n.deliver(u, ev, quit); err != nil {
+				logger.WithError(err).Errorf("Failed to send event %d to webhook %s, dropping it", ev.Seq, u)
+			}
+		}
+	}
+}
+
+// deliver sends ev to u, retrying with an exponential backoff until it is accepted, refused or MaxAttempts is reached
+func (n *Notifier) deliver(u string, ev events.Event, quit <-chan struct{}) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.minBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.send(u, ev, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.config.MaxAttempts {
+			return err
+		}
+
+		logger.WithError(err).Warningf("Webhook %s failed, retrying event %d in %s", u, ev.Seq, backoff)
+		select {
+		case <-quit:
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// send sends a signed request of ev to u, it returns true with the error if the request should be retried
+func (n *Notifier) send(u string, ev events.Event, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeaderName, string(ev.Type))
+	req.Header.Set(DeliveryHeaderName, strconv.FormatUint(ev.Seq, 10))
+	req.Header.Set(TimestampHeaderName, timestamp)
+	req.Header.Set(SignatureHeaderName, Sign(n.config.Secret, timestamp, body))
+
+	rsp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer rsp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(rsp.Body, 4096)) // nolint: errcheck
+
+	switch {
+	case rsp.StatusCode >= 200 && rsp.StatusCode < 300:
+		return false, nil
+	case rsp.StatusCode >= 500, rsp.StatusCode == http.StatusRequestTimeout, rsp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook returned %s", rsp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", rsp.Status)
+	}
+}
+
+// Sign returns the signature of a request, sha256= followed by the hex encoded HMAC-SHA256 of the timestamp of the
+// request, a dot and the body. Receivers must compare it in constant time, and may refuse the requests whose
+// timestamp is too old to be a retry.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp + ".")) // nolint: errcheck
+	h.Write(body)                    // nolint: errcheck
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/events"
+	"github.com/skycoin/hardware-wallet-daemon/src/storage"
+)
+
+// receiver records the requests of a webhook, failing the first ones with status
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	attempts int
+	received []events.Event
+	err      error
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.attempts++
+	if rc.attempts <= rc.failures {
+		w.WriteHeader(rc.status)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil && r.Header.Get(SignatureHeaderName) != Sign([]byte("secret"), r.Header.Get(TimestampHeaderName), body) {
+		err = errInvalidSignature
+	}
+	var ev events.Event
+	if err == nil {
+		err = json.Unmarshal(body, &ev)
+	}
+	if err == nil && (r.Header.Get(EventHeaderName) != string(ev.Type) || r.Header.Get(DeliveryHeaderName) == "") {
+		err = errInvalidHeaders
+	}
+	if err != nil {
+		rc.err = err
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rc.received = append(rc.received, ev)
+}
+
+func (rc *receiver) types() []events.Type {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var types []events.Type
+	for _, ev := range rc.received {
+		types = append(types, ev.Type)
+	}
+	return types
+}
+
+type testError string
+
+func (e testError) Error() string {
+	return string(e)
+}
+
+const (
+	errInvalidSignature = testError("invalid signature")
+	errInvalidHeaders   = testError("invalid headers")
+)
+
+func TestNotifier(t *testing.T) {
+	bus, err := events.NewBus(storage.NewMemoryStore(), 0)
+	require.NoError(t, err)
+
+	// recovers after two failures
+	flaky := &receiver{
+		failures: 2,
+		status:   http.StatusServiceUnavailable,
+	}
+	flakyServer := httptest.NewServer(flaky)
+	defer flakyServer.Close()
+
+	// refuses the first event, which is not retried
+	refusing := &receiver{
+		failures: 1,
+		status:   http.StatusGone,
+	}
+	refusingServer := httptest.NewServer(refusing)
+	defer refusingServer.Close()
+
+	n := New(Config{
+		URLs:   []string{flakyServer.URL, refusingServer.URL},
+		Secret: []byte("secret"),
+	})
+	n.minBackoff = time.Millisecond
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	sub := bus.Subscribe(filter)
+	go func() {
+		defer close(done)
+		n.run(bus, sub, quit)
+	}()
+
+	for _, typ := range []events.Type{
+		events.TypeDeviceConnected,
+		events.TypeOperationFinished,
+		events.TypeTransactionSigned,
+		events.TypeLogFileFailed,
+		events.TypeDeviceDisconnected,
+	} {
+		_, err := bus.Publish(typ, "", nil)
+		require.NoError(t, err)
+	}
+
+	expected := []events.Type{
+		events.TypeDeviceConnected,
+		events.TypeTransactionSigned,
+		events.TypeDeviceDisconnected,
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(flaky.types()) < 3 || len(refusing.types()) < 2; {
+		require.True(t, time.Now().Before(deadline), "the events were not delivered")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(quit)
+	<-done
+
+	require.NoError(t, flaky.err)
+	require.NoError(t, refusing.err)
+	require.Equal(t, expected, flaky.types())
+	require.Equal(t, 5, flaky.attempts)
+	require.Equal(t, expected[1:], refusing.types())
+	require.Equal(t, 3, refusing.attempts)
+}
+
+func TestDeliverMaxAttempts(t *testing.T) {
+	failing := &receiver{
+		failures: 10,
+		status:   http.StatusInternalServerError,
+	}
+	s := httptest.NewServer(failing)
+	defer s.Close()
+
+	n := New(Config{
+		Secret:      []byte("secret"),
+		MaxAttempts: 3,
+	})
+	n.minBackoff = time.Millisecond
+
+	err := n.deliver(s.URL, events.Event{Seq: 1, Type: events.TypeFirmwareUpdated}, make(chan struct{}))
+	require.Error(t, err)
+	require.Equal(t, "webhook returned 500 Internal Server Error", err.Error())
+	require.Equal(t, 3, failing.attempts)
+}
+
+func TestSign(t *testing.T) {
+	require.Equal(t, "sha256=45cfc8bb946128177e67e49289cb7c31be90bcec669ffb244649a1b0f4688e4d", Sign([]byte("secret"), "1570000000", []byte(`{"seq":1}`)))
+}
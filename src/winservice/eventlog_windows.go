@@ -0,0 +1,129 @@
+// +build windows
+
+package winservice
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// eventSourceKey is the registry key of the event log source of the service
+	eventSourceKey = `SYSTEM\CurrentControlSet\Services\EventLog\Application\` + Name
+	// eventMessageFile formats the events with ID 1 to 1000 as their message, so no message file is installed
+	eventMessageFile = `%SystemRoot%\System32\EventCreate.exe`
+	eventID          = 1
+
+	eventTypesSupported = windows.EVENTLOG_ERROR_TYPE | windows.EVENTLOG_WARNING_TYPE | windows.EVENTLOG_INFORMATION_TYPE
+)
+
+var (
+	procRegCreateKeyExW = advapi32.NewProc("RegCreateKeyExW")
+	procRegSetValueExW  = advapi32.NewProc("RegSetValueExW")
+	procRegDeleteKeyW   = advapi32.NewProc("RegDeleteKeyW")
+)
+
+// installEventSource registers the event log source of the service
+func installEventSource() error {
+	var key windows.Handle
+	if r, _, _ := procRegCreateKeyExW.Call(
+		windows.HKEY_LOCAL_MACHINE,
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(eventSourceKey))),
+		0, 0, 0,
+		windows.KEY_SET_VALUE,
+		0,
+		uintptr(unsafe.Pointer(&key)),
+		0); r != 0 {
+		return syscall.Errno(r)
+	}
+	defer windows.RegCloseKey(key) // nolint: errcheck
+
+	messageFile := windows.StringToUTF16(eventMessageFile)
+	if err := setValue(key, "EventMessageFile", windows.REG_EXPAND_SZ, unsafe.Pointer(&messageFile[0]), len(messageFile)*2); err != nil {
+		return err
+	}
+
+	var types uint32 = eventTypesSupported
+	return setValue(key, "TypesSupported", windows.REG_DWORD, unsafe.Pointer(&types), 4)
+}
+
+func setValue(key windows.Handle, name string, valueType uint32, data unsafe.Pointer, size int) error {
+	if r, _, _ := procRegSetValueExW.Call(
+		uintptr(key),
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(name))),
+		0,
+		uintptr(valueType),
+		uintptr(data),
+		uintptr(size)); r != 0 {
+		return syscall.Errno(r)
+	}
+	return nil
+}
+
+// removeEventSource removes the event log source of the service, the logged events are kept
+func removeEventSource() error {
+	r, _, _ := procRegDeleteKeyW.Call(
+		windows.HKEY_LOCAL_MACHINE,
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(eventSourceKey))))
+	if r != 0 && syscall.Errno(r) != windows.ERROR_FILE_NOT_FOUND {
+		return syscall.Errno(r)
+	}
+	return nil
+}
+
+// EventLog is a logrus hook writing the log entries to the Windows event log
+type EventLog struct {
+	handle    windows.Handle
+	formatter logrus.Formatter
+}
+
+// OpenEventLog opens the event log source of the service
+func OpenEventLog() (*EventLog, error) {
+	h, err := windows.RegisterEventSource(nil, windows.StringToUTF16Ptr(Name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLog{
+		handle: h,
+		// the event log records the time of the events
+		formatter: &logrus.TextFormatter{
+			DisableColors:    true,
+			DisableTimestamp: true,
+		},
+	}, nil
+}
+
+// Levels implements logrus.Hook
+func (l *EventLog) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (l *EventLog) Fire(e *logrus.Entry) error {
+	msg, err := l.formatter.Format(e)
+	if err != nil {
+		return err
+	}
+
+	var eventType uint16
+	switch e.Level {
+	case logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel:
+		eventType = windows.EVENTLOG_ERROR_TYPE
+	case logrus.WarnLevel:
+		eventType = windows.EVENTLOG_WARNING_TYPE
+	default:
+		eventType = windows.EVENTLOG_INFORMATION_TYPE
+	}
+
+	s := windows.StringToUTF16Ptr(string(msg))
+	return windows.ReportEvent(l.handle, eventType, 0, eventID, 0, 1, 0, &s, nil)
+}
+
+// Close closes the event log source
+func (l *EventLog) Close() error {
+	return windows.DeregisterEventSource(l.handle)
+}
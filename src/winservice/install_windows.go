@@ -0,0 +1,168 @@
+// +build windows
+
+package winservice
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// restartDelays are the delays of the restarts of the service after its first, second and later failures
+var restartDelays = []time.Duration{
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// failureResetPeriod is how long the service runs without failure before its failure count is reset
+const failureResetPeriod = 24 * time.Hour
+
+// serviceFailureActionsFlag is SERVICE_FAILURE_ACTIONS_FLAG
+type serviceFailureActionsFlag struct {
+	FailureActionsOnNonCrashFailures int32
+}
+
+// Install installs the service running the daemon with args, started with Windows and restarted when it fails.
+// It must be run by an administrator.
+func Install(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return err
+	}
+
+	cmd := []string{syscall.EscapeArg(exe)}
+	for _, a := range args {
+		cmd = append(cmd, syscall.EscapeArg(a))
+	}
+
+	m, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_ALL_ACCESS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager, run as administrator: %v", err)
+	}
+	defer windows.CloseServiceHandle(m) // nolint: errcheck
+
+	s, err := windows.CreateService(m,
+		windows.StringToUTF16Ptr(Name),
+		windows.StringToUTF16Ptr(DisplayName),
+		windows.SERVICE_ALL_ACCESS,
+		windows.SERVICE_WIN32_OWN_PROCESS,
+		windows.SERVICE_AUTO_START,
+		windows.SERVICE_ERROR_NORMAL,
+		windows.StringToUTF16Ptr(strings.Join(cmd, " ")),
+		nil, nil, nil, nil, nil)
+	if err != nil {
+		if err == windows.ERROR_SERVICE_EXISTS {
+			return fmt.Errorf("the %s service is already installed, uninstall it first", Name)
+		}
+		return err
+	}
+	defer windows.CloseServiceHandle(s) // nolint: errcheck
+
+	if err := configure(s); err != nil {
+		windows.DeleteService(s) // nolint: errcheck
+		return err
+	}
+
+	if err := installEventSource(); err != nil {
+		windows.DeleteService(s) // nolint: errcheck
+		return fmt.Errorf("failed to install the event log source: %v", err)
+	}
+
+	return nil
+}
+
+// configure sets the description and the failure actions of the service
+func configure(s windows.Handle) error {
+	description := windows.SERVICE_DESCRIPTION{
+		Description: windows.StringToUTF16Ptr(Description),
+	}
+	if err := windows.ChangeServiceConfig2(s, windows.SERVICE_CONFIG_DESCRIPTION, (*byte)(unsafe.Pointer(&description))); err != nil {
+		return err
+	}
+
+	actions := make([]windows.SC_ACTION, len(restartDelays))
+	for i, d := range restartDelays {
+		actions[i] = windows.SC_ACTION{
+			Type:  windows.SC_ACTION_RESTART,
+			Delay: uint32(d / time.Millisecond),
+		}
+	}
+	failureActions := windows.SERVICE_FAILURE_ACTIONS{
+		ResetPeriod:  uint32(failureResetPeriod / time.Second),
+		ActionsCount: uint32(len(actions)),
+		Actions:      &actions[0],
+	}
+	if err := windows.ChangeServiceConfig2(s, windows.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&failureActions))); err != nil {
+		return err
+	}
+
+	// the failure actions also apply when the daemon stops with an error, not only when it crashes
+	flag := serviceFailureActionsFlag{
+		FailureActionsOnNonCrashFailures: 1,
+	}
+	return windows.ChangeServiceConfig2(s, windows.SERVICE_CONFIG_FAILURE_ACTIONS_FLAG, (*byte)(unsafe.Pointer(&flag)))
+}
+
+// Uninstall stops and removes the service. It must be run by an administrator.
+func Uninstall() error {
+	m, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_ALL_ACCESS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager, run as administrator: %v", err)
+	}
+	defer windows.CloseServiceHandle(m) // nolint: errcheck
+
+	s, err := windows.OpenService(m, windows.StringToUTF16Ptr(Name), windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS|windows.DELETE)
+	if err != nil {
+		if err == windows.ERROR_SERVICE_DOES_NOT_EXIST {
+			return fmt.Errorf("the %s service is not installed", Name)
+		}
+		return err
+	}
+	defer windows.CloseServiceHandle(s) // nolint: errcheck
+
+	if err := stop(s); err != nil {
+		return err
+	}
+
+	if err := windows.DeleteService(s); err != nil {
+		return err
+	}
+
+	return removeEventSource()
+}
+
+// stop stops the service and waits for the daemon to stop
+func stop(s windows.Handle) error {
+	var status windows.SERVICE_STATUS
+	if err := windows.ControlService(s, windows.SERVICE_CONTROL_STOP, &status); err != nil {
+		if err == windows.ERROR_SERVICE_NOT_ACTIVE {
+			return nil
+		}
+		return fmt.Errorf("failed to stop the %s service: %v", Name, err)
+	}
+
+	deadline := time.Now().Add(stopWaitHint)
+	for status.CurrentState != windows.SERVICE_STOPPED {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the %s service did not stop within %s", Name, stopWaitHint)
+		}
+		time.Sleep(300 * time.Millisecond)
+
+		if err := windows.QueryServiceStatus(s, &status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
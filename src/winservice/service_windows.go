@@ -0,0 +1,126 @@
+// +build windows
+
+package winservice
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// stopWaitHint is how long the service control manager waits for the daemon to stop before considering it hung
+const stopWaitHint = 30 * time.Second
+
+var (
+	advapi32                          = windows.NewLazySystemDLL("advapi32.dll")
+	procRegisterServiceCtrlHandlerExW = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+)
+
+// service is the state of the running service, the callbacks of the service control manager cannot carry Go values
+var service struct {
+	run      func(stop <-chan struct{}) error
+	err      error
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	handle windows.Handle
+	status windows.SERVICE_STATUS
+}
+
+// Run runs the daemon as the service until it returns. run must return once stop is closed,
+// when the service is stopped or Windows shuts down.
+// An error returned by run stops the service with a failure, so the service control manager restarts it.
+func Run(run func(stop <-chan struct{}) error) error {
+	service.run = run
+	service.stop = make(chan struct{})
+
+	table := []windows.SERVICE_TABLE_ENTRY{
+		{
+			ServiceName: windows.StringToUTF16Ptr(Name),
+			ServiceProc: windows.NewCallback(serviceMain),
+		},
+		{},
+	}
+
+	// the dispatcher returns once the service is stopped
+	if err := windows.StartServiceCtrlDispatcher(&table[0]); err != nil {
+		if err == windows.ERROR_FAILED_SERVICE_CONTROLLER_CONNECT {
+			return errors.New("-service run is used by the service control manager, start the service with: sc start " + Name)
+		}
+		return err
+	}
+
+	return service.err
+}
+
+// serviceMain is called by the service control manager on a thread of the dispatcher to run the service
+func serviceMain(argc uint32, argv **uint16) uintptr {
+	h, _, err := procRegisterServiceCtrlHandlerExW.Call(
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(Name))),
+		windows.NewCallback(controlHandler),
+		0)
+	if h == 0 {
+		service.err = err
+		return 0
+	}
+
+	service.mu.Lock()
+	service.handle = windows.Handle(h)
+	service.status.ServiceType = windows.SERVICE_WIN32_OWN_PROCESS
+	service.mu.Unlock()
+
+	setStatus(windows.SERVICE_RUNNING, windows.SERVICE_ACCEPT_STOP|windows.SERVICE_ACCEPT_SHUTDOWN)
+
+	service.err = service.run(service.stop)
+
+	service.mu.Lock()
+	if service.err != nil {
+		// a service specific error code makes the service control manager apply the failure actions
+		service.status.Win32ExitCode = uint32(windows.ERROR_SERVICE_SPECIFIC_ERROR)
+		service.status.ServiceSpecificExitCode = 1
+	}
+	service.mu.Unlock()
+
+	setStatus(windows.SERVICE_STOPPED, 0)
+	return 0
+}
+
+// controlHandler is called by the service control manager to control the service
+func controlHandler(control, eventType uint32, eventData, context uintptr) uintptr {
+	switch control {
+	case windows.SERVICE_CONTROL_STOP, windows.SERVICE_CONTROL_SHUTDOWN:
+		setStatus(windows.SERVICE_STOP_PENDING, 0)
+		service.stopOnce.Do(func() {
+			close(service.stop)
+		})
+	case windows.SERVICE_CONTROL_INTERROGATE:
+		service.mu.Lock()
+		windows.SetServiceStatus(service.handle, &service.status) // nolint: errcheck
+		service.mu.Unlock()
+	default:
+		return uintptr(windows.ERROR_CALL_NOT_IMPLEMENTED)
+	}
+
+	return windows.NO_ERROR
+}
+
+// setStatus reports the state of the service to the service control manager
+func setStatus(state, accepts uint32) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.status.CurrentState = state
+	service.status.ControlsAccepted = accepts
+	service.status.CheckPoint = 0
+	service.status.WaitHint = 0
+	if state == windows.SERVICE_STOP_PENDING {
+		service.status.CheckPoint = 1
+		service.status.WaitHint = uint32(stopWaitHint / time.Millisecond)
+	}
+
+	windows.SetServiceStatus(service.handle, &service.status) // nolint: errcheck
+}
@@ -0,0 +1,63 @@
+// Package winservice runs the daemon as a native Windows service. It installs the service, started with Windows and
+// restarted when it fails, reports the status of the daemon to the service control manager, stops the daemon
+// gracefully when the service is stopped and writes the logs to the Windows event log.
+package winservice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Name is the name of the service and of its event log source
+	Name = "skyhwd"
+	// DisplayName is the name of the service shown by the services console
+	DisplayName = "Skycoin Hardware Wallet Daemon"
+	// Description is the description of the service shown by the services console
+	Description = "Serves the Skycoin hardware wallet API to the wallet applications."
+)
+
+// ErrUnsupported is returned by the service commands on the other systems
+var ErrUnsupported = errors.New("the service mode is only supported on Windows")
+
+// Command is a service command
+type Command string
+
+const (
+	// CommandInstall installs the service, running the daemon with the arguments of the install command
+	CommandInstall Command = "install"
+	// CommandUninstall stops and removes the service
+	CommandUninstall Command = "uninstall"
+	// CommandRun runs the daemon as the service, the service control manager starts the daemon with it
+	CommandRun Command = "run"
+)
+
+// ParseCommand parses a service command
+func ParseCommand(s string) (Command, error) {
+	switch c := Command(s); c {
+	case CommandInstall, CommandUninstall, CommandRun:
+		return c, nil
+	default:
+		return "", fmt.Errorf("invalid service command %q, choices are: %s, %s, %s", s, CommandInstall, CommandUninstall, CommandRun)
+	}
+}
+
+// ServiceArgs returns the arguments the service runs the daemon with: the arguments of the install command,
+// without its -service flag, followed by -service run
+func ServiceArgs(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		if strings.HasPrefix(args[i], "-") && (name == "service" || strings.HasPrefix(name, "service=")) {
+			if name == "service" {
+				// the value is the next argument
+				i++
+			}
+			continue
+		}
+		out = append(out, args[i])
+	}
+
+	return append(out, "-service", string(CommandRun))
+}
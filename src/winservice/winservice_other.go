@@ -0,0 +1,45 @@
+// +build !windows
+
+package winservice
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Install installs the service running the daemon with args
+func Install(args []string) error {
+	return ErrUnsupported
+}
+
+// Uninstall stops and removes the service
+func Uninstall() error {
+	return ErrUnsupported
+}
+
+// Run runs the daemon as the service until it returns
+func Run(run func(stop <-chan struct{}) error) error {
+	return ErrUnsupported
+}
+
+// EventLog is a logrus hook writing the log entries to the Windows event log
+type EventLog struct{}
+
+// OpenEventLog opens the event log source of the service
+func OpenEventLog() (*EventLog, error) {
+	return nil, ErrUnsupported
+}
+
+// Levels implements logrus.Hook
+func (l *EventLog) Levels() []logrus.Level {
+	return nil
+}
+
+// Fire implements logrus.Hook
+func (l *EventLog) Fire(e *logrus.Entry) error {
+	return ErrUnsupported
+}
+
+// Close closes the event log source
+func (l *EventLog) Close() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,53 @@
+package winservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	for _, s := range []string{"install", "uninstall", "run"} {
+		c, err := ParseCommand(s)
+		require.NoError(t, err)
+		require.Equal(t, Command(s), c)
+	}
+
+	_, err := ParseCommand("start")
+	require.Equal(t, `invalid service command "start", choices are: install, uninstall, run`, err.Error())
+}
+
+func TestServiceArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		out  []string
+	}{
+		{
+			name: "no args",
+			args: []string{"-service", "install"},
+			out:  []string{"-service", "run"},
+		},
+		{
+			name: "args",
+			args: []string{"-data-dir", `C:\ProgramData\skyhwd`, "-service", "install", "-log-to-file"},
+			out:  []string{"-data-dir", `C:\ProgramData\skyhwd`, "-log-to-file", "-service", "run"},
+		},
+		{
+			name: "flag with value",
+			args: []string{"--service=install", "-web-interface-port=9520"},
+			out:  []string{"-web-interface-port=9520", "-service", "run"},
+		},
+		{
+			name: "value named service",
+			args: []string{"-profile", "service", "-service", "install"},
+			out:  []string{"-profile", "service", "-service", "run"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.out, ServiceArgs(tc.args))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package wirecapture
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+
+	"github.com/skycoin/hardware-wallet-daemon/src/header"
+)
+
+// Driver is a device driver capturing the messages exchanged with the device while the capture is started
+type Driver struct {
+	skyWallet.DeviceDriver
+	capture *Capture
+}
+
+var _ skyWallet.DeviceDriver = (*Driver)(nil)
+
+// NewDriver wraps driver, capturing its messages in capture
+func NewDriver(driver skyWallet.DeviceDriver, capture *Capture) *Driver {
+	return &Driver{
+		DeviceDriver: driver,
+		capture:      capture,
+	}
+}
+
+// SendToDevice implements skyWallet.DeviceDriver
+func (d *Driver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	enabled, payloads := d.capture.capturing()
+	if !enabled {
+		return d.DeviceDriver.SendToDevice(dev, chunks)
+	}
+
+	ex := d.newExchange(chunks, payloads)
+	start := time.Now()
+
+	msg, err := d.DeviceDriver.SendToDevice(dev, chunks)
+
+	ex.Duration = durationMs(time.Since(start))
+	if err != nil {
+		ex.Error = err.Error()
+	} else {
+		ex.Response = &Message{
+			Type: messages.MessageType(msg.Kind).String(),
+			Size: len(msg.Data),
+		}
+		if payloads {
+			ex.Response.Payload = msg.Data
+		}
+		ex.Failure = decodeFailure(msg)
+	}
+
+	d.capture.add(ex)
+	return msg, err
+}
+
+// SendToDeviceNoAnswer implements skyWallet.DeviceDriver
+func (d *Driver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	enabled, payloads := d.capture.capturing()
+	if !enabled {
+		return d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+	}
+
+	ex := d.newExchange(chunks, payloads)
+	ex.NoAnswer = true
+	start := time.Now()
+
+	err := d.DeviceDriver.SendToDeviceNoAnswer(dev, chunks)
+
+	ex.Duration = durationMs(time.Since(start))
+	if err != nil {
+		ex.Error = err.Error()
+	}
+
+	d.capture.add(ex)
+	return err
+}
+
+func (d *Driver) newExchange(chunks [][64]byte, payloads bool) Exchange {
+	ex := Exchange{
+		Time:   time.Now().UTC(),
+		Device: d.DeviceDriver.DeviceType().String(),
+		Request: Message{
+			Type:   "empty message",
+			Chunks: len(chunks),
+		},
+	}
+	if len(chunks) == 0 {
+		return ex
+	}
+
+	ex.Request.Type = header.Kind(chunks).String()
+	ex.Request.Size = header.Size(chunks)
+	if payloads {
+		ex.Request.Payload = readPayload(chunks)
+	}
+	return ex
+}
+
+// readPayload reassembles the payload of the message sent in chunks
+func readPayload(chunks [][64]byte) []byte {
+	var b bytes.Buffer
+	for _, chunk := range chunks {
+		b.Write(chunk[:])
+	}
+
+	msg, err := wire.ReadFrom(&b)
+	if err != nil {
+		return nil
+	}
+	return msg.Data
+}
+
+// decodeFailure returns the code and message of a Failure response, nil for the other responses
+func decodeFailure(msg wire.Message) *Failure {
+	if msg.Kind != uint16(messages.MessageType_MessageType_Failure) {
+		return nil
+	}
+
+	var failure messages.Failure
+	if err := proto.Unmarshal(msg.Data, &failure); err != nil {
+		return &Failure{
+			Code:    "undecodable",
+			Message: err.Error(),
+		}
+	}
+
+	return &Failure{
+		Code:    failure.GetCode().String(),
+		Message: failure.GetMessage(),
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,211 @@
+// Package wirecapture captures the protobuf messages exchanged with the device in a ring buffer, from the admin
+// endpoints, for debugging the devices returning a Failure intermittently. The message types, sizes and timings are
+// captured, and the code and message of the failures. The payloads, which hold the PINs and the seed words, are only
+// captured when the daemon allows it and the capture requests them.
+package wirecapture
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultSize is the number of exchanges kept in the ring buffer
+const DefaultSize = 1000
+
+// ErrPayloadsNotAllowed is returned when the payloads are requested from a daemon which does not allow them
+var ErrPayloadsNotAllowed = errors.New("capturing the payloads requires -wire-capture-payloads")
+
+// Message is a message sent to the device or received from it
+type Message struct {
+	// Type is the message type, such as MessageType_GetFeatures
+	Type string `json:"type"`
+	// Size is the size of the payload
+	Size int `json:"size"`
+	// Chunks is the number of 64 bytes chunks of the messages sent to the device
+	Chunks int `json:"chunks,omitempty"`
+	// Payload is the protobuf encoded payload, only captured with the payloads
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// Failure is the code and message of a Failure response
+type Failure struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Exchange is a message sent to the device and the response of the device
+type Exchange struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Duration is the time until the device answered or the message failed, in milliseconds
+	Duration float64 `json:"duration_ms"`
+	// Device is the device type, USB or EMULATOR
+	Device  string  `json:"device"`
+	Request Message `json:"request"`
+	// Response is nil if the message failed or expects no answer
+	Response *Message `json:"response,omitempty"`
+	// Failure is set if the device answered with a Failure
+	Failure *Failure `json:"failure,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	// NoAnswer is true for the messages which expect no answer, such as a cancel
+	NoAnswer bool `json:"no_answer,omitempty"`
+}
+
+// Status is the status of the capture
+type Status struct {
+	Enabled bool `json:"enabled"`
+	// Payloads is true if the payloads are captured
+	Payloads bool `json:"payloads"`
+	// PayloadsAllowed is true if the daemon allows capturing the payloads
+	PayloadsAllowed bool `json:"payloads_allowed"`
+	// Size is the number of exchanges kept, the oldest are dropped
+	Size     int    `json:"size"`
+	Captured int    `json:"captured"`
+	Dropped  uint64 `json:"dropped"`
+	// StartedAt is when the capture was started, nil if it was never started
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// Capture holds the exchanges captured in a ring buffer
+type Capture struct {
+	size          int
+	allowPayloads bool
+
+	mu        sync.Mutex
+	enabled   bool
+	payloads  bool
+	ring      []Exchange
+	next      int
+	seq       uint64
+	dropped   uint64
+	startedAt time.Time
+}
+
+// New creates a Capture keeping size exchanges, capturing the payloads if allowPayloads is true and they are requested
+func New(size int, allowPayloads bool) *Capture {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	return &Capture{
+		size:          size,
+		allowPayloads: allowPayloads,
+	}
+}
+
+// Start clears the captured exchanges and starts capturing, with the payloads if payloads is true
+func (c *Capture) Start(payloads bool) (Status, error) {
+	if payloads && !c.allowPayloads {
+		return Status{}, ErrPayloadsNotAllowed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clear()
+	c.enabled = true
+	c.payloads = payloads
+	c.startedAt = time.Now().UTC()
+	return c.status(), nil
+}
+
+// Stop stops capturing, the captured exchanges are kept
+func (c *Capture) Stop() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enabled = false
+	c.payloads = false
+	return c.status()
+}
+
+// Clear removes the captured exchanges
+func (c *Capture) Clear() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clear()
+	return c.status()
+}
+
+func (c *Capture) clear() {
+	c.ring = nil
+	c.next = 0
+	c.seq = 0
+	c.dropped = 0
+}
+
+// Status returns the status of the capture
+func (c *Capture) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status()
+}
+
+func (c *Capture) status() Status {
+	s := Status{
+		Enabled:         c.enabled,
+		Payloads:        c.payloads,
+		PayloadsAllowed: c.allowPayloads,
+		Size:            c.size,
+		Captured:        len(c.ring),
+		Dropped:         c.dropped,
+	}
+	if !c.startedAt.IsZero() {
+		startedAt := c.startedAt
+		s.StartedAt = &startedAt
+	}
+	return s
+}
+
+// Exchanges returns the captured exchanges, oldest first
+func (c *Capture) Exchanges() []Exchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	exchanges := make([]Exchange, 0, len(c.ring))
+	if len(c.ring) == c.size {
+		exchanges = append(exchanges, c.ring[c.next:]...)
+		exchanges = append(exchanges, c.ring[:c.next]...)
+	} else {
+		exchanges = append(exchanges, c.ring...)
+	}
+	return exchanges
+}
+
+// capturing returns whether the exchanges are captured, and their payloads
+func (c *Capture) capturing() (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled, c.payloads
+}
+
+// add adds an exchange to the ring buffer, dropping the oldest if it is full
+func (c *Capture) add(ex Exchange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the capture was stopped while the message was exchanged
+	if !c.enabled {
+		return
+	}
+	if !c.payloads {
+		ex.Request.Payload = nil
+		if ex.Response != nil {
+			ex.Response.Payload = nil
+		}
+	}
+
+	c.seq++
+	ex.Seq = c.seq
+
+	if len(c.ring) < c.size {
+		c.ring = append(c.ring, ex)
+		return
+	}
+
+	c.ring[c.next] = ex
+	c.next = (c.next + 1) % c.size
+	c.dropped++
+}
@@ -0,0 +1,146 @@
+package wirecapture
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	skyWallet "github.com/skycoin/hardware-wallet-go/src/skywallet"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/usb"
+	"github.com/skycoin/hardware-wallet-go/src/skywallet/wire"
+	messages "github.com/skycoin/hardware-wallet-protob/go"
+)
+
+type fakeDriver struct {
+	response wire.Message
+	err      error
+}
+
+func (d *fakeDriver) SendToDevice(dev usb.Device, chunks [][64]byte) (wire.Message, error) {
+	return d.response, d.err
+}
+
+func (d *fakeDriver) SendToDeviceNoAnswer(dev usb.Device, chunks [][64]byte) error {
+	return d.err
+}
+
+func (d *fakeDriver) GetDevice() (usb.Device, error) {
+	return nil, skyWallet.ErrNoDeviceConnected
+}
+
+func (d *fakeDriver) GetDeviceInfos() ([]usb.Info, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeviceType() skyWallet.DeviceType {
+	return skyWallet.DeviceTypeEmulator
+}
+
+func (d *fakeDriver) Close() {}
+
+// pinChunks encodes a PinMatrixAck, whose payload is sensitive
+func pinChunks(t *testing.T) ([][64]byte, []byte) {
+	data, err := proto.Marshal(&messages.PinMatrixAck{Pin: proto.String("1234")})
+	require.NoError(t, err)
+
+	chunks, err := skyWallet.MessagePinMatrixAck("1234")
+	require.NoError(t, err)
+	return chunks, data
+}
+
+func TestDriver(t *testing.T) {
+	failure, err := proto.Marshal(&messages.Failure{
+		Code:    messages.FailureType_Failure_PinInvalid.Enum(),
+		Message: proto.String("PIN invalid"),
+	})
+	require.NoError(t, err)
+
+	drv := &fakeDriver{
+		response: wire.Message{
+			Kind: uint16(messages.MessageType_MessageType_Failure),
+			Data: failure,
+		},
+	}
+	c := New(2, true)
+	d := NewDriver(drv, c)
+	chunks, payload := pinChunks(t)
+
+	// nothing is captured until the capture is started
+	_, err = d.SendToDevice(nil, chunks)
+	require.NoError(t, err)
+	require.Empty(t, c.Exchanges())
+	require.Equal(t, Status{
+		PayloadsAllowed: true,
+		Size:            2,
+	}, c.Status())
+
+	_, err = c.Start(false)
+	require.NoError(t, err)
+
+	_, err = d.SendToDevice(nil, chunks)
+	require.NoError(t, err)
+	exchanges := c.Exchanges()
+	require.Len(t, exchanges, 1)
+	ex := exchanges[0]
+	require.Equal(t, uint64(1), ex.Seq)
+	require.Equal(t, "EMULATOR", ex.Device)
+	require.Equal(t, Message{
+		Type:   "MessageType_PinMatrixAck",
+		Size:   len(payload),
+		Chunks: 1,
+	}, ex.Request)
+	require.Equal(t, &Message{
+		Type: "MessageType_Failure",
+		Size: len(failure),
+	}, ex.Response)
+	require.Equal(t, &Failure{
+		Code:    "Failure_PinInvalid",
+		Message: "PIN invalid",
+	}, ex.Failure)
+
+	// the payloads are captured when requested
+	_, err = c.Start(true)
+	require.NoError(t, err)
+	require.Empty(t, c.Exchanges())
+
+	_, err = d.SendToDevice(nil, chunks)
+	require.NoError(t, err)
+	drv.err = errors.New("device disconnected")
+	require.Equal(t, drv.err, d.SendToDeviceNoAnswer(nil, chunks))
+	_, err = d.SendToDevice(nil, chunks)
+	require.Equal(t, drv.err, err)
+
+	// the oldest exchange was dropped
+	exchanges = c.Exchanges()
+	require.Len(t, exchanges, 2)
+	require.Equal(t, uint64(2), exchanges[0].Seq)
+	require.True(t, exchanges[0].NoAnswer)
+	require.Equal(t, payload, exchanges[0].Request.Payload)
+	require.Equal(t, "device disconnected", exchanges[0].Error)
+	require.Equal(t, uint64(3), exchanges[1].Seq)
+	require.Nil(t, exchanges[1].Response)
+
+	s := c.Stop()
+	require.False(t, s.Enabled)
+	require.Equal(t, 2, s.Captured)
+	require.Equal(t, uint64(1), s.Dropped)
+
+	// the exchanges are kept once stopped, and not captured anymore
+	_, err = d.SendToDevice(nil, chunks)
+	require.Equal(t, drv.err, err)
+	require.Len(t, c.Exchanges(), 2)
+
+	require.Equal(t, 0, c.Clear().Captured)
+	require.Empty(t, c.Exchanges())
+}
+
+func TestPayloadsNotAllowed(t *testing.T) {
+	c := New(0, false)
+	require.Equal(t, DefaultSize, c.Status().Size)
+
+	_, err := c.Start(true)
+	require.Equal(t, ErrPayloadsNotAllowed, err)
+	require.False(t, c.Status().Enabled)
+}